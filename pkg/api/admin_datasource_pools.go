@@ -0,0 +1,120 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/tsdb/sqleng"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+// swagger:route GET /admin/datasources/pools admin_datasources adminListDataSourceConnectionPools
+//
+// List the connection pools of the SQL data sources.
+//
+// Returns the statistics of the connection pools of the SQL data sources of all the organizations. The pool of a
+// data source is created at its first query.
+//
+// Security:
+// - basic:
+//
+// Responses:
+// 200: adminListDataSourceConnectionPoolsResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+func (hs *HTTPServer) AdminListDataSourceConnectionPools(c *contextmodel.ReqContext) response.Response {
+	return response.JSON(http.StatusOK, sqleng.ListConnectionPoolStats())
+}
+
+// swagger:route GET /admin/datasources/uid/{uid}/pool admin_datasources adminGetDataSourceConnectionPool
+//
+// Get the connection pool of a SQL data source.
+//
+// Security:
+// - basic:
+//
+// Responses:
+// 200: adminGetDataSourceConnectionPoolResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (hs *HTTPServer) AdminGetDataSourceConnectionPool(c *contextmodel.ReqContext) response.Response {
+	ds, err := hs.getRawDataSourceByUID(c.Req.Context(), web.Params(c.Req)[":uid"], c.OrgID)
+	if err != nil {
+		if errors.Is(err, datasources.ErrDataSourceNotFound) {
+			return response.Error(http.StatusNotFound, "Data source not found", nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to query datasource", err)
+	}
+
+	stats, ok := sqleng.GetConnectionPoolStats(ds.ID)
+	if !ok {
+		return response.Error(http.StatusNotFound, "Data source has no connection pool", nil)
+	}
+	return response.JSON(http.StatusOK, stats)
+}
+
+// swagger:route POST /admin/datasources/uid/{uid}/pool/reset admin_datasources adminResetDataSourceConnectionPool
+//
+// Reset the connection pool of a SQL data source.
+//
+// Replaces the connection pool of the data source by a new one. The connections of the previous pool are closed
+// once the queries running with them are done.
+//
+// Security:
+// - basic:
+//
+// Responses:
+// 200: okResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (hs *HTTPServer) AdminResetDataSourceConnectionPool(c *contextmodel.ReqContext) response.Response {
+	ds, err := hs.getRawDataSourceByUID(c.Req.Context(), web.Params(c.Req)[":uid"], c.OrgID)
+	if err != nil {
+		if errors.Is(err, datasources.ErrDataSourceNotFound) {
+			return response.Error(http.StatusNotFound, "Data source not found", nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to query datasource", err)
+	}
+
+	ok, err := sqleng.ResetConnectionPool(ds.ID)
+	if !ok {
+		return response.Error(http.StatusNotFound, "Data source has no connection pool", nil)
+	}
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to reset the connection pool", err)
+	}
+	return response.Success("Connection pool reset")
+}
+
+// swagger:parameters adminGetDataSourceConnectionPool
+type AdminGetDataSourceConnectionPoolParams struct {
+	// in:path
+	// required:true
+	DatasourceUID string `json:"uid"`
+}
+
+// swagger:parameters adminResetDataSourceConnectionPool
+type AdminResetDataSourceConnectionPoolParams struct {
+	// in:path
+	// required:true
+	DatasourceUID string `json:"uid"`
+}
+
+// swagger:response adminListDataSourceConnectionPoolsResponse
+type AdminListDataSourceConnectionPoolsResponse struct {
+	// in:body
+	Body []sqleng.ConnectionPoolStats `json:"body"`
+}
+
+// swagger:response adminGetDataSourceConnectionPoolResponse
+type AdminGetDataSourceConnectionPoolResponse struct {
+	// in:body
+	Body sqleng.ConnectionPoolStats `json:"body"`
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/datasources"
+	fakeDatasources "github.com/grafana/grafana/pkg/services/datasources/fakes"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/web/webtest"
+)
+
+func TestAPI_AdminDataSourceConnectionPools(t *testing.T) {
+	server := SetupAPITestServer(t, func(hs *HTTPServer) {
+		hs.DataSourcesService = &fakeDatasources.FakeDataSourceService{
+			DataSources: []*datasources.DataSource{{ID: 1, UID: "mysql", OrgID: 1, Type: datasources.DS_MYSQL}},
+		}
+	})
+	admin := &user.SignedInUser{UserID: 1, OrgID: 1, OrgRole: org.RoleAdmin, IsGrafanaAdmin: true}
+	orgAdmin := &user.SignedInUser{UserID: 2, OrgID: 1, OrgRole: org.RoleAdmin}
+
+	tcs := []struct {
+		desc         string
+		req          *http.Request
+		user         *user.SignedInUser
+		expectedCode int
+	}{
+		{desc: "should list the connection pools", req: server.NewGetRequest("/api/admin/datasources/pools"), user: admin, expectedCode: http.StatusOK},
+		{desc: "should not find the pool of a data source not queried", req: server.NewGetRequest("/api/admin/datasources/uid/mysql/pool"), user: admin, expectedCode: http.StatusNotFound},
+		{desc: "should not find the pool of an unknown data source", req: server.NewGetRequest("/api/admin/datasources/uid/unknown/pool"), user: admin, expectedCode: http.StatusNotFound},
+		{desc: "should not reset the pool of a data source not queried", req: server.NewPostRequest("/api/admin/datasources/uid/mysql/pool/reset", nil), user: admin, expectedCode: http.StatusNotFound},
+		{desc: "should forbid the users who are not server admins to list the pools", req: server.NewGetRequest("/api/admin/datasources/pools"), user: orgAdmin, expectedCode: http.StatusForbidden},
+		{desc: "should forbid the users who are not server admins to reset a pool", req: server.NewPostRequest("/api/admin/datasources/uid/mysql/pool/reset", nil), user: orgAdmin, expectedCode: http.StatusForbidden},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.desc, func(t *testing.T) {
+			res, err := server.Send(webtest.RequestWithSignedInUser(tc.req, tc.user))
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedCode, res.StatusCode)
+			require.NoError(t, res.Body.Close())
+		})
+	}
+}
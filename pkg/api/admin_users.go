@@ -3,10 +3,12 @@ package api
 import (
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
 
+	"github.com/ua-parser/uap-go/uaparser"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/grafana/grafana/pkg/api/dtos"
@@ -449,6 +451,93 @@ func (hs *HTTPServer) AdminRevokeUserAuthToken(c *contextmodel.ReqContext) respo
 	return hs.revokeUserAuthTokenInternal(c, userID, cmd)
 }
 
+const (
+	defaultAuthTokenSearchLimit = 100
+	maxAuthTokenSearchLimit     = 1000
+)
+
+// swagger:route GET /admin/auth-tokens admin_users adminSearchUserAuthTokens
+//
+// Search the auth tokens (devices) of all users.
+//
+// Returns the active auth tokens of all users, most recently seen first. The tokens can be filtered by the IP address or network they were last used from, which helps to find the sessions to revoke during an incident.
+// If you are running Grafana Enterprise and have Fine-grained access control enabled, you need to have a permission with action `users.authtoken:list` and scope `global.users:*`.
+//
+// Security:
+// - basic:
+//
+// Responses:
+// 200: adminSearchUserAuthTokensResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) AdminSearchUserAuthTokens(c *contextmodel.ReqContext) response.Response {
+	query := &auth.SearchUserTokensQuery{Limit: c.QueryInt("limit")}
+	if query.Limit <= 0 {
+		query.Limit = defaultAuthTokenSearchLimit
+	}
+	if query.Limit > maxAuthTokenSearchLimit {
+		query.Limit = maxAuthTokenSearchLimit
+	}
+
+	if ip := c.Query("ip"); ip != "" {
+		network, err := parseIPNetwork(ip)
+		if err != nil {
+			return response.Error(http.StatusBadRequest, "ip must be an IP address or a CIDR network", err)
+		}
+		query.IPNetwork = network
+	}
+
+	tokens, err := hs.AuthTokenService.SearchUserTokens(c.Req.Context(), query)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to search user auth tokens", err)
+	}
+
+	parser := uaparser.NewFromSaved()
+	users := map[int64]*user.User{}
+	result := make([]*dtos.UserTokenSearchHit, 0, len(tokens))
+	for _, token := range tokens {
+		usr, ok := users[token.UserId]
+		if !ok {
+			usr, err = hs.userService.GetByID(c.Req.Context(), &user.GetUserByIDQuery{ID: token.UserId})
+			if err != nil && !errors.Is(err, user.ErrUserNotFound) {
+				return response.Error(http.StatusInternalServerError, "Failed to get user", err)
+			}
+			users[token.UserId] = usr
+		}
+
+		hit := &dtos.UserTokenSearchHit{
+			UserToken: *userTokenDTO(parser, token, c.UserToken != nil && c.UserToken.Id == token.Id),
+			UserId:    token.UserId,
+		}
+		if usr != nil {
+			hit.Login = usr.Login
+			hit.Email = usr.Email
+		}
+		result = append(result, hit)
+	}
+
+	return response.JSON(http.StatusOK, result)
+}
+
+// parseIPNetwork parses either a CIDR network or a single IP address
+func parseIPNetwork(value string) (*net.IPNet, error) {
+	if strings.Contains(value, "/") {
+		_, network, err := net.ParseCIDR(value)
+		return network, err
+	}
+
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address %q", value)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
 // swagger:parameters adminUpdateUserPassword
 type AdminUpdateUserPasswordParams struct {
 	// in:body
@@ -487,6 +576,19 @@ type AdminGetUserAuthTokensParams struct {
 	UserID int64 `json:"user_id"`
 }
 
+// swagger:parameters adminSearchUserAuthTokens
+type AdminSearchUserAuthTokensParams struct {
+	// IP address or CIDR network the tokens were last used from
+	// in:query
+	// required:false
+	IP string `json:"ip"`
+	// Maximum number of tokens returned
+	// in:query
+	// required:false
+	// default:100
+	Limit int `json:"limit"`
+}
+
 // swagger:parameters adminLogoutUser
 type AdminLogoutUserParams struct {
 	// in:path
@@ -532,3 +634,9 @@ type AdminGetUserAuthTokensResponse struct {
 	// in:body
 	Body []*auth.UserToken `json:"body"`
 }
+
+// swagger:response adminSearchUserAuthTokensResponse
+type AdminSearchUserAuthTokensResponse struct {
+	// in:body
+	Body []*dtos.UserTokenSearchHit `json:"body"`
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/grafana/grafana/pkg/kinds/dashboard"
 	"github.com/grafana/grafana/pkg/services/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/alerting"
+	"github.com/grafana/grafana/pkg/services/auditlog"
 	"github.com/grafana/grafana/pkg/services/auth/identity"
 	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
 	"github.com/grafana/grafana/pkg/services/dashboards"
@@ -502,6 +503,17 @@ func (hs *HTTPServer) postDashboard(c *contextmodel.ReqContext, cmd dashboards.S
 		return apierrors.ToDashboardErrorResponse(ctx, hs.pluginStore, err)
 	}
 
+	auditlog.SetResource(ctx, "dashboards", dashboard.UID)
+	var before any
+	if !newDashboard {
+		before = util.DynMap{"version": dash.Version}
+	}
+	auditlog.SetChange(ctx, before, util.DynMap{
+		"title":    dashboard.Title,
+		"version":  dashboard.Version,
+		"folderId": dashboard.FolderID,
+	})
+
 	// Clear permission cache for the user who's created the dashboard, so that new permissions are fetched for their next call
 	// Required for cases when caller wants to immediately interact with the newly created object
 	if newDashboard {
@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/grafana/grafana/pkg/api/dtos"
@@ -240,6 +242,137 @@ func (hs *HTTPServer) GetDashboardSnapshot(c *contextmodel.ReqContext) response.
 	return response.JSON(http.StatusOK, dto).SetHeader("Cache-Control", "public, max-age=3600")
 }
 
+// swagger:route GET /snapshots/{key}/export snapshots exportDashboardSnapshot
+//
+// Export a snapshot as a self-contained bundle.
+//
+// The bundle is a zip archive containing a manifest, the dashboard and one file per panel
+// with the snapshot data. It can be imported on any Grafana instance and kept after the
+// snapshot has expired.
+//
+// Produces:
+// - application/zip
+//
+// Responses:
+// 200: exportDashboardSnapshotResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 404: notFoundError
+// 500: internalServerError
+func (hs *HTTPServer) ExportDashboardSnapshot(c *contextmodel.ReqContext) response.Response {
+	if !hs.Cfg.SnapshotEnabled {
+		return response.Error(http.StatusForbidden, "Dashboard Snapshots are disabled", nil)
+	}
+
+	key := web.Params(c.Req)[":key"]
+	if len(key) == 0 {
+		return response.Error(http.StatusBadRequest, "Empty snapshot key", nil)
+	}
+
+	snapshot, err := hs.dashboardsnapshotsService.GetDashboardSnapshot(c.Req.Context(), &dashboardsnapshots.GetDashboardSnapshotQuery{Key: key})
+	if err != nil {
+		return response.Err(err)
+	}
+	if snapshot.Expires.Before(time.Now()) {
+		return response.Error(http.StatusNotFound, "Dashboard snapshot not found", nil)
+	}
+	if snapshot.External {
+		return response.Error(http.StatusBadRequest, "External snapshots cannot be exported", nil)
+	}
+
+	var buf bytes.Buffer
+	if err := dashboardsnapshots.WriteBundle(&buf, snapshot, setting.BuildVersion); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to export snapshot", err)
+	}
+
+	return response.Respond(http.StatusOK, buf.Bytes()).
+		SetHeader("Content-Type", "application/zip").
+		SetHeader("Content-Disposition", fmt.Sprintf(`attachment; filename="snapshot-%s.zip"`, snapshot.Key))
+}
+
+// swagger:route POST /snapshots/import snapshots importDashboardSnapshot
+//
+// Import a snapshot bundle.
+//
+// The bundle exported by the export endpoint is uploaded as the `bundle` field of a multipart
+// form. The optional `name` field overrides the snapshot name and the optional `expires` field
+// sets the expiry in seconds, the imported snapshot never expires by default.
+//
+// Consumes:
+// - multipart/form-data
+//
+// Responses:
+// 200: createDashboardSnapshotResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) ImportDashboardSnapshot(c *contextmodel.ReqContext) response.Response {
+	if !hs.Cfg.SnapshotEnabled {
+		return response.Error(http.StatusForbidden, "Dashboard Snapshots are disabled", nil)
+	}
+
+	maxSize := hs.Cfg.SnapshotMaxBundleSize
+	c.Req.Body = http.MaxBytesReader(c.Resp, c.Req.Body, maxSize)
+	if err := c.Req.ParseMultipartForm(maxSize); err != nil {
+		return response.Error(http.StatusBadRequest, fmt.Sprintf("Please limit the uploaded bundle to %s", util.ByteCountSI(maxSize)), err)
+	}
+	file, _, err := c.Req.FormFile("bundle")
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "Missing snapshot bundle", err)
+	}
+	defer func() { _ = file.Close() }()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "Failed to read snapshot bundle", err)
+	}
+
+	bundle, err := dashboardsnapshots.ReadBundle(data)
+	if err != nil {
+		return response.Err(err)
+	}
+
+	cmd := dashboardsnapshots.CreateDashboardSnapshotCommand{
+		Dashboard: bundle.Dashboard,
+		Name:      bundle.Manifest.Name,
+		OrgID:     c.OrgID,
+		UserID:    c.UserID,
+	}
+	if name := c.Req.FormValue("name"); name != "" {
+		cmd.Name = name
+	}
+	if cmd.Name == "" {
+		cmd.Name = "Imported snapshot"
+	}
+	if expires := c.Req.FormValue("expires"); expires != "" {
+		cmd.Expires, err = strconv.ParseInt(expires, 10, 64)
+		if err != nil || cmd.Expires < 0 {
+			return response.Error(http.StatusBadRequest, "Invalid expires value", err)
+		}
+	}
+	if cmd.Key, err = util.GetRandomString(32); err != nil {
+		return response.Error(http.StatusInternalServerError, "Could not generate random string", err)
+	}
+	if cmd.DeleteKey, err = util.GetRandomString(32); err != nil {
+		return response.Error(http.StatusInternalServerError, "Could not generate random string", err)
+	}
+
+	result, err := hs.dashboardsnapshotsService.CreateDashboardSnapshot(c.Req.Context(), &cmd)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to import snapshot", err)
+	}
+
+	metrics.MApiDashboardSnapshotCreate.Inc()
+
+	return response.JSON(http.StatusOK, util.DynMap{
+		"key":       cmd.Key,
+		"deleteKey": cmd.DeleteKey,
+		"url":       setting.ToAbsUrl("dashboard/snapshot/" + cmd.Key),
+		"deleteUrl": setting.ToAbsUrl("api/snapshots-delete/" + cmd.DeleteKey),
+		"id":        result.ID,
+	})
+}
+
 func deleteExternalDashboardSnapshot(externalUrl string) error {
 	resp, err := client.Get(externalUrl)
 	if err != nil {
@@ -469,6 +602,12 @@ type GetDashboardSnapshotParams struct {
 	Key string `json:"key"`
 }
 
+// swagger:parameters exportDashboardSnapshot
+type ExportDashboardSnapshotParams struct {
+	// in:path
+	Key string `json:"key"`
+}
+
 // swagger:parameters deleteDashboardSnapshot
 type DeleteDashboardSnapshotParams struct {
 	// in:path
@@ -496,6 +635,12 @@ type CreateSnapshotResponse struct {
 	} `json:"body"`
 }
 
+// swagger:response exportDashboardSnapshotResponse
+type ExportDashboardSnapshotResponse struct {
+	// in:body
+	Body []byte `json:"body"`
+}
+
 // swagger:response searchDashboardSnapshotsResponse
 type SearchDashboardSnapshotsResponse struct {
 	// in:body
@@ -1,8 +1,11 @@
 package api
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -408,3 +411,89 @@ func setUpSnapshotTest(t *testing.T, userId int64, deleteUrl string) dashboardsn
 	dashSnapSvc.On("DeleteDashboardSnapshot", mock.Anything, mock.AnythingOfType("*dashboardsnapshots.DeleteDashboardSnapshotCommand")).Return(nil).Maybe()
 	return dashSnapSvc
 }
+
+func TestHTTPServer_ExportImportDashboardSnapshot(t *testing.T) {
+	exportSvc := setUpSnapshotTest(t, 0, "")
+	importSvc := dashboardsnapshots.NewMockService(t)
+	var created *dashboardsnapshots.CreateDashboardSnapshotCommand
+	importSvc.On("CreateDashboardSnapshot", mock.Anything, mock.AnythingOfType("*dashboardsnapshots.CreateDashboardSnapshotCommand")).
+		Run(func(args mock.Arguments) {
+			created = args.Get(1).(*dashboardsnapshots.CreateDashboardSnapshotCommand)
+		}).
+		Return(&dashboardsnapshots.DashboardSnapshot{ID: 2}, nil)
+
+	setup := func(t *testing.T, svc dashboardsnapshots.Service) *webtest.Server {
+		t.Helper()
+		return SetupAPITestServer(t, func(hs *HTTPServer) {
+			cfg := setting.NewCfg()
+			cfg.SnapshotEnabled = true
+			cfg.SnapshotMaxBundleSize = 1 << 20
+			hs.Cfg = cfg
+			hs.dashboardsnapshotsService = svc
+		})
+	}
+	signedInUser := &user.SignedInUser{UserID: 1, OrgID: 1}
+
+	exportServer, importServer := setup(t, exportSvc), setup(t, importSvc)
+
+	res, err := exportServer.Send(webtest.RequestWithSignedInUser(
+		exportServer.NewRequest(http.MethodGet, "/api/snapshots/12345/export", nil), signedInUser))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "application/zip", res.Header.Get("Content-Type"))
+	bundle, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("bundle", "snapshot-12345.zip")
+	require.NoError(t, err)
+	_, err = part.Write(bundle)
+	require.NoError(t, err)
+	require.NoError(t, form.WriteField("name", "Evidence"))
+	require.NoError(t, form.Close())
+
+	req := importServer.NewRequest(http.MethodPost, "/api/snapshots/import", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	res, err = importServer.Send(webtest.RequestWithSignedInUser(req, signedInUser))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.NoError(t, res.Body.Close())
+
+	require.NotNil(t, created)
+	require.Equal(t, "Evidence", created.Name)
+	require.Equal(t, int64(1), created.OrgID)
+	require.NotEqual(t, "12345", created.Key)
+	require.Equal(t, int64(100), created.Dashboard.Get("id").MustInt64())
+
+	newImportRequest := func(t *testing.T, server *webtest.Server, bundle []byte) *http.Request {
+		t.Helper()
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, err := form.CreateFormFile("bundle", "snapshot.zip")
+		require.NoError(t, err)
+		_, err = part.Write(bundle)
+		require.NoError(t, err)
+		require.NoError(t, form.Close())
+		req := server.NewRequest(http.MethodPost, "/api/snapshots/import", &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		return req
+	}
+
+	t.Run("should reject the bundles larger than the configured maximum size", func(t *testing.T) {
+		res, err := importServer.Send(webtest.RequestWithSignedInUser(newImportRequest(t, importServer, make([]byte, 2<<20)), signedInUser))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+
+	t.Run("should require the users to be signed in when the snapshot public mode is disabled", func(t *testing.T) {
+		// the test server keeps the signed in user of the previous requests
+		server := setup(t, importSvc)
+		res, err := server.Send(newImportRequest(t, server, bundle))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+}
@@ -1162,6 +1162,16 @@ func (l *mockLibraryElementService) GetElement(c context.Context, signedInUser i
 	return model.LibraryElementDTO{}, nil
 }
 
+// GetAllElements gets a page of the elements the user can view.
+func (l *mockLibraryElementService) GetAllElements(c context.Context, signedInUser identity.Requester, query model.SearchLibraryElementsQuery) (model.LibraryElementSearchResult, error) {
+	return model.LibraryElementSearchResult{}, nil
+}
+
+// PatchElement updates an element.
+func (l *mockLibraryElementService) PatchElement(c context.Context, signedInUser *user.SignedInUser, cmd model.PatchLibraryElementCommand, uid string) (model.LibraryElementDTO, error) {
+	return model.LibraryElementDTO{}, nil
+}
+
 // GetElementsForDashboard gets all connected elements for a specific dashboard.
 func (l *mockLibraryElementService) GetElementsForDashboard(c context.Context, dashboardID int64) (map[string]model.LibraryElementDTO, error) {
 	return map[string]model.LibraryElementDTO{}, nil
@@ -17,6 +17,7 @@ import (
 	"github.com/grafana/grafana/pkg/api/response"
 	"github.com/grafana/grafana/pkg/components/simplejson"
 	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/auditlog"
 	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
 	"github.com/grafana/grafana/pkg/services/datasources"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/plugincontext"
@@ -536,6 +537,9 @@ func (hs *HTTPServer) updateDataSourceByID(c *contextmodel.ReqContext, ds *datas
 		return response.Error(500, "Failed to query datasource", err)
 	}
 
+	auditlog.SetResource(c.Req.Context(), "datasources", dataSource.UID)
+	auditlog.SetChange(c.Req.Context(), dataSourceAuditSummary(ds), dataSourceAuditSummary(dataSource))
+
 	datasourceDTO := hs.convertModelToDtos(c.Req.Context(), dataSource)
 
 	hs.Live.HandleDatasourceUpdate(c.OrgID, datasourceDTO.UID)
@@ -548,6 +552,109 @@ func (hs *HTTPServer) updateDataSourceByID(c *contextmodel.ReqContext, ds *datas
 	})
 }
 
+// dataSourceAuditSummary returns the settings of a data source recorded by the audit log, secrets are left out
+func dataSourceAuditSummary(ds *datasources.DataSource) util.DynMap {
+	return util.DynMap{
+		"name":      ds.Name,
+		"type":      ds.Type,
+		"url":       ds.URL,
+		"access":    ds.Access,
+		"isDefault": ds.IsDefault,
+		"database":  ds.Database,
+		"user":      ds.User,
+		"basicAuth": ds.BasicAuth,
+		"version":   ds.Version,
+	}
+}
+
+// swagger:route GET /datasources/uid/{uid}/credentials datasources getDataSourceCredentialsByUID
+//
+// Get the credentials status of a data source by UID.
+//
+// Returns the credential the requests to the data source are sent with, the secrets having a secondary value
+// while they are rotated and when each credential was last used.
+//
+// If you are running Grafana Enterprise and have Fine-grained access control enabled
+// you need to have a permission with action: `datasources:read` and scopes: `datasources:*`, `datasources:uid:*` and `datasources:uid:kLtEtcRGk` (single data source).
+//
+// Responses:
+// 200: getDataSourceCredentialsResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (hs *HTTPServer) GetDataSourceCredentialsByUID(c *contextmodel.ReqContext) response.Response {
+	ds, err := hs.getRawDataSourceByUID(c.Req.Context(), web.Params(c.Req)[":uid"], c.OrgID)
+	if err != nil {
+		if errors.Is(err, datasources.ErrDataSourceNotFound) {
+			return response.Error(http.StatusNotFound, "Data source not found", nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to query datasource", err)
+	}
+
+	status, err := hs.DataSourcesService.GetCredentialStatus(c.Req.Context(), ds)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get the datasource credentials", err)
+	}
+
+	return response.JSON(http.StatusOK, status)
+}
+
+// swagger:route POST /datasources/uid/{uid}/credentials/promote datasources promoteDataSourceSecondarySecretsByUID
+//
+// Promote the secondary secrets of a data source by UID.
+//
+// Replaces the secrets of the data source by their secondary value once the rotated secrets are in use, the
+// former values are removed.
+//
+// If you are running Grafana Enterprise and have Fine-grained access control enabled
+// you need to have a permission with action: `datasources:write` and scopes: `datasources:*`, `datasources:uid:*` and `datasources:uid:kLtEtcRGk` (single data source).
+//
+// Responses:
+// 200: createOrUpdateDatasourceResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 409: conflictError
+// 500: internalServerError
+func (hs *HTTPServer) PromoteDataSourceSecondarySecretsByUID(c *contextmodel.ReqContext) response.Response {
+	ds, err := hs.getRawDataSourceByUID(c.Req.Context(), web.Params(c.Req)[":uid"], c.OrgID)
+	if err != nil {
+		if errors.Is(err, datasources.ErrDataSourceNotFound) {
+			return response.Error(http.StatusNotFound, "Data source not found", nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to query datasource", err)
+	}
+	if ds.ReadOnly {
+		return response.Error(http.StatusForbidden, "Cannot update read-only data source", nil)
+	}
+
+	auditlog.SetResource(c.Req.Context(), "datasources", ds.UID)
+
+	dataSource, err := hs.DataSourcesService.PromoteSecondarySecrets(c.Req.Context(), &datasources.PromoteSecondarySecretsCommand{
+		OrgID: c.OrgID,
+		UID:   ds.UID,
+	})
+	if err != nil {
+		if errors.Is(err, datasources.ErrDataSourceUpdatingOldVersion) {
+			return response.Error(http.StatusConflict, "Datasource has already been updated by someone else. Please reload and try again", err)
+		}
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to promote the datasource secondary secrets", err)
+	}
+
+	datasourceDTO := hs.convertModelToDtos(c.Req.Context(), dataSource)
+
+	hs.Live.HandleDatasourceUpdate(c.OrgID, datasourceDTO.UID)
+
+	return response.JSON(http.StatusOK, util.DynMap{
+		"message":    "Datasource secondary secrets promoted",
+		"id":         dataSource.ID,
+		"name":       dataSource.Name,
+		"datasource": datasourceDTO,
+	})
+}
+
 func (hs *HTTPServer) getRawDataSourceById(ctx context.Context, id int64, orgID int64) (*datasources.DataSource, error) {
 	query := datasources.GetDataSourceQuery{
 		ID:    id,
@@ -918,6 +1025,20 @@ type GetDataSourceByUIDParams struct {
 	DatasourceUID string `json:"uid"`
 }
 
+// swagger:parameters getDataSourceCredentialsByUID
+type GetDataSourceCredentialsByUIDParams struct {
+	// in:path
+	// required:true
+	DatasourceUID string `json:"uid"`
+}
+
+// swagger:parameters promoteDataSourceSecondarySecretsByUID
+type PromoteDataSourceSecondarySecretsByUIDParams struct {
+	// in:path
+	// required:true
+	DatasourceUID string `json:"uid"`
+}
+
 // swagger:parameters getDataSourceByName
 type GetDataSourceByNameParams struct {
 	// in:path
@@ -1006,6 +1127,13 @@ type CreateOrUpdateDatasourceResponse struct {
 	} `json:"body"`
 }
 
+// swagger:response getDataSourceCredentialsResponse
+type GetDataSourceCredentialsResponse struct {
+	// The response message
+	// in: body
+	Body datasources.CredentialStatus `json:"body"`
+}
+
 // swagger:response getDataSourceIDResponse
 type GetDataSourceIDresponse struct {
 	// The response message
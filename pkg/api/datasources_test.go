@@ -326,6 +326,33 @@ func TestAPI_datasources_AccessControl(t *testing.T) {
 			permission:   []ac.Permission{},
 			expectedCode: http.StatusForbidden,
 		},
+		{
+			desc:   "should be able to fetch datasource credentials with correct permission",
+			urls:   []string{"/api/datasources/uid/1/credentials"},
+			method: http.MethodGet,
+			permission: []ac.Permission{
+				{Action: datasources.ActionRead, Scope: datasources.ScopeProvider.GetResourceScopeUID("1")},
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			desc:   "should not be able to promote datasource secondary secrets without write permission",
+			urls:   []string{"/api/datasources/uid/1/credentials/promote"},
+			method: http.MethodPost,
+			permission: []ac.Permission{
+				{Action: datasources.ActionRead, Scope: datasources.ScopeProvider.GetResourceScopeUID("1")},
+			},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			desc:   "should be able to promote datasource secondary secrets with correct permission",
+			urls:   []string{"/api/datasources/uid/1/credentials/promote"},
+			method: http.MethodPost,
+			permission: []ac.Permission{
+				{Action: datasources.ActionWrite, Scope: datasources.ScopeProvider.GetResourceScopeUID("1")},
+			},
+			expectedCode: http.StatusOK,
+		},
 		{
 			desc:         "should be able to create datasource with correct permission",
 			urls:         []string{"/api/datasources"},
@@ -391,7 +418,8 @@ type dataSourcesServiceMock struct {
 	expectedDatasource  *datasources.DataSource
 	expectedError       error
 
-	mockUpdateDataSource func(ctx context.Context, cmd *datasources.UpdateDataSourceCommand) (*datasources.DataSource, error)
+	mockUpdateDataSource        func(ctx context.Context, cmd *datasources.UpdateDataSourceCommand) (*datasources.DataSource, error)
+	mockPromoteSecondarySecrets func(ctx context.Context, cmd *datasources.PromoteSecondarySecretsCommand) (*datasources.DataSource, error)
 }
 
 func (m *dataSourcesServiceMock) GetDataSource(ctx context.Context, query *datasources.GetDataSourceQuery) (*datasources.DataSource, error) {
@@ -430,3 +458,50 @@ func (m *dataSourcesServiceMock) DecryptedValues(ctx context.Context, ds *dataso
 	decryptedValues := make(map[string]string)
 	return decryptedValues, m.expectedError
 }
+
+func (m *dataSourcesServiceMock) GetCredentialStatus(ctx context.Context, ds *datasources.DataSource) (*datasources.CredentialStatus, error) {
+	return &datasources.CredentialStatus{ActiveCredential: datasources.CredentialPrimary}, m.expectedError
+}
+
+func (m *dataSourcesServiceMock) PromoteSecondarySecrets(ctx context.Context, cmd *datasources.PromoteSecondarySecretsCommand) (*datasources.DataSource, error) {
+	if m.mockPromoteSecondarySecrets != nil {
+		return m.mockPromoteSecondarySecrets(ctx, cmd)
+	}
+
+	return m.expectedDatasource, m.expectedError
+}
+
+func TestPromoteDataSourceSecondarySecretsByUID(t *testing.T) {
+	permissions := []ac.Permission{{Action: datasources.ActionWrite, Scope: datasources.ScopeProvider.GetResourceScopeUID("1")}}
+
+	t.Run("should not promote the secondary secrets of a read-only datasource", func(t *testing.T) {
+		server := SetupAPITestServer(t, func(hs *HTTPServer) {
+			hs.Cfg = setting.NewCfg()
+			hs.DataSourcesService = &dataSourcesServiceMock{expectedDatasource: &datasources.DataSource{UID: "1", ReadOnly: true}}
+			hs.accesscontrolService = actest.FakeService{}
+		})
+
+		res, err := server.SendJSON(webtest.RequestWithSignedInUser(server.NewPostRequest("/api/datasources/uid/1/credentials/promote", nil), userWithPermissions(1, permissions)))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+
+	t.Run("should fail when the datasource has no secondary secrets", func(t *testing.T) {
+		server := SetupAPITestServer(t, func(hs *HTTPServer) {
+			hs.Cfg = setting.NewCfg()
+			hs.DataSourcesService = &dataSourcesServiceMock{
+				expectedDatasource: &datasources.DataSource{UID: "1"},
+				mockPromoteSecondarySecrets: func(ctx context.Context, cmd *datasources.PromoteSecondarySecretsCommand) (*datasources.DataSource, error) {
+					return nil, datasources.ErrDataSourceNoSecondarySecrets.Errorf("no secondary secrets")
+				},
+			}
+			hs.accesscontrolService = actest.FakeService{}
+		})
+
+		res, err := server.SendJSON(webtest.RequestWithSignedInUser(server.NewPostRequest("/api/datasources/uid/1/credentials/promote", nil), userWithPermissions(1, permissions)))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+}
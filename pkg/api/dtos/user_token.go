@@ -14,3 +14,11 @@ type UserToken struct {
 	CreatedAt              time.Time `json:"createdAt"`
 	SeenAt                 time.Time `json:"seenAt"`
 }
+
+// UserTokenSearchHit is a token returned by the admin search with the user it belongs to
+type UserTokenSearchHit struct {
+	UserToken
+	UserId int64  `json:"userId"`
+	Login  string `json:"login"`
+	Email  string `json:"email"`
+}
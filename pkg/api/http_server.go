@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"net"
@@ -50,6 +51,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/datasourceproxy"
 	"github.com/grafana/grafana/pkg/services/datasources"
 	"github.com/grafana/grafana/pkg/services/datasources/guardian"
+	"github.com/grafana/grafana/pkg/services/dependencygraph"
 	"github.com/grafana/grafana/pkg/services/encryption"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/folder"
@@ -66,6 +68,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/notifications"
 	"github.com/grafana/grafana/pkg/services/oauthtoken"
 	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/orgarchive"
 	"github.com/grafana/grafana/pkg/services/playlist"
 	"github.com/grafana/grafana/pkg/services/plugindashboards"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/plugincontext"
@@ -78,6 +81,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/queryhistory"
 	"github.com/grafana/grafana/pkg/services/quota"
 	"github.com/grafana/grafana/pkg/services/rendering"
+	"github.com/grafana/grafana/pkg/services/scim"
 	"github.com/grafana/grafana/pkg/services/search"
 	"github.com/grafana/grafana/pkg/services/searchV2"
 	"github.com/grafana/grafana/pkg/services/searchusers"
@@ -141,6 +145,9 @@ type HTTPServer struct {
 	ShortURLService              shorturls.Service
 	QueryHistoryService          queryhistory.Service
 	CorrelationsService          correlations.Service
+	DependencyGraphService       dependencygraph.Service
+	OrgArchiveService            orgarchive.Service
+	SCIMService                  scim.Service
 	Live                         *live.GrafanaLive
 	LivePushGateway              *pushhttp.Gateway
 	StorageService               store.StorageService
@@ -220,7 +227,9 @@ func ProvideHTTPServer(opts ServerOptions, cfg *setting.Cfg, routeRegister routi
 	pluginErrorResolver plugins.ErrorResolver, pluginInstaller plugins.Installer, settingsProvider setting.Provider,
 	dataSourceCache datasources.CacheService, userTokenService auth.UserTokenService,
 	cleanUpService *cleanup.CleanUpService, shortURLService shorturls.Service, queryHistoryService queryhistory.Service,
-	correlationsService correlations.Service, remoteCache *remotecache.RemoteCache, provisioningService provisioning.ProvisioningService,
+	correlationsService correlations.Service, dependencyGraphService dependencygraph.Service, orgArchiveService orgarchive.Service,
+	scimService scim.Service,
+	remoteCache *remotecache.RemoteCache, provisioningService provisioning.ProvisioningService,
 	accessControl accesscontrol.AccessControl, dataSourceProxy *datasourceproxy.DataSourceProxyService, searchService *search.SearchService,
 	live *live.GrafanaLive, livePushGateway *pushhttp.Gateway, plugCtxProvider *plugincontext.Provider,
 	contextHandler *contexthandler.ContextHandler, loggerMiddleware loggermw.Logger, features *featuremgmt.FeatureManager,
@@ -280,6 +289,9 @@ func ProvideHTTPServer(opts ServerOptions, cfg *setting.Cfg, routeRegister routi
 		ShortURLService:              shortURLService,
 		QueryHistoryService:          queryHistoryService,
 		CorrelationsService:          correlationsService,
+		DependencyGraphService:       dependencyGraphService,
+		OrgArchiveService:            orgArchiveService,
+		SCIMService:                  scimService,
 		Features:                     features,
 		StorageService:               storageService,
 		RemoteCacheService:           remoteCache,
@@ -515,6 +527,9 @@ func (hs *HTTPServer) configureHttps() error {
 		MinVersion:   minTlsVersion,
 		CipherSuites: tlsCiphers,
 	}
+	if err := hs.configureClientCertAuth(tlsCfg); err != nil {
+		return err
+	}
 
 	hs.httpSrv.TLSConfig = tlsCfg
 	hs.httpSrv.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
@@ -554,12 +569,38 @@ func (hs *HTTPServer) configureHttp2() error {
 		CipherSuites: tlsCiphers,
 		NextProtos:   []string{"h2", "http/1.1"},
 	}
+	if err := hs.configureClientCertAuth(tlsCfg); err != nil {
+		return err
+	}
 
 	hs.httpSrv.TLSConfig = tlsCfg
 
 	return nil
 }
 
+// configureClientCertAuth requests a client certificate issued by the configured authorities.
+// Connections without a certificate are still accepted so the other auth methods keep working.
+func (hs *HTTPServer) configureClientCertAuth(tlsCfg *tls.Config) error {
+	if !hs.Cfg.ClientCertAuthEnabled {
+		return nil
+	}
+
+	// nolint:gosec
+	// We can ignore the gosec G304 warning on this one because `ClientCertAuthCACertPath` comes from the configuration.
+	pem, err := os.ReadFile(hs.Cfg.ClientCertAuthCACertPath)
+	if err != nil {
+		return fmt.Errorf("cannot read client certificate authorities at %q: %w", hs.Cfg.ClientCertAuthCACertPath, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("no certificate found in %q", hs.Cfg.ClientCertAuthCACertPath)
+	}
+
+	tlsCfg.ClientCAs = pool
+	tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
+	return nil
+}
+
 func (hs *HTTPServer) applyRoutes() {
 	// start with middlewares & static routes
 	hs.addMiddlewaresAndStaticRoutes()
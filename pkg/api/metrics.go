@@ -11,10 +11,12 @@ import (
 	"github.com/grafana/grafana/pkg/api/dtos"
 	"github.com/grafana/grafana/pkg/api/response"
 	"github.com/grafana/grafana/pkg/middleware/requestmeta"
+	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
 	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
 	"github.com/grafana/grafana/pkg/services/datasources"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/plugincontext"
+	"github.com/grafana/grafana/pkg/services/query"
 	"github.com/grafana/grafana/pkg/web"
 )
 
@@ -45,6 +47,9 @@ func (hs *HTTPServer) handleQueryMetricsError(err error) *response.NormalRespons
 // If you are running Grafana Enterprise and have Fine-grained access control enabled
 // you need to have a permission with action: `datasources:query`.
 //
+// When `debug` is set, the requests sent upstream by the built-in data sources are returned along with the results
+// for the data sources the user has the `datasources:write` permission on.
+//
 // Responses:
 // 200: queryMetricsWithExpressionsRespons
 // 207: queryMetricsWithExpressionsRespons
@@ -58,6 +63,10 @@ func (hs *HTTPServer) QueryMetricsV2(c *contextmodel.ReqContext) response.Respon
 		return response.Error(http.StatusBadRequest, "bad request data", err)
 	}
 
+	if reqDTO.Debug {
+		return hs.queryMetricsDebug(c, reqDTO)
+	}
+
 	resp, err := hs.queryDataService.QueryData(c.Req.Context(), c.SignedInUser, c.SkipDSCache, reqDTO)
 	if err != nil {
 		return hs.handleQueryMetricsError(err)
@@ -65,7 +74,42 @@ func (hs *HTTPServer) QueryMetricsV2(c *contextmodel.ReqContext) response.Respon
 	return hs.toJsonStreamingResponse(c.Req.Context(), resp)
 }
 
+// queryDebugResponse is the response of POST /ds/query in debug mode
+type queryDebugResponse struct {
+	Results backend.Responses  `json:"results"`
+	Debug   []query.QueryDebug `json:"debug"`
+}
+
+// queryMetricsDebug runs the queries in debug mode and returns the requests sent upstream along with the results.
+// The requests reveal the data source configuration, they are only returned for the data sources the user can edit.
+func (hs *HTTPServer) queryMetricsDebug(c *contextmodel.ReqContext, reqDTO dtos.MetricRequest) response.Response {
+	ctx, collector := query.WithDebug(c.Req.Context())
+	resp, err := hs.queryDataService.QueryData(ctx, c.SignedInUser, c.SkipDSCache, reqDTO)
+	if err != nil {
+		return hs.handleQueryMetricsError(err)
+	}
+
+	debug := []query.QueryDebug{}
+	for _, q := range collector.Queries() {
+		evaluator := ac.EvalPermission(datasources.ActionWrite, datasources.ScopeProvider.GetResourceScopeUID(q.DatasourceUID))
+		canWrite, err := hs.AccessControl.Evaluate(c.Req.Context(), c.SignedInUser, evaluator)
+		if err != nil {
+			return response.Error(http.StatusInternalServerError, "Failed to evaluate permissions", err)
+		}
+		if canWrite {
+			debug = append(debug, q)
+		}
+	}
+
+	return response.JSONStreaming(hs.queryResponseStatus(c.Req.Context(), resp), queryDebugResponse{Results: resp.Responses, Debug: debug})
+}
+
 func (hs *HTTPServer) toJsonStreamingResponse(ctx context.Context, qdr *backend.QueryDataResponse) response.Response {
+	return response.JSONStreaming(hs.queryResponseStatus(ctx, qdr), qdr)
+}
+
+// queryResponseStatus returns the status code of a query response, an error status when a query failed
+func (hs *HTTPServer) queryResponseStatus(ctx context.Context, qdr *backend.QueryDataResponse) int {
 	statusWhenError := http.StatusBadRequest
 	if hs.Features.IsEnabled(featuremgmt.FlagDatasourceQueryMultiStatus) {
 		statusWhenError = http.StatusMultiStatus
@@ -83,7 +127,7 @@ func (hs *HTTPServer) toJsonStreamingResponse(ctx context.Context, qdr *backend.
 		requestmeta.WithDownstreamStatusSource(ctx)
 	}
 
-	return response.JSONStreaming(statusCode, qdr)
+	return statusCode
 }
 
 // swagger:parameters queryMetricsWithExpressions
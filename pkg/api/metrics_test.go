@@ -23,12 +23,14 @@ import (
 	"github.com/grafana/grafana/pkg/plugins/manager/registry"
 	"github.com/grafana/grafana/pkg/services/datasources"
 	fakeDatasources "github.com/grafana/grafana/pkg/services/datasources/fakes"
+	"github.com/grafana/grafana/pkg/services/datasources/guardian"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/plugincontext"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/pluginsettings"
 	pluginSettings "github.com/grafana/grafana/pkg/services/pluginsintegration/pluginsettings/service"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/pluginstore"
 	"github.com/grafana/grafana/pkg/services/query"
+	"github.com/grafana/grafana/pkg/services/query/inspect"
 	"github.com/grafana/grafana/pkg/services/quota/quotatest"
 	secretstest "github.com/grafana/grafana/pkg/services/secrets/fakes"
 	"github.com/grafana/grafana/pkg/services/user"
@@ -78,6 +80,7 @@ func TestAPIEndpoint_Metrics_QueryMetricsV2(t *testing.T) {
 		}, &fakeDatasources.FakeDataSourceService{}, pluginSettings.ProvideService(dbtest.NewFakeDB(),
 			secretstest.NewFakeSecretsService()),
 		),
+		guardian.ProvideGuardian(),
 	)
 	serverFeatureEnabled := SetupAPITestServer(t, func(hs *HTTPServer) {
 		hs.queryDataService = qds
@@ -109,6 +112,64 @@ func TestAPIEndpoint_Metrics_QueryMetricsV2(t *testing.T) {
 	})
 }
 
+func TestAPIEndpoint_Metrics_QueryMetricsV2Debug(t *testing.T) {
+	qds := query.ProvideService(
+		setting.NewCfg(),
+		nil,
+		nil,
+		&fakePluginRequestValidator{},
+		&fakePluginClient{
+			QueryDataHandlerFunc: func(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+				inspect.FromContext(ctx).AddRequest(inspect.Request{Method: http.MethodGet, URL: "http://upstream/query"})
+				return &backend.QueryDataResponse{Responses: backend.Responses{"A": backend.DataResponse{}}}, nil
+			},
+		},
+		plugincontext.ProvideService(localcache.ProvideService(), &pluginstore.FakePluginStore{
+			PluginList: []pluginstore.Plugin{{JSONData: plugins.JSONData{ID: "grafana"}}},
+		}, &fakeDatasources.FakeDataSourceService{}, pluginSettings.ProvideService(dbtest.NewFakeDB(),
+			secretstest.NewFakeSecretsService()),
+		),
+		guardian.ProvideGuardian(),
+	)
+	server := SetupAPITestServer(t, func(hs *HTTPServer) {
+		hs.queryDataService = qds
+		hs.QuotaService = quotatest.New(false, nil)
+	})
+
+	queryDebug := func(t *testing.T, permissions map[string][]string) map[string]json.RawMessage {
+		t.Helper()
+		req := server.NewPostRequest("/api/ds/query", strings.NewReader(`{"debug": true, "queries": [{"datasource": {"uid": "grafana"}, "refId": "A"}]}`))
+		webtest.RequestWithSignedInUser(req, &user.SignedInUser{UserID: 1, OrgID: 1, Permissions: map[int64]map[string][]string{1: permissions}})
+		resp, err := server.SendJSON(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		body := map[string]json.RawMessage{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		require.NoError(t, resp.Body.Close())
+		require.Contains(t, body, "results")
+		return body
+	}
+
+	t.Run("should return the upstream requests to the users who can edit the data source", func(t *testing.T) {
+		body := queryDebug(t, map[string][]string{
+			datasources.ActionQuery: {datasources.ScopeAll},
+			datasources.ActionWrite: {"datasources:uid:grafana"},
+		})
+
+		var debug []query.QueryDebug
+		require.NoError(t, json.Unmarshal(body["debug"], &debug))
+		require.Len(t, debug, 1)
+		require.Equal(t, "A", debug[0].RefID)
+		require.Equal(t, []inspect.Request{{Method: http.MethodGet, URL: "http://upstream/query"}}, debug[0].Requests)
+	})
+
+	t.Run("should not return the upstream requests to the users who cannot edit the data source", func(t *testing.T) {
+		body := queryDebug(t, map[string][]string{datasources.ActionQuery: {datasources.ScopeAll}})
+		require.JSONEq(t, `[]`, string(body["debug"]))
+	})
+}
+
 func TestAPIEndpoint_Metrics_PluginDecryptionFailure(t *testing.T) {
 	ds := &fakeDatasources.FakeDataSourceService{SimulatePluginFailure: true}
 	db := &dbtest.FakeDB{ExpectedError: pluginsettings.ErrPluginSettingNotFound}
@@ -140,6 +201,7 @@ func TestAPIEndpoint_Metrics_PluginDecryptionFailure(t *testing.T) {
 			},
 		},
 		pcp,
+		guardian.ProvideGuardian(),
 	)
 	httpServer := SetupAPITestServer(t, func(hs *HTTPServer) {
 		hs.queryDataService = qds
@@ -300,6 +362,7 @@ func TestDataSourceQueryError(t *testing.T) {
 						ds, pluginSettings.ProvideService(dbtest.NewFakeDB(),
 							secretstest.NewFakeSecretsService()),
 					),
+					guardian.ProvideGuardian(),
 				)
 				hs.QuotaService = quotatest.New(false, nil)
 			})
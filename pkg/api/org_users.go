@@ -10,6 +10,7 @@ import (
 	"github.com/grafana/grafana/pkg/api/dtos"
 	"github.com/grafana/grafana/pkg/api/response"
 	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/auditlog"
 	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/login"
@@ -424,6 +425,9 @@ func (hs *HTTPServer) updateOrgUserHelper(c *contextmodel.ReqContext, cmd org.Up
 		return response.Error(http.StatusInternalServerError, "Failed update org user", err)
 	}
 
+	auditlog.SetResource(c.Req.Context(), "users", strconv.FormatInt(cmd.UserID, 10))
+	auditlog.SetChange(c.Req.Context(), nil, util.DynMap{"orgId": cmd.OrgID, "role": cmd.Role})
+
 	hs.accesscontrolService.ClearUserPermissionCache(&user.SignedInUser{
 		UserID: cmd.UserID,
 		OrgID:  cmd.OrgID,
@@ -14,6 +14,7 @@ import (
 	"github.com/grafana/grafana/pkg/plugins/backendplugin"
 	"github.com/grafana/grafana/pkg/plugins/httpresponsesender"
 	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/datasourceproxy"
 	"github.com/grafana/grafana/pkg/services/datasources"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/plugincontext"
 	"github.com/grafana/grafana/pkg/util/proxyutil"
@@ -74,6 +75,11 @@ func (hs *HTTPServer) callPluginResourceWithDataSource(c *contextmodel.ReqContex
 		return
 	}
 
+	if err := datasourceproxy.ApplyResourceLabelPolicies(c, hs.dsGuardian, ds, web.Params(c.Req)["*"]); err != nil {
+		c.WriteErrOrFallback(http.StatusInternalServerError, "Failed to apply label policies", err)
+		return
+	}
+
 	req, err := hs.pluginResourceRequest(c)
 	if err != nil {
 		c.JsonApiErr(http.StatusBadRequest, "Failed for create plugin resource request", err)
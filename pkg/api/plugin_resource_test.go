@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"net/http"
+	"net/url"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -13,22 +15,29 @@ import (
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/stretchr/testify/require"
 
+	"github.com/grafana/grafana/pkg/components/simplejson"
 	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/db/dbtest"
 	"github.com/grafana/grafana/pkg/infra/localcache"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/plugins"
 	"github.com/grafana/grafana/pkg/plugins/backendplugin/coreplugin"
 	pluginClient "github.com/grafana/grafana/pkg/plugins/manager/client"
 	"github.com/grafana/grafana/pkg/plugins/manager/fakes"
 	"github.com/grafana/grafana/pkg/services/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/caching"
-	datasources "github.com/grafana/grafana/pkg/services/datasources/fakes"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	fakeDatasources "github.com/grafana/grafana/pkg/services/datasources/fakes"
+	"github.com/grafana/grafana/pkg/services/datasources/guardian"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/oauthtoken/oauthtokentest"
+	"github.com/grafana/grafana/pkg/services/org"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/pluginaccesscontrol"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/plugincontext"
 	pluginSettings "github.com/grafana/grafana/pkg/services/pluginsintegration/pluginsettings/service"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/pluginstore"
 	"github.com/grafana/grafana/pkg/services/quota/quotatest"
 	fakeSecrets "github.com/grafana/grafana/pkg/services/secrets/fakes"
 	"github.com/grafana/grafana/pkg/services/user"
@@ -55,7 +64,7 @@ func TestCallResource(t *testing.T) {
 
 	textCtx := pluginsintegration.CreateIntegrationTestCtx(t, cfg, coreRegistry)
 
-	pcp := plugincontext.ProvideService(localcache.ProvideService(), textCtx.PluginStore, &datasources.FakeDataSourceService{},
+	pcp := plugincontext.ProvideService(localcache.ProvideService(), textCtx.PluginStore, &fakeDatasources.FakeDataSourceService{},
 		pluginSettings.ProvideService(db.InitTestDB(t), fakeSecrets.NewFakeSecretsService()))
 
 	srv := SetupAPITestServer(t, func(hs *HTTPServer) {
@@ -94,7 +103,7 @@ func TestCallResource(t *testing.T) {
 			req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
 			return errors.New("something went wrong")
 		}),
-	}, pluginsintegration.CreateMiddlewares(cfg, &oauthtokentest.Service{}, tracing.InitializeTracerForTest(), &caching.OSSCachingService{}, &featuremgmt.FeatureManager{})...)
+	}, pluginsintegration.CreateMiddlewares(cfg, &oauthtokentest.Service{}, tracing.InitializeTracerForTest(), &caching.OSSCachingService{}, &featuremgmt.FeatureManager{}, &fakeDatasources.FakeDataSourceService{})...)
 	require.NoError(t, err)
 
 	srv = SetupAPITestServer(t, func(hs *HTTPServer) {
@@ -126,3 +135,80 @@ func TestCallResource(t *testing.T) {
 		require.Equal(t, 500, resp.StatusCode)
 	})
 }
+
+func TestCallDatasourceResourceWithLabelPolicies(t *testing.T) {
+	ds := &datasources.DataSource{ID: 1, UID: "prom", OrgID: 1, Type: datasources.DS_PROMETHEUS, JsonData: simplejson.NewFromAny(map[string]any{
+		"labelPolicies": []any{
+			map[string]any{"teams": []any{1}, "matchers": `namespace="team-a"`},
+		},
+	})}
+	pluginStore := &pluginstore.FakePluginStore{
+		PluginList: []pluginstore.Plugin{{JSONData: plugins.JSONData{ID: datasources.DS_PROMETHEUS}}},
+	}
+	client := &fakePluginClient{}
+	srv := SetupAPITestServer(t, func(hs *HTTPServer) {
+		hs.DataSourceCache = &fakeDatasources.FakeCacheService{DataSources: []*datasources.DataSource{ds}}
+		hs.pluginStore = pluginStore
+		hs.pluginContextProvider = plugincontext.ProvideService(localcache.ProvideService(), pluginStore,
+			&fakeDatasources.FakeDataSourceService{}, pluginSettings.ProvideService(dbtest.NewFakeDB(), fakeSecrets.NewFakeSecretsService()))
+		hs.PluginRequestValidator = &fakePluginRequestValidator{}
+		hs.pluginClient = client
+		hs.dsGuardian = guardian.ProvideGuardian()
+	})
+	restrictedUser := &user.SignedInUser{UserID: 2, OrgID: 1, OrgRole: org.RoleViewer, Teams: []int64{1}, Permissions: map[int64]map[string][]string{
+		1: {datasources.ActionQuery: []string{datasources.ScopeAll}},
+	}}
+
+	send := func(t *testing.T, req *http.Request) *http.Response {
+		t.Helper()
+		client.req = nil
+		webtest.RequestWithSignedInUser(req, restrictedUser)
+		resp, err := srv.Send(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		return resp
+	}
+	sentQuery := func(t *testing.T) url.Values {
+		t.Helper()
+		require.NotNil(t, client.req)
+		u, err := url.Parse(client.req.URL)
+		require.NoError(t, err)
+		return u.Query()
+	}
+
+	t.Run("should restrict the queries of a restricted user", func(t *testing.T) {
+		resp := send(t, srv.NewGetRequest("/api/datasources/uid/prom/resources/api/v1/query?query=up"))
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, `up{namespace="team-a"}`, sentQuery(t).Get("query"))
+	})
+
+	t.Run("should add the selector of the label policy to list the series", func(t *testing.T) {
+		resp := send(t, srv.NewGetRequest("/api/datasources/uid/prom/resources/api/v1/labels"))
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, `{namespace="team-a"}`, sentQuery(t).Get("match[]"))
+	})
+
+	t.Run("should restrict the queries of a form body", func(t *testing.T) {
+		req := srv.NewPostRequest("/api/datasources/uid/prom/resources/api/v1/query", strings.NewReader(url.Values{"query": {"up"}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp := send(t, req)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		form, err := url.ParseQuery(string(client.req.Body))
+		require.NoError(t, err)
+		require.Equal(t, `up{namespace="team-a"}`, form.Get("query"))
+	})
+
+	t.Run("should reject the multipart bodies sent as forms by the plugin", func(t *testing.T) {
+		req := srv.NewPostRequest("/api/datasources/uid/prom/resources/api/v1/query", strings.NewReader("--b\r\nContent-Disposition: form-data; name=\"x\"\r\n\r\n1&query=up\r\n--b--\r\n"))
+		req.Header.Set("Content-Type", "multipart/form-data; boundary=b")
+		resp := send(t, req)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		require.Nil(t, client.req)
+	})
+
+	t.Run("should deny the other resources to a restricted user", func(t *testing.T) {
+		resp := send(t, srv.NewGetRequest("/api/datasources/uid/prom/resources/api/v1/status/config"))
+		require.Equal(t, http.StatusForbidden, resp.StatusCode)
+		require.Nil(t, client.req)
+	})
+}
@@ -43,6 +43,7 @@ type DataSourceProxy struct {
 	oAuthTokenService  oauthtoken.OAuthTokenService
 	dataSourcesService datasources.DataSourceService
 	tracer             tracing.Tracer
+	maxBodySize        int64
 }
 
 type httpClient interface {
@@ -86,6 +87,11 @@ func (proxy *DataSourceProxy) HandleRequest() {
 		return
 	}
 
+	// the size of the bodies sent without content length is only known while they are proxied
+	if proxy.maxBodySize > 0 && proxy.ctx.Req.Body != nil {
+		proxy.ctx.Req.Body = http.MaxBytesReader(proxy.ctx.Resp, proxy.ctx.Req.Body, proxy.maxBodySize)
+	}
+
 	proxyErrorLogger := logger.New(
 		"userId", proxy.ctx.UserID,
 		"orgId", proxy.ctx.OrgID,
@@ -269,6 +275,10 @@ func (proxy *DataSourceProxy) validateRequest() error {
 		return errors.New("target URL is not a valid target")
 	}
 
+	if err := proxy.checkPolicy(); err != nil {
+		return err
+	}
+
 	if proxy.ds.Type == datasources.DS_ES {
 		if proxy.ctx.Req.Method == "DELETE" {
 			return errors.New("deletes not allowed on proxied Elasticsearch datasource")
@@ -319,6 +329,29 @@ func (proxy *DataSourceProxy) validateRequest() error {
 	return nil
 }
 
+// checkPolicy returns an error if the proxy policy of the data source denies the request
+func (proxy *DataSourceProxy) checkPolicy() error {
+	policy, err := GetProxyPolicy(proxy.ds)
+	if err == nil {
+		err = policy.check(proxy.ctx.Req.Method, proxy.proxyPath, proxy.ctx.Req.URL.Query(), proxy.ctx.Req.ContentLength)
+	}
+	if err != nil {
+		ctxLogger := logger.FromContext(proxy.ctx.Req.Context())
+		ctxLogger.Warn("Data source proxy request denied by policy",
+			"userId", proxy.ctx.UserID,
+			"orgId", proxy.ctx.OrgID,
+			"uname", proxy.ctx.Login,
+			"datasource", proxy.ds.UID,
+			"method", proxy.ctx.Req.Method,
+			"path", proxy.proxyPath,
+			"error", err)
+		return err
+	}
+
+	proxy.maxBodySize = policy.MaxBodySize
+	return nil
+}
+
 func (proxy *DataSourceProxy) logRequest() {
 	if !proxy.cfg.DataProxyLogging {
 		return
@@ -0,0 +1,179 @@
+package pluginproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/gobwas/glob"
+
+	"github.com/grafana/grafana/pkg/services/datasources"
+)
+
+// ProxyPolicy restricts the requests the data source proxy forwards to a data source. The policy is configured
+// in the data source JSON data and extends the default policy of the data source type:
+//
+//	"proxyPolicy": {
+//	  "allow": [{"path": "api/v1/**", "methods": ["GET", "POST"]}],
+//	  "deny": [{"path": "api/v1/status/**"}],
+//	  "maxBodySize": 1048576,
+//	  "queryParams": [{"name": "limit", "pattern": "^[0-9]{1,4}$"}, {"name": "nocache", "deny": true}]
+//	}
+//
+// When allow rules are configured, only the requests matching one of them are forwarded. The deny rules
+// apply to all the requests, including the ones matching an allow rule.
+type ProxyPolicy struct {
+	Allow []ProxyPolicyRule `json:"allow,omitempty"`
+	Deny  []ProxyPolicyRule `json:"deny,omitempty"`
+	// MaxBodySize is the maximum size in bytes of the body of the requests, no limit if 0
+	MaxBodySize int64 `json:"maxBodySize,omitempty"`
+	// QueryParams restrict the query parameters of the requests
+	QueryParams []QueryParamRule `json:"queryParams,omitempty"`
+}
+
+// ProxyPolicyRule matches the requests by path and method.
+type ProxyPolicyRule struct {
+	// Path is a glob pattern matched against the proxied path, * matches within a path segment
+	// and ** across path segments, ex: api/v1/admin/**
+	Path string `json:"path"`
+	// Methods are the HTTP methods the rule applies to, all the methods if empty
+	Methods []string `json:"methods,omitempty"`
+}
+
+// QueryParamRule restricts a query parameter of the requests.
+type QueryParamRule struct {
+	Name string `json:"name"`
+	// Deny denies the requests having the query parameter
+	Deny bool `json:"deny,omitempty"`
+	// Pattern is a regular expression every value of the query parameter must match
+	Pattern string `json:"pattern,omitempty"`
+}
+
+// defaultProxyPolicies deny the requests modifying the data of the data sources, whatever their policy
+var defaultProxyPolicies = map[string]ProxyPolicy{
+	datasources.DS_ES: {
+		Deny: []ProxyPolicyRule{
+			{Path: "**_delete_by_query"},
+			{Path: "**_update_by_query"},
+		},
+	},
+	datasources.DS_PROMETHEUS: {
+		Deny: []ProxyPolicyRule{
+			{Path: "api/v1/admin/**"},
+		},
+	},
+}
+
+// GetProxyPolicy returns the proxy policy of the data source merged with the default policy of its type
+func GetProxyPolicy(ds *datasources.DataSource) (ProxyPolicy, error) {
+	policy := ProxyPolicy{}
+	if ds.JsonData != nil {
+		if raw, ok := ds.JsonData.CheckGet("proxyPolicy"); ok {
+			data, err := raw.MarshalJSON()
+			if err != nil {
+				return policy, err
+			}
+			if err := json.Unmarshal(data, &policy); err != nil {
+				return policy, fmt.Errorf("invalid data source proxy policy: %w", err)
+			}
+		}
+	}
+
+	if defaults, ok := defaultProxyPolicies[ds.Type]; ok {
+		policy.Deny = append(append([]ProxyPolicyRule{}, defaults.Deny...), policy.Deny...)
+	}
+	return policy, nil
+}
+
+func (r ProxyPolicyRule) matches(method string, proxyPath string) (bool, error) {
+	if len(r.Methods) > 0 {
+		allowed := false
+		for _, m := range r.Methods {
+			if m == "*" || strings.EqualFold(m, method) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false, nil
+		}
+	}
+
+	g, err := glob.Compile(strings.TrimPrefix(r.Path, "/"), '/')
+	if err != nil {
+		return false, fmt.Errorf("invalid data source proxy policy path %q: %w", r.Path, err)
+	}
+	return g.Match(proxyPath), nil
+}
+
+// normalizeProxyPath returns the path the proxied request is sent to, relative to the data source URL. The
+// path is unescaped and cleaned so that the path patterns cannot be bypassed by encoded or relative segments.
+func normalizeProxyPath(proxyPath string) string {
+	if unescaped, err := url.PathUnescape(proxyPath); err == nil {
+		proxyPath = unescaped
+	}
+	return strings.TrimPrefix(path.Clean("/"+proxyPath), "/")
+}
+
+// check returns an error if the policy denies the request
+func (p ProxyPolicy) check(method string, proxyPath string, query url.Values, contentLength int64) error {
+	proxyPath = normalizeProxyPath(proxyPath)
+
+	for _, rule := range p.Deny {
+		matched, err := rule.matches(method, proxyPath)
+		if err != nil {
+			return err
+		}
+		if matched {
+			return fmt.Errorf("%s requests to %q are denied by the data source proxy policy", method, proxyPath)
+		}
+	}
+
+	if len(p.Allow) > 0 {
+		allowed := false
+		for _, rule := range p.Allow {
+			matched, err := rule.matches(method, proxyPath)
+			if err != nil {
+				return err
+			}
+			if matched {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("%s requests to %q are not allowed by the data source proxy policy", method, proxyPath)
+		}
+	}
+
+	if p.MaxBodySize > 0 && contentLength > p.MaxBodySize {
+		return fmt.Errorf("request body exceeds the maximum size of %d bytes allowed by the data source proxy policy", p.MaxBodySize)
+	}
+
+	for _, rule := range p.QueryParams {
+		values, ok := query[rule.Name]
+		if !ok {
+			continue
+		}
+		if rule.Deny {
+			return fmt.Errorf("query parameter %q is denied by the data source proxy policy", rule.Name)
+		}
+		if rule.Pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid data source proxy policy pattern for query parameter %q: %w", rule.Name, err)
+		}
+		for _, v := range values {
+			if !re.MatchString(v) {
+				return fmt.Errorf("value of query parameter %q is not allowed by the data source proxy policy", rule.Name)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package pluginproxy
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/infra/httpclient"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	fakeDatasources "github.com/grafana/grafana/pkg/services/datasources/fakes"
+	"github.com/grafana/grafana/pkg/services/oauthtoken"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+func TestProxyPolicy(t *testing.T) {
+	policy := ProxyPolicy{
+		Allow: []ProxyPolicyRule{
+			{Path: "api/v1/**", Methods: []string{http.MethodGet, http.MethodPost}},
+			{Path: "-/healthy"},
+		},
+		Deny: []ProxyPolicyRule{
+			{Path: "api/v1/status/*"},
+		},
+		MaxBodySize: 10,
+		QueryParams: []QueryParamRule{
+			{Name: "limit", Pattern: "^[0-9]{1,3}$"},
+			{Name: "nocache", Deny: true},
+		},
+	}
+
+	tcs := []struct {
+		desc          string
+		method        string
+		path          string
+		query         string
+		contentLength int64
+		err           string
+	}{
+		{desc: "allowed request", method: http.MethodGet, path: "api/v1/query", query: "query=up&limit=100"},
+		{desc: "allowed path for all methods", method: http.MethodDelete, path: "-/healthy"},
+		{desc: "method not allowed", method: http.MethodDelete, path: "api/v1/query", err: "not allowed"},
+		{desc: "path not allowed", method: http.MethodGet, path: "federate", err: "not allowed"},
+		{desc: "denied path", method: http.MethodGet, path: "api/v1/status/config", err: "denied"},
+		{desc: "denied path with relative segments", method: http.MethodGet, path: "api/v1/query/../status/config", err: "denied"},
+		{desc: "denied path with escaped segments", method: http.MethodGet, path: "api/v1/status%2Fconfig", err: "denied"},
+		{desc: "body too large", method: http.MethodPost, path: "api/v1/query", contentLength: 11, err: "maximum size"},
+		{desc: "denied query parameter", method: http.MethodGet, path: "api/v1/query", query: "nocache=true", err: "query parameter \"nocache\" is denied"},
+		{desc: "query parameter not matching its pattern", method: http.MethodGet, path: "api/v1/query", query: "limit=10000", err: "query parameter \"limit\" is not allowed"},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.desc, func(t *testing.T) {
+			query, err := url.ParseQuery(tc.query)
+			require.NoError(t, err)
+
+			err = policy.check(tc.method, tc.path, query, tc.contentLength)
+			if tc.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tc.err)
+		})
+	}
+
+	t.Run("invalid path pattern", func(t *testing.T) {
+		err := ProxyPolicy{Deny: []ProxyPolicyRule{{Path: "api/[v1"}}}.check(http.MethodGet, "api/v1", url.Values{}, 0)
+		require.ErrorContains(t, err, "invalid data source proxy policy path")
+	})
+}
+
+func TestGetProxyPolicy(t *testing.T) {
+	t.Run("should deny the Elasticsearch delete and update by query APIs by default", func(t *testing.T) {
+		policy, err := GetProxyPolicy(&datasources.DataSource{Type: datasources.DS_ES})
+		require.NoError(t, err)
+		require.Error(t, policy.check(http.MethodPost, "logs-*/_delete_by_query", url.Values{}, 0))
+		require.Error(t, policy.check(http.MethodPost, "_update_by_query", url.Values{}, 0))
+		require.NoError(t, policy.check(http.MethodPost, "_msearch", url.Values{}, 0))
+	})
+
+	t.Run("should deny the Prometheus admin APIs by default", func(t *testing.T) {
+		policy, err := GetProxyPolicy(&datasources.DataSource{
+			Type:     datasources.DS_PROMETHEUS,
+			JsonData: simplejson.NewFromAny(map[string]any{"proxyPolicy": map[string]any{"maxBodySize": 1024}}),
+		})
+		require.NoError(t, err)
+		require.Equal(t, int64(1024), policy.MaxBodySize)
+		require.Error(t, policy.check(http.MethodGet, "api/v1/admin/tsdb/snapshot", url.Values{}, 0))
+		require.NoError(t, policy.check(http.MethodGet, "api/v1/query", url.Values{}, 0))
+	})
+
+	t.Run("should fail when the policy is invalid", func(t *testing.T) {
+		_, err := GetProxyPolicy(&datasources.DataSource{
+			Type:     datasources.DS_PROMETHEUS,
+			JsonData: simplejson.NewFromAny(map[string]any{"proxyPolicy": "deny"}),
+		})
+		require.ErrorContains(t, err, "invalid data source proxy policy")
+	})
+}
+
+func TestDataSourceProxy_policy(t *testing.T) {
+	ds := &datasources.DataSource{
+		Type: "test",
+		URL:  "http://localhost:9090",
+		JsonData: simplejson.NewFromAny(map[string]any{
+			"proxyPolicy": map[string]any{
+				"allow":       []any{map[string]any{"path": "api/**", "methods": []string{http.MethodPost}}},
+				"maxBodySize": 4,
+			},
+		}),
+	}
+
+	newProxy := func(t *testing.T, method string, proxyPath string, body string) *DataSourceProxy {
+		req, err := http.NewRequest(method, "http://localhost/api/datasources/proxy/uid/test/"+proxyPath, strings.NewReader(body))
+		require.NoError(t, err)
+		ctx := &contextmodel.ReqContext{
+			Context:      &web.Context{Req: req},
+			SignedInUser: &user.SignedInUser{},
+		}
+		proxy, err := NewDataSourceProxy(ds, nil, ctx, proxyPath, &setting.Cfg{}, httpclient.NewProvider(), &oauthtoken.Service{}, &fakeDatasources.FakeDataSourceService{}, tracing.InitializeTracerForTest())
+		require.NoError(t, err)
+		return proxy
+	}
+
+	t.Run("should forward the requests allowed by the policy", func(t *testing.T) {
+		proxy := newProxy(t, http.MethodPost, "api/query", "up")
+		require.NoError(t, proxy.validateRequest())
+		require.Equal(t, int64(4), proxy.maxBodySize)
+	})
+
+	t.Run("should deny the requests not allowed by the policy", func(t *testing.T) {
+		require.Error(t, newProxy(t, http.MethodGet, "api/query", "").validateRequest())
+		require.Error(t, newProxy(t, http.MethodPost, "api/query", "sum(up)").validateRequest())
+	})
+}
@@ -76,6 +76,43 @@ func (hs *HTTPServer) RevokeUserAuthToken(c *contextmodel.ReqContext) response.R
 	return hs.revokeUserAuthTokenInternal(c, userID, cmd)
 }
 
+// swagger:route POST /user/revoke-other-auth-tokens signed_in_user revokeOtherUserAuthTokens
+//
+// Revoke the other auth tokens of the actual User.
+//
+// Revokes all the auth tokens (devices) of the actual user except the one used by the current session. Users of the revoked auth tokens (devices) will be required to authenticate again upon next activity.
+//
+// Responses:
+// 200: okResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) RevokeOtherUserAuthTokens(c *contextmodel.ReqContext) response.Response {
+	namespace, identifier := c.SignedInUser.GetNamespacedID()
+	if namespace != identity.NamespaceUser {
+		return response.Error(http.StatusForbidden, "entity not allowed to revoke tokens", nil)
+	}
+
+	userID, err := identity.IntIdentifier(namespace, identifier)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "failed to parse user id", err)
+	}
+
+	// without a session token, e.g. when authenticated with an API key, all the tokens would be revoked
+	if c.UserToken == nil {
+		return response.Error(http.StatusBadRequest, "No active user auth token", nil)
+	}
+
+	if err := hs.AuthTokenService.RevokeOtherUserTokens(c.Req.Context(), userID, c.UserToken.Id); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to revoke user auth tokens", err)
+	}
+
+	return response.JSON(http.StatusOK, util.DynMap{
+		"message": "Other user auth tokens revoked",
+	})
+}
+
 func (hs *HTTPServer) RotateUserAuthTokenRedirect(c *contextmodel.ReqContext) response.Response {
 	if err := hs.rotateToken(c); err != nil {
 		hs.log.FromContext(c.Req.Context()).Debug("Failed to rotate token", "error", err)
@@ -182,56 +219,57 @@ func (hs *HTTPServer) getUserAuthTokensInternal(c *contextmodel.ReqContext, user
 		return response.Error(500, "Failed to get user auth tokens", err)
 	}
 
-	result := []*dtos.UserToken{}
+	parser := uaparser.NewFromSaved()
+	result := make([]*dtos.UserToken, 0, len(tokens))
 	for _, token := range tokens {
-		isActive := false
-		if c.UserToken != nil && c.UserToken.Id == token.Id {
-			isActive = true
-		}
-
-		parser := uaparser.NewFromSaved()
-		client := parser.Parse(token.UserAgent)
+		isActive := c.UserToken != nil && c.UserToken.Id == token.Id
+		result = append(result, userTokenDTO(parser, token, isActive))
+	}
 
-		osVersion := ""
-		if client.Os.Major != "" {
-			osVersion = client.Os.Major
+	return response.JSON(http.StatusOK, result)
+}
 
-			if client.Os.Minor != "" {
-				osVersion = osVersion + "." + client.Os.Minor
-			}
-		}
+// userTokenDTO describes the device of the token from its user agent
+func userTokenDTO(parser *uaparser.Parser, token *auth.UserToken, isActive bool) *dtos.UserToken {
+	client := parser.Parse(token.UserAgent)
 
-		browserVersion := ""
-		if client.UserAgent.Major != "" {
-			browserVersion = client.UserAgent.Major
+	osVersion := ""
+	if client.Os.Major != "" {
+		osVersion = client.Os.Major
 
-			if client.UserAgent.Minor != "" {
-				browserVersion = browserVersion + "." + client.UserAgent.Minor
-			}
+		if client.Os.Minor != "" {
+			osVersion = osVersion + "." + client.Os.Minor
 		}
+	}
 
-		createdAt := time.Unix(token.CreatedAt, 0)
-		seenAt := time.Unix(token.SeenAt, 0)
+	browserVersion := ""
+	if client.UserAgent.Major != "" {
+		browserVersion = client.UserAgent.Major
 
-		if token.SeenAt == 0 {
-			seenAt = createdAt
+		if client.UserAgent.Minor != "" {
+			browserVersion = browserVersion + "." + client.UserAgent.Minor
 		}
+	}
+
+	createdAt := time.Unix(token.CreatedAt, 0)
+	seenAt := time.Unix(token.SeenAt, 0)
 
-		result = append(result, &dtos.UserToken{
-			Id:                     token.Id,
-			IsActive:               isActive,
-			ClientIp:               token.ClientIp,
-			Device:                 client.Device.ToString(),
-			OperatingSystem:        client.Os.Family,
-			OperatingSystemVersion: osVersion,
-			Browser:                client.UserAgent.Family,
-			BrowserVersion:         browserVersion,
-			CreatedAt:              createdAt,
-			SeenAt:                 seenAt,
-		})
+	if token.SeenAt == 0 {
+		seenAt = createdAt
 	}
 
-	return response.JSON(http.StatusOK, result)
+	return &dtos.UserToken{
+		Id:                     token.Id,
+		IsActive:               isActive,
+		ClientIp:               token.ClientIp,
+		Device:                 client.Device.ToString(),
+		OperatingSystem:        client.Os.Family,
+		OperatingSystemVersion: osVersion,
+		Browser:                client.UserAgent.Family,
+		BrowserVersion:         browserVersion,
+		CreatedAt:              createdAt,
+		SeenAt:                 seenAt,
+	}
 }
 
 func (hs *HTTPServer) revokeUserAuthTokenInternal(c *contextmodel.ReqContext, userID int64, cmd auth.RevokeAuthTokenCmd) response.Response {
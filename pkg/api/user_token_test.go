@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"testing"
@@ -10,9 +11,11 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/grafana/grafana/pkg/api/dtos"
 	"github.com/grafana/grafana/pkg/api/response"
 	"github.com/grafana/grafana/pkg/api/routing"
 	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/auth"
 	"github.com/grafana/grafana/pkg/services/auth/authtest"
 	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
@@ -21,6 +24,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/grafana/grafana/pkg/services/user/usertest"
 	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/web/webtest"
 )
 
 func TestUserTokenAPIEndpoint(t *testing.T) {
@@ -242,6 +246,102 @@ func TestHTTPServer_RotateUserAuthToken(t *testing.T) {
 	}
 }
 
+func TestHTTPServer_RevokeOtherUserAuthTokens(t *testing.T) {
+	var revokedUserID, keptTokenID int64
+	server := SetupAPITestServer(t, func(hs *HTTPServer) {
+		hs.AuthTokenService = &authtest.FakeUserAuthTokenService{
+			RevokeOtherUserTokensProvider: func(ctx context.Context, userID, currentTokenID int64) error {
+				revokedUserID, keptTokenID = userID, currentTokenID
+				return nil
+			},
+		}
+	})
+
+	t.Run("should revoke all the tokens except the current one", func(t *testing.T) {
+		req := server.NewPostRequest("/api/user/revoke-other-auth-tokens", nil)
+		webtest.RequestWithWebContext(req, &contextmodel.ReqContext{
+			SignedInUser: &user.SignedInUser{UserID: 2, OrgID: 1},
+			UserToken:    &auth.UserToken{Id: 5, UserId: 2},
+			IsSignedIn:   true,
+		})
+		res, err := server.Send(req)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.EqualValues(t, 2, revokedUserID)
+		assert.EqualValues(t, 5, keptTokenID)
+		require.NoError(t, res.Body.Close())
+	})
+
+	t.Run("should fail without a session token", func(t *testing.T) {
+		req := server.NewPostRequest("/api/user/revoke-other-auth-tokens", nil)
+		webtest.RequestWithSignedInUser(req, &user.SignedInUser{UserID: 2, OrgID: 1})
+		res, err := server.Send(req)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+}
+
+func TestHTTPServer_AdminSearchUserAuthTokens(t *testing.T) {
+	var query *auth.SearchUserTokensQuery
+	server := SetupAPITestServer(t, func(hs *HTTPServer) {
+		hs.userService = &usertest.FakeUserService{ExpectedUser: &user.User{ID: 3, Login: "jdoe", Email: "jdoe@example.org"}}
+		hs.AuthTokenService = &authtest.FakeUserAuthTokenService{
+			SearchUserTokensProvider: func(ctx context.Context, q *auth.SearchUserTokensQuery) ([]*auth.UserToken, error) {
+				query = q
+				return []*auth.UserToken{{
+					Id:        1,
+					UserId:    3,
+					ClientIp:  "192.168.1.20",
+					UserAgent: "Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/115.0",
+					CreatedAt: 1000,
+				}}, nil
+			},
+		}
+	})
+	admin := authedUserWithPermissions(1, 1, []accesscontrol.Permission{{Action: accesscontrol.ActionUsersAuthTokenList, Scope: accesscontrol.ScopeGlobalUsersAll}})
+
+	send := func(t *testing.T, url string, usr *user.SignedInUser) *http.Response {
+		req := webtest.RequestWithSignedInUser(server.NewGetRequest(url), usr)
+		res, err := server.Send(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { require.NoError(t, res.Body.Close()) })
+		return res
+	}
+
+	t.Run("should search the tokens of a network", func(t *testing.T) {
+		res := send(t, "/api/admin/auth-tokens?ip=192.168.1.0/24&limit=5000", admin)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "192.168.1.0/24", query.IPNetwork.String())
+		assert.Equal(t, maxAuthTokenSearchLimit, query.Limit)
+
+		var hits []dtos.UserTokenSearchHit
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&hits))
+		require.Len(t, hits, 1)
+		assert.Equal(t, "jdoe", hits[0].Login)
+		assert.Equal(t, "Firefox", hits[0].Browser)
+		assert.Equal(t, "Linux", hits[0].OperatingSystem)
+		assert.Equal(t, hits[0].CreatedAt, hits[0].SeenAt)
+	})
+
+	t.Run("should search the tokens of a single address", func(t *testing.T) {
+		res := send(t, "/api/admin/auth-tokens?ip=2001:db8::1", admin)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "2001:db8::1/128", query.IPNetwork.String())
+		assert.Equal(t, defaultAuthTokenSearchLimit, query.Limit)
+	})
+
+	t.Run("should reject an invalid address", func(t *testing.T) {
+		res := send(t, "/api/admin/auth-tokens?ip=192.168.1", admin)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("should require the permission to list the tokens of all users", func(t *testing.T) {
+		res := send(t, "/api/admin/auth-tokens", authedUserWithPermissions(1, 1, []accesscontrol.Permission{{Action: accesscontrol.ActionUsersAuthTokenList, Scope: "global.users:id:3"}}))
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+	})
+}
+
 func revokeUserAuthTokenScenario(t *testing.T, desc string, url string, routePattern string, cmd auth.RevokeAuthTokenCmd,
 	userId int64, fn scenarioFunc, userService user.Service) {
 	t.Run(fmt.Sprintf("%s %s", desc, url), func(t *testing.T) {
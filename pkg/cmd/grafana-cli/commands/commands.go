@@ -12,6 +12,7 @@ import (
 	"github.com/grafana/grafana/pkg/cmd/grafana-cli/utils"
 	"github.com/grafana/grafana/pkg/infra/db"
 	"github.com/grafana/grafana/pkg/server"
+	"github.com/grafana/grafana/pkg/services/orgarchive"
 	"github.com/grafana/grafana/pkg/setting"
 )
 
@@ -166,6 +167,58 @@ var adminCommands = []*cli.Command{
 			},
 		},
 	},
+	{
+		Name:  "org-archive",
+		Usage: "Exports and imports the folders, dashboards and library panels of an organization",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "export",
+				Usage:  "export <file>",
+				Action: runRunnerCommand(exportOrgArchiveCommand),
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:  "org-id",
+						Usage: "The organization to export",
+						Value: 1,
+					},
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "The archive format, zip or tar",
+						Value: string(orgarchive.FormatZip),
+					},
+					&cli.BoolFlag{
+						Name:  "permissions",
+						Usage: "Include folder and dashboard permissions",
+					},
+				},
+			},
+			{
+				Name:   "import",
+				Usage:  "import <file>",
+				Action: runRunnerCommand(importOrgArchiveCommand),
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:  "org-id",
+						Usage: "The organization to import into",
+						Value: 1,
+					},
+					&cli.StringFlag{
+						Name:  "conflict",
+						Usage: "What to do with entities whose UID already exists: skip, overwrite or rename",
+						Value: string(orgarchive.ConflictSkip),
+					},
+					&cli.StringSliceFlag{
+						Name:  "datasource-uid",
+						Usage: "Maps a datasource UID of the archive to a datasource UID of the organization, as <archive uid>=<target uid>. Can be repeated.",
+					},
+					&cli.BoolFlag{
+						Name:  "permissions",
+						Usage: "Import the folder and dashboard permissions of the archive",
+					},
+				},
+			},
+		},
+	},
 	{
 		Name:  "user-manager",
 		Usage: "Runs different helpful user commands",
@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/logger"
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/utils"
+	"github.com/grafana/grafana/pkg/server"
+	"github.com/grafana/grafana/pkg/services/orgarchive"
+)
+
+func exportOrgArchiveCommand(c utils.CommandLine, runner server.Runner) error {
+	output := c.Args().First()
+	if output == "" {
+		return fmt.Errorf("missing output file, usage: export <file>")
+	}
+
+	opts := orgarchive.ExportOptions{
+		Format:             orgarchive.Format(c.String("format")),
+		IncludePermissions: c.Bool("permissions"),
+	}
+	if !opts.Format.IsValid() {
+		return fmt.Errorf("%w: %s", orgarchive.ErrUnsupportedFormat, opts.Format)
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %w", output, err)
+	}
+
+	orgID := int64(c.Int("org-id"))
+	err = runner.OrgArchiveService.Export(context.Background(), orgarchive.BackgroundUser(orgID), opts, f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("Organization %d exported to %s %s\n", orgID, output, color.GreenString("✔"))
+	return nil
+}
+
+func importOrgArchiveCommand(c utils.CommandLine, runner server.Runner) error {
+	input := c.Args().First()
+	if input == "" {
+		return fmt.Errorf("missing archive file, usage: import <file>")
+	}
+	// nolint:gosec
+	data, err := os.ReadFile(input)
+	if err != nil {
+		return fmt.Errorf("could not read %s: %w", input, err)
+	}
+
+	opts := orgarchive.ImportOptions{
+		ConflictStrategy:     orgarchive.ConflictStrategy(c.String("conflict")),
+		ImportPermissions:    c.Bool("permissions"),
+		DatasourceUIDMapping: map[string]string{},
+	}
+	for _, m := range c.StringSlice("datasource-uid") {
+		from, to, ok := strings.Cut(m, "=")
+		if !ok || from == "" || to == "" {
+			return fmt.Errorf("invalid datasource mapping %q, expected <archive uid>=<target uid>", m)
+		}
+		opts.DatasourceUIDMapping[from] = to
+	}
+
+	orgID := int64(c.Int("org-id"))
+	result, err := runner.OrgArchiveService.Import(context.Background(), orgarchive.BackgroundUser(orgID), data, opts)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	report := func(kind string, entities []orgarchive.ImportedEntity) {
+		for _, e := range entities {
+			switch {
+			case e.Status == orgarchive.ImportStatusFailed:
+				failed++
+				logger.Infof("%s %s %q: %s %s\n", color.RedString("✘"), kind, e.Title, e.Status, e.Error)
+			case e.NewUID != "":
+				logger.Infof("%s %s %q: %s as %s\n", color.GreenString("✔"), kind, e.Title, e.Status, e.NewUID)
+			default:
+				logger.Infof("%s %s %q: %s\n", color.GreenString("✔"), kind, e.Title, e.Status)
+			}
+		}
+	}
+	report("folder", result.Folders)
+	report("library panel", result.LibraryElements)
+	report("dashboard", result.Dashboards)
+	for _, w := range result.Warnings {
+		logger.Infof("%s %s\n", color.YellowString("!"), w)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d entities could not be imported", failed)
+	}
+	logger.Infof("\nArchive imported into organization %d %s\n", orgID, color.GreenString("✔"))
+	return nil
+}
@@ -0,0 +1,222 @@
+package httpclientprovider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	sdkhttpclient "github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// CircuitBreakerMiddlewareName is the middleware name used by CircuitBreakerMiddleware.
+const CircuitBreakerMiddlewareName = "circuit-breaker"
+
+const (
+	circuitBreakerSettingsKey             = "httpCircuitBreaker"
+	defaultCircuitBreakerOpenDuration     = 30 * time.Second
+	defaultCircuitBreakerFailureThreshold = 5
+)
+
+// ErrCircuitOpen is returned without sending the request while the circuit breaker of the data source is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open: the data source is failing")
+
+var (
+	datasourceCircuitBreakerState = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "grafana",
+			Name:      "datasource_circuit_breaker_state",
+			Help:      "State of the circuit breaker of a data source: 0 closed, 1 half-open, 2 open",
+		},
+		[]string{"datasource", "datasource_type"},
+	)
+
+	datasourceCircuitBreakerRejected = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "grafana",
+			Name:      "datasource_circuit_breaker_rejected_total",
+			Help:      "A counter for outgoing data source requests rejected by an open circuit breaker",
+		},
+		[]string{"datasource", "datasource_type"},
+	)
+)
+
+// circuitBreakerSettings are configured in the data source JSON data:
+//
+//	"httpCircuitBreaker": {"enabled": true, "failureThreshold": 5, "openDuration": "30s"}
+type circuitBreakerSettings struct {
+	Enabled bool `json:"enabled"`
+	// FailureThreshold is the number of consecutive failures opening the circuit
+	FailureThreshold int `json:"failureThreshold"`
+	// OpenDuration is how long requests fail fast before a request is sent to probe the data source
+	OpenDuration string `json:"openDuration"`
+}
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitHalfOpen
+	circuitOpen
+)
+
+// CircuitBreakerMiddleware fails fast with ErrCircuitOpen once the requests to a data source fail repeatedly.
+// A request failing with a network error or a 5xx status is a failure. After the open duration a single request
+// probes the data source and closes the circuit when it succeeds. The state is shared by all the clients of a data source.
+func CircuitBreakerMiddleware() sdkhttpclient.Middleware {
+	return sdkhttpclient.NamedMiddlewareFunc(CircuitBreakerMiddlewareName, func(opts sdkhttpclient.Options, next http.RoundTripper) http.RoundTripper {
+		var settings circuitBreakerSettings
+		if !readDataSourceSettings(opts, circuitBreakerSettingsKey, &settings) || !settings.Enabled {
+			return next
+		}
+		config := circuitBreakerConfig{
+			failureThreshold: settings.FailureThreshold,
+			openDuration:     durationSetting(settings.OpenDuration, defaultCircuitBreakerOpenDuration),
+		}
+		if config.failureThreshold <= 0 {
+			config.failureThreshold = defaultCircuitBreakerFailureThreshold
+		}
+		labels := dataSourceMetricLabels(opts)
+		cb := circuitBreakers.get(circuitBreakerKey(opts), config, labels)
+
+		return sdkhttpclient.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if !cb.allow() {
+				if labels != nil {
+					datasourceCircuitBreakerRejected.With(labels).Inc()
+				}
+				return nil, ErrCircuitOpen
+			}
+
+			res, err := next.RoundTrip(req)
+			switch {
+			case err != nil && errors.Is(err, context.Canceled):
+				// the request was canceled by the caller, it says nothing about the data source
+				cb.release()
+			case err != nil || res.StatusCode >= http.StatusInternalServerError:
+				cb.failure()
+			default:
+				cb.success()
+			}
+			return res, err
+		})
+	})
+}
+
+type circuitBreakerConfig struct {
+	failureThreshold int
+	openDuration     time.Duration
+}
+
+type circuitBreaker struct {
+	config circuitBreakerConfig
+	labels prometheus.Labels
+	now    func() time.Time
+
+	mu       sync.Mutex
+	state    circuitState
+	failures int
+	openedAt time.Time
+	// probing is true while the request probing the data source in the half-open state is in flight
+	probing bool
+}
+
+func newCircuitBreaker(config circuitBreakerConfig, labels prometheus.Labels) *circuitBreaker {
+	cb := &circuitBreaker{config: config, labels: labels, now: time.Now}
+	cb.setState(circuitClosed)
+	return cb
+}
+
+// allow returns true if a request can be sent
+func (cb *circuitBreaker) allow() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case circuitOpen:
+		if cb.now().Sub(cb.openedAt) < cb.config.openDuration {
+			return false
+		}
+		cb.setState(circuitHalfOpen)
+	case circuitClosed:
+		return true
+	}
+
+	if cb.probing {
+		return false
+	}
+	cb.probing = true
+	return true
+}
+
+func (cb *circuitBreaker) success() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.failures = 0
+	cb.probing = false
+	if cb.state != circuitClosed {
+		cb.setState(circuitClosed)
+	}
+}
+
+func (cb *circuitBreaker) failure() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.failures++
+	cb.probing = false
+	if cb.state == circuitHalfOpen || cb.failures >= cb.config.failureThreshold {
+		cb.openedAt = cb.now()
+		cb.setState(circuitOpen)
+	}
+}
+
+// release ends a request without changing the state of the circuit
+func (cb *circuitBreaker) release() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.probing = false
+}
+
+// setState must be called with the lock held
+func (cb *circuitBreaker) setState(state circuitState) {
+	cb.state = state
+	if cb.labels != nil {
+		datasourceCircuitBreakerState.With(cb.labels).Set(float64(state))
+	}
+}
+
+// circuitBreakerKey identifies the data source of the client, the data source UID is only unique in an organization
+func circuitBreakerKey(opts sdkhttpclient.Options) string {
+	uid := opts.Labels["datasource_uid"]
+	if uid == "" {
+		return ""
+	}
+	return opts.Labels["datasource_org_id"] + "/" + uid
+}
+
+var circuitBreakers = &circuitBreakerRegistry{breakers: map[string]*circuitBreaker{}}
+
+// circuitBreakerRegistry shares the circuit breaker of a data source across its clients, ex: the data source
+// proxy and the backend of a core data source
+type circuitBreakerRegistry struct {
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+// get returns the circuit breaker of the data source, a new one when the settings of the data source changed
+func (r *circuitBreakerRegistry) get(key string, config circuitBreakerConfig, labels prometheus.Labels) *circuitBreaker {
+	if key == "" {
+		return newCircuitBreaker(config, labels)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cb, ok := r.breakers[key]; ok && cb.config == config {
+		return cb
+	}
+	cb := newCircuitBreaker(config, labels)
+	r.breakers[key] = cb
+	return cb
+}
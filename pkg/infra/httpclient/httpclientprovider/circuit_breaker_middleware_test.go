@@ -0,0 +1,118 @@
+package httpclientprovider
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreakerMiddleware(t *testing.T) {
+	settings := map[string]any{"httpCircuitBreaker": map[string]any{"enabled": true, "failureThreshold": 2, "openDuration": "1m"}}
+
+	roundTrip := func(t *testing.T, rt http.RoundTripper) (*http.Response, error) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, "http://test.com/query", nil)
+		require.NoError(t, err)
+		return rt.RoundTrip(req)
+	}
+
+	t.Run("should not be applied without settings", func(t *testing.T) {
+		next := &statusRoundTripper{statuses: []int{http.StatusOK}}
+		mw := CircuitBreakerMiddleware()
+		rt := mw.CreateMiddleware(dataSourceOptions("cb-disabled", map[string]any{}), next)
+		require.Equal(t, CircuitBreakerMiddlewareName, mw.(httpclient.MiddlewareName).MiddlewareName())
+		require.Same(t, next, rt)
+	})
+
+	t.Run("should open the circuit after consecutive failures and probe the data source after the open duration", func(t *testing.T) {
+		now := time.Now()
+		next := &statusRoundTripper{statuses: []int{http.StatusInternalServerError, 0, http.StatusBadGateway, http.StatusOK, http.StatusOK}}
+		rt := CircuitBreakerMiddleware().CreateMiddleware(dataSourceOptions("cb-open", settings), next)
+		circuitBreakers.breakers["1/cb-open"].now = func() time.Time { return now }
+
+		res, err := roundTrip(t, rt)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusInternalServerError, res.StatusCode)
+		_, err = roundTrip(t, rt)
+		require.Error(t, err)
+
+		_, err = roundTrip(t, rt)
+		require.ErrorIs(t, err, ErrCircuitOpen)
+		require.Equal(t, 2, next.calls)
+
+		// the probe fails and the circuit opens again
+		now = now.Add(time.Minute)
+		res, err = roundTrip(t, rt)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadGateway, res.StatusCode)
+		_, err = roundTrip(t, rt)
+		require.ErrorIs(t, err, ErrCircuitOpen)
+
+		// the probe succeeds and the circuit closes
+		now = now.Add(time.Minute)
+		res, err = roundTrip(t, rt)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		res, err = roundTrip(t, rt)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, 5, next.calls)
+	})
+
+	t.Run("should share the circuit between the clients of a data source", func(t *testing.T) {
+		next := &statusRoundTripper{statuses: []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable}}
+		proxy := CircuitBreakerMiddleware().CreateMiddleware(dataSourceOptions("cb-shared", settings), next)
+		backend := CircuitBreakerMiddleware().CreateMiddleware(dataSourceOptions("cb-shared", settings), next)
+
+		_, _ = roundTrip(t, proxy)
+		_, _ = roundTrip(t, proxy)
+		_, err := roundTrip(t, backend)
+		require.ErrorIs(t, err, ErrCircuitOpen)
+
+		// the circuit is reset when the settings change
+		next = &statusRoundTripper{statuses: []int{http.StatusOK}}
+		backend = CircuitBreakerMiddleware().CreateMiddleware(dataSourceOptions("cb-shared", map[string]any{
+			"httpCircuitBreaker": map[string]any{"enabled": true, "failureThreshold": 3},
+		}), next)
+		_, err = roundTrip(t, backend)
+		require.NoError(t, err)
+	})
+
+	t.Run("should not share the circuit between the data sources of different organizations", func(t *testing.T) {
+		next := &statusRoundTripper{statuses: []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK}}
+		failing := CircuitBreakerMiddleware().CreateMiddleware(dataSourceOptions("cb-orgs", settings), next)
+		otherOpts := dataSourceOptions("cb-orgs", settings)
+		otherOpts.Labels["datasource_org_id"] = "2"
+		other := CircuitBreakerMiddleware().CreateMiddleware(otherOpts, next)
+
+		_, _ = roundTrip(t, failing)
+		_, _ = roundTrip(t, failing)
+		_, err := roundTrip(t, failing)
+		require.ErrorIs(t, err, ErrCircuitOpen)
+
+		res, err := roundTrip(t, other)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("should not count the canceled requests", func(t *testing.T) {
+		cb := newCircuitBreaker(circuitBreakerConfig{failureThreshold: 1, openDuration: time.Minute}, nil)
+		cb.state = circuitHalfOpen
+		next := httpclient.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return nil, context.Canceled
+		})
+		circuitBreakers.breakers["1/cb-canceled"] = cb
+		rt := CircuitBreakerMiddleware().CreateMiddleware(dataSourceOptions("cb-canceled", map[string]any{
+			"httpCircuitBreaker": map[string]any{"enabled": true, "failureThreshold": 1, "openDuration": "1m"},
+		}), next)
+
+		_, err := roundTrip(t, rt)
+		require.ErrorIs(t, err, context.Canceled)
+		require.Equal(t, circuitHalfOpen, cb.state)
+		require.True(t, cb.allow())
+	})
+}
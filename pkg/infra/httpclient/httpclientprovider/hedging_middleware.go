@@ -0,0 +1,174 @@
+package httpclientprovider
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	sdkhttpclient "github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// HedgingMiddlewareName is the middleware name used by HedgingMiddleware.
+const HedgingMiddlewareName = "hedging"
+
+const (
+	hedgingSettingsKey        = "httpHedging"
+	defaultHedgingMaxRequests = 2
+)
+
+var datasourceRequestHedged = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "grafana",
+		Name:      "datasource_request_hedged_total",
+		Help:      "A counter for hedged outgoing data source requests sent while a previous attempt was still in flight",
+	},
+	[]string{"datasource", "datasource_type"},
+)
+
+// hedgingSettings are configured in the data source JSON data:
+//
+//	"httpHedging": {"delay": "500ms", "maxRequests": 2}
+type hedgingSettings struct {
+	// Delay is how long an attempt is waited for before sending the next one, requests are not hedged when empty
+	Delay string `json:"delay"`
+	// MaxRequests is the maximum number of requests sent in parallel, including the first one
+	MaxRequests int `json:"maxRequests"`
+}
+
+type hedgedResponse struct {
+	attempt int
+	res     *http.Response
+	err     error
+}
+
+// HedgingMiddleware reduces the tail latency of the idempotent data source requests by sending the request again
+// when no response is received after the delay. The first successful response is returned and the other
+// attempts are canceled.
+func HedgingMiddleware() sdkhttpclient.Middleware {
+	return sdkhttpclient.NamedMiddlewareFunc(HedgingMiddlewareName, func(opts sdkhttpclient.Options, next http.RoundTripper) http.RoundTripper {
+		var settings hedgingSettings
+		if !readDataSourceSettings(opts, hedgingSettingsKey, &settings) {
+			return next
+		}
+		delay := durationSetting(settings.Delay, 0)
+		maxRequests := settings.MaxRequests
+		if maxRequests == 0 {
+			maxRequests = defaultHedgingMaxRequests
+		}
+		if delay == 0 || maxRequests < 2 {
+			return next
+		}
+		labels := dataSourceMetricLabels(opts)
+
+		return sdkhttpclient.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if !isIdempotent(req) {
+				return next.RoundTrip(req)
+			}
+
+			responses := make(chan hedgedResponse, maxRequests)
+			cancels := make([]context.CancelFunc, 0, maxRequests)
+			send := func() error {
+				attempt := len(cancels)
+				attemptReq := req
+				if attempt > 0 {
+					var err error
+					if attemptReq, err = copyRequest(req); err != nil {
+						return err
+					}
+				}
+				ctx, cancel := context.WithCancel(req.Context())
+				cancels = append(cancels, cancel)
+				go func() {
+					res, err := next.RoundTrip(attemptReq.WithContext(ctx))
+					responses <- hedgedResponse{attempt: attempt, res: res, err: err}
+				}()
+				return nil
+			}
+
+			if err := send(); err != nil {
+				return nil, err
+			}
+			timer := time.NewTimer(delay)
+			defer timer.Stop()
+
+			var last hedgedResponse
+			for pending := 1; pending > 0; {
+				select {
+				case r := <-responses:
+					pending--
+					if r.err == nil && r.res.StatusCode < http.StatusInternalServerError {
+						return winningResponse(r, cancels, responses, pending), nil
+					}
+					if last.res != nil {
+						discardResponse(last.res)
+					}
+					last = r
+				case <-timer.C:
+					if len(cancels) >= maxRequests {
+						continue
+					}
+					if err := send(); err != nil {
+						continue
+					}
+					pending++
+					if labels != nil {
+						datasourceRequestHedged.With(labels).Inc()
+					}
+					timer.Reset(delay)
+				}
+			}
+
+			// all the attempts failed, the last failure is returned
+			if last.res == nil {
+				for _, cancel := range cancels {
+					cancel()
+				}
+				return nil, last.err
+			}
+			cancelOthers(cancels, last.attempt)
+			return withCancelOnClose(last.res, cancels[last.attempt]), nil
+		})
+	})
+}
+
+// winningResponse cancels the other attempts and discards their responses once they return
+func winningResponse(r hedgedResponse, cancels []context.CancelFunc, responses <-chan hedgedResponse, pending int) *http.Response {
+	cancelOthers(cancels, r.attempt)
+	go func() {
+		for ; pending > 0; pending-- {
+			discardResponse((<-responses).res)
+		}
+	}()
+	return withCancelOnClose(r.res, cancels[r.attempt])
+}
+
+func cancelOthers(cancels []context.CancelFunc, attempt int) {
+	for i, cancel := range cancels {
+		if i != attempt {
+			cancel()
+		}
+	}
+}
+
+// withCancelOnClose keeps the context of the attempt alive until the response body is closed
+func withCancelOnClose(res *http.Response, cancel context.CancelFunc) *http.Response {
+	if res.Body == nil {
+		res.Body = http.NoBody
+	}
+	res.Body = &cancelOnCloseBody{ReadCloser: res.Body, cancel: cancel}
+	return res
+}
+
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,90 @@
+package httpclientprovider
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHedgingMiddleware(t *testing.T) {
+	settings := map[string]any{"httpHedging": map[string]any{"delay": "10ms", "maxRequests": 2}}
+
+	// slowFirstRoundTripper blocks the first attempt until it is canceled and answers the next ones right away
+	type slowFirstRoundTripper struct {
+		calls    atomic.Int32
+		canceled atomic.Bool
+	}
+	newRoundTripper := func(rt *slowFirstRoundTripper) http.RoundTripper {
+		return httpclient.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if rt.calls.Add(1) == 1 {
+				<-req.Context().Done()
+				rt.canceled.Store(true)
+				return nil, req.Context().Err()
+			}
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				return nil, err
+			}
+			return &http.Response{StatusCode: http.StatusOK, Request: req, Body: io.NopCloser(strings.NewReader(string(body)))}, nil
+		})
+	}
+
+	t.Run("should not be applied without settings", func(t *testing.T) {
+		next := &statusRoundTripper{statuses: []int{http.StatusOK}}
+		mw := HedgingMiddleware()
+		rt := mw.CreateMiddleware(dataSourceOptions("ds", map[string]any{}), next)
+		require.Equal(t, HedgingMiddlewareName, mw.(httpclient.MiddlewareName).MiddlewareName())
+		require.Same(t, next, rt)
+	})
+
+	t.Run("should return the response of the hedged request and cancel the slow one", func(t *testing.T) {
+		next := &slowFirstRoundTripper{}
+		rt := HedgingMiddleware().CreateMiddleware(dataSourceOptions("ds", settings), newRoundTripper(next))
+
+		req, err := http.NewRequest(http.MethodPut, "http://test.com/query", strings.NewReader("body"))
+		require.NoError(t, err)
+		res, err := rt.RoundTrip(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, "body", string(body))
+		require.NoError(t, res.Body.Close())
+
+		require.Eventually(t, next.canceled.Load, time.Second, time.Millisecond)
+		require.Equal(t, int32(2), next.calls.Load())
+	})
+
+	t.Run("should not hedge the requests answered before the delay", func(t *testing.T) {
+		next := &statusRoundTripper{statuses: []int{http.StatusOK}}
+		rt := HedgingMiddleware().CreateMiddleware(dataSourceOptions("ds", settings), next)
+
+		req, err := http.NewRequest(http.MethodGet, "http://test.com/query", nil)
+		require.NoError(t, err)
+		res, err := rt.RoundTrip(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, 1, next.calls)
+	})
+
+	t.Run("should not hedge the requests which are not idempotent", func(t *testing.T) {
+		next := &slowFirstRoundTripper{}
+		next.calls.Store(1)
+		rt := HedgingMiddleware().CreateMiddleware(dataSourceOptions("ds", settings), newRoundTripper(next))
+
+		req, err := http.NewRequest(http.MethodPost, "http://test.com/query", strings.NewReader("body"))
+		require.NoError(t, err)
+		res, err := rt.RoundTrip(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, int32(2), next.calls.Load())
+	})
+}
@@ -29,6 +29,9 @@ func New(cfg *setting.Cfg, validator validations.PluginRequestValidator, tracer
 		sdkhttpclient.CustomHeadersMiddleware(),
 		ResponseLimitMiddleware(cfg.ResponseLimit),
 		RedirectLimitMiddleware(validator),
+		RetryMiddleware(),
+		HedgingMiddleware(),
+		CircuitBreakerMiddleware(),
 	}
 
 	if cfg.SigV4AuthEnabled {
@@ -39,6 +42,9 @@ func New(cfg *setting.Cfg, validator validations.PluginRequestValidator, tracer
 		middlewares = append(middlewares, HTTPLoggerMiddleware(cfg.PluginSettings))
 	}
 
+	// the upstream requests are recorded as they are sent, once all the other middlewares are applied
+	middlewares = append(middlewares, QueryInspectorMiddleware())
+
 	setDefaultTimeoutOptions(cfg)
 
 	return newProviderFunc(sdkhttpclient.ProviderOptions{
@@ -0,0 +1,182 @@
+package httpclientprovider
+
+import (
+	"bytes"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+
+	sdkhttpclient "github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
+
+	"github.com/grafana/grafana/pkg/services/query/inspect"
+)
+
+// QueryInspectorMiddlewareName is the middleware name used by QueryInspectorMiddleware.
+const QueryInspectorMiddlewareName = "query-inspector"
+
+// QueryInspectorMiddleware records the requests sent upstream, with the secrets redacted, when the query runs in
+// debug mode. It must be the last middleware so that the request is recorded as it is sent, ex: once signed.
+func QueryInspectorMiddleware() sdkhttpclient.Middleware {
+	return sdkhttpclient.NamedMiddlewareFunc(QueryInspectorMiddlewareName, func(opts sdkhttpclient.Options, next http.RoundTripper) http.RoundTripper {
+		secretHeaders := customHeaders(opts)
+		return sdkhttpclient.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			recorder := inspect.FromContext(req.Context())
+			if recorder == nil {
+				return next.RoundTrip(req)
+			}
+
+			recorded := inspect.Request{
+				Method:  req.Method,
+				URL:     inspect.RedactURL(req.URL),
+				Headers: inspect.RedactHeaders(req.Header, secretHeaders...),
+			}
+			if recorded.Method == "" {
+				recorded.Method = http.MethodGet
+			}
+
+			trace := &requestTrace{start: time.Now()}
+			traced := req.WithContext(httptrace.WithClientTrace(req.Context(), trace.clientTrace()))
+			body, truncated, err := peekBody(traced)
+			if err != nil {
+				return nil, err
+			}
+			recorded.Body, recorded.BodyTruncated = inspect.RedactBody(body, req.Header.Get("Content-Type")), truncated
+
+			res, err := next.RoundTrip(traced)
+			recorded.Timings = trace.timings()
+			if err != nil {
+				recorded.Error = err.Error()
+			} else {
+				recorded.StatusCode = res.StatusCode
+			}
+			recorder.AddRequest(recorded)
+			return res, err
+		})
+	})
+}
+
+// customHeaders returns the names of the custom headers of the data source, their values are secure settings
+func customHeaders(opts sdkhttpclient.Options) []string {
+	names := make([]string, 0, len(opts.Headers))
+	for name := range opts.Headers {
+		names = append(names, name)
+	}
+	grafanaData, _ := opts.CustomOptions["grafanaData"].(map[string]any)
+	for key, value := range grafanaData {
+		if name, ok := value.(string); ok && name != "" && strings.HasPrefix(key, "httpHeaderName") {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// peekBody returns the first inspect.MaxBodySize bytes of the body of the request, the body sent is left unchanged
+func peekBody(req *http.Request) (string, bool, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return "", false, nil
+	}
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return "", false, err
+		}
+		defer func() { _ = body.Close() }()
+		return readLimited(body)
+	}
+
+	// the body can only be read once, what is read is sent before the rest of the body
+	var buf bytes.Buffer
+	peeked, truncated, err := readLimited(io.TeeReader(req.Body, &buf))
+	if err != nil {
+		return "", false, err
+	}
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(&buf, req.Body), req.Body}
+	return peeked, truncated, nil
+}
+
+func readLimited(r io.Reader) (string, bool, error) {
+	data, err := io.ReadAll(io.LimitReader(r, inspect.MaxBodySize+1))
+	if err != nil {
+		return "", false, err
+	}
+	if len(data) > inspect.MaxBodySize {
+		return string(data[:inspect.MaxBodySize]), true, nil
+	}
+	return string(data), false, nil
+}
+
+// requestTrace measures the timing breakdown of a request, the trace hooks can be called from the dial goroutines
+type requestTrace struct {
+	start time.Time
+
+	mu                                    sync.Mutex
+	dnsStart, connectStart, tlsStart      time.Time
+	dns, connect, tlsHandshake, firstByte time.Duration
+	reused                                bool
+}
+
+func (t *requestTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.dns = time.Since(t.dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.connectStart = time.Now()
+		},
+		ConnectDone: func(string, string, error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.connect = time.Since(t.connectStart)
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.tlsHandshake = time.Since(t.tlsStart)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.reused = info.Reused
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.firstByte = time.Since(t.start)
+		},
+	}
+}
+
+func (t *requestTrace) timings() inspect.Timings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return inspect.Timings{
+		DNSMs:            inspect.Milliseconds(t.dns),
+		ConnectMs:        inspect.Milliseconds(t.connect),
+		TLSMs:            inspect.Milliseconds(t.tlsHandshake),
+		FirstByteMs:      inspect.Milliseconds(t.firstByte),
+		TotalMs:          inspect.Milliseconds(time.Since(t.start)),
+		ReusedConnection: t.reused,
+	}
+}
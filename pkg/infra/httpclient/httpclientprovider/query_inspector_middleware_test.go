@@ -0,0 +1,111 @@
+package httpclientprovider
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/query/inspect"
+)
+
+func TestQueryInspectorMiddleware(t *testing.T) {
+	t.Run("should not record the requests outside of debug mode", func(t *testing.T) {
+		next := &statusRoundTripper{statuses: []int{http.StatusOK}}
+		mw := QueryInspectorMiddleware()
+		rt := mw.CreateMiddleware(httpclient.Options{}, next)
+		require.Equal(t, QueryInspectorMiddlewareName, mw.(httpclient.MiddlewareName).MiddlewareName())
+
+		req, err := http.NewRequest(http.MethodGet, "http://test.com/query", nil)
+		require.NoError(t, err)
+		res, err := rt.RoundTrip(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("should record the requests with the secrets redacted", func(t *testing.T) {
+		next := &statusRoundTripper{statuses: []int{http.StatusOK}}
+		rt := QueryInspectorMiddleware().CreateMiddleware(httpclient.Options{}, next)
+
+		recorder := inspect.NewRecorder()
+		ctx := inspect.WithRecorder(context.Background(), recorder)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://test.com/api/v1/query?token=secret", strings.NewReader("query=up"))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		res, err := rt.RoundTrip(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, []string{"query=up"}, next.bodies)
+
+		requests := recorder.Requests()
+		require.Len(t, requests, 1)
+		require.Equal(t, http.MethodPost, requests[0].Method)
+		require.Equal(t, "http://test.com/api/v1/query?token=%5BREDACTED%5D", requests[0].URL)
+		require.Equal(t, "[REDACTED]", requests[0].Headers.Get("Authorization"))
+		require.Equal(t, "query=up", requests[0].Body)
+		require.Equal(t, http.StatusOK, requests[0].StatusCode)
+		require.Equal(t, "Bearer secret", req.Header.Get("Authorization"))
+	})
+
+	t.Run("should redact the custom headers of the data source", func(t *testing.T) {
+		next := &statusRoundTripper{statuses: []int{http.StatusOK}}
+		opts := dataSourceOptions("inspect", map[string]any{"httpHeaderName1": "X-Scope-OrgID"})
+		opts.Headers = map[string]string{"X-Scope-OrgID": "tenant"}
+		rt := QueryInspectorMiddleware().CreateMiddleware(opts, next)
+
+		recorder := inspect.NewRecorder()
+		ctx := inspect.WithRecorder(context.Background(), recorder)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://test.com/loki/api/v1/push", strings.NewReader("opaque"))
+		require.NoError(t, err)
+		req.Header.Set("X-Scope-OrgID", "tenant")
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		res, err := rt.RoundTrip(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, []string{"opaque"}, next.bodies)
+
+		requests := recorder.Requests()
+		require.Len(t, requests, 1)
+		require.Equal(t, "[REDACTED]", requests[0].Headers.Get("X-Scope-OrgID"))
+		require.Equal(t, "[REDACTED]", requests[0].Body)
+		require.Equal(t, "tenant", req.Header.Get("X-Scope-OrgID"))
+	})
+
+	t.Run("should record the failed requests", func(t *testing.T) {
+		next := &statusRoundTripper{statuses: []int{0}}
+		rt := QueryInspectorMiddleware().CreateMiddleware(httpclient.Options{}, next)
+
+		recorder := inspect.NewRecorder()
+		req, err := http.NewRequestWithContext(inspect.WithRecorder(context.Background(), recorder), http.MethodGet, "http://test.com/query", nil)
+		require.NoError(t, err)
+		_, err = rt.RoundTrip(req)
+		require.Error(t, err)
+
+		requests := recorder.Requests()
+		require.Len(t, requests, 1)
+		require.Equal(t, "connection refused", requests[0].Error)
+	})
+}
+
+func TestPeekBody(t *testing.T) {
+	t.Run("should leave the body which cannot be read again unchanged", func(t *testing.T) {
+		content := strings.Repeat("a", inspect.MaxBodySize+10)
+		req, err := http.NewRequest(http.MethodPost, "http://test.com/query", io.NopCloser(strings.NewReader(content)))
+		require.NoError(t, err)
+		require.Nil(t, req.GetBody)
+
+		body, truncated, err := peekBody(req)
+		require.NoError(t, err)
+		require.True(t, truncated)
+		require.Len(t, body, inspect.MaxBodySize)
+
+		sent, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		require.Equal(t, content, string(sent))
+	})
+}
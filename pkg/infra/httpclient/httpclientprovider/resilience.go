@@ -0,0 +1,90 @@
+package httpclientprovider
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	sdkhttpclient "github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/grafana/pkg/infra/metrics/metricutil"
+)
+
+// readDataSourceSettings reads the settings stored under key in the JSON data of the data source into v.
+// It returns false when the client is not a data source client or the settings are missing or invalid.
+func readDataSourceSettings(opts sdkhttpclient.Options, key string, v any) bool {
+	grafanaData, ok := opts.CustomOptions["grafanaData"].(map[string]any)
+	if !ok {
+		return false
+	}
+	raw, ok := grafanaData[key]
+	if !ok {
+		return false
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, v) == nil
+}
+
+// durationSetting parses a duration setting of the data source, ex: "500ms"
+func durationSetting(value string, defaultValue time.Duration) time.Duration {
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return d
+	}
+	return defaultValue
+}
+
+// dataSourceMetricLabels returns the labels of the data source metrics, nil when the client is not a data source
+// client or the data source name cannot be turned into a label
+func dataSourceMetricLabels(opts sdkhttpclient.Options) prometheus.Labels {
+	datasourceName, err := metricutil.SanitizeLabelName(opts.Labels["datasource_name"])
+	if err != nil {
+		return nil
+	}
+	datasourceType, err := metricutil.SanitizeLabelName(opts.Labels["datasource_type"])
+	if err != nil {
+		return nil
+	}
+	return prometheus.Labels{"datasource": datasourceName, "datasource_type": datasourceType}
+}
+
+// isIdempotent returns true if the request can be sent more than once, following the rules of http.Transport:
+// the method is idempotent or the request has an idempotency key, and the body can be sent again.
+func isIdempotent(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	_, hasKey := req.Header["Idempotency-Key"]
+	_, hasXKey := req.Header["X-Idempotency-Key"]
+	return hasKey || hasXKey
+}
+
+// copyRequest returns a copy of the request with a new body, so that it can be sent again
+func copyRequest(req *http.Request) (*http.Request, error) {
+	cp := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		cp.Body = body
+	}
+	return cp, nil
+}
+
+// discardResponse reads and closes the body of a response that is not returned, so that the connection can be reused
+func discardResponse(res *http.Response) {
+	if res == nil || res.Body == nil {
+		return
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+	_ = res.Body.Close()
+}
@@ -0,0 +1,139 @@
+package httpclientprovider
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	sdkhttpclient "github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// RetryMiddlewareName is the middleware name used by RetryMiddleware.
+const RetryMiddlewareName = "retry"
+
+const (
+	retrySettingsKey           = "httpRetry"
+	defaultRetryInitialBackoff = 100 * time.Millisecond
+	defaultRetryMaxBackoff     = 2 * time.Second
+)
+
+var datasourceRequestRetries = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "grafana",
+		Name:      "datasource_request_retries_total",
+		Help:      "A counter for outgoing data source requests sent again after a failure",
+	},
+	[]string{"datasource", "datasource_type"},
+)
+
+// retrySettings are configured in the data source JSON data:
+//
+//	"httpRetry": {"maxAttempts": 3, "initialBackoff": "100ms", "maxBackoff": "2s"}
+type retrySettings struct {
+	// MaxAttempts is the maximum number of times a request is sent, requests are not retried when lower than 2
+	MaxAttempts    int    `json:"maxAttempts"`
+	InitialBackoff string `json:"initialBackoff"`
+	MaxBackoff     string `json:"maxBackoff"`
+}
+
+// RetryMiddleware retries the idempotent data source requests failing with a network error or a 429, 502, 503 or
+// 504 status. The backoff between the attempts grows exponentially with jitter, a Retry-After header is honored
+// up to the maximum backoff.
+func RetryMiddleware() sdkhttpclient.Middleware {
+	return sdkhttpclient.NamedMiddlewareFunc(RetryMiddlewareName, func(opts sdkhttpclient.Options, next http.RoundTripper) http.RoundTripper {
+		var settings retrySettings
+		if !readDataSourceSettings(opts, retrySettingsKey, &settings) || settings.MaxAttempts < 2 {
+			return next
+		}
+		initialBackoff := durationSetting(settings.InitialBackoff, defaultRetryInitialBackoff)
+		maxBackoff := durationSetting(settings.MaxBackoff, defaultRetryMaxBackoff)
+		labels := dataSourceMetricLabels(opts)
+
+		return sdkhttpclient.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if !isIdempotent(req) {
+				return next.RoundTrip(req)
+			}
+
+			for attempt := 1; ; attempt++ {
+				res, err := next.RoundTrip(req)
+				if attempt >= settings.MaxAttempts || !shouldRetry(req.Context(), res, err) {
+					return res, err
+				}
+
+				wait := retryBackoff(initialBackoff, maxBackoff, attempt)
+				if after := retryAfter(res); after > 0 {
+					wait = after
+					if wait > maxBackoff {
+						wait = maxBackoff
+					}
+				}
+				discardResponse(res)
+
+				timer := time.NewTimer(wait)
+				select {
+				case <-timer.C:
+				case <-req.Context().Done():
+					timer.Stop()
+					return nil, req.Context().Err()
+				}
+
+				if req, err = copyRequest(req); err != nil {
+					return nil, err
+				}
+				if labels != nil {
+					datasourceRequestRetries.With(labels).Inc()
+				}
+			}
+		})
+	})
+}
+
+func shouldRetry(ctx context.Context, res *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, ErrCircuitOpen)
+	}
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryBackoff returns a random backoff between half and all of the exponential backoff of the attempt
+func retryBackoff(initial, max time.Duration, attempt int) time.Duration {
+	backoff := initial
+	for i := 1; i < attempt && backoff < max; i++ {
+		backoff *= 2
+	}
+	if backoff > max {
+		backoff = max
+	}
+	half := int64(backoff / 2)
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// retryAfter returns the delay of the Retry-After header of the response, in seconds or as an HTTP date
+func retryAfter(res *http.Response) time.Duration {
+	if res == nil {
+		return 0
+	}
+	value := res.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
@@ -0,0 +1,147 @@
+package httpclientprovider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
+	"github.com/stretchr/testify/require"
+)
+
+// dataSourceOptions returns the options of the client of a data source of the first organization with the JSON data
+func dataSourceOptions(uid string, jsonData map[string]any) httpclient.Options {
+	return httpclient.Options{
+		Labels:        map[string]string{"datasource_name": uid, "datasource_type": "prometheus", "datasource_uid": uid, "datasource_org_id": "1"},
+		CustomOptions: map[string]any{"grafanaData": jsonData},
+	}
+}
+
+// statusRoundTripper returns the statuses in order and records the request bodies
+type statusRoundTripper struct {
+	statuses []int
+	bodies   []string
+	calls    int
+}
+
+func (rt *statusRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		rt.bodies = append(rt.bodies, string(body))
+	}
+	status := rt.statuses[rt.calls]
+	rt.calls++
+	if status == 0 {
+		return nil, errors.New("connection refused")
+	}
+	return &http.Response{StatusCode: status, Request: req, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(""))}, nil
+}
+
+func TestRetryMiddleware(t *testing.T) {
+	retrySettings := map[string]any{"httpRetry": map[string]any{"maxAttempts": 3, "initialBackoff": "1ms", "maxBackoff": "2ms"}}
+
+	t.Run("should not retry without settings", func(t *testing.T) {
+		next := &statusRoundTripper{statuses: []int{http.StatusServiceUnavailable}}
+		mw := RetryMiddleware()
+		rt := mw.CreateMiddleware(dataSourceOptions("ds", map[string]any{}), next)
+		require.Equal(t, RetryMiddlewareName, mw.(httpclient.MiddlewareName).MiddlewareName())
+
+		req, err := http.NewRequest(http.MethodGet, "http://test.com/query", nil)
+		require.NoError(t, err)
+		res, err := rt.RoundTrip(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+		require.Equal(t, 1, next.calls)
+	})
+
+	t.Run("should retry the failed requests up to the max attempts", func(t *testing.T) {
+		next := &statusRoundTripper{statuses: []int{0, http.StatusBadGateway, http.StatusServiceUnavailable}}
+		rt := RetryMiddleware().CreateMiddleware(dataSourceOptions("ds", retrySettings), next)
+
+		req, err := http.NewRequest(http.MethodGet, "http://test.com/query", nil)
+		require.NoError(t, err)
+		res, err := rt.RoundTrip(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+		require.Equal(t, 3, next.calls)
+	})
+
+	t.Run("should stop retrying once the request succeeds", func(t *testing.T) {
+		next := &statusRoundTripper{statuses: []int{http.StatusGatewayTimeout, http.StatusOK}}
+		rt := RetryMiddleware().CreateMiddleware(dataSourceOptions("ds", retrySettings), next)
+
+		req, err := http.NewRequest(http.MethodPut, "http://test.com/query", strings.NewReader("body"))
+		require.NoError(t, err)
+		res, err := rt.RoundTrip(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, []string{"body", "body"}, next.bodies)
+	})
+
+	t.Run("should not retry the client errors", func(t *testing.T) {
+		next := &statusRoundTripper{statuses: []int{http.StatusBadRequest}}
+		rt := RetryMiddleware().CreateMiddleware(dataSourceOptions("ds", retrySettings), next)
+
+		req, err := http.NewRequest(http.MethodGet, "http://test.com/query", nil)
+		require.NoError(t, err)
+		res, err := rt.RoundTrip(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		require.Equal(t, 1, next.calls)
+	})
+
+	t.Run("should only retry the idempotent requests", func(t *testing.T) {
+		next := &statusRoundTripper{statuses: []int{http.StatusServiceUnavailable, http.StatusOK}}
+		rt := RetryMiddleware().CreateMiddleware(dataSourceOptions("ds", retrySettings), next)
+
+		req, err := http.NewRequest(http.MethodPost, "http://test.com/query", strings.NewReader("query"))
+		require.NoError(t, err)
+		res, err := rt.RoundTrip(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+		require.Equal(t, 1, next.calls)
+
+		req, err = http.NewRequest(http.MethodPost, "http://test.com/query", strings.NewReader("query"))
+		require.NoError(t, err)
+		req.Header.Set("Idempotency-Key", "abc")
+		res, err = rt.RoundTrip(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("should stop when the request is canceled", func(t *testing.T) {
+		next := &statusRoundTripper{statuses: []int{http.StatusServiceUnavailable, http.StatusOK}}
+		rt := RetryMiddleware().CreateMiddleware(dataSourceOptions("ds", map[string]any{
+			"httpRetry": map[string]any{"maxAttempts": 3, "initialBackoff": "1h", "maxBackoff": "1h"},
+		}), next)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://test.com/query", nil)
+		require.NoError(t, err)
+		_, err = rt.RoundTrip(req)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Equal(t, 1, next.calls)
+	})
+}
+
+func TestRetryBackoff(t *testing.T) {
+	for attempt, expected := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 400 * time.Millisecond, 10: time.Second} {
+		backoff := retryBackoff(100*time.Millisecond, time.Second, attempt)
+		require.GreaterOrEqual(t, backoff, expected/2)
+		require.LessOrEqual(t, backoff, expected)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	require.Equal(t, 2*time.Second, retryAfter(&http.Response{Header: http.Header{"Retry-After": []string{"2"}}}))
+	require.Equal(t, time.Duration(0), retryAfter(&http.Response{Header: http.Header{}}))
+	require.Equal(t, time.Duration(0), retryAfter(nil))
+}
@@ -30,6 +30,8 @@ type Service interface {
 	ClearUserPermissionCache(user identity.Requester)
 	// SearchUserPermissions returns single user's permissions filtered by an action prefix or an action
 	SearchUserPermissions(ctx context.Context, orgID int64, filterOptions SearchOptions) ([]Permission, error)
+	// GetUserPermissionSources returns single user's permissions filtered by an action along with the roles granting them
+	GetUserPermissionSources(ctx context.Context, orgID int64, filterOptions SearchOptions) ([]PermissionSource, error)
 	// DeleteUserPermissions removes all permissions user has in org and all permission to that user
 	// If orgID is set to 0 remove permissions from all orgs
 	DeleteUserPermissions(ctx context.Context, orgID, userID int64) error
@@ -63,3 +63,8 @@ func (a *AccessControl) Evaluate(ctx context.Context, user identity.Requester, e
 func (a *AccessControl) RegisterScopeAttributeResolver(prefix string, resolver accesscontrol.ScopeAttributeResolver) {
 	a.resolvers.AddScopeAttributeResolver(prefix, resolver)
 }
+
+// GetScopeAttributeMutator returns the function resolving the scope attributes of the organization during evaluation
+func (a *AccessControl) GetScopeAttributeMutator(orgID int64) accesscontrol.ScopeAttributeMutator {
+	return a.resolvers.GetScopeAttributeMutator(orgID)
+}
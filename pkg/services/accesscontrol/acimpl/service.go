@@ -74,6 +74,7 @@ type store interface {
 	GetUserPermissions(ctx context.Context, query accesscontrol.GetUserPermissionsQuery) ([]accesscontrol.Permission, error)
 	SearchUsersPermissions(ctx context.Context, orgID int64, options accesscontrol.SearchOptions) (map[int64][]accesscontrol.Permission, error)
 	GetUsersBasicRoles(ctx context.Context, userFilter []int64, orgID int64) (map[int64][]string, error)
+	GetUserPermissionSources(ctx context.Context, query accesscontrol.GetUserPermissionSourcesQuery) ([]accesscontrol.PermissionSource, error)
 	DeleteUserPermissions(ctx context.Context, orgID, userID int64) error
 	SaveExternalServiceRole(ctx context.Context, cmd accesscontrol.SaveExternalServiceRoleCommand) error
 	DeleteExternalServiceRole(ctx context.Context, externalServiceID string) error
@@ -133,7 +134,7 @@ func (s *Service) getUserPermissions(ctx context.Context, user identity.Requeste
 		UserID:       userID,
 		Roles:        accesscontrol.GetOrgRoles(user),
 		TeamIDs:      user.GetTeams(),
-		RolePrefixes: []string{accesscontrol.ManagedRolePrefix, accesscontrol.ExternalServiceRolePrefix},
+		RolePrefixes: []string{accesscontrol.ManagedRolePrefix, accesscontrol.ExternalServiceRolePrefix, accesscontrol.CustomRolePrefix},
 	})
 	if err != nil {
 		return nil, err
@@ -386,6 +387,60 @@ func (s *Service) searchUserPermissionsFromCache(orgID int64, searchOptions acce
 	return filteredPermissions, true
 }
 
+// GetUserPermissionSources returns the permissions of a user filtered by an action along with the roles granting them.
+// Fixed roles are attributed to the basic roles they are granted to, stored roles to their user, team or basic role assignment.
+func (s *Service) GetUserPermissionSources(ctx context.Context, orgID int64, searchOptions accesscontrol.SearchOptions) ([]accesscontrol.PermissionSource, error) {
+	if searchOptions.UserID == 0 {
+		return nil, fmt.Errorf("expected user ID to be specified")
+	}
+
+	roleList, err := s.store.GetUsersBasicRoles(ctx, []int64{searchOptions.UserID}, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch basic roles for the user: %w", err)
+	}
+	roles, ok := roleList[searchOptions.UserID]
+	if !ok {
+		return nil, accesscontrol.ErrUserNotInOrg
+	}
+
+	sources := make([]accesscontrol.PermissionSource, 0)
+	s.registrations.Range(func(registration accesscontrol.RoleRegistration) bool {
+		grantedTo := accesscontrol.BuiltInRolesWithParents(registration.Grants)
+		for _, basicRole := range roles {
+			if _, ok := grantedTo[basicRole]; !ok {
+				continue
+			}
+			for _, permission := range registration.Role.Permissions {
+				if !PermissionMatchesSearchOptions(permission, searchOptions) {
+					continue
+				}
+				sources = append(sources, accesscontrol.PermissionSource{
+					Action:    permission.Action,
+					Scope:     permission.Scope,
+					RoleName:  registration.Role.Name,
+					RoleUID:   registration.Role.UID,
+					Source:    accesscontrol.PermissionSourceBasicRole,
+					BasicRole: basicRole,
+				})
+			}
+		}
+		return true
+	})
+
+	dbSources, err := s.store.GetUserPermissionSources(ctx, accesscontrol.GetUserPermissionSourcesQuery{
+		OrgID:        orgID,
+		UserID:       searchOptions.UserID,
+		Roles:        roles,
+		Action:       searchOptions.Action,
+		RolePrefixes: []string{accesscontrol.ManagedRolePrefix, accesscontrol.ExternalServiceRolePrefix, accesscontrol.CustomRolePrefix},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return append(sources, dbSources...), nil
+}
+
 func PermissionMatchesSearchOptions(permission accesscontrol.Permission, searchOptions accesscontrol.SearchOptions) bool {
 	if searchOptions.Scope != "" && permission.Scope != searchOptions.Scope {
 		return false
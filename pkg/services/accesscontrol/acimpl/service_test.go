@@ -691,6 +691,56 @@ func TestService_SearchUserPermissions(t *testing.T) {
 	}
 }
 
+func TestService_GetUserPermissionSources(t *testing.T) {
+	ctx := context.Background()
+	fixedRole := accesscontrol.RoleDTO{
+		Name: "fixed:teams:reader",
+		UID:  "fixed_teams_reader",
+		Permissions: []accesscontrol.Permission{
+			{Action: accesscontrol.ActionTeamsRead, Scope: "teams:*"},
+			{Action: accesscontrol.ActionUsersCreate},
+		},
+	}
+	managedSource := accesscontrol.PermissionSource{
+		Action:   accesscontrol.ActionTeamsRead,
+		Scope:    "teams:id:1",
+		RoleName: "managed:users:2:permissions",
+		Source:   accesscontrol.PermissionSourceUser,
+	}
+
+	t.Run("should return permissions from fixed roles granted to basic roles and stored roles", func(t *testing.T) {
+		ac := setupTestEnv(t)
+		ac.registrations = accesscontrol.RegistrationList{}
+		ac.registrations.Append(accesscontrol.RoleRegistration{Role: fixedRole, Grants: []string{string(roletype.RoleViewer)}})
+		ac.store = actest.FakeStore{
+			ExpectedUsersRoles: map[int64][]string{2: {string(roletype.RoleEditor)}},
+			ExpectedSources:    []accesscontrol.PermissionSource{managedSource},
+		}
+
+		got, err := ac.GetUserPermissionSources(ctx, 1, accesscontrol.SearchOptions{UserID: 2, Action: accesscontrol.ActionTeamsRead})
+		require.NoError(t, err)
+		assert.Equal(t, []accesscontrol.PermissionSource{
+			{
+				Action:    accesscontrol.ActionTeamsRead,
+				Scope:     "teams:*",
+				RoleName:  fixedRole.Name,
+				RoleUID:   fixedRole.UID,
+				Source:    accesscontrol.PermissionSourceBasicRole,
+				BasicRole: string(roletype.RoleEditor),
+			},
+			managedSource,
+		}, got)
+	})
+
+	t.Run("should return error when user is not in the organization", func(t *testing.T) {
+		ac := setupTestEnv(t)
+		ac.store = actest.FakeStore{ExpectedUsersRoles: map[int64][]string{}}
+
+		_, err := ac.GetUserPermissionSources(ctx, 1, accesscontrol.SearchOptions{UserID: 2})
+		require.ErrorIs(t, err, accesscontrol.ErrUserNotInOrg)
+	})
+}
+
 func TestPermissionCacheKey(t *testing.T) {
 	testcases := []struct {
 		name         string
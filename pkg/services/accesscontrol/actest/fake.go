@@ -16,6 +16,7 @@ type FakeService struct {
 	ExpectedPermissions             []accesscontrol.Permission
 	ExpectedFilteredUserPermissions []accesscontrol.Permission
 	ExpectedUsersPermissions        map[int64][]accesscontrol.Permission
+	ExpectedPermissionSources       []accesscontrol.PermissionSource
 }
 
 func (f FakeService) GetUsageStats(ctx context.Context) map[string]any {
@@ -34,6 +35,10 @@ func (f FakeService) SearchUserPermissions(ctx context.Context, orgID int64, sea
 	return f.ExpectedFilteredUserPermissions, f.ExpectedErr
 }
 
+func (f FakeService) GetUserPermissionSources(ctx context.Context, orgID int64, searchOptions accesscontrol.SearchOptions) ([]accesscontrol.PermissionSource, error) {
+	return f.ExpectedPermissionSources, f.ExpectedErr
+}
+
 func (f FakeService) ClearUserPermissionCache(user identity.Requester) {}
 
 func (f FakeService) DeleteUserPermissions(ctx context.Context, orgID, userID int64) error {
@@ -74,6 +79,7 @@ type FakeStore struct {
 	ExpectedUserPermissions  []accesscontrol.Permission
 	ExpectedUsersPermissions map[int64][]accesscontrol.Permission
 	ExpectedUsersRoles       map[int64][]string
+	ExpectedSources          []accesscontrol.PermissionSource
 	ExpectedErr              error
 }
 
@@ -89,6 +95,10 @@ func (f FakeStore) GetUsersBasicRoles(ctx context.Context, userFilter []int64, o
 	return f.ExpectedUsersRoles, f.ExpectedErr
 }
 
+func (f FakeStore) GetUserPermissionSources(ctx context.Context, query accesscontrol.GetUserPermissionSourcesQuery) ([]accesscontrol.PermissionSource, error) {
+	return f.ExpectedSources, f.ExpectedErr
+}
+
 func (f FakeStore) DeleteUserPermissions(ctx context.Context, orgID, userID int64) error {
 	return f.ExpectedErr
 }
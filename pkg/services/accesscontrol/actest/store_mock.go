@@ -43,6 +43,32 @@ func (_m *MockStore) DeleteUserPermissions(ctx context.Context, orgID int64, use
 	return r0
 }
 
+// GetUserPermissionSources provides a mock function with given fields: ctx, query
+func (_m *MockStore) GetUserPermissionSources(ctx context.Context, query accesscontrol.GetUserPermissionSourcesQuery) ([]accesscontrol.PermissionSource, error) {
+	ret := _m.Called(ctx, query)
+
+	var r0 []accesscontrol.PermissionSource
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, accesscontrol.GetUserPermissionSourcesQuery) ([]accesscontrol.PermissionSource, error)); ok {
+		return rf(ctx, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, accesscontrol.GetUserPermissionSourcesQuery) []accesscontrol.PermissionSource); ok {
+		r0 = rf(ctx, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]accesscontrol.PermissionSource)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, accesscontrol.GetUserPermissionSourcesQuery) error); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetUserPermissions provides a mock function with given fields: ctx, query
 func (_m *MockStore) GetUserPermissions(ctx context.Context, query accesscontrol.GetUserPermissionsQuery) ([]accesscontrol.Permission, error) {
 	ret := _m.Called(ctx, query)
@@ -1,7 +1,9 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"testing"
 
@@ -10,6 +12,7 @@ import (
 	"github.com/grafana/grafana/pkg/api/routing"
 	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/accesscontrol/actest"
+	"github.com/grafana/grafana/pkg/services/auth/identity"
 	"github.com/grafana/grafana/pkg/services/datasources"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/user"
@@ -118,3 +121,134 @@ func TestAPI_getUserPermissions(t *testing.T) {
 		})
 	}
 }
+
+func TestAPI_explainUserPermission(t *testing.T) {
+	type testCase struct {
+		desc           string
+		url            string
+		permissions    []ac.Permission
+		sources        []ac.PermissionSource
+		sourcesErr     error
+		expectedCode   int
+		expectedOutput *explanationDTO
+	}
+
+	folderSource := ac.PermissionSource{Action: "dashboards:read", Scope: "folders:uid:folder1", RoleName: "managed:builtins:viewer:permissions", Source: ac.PermissionSourceBasicRole, BasicRole: "Viewer"}
+	dashboardSource := ac.PermissionSource{Action: "dashboards:read", Scope: "dashboards:uid:other", RoleName: "managed:users:2:permissions", Source: ac.PermissionSourceUser}
+	canExplain := []ac.Permission{{Action: ac.ActionUsersPermissionsRead, Scope: ac.ScopeUsersAll}}
+
+	tests := []testCase{
+		{
+			desc:         "should explain permission granted through scope resolution",
+			url:          "/api/access-control/users/2/explain?action=dashboards:read&scope=dashboards:uid:dashboard1",
+			permissions:  canExplain,
+			sources:      []ac.PermissionSource{folderSource, dashboardSource},
+			expectedCode: http.StatusOK,
+			expectedOutput: &explanationDTO{
+				Allowed:        true,
+				UserID:         2,
+				OrgID:          1,
+				Action:         "dashboards:read",
+				Scope:          "dashboards:uid:dashboard1",
+				ResolvedScopes: []string{"dashboards:uid:dashboard1", "folders:uid:folder1"},
+				Granting:       []ac.PermissionSource{folderSource},
+				Other:          []ac.PermissionSource{dashboardSource},
+			},
+		},
+		{
+			desc:         "should explain denied permission",
+			url:          "/api/access-control/users/2/explain?action=dashboards:read&scope=dashboards:uid:dashboard1",
+			permissions:  canExplain,
+			sources:      []ac.PermissionSource{dashboardSource},
+			expectedCode: http.StatusOK,
+			expectedOutput: &explanationDTO{
+				Allowed:        false,
+				UserID:         2,
+				OrgID:          1,
+				Action:         "dashboards:read",
+				Scope:          "dashboards:uid:dashboard1",
+				ResolvedScopes: []string{"dashboards:uid:dashboard1", "folders:uid:folder1"},
+				Granting:       []ac.PermissionSource{},
+				Other:          []ac.PermissionSource{dashboardSource},
+			},
+		},
+		{
+			desc:         "should return bad request without action",
+			url:          "/api/access-control/users/2/explain?scope=dashboards:uid:dashboard1",
+			permissions:  canExplain,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			desc:         "should return not found for user outside of the organization",
+			url:          "/api/access-control/users/2/explain?action=dashboards:read",
+			permissions:  canExplain,
+			sourcesErr:   ac.ErrUserNotInOrg,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			desc:         "should return forbidden without permission to read user permissions",
+			url:          "/api/access-control/users/2/explain?action=dashboards:read",
+			permissions:  []ac.Permission{{Action: ac.ActionUsersPermissionsRead, Scope: "users:id:3"}},
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			acSvc := actest.FakeService{ExpectedPermissionSources: tt.sources, ExpectedErr: tt.sourcesErr}
+			accessControl := resolvingAccessControl{resolutions: map[string][]string{
+				"dashboards:uid:dashboard1": {"dashboards:uid:dashboard1", "folders:uid:folder1"},
+			}}
+			api := NewAccessControlAPI(routing.NewRouteRegister(), accessControl, acSvc, featuremgmt.WithFeatures())
+			api.RegisterAPIEndpoints()
+
+			server := webtest.NewServer(t, api.RouteRegister)
+			req := server.NewGetRequest(tt.url)
+			webtest.RequestWithSignedInUser(req, &user.SignedInUser{
+				OrgID:       1,
+				Permissions: map[int64]map[string][]string{1: ac.GroupScopesByAction(tt.permissions)},
+			})
+			res, err := server.Send(req)
+			require.NoError(t, err)
+			defer func() { require.NoError(t, res.Body.Close()) }()
+			require.Equal(t, tt.expectedCode, res.StatusCode)
+
+			if tt.expectedOutput != nil {
+				var output explanationDTO
+				require.NoError(t, json.NewDecoder(res.Body).Decode(&output))
+				require.Equal(t, *tt.expectedOutput, output)
+			}
+		})
+	}
+}
+
+// resolvingAccessControl evaluates permissions like the access control service using static scope resolutions
+type resolvingAccessControl struct {
+	resolutions map[string][]string
+}
+
+func (r resolvingAccessControl) Evaluate(ctx context.Context, usr identity.Requester, evaluator ac.Evaluator) (bool, error) {
+	if evaluator.Evaluate(usr.GetPermissions()) {
+		return true, nil
+	}
+	resolved, err := evaluator.MutateScopes(ctx, r.GetScopeAttributeMutator(usr.GetOrgID()))
+	if err != nil {
+		if errors.Is(err, ac.ErrResolverNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return resolved.Evaluate(usr.GetPermissions()), nil
+}
+
+func (r resolvingAccessControl) RegisterScopeAttributeResolver(prefix string, resolver ac.ScopeAttributeResolver) {
+}
+
+func (r resolvingAccessControl) GetScopeAttributeMutator(orgID int64) ac.ScopeAttributeMutator {
+	return func(ctx context.Context, scope string) ([]string, error) {
+		if scopes, ok := r.resolutions[scope]; ok {
+			return scopes, nil
+		}
+		return nil, ac.ErrResolverNotFound
+	}
+}
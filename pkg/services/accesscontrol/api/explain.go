@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+// scopeMutatorProvider is implemented by access control implementations exposing the scope attribute
+// resolution they use during evaluation, e.g. resolving a dashboard to its parent folders
+type scopeMutatorProvider interface {
+	GetScopeAttributeMutator(orgID int64) ac.ScopeAttributeMutator
+}
+
+type explanationDTO struct {
+	Allowed bool   `json:"allowed"`
+	UserID  int64  `json:"userId"`
+	OrgID   int64  `json:"orgId"`
+	Action  string `json:"action"`
+	Scope   string `json:"scope,omitempty"`
+	// ResolvedScopes are the scopes the requested scope resolves to, any of them grants the action
+	ResolvedScopes []string `json:"resolvedScopes,omitempty"`
+	// Granting are the user's permissions granting the action on the scope
+	Granting []ac.PermissionSource `json:"granting"`
+	// Other are the user's permissions for the action that do not cover the scope
+	Other []ac.PermissionSource `json:"other"`
+}
+
+// GET /api/access-control/users/:userID/explain
+func (api *AccessControlAPI) explainUserPermission(c *contextmodel.ReqContext) response.Response {
+	userID, err := strconv.ParseInt(web.Params(c.Req)[":userID"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "user ID is invalid", err)
+	}
+
+	action, scope := c.Query("action"), c.Query("scope")
+	if action == "" {
+		return response.Error(http.StatusBadRequest, "action is required", nil)
+	}
+	if scope != "" && !ac.ValidateScope(scope) {
+		return response.Error(http.StatusBadRequest, "scope is invalid", nil)
+	}
+
+	orgID := c.SignedInUser.GetOrgID()
+	sources, err := api.Service.GetUserPermissionSources(c.Req.Context(), orgID, ac.SearchOptions{UserID: userID, Action: action})
+	if err != nil {
+		if errors.Is(err, ac.ErrUserNotInOrg) {
+			return response.Error(http.StatusNotFound, "user not found", err)
+		}
+		return response.Error(http.StatusInternalServerError, "could not get user permissions", err)
+	}
+
+	evaluator := ac.EvalPermission(action)
+	if scope != "" {
+		evaluator = ac.EvalPermission(action, scope)
+	}
+
+	// the decision is made the same way as for requests of the user
+	permissions := make([]ac.Permission, 0, len(sources))
+	for _, source := range sources {
+		permissions = append(permissions, ac.Permission{Action: source.Action, Scope: source.Scope})
+	}
+	allowed, err := api.AccessControl.Evaluate(c.Req.Context(), &user.SignedInUser{
+		UserID:      userID,
+		OrgID:       orgID,
+		Permissions: map[int64]map[string][]string{orgID: ac.GroupScopesByAction(permissions)},
+	}, evaluator)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "could not evaluate user permissions", err)
+	}
+
+	resolved, resolvedScopes, err := api.resolveScopes(c.Req.Context(), orgID, evaluator)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "could not resolve scope", err)
+	}
+
+	explanation := explanationDTO{
+		Allowed:        allowed,
+		UserID:         userID,
+		OrgID:          orgID,
+		Action:         action,
+		Scope:          scope,
+		ResolvedScopes: resolvedScopes,
+		Granting:       []ac.PermissionSource{},
+		Other:          []ac.PermissionSource{},
+	}
+	for _, source := range sources {
+		granted := map[string][]string{source.Action: {source.Scope}}
+		if evaluator.Evaluate(granted) || (resolved != nil && resolved.Evaluate(granted)) {
+			explanation.Granting = append(explanation.Granting, source)
+		} else {
+			explanation.Other = append(explanation.Other, source)
+		}
+	}
+
+	return response.JSON(http.StatusOK, explanation)
+}
+
+// resolveScopes returns the evaluator with resolved scopes and the resolved scopes,
+// the evaluator is nil if the scopes do not need to be or cannot be resolved
+func (api *AccessControlAPI) resolveScopes(ctx context.Context, orgID int64, evaluator ac.Evaluator) (ac.Evaluator, []string, error) {
+	provider, ok := api.AccessControl.(scopeMutatorProvider)
+	if !ok {
+		return nil, nil, nil
+	}
+
+	var resolvedScopes []string
+	mutate := provider.GetScopeAttributeMutator(orgID)
+	resolved, err := evaluator.MutateScopes(ctx, func(ctx context.Context, scope string) ([]string, error) {
+		scopes, err := mutate(ctx, scope)
+		resolvedScopes = append(resolvedScopes, scopes...)
+		return scopes, err
+	})
+	if err != nil {
+		if errors.Is(err, ac.ErrResolverNotFound) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	return resolved, resolvedScopes, nil
+}
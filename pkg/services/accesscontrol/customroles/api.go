@@ -0,0 +1,297 @@
+package customroles
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/middleware"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+type api struct {
+	ac      accesscontrol.AccessControl
+	router  routing.RouteRegister
+	service *CustomRolesService
+}
+
+func newAPI(ac accesscontrol.AccessControl, router routing.RouteRegister, service *CustomRolesService) *api {
+	return &api{ac: ac, router: router, service: service}
+}
+
+func (a *api) registerEndpoints() {
+	authorize := accesscontrol.Middleware(a.ac)
+	roleScope := accesscontrol.ScopeRolesProvider.GetResourceScopeUID(accesscontrol.Parameter(":roleUID"))
+	userScope := accesscontrol.Scope("users", "id", accesscontrol.Parameter(":userId"))
+
+	a.router.Group("/api/access-control", func(r routing.RouteRegister) {
+		r.Get("/roles", authorize(accesscontrol.EvalPermission(accesscontrol.ActionRolesRead)), routing.Wrap(a.getRoles))
+		r.Get("/roles/:roleUID", authorize(accesscontrol.EvalPermission(accesscontrol.ActionRolesRead, roleScope)), routing.Wrap(a.getRole))
+		r.Post("/roles", authorize(accesscontrol.EvalPermission(accesscontrol.ActionRolesWrite)), routing.Wrap(a.createRole))
+		r.Put("/roles/:roleUID", authorize(accesscontrol.EvalPermission(accesscontrol.ActionRolesWrite, roleScope)), routing.Wrap(a.updateRole))
+		r.Delete("/roles/:roleUID", authorize(accesscontrol.EvalPermission(accesscontrol.ActionRolesDelete, roleScope)), routing.Wrap(a.deleteRole))
+
+		r.Get("/users/:userId/roles", authorize(accesscontrol.EvalPermission(accesscontrol.ActionUsersRolesRead, userScope)), routing.Wrap(a.getUserRoles))
+		r.Post("/users/:userId/roles", authorize(accesscontrol.EvalPermission(accesscontrol.ActionUsersRolesAdd, userScope)), routing.Wrap(a.addUserRole))
+		r.Delete("/users/:userId/roles/:roleUID", authorize(accesscontrol.EvalPermission(accesscontrol.ActionUsersRolesRemove, userScope)), routing.Wrap(a.removeUserRole))
+
+		r.Get("/teams/:teamId/roles", authorize(accesscontrol.EvalPermission(accesscontrol.ActionTeamsRolesRead, accesscontrol.ScopeTeamsID)), routing.Wrap(a.getTeamRoles))
+		r.Post("/teams/:teamId/roles", authorize(accesscontrol.EvalPermission(accesscontrol.ActionTeamsRolesAdd, accesscontrol.ScopeTeamsID)), routing.Wrap(a.addTeamRole))
+		r.Delete("/teams/:teamId/roles/:roleUID", authorize(accesscontrol.EvalPermission(accesscontrol.ActionTeamsRolesRemove, accesscontrol.ScopeTeamsID)), routing.Wrap(a.removeTeamRole))
+	}, middleware.ReqSignedIn)
+}
+
+// GET /api/access-control/roles
+func (a *api) getRoles(c *contextmodel.ReqContext) response.Response {
+	roles, err := a.service.GetRoles(c.Req.Context(), c.SignedInUser.GetOrgID())
+	if err != nil {
+		return errorResponse(err, "Failed to get roles")
+	}
+
+	// only return the roles the user is allowed to read
+	result := make([]*accesscontrol.RoleDTO, 0, len(roles))
+	for _, role := range roles {
+		ok, err := a.ac.Evaluate(c.Req.Context(), c.SignedInUser,
+			accesscontrol.EvalPermission(accesscontrol.ActionRolesRead, accesscontrol.ScopeRolesProvider.GetResourceScopeUID(role.UID)))
+		if err != nil {
+			return response.Error(http.StatusInternalServerError, "Failed to evaluate permissions", err)
+		}
+		if ok {
+			result = append(result, role)
+		}
+	}
+	return response.JSON(http.StatusOK, result)
+}
+
+// GET /api/access-control/roles/:roleUID
+func (a *api) getRole(c *contextmodel.ReqContext) response.Response {
+	role, err := a.service.GetRole(c.Req.Context(), c.SignedInUser.GetOrgID(), web.Params(c.Req)[":roleUID"])
+	if err != nil {
+		return errorResponse(err, "Failed to get role")
+	}
+	return response.JSON(http.StatusOK, role)
+}
+
+// POST /api/access-control/roles
+func (a *api) createRole(c *contextmodel.ReqContext) response.Response {
+	var cmd CreateRoleCommand
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "Bad request data", err)
+	}
+	cmd.OrgID = c.SignedInUser.GetOrgID()
+
+	if cmd.Global && !c.SignedInUser.GetIsGrafanaAdmin() {
+		return response.Error(http.StatusForbidden, "Only server admins can create global roles", nil)
+	}
+	if resp := a.checkDelegation(c, cmd.Permissions); resp != nil {
+		return resp
+	}
+
+	role, err := a.service.CreateRole(c.Req.Context(), cmd)
+	if err != nil {
+		return errorResponse(err, "Failed to create role")
+	}
+	return response.JSON(http.StatusCreated, role)
+}
+
+// PUT /api/access-control/roles/:roleUID
+func (a *api) updateRole(c *contextmodel.ReqContext) response.Response {
+	var cmd UpdateRoleCommand
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "Bad request data", err)
+	}
+	cmd.OrgID = c.SignedInUser.GetOrgID()
+	cmd.UID = web.Params(c.Req)[":roleUID"]
+
+	if resp := a.checkGlobalRole(c, cmd.UID); resp != nil {
+		return resp
+	}
+	if resp := a.checkDelegation(c, cmd.Permissions); resp != nil {
+		return resp
+	}
+
+	role, err := a.service.UpdateRole(c.Req.Context(), cmd)
+	if err != nil {
+		return errorResponse(err, "Failed to update role")
+	}
+	return response.JSON(http.StatusOK, role)
+}
+
+// DELETE /api/access-control/roles/:roleUID
+func (a *api) deleteRole(c *contextmodel.ReqContext) response.Response {
+	cmd := DeleteRoleCommand{
+		OrgID: c.SignedInUser.GetOrgID(),
+		UID:   web.Params(c.Req)[":roleUID"],
+		Force: c.QueryBool("force"),
+	}
+
+	if resp := a.checkGlobalRole(c, cmd.UID); resp != nil {
+		return resp
+	}
+
+	if err := a.service.DeleteRole(c.Req.Context(), cmd); err != nil {
+		return errorResponse(err, "Failed to delete role")
+	}
+	return response.Success("Role deleted")
+}
+
+// GET /api/access-control/users/:userId/roles
+func (a *api) getUserRoles(c *contextmodel.ReqContext) response.Response {
+	userID, err := strconv.ParseInt(web.Params(c.Req)[":userId"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "User ID is invalid", err)
+	}
+
+	roles, err := a.service.GetUserRoles(c.Req.Context(), c.SignedInUser.GetOrgID(), userID)
+	if err != nil {
+		return errorResponse(err, "Failed to get user roles")
+	}
+	return response.JSON(http.StatusOK, roles)
+}
+
+// POST /api/access-control/users/:userId/roles
+func (a *api) addUserRole(c *contextmodel.ReqContext) response.Response {
+	userID, err := strconv.ParseInt(web.Params(c.Req)[":userId"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "User ID is invalid", err)
+	}
+	var cmd AddRoleAssignmentCommand
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "Bad request data", err)
+	}
+
+	if resp := a.checkRoleDelegation(c, cmd.RoleUID); resp != nil {
+		return resp
+	}
+
+	if err := a.service.AddUserRole(c.Req.Context(), c.SignedInUser.GetOrgID(), userID, cmd.RoleUID); err != nil {
+		return errorResponse(err, "Failed to assign role")
+	}
+	return response.Success("Role assigned")
+}
+
+// DELETE /api/access-control/users/:userId/roles/:roleUID
+func (a *api) removeUserRole(c *contextmodel.ReqContext) response.Response {
+	userID, err := strconv.ParseInt(web.Params(c.Req)[":userId"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "User ID is invalid", err)
+	}
+
+	roleUID := web.Params(c.Req)[":roleUID"]
+	if resp := a.checkRoleDelegation(c, roleUID); resp != nil {
+		return resp
+	}
+
+	if err := a.service.RemoveUserRole(c.Req.Context(), c.SignedInUser.GetOrgID(), userID, roleUID); err != nil {
+		return errorResponse(err, "Failed to unassign role")
+	}
+	return response.Success("Role unassigned")
+}
+
+// GET /api/access-control/teams/:teamId/roles
+func (a *api) getTeamRoles(c *contextmodel.ReqContext) response.Response {
+	teamID, err := strconv.ParseInt(web.Params(c.Req)[":teamId"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "Team ID is invalid", err)
+	}
+
+	roles, err := a.service.GetTeamRoles(c.Req.Context(), c.SignedInUser.GetOrgID(), teamID)
+	if err != nil {
+		return errorResponse(err, "Failed to get team roles")
+	}
+	return response.JSON(http.StatusOK, roles)
+}
+
+// POST /api/access-control/teams/:teamId/roles
+func (a *api) addTeamRole(c *contextmodel.ReqContext) response.Response {
+	teamID, err := strconv.ParseInt(web.Params(c.Req)[":teamId"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "Team ID is invalid", err)
+	}
+	var cmd AddRoleAssignmentCommand
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "Bad request data", err)
+	}
+
+	if resp := a.checkRoleDelegation(c, cmd.RoleUID); resp != nil {
+		return resp
+	}
+
+	if err := a.service.AddTeamRole(c.Req.Context(), c.SignedInUser.GetOrgID(), teamID, cmd.RoleUID); err != nil {
+		return errorResponse(err, "Failed to assign role")
+	}
+	return response.Success("Role assigned")
+}
+
+// DELETE /api/access-control/teams/:teamId/roles/:roleUID
+func (a *api) removeTeamRole(c *contextmodel.ReqContext) response.Response {
+	teamID, err := strconv.ParseInt(web.Params(c.Req)[":teamId"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "Team ID is invalid", err)
+	}
+
+	roleUID := web.Params(c.Req)[":roleUID"]
+	if resp := a.checkRoleDelegation(c, roleUID); resp != nil {
+		return resp
+	}
+
+	if err := a.service.RemoveTeamRole(c.Req.Context(), c.SignedInUser.GetOrgID(), teamID, roleUID); err != nil {
+		return errorResponse(err, "Failed to unassign role")
+	}
+	return response.Success("Role unassigned")
+}
+
+// checkDelegation prevents privilege escalation: users can only grant the permissions they have
+func (a *api) checkDelegation(c *contextmodel.ReqContext, permissions []accesscontrol.Permission) response.Response {
+	for _, p := range permissions {
+		evaluator := accesscontrol.EvalPermission(p.Action)
+		if p.Scope != "" {
+			evaluator = accesscontrol.EvalPermission(p.Action, p.Scope)
+		}
+		ok, err := a.ac.Evaluate(c.Req.Context(), c.SignedInUser, evaluator)
+		if err != nil {
+			return response.Error(http.StatusInternalServerError, "Failed to evaluate permissions", err)
+		}
+		if !ok {
+			return response.Error(http.StatusForbidden, "Cannot grant permission "+evaluator.String()+" that you do not have", nil)
+		}
+	}
+	return nil
+}
+
+// checkRoleDelegation prevents users from assigning or unassigning roles granting permissions they do not have
+func (a *api) checkRoleDelegation(c *contextmodel.ReqContext, roleUID string) response.Response {
+	role, err := a.service.GetRole(c.Req.Context(), c.SignedInUser.GetOrgID(), roleUID)
+	if err != nil {
+		return errorResponse(err, "Failed to get role")
+	}
+	return a.checkDelegation(c, role.Permissions)
+}
+
+// checkGlobalRole restricts the changes of global roles to server admins since they apply to all organizations
+func (a *api) checkGlobalRole(c *contextmodel.ReqContext, roleUID string) response.Response {
+	role, err := a.service.GetRole(c.Req.Context(), c.SignedInUser.GetOrgID(), roleUID)
+	if err != nil {
+		return errorResponse(err, "Failed to get role")
+	}
+	if role.Global() && !c.SignedInUser.GetIsGrafanaAdmin() {
+		return response.Error(http.StatusForbidden, "Only server admins can change global roles", nil)
+	}
+	return nil
+}
+
+func errorResponse(err error, message string) response.Response {
+	switch {
+	case errors.Is(err, ErrRoleNotFound), errors.Is(err, ErrAssigneeNotFound):
+		return response.Error(http.StatusNotFound, err.Error(), err)
+	case errors.Is(err, ErrRoleInvalid):
+		return response.Error(http.StatusBadRequest, err.Error(), err)
+	case errors.Is(err, ErrRoleAlreadyExists), errors.Is(err, ErrRoleVersionConflict), errors.Is(err, ErrRoleAssigned):
+		return response.Error(http.StatusConflict, err.Error(), err)
+	}
+	return response.Error(http.StatusInternalServerError, message, err)
+}
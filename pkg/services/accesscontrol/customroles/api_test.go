@@ -0,0 +1,188 @@
+package customroles
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/web/webtest"
+)
+
+var roleWriterPermissions = []accesscontrol.Permission{
+	{Action: accesscontrol.ActionRolesRead, Scope: accesscontrol.ScopeRolesAll},
+	{Action: accesscontrol.ActionRolesWrite, Scope: accesscontrol.ScopeRolesAll},
+	{Action: accesscontrol.ActionRolesDelete, Scope: accesscontrol.ScopeRolesAll},
+	{Action: accesscontrol.ActionUsersRolesAdd, Scope: accesscontrol.ScopeUsersAll},
+}
+
+func TestIntegrationApi_createRole(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	type testCase struct {
+		desc           string
+		body           string
+		isServerAdmin  bool
+		permissions    []accesscontrol.Permission
+		expectedStatus int
+	}
+
+	body := `{"uid": "alerting_editor", "name": "custom:alerting:editor", "permissions": [{"action": "alert.rules:write", "scope": "folders:uid:folder1"}]}`
+	tests := []testCase{
+		{
+			desc:           "should create role with permissions the user has",
+			body:           body,
+			permissions:    append([]accesscontrol.Permission{{Action: "alert.rules:write", Scope: "folders:*"}}, roleWriterPermissions...),
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			desc:           "should not create role with permissions the user does not have",
+			body:           body,
+			permissions:    append([]accesscontrol.Permission{{Action: "alert.rules:write", Scope: "folders:uid:folder2"}}, roleWriterPermissions...),
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			desc:           "should not create role without roles:write",
+			body:           body,
+			permissions:    []accesscontrol.Permission{{Action: "alert.rules:write", Scope: "folders:*"}},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			desc:           "should not create global role if not server admin",
+			body:           `{"name": "custom:global", "global": true}`,
+			permissions:    roleWriterPermissions,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			desc:           "should create global role if server admin",
+			body:           `{"name": "custom:global", "global": true}`,
+			isServerAdmin:  true,
+			permissions:    roleWriterPermissions,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			desc:           "should return bad request for invalid role",
+			body:           `{"name": "basic:viewer"}`,
+			permissions:    roleWriterPermissions,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			env := setupTestEnv(t)
+			server := webtest.NewServer(t, env.router)
+
+			req := server.NewPostRequest("/api/access-control/roles", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			webtest.RequestWithSignedInUser(req, testUser(tt.isServerAdmin, tt.permissions))
+			res, err := server.Send(req)
+			require.NoError(t, err)
+			require.NoError(t, res.Body.Close())
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+		})
+	}
+}
+
+func TestIntegrationApi_updateRole(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	env := setupTestEnv(t)
+	server := webtest.NewServer(t, env.router)
+	_, err := env.service.CreateRole(context.Background(), alertingEditor)
+	require.NoError(t, err)
+
+	update := func(version int64) *http.Response {
+		body := fmt.Sprintf(`{"name": "custom:alerting:editor", "version": %d, "permissions": [{"action": "alert.rules:read", "scope": "folders:*"}]}`, version)
+		req := server.NewRequest(http.MethodPut, "/api/access-control/roles/alerting_editor", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		webtest.RequestWithSignedInUser(req, testUser(false, append([]accesscontrol.Permission{{Action: "alert.rules:read", Scope: "folders:*"}}, roleWriterPermissions...)))
+		res, err := server.Send(req)
+		require.NoError(t, err)
+		return res
+	}
+
+	res := update(1)
+	require.NoError(t, res.Body.Close())
+	assert.Equal(t, http.StatusConflict, res.StatusCode)
+
+	res = update(2)
+	var role accesscontrol.RoleDTO
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&role))
+	require.NoError(t, res.Body.Close())
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, int64(2), role.Version)
+	assert.Equal(t, []accesscontrol.Permission{{Action: "alert.rules:read", Scope: "folders:*"}}, stripPermissions(role.Permissions))
+}
+
+func TestIntegrationApi_addUserRole(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	type testCase struct {
+		desc           string
+		permissions    []accesscontrol.Permission
+		expectedStatus int
+	}
+
+	tests := []testCase{
+		{
+			desc:           "should assign role with permissions the user has",
+			permissions:    append([]accesscontrol.Permission{{Action: "alert.rules:read", Scope: "folders:*"}, {Action: "alert.rules:write", Scope: "folders:*"}}, roleWriterPermissions...),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:           "should not assign role with permissions the user does not have",
+			permissions:    append([]accesscontrol.Permission{{Action: "alert.rules:read", Scope: "folders:*"}}, roleWriterPermissions...),
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			env := setupTestEnv(t)
+			server := webtest.NewServer(t, env.router)
+			_, err := env.service.CreateRole(context.Background(), alertingEditor)
+			require.NoError(t, err)
+			usr, err := env.userSvc.Create(context.Background(), &user.CreateUserCommand{Login: "user", OrgID: 1})
+			require.NoError(t, err)
+
+			req := server.NewPostRequest(fmt.Sprintf("/api/access-control/users/%d/roles", usr.ID), strings.NewReader(`{"roleUid": "alerting_editor"}`))
+			req.Header.Set("Content-Type", "application/json")
+			webtest.RequestWithSignedInUser(req, testUser(false, tt.permissions))
+			res, err := server.Send(req)
+			require.NoError(t, err)
+			require.NoError(t, res.Body.Close())
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+		})
+	}
+}
+
+func testUser(isServerAdmin bool, permissions []accesscontrol.Permission) *user.SignedInUser {
+	return &user.SignedInUser{
+		UserID:         1,
+		OrgID:          1,
+		IsGrafanaAdmin: isServerAdmin,
+		Permissions:    map[int64]map[string][]string{1: accesscontrol.GroupScopesByAction(permissions)},
+	}
+}
+
+func stripPermissions(permissions []accesscontrol.Permission) []accesscontrol.Permission {
+	result := make([]accesscontrol.Permission, 0, len(permissions))
+	for _, p := range permissions {
+		result = append(result, accesscontrol.Permission{Action: p.Action, Scope: p.Scope})
+	}
+	return result
+}
@@ -0,0 +1,125 @@
+package customroles
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+var (
+	ErrRoleNotFound        = errors.New("role not found")
+	ErrRoleInvalid         = errors.New("invalid role")
+	ErrRoleAlreadyExists   = errors.New("a role with the same name or uid already exists")
+	ErrRoleVersionConflict = errors.New("role version must be greater than the current version")
+	ErrRoleAssigned        = errors.New("role is assigned, use force to delete the role and its assignments")
+	ErrAssigneeNotFound    = errors.New("user or team not found")
+)
+
+const (
+	maxNameLength = 190
+	maxUIDLength  = 40
+)
+
+// CreateRoleCommand creates a custom role in the organization or a global role assignable in all organizations
+type CreateRoleCommand struct {
+	OrgID       int64                      `json:"-"`
+	UID         string                     `json:"uid"`
+	Name        string                     `json:"name"`
+	DisplayName string                     `json:"displayName"`
+	Description string                     `json:"description"`
+	Group       string                     `json:"group"`
+	Hidden      bool                       `json:"hidden"`
+	Global      bool                       `json:"global"`
+	Version     int64                      `json:"version"`
+	Permissions []accesscontrol.Permission `json:"permissions"`
+}
+
+func (cmd *CreateRoleCommand) Validate() error {
+	if cmd.UID == "" {
+		cmd.UID = util.GenerateShortUID()
+	}
+	if cmd.Version == 0 {
+		cmd.Version = 1
+	}
+	if cmd.Global {
+		cmd.OrgID = accesscontrol.GlobalOrgID
+	}
+
+	permissions, err := validateRole(cmd.UID, cmd.Name, cmd.Permissions)
+	if err != nil {
+		return err
+	}
+	cmd.Permissions = permissions
+	return nil
+}
+
+// UpdateRoleCommand replaces the definition of a custom role, the version must be greater than the stored
+// version so that concurrent updates cannot silently overwrite each other
+type UpdateRoleCommand struct {
+	OrgID       int64                      `json:"-"`
+	UID         string                     `json:"-"`
+	Name        string                     `json:"name"`
+	DisplayName string                     `json:"displayName"`
+	Description string                     `json:"description"`
+	Group       string                     `json:"group"`
+	Hidden      bool                       `json:"hidden"`
+	Version     int64                      `json:"version"`
+	Permissions []accesscontrol.Permission `json:"permissions"`
+}
+
+func (cmd *UpdateRoleCommand) Validate() error {
+	permissions, err := validateRole(cmd.UID, cmd.Name, cmd.Permissions)
+	if err != nil {
+		return err
+	}
+	cmd.Permissions = permissions
+	return nil
+}
+
+type DeleteRoleCommand struct {
+	OrgID int64
+	UID   string
+	// Force deletes the role even if it is assigned, the assignments are removed
+	Force bool
+}
+
+type AddRoleAssignmentCommand struct {
+	RoleUID string `json:"roleUid"`
+}
+
+// validateRole checks the role name and uid and returns the deduplicated permissions
+func validateRole(uid, name string, permissions []accesscontrol.Permission) ([]accesscontrol.Permission, error) {
+	if !strings.HasPrefix(name, accesscontrol.CustomRolePrefix) || len(name) == len(accesscontrol.CustomRolePrefix) {
+		return nil, fmt.Errorf("%w: role name %q must be prefixed with %q", ErrRoleInvalid, name, accesscontrol.CustomRolePrefix)
+	}
+	if len(name) > maxNameLength {
+		return nil, fmt.Errorf("%w: role name must not be longer than %d characters", ErrRoleInvalid, maxNameLength)
+	}
+	if len(uid) > maxUIDLength {
+		return nil, fmt.Errorf("%w: role uid must not be longer than %d characters", ErrRoleInvalid, maxUIDLength)
+	}
+	if !util.IsValidShortUID(uid) {
+		return nil, fmt.Errorf("%w: role uid %q contains invalid characters", ErrRoleInvalid, uid)
+	}
+
+	seen := map[string]bool{}
+	result := make([]accesscontrol.Permission, 0, len(permissions))
+	for _, p := range permissions {
+		if p.Action == "" {
+			return nil, fmt.Errorf("%w: permission with no action", ErrRoleInvalid)
+		}
+		if p.Scope != "" && !accesscontrol.ValidateScope(p.Scope) {
+			return nil, fmt.Errorf("%w: invalid scope %q for action %q", ErrRoleInvalid, p.Scope, p.Action)
+		}
+		key := fmt.Sprintf("%s|%s", p.Action, p.Scope)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, accesscontrol.Permission{Action: p.Action, Scope: p.Scope})
+	}
+	return result, nil
+}
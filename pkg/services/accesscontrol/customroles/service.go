@@ -0,0 +1,205 @@
+package customroles
+
+import (
+	"context"
+	"errors"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/team"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+// Service manages the custom roles and their assignments to users, service accounts and teams.
+// The permissions of the assigned roles are loaded with the other stored permissions by the
+// access control service, so they are evaluated like any other permission.
+type Service interface {
+	GetRoles(ctx context.Context, orgID int64) ([]*accesscontrol.RoleDTO, error)
+	GetRole(ctx context.Context, orgID int64, uid string) (*accesscontrol.RoleDTO, error)
+	CreateRole(ctx context.Context, cmd CreateRoleCommand) (*accesscontrol.RoleDTO, error)
+	UpdateRole(ctx context.Context, cmd UpdateRoleCommand) (*accesscontrol.RoleDTO, error)
+	DeleteRole(ctx context.Context, cmd DeleteRoleCommand) error
+
+	GetUserRoles(ctx context.Context, orgID, userID int64) ([]*accesscontrol.RoleDTO, error)
+	AddUserRole(ctx context.Context, orgID, userID int64, roleUID string) error
+	RemoveUserRole(ctx context.Context, orgID, userID int64, roleUID string) error
+
+	GetTeamRoles(ctx context.Context, orgID, teamID int64) ([]*accesscontrol.RoleDTO, error)
+	AddTeamRole(ctx context.Context, orgID, teamID int64, roleUID string) error
+	RemoveTeamRole(ctx context.Context, orgID, teamID int64, roleUID string) error
+}
+
+type CustomRolesService struct {
+	store       *store
+	acService   accesscontrol.Service
+	userService user.Service
+	teamService team.Service
+	log         log.Logger
+}
+
+func ProvideService(sql db.DB, features featuremgmt.FeatureToggles, routeRegister routing.RouteRegister,
+	ac accesscontrol.AccessControl, acService accesscontrol.Service, userService user.Service, teamService team.Service,
+) (*CustomRolesService, error) {
+	s := &CustomRolesService{
+		store:       &store{sql: sql, features: features},
+		acService:   acService,
+		userService: userService,
+		teamService: teamService,
+		log:         log.New("accesscontrol.customroles"),
+	}
+
+	if err := s.declareFixedRoles(); err != nil {
+		return nil, err
+	}
+	newAPI(ac, routeRegister, s).registerEndpoints()
+
+	return s, nil
+}
+
+func (s *CustomRolesService) GetRoles(ctx context.Context, orgID int64) ([]*accesscontrol.RoleDTO, error) {
+	return s.store.getRoles(ctx, orgID)
+}
+
+func (s *CustomRolesService) GetRole(ctx context.Context, orgID int64, uid string) (*accesscontrol.RoleDTO, error) {
+	return s.store.getRole(ctx, orgID, uid)
+}
+
+func (s *CustomRolesService) CreateRole(ctx context.Context, cmd CreateRoleCommand) (*accesscontrol.RoleDTO, error) {
+	if err := cmd.Validate(); err != nil {
+		return nil, err
+	}
+	return s.store.createRole(ctx, cmd)
+}
+
+func (s *CustomRolesService) UpdateRole(ctx context.Context, cmd UpdateRoleCommand) (*accesscontrol.RoleDTO, error) {
+	if err := cmd.Validate(); err != nil {
+		return nil, err
+	}
+	return s.store.updateRole(ctx, cmd)
+}
+
+func (s *CustomRolesService) DeleteRole(ctx context.Context, cmd DeleteRoleCommand) error {
+	return s.store.deleteRole(ctx, cmd)
+}
+
+func (s *CustomRolesService) GetUserRoles(ctx context.Context, orgID, userID int64) ([]*accesscontrol.RoleDTO, error) {
+	if err := s.validateUser(ctx, orgID, userID); err != nil {
+		return nil, err
+	}
+	return s.store.getUserRoles(ctx, orgID, userID)
+}
+
+func (s *CustomRolesService) AddUserRole(ctx context.Context, orgID, userID int64, roleUID string) error {
+	if err := s.validateUser(ctx, orgID, userID); err != nil {
+		return err
+	}
+	if err := s.store.addUserRole(ctx, orgID, userID, roleUID); err != nil {
+		return err
+	}
+	s.clearPermissionCache(orgID, userID)
+	return nil
+}
+
+func (s *CustomRolesService) RemoveUserRole(ctx context.Context, orgID, userID int64, roleUID string) error {
+	if err := s.validateUser(ctx, orgID, userID); err != nil {
+		return err
+	}
+	if err := s.store.removeUserRole(ctx, orgID, userID, roleUID); err != nil {
+		return err
+	}
+	s.clearPermissionCache(orgID, userID)
+	return nil
+}
+
+func (s *CustomRolesService) GetTeamRoles(ctx context.Context, orgID, teamID int64) ([]*accesscontrol.RoleDTO, error) {
+	if err := s.validateTeam(ctx, orgID, teamID); err != nil {
+		return nil, err
+	}
+	return s.store.getTeamRoles(ctx, orgID, teamID)
+}
+
+func (s *CustomRolesService) AddTeamRole(ctx context.Context, orgID, teamID int64, roleUID string) error {
+	if err := s.validateTeam(ctx, orgID, teamID); err != nil {
+		return err
+	}
+	return s.store.addTeamRole(ctx, orgID, teamID, roleUID)
+}
+
+func (s *CustomRolesService) RemoveTeamRole(ctx context.Context, orgID, teamID int64, roleUID string) error {
+	if err := s.validateTeam(ctx, orgID, teamID); err != nil {
+		return err
+	}
+	return s.store.removeTeamRole(ctx, orgID, teamID, roleUID)
+}
+
+// validateUser checks that the user or service account belongs to the organization
+func (s *CustomRolesService) validateUser(ctx context.Context, orgID, userID int64) error {
+	usr, err := s.userService.GetSignedInUser(ctx, &user.GetSignedInUserQuery{OrgID: orgID, UserID: userID})
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return ErrAssigneeNotFound
+		}
+		return err
+	}
+	if usr.OrgID != orgID {
+		return ErrAssigneeNotFound
+	}
+	return nil
+}
+
+func (s *CustomRolesService) validateTeam(ctx context.Context, orgID, teamID int64) error {
+	if _, err := s.teamService.GetTeamByID(ctx, &team.GetTeamByIDQuery{OrgID: orgID, ID: teamID}); err != nil {
+		if errors.Is(err, team.ErrTeamNotFound) {
+			return ErrAssigneeNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// clearPermissionCache applies the assignment change immediately for the user, team assignments and
+// role updates are applied when the cached permissions expire
+func (s *CustomRolesService) clearPermissionCache(orgID, userID int64) {
+	s.acService.ClearUserPermissionCache(&user.SignedInUser{OrgID: orgID, UserID: userID})
+}
+
+func (s *CustomRolesService) declareFixedRoles() error {
+	reader := accesscontrol.RoleRegistration{
+		Role: accesscontrol.RoleDTO{
+			Name:        "fixed:roles:reader",
+			DisplayName: "Custom role reader",
+			Description: "Read custom roles and their assignments.",
+			Group:       "Access control",
+			Permissions: []accesscontrol.Permission{
+				{Action: accesscontrol.ActionRolesRead, Scope: accesscontrol.ScopeRolesAll},
+				{Action: accesscontrol.ActionUsersRolesRead, Scope: accesscontrol.ScopeUsersAll},
+				{Action: accesscontrol.ActionTeamsRolesRead, Scope: accesscontrol.ScopeTeamsAll},
+			},
+		},
+		Grants: []string{string(org.RoleAdmin)},
+	}
+
+	writer := accesscontrol.RoleRegistration{
+		Role: accesscontrol.RoleDTO{
+			Name:        "fixed:roles:writer",
+			DisplayName: "Custom role writer",
+			Description: "Create, update and delete custom roles and assign them to users, service accounts and teams.",
+			Group:       "Access control",
+			Permissions: accesscontrol.ConcatPermissions(reader.Role.Permissions, []accesscontrol.Permission{
+				{Action: accesscontrol.ActionRolesWrite, Scope: accesscontrol.ScopeRolesAll},
+				{Action: accesscontrol.ActionRolesDelete, Scope: accesscontrol.ScopeRolesAll},
+				{Action: accesscontrol.ActionUsersRolesAdd, Scope: accesscontrol.ScopeUsersAll},
+				{Action: accesscontrol.ActionUsersRolesRemove, Scope: accesscontrol.ScopeUsersAll},
+				{Action: accesscontrol.ActionTeamsRolesAdd, Scope: accesscontrol.ScopeTeamsAll},
+				{Action: accesscontrol.ActionTeamsRolesRemove, Scope: accesscontrol.ScopeTeamsAll},
+			}),
+		},
+		Grants: []string{string(org.RoleAdmin)},
+	}
+
+	return s.acService.DeclareFixedRoles(reader, writer)
+}
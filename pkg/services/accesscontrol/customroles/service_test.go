@@ -0,0 +1,207 @@
+package customroles
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/localcache"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/accesscontrol/acimpl"
+	"github.com/grafana/grafana/pkg/services/accesscontrol/actest"
+	"github.com/grafana/grafana/pkg/services/accesscontrol/database"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/org/orgimpl"
+	"github.com/grafana/grafana/pkg/services/quota/quotatest"
+	"github.com/grafana/grafana/pkg/services/supportbundles/supportbundlestest"
+	"github.com/grafana/grafana/pkg/services/team"
+	"github.com/grafana/grafana/pkg/services/team/teamimpl"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/services/user/userimpl"
+)
+
+var alertingEditor = CreateRoleCommand{
+	OrgID: 1,
+	UID:   "alerting_editor",
+	Name:  "custom:alerting:editor",
+	Permissions: []accesscontrol.Permission{
+		{Action: "alert.rules:read", Scope: "folders:uid:folder1"},
+		{Action: "alert.rules:write", Scope: "folders:uid:folder1"},
+		{Action: "alert.rules:write", Scope: "folders:uid:folder1"},
+	},
+}
+
+func TestIntegrationCustomRolesService_Roles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	env := setupTestEnv(t)
+
+	t.Run("should create role with deduplicated permissions", func(t *testing.T) {
+		role, err := env.service.CreateRole(ctx, alertingEditor)
+		require.NoError(t, err)
+		assert.Equal(t, "alerting_editor", role.UID)
+		assert.Equal(t, int64(1), role.Version)
+		assert.Len(t, role.Permissions, 2)
+	})
+
+	t.Run("should fail to create role with the same uid", func(t *testing.T) {
+		cmd := alertingEditor
+		cmd.Name = "custom:other"
+		_, err := env.service.CreateRole(ctx, cmd)
+		assert.ErrorIs(t, err, ErrRoleAlreadyExists)
+	})
+
+	t.Run("should fail to create role without custom prefix", func(t *testing.T) {
+		cmd := alertingEditor
+		cmd.UID = "fixed"
+		cmd.Name = "fixed:alerting:editor"
+		_, err := env.service.CreateRole(ctx, cmd)
+		assert.ErrorIs(t, err, ErrRoleInvalid)
+	})
+
+	t.Run("should not return roles of another organization", func(t *testing.T) {
+		roles, err := env.service.GetRoles(ctx, 2)
+		require.NoError(t, err)
+		assert.Empty(t, roles)
+
+		_, err = env.service.GetRole(ctx, 2, alertingEditor.UID)
+		assert.ErrorIs(t, err, ErrRoleNotFound)
+	})
+
+	t.Run("should return global roles in all organizations", func(t *testing.T) {
+		_, err := env.service.CreateRole(ctx, CreateRoleCommand{OrgID: 1, Global: true, UID: "global", Name: "custom:global"})
+		require.NoError(t, err)
+
+		for _, orgID := range []int64{1, 2} {
+			role, err := env.service.GetRole(ctx, orgID, "global")
+			require.NoError(t, err)
+			assert.True(t, role.Global())
+		}
+	})
+
+	t.Run("should only update role with a greater version", func(t *testing.T) {
+		cmd := UpdateRoleCommand{
+			OrgID:       1,
+			UID:         alertingEditor.UID,
+			Name:        alertingEditor.Name,
+			Version:     1,
+			Permissions: []accesscontrol.Permission{{Action: "alert.rules:read", Scope: "folders:uid:folder1"}},
+		}
+		_, err := env.service.UpdateRole(ctx, cmd)
+		assert.ErrorIs(t, err, ErrRoleVersionConflict)
+
+		cmd.Version = 2
+		role, err := env.service.UpdateRole(ctx, cmd)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), role.Version)
+		assert.Len(t, role.Permissions, 1)
+	})
+}
+
+func TestIntegrationCustomRolesService_Assignments(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	env := setupTestEnv(t)
+
+	role, err := env.service.CreateRole(ctx, alertingEditor)
+	require.NoError(t, err)
+
+	usr, err := env.userSvc.Create(ctx, &user.CreateUserCommand{Login: "user", OrgID: 1})
+	require.NoError(t, err)
+	tm, err := env.teamSvc.CreateTeam("team", "", 1)
+	require.NoError(t, err)
+	require.NoError(t, env.teamSvc.AddTeamMember(usr.ID, 1, tm.ID, false, dashboards.PERMISSION_VIEW))
+
+	getPermissions := func(t *testing.T, userID int64, teamIDs []int64) []accesscontrol.Permission {
+		t.Helper()
+		permissions, err := env.acStore.GetUserPermissions(ctx, accesscontrol.GetUserPermissionsQuery{
+			OrgID:        1,
+			UserID:       userID,
+			TeamIDs:      teamIDs,
+			RolePrefixes: []string{accesscontrol.CustomRolePrefix},
+		})
+		require.NoError(t, err)
+		return permissions
+	}
+
+	t.Run("should grant role permissions to assigned user", func(t *testing.T) {
+		require.NoError(t, env.service.AddUserRole(ctx, 1, usr.ID, role.UID))
+		// assignment is idempotent
+		require.NoError(t, env.service.AddUserRole(ctx, 1, usr.ID, role.UID))
+
+		roles, err := env.service.GetUserRoles(ctx, 1, usr.ID)
+		require.NoError(t, err)
+		require.Len(t, roles, 1)
+		assert.Equal(t, role.UID, roles[0].UID)
+		assert.Len(t, getPermissions(t, usr.ID, nil), 2)
+	})
+
+	t.Run("should grant role permissions to assigned team members", func(t *testing.T) {
+		require.NoError(t, env.service.AddTeamRole(ctx, 1, tm.ID, role.UID))
+
+		roles, err := env.service.GetTeamRoles(ctx, 1, tm.ID)
+		require.NoError(t, err)
+		require.Len(t, roles, 1)
+		assert.Len(t, getPermissions(t, 0, []int64{tm.ID}), 2)
+	})
+
+	t.Run("should fail to assign role to unknown user or team", func(t *testing.T) {
+		assert.ErrorIs(t, env.service.AddUserRole(ctx, 1, 1000, role.UID), ErrAssigneeNotFound)
+		assert.ErrorIs(t, env.service.AddTeamRole(ctx, 1, 1000, role.UID), ErrAssigneeNotFound)
+		assert.ErrorIs(t, env.service.AddUserRole(ctx, 1, usr.ID, "unknown"), ErrRoleNotFound)
+	})
+
+	t.Run("should remove user assignment", func(t *testing.T) {
+		require.NoError(t, env.service.RemoveUserRole(ctx, 1, usr.ID, role.UID))
+		roles, err := env.service.GetUserRoles(ctx, 1, usr.ID)
+		require.NoError(t, err)
+		assert.Empty(t, roles)
+	})
+
+	t.Run("should only delete assigned role when forced", func(t *testing.T) {
+		err := env.service.DeleteRole(ctx, DeleteRoleCommand{OrgID: 1, UID: role.UID})
+		assert.ErrorIs(t, err, ErrRoleAssigned)
+
+		require.NoError(t, env.service.DeleteRole(ctx, DeleteRoleCommand{OrgID: 1, UID: role.UID, Force: true}))
+		assert.Empty(t, getPermissions(t, usr.ID, []int64{tm.ID}))
+		_, err = env.service.GetRole(ctx, 1, role.UID)
+		assert.ErrorIs(t, err, ErrRoleNotFound)
+	})
+}
+
+type testEnv struct {
+	service *CustomRolesService
+	router  routing.RouteRegister
+	acStore *database.AccessControlStore
+	userSvc user.Service
+	teamSvc team.Service
+}
+
+func setupTestEnv(t *testing.T) testEnv {
+	t.Helper()
+
+	sql, cfg := db.InitTestDBwithCfg(t)
+	teamSvc := teamimpl.ProvideService(sql, cfg)
+	orgSvc, err := orgimpl.ProvideService(sql, cfg, quotatest.New(false, nil))
+	require.NoError(t, err)
+	userSvc, err := userimpl.ProvideService(sql, orgSvc, cfg, teamSvc, localcache.ProvideService(), quotatest.New(false, nil), supportbundlestest.NewFakeBundleService())
+	require.NoError(t, err)
+
+	router := routing.NewRouteRegister()
+	service, err := ProvideService(sql, featuremgmt.WithFeatures(), router,
+		acimpl.ProvideAccessControl(cfg), &actest.FakeService{}, userSvc, teamSvc)
+	require.NoError(t, err)
+
+	return testEnv{service: service, router: router, acStore: database.ProvideService(sql), userSvc: userSvc, teamSvc: teamSvc}
+}
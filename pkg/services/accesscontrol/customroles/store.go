@@ -0,0 +1,342 @@
+package customroles
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+)
+
+type store struct {
+	sql      db.DB
+	features featuremgmt.FeatureToggles
+}
+
+// getRoles returns the custom roles of the organization and the global custom roles
+func (s *store) getRoles(ctx context.Context, orgID int64) ([]*accesscontrol.RoleDTO, error) {
+	var result []*accesscontrol.RoleDTO
+	err := s.sql.WithDbSession(ctx, func(sess *db.Session) error {
+		var roles []accesscontrol.Role
+		if err := sess.Where("(org_id = ? OR org_id = ?) AND name LIKE ?", orgID, accesscontrol.GlobalOrgID, accesscontrol.CustomRolePrefix+"%").
+			Asc("name").Find(&roles); err != nil {
+			return err
+		}
+
+		var err error
+		result, err = s.withPermissions(sess, roles)
+		return err
+	})
+	return result, err
+}
+
+func (s *store) getRole(ctx context.Context, orgID int64, uid string) (*accesscontrol.RoleDTO, error) {
+	var result *accesscontrol.RoleDTO
+	err := s.sql.WithDbSession(ctx, func(sess *db.Session) error {
+		role, err := getRole(sess, orgID, uid)
+		if err != nil {
+			return err
+		}
+
+		roles, err := s.withPermissions(sess, []accesscontrol.Role{*role})
+		if err != nil {
+			return err
+		}
+		result = roles[0]
+		return nil
+	})
+	return result, err
+}
+
+func (s *store) createRole(ctx context.Context, cmd CreateRoleCommand) (*accesscontrol.RoleDTO, error) {
+	err := s.sql.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		if err := checkRoleUnique(sess, cmd.OrgID, 0, cmd.UID, cmd.Name); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		role := accesscontrol.Role{
+			OrgID:       cmd.OrgID,
+			Version:     cmd.Version,
+			UID:         cmd.UID,
+			Name:        cmd.Name,
+			DisplayName: cmd.DisplayName,
+			Group:       cmd.Group,
+			Description: cmd.Description,
+			Hidden:      cmd.Hidden,
+			Created:     now,
+			Updated:     now,
+		}
+		if _, err := sess.Insert(&role); err != nil {
+			return err
+		}
+
+		return s.createPermissions(sess, role.ID, cmd.Permissions)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.getRole(ctx, cmd.OrgID, cmd.UID)
+}
+
+func (s *store) updateRole(ctx context.Context, cmd UpdateRoleCommand) (*accesscontrol.RoleDTO, error) {
+	var orgID int64
+	err := s.sql.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		role, err := getRole(sess, cmd.OrgID, cmd.UID)
+		if err != nil {
+			return err
+		}
+		if cmd.Version <= role.Version {
+			return ErrRoleVersionConflict
+		}
+		if err := checkRoleUnique(sess, role.OrgID, role.ID, role.UID, cmd.Name); err != nil {
+			return err
+		}
+
+		// the update is conditioned on the version read so a concurrent update fails instead of being overwritten
+		updated := accesscontrol.Role{
+			Version:     cmd.Version,
+			Name:        cmd.Name,
+			DisplayName: cmd.DisplayName,
+			Group:       cmd.Group,
+			Description: cmd.Description,
+			Hidden:      cmd.Hidden,
+			Updated:     time.Now(),
+		}
+		affected, err := sess.Where("id = ? AND version = ?", role.ID, role.Version).
+			Cols("version", "name", "display_name", "group_name", "description", "hidden", "updated").Update(&updated)
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrRoleVersionConflict
+		}
+
+		if _, err := sess.Exec("DELETE FROM permission WHERE role_id = ?", role.ID); err != nil {
+			return err
+		}
+		orgID = role.OrgID
+		return s.createPermissions(sess, role.ID, cmd.Permissions)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.getRole(ctx, orgID, cmd.UID)
+}
+
+func (s *store) deleteRole(ctx context.Context, cmd DeleteRoleCommand) error {
+	return s.sql.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		role, err := getRole(sess, cmd.OrgID, cmd.UID)
+		if err != nil {
+			return err
+		}
+
+		if !cmd.Force {
+			assigned, err := isAssigned(sess, role.ID)
+			if err != nil {
+				return err
+			}
+			if assigned {
+				return ErrRoleAssigned
+			}
+		}
+
+		for _, query := range []string{
+			"DELETE FROM user_role WHERE role_id = ?",
+			"DELETE FROM team_role WHERE role_id = ?",
+			"DELETE FROM builtin_role WHERE role_id = ?",
+			"DELETE FROM permission WHERE role_id = ?",
+			"DELETE FROM role WHERE id = ?",
+		} {
+			if _, err := sess.Exec(query, role.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// getUserRoles returns the custom roles assigned to the user in the organization
+func (s *store) getUserRoles(ctx context.Context, orgID, userID int64) ([]*accesscontrol.RoleDTO, error) {
+	return s.getAssignedRoles(ctx, "user_role", "user_id", orgID, userID)
+}
+
+func (s *store) getTeamRoles(ctx context.Context, orgID, teamID int64) ([]*accesscontrol.RoleDTO, error) {
+	return s.getAssignedRoles(ctx, "team_role", "team_id", orgID, teamID)
+}
+
+func (s *store) getAssignedRoles(ctx context.Context, table, column string, orgID, id int64) ([]*accesscontrol.RoleDTO, error) {
+	var result []*accesscontrol.RoleDTO
+	err := s.sql.WithDbSession(ctx, func(sess *db.Session) error {
+		var roles []accesscontrol.Role
+		if err := sess.SQL(`
+			SELECT role.* FROM role
+			INNER JOIN `+table+` AS a ON a.role_id = role.id
+			WHERE a.`+column+` = ? AND a.org_id = ? AND role.name LIKE ?
+			ORDER BY role.name ASC`,
+			id, orgID, accesscontrol.CustomRolePrefix+"%").Find(&roles); err != nil {
+			return err
+		}
+
+		var err error
+		result, err = s.withPermissions(sess, roles)
+		return err
+	})
+	return result, err
+}
+
+func (s *store) addUserRole(ctx context.Context, orgID, userID int64, roleUID string) error {
+	return s.sql.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		role, err := getRole(sess, orgID, roleUID)
+		if err != nil {
+			return err
+		}
+
+		has, err := sess.Where("org_id = ? AND user_id = ? AND role_id = ?", orgID, userID, role.ID).Exist(&accesscontrol.UserRole{})
+		if err != nil || has {
+			return err
+		}
+		_, err = sess.Insert(&accesscontrol.UserRole{OrgID: orgID, UserID: userID, RoleID: role.ID, Created: time.Now()})
+		return err
+	})
+}
+
+func (s *store) removeUserRole(ctx context.Context, orgID, userID int64, roleUID string) error {
+	return s.sql.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		role, err := getRole(sess, orgID, roleUID)
+		if err != nil {
+			return err
+		}
+		_, err = sess.Exec("DELETE FROM user_role WHERE org_id = ? AND user_id = ? AND role_id = ?", orgID, userID, role.ID)
+		return err
+	})
+}
+
+func (s *store) addTeamRole(ctx context.Context, orgID, teamID int64, roleUID string) error {
+	return s.sql.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		role, err := getRole(sess, orgID, roleUID)
+		if err != nil {
+			return err
+		}
+
+		has, err := sess.Where("org_id = ? AND team_id = ? AND role_id = ?", orgID, teamID, role.ID).Exist(&accesscontrol.TeamRole{})
+		if err != nil || has {
+			return err
+		}
+		_, err = sess.Insert(&accesscontrol.TeamRole{OrgID: orgID, TeamID: teamID, RoleID: role.ID, Created: time.Now()})
+		return err
+	})
+}
+
+func (s *store) removeTeamRole(ctx context.Context, orgID, teamID int64, roleUID string) error {
+	return s.sql.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		role, err := getRole(sess, orgID, roleUID)
+		if err != nil {
+			return err
+		}
+		_, err = sess.Exec("DELETE FROM team_role WHERE org_id = ? AND team_id = ? AND role_id = ?", orgID, teamID, role.ID)
+		return err
+	})
+}
+
+func (s *store) createPermissions(sess *db.Session, roleID int64, permissions []accesscontrol.Permission) error {
+	if len(permissions) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	toInsert := make([]accesscontrol.Permission, 0, len(permissions))
+	for _, p := range permissions {
+		p.RoleID = roleID
+		p.Created = now
+		p.Updated = now
+		if s.features.IsEnabled(featuremgmt.FlagSplitScopes) {
+			p.Kind, p.Attribute, p.Identifier = p.SplitScope()
+		}
+		toInsert = append(toInsert, p)
+	}
+
+	_, err := sess.InsertMulti(&toInsert)
+	return err
+}
+
+func (s *store) withPermissions(sess *db.Session, roles []accesscontrol.Role) ([]*accesscontrol.RoleDTO, error) {
+	result := make([]*accesscontrol.RoleDTO, 0, len(roles))
+	if len(roles) == 0 {
+		return result, nil
+	}
+
+	ids := make([]int64, 0, len(roles))
+	for _, r := range roles {
+		ids = append(ids, r.ID)
+	}
+
+	var permissions []accesscontrol.Permission
+	if err := sess.In("role_id", ids).Asc("action", "scope").Find(&permissions); err != nil {
+		return nil, err
+	}
+	byRole := map[int64][]accesscontrol.Permission{}
+	for _, p := range permissions {
+		byRole[p.RoleID] = append(byRole[p.RoleID], p)
+	}
+
+	for _, r := range roles {
+		result = append(result, &accesscontrol.RoleDTO{
+			ID:          r.ID,
+			OrgID:       r.OrgID,
+			Version:     r.Version,
+			UID:         r.UID,
+			Name:        r.Name,
+			DisplayName: r.DisplayName,
+			Description: r.Description,
+			Group:       r.Group,
+			Hidden:      r.Hidden,
+			Permissions: byRole[r.ID],
+			Created:     r.Created,
+			Updated:     r.Updated,
+		})
+	}
+	return result, nil
+}
+
+// getRole returns the custom role of the organization or the global custom role with the uid
+func getRole(sess *db.Session, orgID int64, uid string) (*accesscontrol.Role, error) {
+	var role accesscontrol.Role
+	has, err := sess.Where("uid = ? AND (org_id = ? OR org_id = ?) AND name LIKE ?", uid, orgID, accesscontrol.GlobalOrgID, accesscontrol.CustomRolePrefix+"%").
+		Get(&role)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, ErrRoleNotFound
+	}
+	return &role, nil
+}
+
+// checkRoleUnique ensures that the uid and name are unique among the roles of the organization and the global roles.
+// Global roles must be unique across all organizations since they are visible from all of them.
+func checkRoleUnique(sess *db.Session, orgID, roleID int64, uid, name string) error {
+	query := sess.Where("(uid = ? OR name = ?) AND id <> ?", uid, name, roleID)
+	if orgID != accesscontrol.GlobalOrgID {
+		query = query.And("(org_id = ? OR org_id = ?)", orgID, accesscontrol.GlobalOrgID)
+	}
+	has, err := query.Exist(&accesscontrol.Role{})
+	if err != nil {
+		return err
+	}
+	if has {
+		return ErrRoleAlreadyExists
+	}
+	return nil
+}
+
+func isAssigned(sess *db.Session, roleID int64) (bool, error) {
+	for _, assignment := range []any{&accesscontrol.UserRole{}, &accesscontrol.TeamRole{}, &accesscontrol.BuiltinRole{}} {
+		has, err := sess.Where("role_id = ?", roleID).Exist(assignment)
+		if err != nil || has {
+			return has, err
+		}
+	}
+	return false, nil
+}
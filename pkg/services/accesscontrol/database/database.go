@@ -53,6 +53,68 @@ func (s *AccessControlStore) GetUserPermissions(ctx context.Context, query acces
 	return result, err
 }
 
+// GetUserPermissionSources returns the stored permissions of the user along with the role granting them and
+// how the role is assigned: directly to the user, to one of the user's teams or to one of the user's basic roles
+func (s *AccessControlStore) GetUserPermissionSources(ctx context.Context, query accesscontrol.GetUserPermissionSourcesQuery) ([]accesscontrol.PermissionSource, error) {
+	result := make([]accesscontrol.PermissionSource, 0)
+	err := s.sql.WithDbSession(ctx, func(sess *db.Session) error {
+		q := `
+		SELECT
+			permission.action,
+			permission.scope,
+			role.name AS role_name,
+			role.uid AS role_uid,
+			assignment.source,
+			assignment.basic_role,
+			assignment.team_id
+		FROM permission
+		INNER JOIN role ON role.id = permission.role_id
+		INNER JOIN (
+			SELECT ur.role_id, 'user' AS source, '' AS basic_role, 0 AS team_id
+				FROM user_role AS ur
+				WHERE ur.user_id = ? AND (ur.org_id = ? OR ur.org_id = ?)
+			UNION ALL
+			SELECT tr.role_id, 'team' AS source, '' AS basic_role, tr.team_id
+				FROM team_role AS tr
+				INNER JOIN team_member AS tm ON tm.team_id = tr.team_id
+				WHERE tm.user_id = ? AND tr.org_id = ?
+		`
+		params := []any{query.UserID, query.OrgID, accesscontrol.GlobalOrgID, query.UserID, query.OrgID}
+
+		if len(query.Roles) > 0 {
+			q += `
+			UNION ALL
+			SELECT br.role_id, 'basic' AS source, br.role AS basic_role, 0 AS team_id
+				FROM builtin_role AS br
+				WHERE br.role IN (?` + strings.Repeat(", ?", len(query.Roles)-1) + `) AND (br.org_id = ? OR br.org_id = ?)
+			`
+			for _, role := range query.Roles {
+				params = append(params, role)
+			}
+			params = append(params, query.OrgID, accesscontrol.GlobalOrgID)
+		}
+		q += `) AS assignment ON assignment.role_id = role.id WHERE 1 = 1`
+
+		if query.Action != "" {
+			q += ` AND permission.action = ?`
+			params = append(params, query.Action)
+		}
+
+		if len(query.RolePrefixes) > 0 {
+			q += " AND ( " + strings.Repeat("role.name LIKE ? OR ", len(query.RolePrefixes))
+			q = q[:len(q)-4] + " )" // remove last " OR "
+			for i := range query.RolePrefixes {
+				params = append(params, query.RolePrefixes[i]+"%")
+			}
+		}
+		q += ` ORDER BY permission.action, permission.scope, role.name`
+
+		return sess.SQL(q, params...).Find(&result)
+	})
+
+	return result, err
+}
+
 // SearchUsersPermissions returns the list of user permissions indexed by UserID
 func (s *AccessControlStore) SearchUsersPermissions(ctx context.Context, orgID int64, options accesscontrol.SearchOptions) (map[int64][]accesscontrol.Permission, error) {
 	type UserRBACPermission struct {
@@ -154,6 +154,51 @@ func TestAccessControlStore_GetUserPermissions(t *testing.T) {
 	}
 }
 
+func TestAccessControlStore_GetUserPermissionSources(t *testing.T) {
+	store, permissionStore, sql, teamSvc, _ := setupTestEnv(t)
+	user, team := createUserAndTeam(t, sql, teamSvc, 1)
+
+	_, err := permissionStore.SetUserResourcePermission(context.Background(), 1, accesscontrol.User{ID: user.ID}, rs.SetResourcePermissionCommand{
+		Actions:    []string{"dashboards:read"},
+		Resource:   "dashboards",
+		ResourceID: "1",
+	}, nil)
+	require.NoError(t, err)
+	_, err = permissionStore.SetTeamResourcePermission(context.Background(), 1, team.ID, rs.SetResourcePermissionCommand{
+		Actions:    []string{"dashboards:read"},
+		Resource:   "dashboards",
+		ResourceID: "2",
+	}, nil)
+	require.NoError(t, err)
+	_, err = permissionStore.SetBuiltInResourcePermission(context.Background(), 1, "Viewer", rs.SetResourcePermissionCommand{
+		Actions:    []string{"dashboards:read", "dashboards:write"},
+		Resource:   "dashboards",
+		ResourceID: "3",
+	}, nil)
+	require.NoError(t, err)
+
+	sources, err := store.GetUserPermissionSources(context.Background(), accesscontrol.GetUserPermissionSourcesQuery{
+		OrgID:        1,
+		UserID:       user.ID,
+		Roles:        []string{"Viewer"},
+		Action:       "dashboards:read",
+		RolePrefixes: []string{accesscontrol.ManagedRolePrefix},
+	})
+	require.NoError(t, err)
+	require.Len(t, sources, 3)
+
+	bySource := map[string]accesscontrol.PermissionSource{}
+	for _, source := range sources {
+		assert.Equal(t, "dashboards:read", source.Action)
+		bySource[source.Source] = source
+	}
+	assert.Equal(t, "dashboards::1", bySource[accesscontrol.PermissionSourceUser].Scope)
+	assert.Equal(t, "dashboards::2", bySource[accesscontrol.PermissionSourceTeam].Scope)
+	assert.Equal(t, team.ID, bySource[accesscontrol.PermissionSourceTeam].TeamID)
+	assert.Equal(t, "dashboards::3", bySource[accesscontrol.PermissionSourceBasicRole].Scope)
+	assert.Equal(t, "Viewer", bySource[accesscontrol.PermissionSourceBasicRole].BasicRole)
+}
+
 func TestAccessControlStore_DeleteUserPermissions(t *testing.T) {
 	t.Run("expect permissions in all orgs to be deleted", func(t *testing.T) {
 		store, permissionsStore, sql, teamSvc, _ := setupTestEnv(t)
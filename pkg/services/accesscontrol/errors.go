@@ -13,6 +13,7 @@ var (
 	ErrResolverNotFound       = errors.New("no resolver found")
 	ErrPluginIDRequired       = errors.New("plugin ID is required")
 	ErrRoleNotFound           = errors.New("role not found")
+	ErrUserNotInOrg           = errors.New("user is not a member of the organization")
 )
 
 type ErrorInvalidRole struct{}
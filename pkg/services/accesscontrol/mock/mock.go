@@ -30,6 +30,7 @@ type Calls struct {
 	DeleteUserPermissions          []interface{}
 	SearchUsersPermissions         []interface{}
 	SearchUserPermissions          []interface{}
+	GetUserPermissionSources       []interface{}
 	SaveExternalServiceRole        []interface{}
 	DeleteExternalServiceRole      []interface{}
 }
@@ -55,6 +56,7 @@ type Mock struct {
 	DeleteUserPermissionsFunc          func(context.Context, int64) error
 	SearchUsersPermissionsFunc         func(context.Context, identity.Requester, int64, accesscontrol.SearchOptions) (map[int64][]accesscontrol.Permission, error)
 	SearchUserPermissionsFunc          func(ctx context.Context, orgID int64, searchOptions accesscontrol.SearchOptions) ([]accesscontrol.Permission, error)
+	GetUserPermissionSourcesFunc       func(ctx context.Context, orgID int64, searchOptions accesscontrol.SearchOptions) ([]accesscontrol.PermissionSource, error)
 	SaveExternalServiceRoleFunc        func(ctx context.Context, cmd accesscontrol.SaveExternalServiceRoleCommand) error
 	DeleteExternalServiceRoleFunc      func(ctx context.Context, externalServiceID string) error
 
@@ -218,6 +220,15 @@ func (m *Mock) SearchUserPermissions(ctx context.Context, orgID int64, searchOpt
 	return nil, nil
 }
 
+func (m *Mock) GetUserPermissionSources(ctx context.Context, orgID int64, searchOptions accesscontrol.SearchOptions) ([]accesscontrol.PermissionSource, error) {
+	m.Calls.GetUserPermissionSources = append(m.Calls.GetUserPermissionSources, []interface{}{ctx, orgID, searchOptions})
+	// Use override if provided
+	if m.GetUserPermissionSourcesFunc != nil {
+		return m.GetUserPermissionSourcesFunc(ctx, orgID, searchOptions)
+	}
+	return nil, nil
+}
+
 func (m *Mock) SaveExternalServiceRole(ctx context.Context, cmd accesscontrol.SaveExternalServiceRoleCommand) error {
 	m.Calls.SaveExternalServiceRole = append(m.Calls.SaveExternalServiceRole, []interface{}{ctx, cmd})
 	// Use override if provided
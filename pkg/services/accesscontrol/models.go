@@ -131,6 +131,10 @@ func (r *RoleDTO) IsBasic() bool {
 	return strings.HasPrefix(r.Name, BasicRolePrefix) || strings.HasPrefix(r.UID, BasicRoleUIDPrefix)
 }
 
+func (r *RoleDTO) IsCustom() bool {
+	return strings.HasPrefix(r.Name, CustomRolePrefix)
+}
+
 func (r *RoleDTO) IsExternalService() bool {
 	return strings.HasPrefix(r.Name, ExternalServiceRolePrefix) || strings.HasPrefix(r.UID, ExternalServiceRoleUIDPrefix)
 }
@@ -214,6 +218,27 @@ func (p Permission) SplitScope() (string, string, string) {
 	}
 }
 
+const (
+	// PermissionSourceBasicRole is a permission granted through the basic role of the user
+	PermissionSourceBasicRole = "basic"
+	// PermissionSourceUser is a permission granted through a role assigned to the user
+	PermissionSourceUser = "user"
+	// PermissionSourceTeam is a permission granted through a role assigned to a team of the user
+	PermissionSourceTeam = "team"
+)
+
+// PermissionSource is a permission along with the role granting it and how the role is assigned to the user
+type PermissionSource struct {
+	Action   string `json:"action"`
+	Scope    string `json:"scope"`
+	RoleName string `json:"roleName" xorm:"role_name"`
+	RoleUID  string `json:"roleUid,omitempty" xorm:"role_uid"`
+	// Source is one of PermissionSourceBasicRole, PermissionSourceUser or PermissionSourceTeam
+	Source    string `json:"source"`
+	BasicRole string `json:"basicRole,omitempty" xorm:"basic_role"`
+	TeamID    int64  `json:"teamId,omitempty" xorm:"team_id"`
+}
+
 type GetUserPermissionsQuery struct {
 	OrgID        int64
 	UserID       int64
@@ -222,6 +247,14 @@ type GetUserPermissionsQuery struct {
 	RolePrefixes []string
 }
 
+type GetUserPermissionSourcesQuery struct {
+	OrgID        int64
+	UserID       int64
+	Roles        []string
+	Action       string
+	RolePrefixes []string
+}
+
 // ResourcePermission is structure that holds all actions that either a team / user / builtin-role
 // can perform against specific resource.
 type ResourcePermission struct {
@@ -324,6 +357,7 @@ const (
 	BasicRolePrefix              = "basic:"
 	PluginRolePrefix             = "plugins:"
 	ExternalServiceRolePrefix    = "externalservice:"
+	CustomRolePrefix             = "custom:"
 	BasicRoleUIDPrefix           = "basic_"
 	ExternalServiceRoleUIDPrefix = "externalservice_"
 	RoleGrafanaAdmin             = "Grafana Admin"
@@ -463,6 +497,22 @@ const (
 	ActionAlertingProvisioningReadSecrets = "alert.provisioning.secrets:read"
 	ActionAlertingProvisioningWrite       = "alert.provisioning:write"
 
+	// Custom roles actions
+	ActionRolesRead   = "roles:read"
+	ActionRolesWrite  = "roles:write"
+	ActionRolesDelete = "roles:delete"
+
+	// Role assignments actions
+	ActionUsersRolesRead   = "users.roles:read"
+	ActionUsersRolesAdd    = "users.roles:add"
+	ActionUsersRolesRemove = "users.roles:remove"
+	ActionTeamsRolesRead   = "teams.roles:read"
+	ActionTeamsRolesAdd    = "teams.roles:add"
+	ActionTeamsRolesRemove = "teams.roles:remove"
+
+	// Custom roles scope
+	ScopeRolesAll = "roles:*"
+
 	// Feature Management actions
 	ActionFeatureManagementRead  = "featuremgmt.read"
 	ActionFeatureManagementWrite = "featuremgmt.write"
@@ -472,6 +522,9 @@ var (
 	// Team scope
 	ScopeTeamsID = Scope("teams", "id", Parameter(":teamId"))
 
+	// Custom roles scopes
+	ScopeRolesProvider = NewScopeProvider("roles")
+
 	// Annotation scopes
 	ScopeAnnotationsRoot             = "annotations"
 	ScopeAnnotationsProvider         = NewScopeProvider(ScopeAnnotationsRoot)
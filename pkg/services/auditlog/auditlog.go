@@ -0,0 +1,106 @@
+package auditlog
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Service records security relevant actions (API changes, logins) and allows searching them.
+type Service interface {
+	// Record queues the event to be written to the configured sinks.
+	// It never blocks the caller, events are dropped when the queue is full.
+	Record(ctx context.Context, event *Event)
+	// Search returns the events matching the query, most recent first.
+	Search(ctx context.Context, query *SearchQuery) (*SearchResult, error)
+}
+
+type Result string
+
+const (
+	ResultSuccess Result = "success"
+	ResultFailure Result = "failure"
+)
+
+const (
+	ActionLogin = "login"
+)
+
+// Event is a single entry of the audit log.
+type Event struct {
+	ID      int64     `json:"id"`
+	OrgID   int64     `json:"orgId"`
+	Created time.Time `json:"created"`
+	// ActorID is the namespaced ID of the identity performing the action, ex: user:1, service-account:2
+	ActorID    string `json:"actorId"`
+	ActorLogin string `json:"actorLogin"`
+	// Action is either a well known action (ex: login) or the method and route of the HTTP request,
+	// ex: POST /api/dashboards/db
+	Action       string `json:"action"`
+	ResourceKind string `json:"resourceKind,omitempty"`
+	ResourceID   string `json:"resourceId,omitempty"`
+	// Before and After summarize the state of the resource around the action, secrets are never included
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	IPAddress  string          `json:"ipAddress"`
+	Result     Result          `json:"result"`
+	StatusCode int             `json:"statusCode,omitempty"`
+}
+
+type SearchQuery struct {
+	OrgID        int64
+	ActorLogin   string
+	Action       string
+	ResourceKind string
+	ResourceID   string
+	Result       Result
+	From         time.Time
+	To           time.Time
+	Page         int
+	PerPage      int
+}
+
+type SearchResult struct {
+	TotalCount int64    `json:"totalCount"`
+	Events     []*Event `json:"events"`
+	Page       int      `json:"page"`
+	PerPage    int      `json:"perPage"`
+}
+
+type eventContextKey struct{}
+
+// WithEvent returns a context carrying the event of the request being audited,
+// allowing handlers to complete it with SetResource and SetChange.
+func WithEvent(ctx context.Context, event *Event) context.Context {
+	return context.WithValue(ctx, eventContextKey{}, event)
+}
+
+// EventFromContext returns the event of the request being audited, or nil if the request is not audited.
+func EventFromContext(ctx context.Context) *Event {
+	event, _ := ctx.Value(eventContextKey{}).(*Event)
+	return event
+}
+
+// SetResource records the resource affected by the request being audited.
+// It overrides the resource derived from the route. It is a noop when the request is not audited.
+func SetResource(ctx context.Context, kind, id string) {
+	if event := EventFromContext(ctx); event != nil {
+		event.ResourceKind = kind
+		event.ResourceID = id
+	}
+}
+
+// SetChange records a summary of the state of the resource before and after the request being audited.
+// Summaries must not contain secrets. It is a noop when the request is not audited.
+func SetChange(ctx context.Context, before, after any) {
+	event := EventFromContext(ctx)
+	if event == nil {
+		return
+	}
+	if before != nil {
+		event.Before, _ = json.Marshal(before)
+	}
+	if after != nil {
+		event.After, _ = json.Marshal(after)
+	}
+}
@@ -0,0 +1,46 @@
+package auditlogimpl
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/auditlog"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+)
+
+func (s *Service) registerAPIEndpoints(routeRegister routing.RouteRegister) {
+	authorize := ac.Middleware(s.accessControl)
+
+	routeRegister.Group("/api/admin/audit-logs", func(subrouter routing.RouteRegister) {
+		subrouter.Get("/", authorize(ac.EvalPermission(ActionRead)), routing.Wrap(s.handleSearch))
+	})
+}
+
+// handleSearch searches the audit log events, filters are optional and from / to are epoch milliseconds
+func (s *Service) handleSearch(c *contextmodel.ReqContext) response.Response {
+	query := &auditlog.SearchQuery{
+		OrgID:        c.QueryInt64("orgId"),
+		ActorLogin:   c.Query("actor"),
+		Action:       c.Query("action"),
+		ResourceKind: c.Query("resourceKind"),
+		ResourceID:   c.Query("resourceId"),
+		Result:       auditlog.Result(c.Query("result")),
+		Page:         c.QueryInt("page"),
+		PerPage:      c.QueryInt("perpage"),
+	}
+	if from := c.QueryInt64("from"); from > 0 {
+		query.From = time.UnixMilli(from)
+	}
+	if to := c.QueryInt64("to"); to > 0 {
+		query.To = time.UnixMilli(to)
+	}
+
+	result, err := s.Search(c.Req.Context(), query)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "failed to search audit log", err)
+	}
+	return response.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,155 @@
+package auditlogimpl
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/middleware"
+	"github.com/grafana/grafana/pkg/services/auditlog"
+	"github.com/grafana/grafana/pkg/services/auth/identity"
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/contexthandler"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+// ignoredPaths are API paths that do not change any state despite their method, ex: data source queries
+var ignoredPaths = []string{
+	"/api/ds/query",
+	"/api/tsdb/",
+	"/api/datasources/proxy/",
+	"/api/frontend-metrics",
+	"/api/frontend/",
+	"/api/live/",
+	"/api/search-v2",
+	"/api/user/helpflags",
+}
+
+func isAudited(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return false
+	}
+
+	if !strings.HasPrefix(r.URL.Path, "/api/") {
+		return false
+	}
+	for _, path := range ignoredPaths {
+		if strings.HasPrefix(r.URL.Path, path) {
+			return false
+		}
+	}
+	// plugin and data source resources are not Grafana resources
+	return !strings.Contains(r.URL.Path, "/resources")
+}
+
+// middleware records an event for each API request changing the state of Grafana
+func (s *Service) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isAudited(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		event := &auditlog.Event{}
+		// the request is shared with the context handler, handlers can complete the event from the request context
+		*r = *r.WithContext(auditlog.WithEvent(r.Context(), event))
+		next.ServeHTTP(w, r)
+
+		c := contexthandler.FromContext(r.Context())
+		if c == nil {
+			return
+		}
+		s.completeRequestEvent(c, event)
+		s.Record(r.Context(), event)
+	})
+}
+
+func (s *Service) completeRequestEvent(c *contextmodel.ReqContext, event *auditlog.Event) {
+	event.Created = s.now()
+	event.IPAddress = c.RemoteAddr()
+	event.StatusCode = c.Resp.Status()
+	event.Result = auditlog.ResultSuccess
+	if event.StatusCode >= http.StatusBadRequest {
+		event.Result = auditlog.ResultFailure
+	}
+
+	if c.SignedInUser != nil && !c.SignedInUser.IsNil() {
+		event.OrgID = c.SignedInUser.GetOrgID()
+		event.ActorID = namespacedID(c.SignedInUser)
+		event.ActorLogin = c.SignedInUser.GetLogin()
+	}
+
+	route, ok := middleware.RouteOperationName(c.Req)
+	if !ok {
+		route = c.Req.URL.Path
+	}
+	if event.Action == "" {
+		event.Action = c.Req.Method + " " + route
+	}
+	if event.ResourceKind == "" {
+		event.ResourceKind, event.ResourceID = resourceFromRoute(route, web.Params(c.Req))
+	}
+}
+
+// resourceFromRoute derives the resource from the route of the request: the kind is the first segment of the route
+// and the ID is the value of the first route parameter, ex: /api/dashboards/uid/:uid.
+func resourceFromRoute(route string, params map[string]string) (string, string) {
+	segments := strings.Split(strings.TrimPrefix(route, "/api/"), "/")
+	// administration routes act on the resource that follows
+	if segments[0] == "admin" && len(segments) > 1 {
+		segments = segments[1:]
+	}
+
+	kind, id := segments[0], ""
+	for _, segment := range segments[1:] {
+		if strings.HasPrefix(segment, ":") {
+			id = params[segment]
+			break
+		}
+	}
+	return kind, id
+}
+
+// loginHook records an event for each login attempt, successful or not
+func (s *Service) loginHook(ctx context.Context, ident *authn.Identity, r *authn.Request, err error) {
+	event := &auditlog.Event{
+		Created:      s.now(),
+		Action:       auditlog.ActionLogin,
+		ResourceKind: "users",
+		Result:       auditlog.ResultSuccess,
+		StatusCode:   http.StatusOK,
+	}
+	if r != nil && r.HTTPRequest != nil {
+		// the client IP address is resolved the same way as for the other events
+		if c := contexthandler.FromContext(r.HTTPRequest.Context()); c != nil {
+			event.IPAddress = c.RemoteAddr()
+		} else {
+			event.IPAddress = web.RemoteAddr(r.HTTPRequest)
+		}
+	}
+	if err != nil {
+		event.Result = auditlog.ResultFailure
+		event.StatusCode = http.StatusUnauthorized
+	}
+	if ident != nil {
+		event.OrgID = ident.OrgID
+		event.ActorID = ident.ID
+		event.ActorLogin = ident.Login
+		if _, id := ident.NamespacedID(); id > 0 {
+			event.ResourceID = strconv.FormatInt(id, 10)
+		}
+	}
+	s.Record(ctx, event)
+}
+
+func namespacedID(requester identity.Requester) string {
+	namespace, id := requester.GetNamespacedID()
+	if id == "" {
+		return namespace
+	}
+	return namespace + ":" + id
+}
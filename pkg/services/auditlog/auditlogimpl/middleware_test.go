@@ -0,0 +1,142 @@
+package auditlogimpl
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/auditlog"
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/contexthandler/ctxkey"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+func TestIsAudited(t *testing.T) {
+	tests := []struct {
+		method   string
+		path     string
+		expected bool
+	}{
+		{method: http.MethodPost, path: "/api/dashboards/db", expected: true},
+		{method: http.MethodPut, path: "/api/datasources/uid/abc", expected: true},
+		{method: http.MethodDelete, path: "/api/admin/users/2", expected: true},
+		{method: http.MethodPatch, path: "/api/org/users/2", expected: true},
+		{method: http.MethodGet, path: "/api/dashboards/uid/abc", expected: false},
+		{method: http.MethodPost, path: "/login", expected: false},
+		{method: http.MethodPost, path: "/api/ds/query", expected: false},
+		{method: http.MethodPost, path: "/api/datasources/proxy/uid/abc/api/v1/query", expected: false},
+		{method: http.MethodPost, path: "/api/datasources/uid/abc/resources/query", expected: false},
+		{method: http.MethodPost, path: "/api/frontend-metrics", expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isAudited(httptest.NewRequest(tt.method, tt.path, nil)))
+		})
+	}
+}
+
+func TestResourceFromRoute(t *testing.T) {
+	tests := []struct {
+		route        string
+		params       map[string]string
+		expectedKind string
+		expectedID   string
+	}{
+		{route: "/api/dashboards/db/", expectedKind: "dashboards"},
+		{route: "/api/dashboards/uid/:uid", params: map[string]string{":uid": "abc"}, expectedKind: "dashboards", expectedID: "abc"},
+		{route: "/api/admin/users/:id/password", params: map[string]string{":id": "2"}, expectedKind: "users", expectedID: "2"},
+		{route: "/api/teams/:teamId/members/:userId", params: map[string]string{":teamId": "3", ":userId": "2"}, expectedKind: "teams", expectedID: "3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.route, func(t *testing.T) {
+			kind, id := resourceFromRoute(tt.route, tt.params)
+			assert.Equal(t, tt.expectedKind, kind)
+			assert.Equal(t, tt.expectedID, id)
+		})
+	}
+}
+
+func TestService_CompleteRequestEvent(t *testing.T) {
+	now := time.Date(2023, 10, 22, 8, 0, 0, 0, time.UTC)
+	s := &Service{log: log.NewNopLogger(), now: func() time.Time { return now }}
+
+	newReqContext := func(status int) *contextmodel.ReqContext {
+		req := httptest.NewRequest(http.MethodDelete, "/api/datasources/uid/abc", nil)
+		req = web.SetURLParams(req, map[string]string{":uid": "abc"})
+		req.RemoteAddr = "10.0.0.1:1234"
+		resp := web.NewResponseWriter(req.Method, httptest.NewRecorder())
+		resp.WriteHeader(status)
+		return &contextmodel.ReqContext{
+			Context:      &web.Context{Req: req, Resp: resp},
+			SignedInUser: &user.SignedInUser{UserID: 2, OrgID: 1, Login: "editor"},
+		}
+	}
+
+	t.Run("should derive the event from the request", func(t *testing.T) {
+		event := &auditlog.Event{}
+		s.completeRequestEvent(newReqContext(http.StatusOK), event)
+
+		assert.Equal(t, &auditlog.Event{
+			OrgID:        1,
+			Created:      now,
+			ActorID:      "user:2",
+			ActorLogin:   "editor",
+			Action:       "DELETE /api/datasources/uid/abc",
+			ResourceKind: "datasources",
+			IPAddress:    "10.0.0.1",
+			Result:       auditlog.ResultSuccess,
+			StatusCode:   http.StatusOK,
+		}, event)
+	})
+
+	t.Run("should keep the resource set by the handler and record failures", func(t *testing.T) {
+		event := &auditlog.Event{ResourceKind: "datasources", ResourceID: "abc"}
+		s.completeRequestEvent(newReqContext(http.StatusForbidden), event)
+
+		assert.Equal(t, "abc", event.ResourceID)
+		assert.Equal(t, auditlog.ResultFailure, event.Result)
+		assert.Equal(t, http.StatusForbidden, event.StatusCode)
+	})
+}
+
+func TestService_LoginHook(t *testing.T) {
+	s := &Service{log: log.NewNopLogger(), now: time.Now, enabled: true, events: make(chan *auditlog.Event, 2)}
+	req := &authn.Request{HTTPRequest: httptest.NewRequest(http.MethodPost, "/login", nil)}
+
+	s.loginHook(req.HTTPRequest.Context(), &authn.Identity{ID: "user:2", OrgID: 1, Login: "editor"}, req, nil)
+	s.loginHook(req.HTTPRequest.Context(), nil, req, errors.New("invalid username or password"))
+
+	success := <-s.events
+	assert.Equal(t, auditlog.ActionLogin, success.Action)
+	assert.Equal(t, auditlog.ResultSuccess, success.Result)
+	assert.Equal(t, "user:2", success.ActorID)
+	assert.Equal(t, "2", success.ResourceID)
+	assert.Equal(t, "192.0.2.1", success.IPAddress)
+
+	failure := <-s.events
+	require.Equal(t, auditlog.ResultFailure, failure.Result)
+	assert.Equal(t, http.StatusUnauthorized, failure.StatusCode)
+	assert.Empty(t, failure.ActorID)
+}
+
+func TestService_LoginHookUsesContextRemoteAddr(t *testing.T) {
+	s := &Service{log: log.NewNopLogger(), now: time.Now, enabled: true, events: make(chan *auditlog.Event, 1)}
+	ctxReq := httptest.NewRequest(http.MethodPost, "/login", nil)
+	ctxReq.RemoteAddr = "10.0.0.1:1234"
+	reqContext := &contextmodel.ReqContext{Context: &web.Context{Req: ctxReq}}
+	httpReq := httptest.NewRequest(http.MethodPost, "/login", nil)
+	req := &authn.Request{HTTPRequest: httpReq.WithContext(ctxkey.Set(httpReq.Context(), reqContext))}
+
+	s.loginHook(req.HTTPRequest.Context(), &authn.Identity{ID: "user:2", OrgID: 1, Login: "editor"}, req, nil)
+
+	event := <-s.events
+	assert.Equal(t, "10.0.0.1", event.IPAddress)
+}
@@ -0,0 +1,26 @@
+package auditlogimpl
+
+import (
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+)
+
+const (
+	ActionRead = "auditlogs:read"
+)
+
+var auditLogReaderRole = accesscontrol.RoleDTO{
+	Name:        "fixed:auditlogs:reader",
+	DisplayName: "Audit log reader",
+	Description: "Search the audit log of all organizations",
+	Group:       "Audit log",
+	Permissions: []accesscontrol.Permission{
+		{Action: ActionRead},
+	},
+}
+
+func declareFixedRoles(service accesscontrol.Service) error {
+	return service.DeclareFixedRoles(accesscontrol.RoleRegistration{
+		Role:   auditLogReaderRole,
+		Grants: []string{accesscontrol.RoleGrafanaAdmin},
+	})
+}
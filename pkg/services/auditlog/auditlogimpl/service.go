@@ -0,0 +1,271 @@
+package auditlogimpl
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
+
+	grafanaApi "github.com/grafana/grafana/pkg/api"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/serverlock"
+	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/auditlog"
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util/errutil"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+const (
+	queueSize         = 10000
+	batchSize         = 100
+	flushInterval     = time.Second
+	flushTimeout      = 10 * time.Second
+	retentionInterval = time.Hour
+)
+
+var errSearchUnavailable = errutil.BadRequest("auditlog.searchUnavailable").
+	Errorf("audit log search requires the sql sink")
+
+var _ auditlog.Service = (*Service)(nil)
+
+type Service struct {
+	log           log.Logger
+	accessControl ac.AccessControl
+	serverLock    *serverlock.ServerLockService
+	store         store
+	sinks         []sink
+	events        chan *auditlog.Event
+	now           func() time.Time
+
+	enabled    bool
+	searchable bool
+	retention  time.Duration
+}
+
+type settings struct {
+	enabled           bool
+	sinks             []string
+	filePath          string
+	lokiURL           string
+	lokiTenantID      string
+	lokiBasicAuthUser string
+	lokiBasicAuthPass string
+	retention         time.Duration
+}
+
+func readSettings(cfg *setting.Cfg) (settings, error) {
+	section := cfg.SectionWithEnvOverrides("audit_log")
+	s := settings{
+		enabled:           section.Key("enabled").MustBool(false),
+		filePath:          section.Key("file_path").MustString(filepath.Join(cfg.LogsPath, "audit.log")),
+		lokiURL:           section.Key("loki_url").MustString(""),
+		lokiTenantID:      section.Key("loki_tenant_id").MustString(""),
+		lokiBasicAuthUser: section.Key("loki_basic_auth_user").MustString(""),
+		lokiBasicAuthPass: section.Key("loki_basic_auth_password").MustString(""),
+	}
+	for _, name := range strings.Split(section.Key("sinks").MustString(sinkSQL), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			s.sinks = append(s.sinks, name)
+		}
+	}
+
+	retention, err := gtime.ParseDuration(section.Key("retention").MustString("90d"))
+	if err != nil {
+		return s, err
+	}
+	s.retention = retention
+	return s, nil
+}
+
+func ProvideService(cfg *setting.Cfg, sqlStore db.DB, accessControl ac.AccessControl, acService ac.Service,
+	authnService authn.Service, httpServer *grafanaApi.HTTPServer, routeRegister routing.RouteRegister,
+	serverLockService *serverlock.ServerLockService) (*Service, error) {
+	settings, err := readSettings(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Service{
+		log:           log.New("auditlog"),
+		accessControl: accessControl,
+		serverLock:    serverLockService,
+		store:         &xormStore{db: sqlStore},
+		events:        make(chan *auditlog.Event, queueSize),
+		now:           time.Now,
+		enabled:       settings.enabled,
+		retention:     settings.retention,
+	}
+	if !s.enabled {
+		return s, nil
+	}
+
+	for _, name := range settings.sinks {
+		switch name {
+		case sinkSQL:
+			s.sinks = append(s.sinks, &sqlSink{store: s.store})
+			s.searchable = true
+		case sinkFile:
+			fileSink, err := newFileSink(settings.filePath)
+			if err != nil {
+				return nil, err
+			}
+			s.sinks = append(s.sinks, fileSink)
+		case sinkLoki:
+			if settings.lokiURL == "" {
+				return nil, errors.New("audit log loki sink requires loki_url")
+			}
+			s.sinks = append(s.sinks, newLokiSink(settings.lokiURL, settings.lokiTenantID, settings.lokiBasicAuthUser, settings.lokiBasicAuthPass))
+		default:
+			return nil, errors.New("unknown audit log sink: " + name)
+		}
+	}
+
+	if err := declareFixedRoles(acService); err != nil {
+		return nil, err
+	}
+
+	httpServer.AddMiddleware(web.Middleware(s.middleware))
+	authnService.RegisterPostLoginHook(s.loginHook, 200)
+	s.registerAPIEndpoints(routeRegister)
+
+	return s, nil
+}
+
+func (s *Service) IsDisabled() bool {
+	return !s.enabled
+}
+
+// Run writes the recorded events to the sinks in batches and deletes the events past retention
+func (s *Service) Run(ctx context.Context) error {
+	flushTicker := time.NewTicker(flushInterval)
+	defer flushTicker.Stop()
+	retentionTicker := time.NewTicker(retentionInterval)
+	defer retentionTicker.Stop()
+
+	s.deleteExpiredEvents(ctx)
+
+	batch := make([]*auditlog.Event, 0, batchSize)
+	for {
+		select {
+		case event := <-s.events:
+			batch = append(batch, event)
+			if len(batch) >= batchSize {
+				s.write(ctx, batch)
+				batch = batch[:0]
+			}
+		case <-flushTicker.C:
+			if len(batch) > 0 {
+				s.write(ctx, batch)
+				batch = batch[:0]
+			}
+		case <-retentionTicker.C:
+			s.deleteExpiredEvents(ctx)
+		case <-ctx.Done():
+			// write the events queued before shutdown
+			flushCtx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+			defer cancel()
+			s.write(flushCtx, append(batch, s.drain()...))
+			s.close()
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *Service) Record(_ context.Context, event *auditlog.Event) {
+	if !s.enabled {
+		return
+	}
+	if event.Created.IsZero() {
+		event.Created = s.now()
+	}
+
+	select {
+	case s.events <- event:
+	default:
+		s.log.Warn("Audit log queue is full, dropping event", "action", event.Action, "actor", event.ActorID)
+	}
+}
+
+func (s *Service) Search(ctx context.Context, query *auditlog.SearchQuery) (*auditlog.SearchResult, error) {
+	if !s.searchable {
+		return nil, errSearchUnavailable
+	}
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	if query.PerPage <= 0 || query.PerPage > 1000 {
+		query.PerPage = 100
+	}
+	return s.store.Search(ctx, query)
+}
+
+// drain returns the events remaining in the queue without blocking
+func (s *Service) drain() []*auditlog.Event {
+	var events []*auditlog.Event
+	for {
+		select {
+		case event := <-s.events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func (s *Service) write(ctx context.Context, events []*auditlog.Event) {
+	if len(events) == 0 {
+		return
+	}
+	for _, sink := range s.sinks {
+		if err := sink.Write(ctx, events); err != nil {
+			s.log.Error("Failed to write audit log events", "sink", sinkName(sink), "count", len(events), "error", err)
+		}
+	}
+}
+
+func (s *Service) close() {
+	for _, sink := range s.sinks {
+		if err := sink.Close(); err != nil {
+			s.log.Warn("Failed to close audit log sink", "sink", sinkName(sink), "error", err)
+		}
+	}
+}
+
+func (s *Service) deleteExpiredEvents(ctx context.Context) {
+	if !s.searchable || s.retention <= 0 {
+		return
+	}
+	// the events are shared by all the instances, a single one deletes them
+	err := s.serverLock.LockAndExecute(ctx, "delete expired audit log events", retentionInterval, func(ctx context.Context) {
+		deleted, err := s.store.DeleteOlderThan(ctx, s.now().Add(-s.retention))
+		if err != nil {
+			s.log.Error("Failed to delete expired audit log events", "error", err)
+			return
+		}
+		if deleted > 0 {
+			s.log.Debug("Deleted expired audit log events", "count", deleted)
+		}
+	})
+	if err != nil {
+		s.log.Error("Failed to lock and execute the deletion of expired audit log events", "error", err)
+	}
+}
+
+func sinkName(s sink) string {
+	switch s.(type) {
+	case *sqlSink:
+		return sinkSQL
+	case *fileSink:
+		return sinkFile
+	case *lokiSink:
+		return sinkLoki
+	}
+	return "unknown"
+}
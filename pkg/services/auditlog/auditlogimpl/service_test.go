@@ -0,0 +1,134 @@
+package auditlogimpl
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/serverlock"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/auditlog"
+)
+
+type fakeSink struct {
+	mu     sync.Mutex
+	events []*auditlog.Event
+	closed bool
+}
+
+func (f *fakeSink) Write(_ context.Context, events []*auditlog.Event) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, events...)
+	return nil
+}
+
+func (f *fakeSink) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+func TestService_Run(t *testing.T) {
+	fake := &fakeSink{}
+	s := &Service{
+		log:     log.NewNopLogger(),
+		sinks:   []sink{fake},
+		events:  make(chan *auditlog.Event, queueSize),
+		now:     time.Now,
+		enabled: true,
+	}
+
+	for i := 0; i < batchSize+1; i++ {
+		s.Record(context.Background(), &auditlog.Event{Action: auditlog.ActionLogin})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	// the full batch is written right away
+	require.Eventually(t, func() bool {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		return len(fake.events) >= batchSize
+	}, time.Second, 10*time.Millisecond)
+
+	// the remaining events are written on shutdown
+	s.Record(context.Background(), &auditlog.Event{Action: auditlog.ActionLogin})
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+
+	assert.Len(t, fake.events, batchSize+2)
+	assert.True(t, fake.closed)
+	assert.False(t, fake.events[0].Created.IsZero())
+}
+
+func TestService_Record(t *testing.T) {
+	t.Run("should ignore events when disabled", func(t *testing.T) {
+		s := &Service{log: log.NewNopLogger(), events: make(chan *auditlog.Event, 1), now: time.Now}
+		s.Record(context.Background(), &auditlog.Event{})
+		assert.Len(t, s.events, 0)
+	})
+
+	t.Run("should drop events when the queue is full", func(t *testing.T) {
+		s := &Service{log: log.NewNopLogger(), events: make(chan *auditlog.Event, 1), now: time.Now, enabled: true}
+		s.Record(context.Background(), &auditlog.Event{Action: "first"})
+		s.Record(context.Background(), &auditlog.Event{Action: "second"})
+		require.Len(t, s.events, 1)
+		assert.Equal(t, "first", (<-s.events).Action)
+	})
+}
+
+func TestService_Search(t *testing.T) {
+	s := &Service{log: log.NewNopLogger()}
+	_, err := s.Search(context.Background(), &auditlog.SearchQuery{})
+	assert.ErrorIs(t, err, errSearchUnavailable)
+}
+
+func TestIntegrationService_DeleteExpiredEvents(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	now := time.Now()
+	sqlStore := db.InitTestDB(t)
+	s := &Service{
+		log:        log.NewNopLogger(),
+		serverLock: serverlock.ProvideService(sqlStore, tracing.InitializeTracerForTest()),
+		store:      &xormStore{db: sqlStore},
+		now:        func() time.Time { return now },
+		enabled:    true,
+		searchable: true,
+		retention:  24 * time.Hour,
+	}
+	require.NoError(t, s.store.Insert(context.Background(), []*auditlog.Event{
+		{OrgID: 1, Created: now.Add(-48 * time.Hour), Action: auditlog.ActionLogin, ResourceKind: "users", ResourceID: "1"},
+		{OrgID: 1, Created: now.Add(-time.Hour), Action: auditlog.ActionLogin, ResourceKind: "users", ResourceID: "2"},
+	}))
+
+	s.deleteExpiredEvents(context.Background())
+
+	res, err := s.store.Search(context.Background(), &auditlog.SearchQuery{PerPage: 10})
+	require.NoError(t, err)
+	require.Len(t, res.Events, 1)
+	assert.Equal(t, "2", res.Events[0].ResourceID)
+
+	// another instance does not delete the events again within the interval
+	require.NoError(t, s.store.Insert(context.Background(), []*auditlog.Event{
+		{OrgID: 1, Created: now.Add(-48 * time.Hour), Action: auditlog.ActionLogin, ResourceKind: "users", ResourceID: "3"},
+	}))
+	other := *s
+	other.deleteExpiredEvents(context.Background())
+
+	res, err = s.store.Search(context.Background(), &auditlog.SearchQuery{PerPage: 10})
+	require.NoError(t, err)
+	require.Len(t, res.Events, 2)
+}
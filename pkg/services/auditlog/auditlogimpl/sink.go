@@ -0,0 +1,175 @@
+package auditlogimpl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/auditlog"
+)
+
+const (
+	sinkSQL  = "sql"
+	sinkFile = "file"
+	sinkLoki = "loki"
+)
+
+// sink is a destination events are written to
+type sink interface {
+	Write(ctx context.Context, events []*auditlog.Event) error
+	Close() error
+}
+
+type sqlSink struct {
+	store store
+}
+
+func (s *sqlSink) Write(ctx context.Context, events []*auditlog.Event) error {
+	return s.store.Insert(ctx, events)
+}
+
+func (s *sqlSink) Close() error {
+	return nil
+}
+
+// fileSink appends events to a file in the JSON lines format
+type fileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func newFileSink(path string) (*fileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return nil, err
+	}
+	// nolint:gosec
+	// We can ignore the gosec G304 warning since the path comes from the configuration
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSink{file: file}, nil
+}
+
+func (s *fileSink) Write(_ context.Context, events []*auditlog.Event) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.file.Write(buf.Bytes())
+	return err
+}
+
+func (s *fileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// lokiSink pushes events to Loki, one stream per organization and result
+type lokiSink struct {
+	client   *http.Client
+	url      string
+	tenantID string
+	user     string
+	password string
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+type lokiPushRequest struct {
+	Streams []*lokiStream `json:"streams"`
+}
+
+func newLokiSink(url, tenantID, user, password string) *lokiSink {
+	return &lokiSink{
+		client:   &http.Client{Timeout: 10 * time.Second},
+		url:      strings.TrimSuffix(url, "/") + "/loki/api/v1/push",
+		tenantID: tenantID,
+		user:     user,
+		password: password,
+	}
+}
+
+func (s *lokiSink) Write(ctx context.Context, events []*auditlog.Event) error {
+	streams := map[string]*lokiStream{}
+	for _, event := range events {
+		line, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+
+		// labels are kept to a low cardinality, the other fields are part of the log line
+		orgID := strconv.FormatInt(event.OrgID, 10)
+		key := orgID + "/" + string(event.Result)
+		stream, ok := streams[key]
+		if !ok {
+			stream = &lokiStream{Stream: map[string]string{
+				"job":    "grafana-audit",
+				"org_id": orgID,
+				"result": string(event.Result),
+			}}
+			streams[key] = stream
+		}
+		stream.Values = append(stream.Values, [2]string{strconv.FormatInt(event.Created.UnixNano(), 10), string(line)})
+	}
+
+	push := lokiPushRequest{Streams: make([]*lokiStream, 0, len(streams))}
+	for _, stream := range streams {
+		push.Streams = append(push.Streams, stream)
+	}
+	// make the request deterministic
+	sort.Slice(push.Streams, func(i, j int) bool {
+		return push.Streams[i].Stream["org_id"]+push.Streams[i].Stream["result"] < push.Streams[j].Stream["org_id"]+push.Streams[j].Stream["result"]
+	})
+
+	body, err := json.Marshal(push)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.tenantID != "" {
+		req.Header.Set("X-Scope-OrgID", s.tenantID)
+	}
+	if s.user != "" {
+		req.SetBasicAuth(s.user, s.password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("loki push failed with status %d: %s", resp.StatusCode, msg)
+	}
+	return nil
+}
+
+func (s *lokiSink) Close() error {
+	return nil
+}
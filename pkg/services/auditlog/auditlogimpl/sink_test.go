@@ -0,0 +1,94 @@
+package auditlogimpl
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/auditlog"
+)
+
+func testEvents() []*auditlog.Event {
+	created := time.Date(2023, 10, 22, 8, 0, 0, 0, time.UTC)
+	return []*auditlog.Event{
+		{OrgID: 1, Created: created, ActorLogin: "admin", Action: auditlog.ActionLogin, Result: auditlog.ResultSuccess},
+		{OrgID: 1, Created: created, ActorLogin: "admin", Action: auditlog.ActionLogin, Result: auditlog.ResultFailure},
+		{OrgID: 2, Created: created, ActorLogin: "editor", Action: "DELETE /api/dashboards/uid/:uid", Result: auditlog.ResultSuccess},
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "audit.log")
+	s, err := newFileSink(path)
+	require.NoError(t, err)
+
+	require.NoError(t, s.Write(context.Background(), testEvents()[:2]))
+	require.NoError(t, s.Write(context.Background(), testEvents()[2:]))
+	require.NoError(t, s.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer func() { _ = file.Close() }()
+
+	var logins []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event auditlog.Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		logins = append(logins, event.ActorLogin)
+	}
+	require.NoError(t, scanner.Err())
+	assert.Equal(t, []string{"admin", "admin", "editor"}, logins)
+}
+
+func TestLokiSink(t *testing.T) {
+	t.Run("should push one stream per org and result", func(t *testing.T) {
+		var push lokiPushRequest
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/loki/api/v1/push", r.URL.Path)
+			assert.Equal(t, "tenant", r.Header.Get("X-Scope-OrgID"))
+			user, password, ok := r.BasicAuth()
+			assert.True(t, ok)
+			assert.Equal(t, "user", user)
+			assert.Equal(t, "password", password)
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&push))
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		s := newLokiSink(server.URL+"/", "tenant", "user", "password")
+		require.NoError(t, s.Write(context.Background(), testEvents()))
+
+		require.Len(t, push.Streams, 3)
+		assert.Equal(t, map[string]string{"job": "grafana-audit", "org_id": "1", "result": "failure"}, push.Streams[0].Stream)
+		assert.Equal(t, map[string]string{"job": "grafana-audit", "org_id": "1", "result": "success"}, push.Streams[1].Stream)
+		assert.Equal(t, map[string]string{"job": "grafana-audit", "org_id": "2", "result": "success"}, push.Streams[2].Stream)
+
+		value := push.Streams[2].Values[0]
+		assert.Equal(t, "1697961600000000000", value[0])
+		var event auditlog.Event
+		require.NoError(t, json.Unmarshal([]byte(value[1]), &event))
+		assert.Equal(t, "editor", event.ActorLogin)
+	})
+
+	t.Run("should fail when Loki rejects the push", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "rate limited", http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		s := newLokiSink(server.URL, "", "", "")
+		err := s.Write(context.Background(), testEvents())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "429")
+	})
+}
@@ -0,0 +1,173 @@
+package auditlogimpl
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/auditlog"
+)
+
+type store interface {
+	Insert(ctx context.Context, events []*auditlog.Event) error
+	Search(ctx context.Context, query *auditlog.SearchQuery) (*auditlog.SearchResult, error)
+	DeleteOlderThan(ctx context.Context, olderThan time.Time) (int64, error)
+}
+
+type xormStore struct {
+	db db.DB
+}
+
+// auditLogEntry is the database representation of auditlog.Event
+type auditLogEntry struct {
+	ID           int64  `xorm:"pk autoincr 'id'"`
+	OrgID        int64  `xorm:"org_id"`
+	Created      int64  `xorm:"'created'"` // epoch milliseconds
+	ActorID      string `xorm:"actor_id"`
+	ActorLogin   string `xorm:"actor_login"`
+	Action       string `xorm:"action"`
+	ResourceKind string `xorm:"resource_kind"`
+	ResourceID   string `xorm:"resource_id"`
+	BeforeState  string `xorm:"before_state"`
+	AfterState   string `xorm:"after_state"`
+	IPAddress    string `xorm:"ip_address"`
+	Result       string `xorm:"result"`
+	StatusCode   int    `xorm:"status_code"`
+}
+
+func (auditLogEntry) TableName() string {
+	return "audit_log"
+}
+
+func (e *auditLogEntry) toEvent() *auditlog.Event {
+	event := &auditlog.Event{
+		ID:           e.ID,
+		OrgID:        e.OrgID,
+		Created:      time.UnixMilli(e.Created),
+		ActorID:      e.ActorID,
+		ActorLogin:   e.ActorLogin,
+		Action:       e.Action,
+		ResourceKind: e.ResourceKind,
+		ResourceID:   e.ResourceID,
+		IPAddress:    e.IPAddress,
+		Result:       auditlog.Result(e.Result),
+		StatusCode:   e.StatusCode,
+	}
+	if e.BeforeState != "" {
+		event.Before = []byte(e.BeforeState)
+	}
+	if e.AfterState != "" {
+		event.After = []byte(e.AfterState)
+	}
+	return event
+}
+
+func (xs *xormStore) Insert(ctx context.Context, events []*auditlog.Event) error {
+	return xs.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		for _, event := range events {
+			entry := &auditLogEntry{
+				OrgID:        event.OrgID,
+				Created:      event.Created.UnixMilli(),
+				ActorID:      event.ActorID,
+				ActorLogin:   event.ActorLogin,
+				Action:       event.Action,
+				ResourceKind: event.ResourceKind,
+				ResourceID:   event.ResourceID,
+				BeforeState:  string(event.Before),
+				AfterState:   string(event.After),
+				IPAddress:    event.IPAddress,
+				Result:       string(event.Result),
+				StatusCode:   event.StatusCode,
+			}
+			if _, err := sess.Insert(entry); err != nil {
+				return err
+			}
+			event.ID = entry.ID
+		}
+		return nil
+	})
+}
+
+func (xs *xormStore) Search(ctx context.Context, query *auditlog.SearchQuery) (*auditlog.SearchResult, error) {
+	result := &auditlog.SearchResult{
+		Events:  make([]*auditlog.Event, 0),
+		Page:    query.Page,
+		PerPage: query.PerPage,
+	}
+
+	var where []string
+	var args []any
+	if query.OrgID != 0 {
+		where = append(where, "org_id = ?")
+		args = append(args, query.OrgID)
+	}
+	if query.ActorLogin != "" {
+		where = append(where, "actor_login = ?")
+		args = append(args, query.ActorLogin)
+	}
+	if query.Action != "" {
+		where = append(where, "action = ?")
+		args = append(args, query.Action)
+	}
+	if query.ResourceKind != "" {
+		where = append(where, "resource_kind = ?")
+		args = append(args, query.ResourceKind)
+	}
+	if query.ResourceID != "" {
+		where = append(where, "resource_id = ?")
+		args = append(args, query.ResourceID)
+	}
+	if query.Result != "" {
+		where = append(where, "result = ?")
+		args = append(args, string(query.Result))
+	}
+	if !query.From.IsZero() {
+		where = append(where, "created >= ?")
+		args = append(args, query.From.UnixMilli())
+	}
+	if !query.To.IsZero() {
+		where = append(where, "created <= ?")
+		args = append(args, query.To.UnixMilli())
+	}
+
+	err := xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		filter := "1 = 1"
+		if len(where) > 0 {
+			filter = strings.Join(where, " AND ")
+		}
+
+		count, err := sess.Where(filter, args...).Count(&auditLogEntry{})
+		if err != nil {
+			return err
+		}
+		result.TotalCount = count
+
+		entries := make([]*auditLogEntry, 0)
+		err = sess.Where(filter, args...).
+			Desc("created", "id").
+			Limit(query.PerPage, (query.Page-1)*query.PerPage).
+			Find(&entries)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			result.Events = append(result.Events, entry.toEvent())
+		}
+		return nil
+	})
+	return result, err
+}
+
+func (xs *xormStore) DeleteOlderThan(ctx context.Context, olderThan time.Time) (int64, error) {
+	var deletedRows int64
+	err := xs.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		res, err := sess.Exec("DELETE FROM audit_log WHERE created < ?", olderThan.UnixMilli())
+		if err != nil {
+			return err
+		}
+		deletedRows, err = res.RowsAffected()
+		return err
+	})
+	return deletedRows, err
+}
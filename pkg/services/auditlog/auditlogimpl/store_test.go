@@ -0,0 +1,107 @@
+package auditlogimpl
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/auditlog"
+)
+
+func TestIntegrationAuditLogStore(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	beginningOfTime := time.Date(2023, 10, 22, 8, 0, 0, 0, time.UTC)
+
+	setup := func(t *testing.T) *xormStore {
+		t.Helper()
+		s := &xormStore{db: db.InitTestDB(t)}
+		err := s.Insert(context.Background(), []*auditlog.Event{
+			{
+				OrgID: 1, Created: beginningOfTime, ActorID: "user:1", ActorLogin: "admin", Action: auditlog.ActionLogin,
+				ResourceKind: "users", ResourceID: "1", Result: auditlog.ResultSuccess, StatusCode: 200,
+			},
+			{
+				OrgID: 1, Created: beginningOfTime.Add(time.Minute), ActorID: "user:1", ActorLogin: "admin", Action: "POST /api/dashboards/db/",
+				ResourceKind: "dashboards", ResourceID: "abc", Before: json.RawMessage(`{"version":1}`), After: json.RawMessage(`{"version":2}`),
+				Result: auditlog.ResultSuccess, StatusCode: 200,
+			},
+			{
+				OrgID: 2, Created: beginningOfTime.Add(2 * time.Minute), ActorID: "user:2", ActorLogin: "editor", Action: "DELETE /api/datasources/uid/:uid",
+				ResourceKind: "datasources", ResourceID: "xyz", Result: auditlog.ResultFailure, StatusCode: 403,
+			},
+		})
+		require.NoError(t, err)
+		return s
+	}
+
+	t.Run("should search with filters", func(t *testing.T) {
+		s := setup(t)
+
+		tests := []struct {
+			desc     string
+			query    auditlog.SearchQuery
+			expected []string
+		}{
+			{desc: "no filter", query: auditlog.SearchQuery{}, expected: []string{"xyz", "abc", "1"}},
+			{desc: "org", query: auditlog.SearchQuery{OrgID: 1}, expected: []string{"abc", "1"}},
+			{desc: "actor", query: auditlog.SearchQuery{ActorLogin: "editor"}, expected: []string{"xyz"}},
+			{desc: "action", query: auditlog.SearchQuery{Action: auditlog.ActionLogin}, expected: []string{"1"}},
+			{desc: "resource", query: auditlog.SearchQuery{ResourceKind: "dashboards", ResourceID: "abc"}, expected: []string{"abc"}},
+			{desc: "result", query: auditlog.SearchQuery{Result: auditlog.ResultFailure}, expected: []string{"xyz"}},
+			{
+				desc:     "time range",
+				query:    auditlog.SearchQuery{From: beginningOfTime.Add(time.Minute), To: beginningOfTime.Add(time.Minute)},
+				expected: []string{"abc"},
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.desc, func(t *testing.T) {
+				tt.query.Page, tt.query.PerPage = 1, 10
+				result, err := s.Search(context.Background(), &tt.query)
+				require.NoError(t, err)
+
+				ids := make([]string, 0, len(result.Events))
+				for _, event := range result.Events {
+					ids = append(ids, event.ResourceID)
+				}
+				assert.Equal(t, tt.expected, ids)
+				assert.Equal(t, int64(len(tt.expected)), result.TotalCount)
+			})
+		}
+	})
+
+	t.Run("should paginate and return the event details", func(t *testing.T) {
+		s := setup(t)
+
+		result, err := s.Search(context.Background(), &auditlog.SearchQuery{Page: 2, PerPage: 1})
+		require.NoError(t, err)
+		require.Len(t, result.Events, 1)
+		assert.Equal(t, int64(3), result.TotalCount)
+
+		event := result.Events[0]
+		assert.Equal(t, "abc", event.ResourceID)
+		assert.Equal(t, beginningOfTime.Add(time.Minute).UnixMilli(), event.Created.UnixMilli())
+		assert.JSONEq(t, `{"version":1}`, string(event.Before))
+		assert.JSONEq(t, `{"version":2}`, string(event.After))
+	})
+
+	t.Run("should delete events older than a time", func(t *testing.T) {
+		s := setup(t)
+
+		deleted, err := s.DeleteOlderThan(context.Background(), beginningOfTime.Add(time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+
+		result, err := s.Search(context.Background(), &auditlog.SearchQuery{Page: 1, PerPage: 10})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), result.TotalCount)
+	})
+}
@@ -58,6 +58,14 @@ type RevokeAuthTokenCmd struct {
 	AuthTokenId int64 `json:"authTokenId"`
 }
 
+// SearchUserTokensQuery searches the active tokens of all users
+type SearchUserTokensQuery struct {
+	// IPNetwork only returns the tokens last used from an address of the network
+	IPNetwork *net.IPNet
+	// Limit is the maximum number of tokens returned, the most recently seen tokens are returned first
+	Limit int
+}
+
 type RotateCommand struct {
 	// token is the un-hashed token
 	UnHashedToken string
@@ -74,10 +82,13 @@ type UserTokenService interface {
 	TryRotateToken(ctx context.Context, token *UserToken, clientIP net.IP, userAgent string) (bool, *UserToken, error)
 	RevokeToken(ctx context.Context, token *UserToken, soft bool) error
 	RevokeAllUserTokens(ctx context.Context, userID int64) error
+	// RevokeOtherUserTokens revokes all the tokens of the user except the token of the current session
+	RevokeOtherUserTokens(ctx context.Context, userID, currentTokenID int64) error
 	GetUserToken(ctx context.Context, userID, userTokenID int64) (*UserToken, error)
 	GetUserTokens(ctx context.Context, userID int64) ([]*UserToken, error)
 	ActiveTokenCount(ctx context.Context, userID *int64) (int64, error)
 	GetUserRevokedTokens(ctx context.Context, userID int64) ([]*UserToken, error)
+	SearchUserTokens(ctx context.Context, query *SearchUserTokensQuery) ([]*UserToken, error)
 }
 
 type UserTokenBackgroundService interface {
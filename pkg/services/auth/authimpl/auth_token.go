@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 	"time"
 
@@ -454,6 +455,25 @@ func (s *UserAuthTokenService) RevokeAllUserTokens(ctx context.Context, userId i
 	})
 }
 
+func (s *UserAuthTokenService) RevokeOtherUserTokens(ctx context.Context, userId, currentTokenId int64) error {
+	return s.sqlStore.WithDbSession(ctx, func(dbSession *db.Session) error {
+		sql := `DELETE from user_auth_token WHERE user_id = ? AND id <> ?`
+		res, err := dbSession.Exec(sql, userId, currentTokenId)
+		if err != nil {
+			return err
+		}
+
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		s.log.FromContext(ctx).Debug("Other user tokens for user revoked", "userId", userId, "tokenId", currentTokenId, "count", affected)
+
+		return err
+	})
+}
+
 func (s *UserAuthTokenService) BatchRevokeAllUserTokens(ctx context.Context, userIds []int64) error {
 	return s.sqlStore.WithTransactionalDbSession(ctx, func(dbSession *db.Session) error {
 		if len(userIds) == 0 {
@@ -592,6 +612,84 @@ func (s *UserAuthTokenService) GetUserRevokedTokens(ctx context.Context, userId
 	return result, err
 }
 
+// searchUserTokensPageSize is the number of tokens read at once when searching the tokens by IP network
+var searchUserTokensPageSize = 1000
+
+// SearchUserTokens returns the active tokens of all users. The client addresses are stored as strings, the
+// tokens of an IP network are pre-filtered by the whole bytes of an IPv4 network prefix and checked after being
+// read page by page.
+func (s *UserAuthTokenService) SearchUserTokens(ctx context.Context, query *auth.SearchUserTokensQuery) ([]*auth.UserToken, error) {
+	result := []*auth.UserToken{}
+	err := s.sqlStore.WithDbSession(ctx, func(dbSession *db.Session) error {
+		for offset := 0; ; offset += searchUserTokensPageSize {
+			sess := dbSession.Where("created_at > ? AND rotated_at > ? AND revoked_at = 0",
+				s.createdAfterParam(),
+				s.rotatedAfterParam())
+			if query.IPNetwork == nil {
+				if query.Limit > 0 {
+					sess.Limit(query.Limit)
+				}
+			} else {
+				if prefix := ipv4NetworkPrefix(query.IPNetwork); prefix != "" {
+					sess.And("client_ip LIKE ?", prefix+"%")
+				}
+				sess.Limit(searchUserTokensPageSize, offset)
+			}
+
+			var tokens []*userAuthToken
+			if err := sess.Desc("seen_at", "id").Find(&tokens); err != nil {
+				return err
+			}
+
+			for _, token := range tokens {
+				if query.IPNetwork != nil {
+					ip := net.ParseIP(token.ClientIp)
+					if ip == nil || !query.IPNetwork.Contains(ip) {
+						continue
+					}
+				}
+
+				var userToken auth.UserToken
+				if err := token.toUserToken(&userToken); err != nil {
+					return err
+				}
+				result = append(result, &userToken)
+
+				if query.Limit > 0 && len(result) >= query.Limit {
+					return nil
+				}
+			}
+
+			if query.IPNetwork == nil || len(tokens) < searchUserTokensPageSize {
+				return nil
+			}
+		}
+	})
+
+	return result, err
+}
+
+// ipv4NetworkPrefix returns the string prefix of the addresses of an IPv4 network made of the whole bytes of the
+// network prefix up to the third one, ex: "192.168." for 192.168.0.0/20. It is empty for an IPv6 network or a prefix shorter than 8.
+func ipv4NetworkPrefix(network *net.IPNet) string {
+	ip := network.IP.To4()
+	ones, bits := network.Mask.Size()
+	if ip == nil || bits != 8*net.IPv4len || ones < 8 {
+		return ""
+	}
+
+	// the last byte of an address is not followed by a dot
+	n := ones / 8
+	if n == net.IPv4len {
+		n--
+	}
+	prefix := ""
+	for i := 0; i < n; i++ {
+		prefix += strconv.Itoa(int(ip[i])) + "."
+	}
+	return prefix
+}
+
 func (s *UserAuthTokenService) reportActiveTokenCount(ctx context.Context, _ *quota.ScopeParameters) (*quota.Map, error) {
 	count, err := s.ActiveTokenCount(ctx, nil)
 	if err != nil {
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -168,6 +169,103 @@ func TestIntegrationUserAuthToken(t *testing.T) {
 		})
 	})
 
+	t.Run("Can revoke other user tokens", func(t *testing.T) {
+		ctx := createTestContext(t)
+		current, err := ctx.tokenService.CreateToken(context.Background(), usr,
+			net.ParseIP("192.168.10.11"), "some user agent")
+		require.Nil(t, err)
+		other, err := ctx.tokenService.CreateToken(context.Background(), usr,
+			net.ParseIP("192.168.10.12"), "some other user agent")
+		require.Nil(t, err)
+		otherUserToken, err := ctx.tokenService.CreateToken(context.Background(), &user.User{ID: usr.ID + 1},
+			net.ParseIP("192.168.10.12"), "some user agent")
+		require.Nil(t, err)
+
+		err = ctx.tokenService.RevokeOtherUserTokens(context.Background(), usr.ID, current.Id)
+		require.Nil(t, err)
+
+		model, err := ctx.getAuthTokenByID(current.Id)
+		require.Nil(t, err)
+		require.NotNil(t, model)
+
+		model, err = ctx.getAuthTokenByID(other.Id)
+		require.Nil(t, err)
+		require.Nil(t, model)
+
+		model, err = ctx.getAuthTokenByID(otherUserToken.Id)
+		require.Nil(t, err)
+		require.NotNil(t, model)
+	})
+
+	t.Run("Can search user tokens by IP network", func(t *testing.T) {
+		ctx := createTestContext(t)
+		for i, ip := range []string{"192.168.10.11", "10.0.0.1", "192.168.10.200", "::1", "2001:db8::1"} {
+			_, err := ctx.tokenService.CreateToken(context.Background(), &user.User{ID: usr.ID + int64(i)},
+				net.ParseIP(ip), "some user agent")
+			require.Nil(t, err)
+		}
+
+		tokens, err := ctx.tokenService.SearchUserTokens(context.Background(), &auth.SearchUserTokensQuery{})
+		require.Nil(t, err)
+		require.Len(t, tokens, 5)
+
+		_, network, err := net.ParseCIDR("192.168.10.0/24")
+		require.Nil(t, err)
+		tokens, err = ctx.tokenService.SearchUserTokens(context.Background(), &auth.SearchUserTokensQuery{IPNetwork: network})
+		require.Nil(t, err)
+		require.Len(t, tokens, 2)
+		for _, token := range tokens {
+			require.True(t, strings.HasPrefix(token.ClientIp, "192.168.10."))
+		}
+
+		_, network, err = net.ParseCIDR("2001:db8::/32")
+		require.Nil(t, err)
+		tokens, err = ctx.tokenService.SearchUserTokens(context.Background(), &auth.SearchUserTokensQuery{IPNetwork: network})
+		require.Nil(t, err)
+		require.Len(t, tokens, 1)
+		require.Equal(t, usr.ID+4, tokens[0].UserId)
+
+		tokens, err = ctx.tokenService.SearchUserTokens(context.Background(), &auth.SearchUserTokensQuery{Limit: 3})
+		require.Nil(t, err)
+		require.Len(t, tokens, 3)
+
+		_, network, err = net.ParseCIDR("10.0.0.1/32")
+		require.Nil(t, err)
+		tokens, err = ctx.tokenService.SearchUserTokens(context.Background(), &auth.SearchUserTokensQuery{IPNetwork: network})
+		require.Nil(t, err)
+		require.Len(t, tokens, 1)
+		require.Equal(t, "10.0.0.1", tokens[0].ClientIp)
+	})
+
+	t.Run("Search by IP network reads the tokens page by page", func(t *testing.T) {
+		ctx := createTestContext(t)
+		pageSize := searchUserTokensPageSize
+		searchUserTokensPageSize = 2
+		t.Cleanup(func() { searchUserTokensPageSize = pageSize })
+
+		for i, ip := range []string{"192.168.1.1", "10.0.0.1", "192.168.1.2", "10.0.0.2", "10.0.0.3", "192.168.1.3", "::1"} {
+			_, err := ctx.tokenService.CreateToken(context.Background(), &user.User{ID: usr.ID + int64(i)},
+				net.ParseIP(ip), "some user agent")
+			require.Nil(t, err)
+		}
+
+		_, network, err := net.ParseCIDR("192.168.1.0/24")
+		require.Nil(t, err)
+		tokens, err := ctx.tokenService.SearchUserTokens(context.Background(), &auth.SearchUserTokensQuery{IPNetwork: network})
+		require.Nil(t, err)
+		require.Len(t, tokens, 3)
+
+		_, network, err = net.ParseCIDR("0.0.0.0/0")
+		require.Nil(t, err)
+		tokens, err = ctx.tokenService.SearchUserTokens(context.Background(), &auth.SearchUserTokensQuery{IPNetwork: network})
+		require.Nil(t, err)
+		require.Len(t, tokens, 6)
+
+		tokens, err = ctx.tokenService.SearchUserTokens(context.Background(), &auth.SearchUserTokensQuery{IPNetwork: network, Limit: 5})
+		require.Nil(t, err)
+		require.Len(t, tokens, 5)
+	})
+
 	t.Run("expires correctly", func(t *testing.T) {
 		ctx := createTestContext(t)
 		userToken, err := ctx.tokenService.CreateToken(context.Background(), usr,
@@ -738,3 +836,17 @@ func TestIntegrationTokenCount(t *testing.T) {
 	require.Nil(t, err)
 	require.Equal(t, int64(0), count)
 }
+
+func TestIPv4NetworkPrefix(t *testing.T) {
+	for cidr, expected := range map[string]string{
+		"192.168.10.0/24": "192.168.10.",
+		"192.168.0.0/20":  "192.168.",
+		"10.0.0.1/32":     "10.0.0.",
+		"10.0.0.0/7":      "",
+		"2001:db8::/32":   "",
+	} {
+		_, network, err := net.ParseCIDR(cidr)
+		require.Nil(t, err)
+		require.Equal(t, expected, ipv4NetworkPrefix(network), cidr)
+	}
+}
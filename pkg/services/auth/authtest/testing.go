@@ -15,17 +15,19 @@ import (
 )
 
 type FakeUserAuthTokenService struct {
-	CreateTokenProvider          func(ctx context.Context, user *user.User, clientIP net.IP, userAgent string) (*auth.UserToken, error)
-	RotateTokenProvider          func(ctx context.Context, cmd auth.RotateCommand) (*auth.UserToken, error)
-	TryRotateTokenProvider       func(ctx context.Context, token *auth.UserToken, clientIP net.IP, userAgent string) (bool, *auth.UserToken, error)
-	LookupTokenProvider          func(ctx context.Context, unhashedToken string) (*auth.UserToken, error)
-	RevokeTokenProvider          func(ctx context.Context, token *auth.UserToken, soft bool) error
-	RevokeAllUserTokensProvider  func(ctx context.Context, userID int64) error
-	ActiveTokenCountProvider     func(ctx context.Context, userID *int64) (int64, error)
-	GetUserTokenProvider         func(ctx context.Context, userID, userTokenID int64) (*auth.UserToken, error)
-	GetUserTokensProvider        func(ctx context.Context, userID int64) ([]*auth.UserToken, error)
-	GetUserRevokedTokensProvider func(ctx context.Context, userID int64) ([]*auth.UserToken, error)
-	BatchRevokedTokenProvider    func(ctx context.Context, userIDs []int64) error
+	CreateTokenProvider           func(ctx context.Context, user *user.User, clientIP net.IP, userAgent string) (*auth.UserToken, error)
+	RotateTokenProvider           func(ctx context.Context, cmd auth.RotateCommand) (*auth.UserToken, error)
+	TryRotateTokenProvider        func(ctx context.Context, token *auth.UserToken, clientIP net.IP, userAgent string) (bool, *auth.UserToken, error)
+	LookupTokenProvider           func(ctx context.Context, unhashedToken string) (*auth.UserToken, error)
+	RevokeTokenProvider           func(ctx context.Context, token *auth.UserToken, soft bool) error
+	RevokeAllUserTokensProvider   func(ctx context.Context, userID int64) error
+	ActiveTokenCountProvider      func(ctx context.Context, userID *int64) (int64, error)
+	GetUserTokenProvider          func(ctx context.Context, userID, userTokenID int64) (*auth.UserToken, error)
+	GetUserTokensProvider         func(ctx context.Context, userID int64) ([]*auth.UserToken, error)
+	GetUserRevokedTokensProvider  func(ctx context.Context, userID int64) ([]*auth.UserToken, error)
+	BatchRevokedTokenProvider     func(ctx context.Context, userIDs []int64) error
+	RevokeOtherUserTokensProvider func(ctx context.Context, userID, currentTokenID int64) error
+	SearchUserTokensProvider      func(ctx context.Context, query *auth.SearchUserTokensQuery) ([]*auth.UserToken, error)
 }
 
 func NewFakeUserAuthTokenService() *FakeUserAuthTokenService {
@@ -54,6 +56,9 @@ func NewFakeUserAuthTokenService() *FakeUserAuthTokenService {
 		BatchRevokedTokenProvider: func(ctx context.Context, userIds []int64) error {
 			return nil
 		},
+		RevokeOtherUserTokensProvider: func(ctx context.Context, userId, currentTokenId int64) error {
+			return nil
+		},
 		ActiveTokenCountProvider: func(ctx context.Context, userID *int64) (int64, error) {
 			return 10, nil
 		},
@@ -63,6 +68,9 @@ func NewFakeUserAuthTokenService() *FakeUserAuthTokenService {
 		GetUserTokensProvider: func(ctx context.Context, userId int64) ([]*auth.UserToken, error) {
 			return nil, nil
 		},
+		SearchUserTokensProvider: func(ctx context.Context, query *auth.SearchUserTokensQuery) ([]*auth.UserToken, error) {
+			return nil, nil
+		},
 	}
 }
 
@@ -117,6 +125,14 @@ func (s *FakeUserAuthTokenService) BatchRevokeAllUserTokens(ctx context.Context,
 	return s.BatchRevokedTokenProvider(ctx, userIds)
 }
 
+func (s *FakeUserAuthTokenService) RevokeOtherUserTokens(ctx context.Context, userId, currentTokenId int64) error {
+	return s.RevokeOtherUserTokensProvider(ctx, userId, currentTokenId)
+}
+
+func (s *FakeUserAuthTokenService) SearchUserTokens(ctx context.Context, query *auth.SearchUserTokensQuery) ([]*auth.UserToken, error) {
+	return s.SearchUserTokensProvider(ctx, query)
+}
+
 type FakeOAuthTokenService struct {
 	passThruEnabled  bool
 	ExpectedAuthUser *login.UserAuth
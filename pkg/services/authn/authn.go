@@ -33,6 +33,7 @@ const (
 	ClientForm        = "auth.client.form"
 	ClientProxy       = "auth.client.proxy"
 	ClientSAML        = "auth.client.saml"
+	ClientCert        = "auth.client.cert"
 )
 
 const (
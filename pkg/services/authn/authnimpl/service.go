@@ -32,6 +32,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/quota"
 	"github.com/grafana/grafana/pkg/services/rendering"
 	"github.com/grafana/grafana/pkg/services/signingkeys"
+	"github.com/grafana/grafana/pkg/services/totp"
 	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/util/errutil"
@@ -72,6 +73,7 @@ func ProvideService(
 	socialService social.Service, cache *remotecache.RemoteCache,
 	ldapService service.LDAP, registerer prometheus.Registerer,
 	signingKeysService signingkeys.Service, oauthServer oauthserver.OAuth2Server,
+	totpService totp.Service,
 ) *Service {
 	s := &Service{
 		log:            log.New("authn.service"),
@@ -100,6 +102,11 @@ func ProvideService(
 
 	var proxyClients []authn.ProxyClient
 	var passwordClients []authn.PasswordClient
+	// app passwords are checked first so they are never sent to the ldap server
+	if !s.cfg.DisableLogin && totpService.IsEnabled() {
+		passwordClients = append(passwordClients, clients.ProvideAppPassword(totpService, userService))
+	}
+
 	if s.cfg.LDAPAuthEnabled {
 		ldap := clients.ProvideLDAP(cfg, ldapService, userService, authInfoService)
 		proxyClients = append(proxyClients, ldap)
@@ -116,11 +123,11 @@ func ProvideService(
 	if len(passwordClients) > 0 {
 		passwordClient := clients.ProvidePassword(loginAttempts, passwordClients...)
 		if s.cfg.BasicAuthEnabled {
-			s.RegisterClient(clients.ProvideBasic(passwordClient))
+			s.RegisterClient(clients.ProvideBasic(passwordClient, totpService))
 		}
 
 		if !s.cfg.DisableLoginForm {
-			s.RegisterClient(clients.ProvideForm(passwordClient))
+			s.RegisterClient(clients.ProvideForm(passwordClient, totpService, loginAttempts))
 		}
 	}
 
@@ -137,6 +144,15 @@ func ProvideService(
 		s.RegisterClient(clients.ProvideJWT(jwtService, cfg))
 	}
 
+	if s.cfg.ClientCertAuthEnabled {
+		clientCert, err := clients.ProvideClientCert(cfg)
+		if err != nil {
+			s.log.Error("Failed to configure client certificate auth", "err", err)
+		} else {
+			s.RegisterClient(clientCert)
+		}
+	}
+
 	if s.cfg.ExtendedJWTAuthEnabled && features.IsEnabled(featuremgmt.FlagExternalServiceAuth) {
 		s.RegisterClient(clients.ProvideExtendedJWT(userService, cfg, signingKeysService, oauthServer))
 	}
@@ -0,0 +1,60 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/login"
+	"github.com/grafana/grafana/pkg/services/totp"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+var _ authn.PasswordClient = new(AppPassword)
+
+func ProvideAppPassword(totpService totp.Service, userService user.Service) *AppPassword {
+	return &AppPassword{totpService, userService}
+}
+
+// AppPassword authenticates users with two-factor authentication using one of their app passwords,
+// so they can keep using basic auth.
+type AppPassword struct {
+	totpService totp.Service
+	userService user.Service
+}
+
+func (c *AppPassword) String() string {
+	return "app_password"
+}
+
+func (c *AppPassword) AuthenticatePassword(ctx context.Context, r *authn.Request, username, password string) (*authn.Identity, error) {
+	if !strings.HasPrefix(password, totp.AppPasswordPrefix) {
+		return nil, errIdentityNotFound.Errorf("not an app password")
+	}
+
+	usr, err := c.userService.GetByLogin(ctx, &user.GetUserByLoginQuery{LoginOrEmail: username})
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return nil, errIdentityNotFound.Errorf("no user found: %w", err)
+		}
+		return nil, err
+	}
+
+	ok, err := c.totpService.AuthenticateAppPassword(ctx, usr.ID, password)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errInvalidPassword.Errorf("invalid app password")
+	}
+
+	r.SetMeta(authn.MetaKeyAuthModule, "grafana")
+
+	signedInUser, err := c.userService.GetSignedInUserWithCacheCtx(ctx, &user.GetSignedInUserQuery{OrgID: r.OrgID, UserID: usr.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	return authn.IdentityFromSignedInUser(authn.NamespacedID(authn.NamespaceUser, signedInUser.UserID), signedInUser, authn.ClientParams{SyncPermissions: true}, login.AppPasswordAuthModule), nil
+}
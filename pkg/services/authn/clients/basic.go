@@ -5,21 +5,25 @@ import (
 	"strings"
 
 	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/login"
+	"github.com/grafana/grafana/pkg/services/totp"
 	"github.com/grafana/grafana/pkg/util/errutil"
 )
 
 var (
 	errDecodingBasicAuthHeader = errutil.BadRequest("basic-auth.invalid-header", errutil.WithPublicMessage("Invalid Basic Auth Header"))
+	errBasicAuthTOTPRequired   = errutil.Unauthorized("basic-auth.totp-required", errutil.WithPublicMessage("Two-factor authentication is enabled for this user, use an app password instead"))
 )
 
 var _ authn.ContextAwareClient = new(Basic)
 
-func ProvideBasic(client authn.PasswordClient) *Basic {
-	return &Basic{client}
+func ProvideBasic(client authn.PasswordClient, totpService totp.Service) *Basic {
+	return &Basic{client, totpService}
 }
 
 type Basic struct {
-	client authn.PasswordClient
+	client      authn.PasswordClient
+	totpService totp.Service
 }
 
 func (c *Basic) String() string {
@@ -36,7 +40,25 @@ func (c *Basic) Authenticate(ctx context.Context, r *authn.Request) (*authn.Iden
 		return nil, errDecodingBasicAuthHeader.Errorf("failed to decode basic auth header")
 	}
 
-	return c.client.AuthenticatePassword(ctx, r, username, password)
+	identity, err := c.client.AuthenticatePassword(ctx, r, username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	// basic auth can not carry a second factor, users who need one have to use an app password
+	if identity.AuthenticatedBy == login.PasswordAuthModule && c.totpService.IsEnabled() {
+		_, userID := identity.NamespacedID()
+		isGrafanaAdmin := identity.IsGrafanaAdmin != nil && *identity.IsGrafanaAdmin
+		required, err := c.totpService.RequiresSecondFactor(ctx, userID, isGrafanaAdmin)
+		if err != nil {
+			return nil, err
+		}
+		if required {
+			return nil, errBasicAuthTOTPRequired.Errorf("user %d requires a second factor", userID)
+		}
+	}
+
+	return identity, nil
 }
 
 func (c *Basic) Test(ctx context.Context, r *authn.Request) bool {
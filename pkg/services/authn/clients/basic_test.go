@@ -9,6 +9,8 @@ import (
 
 	"github.com/grafana/grafana/pkg/services/authn"
 	"github.com/grafana/grafana/pkg/services/authn/authntest"
+	"github.com/grafana/grafana/pkg/services/login"
+	"github.com/grafana/grafana/pkg/services/totp/totptest"
 )
 
 func TestBasic_Authenticate(t *testing.T) {
@@ -16,6 +18,7 @@ func TestBasic_Authenticate(t *testing.T) {
 		desc             string
 		req              *authn.Request
 		client           authn.PasswordClient
+		totpRequired     bool
 		expectedErr      error
 		expectedIdentity *authn.Identity
 	}
@@ -27,6 +30,20 @@ func TestBasic_Authenticate(t *testing.T) {
 			client:           authntest.FakePasswordClient{ExpectedIdentity: &authn.Identity{ID: "user:1"}},
 			expectedIdentity: &authn.Identity{ID: "user:1"},
 		},
+		{
+			desc:             "should success when user requiring a second factor uses an app password",
+			req:              &authn.Request{HTTPRequest: &http.Request{Header: map[string][]string{authorizationHeaderName: {encodeBasicAuth("user", "glap_secret")}}}},
+			client:           authntest.FakePasswordClient{ExpectedIdentity: &authn.Identity{ID: "user:1", AuthenticatedBy: login.AppPasswordAuthModule}},
+			totpRequired:     true,
+			expectedIdentity: &authn.Identity{ID: "user:1", AuthenticatedBy: login.AppPasswordAuthModule},
+		},
+		{
+			desc:         "should fail when user requiring a second factor uses the password",
+			req:          &authn.Request{HTTPRequest: &http.Request{Header: map[string][]string{authorizationHeaderName: {encodeBasicAuth("user", "password")}}}},
+			client:       authntest.FakePasswordClient{ExpectedIdentity: &authn.Identity{ID: "user:1", AuthenticatedBy: login.PasswordAuthModule}},
+			totpRequired: true,
+			expectedErr:  errBasicAuthTOTPRequired,
+		},
 		{
 			desc:        "should fail when basic auth header could not be decoded",
 			req:         &authn.Request{HTTPRequest: &http.Request{Header: map[string][]string{authorizationHeaderName: {}}}},
@@ -36,7 +53,7 @@ func TestBasic_Authenticate(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.desc, func(t *testing.T) {
-			c := ProvideBasic(tt.client)
+			c := ProvideBasic(tt.client, &totptest.FakeService{ExpectedEnabled: true, ExpectedRequired: tt.totpRequired})
 
 			identity, err := c.Authenticate(context.Background(), tt.req)
 			if tt.expectedErr != nil {
@@ -95,7 +112,7 @@ func TestBasic_Test(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.desc, func(t *testing.T) {
-			c := ProvideBasic(authntest.FakePasswordClient{})
+			c := ProvideBasic(authntest.FakePasswordClient{}, &totptest.FakeService{})
 			assert.Equal(t, tt.expected, c.Test(context.Background(), tt.req))
 		})
 	}
@@ -0,0 +1,203 @@
+package clients
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/login"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util/errutil"
+)
+
+var _ authn.ContextAwareClient = new(ClientCert)
+
+var (
+	errClientCertInvalid = errutil.Unauthorized(
+		"client-cert.invalid", errutil.WithPublicMessage("Failed to verify client certificate"))
+	errClientCertMissingAttribute = errutil.Unauthorized(
+		"client-cert.missing-attribute", errutil.WithPublicMessage("Missing login and email in client certificate"))
+	errClientCertInvalidRole = errutil.Forbidden(
+		"client-cert.invalid-role", errutil.WithPublicMessage("Invalid role in client certificate"))
+)
+
+func ProvideClientCert(cfg *setting.Cfg) (*ClientCert, error) {
+	roots, err := loadCertPool(cfg.ClientCertAuthCACertPath)
+	if err != nil {
+		return nil, err
+	}
+	return &ClientCert{
+		cfg:   cfg,
+		log:   log.New(authn.ClientCert),
+		roots: roots,
+		now:   time.Now,
+	}, nil
+}
+
+// ClientCert authenticates users with the verified TLS client certificate of the connection.
+// The certificate fields are mapped to the identity with JMESPath expressions, like the claims of a JWT.
+type ClientCert struct {
+	cfg   *setting.Cfg
+	log   log.Logger
+	roots *x509.CertPool
+	now   func() time.Time
+}
+
+func (c *ClientCert) Name() string {
+	return authn.ClientCert
+}
+
+func (c *ClientCert) Authenticate(ctx context.Context, r *authn.Request) (*authn.Identity, error) {
+	peerCerts := r.HTTPRequest.TLS.PeerCertificates
+	cert := peerCerts[0]
+
+	// the listener already verifies the certificate, it is verified again in case the
+	// connection was accepted with a certificate issued by another authority
+	intermediates := x509.NewCertPool()
+	for _, intermediate := range peerCerts[1:] {
+		intermediates.AddCert(intermediate)
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:         c.roots,
+		Intermediates: intermediates,
+		CurrentTime:   c.now(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		c.log.FromContext(ctx).Debug("Failed to verify client certificate", "subject", cert.Subject.String(), "error", err)
+		return nil, errClientCertInvalid.Errorf("failed to verify client certificate: %w", err)
+	}
+
+	claims := certificateClaims(cert)
+
+	id := &authn.Identity{
+		AuthenticatedBy: login.ClientCertAuthModule,
+		AuthID:          cert.Subject.String(),
+		OrgRoles:        map[int64]org.RoleType{},
+		ClientParams: authn.ClientParams{
+			SyncUser:        true,
+			FetchSyncedUser: true,
+			SyncPermissions: true,
+			SyncOrgRoles:    !c.cfg.ClientCertAuthSkipOrgRoleSync,
+			AllowSignUp:     c.cfg.ClientCertAuthAutoSignUp,
+		}}
+
+	if path := c.cfg.ClientCertAuthLoginAttributePath; path != "" {
+		id.Login, _ = searchClaimsForStringAttr(path, claims)
+		id.ClientParams.LookUpParams.Login = &id.Login
+	}
+	if path := c.cfg.ClientCertAuthEmailAttributePath; path != "" {
+		id.Email, _ = searchClaimsForStringAttr(path, claims)
+		id.ClientParams.LookUpParams.Email = &id.Email
+	}
+	if path := c.cfg.ClientCertAuthNameAttributePath; path != "" {
+		id.Name, _ = searchClaimsForStringAttr(path, claims)
+	}
+
+	orgRoles, isGrafanaAdmin, err := getRoles(c.cfg, func() (org.RoleType, *bool, error) {
+		if c.cfg.ClientCertAuthSkipOrgRoleSync || c.cfg.ClientCertAuthRoleAttributePath == "" {
+			return "", nil, nil
+		}
+
+		value, _ := searchClaimsForStringAttr(c.cfg.ClientCertAuthRoleAttributePath, claims)
+		role, grafanaAdmin := org.RoleType(value), false
+		if value == roleGrafanaAdmin {
+			role, grafanaAdmin = org.RoleAdmin, true
+		}
+
+		if c.cfg.ClientCertAuthRoleAttributeStrict && !role.IsValid() {
+			return "", nil, errClientCertInvalidRole.Errorf("invalid role in client certificate: %s", role)
+		}
+
+		if !c.cfg.ClientCertAuthAllowAssignGrafanaAdmin {
+			return role, nil, nil
+		}
+
+		return role, &grafanaAdmin, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	id.OrgRoles = orgRoles
+	id.IsGrafanaAdmin = isGrafanaAdmin
+
+	if id.Login == "" && id.Email == "" {
+		c.log.FromContext(ctx).Debug("Failed to get login or email from client certificate", "subject", cert.Subject.String())
+		return nil, errClientCertMissingAttribute.Errorf("missing login and email in client certificate")
+	}
+
+	return id, nil
+}
+
+func (c *ClientCert) Test(ctx context.Context, r *authn.Request) bool {
+	if r.HTTPRequest == nil || r.HTTPRequest.TLS == nil {
+		return false
+	}
+	return len(r.HTTPRequest.TLS.PeerCertificates) > 0
+}
+
+func (c *ClientCert) Priority() uint {
+	return 25
+}
+
+// certificateClaims returns the fields of the certificate available to the attribute paths
+func certificateClaims(cert *x509.Certificate) map[string]any {
+	uris := make([]any, 0, len(cert.URIs))
+	for _, u := range cert.URIs {
+		uris = append(uris, u.String())
+	}
+
+	return map[string]any{
+		"subject":         nameClaims(cert.Subject),
+		"issuer":          nameClaims(cert.Issuer),
+		"email_addresses": stringsToAny(cert.EmailAddresses),
+		"dns_names":       stringsToAny(cert.DNSNames),
+		"uris":            uris,
+		"serial_number":   cert.SerialNumber.Text(16),
+	}
+}
+
+func nameClaims(name pkix.Name) map[string]any {
+	return map[string]any{
+		"common_name":         name.CommonName,
+		"serial_number":       name.SerialNumber,
+		"organization":        stringsToAny(name.Organization),
+		"organizational_unit": stringsToAny(name.OrganizationalUnit),
+		"country":             stringsToAny(name.Country),
+		"locality":            stringsToAny(name.Locality),
+		"province":            stringsToAny(name.Province),
+	}
+}
+
+// stringsToAny converts the values so JMESPath functions like contains can be applied to them
+func stringsToAny(values []string) []any {
+	result := make([]any, 0, len(values))
+	for _, v := range values {
+		result = append(result, v)
+	}
+	return result
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	if path == "" {
+		return nil, errors.New("ca_cert_path is required for client certificate authentication")
+	}
+	// nolint:gosec
+	// We can ignore the gosec G304 warning on this one because `path` comes from the configuration.
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client certificate authorities: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificate found in %s", path)
+	}
+	return pool, nil
+}
@@ -0,0 +1,224 @@
+package clients
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/login"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key}
+}
+
+func (ca *testCA) issue(t *testing.T, template *x509.Certificate) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template.SerialNumber = big.NewInt(2)
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+func (ca *testCA) writePEM(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}), 0600))
+	return path
+}
+
+func requestWithCert(cert *x509.Certificate) *authn.Request {
+	return &authn.Request{HTTPRequest: &http.Request{TLS: &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}}}
+}
+
+func TestClientCert_Authenticate(t *testing.T) {
+	ca := newTestCA(t)
+	spiffe, err := url.Parse("spiffe://example.org/ns/prod/sa/deployer")
+	require.NoError(t, err)
+
+	userCert := ca.issue(t, &x509.Certificate{
+		Subject:        pkix.Name{CommonName: "jdoe", OrganizationalUnit: []string{"sre"}},
+		EmailAddresses: []string{"jdoe@example.org"},
+		URIs:           []*url.URL{spiffe},
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+
+	type testCase struct {
+		desc             string
+		cert             *x509.Certificate
+		cfg              func(cfg *setting.Cfg)
+		expectedErr      error
+		expectedIdentity *authn.Identity
+	}
+
+	boolPtr := func(b bool) *bool { return &b }
+
+	tests := []testCase{
+		{
+			desc: "should map the certificate fields to the identity",
+			cert: userCert,
+			cfg: func(cfg *setting.Cfg) {
+				cfg.ClientCertAuthRoleAttributePath = "contains(subject.organizational_unit[*], 'sre') && 'Editor' || 'Viewer'"
+				cfg.ClientCertAuthAutoSignUp = true
+			},
+			expectedIdentity: &authn.Identity{
+				Login:           "jdoe",
+				Email:           "jdoe@example.org",
+				Name:            "jdoe",
+				AuthenticatedBy: login.ClientCertAuthModule,
+				AuthID:          "CN=jdoe,OU=sre",
+				OrgRoles:        map[int64]org.RoleType{1: org.RoleEditor},
+				ClientParams: authn.ClientParams{
+					SyncUser:        true,
+					FetchSyncedUser: true,
+					SyncPermissions: true,
+					SyncOrgRoles:    true,
+					AllowSignUp:     true,
+				},
+			},
+		},
+		{
+			desc: "should map the uri of a workload certificate and assign grafana admin",
+			cert: userCert,
+			cfg: func(cfg *setting.Cfg) {
+				cfg.ClientCertAuthLoginAttributePath = "uris[0]"
+				cfg.ClientCertAuthEmailAttributePath = ""
+				cfg.ClientCertAuthRoleAttributePath = "'GrafanaAdmin'"
+				cfg.ClientCertAuthAllowAssignGrafanaAdmin = true
+			},
+			expectedIdentity: &authn.Identity{
+				Login:           "spiffe://example.org/ns/prod/sa/deployer",
+				Name:            "jdoe",
+				AuthenticatedBy: login.ClientCertAuthModule,
+				AuthID:          "CN=jdoe,OU=sre",
+				OrgRoles:        map[int64]org.RoleType{1: org.RoleAdmin},
+				IsGrafanaAdmin:  boolPtr(true),
+				ClientParams: authn.ClientParams{
+					SyncUser:        true,
+					FetchSyncedUser: true,
+					SyncPermissions: true,
+					SyncOrgRoles:    true,
+				},
+			},
+		},
+		{
+			desc:        "should fail for a certificate issued by another authority",
+			cert:        newTestCA(t).issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "jdoe"}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}),
+			expectedErr: errClientCertInvalid,
+		},
+		{
+			desc:        "should fail for a certificate not issued for client authentication",
+			cert:        ca.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "jdoe"}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}}),
+			expectedErr: errClientCertInvalid,
+		},
+		{
+			desc:        "should fail when login and email are missing",
+			cert:        ca.issue(t, &x509.Certificate{Subject: pkix.Name{Organization: []string{"grafana"}}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}),
+			expectedErr: errClientCertMissingAttribute,
+		},
+		{
+			desc: "should fail for an invalid role in strict mode",
+			cert: userCert,
+			cfg: func(cfg *setting.Cfg) {
+				cfg.ClientCertAuthRoleAttributePath = "subject.common_name"
+				cfg.ClientCertAuthRoleAttributeStrict = true
+			},
+			expectedErr: errClientCertInvalidRole,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			cfg := setting.NewCfg()
+			cfg.ClientCertAuthCACertPath = ca.writePEM(t)
+			cfg.ClientCertAuthLoginAttributePath = "subject.common_name"
+			cfg.ClientCertAuthEmailAttributePath = "email_addresses[0]"
+			cfg.ClientCertAuthNameAttributePath = "subject.common_name"
+			if tt.cfg != nil {
+				tt.cfg(cfg)
+			}
+
+			c, err := ProvideClientCert(cfg)
+			require.NoError(t, err)
+
+			identity, err := c.Authenticate(context.Background(), requestWithCert(tt.cert))
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				assert.Nil(t, identity)
+				return
+			}
+
+			require.NoError(t, err)
+			// the lookup params point to the identity fields
+			tt.expectedIdentity.ClientParams.LookUpParams = identity.ClientParams.LookUpParams
+			assert.EqualValues(t, tt.expectedIdentity, identity)
+		})
+	}
+}
+
+func TestClientCert_Test(t *testing.T) {
+	ca := newTestCA(t)
+	cfg := setting.NewCfg()
+	cfg.ClientCertAuthCACertPath = ca.writePEM(t)
+	c, err := ProvideClientCert(cfg)
+	require.NoError(t, err)
+
+	assert.False(t, c.Test(context.Background(), &authn.Request{HTTPRequest: &http.Request{}}))
+	assert.False(t, c.Test(context.Background(), &authn.Request{HTTPRequest: &http.Request{TLS: &tls.ConnectionState{}}}))
+	assert.True(t, c.Test(context.Background(), requestWithCert(ca.cert)))
+}
+
+func TestProvideClientCert(t *testing.T) {
+	cfg := setting.NewCfg()
+	_, err := ProvideClientCert(cfg)
+	assert.Error(t, err)
+
+	cfg.ClientCertAuthCACertPath = filepath.Join(t.TempDir(), "missing.pem")
+	_, err = ProvideClientCert(cfg)
+	assert.Error(t, err)
+}
@@ -2,29 +2,38 @@ package clients
 
 import (
 	"context"
+	"errors"
 
 	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/login"
+	"github.com/grafana/grafana/pkg/services/loginattempt"
+	"github.com/grafana/grafana/pkg/services/totp"
 	"github.com/grafana/grafana/pkg/util/errutil"
 	"github.com/grafana/grafana/pkg/web"
 )
 
 var (
-	errBadForm = errutil.BadRequest("form-auth.invalid", errutil.WithPublicMessage("bad login data"))
+	errBadForm             = errutil.BadRequest("form-auth.invalid", errutil.WithPublicMessage("bad login data"))
+	errFormAppPasswordUsed = errutil.Unauthorized("form-auth.app-password", errutil.WithPublicMessage("App passwords can not be used to log in"))
 )
 
 var _ authn.Client = new(Form)
 
-func ProvideForm(client authn.PasswordClient) *Form {
-	return &Form{client}
+func ProvideForm(client authn.PasswordClient, totpService totp.Service, loginAttempts loginattempt.Service) *Form {
+	return &Form{client, totpService, loginAttempts}
 }
 
 type Form struct {
-	client authn.PasswordClient
+	client        authn.PasswordClient
+	totpService   totp.Service
+	loginAttempts loginattempt.Service
 }
 
 type loginForm struct {
 	Username string `json:"user" binding:"Required"`
 	Password string `json:"password" binding:"Required"`
+	// TOTPCode is the second factor of users with two-factor authentication, it can also be a recovery code
+	TOTPCode string `json:"totpCode"`
 }
 
 func (c *Form) Name() string {
@@ -36,5 +45,35 @@ func (c *Form) Authenticate(ctx context.Context, r *authn.Request) (*authn.Ident
 	if err := web.Bind(r.HTTPRequest, &form); err != nil {
 		return nil, errBadForm.Errorf("failed to parse request: %w", err)
 	}
-	return c.client.AuthenticatePassword(ctx, r, form.Username, form.Password)
+
+	identity, err := c.client.AuthenticatePassword(ctx, r, form.Username, form.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	switch identity.AuthenticatedBy {
+	case login.AppPasswordAuthModule:
+		// app passwords bypass the second factor so they are limited to basic auth
+		return nil, errFormAppPasswordUsed.Errorf("app password used for login form")
+	case login.PasswordAuthModule:
+		if err := c.verifySecondFactor(ctx, r, identity, form); err != nil {
+			return nil, err
+		}
+	}
+
+	return identity, nil
+}
+
+func (c *Form) verifySecondFactor(ctx context.Context, r *authn.Request, identity *authn.Identity, form loginForm) error {
+	if !c.totpService.IsEnabled() {
+		return nil
+	}
+
+	_, userID := identity.NamespacedID()
+	isGrafanaAdmin := identity.IsGrafanaAdmin != nil && *identity.IsGrafanaAdmin
+	err := c.totpService.VerifyLogin(ctx, userID, isGrafanaAdmin, form.TOTPCode)
+	if errors.Is(err, totp.ErrInvalidCode) {
+		_ = c.loginAttempts.Add(ctx, form.Username, c.loginAttempts.ClientIP(r.HTTPRequest))
+	}
+	return err
 }
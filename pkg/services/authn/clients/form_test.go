@@ -11,6 +11,10 @@ import (
 
 	"github.com/grafana/grafana/pkg/services/authn"
 	"github.com/grafana/grafana/pkg/services/authn/authntest"
+	"github.com/grafana/grafana/pkg/services/login"
+	"github.com/grafana/grafana/pkg/services/loginattempt/loginattempttest"
+	"github.com/grafana/grafana/pkg/services/totp"
+	"github.com/grafana/grafana/pkg/services/totp/totptest"
 )
 
 func TestForm_Authenticate(t *testing.T) {
@@ -40,9 +44,79 @@ func TestForm_Authenticate(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.desc, func(t *testing.T) {
-			c := ProvideForm(&authntest.FakePasswordClient{})
+			c := ProvideForm(&authntest.FakePasswordClient{ExpectedIdentity: &authn.Identity{ID: "user:1"}}, &totptest.FakeService{}, loginattempttest.FakeLoginAttemptService{})
 			_, err := c.Authenticate(context.Background(), tt.req)
 			assert.ErrorIs(t, err, tt.expectedErr)
 		})
 	}
 }
+
+func TestForm_AuthenticateTOTP(t *testing.T) {
+	type testCase struct {
+		desc            string
+		body            string
+		identity        *authn.Identity
+		verifyErr       error
+		expectedErr     error
+		expectedCode    string
+		expectedAttempt bool
+	}
+
+	tests := []testCase{
+		{
+			desc:         "should pass the code to the totp service",
+			body:         `{"user": "test", "password": "test", "totpCode": "123456"}`,
+			identity:     &authn.Identity{ID: "user:1", AuthenticatedBy: login.PasswordAuthModule},
+			expectedCode: "123456",
+		},
+		{
+			desc:            "should fail and count a login attempt on invalid code",
+			body:            `{"user": "test", "password": "test", "totpCode": "000000"}`,
+			identity:        &authn.Identity{ID: "user:1", AuthenticatedBy: login.PasswordAuthModule},
+			verifyErr:       totp.ErrInvalidCode.Errorf("invalid code"),
+			expectedErr:     totp.ErrInvalidCode,
+			expectedCode:    "000000",
+			expectedAttempt: true,
+		},
+		{
+			desc:        "should fail when the code is missing",
+			body:        `{"user": "test", "password": "test"}`,
+			identity:    &authn.Identity{ID: "user:1", AuthenticatedBy: login.PasswordAuthModule},
+			verifyErr:   totp.ErrCodeRequired.Errorf("missing code"),
+			expectedErr: totp.ErrCodeRequired,
+		},
+		{
+			desc:        "should reject app passwords",
+			body:        `{"user": "test", "password": "glap_secret"}`,
+			identity:    &authn.Identity{ID: "user:1", AuthenticatedBy: login.AppPasswordAuthModule},
+			expectedErr: errFormAppPasswordUsed,
+		},
+		{
+			desc:     "should not check the second factor of ldap users",
+			body:     `{"user": "test", "password": "test"}`,
+			identity: &authn.Identity{ID: "user:1", AuthenticatedBy: login.LDAPAuthModule},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			totpService := &totptest.FakeService{ExpectedEnabled: true, ExpectedVerifyLoginErr: tt.verifyErr}
+			loginAttempts := &loginattempttest.MockLoginAttemptService{}
+			c := ProvideForm(&authntest.FakePasswordClient{ExpectedIdentity: tt.identity}, totpService, loginAttempts)
+
+			identity, err := c.Authenticate(context.Background(), &authn.Request{HTTPRequest: &http.Request{
+				Header: map[string][]string{"Content-Type": {"application/json"}},
+				Body:   io.NopCloser(strings.NewReader(tt.body)),
+			}})
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				assert.Nil(t, identity)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.identity, identity)
+			}
+			assert.Equal(t, tt.expectedCode, totpService.VerifyLoginCode)
+			assert.Equal(t, tt.expectedAttempt, loginAttempts.AddCalled)
+		})
+	}
+}
@@ -19,7 +19,10 @@ import (
 	"github.com/grafana/grafana/pkg/services/annotations"
 	"github.com/grafana/grafana/pkg/services/dashboardsnapshots"
 	dashver "github.com/grafana/grafana/pkg/services/dashboardversion"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/ngalert/image"
+	"github.com/grafana/grafana/pkg/services/oauthserver"
+	oasstore "github.com/grafana/grafana/pkg/services/oauthserver/store"
 	"github.com/grafana/grafana/pkg/services/queryhistory"
 	"github.com/grafana/grafana/pkg/services/shorturls"
 	tempuser "github.com/grafana/grafana/pkg/services/temp_user"
@@ -43,6 +46,7 @@ func ProvideService(cfg *setting.Cfg, serverLockService *serverlock.ServerLockSe
 		tempUserService:           tempUserService,
 		tracer:                    tracer,
 		annotationCleaner:         annotationCleaner,
+		oauthSessionStore:         oasstore.NewSessionStore(sqlstore),
 	}
 	return s
 }
@@ -60,6 +64,7 @@ type CleanUpService struct {
 	deleteExpiredImageService *image.DeleteExpiredService
 	tempUserService           tempuser.Service
 	annotationCleaner         annotations.Cleaner
+	oauthSessionStore         oauthserver.SessionStore
 }
 
 type cleanUpJob struct {
@@ -102,6 +107,7 @@ func (srv *CleanUpService) clean(ctx context.Context) {
 		{"expire old user invites", srv.expireOldUserInvites},
 		{"delete stale short URLs", srv.deleteStaleShortURLs},
 		{"delete stale query history", srv.deleteStaleQueryHistory},
+		{"delete expired OAuth2 sessions", srv.deleteExpiredOAuthSessions},
 	}
 
 	logger := srv.log.FromContext(ctx)
@@ -237,6 +243,19 @@ func (srv *CleanUpService) expireOldUserInvites(ctx context.Context) {
 	}
 }
 
+func (srv *CleanUpService) deleteExpiredOAuthSessions(ctx context.Context) {
+	logger := srv.log.FromContext(ctx)
+	// The sessions are only stored when the OAuth2 server is enabled
+	if !srv.Cfg.IsFeatureToggleEnabled(featuremgmt.FlagExternalServiceAuth) {
+		return
+	}
+	if rowsAffected, err := srv.oauthSessionStore.DeleteExpiredSessions(ctx, time.Now()); err != nil {
+		logger.Error("Failed to delete expired OAuth2 sessions", "error", err.Error())
+	} else {
+		logger.Debug("Deleted expired OAuth2 sessions", "rows affected", rowsAffected)
+	}
+}
+
 func (srv *CleanUpService) deleteStaleShortURLs(ctx context.Context) {
 	logger := srv.log.FromContext(ctx)
 	cmd := shorturls.DeleteShortUrlCommand{
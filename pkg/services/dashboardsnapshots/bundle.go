@@ -0,0 +1,249 @@
+package dashboardsnapshots
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+)
+
+// BundleVersion is the version of the snapshot bundle layout written by WriteBundle.
+const BundleVersion = 1
+
+const (
+	bundleManifestPath  = "manifest.json"
+	bundleDashboardPath = "dashboard.json"
+	bundleDataDir       = "data"
+
+	// snapshotDataPathKey replaces the snapshotData of panels and annotations in the bundled
+	// dashboard and points to the file holding the data.
+	snapshotDataPathKey = "snapshotDataPath"
+
+	// maxBundleEntrySize limits the decompressed size of a single file of a bundle.
+	maxBundleEntrySize = 512 << 20
+)
+
+// BundleManifest describes the content of a snapshot bundle. It is stored as manifest.json
+// at the root of the bundle, next to dashboard.json and one file per panel with data.
+type BundleManifest struct {
+	Version        int          `json:"version"`
+	GrafanaVersion string       `json:"grafanaVersion"`
+	ExportedAt     time.Time    `json:"exportedAt"`
+	Name           string       `json:"name"`
+	Key            string       `json:"key"`
+	OriginalURL    string       `json:"originalUrl,omitempty"`
+	Created        time.Time    `json:"created"`
+	Expires        time.Time    `json:"expires"`
+	Data           []BundleData `json:"data"`
+}
+
+// BundleData points to the data of a single panel or annotation query.
+type BundleData struct {
+	PanelID int64  `json:"panelId,omitempty"`
+	Title   string `json:"title,omitempty"`
+	// Path of the file holding the data frames, in the data frame JSON format used by snapshots.
+	Path   string `json:"path"`
+	Frames int    `json:"frames"`
+}
+
+// Bundle is a snapshot read from a bundle.
+type Bundle struct {
+	Manifest BundleManifest
+	// Dashboard is the snapshot dashboard with the data embedded again.
+	Dashboard *simplejson.Json
+}
+
+// WriteBundle writes the snapshot as a self-contained zip bundle. The data embedded in the
+// dashboard is moved to one file per panel so that it can be inspected without Grafana.
+func WriteBundle(w io.Writer, snapshot *DashboardSnapshot, grafanaVersion string) error {
+	if snapshot.Dashboard == nil {
+		return fmt.Errorf("snapshot %q has no dashboard", snapshot.Key)
+	}
+
+	// the dashboard is modified below, work on a copy
+	raw, err := snapshot.Dashboard.Encode()
+	if err != nil {
+		return err
+	}
+	var dashboard map[string]any
+	if err := json.Unmarshal(raw, &dashboard); err != nil {
+		return err
+	}
+
+	manifest := BundleManifest{
+		Version:        BundleVersion,
+		GrafanaVersion: grafanaVersion,
+		ExportedAt:     time.Now().UTC(),
+		Name:           snapshot.Name,
+		Key:            snapshot.Key,
+		OriginalURL:    snapshot.Dashboard.GetPath("snapshot", "originalUrl").MustString(),
+		Created:        snapshot.Created,
+		Expires:        snapshot.Expires,
+		Data:           []BundleData{},
+	}
+
+	zw := zip.NewWriter(w)
+	writeFile := func(name string, v any) error {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	var extractErr error
+	walkSnapshotData(dashboard, func(holder map[string]any) {
+		if extractErr != nil {
+			return
+		}
+		data := holder["snapshotData"]
+		delete(holder, "snapshotData")
+
+		entry := BundleData{
+			Path: path.Join(bundleDataDir, fmt.Sprintf("%d.json", len(manifest.Data)+1)),
+		}
+		if id, ok := holder["id"].(float64); ok {
+			entry.PanelID = int64(id)
+		}
+		if title, ok := holder["title"].(string); ok {
+			entry.Title = title
+		} else if name, ok := holder["name"].(string); ok {
+			entry.Title = name
+		}
+		if frames, ok := data.([]any); ok {
+			entry.Frames = len(frames)
+		}
+		holder[snapshotDataPathKey] = entry.Path
+		manifest.Data = append(manifest.Data, entry)
+		extractErr = writeFile(entry.Path, data)
+	})
+	if extractErr != nil {
+		return extractErr
+	}
+
+	if err := writeFile(bundleDashboardPath, dashboard); err != nil {
+		return err
+	}
+	if err := writeFile(bundleManifestPath, manifest); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// ReadBundle reads a bundle written by WriteBundle and embeds the data into the dashboard again.
+// The returned errors wrap ErrBundleInvalid when the bundle is malformed.
+func ReadBundle(data []byte) (*Bundle, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, ErrBundleInvalid.Errorf("failed to open bundle: %w", err)
+	}
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[path.Clean(f.Name)] = f
+	}
+	readFile := func(name string, v any) error {
+		f, ok := files[path.Clean(name)]
+		if !ok {
+			return ErrBundleInvalid.Errorf("bundle does not contain %s", name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return ErrBundleInvalid.Errorf("failed to read %s: %w", name, err)
+		}
+		defer func() { _ = rc.Close() }()
+		content, err := io.ReadAll(io.LimitReader(rc, maxBundleEntrySize+1))
+		if err != nil {
+			return ErrBundleInvalid.Errorf("failed to read %s: %w", name, err)
+		}
+		if len(content) > maxBundleEntrySize {
+			return ErrBundleInvalid.Errorf("%s is too large", name)
+		}
+		if err := json.Unmarshal(content, v); err != nil {
+			return ErrBundleInvalid.Errorf("failed to parse %s: %w", name, err)
+		}
+		return nil
+	}
+
+	var manifest BundleManifest
+	if err := readFile(bundleManifestPath, &manifest); err != nil {
+		return nil, err
+	}
+	if manifest.Version < 1 || manifest.Version > BundleVersion {
+		return nil, ErrBundleInvalid.Errorf("unsupported bundle version %d", manifest.Version)
+	}
+
+	var dashboard map[string]any
+	if err := readFile(bundleDashboardPath, &dashboard); err != nil {
+		return nil, err
+	}
+
+	var embedErr error
+	walkSnapshotData(dashboard, func(holder map[string]any) {
+		p, ok := holder[snapshotDataPathKey].(string)
+		if !ok || embedErr != nil {
+			return
+		}
+		delete(holder, snapshotDataPathKey)
+		var frames any
+		if err := readFile(p, &frames); err != nil {
+			embedErr = err
+			return
+		}
+		holder["snapshotData"] = frames
+	})
+	if embedErr != nil {
+		return nil, embedErr
+	}
+
+	return &Bundle{
+		Manifest:  manifest,
+		Dashboard: simplejson.NewFromAny(dashboard),
+	}, nil
+}
+
+// walkSnapshotData calls fn for every panel, including the panels of collapsed rows, and
+// every annotation query of the dashboard which has snapshot data or points to it.
+func walkSnapshotData(dashboard map[string]any, fn func(holder map[string]any)) {
+	visit := func(holder map[string]any) {
+		_, hasData := holder["snapshotData"]
+		_, hasPath := holder[snapshotDataPathKey]
+		if hasData || hasPath {
+			fn(holder)
+		}
+	}
+
+	var walkPanels func(panels any)
+	walkPanels = func(panels any) {
+		list, ok := panels.([]any)
+		if !ok {
+			return
+		}
+		for _, p := range list {
+			panel, ok := p.(map[string]any)
+			if !ok {
+				continue
+			}
+			visit(panel)
+			walkPanels(panel["panels"])
+		}
+	}
+	walkPanels(dashboard["panels"])
+
+	if annotations, ok := dashboard["annotations"].(map[string]any); ok {
+		if list, ok := annotations["list"].([]any); ok {
+			for _, a := range list {
+				if annotation, ok := a.(map[string]any); ok {
+					visit(annotation)
+				}
+			}
+		}
+	}
+}
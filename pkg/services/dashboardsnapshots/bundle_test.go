@@ -0,0 +1,121 @@
+package dashboardsnapshots
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/util/errutil"
+)
+
+const snapshotDashboard = `{
+	"title": "Incident",
+	"snapshot": {"originalUrl": "http://localhost:3000/d/abc"},
+	"annotations": {"list": [{"name": "Deployments", "snapshotData": [{"time": 1, "text": "deploy"}]}]},
+	"panels": [
+		{"id": 1, "title": "CPU", "snapshotData": [{"fields": [{"name": "time", "values": [1, 2]}, {"name": "cpu", "values": [0.5, 0.7]}]}]},
+		{"id": 2, "type": "row", "collapsed": true, "panels": [
+			{"id": 3, "title": "Memory", "snapshotData": [{"fields": []}, {"fields": []}]}
+		]},
+		{"id": 4, "type": "text"}
+	]
+}`
+
+func TestBundleRoundTrip(t *testing.T) {
+	dashboard, err := simplejson.NewJson([]byte(snapshotDashboard))
+	require.NoError(t, err)
+	created := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	err = WriteBundle(&buf, &DashboardSnapshot{
+		Name:      "Incident 42",
+		Key:       "abc",
+		Dashboard: dashboard,
+		Created:   created,
+		Expires:   created.Add(time.Hour),
+	}, "10.1.0")
+	require.NoError(t, err)
+
+	// the data is stored next to the dashboard, not inside of it
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	names := []string{}
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		if f.Name == bundleDashboardPath {
+			rc, err := f.Open()
+			require.NoError(t, err)
+			content, err := io.ReadAll(rc)
+			require.NoError(t, err)
+			require.NotContains(t, string(content), "snapshotData\"")
+		}
+	}
+	require.ElementsMatch(t, []string{"manifest.json", "dashboard.json", "data/1.json", "data/2.json", "data/3.json"}, names)
+
+	bundle, err := ReadBundle(buf.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, "Incident 42", bundle.Manifest.Name)
+	require.Equal(t, "10.1.0", bundle.Manifest.GrafanaVersion)
+	require.Equal(t, "http://localhost:3000/d/abc", bundle.Manifest.OriginalURL)
+	require.Equal(t, []BundleData{
+		{PanelID: 1, Title: "CPU", Path: "data/1.json", Frames: 1},
+		{PanelID: 3, Title: "Memory", Path: "data/2.json", Frames: 2},
+		{Title: "Deployments", Path: "data/3.json", Frames: 1},
+	}, bundle.Manifest.Data)
+
+	expected, err := dashboard.Encode()
+	require.NoError(t, err)
+	actual, err := bundle.Dashboard.Encode()
+	require.NoError(t, err)
+	require.JSONEq(t, string(expected), string(actual))
+}
+
+func TestReadBundle(t *testing.T) {
+	writeZip := func(t *testing.T, files map[string]string) []byte {
+		t.Helper()
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for name, content := range files {
+			f, err := zw.Create(name)
+			require.NoError(t, err)
+			_, err = f.Write([]byte(content))
+			require.NoError(t, err)
+		}
+		require.NoError(t, zw.Close())
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{name: "missing manifest", files: map[string]string{"dashboard.json": `{}`}},
+		{name: "unsupported version", files: map[string]string{"manifest.json": `{"version": 2}`, "dashboard.json": `{}`}},
+		{name: "missing dashboard", files: map[string]string{"manifest.json": `{"version": 1}`}},
+		{name: "missing data", files: map[string]string{
+			"manifest.json":  `{"version": 1}`,
+			"dashboard.json": `{"panels": [{"id": 1, "snapshotDataPath": "data/1.json"}]}`,
+		}},
+		{name: "invalid json", files: map[string]string{"manifest.json": `{"version":`}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ReadBundle(writeZip(t, tc.files))
+			require.ErrorIs(t, err, ErrBundleInvalid)
+
+			var gfErr errutil.Error
+			require.ErrorAs(t, err, &gfErr)
+			require.Equal(t, errutil.StatusBadRequest, gfErr.Reason.Status())
+		})
+	}
+
+	t.Run("not a zip archive", func(t *testing.T) {
+		_, err := ReadBundle([]byte(`{"dashboard": {}}`))
+		require.ErrorIs(t, err, ErrBundleInvalid)
+	})
+}
@@ -5,3 +5,5 @@ import (
 )
 
 var ErrBaseNotFound = errutil.NotFound("dashboardsnapshots.not-found", errutil.WithPublicMessage("Snapshot not found"))
+
+var ErrBundleInvalid = errutil.BadRequest("dashboardsnapshots.bundle-invalid", errutil.WithPublicMessage("Snapshot bundle is not valid"))
@@ -0,0 +1,170 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/robfig/cron/v3"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/serverlock"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/auth/identity"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/dashboardsnapshots"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/query"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+// SnapshotScheduler takes the dashboard snapshots configured in the snapshot_schedule.* sections.
+type SnapshotScheduler struct {
+	cfg               *setting.Cfg
+	log               log.Logger
+	serverLock        *serverlock.ServerLockService
+	dashboardService  dashboards.DashboardService
+	dataSourceService datasources.DataSourceService
+	queryService      query.Service
+	snapshotService   dashboardsnapshots.Service
+
+	schedules []schedule
+	now       func() time.Time
+}
+
+type schedule struct {
+	setting.SnapshotSchedule
+	cron cron.Schedule
+}
+
+func ProvideService(
+	cfg *setting.Cfg,
+	serverLock *serverlock.ServerLockService,
+	dashboardService dashboards.DashboardService,
+	dataSourceService datasources.DataSourceService,
+	queryService query.Service,
+	snapshotService dashboardsnapshots.Service,
+) (*SnapshotScheduler, error) {
+	s := &SnapshotScheduler{
+		cfg:               cfg,
+		log:               log.New("dashboardsnapshots.scheduler"),
+		serverLock:        serverLock,
+		dashboardService:  dashboardService,
+		dataSourceService: dataSourceService,
+		queryService:      queryService,
+		snapshotService:   snapshotService,
+		now:               time.Now,
+	}
+
+	for _, sc := range cfg.SnapshotSchedules {
+		parsed, err := cron.ParseStandard(sc.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot schedule %q: invalid cron expression %q: %w", sc.Name, sc.Cron, err)
+		}
+		s.schedules = append(s.schedules, schedule{SnapshotSchedule: sc, cron: parsed})
+	}
+
+	return s, nil
+}
+
+// IsDisabled returns true when snapshots are disabled or no schedule is configured.
+func (s *SnapshotScheduler) IsDisabled() bool {
+	return !s.cfg.SnapshotEnabled || len(s.schedules) == 0
+}
+
+func (s *SnapshotScheduler) Run(ctx context.Context) error {
+	c := cron.New(cron.WithLocation(time.UTC))
+	for _, sc := range s.schedules {
+		sc := sc
+		c.Schedule(sc.cron, cron.FuncJob(func() {
+			s.run(ctx, sc)
+		}))
+		s.log.Info("Scheduled dashboard snapshot", "schedule", sc.Name, "dashboard", sc.DashboardUID, "orgId", sc.OrgID, "cron", sc.Cron)
+	}
+
+	c.Start()
+	<-ctx.Done()
+	<-c.Stop().Done()
+	return ctx.Err()
+}
+
+// run takes the snapshot of a schedule on a single instance. The lock is held for half of the interval
+// between two runs, which prevents another instance from taking the same snapshot while still allowing
+// the next run when the clocks of the instances drift a bit.
+func (s *SnapshotScheduler) run(ctx context.Context, sc schedule) {
+	next := sc.cron.Next(s.now().UTC())
+	interval := sc.cron.Next(next).Sub(next)
+
+	err := s.serverLock.LockAndExecute(ctx, "dashboard snapshot schedule "+sc.Name, interval/2, func(ctx context.Context) {
+		snapshot, err := s.takeSnapshot(ctx, sc.SnapshotSchedule)
+		if err != nil {
+			s.log.Error("Failed to take scheduled dashboard snapshot", "schedule", sc.Name, "dashboard", sc.DashboardUID, "error", err)
+			return
+		}
+		s.log.Info("Took scheduled dashboard snapshot", "schedule", sc.Name, "dashboard", sc.DashboardUID, "key", snapshot.Key)
+	})
+	if err != nil {
+		s.log.Error("Failed to lock and execute scheduled dashboard snapshot", "schedule", sc.Name, "error", err)
+	}
+}
+
+// takeSnapshot runs the queries of the dashboard and stores the result as a snapshot. Failing queries
+// are logged and leave the panel without data, the snapshot is stored anyway.
+func (s *SnapshotScheduler) takeSnapshot(ctx context.Context, sc setting.SnapshotSchedule) (*dashboardsnapshots.DashboardSnapshot, error) {
+	now := s.now()
+
+	dash, err := s.dashboardService.GetDashboard(ctx, &dashboards.GetDashboardQuery{UID: sc.DashboardUID, OrgID: sc.OrgID})
+	if err != nil {
+		return nil, err
+	}
+	if dash.IsFolder {
+		return nil, dashboards.ErrDashboardNotFound
+	}
+
+	tr, err := resolveTimeRange(dash.Data, sc.TimeFrom, sc.TimeTo, now)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultDS, err := s.dataSourceService.GetDefaultDataSource(ctx, &datasources.GetDefaultDataSourceQuery{OrgID: sc.OrgID})
+	if err != nil && !errors.Is(err, datasources.ErrDataSourceNotFound) {
+		return nil, err
+	}
+
+	model, errs := buildSnapshot(ctx, dash.Data, tr, defaultDS, backgroundUser(sc.OrgID), s.queryData, now)
+	for _, err := range errs {
+		s.log.Warn("Query of scheduled dashboard snapshot failed", "schedule", sc.Name, "dashboard", sc.DashboardUID, "error", err)
+	}
+
+	cmd := &dashboardsnapshots.CreateDashboardSnapshotCommand{
+		Dashboard: model,
+		Name:      fmt.Sprintf("%s %s", dash.Title, now.UTC().Format("2006-01-02 15:04 UTC")),
+		Expires:   int64(sc.Expires.Seconds()),
+		OrgID:     sc.OrgID,
+	}
+	if cmd.Key, err = util.GetRandomString(32); err != nil {
+		return nil, err
+	}
+	if cmd.DeleteKey, err = util.GetRandomString(32); err != nil {
+		return nil, err
+	}
+
+	return s.snapshotService.CreateDashboardSnapshot(ctx, cmd)
+}
+
+func (s *SnapshotScheduler) queryData(ctx context.Context, user identity.Requester, req dtos.MetricRequest) (*backend.QueryDataResponse, error) {
+	return s.queryService.QueryData(ctx, user, false, req)
+}
+
+// backgroundUser is the identity the scheduled queries are executed as.
+func backgroundUser(orgID int64) identity.Requester {
+	return accesscontrol.BackgroundUser("dashboard_snapshot_scheduler", orgID, org.RoleViewer, []accesscontrol.Permission{
+		{Action: datasources.ActionQuery, Scope: datasources.ScopeAll},
+		{Action: datasources.ActionRead, Scope: datasources.ScopeAll},
+	})
+}
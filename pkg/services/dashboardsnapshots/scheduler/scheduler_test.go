@@ -0,0 +1,214 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/auth/identity"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/dashboardsnapshots"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	datasourcefakes "github.com/grafana/grafana/pkg/services/datasources/fakes"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const capacityDashboard = `{
+	"uid": "capacity",
+	"title": "Capacity",
+	"time": {"from": "now-1d/d", "to": "now-1d/d"},
+	"templating": {"list": [
+		{"name": "job", "type": "query", "query": "label_values(job)", "current": {"text": "api", "value": "api"}, "datasource": {"uid": "prom"}}
+	]},
+	"panels": [
+		{"id": 1, "title": "CPU", "datasource": {"uid": "prom", "type": "prometheus"}, "links": [{"url": "http://internal"}],
+		 "targets": [{"refId": "A", "expr": "rate(cpu{job=\"$job\"}[5m])"}, {"refId": "B", "expr": "up", "hide": true}]},
+		{"id": 2, "type": "row", "collapsed": true, "panels": [
+			{"id": 3, "title": "Disk", "targets": [{"refId": "A", "expr": "disk{job=\"${job}\"}"}]}
+		]},
+		{"id": 4, "type": "text", "options": {"content": "notes"}}
+	]
+}`
+
+type fakeQueryService struct {
+	requests []dtos.MetricRequest
+	users    []identity.Requester
+}
+
+func (f *fakeQueryService) Run(ctx context.Context) error { return nil }
+
+func (f *fakeQueryService) QueryData(ctx context.Context, user identity.Requester, skipDSCache bool, req dtos.MetricRequest) (*backend.QueryDataResponse, error) {
+	f.requests = append(f.requests, req)
+	f.users = append(f.users, user)
+
+	resp := backend.NewQueryDataResponse()
+	for _, q := range req.Queries {
+		refID := q.Get("refId").MustString()
+		frame := data.NewFrame("",
+			data.NewField("time", nil, []time.Time{time.UnixMilli(1000), time.UnixMilli(2000)}),
+			data.NewField("value", data.Labels{"job": "api"}, []*float64{float64Ptr(0.5), nil}),
+		)
+		frame.Meta = &data.FrameMeta{ExecutedQueryString: "secret"}
+		resp.Responses[refID] = backend.DataResponse{Frames: data.Frames{frame}}
+	}
+	return resp, nil
+}
+
+type fakeDataSourceService struct {
+	datasourcefakes.FakeDataSourceService
+	defaultDS *datasources.DataSource
+}
+
+func (f *fakeDataSourceService) GetDefaultDataSource(ctx context.Context, query *datasources.GetDefaultDataSourceQuery) (*datasources.DataSource, error) {
+	if f.defaultDS == nil {
+		return nil, datasources.ErrDataSourceNotFound
+	}
+	return f.defaultDS, nil
+}
+
+func float64Ptr(f float64) *float64 { return &f }
+
+func TestTakeSnapshot(t *testing.T) {
+	model, err := simplejson.NewJson([]byte(capacityDashboard))
+	require.NoError(t, err)
+
+	dashboardService := dashboards.NewFakeDashboardService(t)
+	dashboardService.On("GetDashboard", mock.Anything, &dashboards.GetDashboardQuery{UID: "capacity", OrgID: 2}).
+		Return(&dashboards.Dashboard{UID: "capacity", OrgID: 2, Title: "Capacity", Data: model}, nil)
+
+	var created *dashboardsnapshots.CreateDashboardSnapshotCommand
+	snapshotService := dashboardsnapshots.NewMockService(t)
+	snapshotService.On("CreateDashboardSnapshot", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			created = args.Get(1).(*dashboardsnapshots.CreateDashboardSnapshotCommand)
+		}).
+		Return(&dashboardsnapshots.DashboardSnapshot{Key: "key"}, nil)
+
+	queryService := &fakeQueryService{}
+	now := time.Date(2023, 7, 15, 6, 0, 0, 0, time.UTC)
+	s := &SnapshotScheduler{
+		cfg:               setting.NewCfg(),
+		log:               log.NewNopLogger(),
+		dashboardService:  dashboardService,
+		dataSourceService: &fakeDataSourceService{defaultDS: &datasources.DataSource{UID: "default-prom", Type: "prometheus"}},
+		queryService:      queryService,
+		snapshotService:   snapshotService,
+		now:               func() time.Time { return now },
+	}
+
+	_, err = s.takeSnapshot(context.Background(), setting.SnapshotSchedule{
+		Name: "capacity", OrgID: 2, DashboardUID: "capacity", Expires: 24 * time.Hour,
+	})
+	require.NoError(t, err)
+
+	// one request per panel with queries, hidden queries are skipped
+	require.Len(t, queryService.requests, 2)
+	from := time.Date(2023, 7, 14, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 7, 15, 0, 0, 0, 0, time.UTC).Add(-time.Millisecond)
+	for _, req := range queryService.requests {
+		require.Equal(t, "1689292800000", req.From)
+		require.Equal(t, "1689379199999", req.To)
+		require.Len(t, req.Queries, 1)
+		require.Equal(t, to.Sub(from).Milliseconds()/defaultMaxDataPoints, req.Queries[0].Get("intervalMs").MustInt64())
+	}
+	require.Equal(t, "prom", queryService.requests[0].Queries[0].GetPath("datasource", "uid").MustString())
+	require.Equal(t, `rate(cpu{job="api"}[5m])`, queryService.requests[0].Queries[0].Get("expr").MustString())
+	require.Equal(t, "default-prom", queryService.requests[1].Queries[0].GetPath("datasource", "uid").MustString())
+	require.Equal(t, `disk{job="api"}`, queryService.requests[1].Queries[0].Get("expr").MustString())
+	require.Equal(t, int64(2), queryService.users[0].GetOrgID())
+
+	require.NotNil(t, created)
+	require.Equal(t, "Capacity 2023-07-15 06:00 UTC", created.Name)
+	require.Equal(t, int64(86400), created.Expires)
+	require.Equal(t, int64(2), created.OrgID)
+	require.Len(t, created.Key, 32)
+	require.NotEqual(t, created.Key, created.DeleteKey)
+
+	snapshot := created.Dashboard
+	require.Equal(t, from.Format(time.RFC3339Nano), snapshot.GetPath("time", "from").MustString())
+	require.Equal(t, "2023-07-15T06:00:00Z", snapshot.GetPath("snapshot", "timestamp").MustString())
+
+	cpu := snapshot.Get("panels").GetIndex(0)
+	require.Empty(t, cpu.Get("targets").MustArray())
+	require.Empty(t, cpu.Get("links").MustArray())
+	_, hasDatasource := cpu.CheckGet("datasource")
+	require.False(t, hasDatasource)
+	expected, err := simplejson.NewJson([]byte(`[{
+		"name": "",
+		"refId": "A",
+		"meta": {"typeVersion": [0, 0]},
+		"fields": [
+			{"name": "time", "type": "time", "config": {}, "values": [1000, 2000]},
+			{"name": "value", "type": "number", "config": {}, "labels": {"job": "api"}, "values": [0.5, null]}
+		]
+	}]`))
+	require.NoError(t, err)
+	requireJSONEq(t, expected, cpu.Get("snapshotData"))
+
+	disk := snapshot.Get("panels").GetIndex(1).Get("panels").GetIndex(0)
+	require.Len(t, disk.Get("snapshotData").MustArray(), 1)
+	_, hasData := snapshot.Get("panels").GetIndex(2).CheckGet("snapshotData")
+	require.False(t, hasData)
+
+	job := snapshot.GetPath("templating", "list").GetIndex(0)
+	require.Equal(t, "", job.Get("query").MustString())
+	require.Len(t, job.Get("options").MustArray(), 1)
+}
+
+func TestTakeSnapshotDashboardNotFound(t *testing.T) {
+	dashboardService := dashboards.NewFakeDashboardService(t)
+	dashboardService.On("GetDashboard", mock.Anything, mock.Anything).Return(nil, dashboards.ErrDashboardNotFound)
+
+	s := &SnapshotScheduler{
+		log:              log.NewNopLogger(),
+		dashboardService: dashboardService,
+		snapshotService:  dashboardsnapshots.NewMockService(t),
+		now:              time.Now,
+	}
+	_, err := s.takeSnapshot(context.Background(), setting.SnapshotSchedule{OrgID: 1, DashboardUID: "missing"})
+	require.True(t, errors.Is(err, dashboards.ErrDashboardNotFound))
+}
+
+func TestProvideService(t *testing.T) {
+	cfg := setting.NewCfg()
+	cfg.SnapshotEnabled = true
+
+	s, err := ProvideService(cfg, nil, nil, nil, nil, nil)
+	require.NoError(t, err)
+	require.True(t, s.IsDisabled())
+
+	cfg.SnapshotSchedules = []setting.SnapshotSchedule{{Name: "daily", DashboardUID: "abc", Cron: "0 6 * * *"}}
+	s, err = ProvideService(cfg, nil, nil, nil, nil, nil)
+	require.NoError(t, err)
+	require.False(t, s.IsDisabled())
+
+	cfg.SnapshotSchedules = []setting.SnapshotSchedule{{Name: "invalid", DashboardUID: "abc", Cron: "every day"}}
+	_, err = ProvideService(cfg, nil, nil, nil, nil, nil)
+	require.Error(t, err)
+}
+
+func TestFieldValue(t *testing.T) {
+	field := data.NewField("value", nil, []float64{1, math.NaN(), math.Inf(1)})
+	require.Equal(t, 1.0, fieldValue(field, 0))
+	require.Nil(t, fieldValue(field, 1))
+	require.Nil(t, fieldValue(field, 2))
+}
+
+func requireJSONEq(t *testing.T, expected, actual *simplejson.Json) {
+	t.Helper()
+	e, err := expected.Encode()
+	require.NoError(t, err)
+	a, err := actual.Encode()
+	require.NoError(t, err)
+	require.JSONEq(t, string(e), string(a))
+}
@@ -0,0 +1,318 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/services/auth/identity"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/tsdb/legacydata"
+)
+
+// defaultMaxDataPoints is used for panels without maxDataPoints, it matches the width of a wide panel.
+const defaultMaxDataPoints = 1000
+
+// variableRegex matches the $var, ${var}, ${var:format} and [[var]] template variable syntaxes.
+var variableRegex = regexp.MustCompile(`\$(\w+)|\[\[(\w+?)(?::\w+)?\]\]|\$\{(\w+)(?::[^}]+)?\}`)
+
+// timeRange is the absolute time range of a snapshot.
+type timeRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// resolveTimeRange resolves the configured or saved time range of the dashboard at now.
+func resolveTimeRange(dashboard *simplejson.Json, from, to string, now time.Time) (timeRange, error) {
+	if from == "" || to == "" {
+		from = dashboard.GetPath("time", "from").MustString("now-6h")
+		to = dashboard.GetPath("time", "to").MustString("now")
+	}
+
+	location := time.UTC
+	if tz, err := time.LoadLocation(dashboard.Get("timezone").MustString()); err == nil {
+		location = tz
+	}
+
+	tr := legacydata.DataTimeRange{From: from, To: to, Now: now}
+	start, err := tr.ParseFrom(legacydata.WithLocation(location))
+	if err != nil {
+		return timeRange{}, fmt.Errorf("invalid time range start %q: %w", from, err)
+	}
+	end, err := tr.ParseTo(legacydata.WithLocation(location))
+	if err != nil {
+		return timeRange{}, fmt.Errorf("invalid time range end %q: %w", to, err)
+	}
+	return timeRange{From: start, To: end}, nil
+}
+
+// templateValues returns the saved current value of every template variable of the dashboard.
+func templateValues(dashboard *simplejson.Json) map[string]string {
+	values := map[string]string{}
+	for _, v := range dashboard.GetPath("templating", "list").MustArray() {
+		variable := simplejson.NewFromAny(v)
+		name := variable.Get("name").MustString()
+		if name == "" {
+			continue
+		}
+		current := variable.GetPath("current", "value")
+		if s, err := current.String(); err == nil {
+			values[name] = s
+			continue
+		}
+		if list, err := current.StringArray(); err == nil && len(list) > 0 {
+			values[name] = strings.Join(list, ",")
+		}
+	}
+	return values
+}
+
+// interpolate replaces the template variables of every string of the value.
+func interpolate(value any, variables map[string]string) any {
+	switch v := value.(type) {
+	case string:
+		return variableRegex.ReplaceAllStringFunc(v, func(match string) string {
+			groups := variableRegex.FindStringSubmatch(match)
+			for _, name := range groups[1:] {
+				if replacement, ok := variables[name]; ok && name != "" {
+					return replacement
+				}
+			}
+			return match
+		})
+	case []any:
+		for i := range v {
+			v[i] = interpolate(v[i], variables)
+		}
+	case map[string]any:
+		for k := range v {
+			v[k] = interpolate(v[k], variables)
+		}
+	}
+	return value
+}
+
+// panelDatasource returns the datasource reference of a panel or query, resolving the default datasource.
+func panelDatasource(holder *simplejson.Json, defaultDS *datasources.DataSource) any {
+	ds, ok := holder.CheckGet("datasource")
+	if ok && ds.Interface() != nil {
+		if uid, err := ds.String(); err != nil || (uid != "" && uid != "default") {
+			return ds.Interface()
+		}
+	}
+	if defaultDS == nil {
+		return nil
+	}
+	return map[string]any{"uid": defaultDS.UID, "type": defaultDS.Type}
+}
+
+// metricRequest builds the query request of a panel, or returns false when the panel has no queries.
+func metricRequest(panel *simplejson.Json, tr timeRange, defaultDS *datasources.DataSource) (dtos.MetricRequest, bool) {
+	maxDataPoints := panel.Get("maxDataPoints").MustInt64(defaultMaxDataPoints)
+	if maxDataPoints <= 0 {
+		maxDataPoints = defaultMaxDataPoints
+	}
+	intervalMs := tr.To.Sub(tr.From).Milliseconds() / maxDataPoints
+	if intervalMs < 1 {
+		intervalMs = 1
+	}
+
+	panelDS := panelDatasource(panel, defaultDS)
+	var queries []*simplejson.Json
+	for _, t := range panel.Get("targets").MustArray() {
+		query := simplejson.NewFromAny(t)
+		if query.Get("hide").MustBool() {
+			continue
+		}
+		if ds := panelDatasource(query, nil); ds != nil {
+			query.Set("datasource", ds)
+		} else if panelDS != nil {
+			query.Set("datasource", panelDS)
+		}
+		query.Set("intervalMs", intervalMs)
+		query.Set("maxDataPoints", maxDataPoints)
+		queries = append(queries, query)
+	}
+	if len(queries) == 0 {
+		return dtos.MetricRequest{}, false
+	}
+
+	return dtos.MetricRequest{
+		From:    strconv.FormatInt(tr.From.UnixMilli(), 10),
+		To:      strconv.FormatInt(tr.To.UnixMilli(), 10),
+		Queries: queries,
+	}, true
+}
+
+// panels returns every panel which can hold data, including the panels of collapsed rows.
+func panels(dashboard *simplejson.Json) []*simplejson.Json {
+	var result []*simplejson.Json
+	var walk func(list []any)
+	walk = func(list []any) {
+		for _, p := range list {
+			panel := simplejson.NewFromAny(p)
+			if panel.Get("type").MustString() == "row" {
+				walk(panel.Get("panels").MustArray())
+				continue
+			}
+			result = append(result, panel)
+		}
+	}
+	walk(dashboard.Get("panels").MustArray())
+	return result
+}
+
+// queryFunc executes a metric request.
+type queryFunc func(ctx context.Context, user identity.Requester, req dtos.MetricRequest) (*backend.QueryDataResponse, error)
+
+// buildSnapshot runs the queries of every panel and returns the dashboard with the results embedded
+// as snapshot data, in the form the frontend produces when sharing a snapshot.
+func buildSnapshot(ctx context.Context, dashboard *simplejson.Json, tr timeRange, defaultDS *datasources.DataSource, user identity.Requester, query queryFunc, now time.Time) (*simplejson.Json, []error) {
+	var errs []error
+	variables := templateValues(dashboard)
+
+	for _, panel := range panels(dashboard) {
+		req, ok := metricRequest(panel, tr, defaultDS)
+		if ok {
+			for _, q := range req.Queries {
+				interpolate(q.Interface(), variables)
+			}
+
+			snapshotData := []any{}
+			resp, err := query(ctx, user, req)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("panel %d: %w", panel.Get("id").MustInt64(), err))
+			} else {
+				for _, q := range req.Queries {
+					refID := q.Get("refId").MustString()
+					res, ok := resp.Responses[refID]
+					if !ok {
+						continue
+					}
+					if res.Error != nil {
+						errs = append(errs, fmt.Errorf("panel %d query %s: %w", panel.Get("id").MustInt64(), refID, res.Error))
+					}
+					for _, frame := range res.Frames {
+						snapshotData = append(snapshotData, frameToDTO(frame, refID))
+					}
+				}
+			}
+			panel.Set("snapshotData", snapshotData)
+		}
+
+		// the queries and links are not needed to display the snapshot and may leak internal details
+		panel.Set("targets", []any{})
+		panel.Set("links", []any{})
+		panel.Del("datasource")
+	}
+
+	for _, v := range dashboard.GetPath("templating", "list").MustArray() {
+		variable := simplejson.NewFromAny(v)
+		variable.Set("query", "")
+		if current, ok := variable.CheckGet("current"); ok {
+			variable.Set("options", []any{current.Interface()})
+		} else {
+			variable.Set("options", []any{})
+		}
+		variable.Set("refresh", 0)
+		variable.Del("datasource")
+	}
+
+	dashboard.Set("time", map[string]any{
+		"from": tr.From.UTC().Format(time.RFC3339Nano),
+		"to":   tr.To.UTC().Format(time.RFC3339Nano),
+		"raw":  map[string]any{"from": tr.From.UTC().Format(time.RFC3339Nano), "to": tr.To.UTC().Format(time.RFC3339Nano)},
+	})
+	dashboard.Set("snapshot", map[string]any{
+		"timestamp":   now.UTC().Format(time.RFC3339Nano),
+		"originalUrl": setting.ToAbsUrl("d/" + dashboard.Get("uid").MustString()),
+	})
+
+	return dashboard, errs
+}
+
+// frameToDTO converts a data frame to the DataFrameDTO form used by the frontend for snapshot data.
+func frameToDTO(frame *data.Frame, refID string) map[string]any {
+	fields := make([]any, 0, len(frame.Fields))
+	for _, field := range frame.Fields {
+		values := make([]any, field.Len())
+		for i := range values {
+			values[i] = fieldValue(field, i)
+		}
+
+		dto := map[string]any{
+			"name":   field.Name,
+			"type":   fieldType(field.Type()),
+			"values": values,
+		}
+		if field.Config != nil {
+			dto["config"] = field.Config
+		} else {
+			dto["config"] = map[string]any{}
+		}
+		if len(field.Labels) > 0 {
+			dto["labels"] = field.Labels
+		}
+		fields = append(fields, dto)
+	}
+
+	if frame.RefID != "" {
+		refID = frame.RefID
+	}
+	dto := map[string]any{
+		"name":   frame.Name,
+		"refId":  refID,
+		"fields": fields,
+	}
+	if frame.Meta != nil {
+		meta := *frame.Meta
+		meta.ExecutedQueryString = ""
+		dto["meta"] = meta
+	}
+	return dto
+}
+
+func fieldType(t data.FieldType) string {
+	switch {
+	case t.Time():
+		return "time"
+	case t.Numeric():
+		return "number"
+	case t == data.FieldTypeString || t == data.FieldTypeNullableString:
+		return "string"
+	case t == data.FieldTypeBool || t == data.FieldTypeNullableBool:
+		return "boolean"
+	default:
+		return "other"
+	}
+}
+
+func fieldValue(field *data.Field, i int) any {
+	v, ok := field.ConcreteAt(i)
+	if !ok {
+		return nil
+	}
+	switch value := v.(type) {
+	case time.Time:
+		return value.UnixMilli()
+	case float64:
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return nil
+		}
+	case float32:
+		if math.IsNaN(float64(value)) || math.IsInf(float64(value), 0) {
+			return nil
+		}
+	}
+	return v
+}
@@ -0,0 +1,236 @@
+package datasourceproxy
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/prometheus/model/labels"
+
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/datasources/guardian"
+	"github.com/grafana/grafana/pkg/util/errutil"
+)
+
+var (
+	errLabelPolicyPath = errutil.Forbidden("datasourceproxy.labelPolicyPath",
+		errutil.WithPublicMessage("The data source path is not available to users restricted by label policies"))
+	errLabelPolicyBody = errutil.BadRequest("datasourceproxy.labelPolicyBody",
+		errutil.WithPublicMessage("The request body must be a form for users restricted by label policies"))
+)
+
+// restrictedPath describes a proxied API path allowed to users restricted by label policies
+type restrictedPath struct {
+	path *regexp.Regexp
+	// param is the request parameter holding the queries or series selectors restricted by the label policies
+	param string
+	// addSelector adds a selector of the label policy when the request has no selector, ex: to list label values
+	addSelector bool
+}
+
+// restrictedRoutes are the paths of a route to the data sources allowed to users restricted by label policies
+type restrictedRoutes struct {
+	paths map[string][]restrictedPath
+	// multipartForms is true if the multipart form bodies are sent unchanged to the data source, the resource
+	// calls send the bodies as URL encoded forms
+	multipartForms bool
+}
+
+var proxyRoutes = restrictedRoutes{
+	paths: map[string][]restrictedPath{
+		datasources.DS_PROMETHEUS: {
+			{path: regexp.MustCompile(`^api/v1/(query|query_range|query_exemplars)$`), param: "query"},
+			{path: regexp.MustCompile(`^api/v1/(series|labels|label/[^/]+/values)$`), param: "match[]", addSelector: true},
+			{path: regexp.MustCompile(`^api/v1/status/buildinfo$`)},
+		},
+		datasources.DS_LOKI: {
+			{path: regexp.MustCompile(`^loki/api/v1/(query|query_range|index/stats|index/volume|index/volume_range)$`), param: "query"},
+			{path: regexp.MustCompile(`^loki/api/v1/series$`), param: "match[]", addSelector: true},
+			{path: regexp.MustCompile(`^loki/api/v1/(labels|label/[^/]+/values)$`), param: "query", addSelector: true},
+			{path: regexp.MustCompile(`^loki/api/v1/status/buildinfo$`)},
+		},
+	},
+	multipartForms: true,
+}
+
+// resourceRoutes are the resource paths of the data source plugins, the Loki plugin adds the loki/api/v1/ prefix
+var resourceRoutes = restrictedRoutes{
+	paths: map[string][]restrictedPath{
+		datasources.DS_PROMETHEUS: {
+			{path: regexp.MustCompile(`^api/v1/(query|query_range|query_exemplars)$`), param: "query"},
+			{path: regexp.MustCompile(`^api/v1/(series|labels|label/[^/]+/values)$`), param: "match[]", addSelector: true},
+			{path: regexp.MustCompile(`^(api/v1/status/buildinfo|version-detect)$`)},
+		},
+		datasources.DS_LOKI: {
+			{path: regexp.MustCompile(`^index/stats$`), param: "query"},
+			{path: regexp.MustCompile(`^series$`), param: "match[]", addSelector: true},
+			{path: regexp.MustCompile(`^(labels|label/[^/]+/values)$`), param: "query", addSelector: true},
+		},
+	},
+}
+
+// applyLabelPolicies restricts the queries proxied to Prometheus and Loki data sources to the series allowed
+// by the label policies of the user. Restricted users can only use the query APIs of the data source.
+func (p *DataSourceProxyService) applyLabelPolicies(c *contextmodel.ReqContext, ds *datasources.DataSource, proxyPath string) error {
+	return applyLabelPolicies(c, p.dsGuardian, ds, proxyPath, proxyRoutes)
+}
+
+// ApplyResourceLabelPolicies restricts the resource calls to Prometheus and Loki data sources to the series
+// allowed by the label policies of the user, like the proxied requests.
+func ApplyResourceLabelPolicies(c *contextmodel.ReqContext, dsGuardian guardian.DatasourceGuardianProvider, ds *datasources.DataSource, resourcePath string) error {
+	return applyLabelPolicies(c, dsGuardian, ds, resourcePath, resourceRoutes)
+}
+
+func applyLabelPolicies(c *contextmodel.ReqContext, dsGuardian guardian.DatasourceGuardianProvider, ds *datasources.DataSource, requestPath string, routes restrictedRoutes) error {
+	if !guardian.SupportsLabelPolicies(ds.Type) {
+		return nil
+	}
+	matchers, err := dsGuardian.New(ds.OrgID, c.SignedInUser).LabelMatchers(ds)
+	if err != nil || len(matchers) == 0 {
+		return err
+	}
+
+	path := strings.Trim(requestPath, "/")
+	for _, allowed := range routes.paths[ds.Type] {
+		if !allowed.path.MatchString(path) {
+			continue
+		}
+		if allowed.param == "" {
+			return nil
+		}
+		return restrictRequest(c, ds.Type, allowed, matchers, routes.multipartForms)
+	}
+	return errLabelPolicyPath.Errorf("path %s of data source %s is not allowed by label policies", path, ds.UID)
+}
+
+func restrictRequest(c *contextmodel.ReqContext, dsType string, allowed restrictedPath, matchers []*labels.Matcher, multipartForms bool) error {
+	req := c.Req
+	found := false
+
+	query := req.URL.Query()
+	if values, ok := query[allowed.param]; ok {
+		if err := restrictValues(dsType, values, matchers); err != nil {
+			return err
+		}
+		req.URL.RawQuery = query.Encode()
+		found = true
+	}
+
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return err
+		}
+		if len(body) > 0 {
+			// the bodies the data source could read queries from without the rewrite are rejected
+			contentType := req.Header.Get("Content-Type")
+			mediaType, params, err := mime.ParseMediaType(contentType)
+			restricted := false
+			switch {
+			case err != nil:
+				return errLabelPolicyBody.Errorf("invalid content type %q: %w", contentType, err)
+			case mediaType == "application/x-www-form-urlencoded":
+				body, restricted, err = restrictURLEncodedForm(body, dsType, allowed, matchers)
+			case mediaType == "multipart/form-data" && multipartForms:
+				body, restricted, err = restrictMultipartForm(body, params["boundary"], dsType, allowed, matchers)
+			default:
+				return errLabelPolicyBody.Errorf("content type %q is not allowed", mediaType)
+			}
+			if err != nil {
+				return err
+			}
+			found = found || restricted
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+		req.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	}
+
+	if !found && allowed.addSelector {
+		query.Set(allowed.param, guardian.MatchersSelector(matchers))
+		req.URL.RawQuery = query.Encode()
+	}
+	return nil
+}
+
+// restrictURLEncodedForm rewrites the values of the parameter, false if the form has no value of the parameter
+func restrictURLEncodedForm(body []byte, dsType string, allowed restrictedPath, matchers []*labels.Matcher) ([]byte, bool, error) {
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, false, errLabelPolicyBody.Errorf("invalid form: %w", err)
+	}
+	values, ok := form[allowed.param]
+	if !ok {
+		return body, false, nil
+	}
+	if err := restrictValues(dsType, values, matchers); err != nil {
+		return nil, false, err
+	}
+	return []byte(form.Encode()), true, nil
+}
+
+// restrictMultipartForm rewrites the form fields of the parameter, false if the form has no field of the
+// parameter. The other parts are kept with the boundary.
+func restrictMultipartForm(body []byte, boundary, dsType string, allowed restrictedPath, matchers []*labels.Matcher) ([]byte, bool, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return nil, false, errLabelPolicyBody.Errorf("invalid multipart boundary: %w", err)
+	}
+
+	found := false
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, false, errLabelPolicyBody.Errorf("invalid multipart form: %w", err)
+		}
+		value, err := io.ReadAll(part)
+		if err != nil {
+			return nil, false, errLabelPolicyBody.Errorf("invalid multipart form: %w", err)
+		}
+
+		if part.FormName() == allowed.param {
+			restricted, err := guardian.RestrictQuery(dsType, string(value), matchers)
+			if err != nil {
+				return nil, false, err
+			}
+			value = []byte(restricted)
+			found = true
+		}
+
+		w, err := writer.CreatePart(part.Header)
+		if err != nil {
+			return nil, false, err
+		}
+		if _, err := w.Write(value); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, false, err
+	}
+	return buf.Bytes(), found, nil
+}
+
+func restrictValues(dsType string, values []string, matchers []*labels.Matcher) error {
+	for i, value := range values {
+		restricted, err := guardian.RestrictQuery(dsType, value, matchers)
+		if err != nil {
+			return err
+		}
+		values[i] = restricted
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package datasourceproxy
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/datasources/guardian"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+func TestDataSourceProxyService_applyLabelPolicies(t *testing.T) {
+	p := &DataSourceProxyService{dsGuardian: guardian.ProvideGuardian()}
+	newDataSource := func(dsType string) *datasources.DataSource {
+		return &datasources.DataSource{UID: "ds", Type: dsType, JsonData: simplejson.NewFromAny(map[string]any{
+			"labelPolicies": []any{
+				map[string]any{"teams": []any{1}, "matchers": `namespace="team-a"`},
+				map[string]any{"roles": []any{"Admin"}},
+			},
+		})}
+	}
+	newReqContext := func(req *http.Request, signedInUser *user.SignedInUser) *contextmodel.ReqContext {
+		return &contextmodel.ReqContext{
+			Context:      &web.Context{Req: req},
+			SignedInUser: signedInUser,
+		}
+	}
+	teamMember := &user.SignedInUser{UserID: 2, OrgRole: org.RoleViewer, Teams: []int64{1}}
+
+	t.Run("should restrict the queries in the URL", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/datasources/proxy/uid/ds/api/v1/query?query=up&time=1", nil)
+		c := newReqContext(req, teamMember)
+
+		err := p.applyLabelPolicies(c, newDataSource(datasources.DS_PROMETHEUS), "api/v1/query")
+		require.NoError(t, err)
+		assert.Equal(t, `up{namespace="team-a"}`, c.Req.URL.Query().Get("query"))
+		assert.Equal(t, "1", c.Req.URL.Query().Get("time"))
+	})
+
+	t.Run("should restrict the queries in the form body", func(t *testing.T) {
+		form := url.Values{"query": {`sum(rate({app="api"}[5m]))`}}
+		req := httptest.NewRequest(http.MethodPost, "/api/datasources/proxy/uid/ds/loki/api/v1/query_range", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		c := newReqContext(req, teamMember)
+
+		err := p.applyLabelPolicies(c, newDataSource(datasources.DS_LOKI), "loki/api/v1/query_range")
+		require.NoError(t, err)
+
+		body, err := io.ReadAll(c.Req.Body)
+		require.NoError(t, err)
+		values, err := url.ParseQuery(string(body))
+		require.NoError(t, err)
+		assert.Equal(t, `sum(rate({app="api", namespace="team-a"}[5m]))`, values.Get("query"))
+		assert.Equal(t, int64(len(body)), c.Req.ContentLength)
+	})
+
+	t.Run("should restrict the queries in a form body with parameters", func(t *testing.T) {
+		form := url.Values{"query": {"up"}}
+		req := httptest.NewRequest(http.MethodPost, "/api/datasources/proxy/uid/ds/api/v1/query", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "Application/X-WWW-Form-Urlencoded; charset=UTF-8")
+		c := newReqContext(req, teamMember)
+
+		err := p.applyLabelPolicies(c, newDataSource(datasources.DS_PROMETHEUS), "api/v1/query")
+		require.NoError(t, err)
+
+		body, err := io.ReadAll(c.Req.Body)
+		require.NoError(t, err)
+		values, err := url.ParseQuery(string(body))
+		require.NoError(t, err)
+		assert.Equal(t, `up{namespace="team-a"}`, values.Get("query"))
+	})
+
+	t.Run("should restrict the queries in a multipart form body", func(t *testing.T) {
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		require.NoError(t, writer.WriteField("query", "up"))
+		require.NoError(t, writer.WriteField("time", "1"))
+		require.NoError(t, writer.Close())
+		req := httptest.NewRequest(http.MethodPost, "/api/datasources/proxy/uid/ds/api/v1/query", &buf)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		c := newReqContext(req, teamMember)
+
+		err := p.applyLabelPolicies(c, newDataSource(datasources.DS_PROMETHEUS), "api/v1/query")
+		require.NoError(t, err)
+
+		require.NoError(t, c.Req.ParseMultipartForm(1<<20))
+		assert.Equal(t, []string{`up{namespace="team-a"}`}, c.Req.MultipartForm.Value["query"])
+		assert.Equal(t, []string{"1"}, c.Req.MultipartForm.Value["time"])
+	})
+
+	t.Run("should reject the other bodies", func(t *testing.T) {
+		for _, contentType := range []string{"application/json", "text/plain", ""} {
+			req := httptest.NewRequest(http.MethodPost, "/api/datasources/proxy/uid/ds/api/v1/query", strings.NewReader("query=up"))
+			req.Header.Set("Content-Type", contentType)
+
+			err := p.applyLabelPolicies(newReqContext(req, teamMember), newDataSource(datasources.DS_PROMETHEUS), "api/v1/query")
+			assert.ErrorIs(t, err, errLabelPolicyBody, contentType)
+		}
+	})
+
+	t.Run("should restrict the resource calls", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/datasources/uid/ds/resources/labels?start=1", nil)
+		c := newReqContext(req, teamMember)
+
+		err := ApplyResourceLabelPolicies(c, p.dsGuardian, newDataSource(datasources.DS_LOKI), "labels")
+		require.NoError(t, err)
+		assert.Equal(t, `{namespace="team-a"}`, c.Req.URL.Query().Get("query"))
+
+		req = httptest.NewRequest(http.MethodGet, "/api/datasources/uid/ds/resources/detected_fields", nil)
+		err = ApplyResourceLabelPolicies(newReqContext(req, teamMember), p.dsGuardian, newDataSource(datasources.DS_LOKI), "detected_fields")
+		assert.ErrorIs(t, err, errLabelPolicyPath)
+	})
+
+	t.Run("should add a selector to list the label values", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/datasources/proxy/uid/ds/api/v1/label/job/values", nil)
+		c := newReqContext(req, teamMember)
+
+		err := p.applyLabelPolicies(c, newDataSource(datasources.DS_PROMETHEUS), "api/v1/label/job/values")
+		require.NoError(t, err)
+		assert.Equal(t, `{namespace="team-a"}`, c.Req.URL.Query().Get("match[]"))
+	})
+
+	t.Run("should deny the other paths to restricted users", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/datasources/proxy/uid/ds/api/v1/targets", nil)
+		err := p.applyLabelPolicies(newReqContext(req, teamMember), newDataSource(datasources.DS_PROMETHEUS), "api/v1/targets")
+		assert.ErrorIs(t, err, errLabelPolicyPath)
+	})
+
+	t.Run("should not restrict unrestricted users", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/datasources/proxy/uid/ds/api/v1/targets?query=up", nil)
+		c := newReqContext(req, &user.SignedInUser{UserID: 1, OrgRole: org.RoleAdmin})
+
+		err := p.applyLabelPolicies(c, newDataSource(datasources.DS_PROMETHEUS), "api/v1/targets")
+		require.NoError(t, err)
+		assert.Equal(t, "query=up", c.Req.URL.RawQuery)
+	})
+
+	t.Run("should deny users without label policy", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/datasources/proxy/uid/ds/api/v1/query?query=up", nil)
+		c := newReqContext(req, &user.SignedInUser{UserID: 3, OrgRole: org.RoleViewer})
+
+		err := p.applyLabelPolicies(c, newDataSource(datasources.DS_PROMETHEUS), "api/v1/query")
+		assert.ErrorIs(t, err, guardian.ErrLabelPolicyDenied)
+	})
+}
@@ -0,0 +1,123 @@
+package datasources
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// SecondarySecretSuffix is appended to the key of a secureJsonData value to store its secondary value while the
+// secret is rotated, ex: password.secondary is the secondary value of password.
+const SecondarySecretSuffix = ".secondary"
+
+// Credential is one of the two sets of secrets of a datasource while its secrets are rotated.
+type Credential string
+
+const (
+	CredentialPrimary   Credential = "primary"
+	CredentialSecondary Credential = "secondary"
+)
+
+// Other returns the credential to fall back to when the credential fails to authenticate.
+func (c Credential) Other() Credential {
+	if c == CredentialSecondary {
+		return CredentialPrimary
+	}
+	return CredentialSecondary
+}
+
+// CredentialUsage is when a credential of a datasource was last used to successfully authenticate.
+type CredentialUsage struct {
+	Credential Credential `json:"credential"`
+	LastUsed   time.Time  `json:"lastUsed"`
+}
+
+// CredentialService tracks the credential used by the datasources having secondary secrets, so that the requests
+// keep on succeeding while the credentials of a datasource are rotated.
+type CredentialService interface {
+	// ActiveCredential returns the credential the requests to the datasource are sent with and since when it is active.
+	ActiveCredential(ds *DataSource) (Credential, time.Time)
+	// UseCredential makes the credential active after it authenticated successfully while the active one failed.
+	// The credential remains active until the datasource is updated.
+	UseCredential(ds *DataSource, credential Credential, since time.Time)
+	// RecordCredentialUsage records that the credential successfully authenticated a request to the datasource.
+	RecordCredentialUsage(ctx context.Context, ds *DataSource, credential Credential)
+}
+
+// CredentialStatus describes the rotation of the secrets of a datasource.
+type CredentialStatus struct {
+	ActiveCredential Credential `json:"activeCredential"`
+	// SecondarySecrets are the keys of the secureJsonData values having a secondary value
+	SecondarySecrets []string           `json:"secondarySecrets"`
+	Usage            []*CredentialUsage `json:"usage"`
+}
+
+// PromoteSecondarySecretsCommand replaces the primary secrets of a datasource by its secondary secrets.
+type PromoteSecondarySecretsCommand struct {
+	OrgID int64
+	UID   string
+}
+
+// SecondarySecretKey returns the key of the secondary value of the secureJsonData value.
+func SecondarySecretKey(key string) string {
+	return key + SecondarySecretSuffix
+}
+
+// HasSecondarySecrets returns true if some of the decrypted secureJsonData values have a secondary value.
+func HasSecondarySecrets(decrypted map[string]string) bool {
+	for k := range decrypted {
+		if strings.HasSuffix(k, SecondarySecretSuffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// CredentialSecrets returns the decrypted secureJsonData values to authenticate with the credential. The secondary
+// credential is made of the secondary values, and the primary values of the secrets without secondary value.
+func CredentialSecrets(decrypted map[string]string, credential Credential) map[string]string {
+	secrets := make(map[string]string, len(decrypted))
+	for k, v := range decrypted {
+		if !strings.HasSuffix(k, SecondarySecretSuffix) {
+			secrets[k] = v
+		}
+	}
+	if credential == CredentialSecondary {
+		for k, v := range decrypted {
+			if key, ok := strings.CutSuffix(k, SecondarySecretSuffix); ok {
+				secrets[key] = v
+			}
+		}
+	}
+	return secrets
+}
+
+// IsAuthenticationFailure returns true if the error returned by a datasource means its credentials were rejected.
+func IsAuthenticationFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, pattern := range authenticationFailurePatterns {
+		if strings.Contains(msg, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticationFailurePatterns are found in the errors of the datasources rejecting the credentials, ex:
+// the SQL datasources report that the password authentication failed. A forbidden request is not a failure of
+// the credentials, they are valid but lack a permission the other credentials would lack as well.
+var authenticationFailurePatterns = []string{
+	"401 unauthorized",
+	"status code 401",
+	"authentication failed",
+	// mysql error 1045, the access denied errors of missing privileges are not about the credentials
+	"error 1045",
+	"(using password:",
+	"login failed for user",
+	"invalid credentials",
+	"invalid api key",
+	"invalid token",
+}
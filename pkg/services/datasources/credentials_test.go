@@ -0,0 +1,34 @@
+package datasources
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCredentialSecrets(t *testing.T) {
+	decrypted := map[string]string{
+		"password":           "old",
+		"password.secondary": "new",
+		"tlsClientKey":       "key",
+	}
+
+	require.True(t, HasSecondarySecrets(decrypted))
+	require.False(t, HasSecondarySecrets(map[string]string{"password": "old"}))
+	require.Equal(t, map[string]string{"password": "old", "tlsClientKey": "key"}, CredentialSecrets(decrypted, CredentialPrimary))
+	require.Equal(t, map[string]string{"password": "new", "tlsClientKey": "key"}, CredentialSecrets(decrypted, CredentialSecondary))
+	require.Equal(t, "old", decrypted["password"])
+}
+
+func TestIsAuthenticationFailure(t *testing.T) {
+	require.True(t, IsAuthenticationFailure(errors.New(`pq: password authentication failed for user "grafana"`)))
+	require.True(t, IsAuthenticationFailure(errors.New("Error 1045: Access denied for user 'grafana'@'localhost'")))
+	require.True(t, IsAuthenticationFailure(errors.New("request failed with status code 401")))
+	require.True(t, IsAuthenticationFailure(errors.New("401 Unauthorized")))
+	require.False(t, IsAuthenticationFailure(errors.New("request failed with status code 403")))
+	require.False(t, IsAuthenticationFailure(errors.New("403 Forbidden")))
+	require.False(t, IsAuthenticationFailure(errors.New("Error 1044: Access denied for user 'grafana'@'%' to database 'metrics'")))
+	require.False(t, IsAuthenticationFailure(errors.New("dial tcp: connection refused")))
+	require.False(t, IsAuthenticationFailure(nil))
+}
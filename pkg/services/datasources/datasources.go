@@ -59,6 +59,12 @@ type DataSourceService interface {
 	// configured for this Datasource. Not every datasource can has the option
 	// to configure those.
 	CustomHeaders(ctx context.Context, ds *DataSource) (map[string]string, error)
+
+	// GetCredentialStatus returns the status of the rotation of the secrets of the datasource.
+	GetCredentialStatus(ctx context.Context, ds *DataSource) (*CredentialStatus, error)
+
+	// PromoteSecondarySecrets replaces the primary secrets of the datasource by its secondary secrets.
+	PromoteSecondarySecrets(ctx context.Context, cmd *PromoteSecondarySecretsCommand) (*DataSource, error)
 }
 
 // CacheService interface for retrieving a cached datasource.
@@ -3,6 +3,7 @@ package datasources
 import (
 	"context"
 	"net/http"
+	"time"
 
 	sdkhttpclient "github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
 
@@ -17,6 +18,7 @@ type FakeDataSourceService struct {
 }
 
 var _ datasources.DataSourceService = &FakeDataSourceService{}
+var _ datasources.CredentialService = &FakeDataSourceService{}
 
 func (s *FakeDataSourceService) GetDataSource(ctx context.Context, query *datasources.GetDataSourceQuery) (*datasources.DataSource, error) {
 	for _, dataSource := range s.DataSources {
@@ -137,3 +139,21 @@ func (s *FakeDataSourceService) DecryptedPassword(ctx context.Context, ds *datas
 func (s *FakeDataSourceService) CustomHeaders(ctx context.Context, ds *datasources.DataSource) (map[string]string, error) {
 	return nil, nil
 }
+
+func (s *FakeDataSourceService) GetCredentialStatus(ctx context.Context, ds *datasources.DataSource) (*datasources.CredentialStatus, error) {
+	return &datasources.CredentialStatus{ActiveCredential: datasources.CredentialPrimary, SecondarySecrets: []string{}, Usage: []*datasources.CredentialUsage{}}, nil
+}
+
+func (s *FakeDataSourceService) PromoteSecondarySecrets(ctx context.Context, cmd *datasources.PromoteSecondarySecretsCommand) (*datasources.DataSource, error) {
+	return s.GetDataSource(ctx, &datasources.GetDataSourceQuery{UID: cmd.UID, OrgID: cmd.OrgID})
+}
+
+func (s *FakeDataSourceService) ActiveCredential(ds *datasources.DataSource) (datasources.Credential, time.Time) {
+	return datasources.CredentialPrimary, ds.Updated
+}
+
+func (s *FakeDataSourceService) UseCredential(ds *datasources.DataSource, credential datasources.Credential, since time.Time) {
+}
+
+func (s *FakeDataSourceService) RecordCredentialUsage(ctx context.Context, ds *datasources.DataSource, credential datasources.Credential) {
+}
@@ -0,0 +1,125 @@
+package guardian
+
+import (
+	"encoding/json"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	"github.com/grafana/grafana/pkg/services/auth/identity"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/util/errutil"
+)
+
+var (
+	ErrLabelPolicyDenied  = errutil.Forbidden("datasources.labelPolicyDenied", errutil.WithPublicMessage("No label policy of the data source grants access to the user"))
+	ErrInvalidLabelPolicy = errutil.Internal("datasources.invalidLabelPolicy", errutil.WithPublicMessage("The label policies of the data source are invalid"))
+	ErrRestrictQuery      = errutil.BadRequest("datasources.restrictQuery", errutil.WithPublicMessage("The query cannot be restricted by the label policies of the data source"))
+)
+
+// LabelPolicy restricts the series the matching users can query from a Prometheus or Loki data source.
+// Label policies are configured in the data source JSON data and the first policy matching the user applies:
+//
+//	"labelPolicies": [
+//	  {"teams": [1], "matchers": "namespace=~\"team-a-.*\""},
+//	  {"roles": ["Admin"]}
+//	]
+//
+// A policy without matchers grants access to all series. When label policies are configured, the users
+// not matching any policy cannot query the data source.
+type LabelPolicy struct {
+	// Teams are the IDs of the teams the policy applies to
+	Teams []int64 `json:"teams,omitempty"`
+	// Users are the logins of the users the policy applies to
+	Users []string `json:"users,omitempty"`
+	// Roles are the organization roles the policy applies to
+	Roles []string `json:"roles,omitempty"`
+	// Matchers are the label matchers added to every series selector, ex: namespace=~"team-a-.*"
+	Matchers string `json:"matchers,omitempty"`
+}
+
+func (p LabelPolicy) appliesTo(user identity.Requester) bool {
+	if user == nil || user.IsNil() {
+		return false
+	}
+	for _, login := range p.Users {
+		if login == user.GetLogin() {
+			return true
+		}
+	}
+	for _, role := range p.Roles {
+		if role == string(user.GetOrgRole()) {
+			return true
+		}
+	}
+	for _, teamID := range p.Teams {
+		for _, userTeamID := range user.GetTeams() {
+			if teamID == userTeamID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SupportsLabelPolicies returns true if the queries of the data source type can be restricted by label policies
+func SupportsLabelPolicies(dsType string) bool {
+	return dsType == datasources.DS_PROMETHEUS || dsType == datasources.DS_LOKI
+}
+
+// GetLabelPolicies returns the label policies configured in the data source JSON data
+func GetLabelPolicies(ds *datasources.DataSource) ([]LabelPolicy, error) {
+	if ds.JsonData == nil || !SupportsLabelPolicies(ds.Type) {
+		return nil, nil
+	}
+	raw, ok := ds.JsonData.CheckGet("labelPolicies")
+	if !ok {
+		return nil, nil
+	}
+
+	data, err := raw.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	var policies []LabelPolicy
+	if err := json.Unmarshal(data, &policies); err != nil {
+		return nil, ErrInvalidLabelPolicy.Errorf("failed to read label policies: %w", err)
+	}
+	return policies, nil
+}
+
+// labelMatchers returns the label matchers of the first label policy of the data source matching the user,
+// nil if the user is not restricted
+func labelMatchers(ds *datasources.DataSource, user identity.Requester) ([]*labels.Matcher, error) {
+	policies, err := GetLabelPolicies(ds)
+	if err != nil || len(policies) == 0 {
+		return nil, err
+	}
+
+	for _, policy := range policies {
+		if !policy.appliesTo(user) {
+			continue
+		}
+		if policy.Matchers == "" {
+			return nil, nil
+		}
+		matchers, err := parser.ParseMetricSelector("{" + policy.Matchers + "}")
+		if err != nil {
+			return nil, ErrInvalidLabelPolicy.Errorf("invalid label policy matchers %q: %w", policy.Matchers, err)
+		}
+		return matchers, nil
+	}
+	return nil, ErrLabelPolicyDenied.Errorf("no label policy of data source %s applies to the user", ds.UID)
+}
+
+var _ DatasourceGuardian = new(labelPolicyGuardian)
+
+// labelPolicyGuardian enforces the label policies of the data sources
+type labelPolicyGuardian struct {
+	AllowGuardian
+	user identity.Requester
+}
+
+func (g *labelPolicyGuardian) LabelMatchers(ds *datasources.DataSource) ([]*labels.Matcher, error) {
+	return labelMatchers(ds, g.user)
+}
@@ -0,0 +1,85 @@
+package guardian
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestLabelPolicyGuardian_LabelMatchers(t *testing.T) {
+	ds := &datasources.DataSource{UID: "prom", Type: datasources.DS_PROMETHEUS, JsonData: simplejson.NewFromAny(map[string]any{
+		"labelPolicies": []any{
+			map[string]any{"teams": []any{1}, "matchers": `namespace=~"team-a-.*"`},
+			map[string]any{"users": []any{"auditor"}, "matchers": `env="prod", namespace!="secret"`},
+			map[string]any{"roles": []any{"Admin"}},
+		},
+	})}
+
+	tests := []struct {
+		desc        string
+		user        *user.SignedInUser
+		expected    []string
+		expectedErr error
+	}{
+		{
+			desc:     "should restrict team members",
+			user:     &user.SignedInUser{UserID: 2, Login: "alice", OrgRole: org.RoleViewer, Teams: []int64{3, 1}},
+			expected: []string{`namespace=~"team-a-.*"`},
+		},
+		{
+			desc:     "should restrict users",
+			user:     &user.SignedInUser{UserID: 3, Login: "auditor", OrgRole: org.RoleViewer},
+			expected: []string{`env="prod"`, `namespace!="secret"`},
+		},
+		{
+			desc:     "should apply the first matching policy",
+			user:     &user.SignedInUser{UserID: 4, Login: "bob", OrgRole: org.RoleAdmin, Teams: []int64{1}},
+			expected: []string{`namespace=~"team-a-.*"`},
+		},
+		{
+			desc: "should not restrict policies without matchers",
+			user: &user.SignedInUser{UserID: 1, Login: "admin", OrgRole: org.RoleAdmin},
+		},
+		{
+			desc:        "should deny users without policy",
+			user:        &user.SignedInUser{UserID: 5, Login: "eve", OrgRole: org.RoleEditor},
+			expectedErr: ErrLabelPolicyDenied,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			matchers, err := ProvideGuardian().New(1, tt.user).LabelMatchers(ds)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			var actual []string
+			for _, m := range matchers {
+				actual = append(actual, m.String())
+			}
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+
+	t.Run("should not restrict data sources without policies", func(t *testing.T) {
+		matchers, err := ProvideGuardian().New(1, &user.SignedInUser{}).LabelMatchers(&datasources.DataSource{Type: datasources.DS_LOKI, JsonData: simplejson.New()})
+		require.NoError(t, err)
+		assert.Nil(t, matchers)
+	})
+
+	t.Run("should fail on invalid matchers", func(t *testing.T) {
+		invalid := &datasources.DataSource{Type: datasources.DS_LOKI, JsonData: simplejson.NewFromAny(map[string]any{
+			"labelPolicies": []any{map[string]any{"roles": []any{"Viewer"}, "matchers": `namespace=`}},
+		})}
+		_, err := ProvideGuardian().New(1, &user.SignedInUser{OrgRole: org.RoleViewer}).LabelMatchers(invalid)
+		assert.ErrorIs(t, err, ErrInvalidLabelPolicy)
+	})
+}
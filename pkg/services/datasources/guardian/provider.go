@@ -1,6 +1,8 @@
 package guardian
 
 import (
+	"github.com/prometheus/prometheus/model/labels"
+
 	"github.com/grafana/grafana/pkg/services/auth/identity"
 	"github.com/grafana/grafana/pkg/services/datasources"
 )
@@ -12,6 +14,8 @@ type DatasourceGuardianProvider interface {
 type DatasourceGuardian interface {
 	CanQuery(datasourceID int64) (bool, error)
 	FilterDatasourcesByQueryPermissions([]*datasources.DataSource) ([]*datasources.DataSource, error)
+	// LabelMatchers returns the label matchers the queries to the data source must be restricted to, nil if the queries are not restricted
+	LabelMatchers(ds *datasources.DataSource) ([]*labels.Matcher, error)
 }
 
 func ProvideGuardian() *OSSProvider {
@@ -21,5 +25,5 @@ func ProvideGuardian() *OSSProvider {
 type OSSProvider struct{}
 
 func (p *OSSProvider) New(orgID int64, user identity.Requester, dataSources ...datasources.DataSource) DatasourceGuardian {
-	return &AllowGuardian{}
+	return &labelPolicyGuardian{user: user}
 }
@@ -0,0 +1,167 @@
+package guardian
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	"github.com/grafana/grafana/pkg/services/datasources"
+)
+
+// RestrictQuery adds the label matchers to every series selector of a PromQL or LogQL query
+func RestrictQuery(dsType, query string, matchers []*labels.Matcher) (string, error) {
+	if len(matchers) == 0 {
+		return query, nil
+	}
+
+	var restricted string
+	var err error
+	switch dsType {
+	case datasources.DS_PROMETHEUS:
+		restricted, err = restrictPromQL(query, matchers)
+	case datasources.DS_LOKI:
+		restricted, err = restrictLogQL(query, matchers)
+	default:
+		return "", ErrRestrictQuery.Errorf("label policies are not supported by %s data sources", dsType)
+	}
+	if err != nil {
+		return "", ErrRestrictQuery.Errorf("failed to apply label policy: %w", err)
+	}
+	return restricted, nil
+}
+
+// MatchersSelector returns a series selector matching the label matchers, ex: {namespace=~"team-a-.*"}
+func MatchersSelector(matchers []*labels.Matcher) string {
+	parts := make([]string, 0, len(matchers))
+	for _, m := range matchers {
+		parts = append(parts, m.String())
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// grafanaVariableRegexp matches the global variables interpolated by the data source backends, ex: $__rate_interval
+var grafanaVariableRegexp = regexp.MustCompile(`\$__\w+|\$\{__\w+\}`)
+
+func restrictPromQL(query string, matchers []*labels.Matcher) (string, error) {
+	// the variables left to the backend are not valid PromQL, they are replaced by unique placeholders
+	// with the same type before parsing and restored afterwards
+	var placeholders []string
+	var variables []string
+	query = grafanaVariableRegexp.ReplaceAllStringFunc(query, func(variable string) string {
+		i := len(placeholders)
+		name := strings.Trim(variable, "${}")
+		var placeholder, printed string
+		if strings.HasSuffix(name, "_ms") || strings.HasSuffix(name, "_s") {
+			placeholder = strconv.Itoa(987654321123 + i)
+			printed = (&parser.NumberLiteral{Val: float64(987654321123 + i)}).String()
+		} else {
+			d := time.Duration(987654321123+i) * time.Millisecond
+			placeholder = fmt.Sprintf("%dms", d.Milliseconds())
+			printed = model.Duration(d).String()
+		}
+		placeholders = append(placeholders, printed)
+		variables = append(variables, variable)
+		return placeholder
+	})
+
+	expr, err := parser.ParseExpr(query)
+	if err != nil {
+		return "", err
+	}
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		if selector, ok := node.(*parser.VectorSelector); ok {
+			selector.LabelMatchers = append(selector.LabelMatchers, matchers...)
+		}
+		return nil
+	})
+
+	restricted := expr.String()
+	for i, placeholder := range placeholders {
+		restricted = strings.Replace(restricted, placeholder, variables[i], 1)
+	}
+	return restricted, nil
+}
+
+// restrictLogQL adds the matchers to the stream selectors of a LogQL query. The stream selectors
+// are the curly braces outside of the strings, the rest of the query is left untouched.
+func restrictLogQL(query string, matchers []*labels.Matcher) (string, error) {
+	extra := MatchersSelector(matchers)
+	extra = extra[1 : len(extra)-1]
+
+	var b strings.Builder
+	selectors := 0
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch c {
+		case '"', '`':
+			end := stringEnd(query, i)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated string in query")
+			}
+			b.WriteString(query[i : end+1])
+			i = end
+		case '{':
+			end := selectorEnd(query, i)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated stream selector in query")
+			}
+			content := strings.TrimSpace(query[i+1 : end])
+			b.WriteByte('{')
+			if content != "" {
+				b.WriteString(content)
+				b.WriteString(", ")
+			}
+			b.WriteString(extra)
+			b.WriteByte('}')
+			i = end
+			selectors++
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	if selectors == 0 {
+		return "", fmt.Errorf("no stream selector in query")
+	}
+	return b.String(), nil
+}
+
+// stringEnd returns the index of the quote ending the string starting at start, -1 if the string is not terminated
+func stringEnd(query string, start int) int {
+	quote := query[start]
+	for i := start + 1; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			// raw strings have no escape sequences
+			if quote == '"' {
+				i++
+			}
+		case quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// selectorEnd returns the index of the curly brace ending the stream selector starting at start, -1 if none
+func selectorEnd(query string, start int) int {
+	for i := start + 1; i < len(query); i++ {
+		switch query[i] {
+		case '"', '`':
+			end := stringEnd(query, i)
+			if end < 0 {
+				return -1
+			}
+			i = end
+		case '}':
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,79 @@
+package guardian
+
+import (
+	"testing"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/datasources"
+)
+
+func TestRestrictQuery(t *testing.T) {
+	matchers := []*labels.Matcher{
+		labels.MustNewMatcher(labels.MatchRegexp, "namespace", "team-a-.*"),
+	}
+
+	t.Run("prometheus", func(t *testing.T) {
+		tests := []struct {
+			query    string
+			expected string
+		}{
+			{query: `up`, expected: `up{namespace=~"team-a-.*"}`},
+			{query: `up{job="api"}`, expected: `up{job="api",namespace=~"team-a-.*"}`},
+			{
+				query:    `sum by (job) (rate(http_requests_total{code="500"}[5m])) / sum by (job) (rate(http_requests_total[5m]))`,
+				expected: `sum by (job) (rate(http_requests_total{code="500",namespace=~"team-a-.*"}[5m])) / sum by (job) (rate(http_requests_total{namespace=~"team-a-.*"}[5m]))`,
+			},
+			{
+				query:    `rate(http_requests_total[$__rate_interval] offset $__interval)`,
+				expected: `rate(http_requests_total{namespace=~"team-a-.*"}[$__rate_interval] offset $__interval)`,
+			},
+			{query: `up / ${__range_s}`, expected: `up{namespace=~"team-a-.*"} / ${__range_s}`},
+		}
+		for _, tt := range tests {
+			t.Run(tt.query, func(t *testing.T) {
+				restricted, err := RestrictQuery(datasources.DS_PROMETHEUS, tt.query, matchers)
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, restricted)
+			})
+		}
+	})
+
+	t.Run("loki", func(t *testing.T) {
+		tests := []struct {
+			query    string
+			expected string
+		}{
+			{query: `{app="api"}`, expected: `{app="api", namespace=~"team-a-.*"}`},
+			{
+				query:    `{app="api"} |= "{not a selector}" | json | line_format "{{.msg}}"`,
+				expected: `{app="api", namespace=~"team-a-.*"} |= "{not a selector}" | json | line_format "{{.msg}}"`,
+			},
+			{
+				query:    "sum by (app) (count_over_time({app=~`a|b`} |~ `\\d+` [$__interval]))",
+				expected: "sum by (app) (count_over_time({app=~`a|b`, namespace=~\"team-a-.*\"} |~ `\\d+` [$__interval]))",
+			},
+			{query: `{app="say \"}\""}`, expected: `{app="say \"}\"", namespace=~"team-a-.*"}`},
+		}
+		for _, tt := range tests {
+			t.Run(tt.query, func(t *testing.T) {
+				restricted, err := RestrictQuery(datasources.DS_LOKI, tt.query, matchers)
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, restricted)
+			})
+		}
+	})
+
+	t.Run("should fail on invalid queries", func(t *testing.T) {
+		_, err := RestrictQuery(datasources.DS_PROMETHEUS, `sum(`, matchers)
+		assert.ErrorIs(t, err, ErrRestrictQuery)
+
+		_, err = RestrictQuery(datasources.DS_LOKI, `{app="api"`, matchers)
+		assert.ErrorIs(t, err, ErrRestrictQuery)
+
+		_, err = RestrictQuery(datasources.DS_LOKI, `"no selector"`, matchers)
+		assert.ErrorIs(t, err, ErrRestrictQuery)
+	})
+}
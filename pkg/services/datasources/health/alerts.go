@@ -0,0 +1,99 @@
+package health
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/grafana/pkg/services/ngalert"
+	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/notifier"
+)
+
+// UnhealthyAlertName is the name of the alert fired when a data source health check fails
+const UnhealthyAlertName = "DatasourceUnhealthy"
+
+type alertmanagers interface {
+	AlertmanagerFor(orgID int64) (notifier.Alertmanager, error)
+}
+
+// ngAlertmanagers resolves the Alertmanager of the organizations once Grafana Alerting is initialized
+type ngAlertmanagers struct {
+	ng *ngalert.AlertNG
+}
+
+func (a *ngAlertmanagers) AlertmanagerFor(orgID int64) (notifier.Alertmanager, error) {
+	if a.ng.MultiOrgAlertmanager == nil {
+		return nil, notifier.ErrAlertmanagerNotReady
+	}
+	return a.ng.MultiOrgAlertmanager.AlertmanagerFor(orgID)
+}
+
+// sendAlerts fires an alert to the Grafana Alertmanager of the organization for every unhealthy data source and
+// resolves the alerts of the data sources that recovered. The firing alerts are sent again at every check and
+// expire by themselves when the data source is deleted.
+func (s *Service) sendAlerts(previous, checks []*Check) {
+	wasUnhealthy := make(map[string]bool, len(previous))
+	for _, check := range previous {
+		wasUnhealthy[checkKey(check)] = !check.Healthy()
+	}
+
+	now := s.now()
+	byOrg := make(map[int64][]models.PostableAlert)
+	for _, check := range checks {
+		healthy := check.Healthy()
+		if healthy && !wasUnhealthy[checkKey(check)] {
+			continue
+		}
+
+		endsAt := now.Add(3 * s.settings.interval)
+		if healthy {
+			endsAt = now
+		}
+		byOrg[check.OrgID] = append(byOrg[check.OrgID], s.alert(check, now, endsAt))
+	}
+
+	for orgID, alerts := range byOrg {
+		am, err := s.alertmanagers.AlertmanagerFor(orgID)
+		if err != nil {
+			if !errors.Is(err, notifier.ErrNoAlertmanagerForOrg) {
+				s.log.Warn("Failed to get the Alertmanager of the organization", "orgId", orgID, "error", err)
+			}
+			continue
+		}
+		if err := am.PutAlerts(apimodels.PostableAlerts{PostableAlerts: alerts}); err != nil {
+			s.log.Error("Failed to send data source health alerts", "orgId", orgID, "error", err)
+		}
+	}
+}
+
+func (s *Service) alert(check *Check, startsAt, endsAt time.Time) models.PostableAlert {
+	annotations := models.LabelSet{"summary": "Data source " + check.DataSourceName + " is unhealthy"}
+	if check.Message != "" {
+		annotations["description"] = check.Message
+	}
+
+	return models.PostableAlert{
+		Annotations: annotations,
+		StartsAt:    strfmt.DateTime(startsAt),
+		EndsAt:      strfmt.DateTime(endsAt),
+		Alert: models.Alert{
+			Labels: models.LabelSet{
+				model.AlertNameLabel: UnhealthyAlertName,
+				"datasource_uid":     check.DataSourceUID,
+				"datasource_name":    check.DataSourceName,
+				"datasource_type":    check.DataSourceType,
+			},
+			GeneratorURL: strfmt.URI(strings.TrimSuffix(s.cfg.AppURL, "/") + "/connections/datasources/edit/" + check.DataSourceUID),
+		},
+	}
+}
+
+func checkKey(check *Check) string {
+	return strconv.FormatInt(check.OrgID, 10) + "/" + check.DataSourceUID
+}
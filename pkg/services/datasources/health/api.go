@@ -0,0 +1,73 @@
+package health
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/middleware"
+	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+func (s *Service) registerAPIEndpoints(routeRegister routing.RouteRegister) {
+	authorize := ac.Middleware(s.accessControl)
+	uidScope := datasources.ScopeProvider.GetResourceScopeUID(ac.Parameter(":uid"))
+
+	routeRegister.Group("/api/datasources", func(datasourceRoute routing.RouteRegister) {
+		datasourceRoute.Get("/health/unhealthy", middleware.ReqSignedIn, authorize(ac.EvalPermission(datasources.ActionRead)), routing.Wrap(s.handleGetUnhealthy))
+		datasourceRoute.Get("/uid/:uid/health/history", middleware.ReqSignedIn, authorize(ac.EvalPermission(datasources.ActionRead, uidScope)), routing.Wrap(s.handleGetHistory))
+	})
+}
+
+// handleGetUnhealthy lists the data sources of the organization whose latest health check failed,
+// restricted to the data sources the user can read
+func (s *Service) handleGetUnhealthy(c *contextmodel.ReqContext) response.Response {
+	checks, err := s.GetUnhealthy(c.Req.Context(), c.SignedInUser.GetOrgID())
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get the unhealthy data sources", err)
+	}
+
+	allowed := make([]*Check, 0, len(checks))
+	for _, check := range checks {
+		evaluator := ac.EvalPermission(datasources.ActionRead, datasources.ScopeProvider.GetResourceScopeUID(check.DataSourceUID))
+		if ok, err := s.accessControl.Evaluate(c.Req.Context(), c.SignedInUser, evaluator); err != nil || !ok {
+			continue
+		}
+		allowed = append(allowed, check)
+	}
+	return response.JSON(http.StatusOK, allowed)
+}
+
+// handleGetHistory returns the health checks of a data source, from / to are epoch milliseconds
+func (s *Service) handleGetHistory(c *contextmodel.ReqContext) response.Response {
+	uid := web.Params(c.Req)[":uid"]
+	if _, err := s.dataSourceService.GetDataSource(c.Req.Context(), &datasources.GetDataSourceQuery{UID: uid, OrgID: c.SignedInUser.GetOrgID()}); err != nil {
+		if errors.Is(err, datasources.ErrDataSourceNotFound) {
+			return response.Error(http.StatusNotFound, "Data source not found", nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to query data source", err)
+	}
+
+	query := &HistoryQuery{
+		OrgID:         c.SignedInUser.GetOrgID(),
+		DataSourceUID: uid,
+		Limit:         c.QueryInt("limit"),
+	}
+	if from := c.QueryInt64("from"); from > 0 {
+		query.From = time.UnixMilli(from)
+	}
+	if to := c.QueryInt64("to"); to > 0 {
+		query.To = time.UnixMilli(to)
+	}
+
+	checks, err := s.GetHistory(c.Req.Context(), query)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get the data source health history", err)
+	}
+	return response.JSON(http.StatusOK, checks)
+}
@@ -0,0 +1,399 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/serverlock"
+	"github.com/grafana/grafana/pkg/plugins"
+	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/auth/identity"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/ngalert"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/plugincontext"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/pluginstore"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const (
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 5000
+
+	// lockPollsPerInterval is how many times per interval the instances try to take the lock of the checks
+	lockPollsPerInterval = 4
+)
+
+var (
+	healthStatus = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "grafana",
+		Subsystem: "datasource",
+		Name:      "health_status",
+		Help:      "Result of the last health check of the data source, 1 when healthy and 0 otherwise",
+	}, []string{"org_id", "datasource", "datasource_type"})
+
+	healthCheckDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "grafana",
+		Subsystem: "datasource",
+		Name:      "health_check_duration_seconds",
+		Help:      "Duration of the background data source health checks",
+		Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"datasource_type", "status"})
+)
+
+// Check is the result of the health check of a data source
+type Check struct {
+	OrgID          int64     `json:"orgId"`
+	DataSourceUID  string    `json:"datasourceUid"`
+	DataSourceName string    `json:"datasourceName,omitempty"`
+	DataSourceType string    `json:"datasourceType,omitempty"`
+	Status         string    `json:"status"`
+	Message        string    `json:"message,omitempty"`
+	LatencyMs      int64     `json:"latencyMs"`
+	Checked        time.Time `json:"checked"`
+}
+
+func (c *Check) Healthy() bool {
+	return c.Status == backend.HealthStatusOk.String()
+}
+
+type HistoryQuery struct {
+	OrgID         int64
+	DataSourceUID string
+	From          time.Time
+	To            time.Time
+	Limit         int
+}
+
+type settings struct {
+	enabled          bool
+	interval         time.Duration
+	timeout          time.Duration
+	maxConcurrent    int
+	historyRetention time.Duration
+	alerting         bool
+}
+
+func readSettings(cfg *setting.Cfg) (settings, error) {
+	section := cfg.SectionWithEnvOverrides("datasource_health")
+	s := settings{
+		enabled:       section.Key("enabled").MustBool(false),
+		interval:      section.Key("interval").MustDuration(5 * time.Minute),
+		timeout:       section.Key("timeout").MustDuration(30 * time.Second),
+		maxConcurrent: section.Key("max_concurrent_checks").MustInt(10),
+		alerting:      section.Key("alerting_enabled").MustBool(false),
+	}
+	if s.interval < time.Minute {
+		s.interval = time.Minute
+	}
+	if s.maxConcurrent < 1 {
+		s.maxConcurrent = 1
+	}
+
+	retention, err := gtime.ParseDuration(section.Key("history_retention").MustString("7d"))
+	if err != nil {
+		return s, err
+	}
+	s.historyRetention = retention
+	return s, nil
+}
+
+type pluginContextProvider interface {
+	GetWithDataSource(ctx context.Context, pluginID string, user identity.Requester, ds *datasources.DataSource) (backend.PluginContext, error)
+}
+
+// Service periodically runs the health checks of all data sources and records their history.
+type Service struct {
+	cfg                   *setting.Cfg
+	settings              settings
+	log                   log.Logger
+	store                 store
+	serverLock            *serverlock.ServerLockService
+	accessControl         ac.AccessControl
+	dataSourceService     datasources.DataSourceService
+	pluginStore           pluginstore.Store
+	pluginContextProvider pluginContextProvider
+	pluginClient          backend.CheckHealthHandler
+	alertmanagers         alertmanagers
+	now                   func() time.Time
+
+	mu sync.Mutex
+	// reported are the labels of the health status series currently exported
+	reported map[healthStatusLabels]struct{}
+}
+
+type healthStatusLabels struct {
+	orgID          string
+	dataSourceUID  string
+	dataSourceType string
+}
+
+func ProvideService(cfg *setting.Cfg, sqlStore db.DB, serverLock *serverlock.ServerLockService, accessControl ac.AccessControl,
+	dataSourceService datasources.DataSourceService, pluginStore pluginstore.Store, pluginContextProvider *plugincontext.Provider,
+	pluginClient plugins.Client, ng *ngalert.AlertNG, routeRegister routing.RouteRegister) (*Service, error) {
+	settings, err := readSettings(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Service{
+		cfg:                   cfg,
+		settings:              settings,
+		log:                   log.New("datasources.health"),
+		store:                 &xormStore{db: sqlStore},
+		serverLock:            serverLock,
+		accessControl:         accessControl,
+		dataSourceService:     dataSourceService,
+		pluginStore:           pluginStore,
+		pluginContextProvider: pluginContextProvider,
+		pluginClient:          pluginClient,
+		now:                   time.Now,
+	}
+	if !settings.enabled {
+		return s, nil
+	}
+
+	if settings.alerting {
+		if ng == nil || ng.IsDisabled() {
+			s.log.Warn("Data source health alerts require Grafana Alerting, they are disabled")
+		} else {
+			s.alertmanagers = &ngAlertmanagers{ng: ng}
+		}
+	}
+
+	s.registerAPIEndpoints(routeRegister)
+	return s, nil
+}
+
+func (s *Service) IsDisabled() bool {
+	return !s.settings.enabled
+}
+
+// Run checks the health of the data sources at every interval. The checks run on a single instance, which holds
+// the lock for the whole interval. The instances try to take the lock several times per interval so that the checks
+// still run about every interval when the clocks of the instances drift. Every instance updates the health status
+// metric from the latest recorded checks.
+func (s *Service) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.settings.interval / lockPollsPerInterval)
+	defer ticker.Stop()
+
+	for {
+		err := s.serverLock.LockAndExecute(ctx, "datasource health checks", s.settings.interval, func(ctx context.Context) {
+			s.checkAll(ctx)
+			s.deleteExpiredChecks(ctx)
+		})
+		if err != nil {
+			s.log.Error("Failed to lock and execute data source health checks", "error", err)
+		}
+		s.updateHealthStatus(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// checkAll checks the health of all data sources, records the results and updates the alerts
+func (s *Service) checkAll(ctx context.Context) {
+	previous, err := s.store.GetLatest(ctx, 0)
+	if err != nil {
+		s.log.Error("Failed to get the latest data source health checks", "error", err)
+		return
+	}
+	dataSources, err := s.dataSourceService.GetAllDataSources(ctx, &datasources.GetAllDataSourcesQuery{})
+	if err != nil {
+		s.log.Error("Failed to get the data sources", "error", err)
+		return
+	}
+
+	var mu sync.Mutex
+	checks := make([]*Check, 0, len(dataSources))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(s.settings.maxConcurrent)
+	for _, ds := range dataSources {
+		ds := ds
+		g.Go(func() error {
+			check := s.check(gctx, ds)
+			if check == nil {
+				return nil
+			}
+			mu.Lock()
+			checks = append(checks, check)
+			mu.Unlock()
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	if err := s.store.Insert(ctx, checks); err != nil {
+		s.log.Error("Failed to record the data source health checks", "error", err)
+	}
+
+	unhealthy := 0
+	for _, check := range checks {
+		if !check.Healthy() {
+			unhealthy++
+		}
+	}
+	s.log.Debug("Checked the health of the data sources", "checked", len(checks), "unhealthy", unhealthy)
+
+	if s.alertmanagers != nil {
+		s.sendAlerts(previous, checks)
+	}
+}
+
+// updateHealthStatus sets the health status metric from the latest recorded checks, the series of the data
+// sources deleted since the last update are removed
+func (s *Service) updateHealthStatus(ctx context.Context) {
+	latest, err := s.store.GetLatest(ctx, 0)
+	if err != nil {
+		s.log.Error("Failed to get the latest data source health checks", "error", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reported := make(map[healthStatusLabels]struct{}, len(latest))
+	for _, check := range latest {
+		labels := healthStatusLabels{
+			orgID:          strconv.FormatInt(check.OrgID, 10),
+			dataSourceUID:  check.DataSourceUID,
+			dataSourceType: check.DataSourceType,
+		}
+		value := 0.0
+		if check.Healthy() {
+			value = 1
+		}
+		healthStatus.WithLabelValues(labels.orgID, labels.dataSourceUID, labels.dataSourceType).Set(value)
+		reported[labels] = struct{}{}
+	}
+	for labels := range s.reported {
+		if _, ok := reported[labels]; !ok {
+			healthStatus.DeleteLabelValues(labels.orgID, labels.dataSourceUID, labels.dataSourceType)
+		}
+	}
+	s.reported = reported
+}
+
+// check runs the health check of a data source, it returns nil when the plugin of the data source
+// does not support health checks
+func (s *Service) check(ctx context.Context, ds *datasources.DataSource) *Check {
+	check := &Check{
+		OrgID:          ds.OrgID,
+		DataSourceUID:  ds.UID,
+		DataSourceName: ds.Name,
+		DataSourceType: ds.Type,
+		Checked:        s.now(),
+	}
+
+	plugin, exists := s.pluginStore.Plugin(ctx, ds.Type)
+	if !exists {
+		check.Status = backend.HealthStatusError.String()
+		check.Message = "Data source plugin is not installed"
+		return check
+	}
+	if !plugin.Backend {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.settings.timeout)
+	defer cancel()
+
+	start := time.Now()
+	resp, err := s.checkHealth(ctx, ds)
+	latency := time.Since(start)
+	if errors.Is(err, plugins.ErrMethodNotImplemented) {
+		return nil
+	}
+
+	check.LatencyMs = latency.Milliseconds()
+	if err != nil {
+		check.Status = backend.HealthStatusError.String()
+		check.Message = err.Error()
+		if errors.Is(err, context.DeadlineExceeded) {
+			check.Message = "Health check timed out after " + s.settings.timeout.String()
+		}
+	} else {
+		check.Status = resp.Status.String()
+		check.Message = resp.Message
+	}
+	healthCheckDuration.WithLabelValues(ds.Type, check.Status).Observe(latency.Seconds())
+
+	if !check.Healthy() {
+		s.log.Warn("Data source is unhealthy", "orgId", ds.OrgID, "datasource", ds.UID, "type", ds.Type, "status", check.Status, "message", check.Message)
+	}
+	return check
+}
+
+func (s *Service) checkHealth(ctx context.Context, ds *datasources.DataSource) (*backend.CheckHealthResult, error) {
+	pCtx, err := s.pluginContextProvider.GetWithDataSource(ctx, ds.Type, backgroundUser(ds.OrgID), ds)
+	if err != nil {
+		return nil, err
+	}
+	return s.pluginClient.CheckHealth(ctx, &backend.CheckHealthRequest{
+		PluginContext: pCtx,
+		Headers:       map[string]string{},
+	})
+}
+
+func (s *Service) deleteExpiredChecks(ctx context.Context) {
+	if s.settings.historyRetention <= 0 {
+		return
+	}
+	deleted, err := s.store.DeleteOlderThan(ctx, s.now().Add(-s.settings.historyRetention))
+	if err != nil {
+		s.log.Error("Failed to delete expired data source health checks", "error", err)
+		return
+	}
+	if deleted > 0 {
+		s.log.Debug("Deleted expired data source health checks", "count", deleted)
+	}
+}
+
+// GetUnhealthy returns the latest check of the unhealthy data sources of an organization
+func (s *Service) GetUnhealthy(ctx context.Context, orgID int64) ([]*Check, error) {
+	latest, err := s.store.GetLatest(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	unhealthy := make([]*Check, 0)
+	for _, check := range latest {
+		if !check.Healthy() {
+			unhealthy = append(unhealthy, check)
+		}
+	}
+	return unhealthy, nil
+}
+
+// GetHistory returns the health checks of a data source, the most recent first
+func (s *Service) GetHistory(ctx context.Context, query *HistoryQuery) ([]*Check, error) {
+	if query.Limit <= 0 {
+		query.Limit = defaultHistoryLimit
+	}
+	if query.Limit > maxHistoryLimit {
+		query.Limit = maxHistoryLimit
+	}
+	return s.store.GetHistory(ctx, query)
+}
+
+// backgroundUser is the identity the health checks are executed as.
+func backgroundUser(orgID int64) identity.Requester {
+	return ac.BackgroundUser("datasource_health_monitor", orgID, org.RoleAdmin, []ac.Permission{
+		{Action: datasources.ActionQuery, Scope: datasources.ScopeAll},
+		{Action: datasources.ActionRead, Scope: datasources.ScopeAll},
+	})
+}
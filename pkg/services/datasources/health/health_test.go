@@ -0,0 +1,261 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/services/auth/identity"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	fakeDatasources "github.com/grafana/grafana/pkg/services/datasources/fakes"
+	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/notifier"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/pluginstore"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestReadSettings(t *testing.T) {
+	t.Run("should use the defaults", func(t *testing.T) {
+		s, err := readSettings(setting.NewCfg())
+		require.NoError(t, err)
+		assert.False(t, s.enabled)
+		assert.Equal(t, 5*time.Minute, s.interval)
+		assert.Equal(t, 30*time.Second, s.timeout)
+		assert.Equal(t, 10, s.maxConcurrent)
+		assert.Equal(t, 7*24*time.Hour, s.historyRetention)
+		assert.False(t, s.alerting)
+	})
+
+	t.Run("should not check more often than every minute", func(t *testing.T) {
+		cfg := setting.NewCfg()
+		_, err := cfg.Raw.Section("datasource_health").NewKey("interval", "10s")
+		require.NoError(t, err)
+
+		s, err := readSettings(cfg)
+		require.NoError(t, err)
+		assert.Equal(t, time.Minute, s.interval)
+	})
+}
+
+func TestCheckAll(t *testing.T) {
+	now := time.Date(2023, 10, 22, 8, 0, 0, 0, time.UTC)
+
+	setup := func(t *testing.T, results map[string]*backend.CheckHealthResult) (*Service, *fakeStore, *fakeAlertmanager) {
+		t.Helper()
+		store := &fakeStore{}
+		am := &fakeAlertmanager{}
+		s := &Service{
+			cfg:      &setting.Cfg{AppURL: "http://localhost:3000/"},
+			settings: settings{interval: time.Minute, timeout: time.Second, maxConcurrent: 2, historyRetention: time.Hour},
+			log:      log.NewNopLogger(),
+			store:    store,
+			dataSourceService: &fakeDatasources.FakeDataSourceService{DataSources: []*datasources.DataSource{
+				{OrgID: 1, UID: "prom", Name: "Prometheus", Type: "prometheus"},
+				{OrgID: 1, UID: "loki", Name: "Loki", Type: "loki"},
+				{OrgID: 2, UID: "frontend", Name: "Frontend", Type: "frontend"},
+				{OrgID: 2, UID: "missing", Name: "Missing", Type: "missing"},
+				{OrgID: 2, UID: "nohealth", Name: "No health", Type: "nohealth"},
+			}},
+			pluginStore: &pluginstore.FakePluginStore{PluginList: []pluginstore.Plugin{
+				{JSONData: plugins.JSONData{ID: "prometheus", Backend: true}},
+				{JSONData: plugins.JSONData{ID: "loki", Backend: true}},
+				{JSONData: plugins.JSONData{ID: "nohealth", Backend: true}},
+				{JSONData: plugins.JSONData{ID: "frontend"}},
+			}},
+			pluginContextProvider: &fakePluginContextProvider{},
+			pluginClient:          &fakeHealthChecker{results: results},
+			alertmanagers:         &fakeAlertmanagers{am: am},
+			now:                   func() time.Time { return now },
+		}
+		return s, store, am
+	}
+
+	t.Run("should record the checks of the data sources supporting health checks", func(t *testing.T) {
+		s, store, _ := setup(t, map[string]*backend.CheckHealthResult{
+			"prom": {Status: backend.HealthStatusOk, Message: "Data source is working"},
+			"loki": {Status: backend.HealthStatusError, Message: "connection refused"},
+		})
+		s.checkAll(context.Background())
+
+		checks := store.checks
+		sort.Slice(checks, func(i, j int) bool { return checks[i].DataSourceUID < checks[j].DataSourceUID })
+		require.Len(t, checks, 3)
+
+		assert.Equal(t, "loki", checks[0].DataSourceUID)
+		assert.Equal(t, "ERROR", checks[0].Status)
+		assert.Equal(t, "connection refused", checks[0].Message)
+		assert.Equal(t, now, checks[0].Checked)
+
+		assert.Equal(t, "missing", checks[1].DataSourceUID)
+		assert.Equal(t, "ERROR", checks[1].Status)
+		assert.Equal(t, "Data source plugin is not installed", checks[1].Message)
+
+		assert.Equal(t, "prom", checks[2].DataSourceUID)
+		assert.True(t, checks[2].Healthy())
+	})
+
+	t.Run("should record the plugin errors as unhealthy", func(t *testing.T) {
+		s, store, _ := setup(t, map[string]*backend.CheckHealthResult{})
+		s.pluginClient = &fakeHealthChecker{err: plugins.ErrPluginUnavailable.Errorf("plugin unavailable")}
+		s.checkAll(context.Background())
+
+		for _, check := range store.checks {
+			assert.False(t, check.Healthy())
+		}
+	})
+
+	t.Run("should fire alerts for the unhealthy data sources and resolve the recovered ones", func(t *testing.T) {
+		s, store, am := setup(t, map[string]*backend.CheckHealthResult{
+			"prom": {Status: backend.HealthStatusOk},
+			"loki": {Status: backend.HealthStatusError, Message: "connection refused"},
+		})
+		s.checkAll(context.Background())
+
+		require.Len(t, am.alerts, 2)
+		alerts := map[string]apimodels.PostableAlerts{}
+		for _, a := range am.alerts {
+			alerts[a.PostableAlerts[0].Labels["datasource_uid"]] = a
+		}
+
+		loki := alerts["loki"].PostableAlerts
+		require.Len(t, loki, 1)
+		assert.Equal(t, UnhealthyAlertName, loki[0].Labels["alertname"])
+		assert.Equal(t, "connection refused", loki[0].Annotations["description"])
+		assert.Equal(t, now.Add(3*time.Minute), time.Time(loki[0].EndsAt))
+		assert.Equal(t, "http://localhost:3000/connections/datasources/edit/loki", loki[0].GeneratorURL.String())
+		assert.Len(t, alerts["missing"].PostableAlerts, 1)
+
+		// loki recovers
+		am.alerts = nil
+		s.pluginClient = &fakeHealthChecker{results: map[string]*backend.CheckHealthResult{
+			"prom": {Status: backend.HealthStatusOk},
+			"loki": {Status: backend.HealthStatusOk},
+		}}
+		store.latest = store.checks
+		s.checkAll(context.Background())
+
+		resolved := false
+		for _, a := range am.alerts {
+			for _, alert := range a.PostableAlerts {
+				if alert.Labels["datasource_uid"] == "loki" {
+					resolved = true
+					assert.Equal(t, now, time.Time(alert.EndsAt))
+				}
+				assert.NotEqual(t, "prom", alert.Labels["datasource_uid"])
+			}
+		}
+		assert.True(t, resolved)
+	})
+}
+
+func TestGetUnhealthy(t *testing.T) {
+	store := &fakeStore{latest: []*Check{
+		{OrgID: 1, DataSourceUID: "a", Status: "OK"},
+		{OrgID: 1, DataSourceUID: "b", Status: "ERROR"},
+		{OrgID: 1, DataSourceUID: "c", Status: "UNKNOWN"},
+	}}
+	s := &Service{store: store}
+
+	unhealthy, err := s.GetUnhealthy(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, unhealthy, 2)
+	assert.Equal(t, "b", unhealthy[0].DataSourceUID)
+	assert.Equal(t, "c", unhealthy[1].DataSourceUID)
+}
+
+func TestUpdateHealthStatus(t *testing.T) {
+	store := &fakeStore{latest: []*Check{
+		{OrgID: 1, DataSourceUID: "a", DataSourceType: "prometheus", Status: "OK"},
+		{OrgID: 2, DataSourceUID: "b", DataSourceType: "loki", Status: "ERROR"},
+	}}
+	s := &Service{log: log.NewNopLogger(), store: store}
+	t.Cleanup(healthStatus.Reset)
+
+	s.updateHealthStatus(context.Background())
+	assert.Equal(t, 2, testutil.CollectAndCount(healthStatus))
+	assert.Equal(t, 1.0, testutil.ToFloat64(healthStatus.WithLabelValues("1", "a", "prometheus")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(healthStatus.WithLabelValues("2", "b", "loki")))
+
+	// b is deleted and a becomes unhealthy
+	store.latest = []*Check{{OrgID: 1, DataSourceUID: "a", DataSourceType: "prometheus", Status: "ERROR"}}
+	s.updateHealthStatus(context.Background())
+	assert.Equal(t, 1, testutil.CollectAndCount(healthStatus))
+	assert.Equal(t, 0.0, testutil.ToFloat64(healthStatus.WithLabelValues("1", "a", "prometheus")))
+}
+
+type fakeStore struct {
+	checks []*Check
+	latest []*Check
+}
+
+func (f *fakeStore) Insert(_ context.Context, checks []*Check) error {
+	f.checks = append(f.checks, checks...)
+	return nil
+}
+
+func (f *fakeStore) GetLatest(_ context.Context, _ int64) ([]*Check, error) {
+	return f.latest, nil
+}
+
+func (f *fakeStore) GetHistory(_ context.Context, _ *HistoryQuery) ([]*Check, error) {
+	return f.checks, nil
+}
+
+func (f *fakeStore) DeleteOlderThan(_ context.Context, _ time.Time) (int64, error) {
+	return 0, nil
+}
+
+type fakePluginContextProvider struct{}
+
+func (f *fakePluginContextProvider) GetWithDataSource(_ context.Context, pluginID string, _ identity.Requester, ds *datasources.DataSource) (backend.PluginContext, error) {
+	return backend.PluginContext{
+		PluginID:                   pluginID,
+		DataSourceInstanceSettings: &backend.DataSourceInstanceSettings{UID: ds.UID},
+	}, nil
+}
+
+type fakeHealthChecker struct {
+	results map[string]*backend.CheckHealthResult
+	err     error
+}
+
+func (f *fakeHealthChecker) CheckHealth(_ context.Context, req *backend.CheckHealthRequest) (*backend.CheckHealthResult, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	result, ok := f.results[req.PluginContext.DataSourceInstanceSettings.UID]
+	if !ok {
+		return nil, plugins.ErrMethodNotImplemented.Errorf("not implemented")
+	}
+	return result, nil
+}
+
+type fakeAlertmanagers struct {
+	am *fakeAlertmanager
+}
+
+func (f *fakeAlertmanagers) AlertmanagerFor(orgID int64) (notifier.Alertmanager, error) {
+	if orgID > 2 {
+		return nil, errors.New("unexpected organization")
+	}
+	return f.am, nil
+}
+
+type fakeAlertmanager struct {
+	notifier.Alertmanager
+	alerts []apimodels.PostableAlerts
+}
+
+func (f *fakeAlertmanager) PutAlerts(alerts apimodels.PostableAlerts) error {
+	f.alerts = append(f.alerts, alerts)
+	return nil
+}
@@ -0,0 +1,149 @@
+package health
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+)
+
+type store interface {
+	Insert(ctx context.Context, checks []*Check) error
+	// GetLatest returns the latest check of the existing data sources of an organization, of all organizations when orgID is 0
+	GetLatest(ctx context.Context, orgID int64) ([]*Check, error)
+	GetHistory(ctx context.Context, query *HistoryQuery) ([]*Check, error)
+	DeleteOlderThan(ctx context.Context, olderThan time.Time) (int64, error)
+}
+
+type xormStore struct {
+	db db.DB
+}
+
+// healthCheckEntry is the database representation of Check
+type healthCheckEntry struct {
+	ID            int64  `xorm:"pk autoincr 'id'"`
+	OrgID         int64  `xorm:"org_id"`
+	DataSourceUID string `xorm:"data_source_uid"`
+	Status        string `xorm:"status"`
+	Message       string `xorm:"message"`
+	LatencyMs     int64  `xorm:"latency_ms"`
+	Checked       int64  `xorm:"'checked'"` // epoch milliseconds
+}
+
+func (healthCheckEntry) TableName() string {
+	return "data_source_health_check"
+}
+
+// latestCheckRow is a health check joined with its data source
+type latestCheckRow struct {
+	OrgID          int64  `xorm:"org_id"`
+	DataSourceUID  string `xorm:"data_source_uid"`
+	DataSourceName string `xorm:"data_source_name"`
+	DataSourceType string `xorm:"data_source_type"`
+	Status         string `xorm:"status"`
+	Message        string `xorm:"message"`
+	LatencyMs      int64  `xorm:"latency_ms"`
+	Checked        int64  `xorm:"'checked'"`
+}
+
+func (xs *xormStore) Insert(ctx context.Context, checks []*Check) error {
+	return xs.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		for _, check := range checks {
+			entry := &healthCheckEntry{
+				OrgID:         check.OrgID,
+				DataSourceUID: check.DataSourceUID,
+				Status:        check.Status,
+				Message:       check.Message,
+				LatencyMs:     check.LatencyMs,
+				Checked:       check.Checked.UnixMilli(),
+			}
+			if _, err := sess.Insert(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (xs *xormStore) GetLatest(ctx context.Context, orgID int64) ([]*Check, error) {
+	checks := make([]*Check, 0)
+	err := xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		filter := ""
+		var args []any
+		if orgID != 0 {
+			filter = "WHERE org_id = ?"
+			args = append(args, orgID)
+		}
+
+		rawSQL := `SELECT h.org_id, h.data_source_uid, ds.name AS data_source_name, ds.type AS data_source_type,
+			h.status, h.message, h.latency_ms, h.checked
+		FROM data_source_health_check h
+		INNER JOIN (
+			SELECT MAX(id) AS id FROM data_source_health_check ` + filter + ` GROUP BY org_id, data_source_uid
+		) latest ON latest.id = h.id
+		INNER JOIN data_source ds ON ds.org_id = h.org_id AND ds.uid = h.data_source_uid
+		ORDER BY h.org_id, ds.name`
+
+		rows := make([]*latestCheckRow, 0)
+		if err := sess.SQL(rawSQL, args...).Find(&rows); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			checks = append(checks, &Check{
+				OrgID:          row.OrgID,
+				DataSourceUID:  row.DataSourceUID,
+				DataSourceName: row.DataSourceName,
+				DataSourceType: row.DataSourceType,
+				Status:         row.Status,
+				Message:        row.Message,
+				LatencyMs:      row.LatencyMs,
+				Checked:        time.UnixMilli(row.Checked),
+			})
+		}
+		return nil
+	})
+	return checks, err
+}
+
+func (xs *xormStore) GetHistory(ctx context.Context, query *HistoryQuery) ([]*Check, error) {
+	checks := make([]*Check, 0)
+	err := xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		sess.Where("org_id = ? AND data_source_uid = ?", query.OrgID, query.DataSourceUID)
+		if !query.From.IsZero() {
+			sess.And("checked >= ?", query.From.UnixMilli())
+		}
+		if !query.To.IsZero() {
+			sess.And("checked <= ?", query.To.UnixMilli())
+		}
+
+		entries := make([]*healthCheckEntry, 0)
+		if err := sess.Desc("checked", "id").Limit(query.Limit).Find(&entries); err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			checks = append(checks, &Check{
+				OrgID:         entry.OrgID,
+				DataSourceUID: entry.DataSourceUID,
+				Status:        entry.Status,
+				Message:       entry.Message,
+				LatencyMs:     entry.LatencyMs,
+				Checked:       time.UnixMilli(entry.Checked),
+			})
+		}
+		return nil
+	})
+	return checks, err
+}
+
+func (xs *xormStore) DeleteOlderThan(ctx context.Context, olderThan time.Time) (int64, error) {
+	var deletedRows int64
+	err := xs.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		res, err := sess.Exec("DELETE FROM data_source_health_check WHERE checked < ?", olderThan.UnixMilli())
+		if err != nil {
+			return err
+		}
+		deletedRows, err = res.RowsAffected()
+		return err
+	})
+	return deletedRows, err
+}
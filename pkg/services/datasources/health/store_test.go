@@ -0,0 +1,89 @@
+package health
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+)
+
+func TestIntegrationHealthCheckStore(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	beginningOfTime := time.Date(2023, 10, 22, 8, 0, 0, 0, time.UTC)
+
+	setup := func(t *testing.T) *xormStore {
+		t.Helper()
+		sqlStore := db.InitTestDB(t)
+		err := sqlStore.WithDbSession(context.Background(), func(sess *db.Session) error {
+			_, err := sess.Exec(`INSERT INTO data_source (org_id, version, type, name, access, url, basic_auth, is_default, uid, created, updated)
+				VALUES (1, 1, 'prometheus', 'Prometheus', 'proxy', '', ?, ?, 'prom', ?, ?), (1, 1, 'loki', 'Loki', 'proxy', '', ?, ?, 'loki', ?, ?)`,
+				false, false, beginningOfTime, beginningOfTime, false, false, beginningOfTime, beginningOfTime)
+			return err
+		})
+		require.NoError(t, err)
+
+		s := &xormStore{db: sqlStore}
+		err = s.Insert(context.Background(), []*Check{
+			{OrgID: 1, DataSourceUID: "prom", Status: "ERROR", Message: "connection refused", LatencyMs: 10, Checked: beginningOfTime},
+			{OrgID: 1, DataSourceUID: "loki", Status: "OK", LatencyMs: 20, Checked: beginningOfTime},
+			{OrgID: 1, DataSourceUID: "prom", Status: "OK", LatencyMs: 30, Checked: beginningOfTime.Add(time.Minute)},
+			{OrgID: 1, DataSourceUID: "loki", Status: "ERROR", Message: "timeout", LatencyMs: 40, Checked: beginningOfTime.Add(time.Minute)},
+			// the data source was deleted
+			{OrgID: 1, DataSourceUID: "deleted", Status: "ERROR", Checked: beginningOfTime.Add(time.Minute)},
+		})
+		require.NoError(t, err)
+		return s
+	}
+
+	t.Run("should get the latest check of the existing data sources", func(t *testing.T) {
+		s := setup(t)
+
+		latest, err := s.GetLatest(context.Background(), 1)
+		require.NoError(t, err)
+		require.Len(t, latest, 2)
+		assert.Equal(t, &Check{
+			OrgID: 1, DataSourceUID: "loki", DataSourceName: "Loki", DataSourceType: "loki",
+			Status: "ERROR", Message: "timeout", LatencyMs: 40, Checked: beginningOfTime.Add(time.Minute).Local(),
+		}, latest[0])
+		assert.Equal(t, "prom", latest[1].DataSourceUID)
+		assert.Equal(t, "OK", latest[1].Status)
+
+		latest, err = s.GetLatest(context.Background(), 2)
+		require.NoError(t, err)
+		assert.Empty(t, latest)
+	})
+
+	t.Run("should get the history of a data source", func(t *testing.T) {
+		s := setup(t)
+
+		history, err := s.GetHistory(context.Background(), &HistoryQuery{OrgID: 1, DataSourceUID: "prom", Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+		assert.Equal(t, int64(30), history[0].LatencyMs)
+		assert.Equal(t, int64(10), history[1].LatencyMs)
+
+		history, err = s.GetHistory(context.Background(), &HistoryQuery{OrgID: 1, DataSourceUID: "prom", To: beginningOfTime, Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		assert.Equal(t, "connection refused", history[0].Message)
+	})
+
+	t.Run("should delete the checks past retention", func(t *testing.T) {
+		s := setup(t)
+
+		deleted, err := s.DeleteOlderThan(context.Background(), beginningOfTime.Add(time.Second))
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), deleted)
+
+		history, err := s.GetHistory(context.Background(), &HistoryQuery{OrgID: 1, DataSourceUID: "loki", Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+	})
+}
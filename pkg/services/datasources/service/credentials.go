@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/datasources"
+)
+
+// credentialUsageInterval is the minimum interval between two writes of the last use of a credential
+const credentialUsageInterval = time.Minute
+
+var _ datasources.CredentialService = (*Service)(nil)
+
+type activeCredential struct {
+	credential datasources.Credential
+	since      time.Time
+	// dsUpdated is the last update of the datasource when the credential became active, the primary credential
+	// is active again once the datasource is updated
+	dsUpdated time.Time
+}
+
+// credentialTracker holds the state of the datasources having secondary secrets
+type credentialTracker struct {
+	mu       sync.Mutex
+	active   map[string]activeCredential
+	recorded map[string]time.Time
+}
+
+func credentialKey(ds *datasources.DataSource) string {
+	return fmt.Sprintf("%d/%s", ds.OrgID, ds.UID)
+}
+
+func (s *Service) ActiveCredential(ds *datasources.DataSource) (datasources.Credential, time.Time) {
+	s.credentials.mu.Lock()
+	defer s.credentials.mu.Unlock()
+
+	if a, ok := s.credentials.active[credentialKey(ds)]; ok && a.dsUpdated.Equal(ds.Updated) {
+		return a.credential, a.since
+	}
+	return datasources.CredentialPrimary, ds.Updated
+}
+
+func (s *Service) UseCredential(ds *datasources.DataSource, credential datasources.Credential, since time.Time) {
+	s.credentials.mu.Lock()
+	defer s.credentials.mu.Unlock()
+
+	if s.credentials.active == nil {
+		s.credentials.active = map[string]activeCredential{}
+	}
+	s.credentials.active[credentialKey(ds)] = activeCredential{credential: credential, since: since, dsUpdated: ds.Updated}
+	s.logger.Warn("Switched the datasource credential after an authentication failure", "uid", ds.UID, "orgId", ds.OrgID, "credential", credential)
+}
+
+func (s *Service) RecordCredentialUsage(ctx context.Context, ds *datasources.DataSource, credential datasources.Credential) {
+	now := time.Now()
+	key := credentialKey(ds) + "/" + string(credential)
+
+	s.credentials.mu.Lock()
+	if last, ok := s.credentials.recorded[key]; ok && now.Sub(last) < credentialUsageInterval {
+		s.credentials.mu.Unlock()
+		return
+	}
+	if s.credentials.recorded == nil {
+		s.credentials.recorded = map[string]time.Time{}
+	}
+	s.credentials.recorded[key] = now
+	s.credentials.mu.Unlock()
+
+	if err := s.SQLStore.SetCredentialLastUsed(ctx, ds.OrgID, ds.UID, credential, now); err != nil {
+		s.logger.Warn("Failed to record the last use of the datasource credential", "uid", ds.UID, "credential", credential, "error", err)
+	}
+}
+
+// GetCredentialStatus returns the active credential of the datasource, its secrets having a secondary value and
+// when each credential was last used.
+func (s *Service) GetCredentialStatus(ctx context.Context, ds *datasources.DataSource) (*datasources.CredentialStatus, error) {
+	decrypted, err := s.DecryptedValues(ctx, ds)
+	if err != nil {
+		return nil, err
+	}
+	usage, err := s.SQLStore.GetCredentialUsage(ctx, ds.OrgID, ds.UID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &datasources.CredentialStatus{SecondarySecrets: []string{}, Usage: usage}
+	status.ActiveCredential, _ = s.ActiveCredential(ds)
+	for k := range decrypted {
+		if key, ok := strings.CutSuffix(k, datasources.SecondarySecretSuffix); ok {
+			status.SecondarySecrets = append(status.SecondarySecrets, key)
+		}
+	}
+	sort.Strings(status.SecondarySecrets)
+	return status, nil
+}
+
+// PromoteSecondarySecrets replaces the primary secrets of the datasource by its secondary secrets once the
+// secondary credential is in use, the former primary secrets are removed.
+func (s *Service) PromoteSecondarySecrets(ctx context.Context, cmd *datasources.PromoteSecondarySecretsCommand) (*datasources.DataSource, error) {
+	ds, err := s.SQLStore.GetDataSource(ctx, &datasources.GetDataSourceQuery{UID: cmd.UID, OrgID: cmd.OrgID})
+	if err != nil {
+		return nil, err
+	}
+	decrypted, err := s.DecryptedValues(ctx, ds)
+	if err != nil {
+		return nil, err
+	}
+	if !datasources.HasSecondarySecrets(decrypted) {
+		return nil, datasources.ErrDataSourceNoSecondarySecrets.Errorf("datasource %s has no secondary secrets", ds.UID)
+	}
+
+	var dataSource *datasources.DataSource
+	err = s.db.InTransaction(ctx, func(ctx context.Context) error {
+		dataSource, err = s.UpdateDataSource(ctx, &datasources.UpdateDataSourceCommand{
+			ID:                      ds.ID,
+			UID:                     ds.UID,
+			OrgID:                   ds.OrgID,
+			Name:                    ds.Name,
+			Type:                    ds.Type,
+			Access:                  ds.Access,
+			URL:                     ds.URL,
+			User:                    ds.User,
+			Database:                ds.Database,
+			BasicAuth:               ds.BasicAuth,
+			BasicAuthUser:           ds.BasicAuthUser,
+			WithCredentials:         ds.WithCredentials,
+			IsDefault:               ds.IsDefault,
+			JsonData:                ds.JsonData,
+			ReadOnly:                ds.ReadOnly,
+			Version:                 ds.Version,
+			SecureJsonData:          datasources.CredentialSecrets(decrypted, datasources.CredentialSecondary),
+			IgnoreOldSecureJsonData: true,
+		})
+		if err != nil {
+			return err
+		}
+		return s.SQLStore.PromoteCredentialUsage(ctx, ds.OrgID, ds.UID)
+	})
+	return dataSource, err
+}
+
+// credentialFailoverTransport sends the requests with the active credential of the datasource, and again with the
+// other credential when the active one fails to authenticate
+type credentialFailoverTransport struct {
+	ds          *datasources.DataSource
+	credentials datasources.CredentialService
+	transports  map[datasources.Credential]http.RoundTripper
+}
+
+func (t *credentialFailoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// the copy is made before sending the request since the authentication middlewares set the headers of the
+	// request they send, and leave the headers already set unchanged
+	retry, canRetry := replayRequest(req)
+
+	active, _ := t.credentials.ActiveCredential(t.ds)
+	res, err := t.transports[active].RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if !isAuthenticationFailureStatus(res.StatusCode) {
+		t.credentials.RecordCredentialUsage(req.Context(), t.ds, active)
+		return res, nil
+	}
+	if !canRetry {
+		return res, nil
+	}
+
+	other := active.Other()
+	since := time.Now()
+	otherRes, err := t.transports[other].RoundTrip(retry)
+	if err != nil {
+		return res, nil
+	}
+	if isAuthenticationFailureStatus(otherRes.StatusCode) {
+		discardBody(otherRes)
+		return res, nil
+	}
+
+	discardBody(res)
+	t.credentials.UseCredential(t.ds, other, since)
+	t.credentials.RecordCredentialUsage(req.Context(), t.ds, other)
+	return otherRes, nil
+}
+
+func isAuthenticationFailureStatus(status int) bool {
+	return status == http.StatusUnauthorized || status == http.StatusForbidden
+}
+
+// replayRequest returns a copy of the request to send it again, false if its body cannot be read again
+func replayRequest(req *http.Request) (*http.Request, bool) {
+	retry := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return retry, true
+	}
+	if req.GetBody == nil {
+		return nil, false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	retry.Body = body
+	return retry, true
+}
+
+func discardBody(res *http.Response) {
+	if res.Body == nil {
+		return
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+	_ = res.Body.Close()
+}
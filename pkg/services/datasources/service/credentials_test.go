@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	sdkhttpclient "github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/httpclient"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	acmock "github.com/grafana/grafana/pkg/services/accesscontrol/mock"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/quota/quotatest"
+	"github.com/grafana/grafana/pkg/services/secrets/fakes"
+	secretskvs "github.com/grafana/grafana/pkg/services/secrets/kvstore"
+	secretsmng "github.com/grafana/grafana/pkg/services/secrets/manager"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func setupCredentialsTest(t *testing.T, password string) (*Service, *datasources.DataSource, *httptest.Server) {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, p, ok := r.BasicAuth(); !ok || p != password {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+
+	sqlStore := db.InitTestDB(t)
+	secretsService := secretsmng.SetupTestService(t, fakes.NewFakeSecretsStore())
+	secretsStore := secretskvs.NewSQLSecretsKVStore(sqlStore, secretsService, log.New("test.logger"))
+	mockPermission := acmock.NewMockedPermissionsService()
+	mockPermission.On("SetPermissions", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]accesscontrol.ResourcePermission{}, nil)
+	dsService, err := ProvideService(sqlStore, secretsService, secretsStore, &setting.Cfg{}, featuremgmt.WithFeatures(), acmock.New(), mockPermission, quotatest.New(false, nil))
+	require.NoError(t, err)
+
+	ds, err := dsService.AddDataSource(context.Background(), &datasources.AddDataSourceCommand{
+		OrgID:         1,
+		Name:          "test",
+		Type:          "prometheus",
+		Access:        datasources.DS_ACCESS_PROXY,
+		URL:           srv.URL,
+		BasicAuth:     true,
+		BasicAuthUser: "admin",
+		SecureJsonData: map[string]string{
+			"basicAuthPassword":           "old",
+			"basicAuthPassword.secondary": "new",
+		},
+	})
+	require.NoError(t, err)
+
+	return dsService, ds, srv
+}
+
+func TestService_CredentialFailover(t *testing.T) {
+	provider := httpclient.NewProvider(sdkhttpclient.ProviderOptions{})
+
+	t.Run("should send the requests with the primary credential while it authenticates", func(t *testing.T) {
+		dsService, ds, srv := setupCredentialsTest(t, "old")
+
+		rt, err := dsService.GetHTTPTransport(context.Background(), ds, provider)
+		require.NoError(t, err)
+		res := sendRequest(t, rt, srv.URL)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		active, _ := dsService.ActiveCredential(ds)
+		require.Equal(t, datasources.CredentialPrimary, active)
+
+		status, err := dsService.GetCredentialStatus(context.Background(), ds)
+		require.NoError(t, err)
+		require.Equal(t, []string{"basicAuthPassword"}, status.SecondarySecrets)
+		require.Len(t, status.Usage, 1)
+		require.Equal(t, datasources.CredentialPrimary, status.Usage[0].Credential)
+	})
+
+	t.Run("should switch to the secondary credential when the primary one fails to authenticate", func(t *testing.T) {
+		dsService, ds, srv := setupCredentialsTest(t, "new")
+
+		rt, err := dsService.GetHTTPTransport(context.Background(), ds, provider)
+		require.NoError(t, err)
+		res := sendRequest(t, rt, srv.URL)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		active, _ := dsService.ActiveCredential(ds)
+		require.Equal(t, datasources.CredentialSecondary, active)
+
+		status, err := dsService.GetCredentialStatus(context.Background(), ds)
+		require.NoError(t, err)
+		require.Equal(t, datasources.CredentialSecondary, status.ActiveCredential)
+		require.Len(t, status.Usage, 1)
+		require.Equal(t, datasources.CredentialSecondary, status.Usage[0].Credential)
+	})
+
+	t.Run("should return the response of the active credential when both fail to authenticate", func(t *testing.T) {
+		dsService, ds, srv := setupCredentialsTest(t, "other")
+
+		rt, err := dsService.GetHTTPTransport(context.Background(), ds, provider)
+		require.NoError(t, err)
+		res := sendRequest(t, rt, srv.URL)
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+		active, _ := dsService.ActiveCredential(ds)
+		require.Equal(t, datasources.CredentialPrimary, active)
+	})
+
+	t.Run("should use the primary credential again once the datasource is updated", func(t *testing.T) {
+		dsService, ds, _ := setupCredentialsTest(t, "new")
+
+		dsService.UseCredential(ds, datasources.CredentialSecondary, ds.Updated)
+		updated := *ds
+		updated.Updated = ds.Updated.Add(1)
+		active, since := dsService.ActiveCredential(&updated)
+		require.Equal(t, datasources.CredentialPrimary, active)
+		require.Equal(t, updated.Updated, since)
+	})
+}
+
+func TestService_PromoteSecondarySecrets(t *testing.T) {
+	t.Run("should replace the primary secrets by the secondary secrets", func(t *testing.T) {
+		dsService, ds, srv := setupCredentialsTest(t, "new")
+
+		rt, err := dsService.GetHTTPTransport(context.Background(), ds, httpclient.NewProvider(sdkhttpclient.ProviderOptions{}))
+		require.NoError(t, err)
+		sendRequest(t, rt, srv.URL)
+
+		promoted, err := dsService.PromoteSecondarySecrets(context.Background(), &datasources.PromoteSecondarySecretsCommand{OrgID: ds.OrgID, UID: ds.UID})
+		require.NoError(t, err)
+		require.Equal(t, ds.Version+1, promoted.Version)
+
+		decrypted, err := dsService.DecryptedValues(context.Background(), promoted)
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"basicAuthPassword": "new"}, decrypted)
+
+		status, err := dsService.GetCredentialStatus(context.Background(), promoted)
+		require.NoError(t, err)
+		require.Equal(t, datasources.CredentialPrimary, status.ActiveCredential)
+		require.Empty(t, status.SecondarySecrets)
+		require.Len(t, status.Usage, 1)
+		require.Equal(t, datasources.CredentialPrimary, status.Usage[0].Credential)
+	})
+
+	t.Run("should fail when the datasource has no secondary secrets", func(t *testing.T) {
+		dsService, ds, _ := setupCredentialsTest(t, "old")
+
+		_, err := dsService.PromoteSecondarySecrets(context.Background(), &datasources.PromoteSecondarySecretsCommand{OrgID: ds.OrgID, UID: ds.UID})
+		require.NoError(t, err)
+		_, err = dsService.PromoteSecondarySecrets(context.Background(), &datasources.PromoteSecondarySecretsCommand{OrgID: ds.OrgID, UID: ds.UID})
+		require.ErrorIs(t, err, datasources.ErrDataSourceNoSecondarySecrets)
+	})
+}
+
+func sendRequest(t *testing.T, rt http.RoundTripper, url string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader("query=up"))
+	require.NoError(t, err)
+	res, err := rt.RoundTrip(req)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	return res
+}
@@ -44,7 +44,8 @@ type Service struct {
 	logger             log.Logger
 	db                 db.DB
 
-	ptc proxyTransportCache
+	ptc         proxyTransportCache
+	credentials credentialTracker
 }
 
 type proxyTransportCache struct {
@@ -63,7 +64,7 @@ func ProvideService(
 	quotaService quota.Service,
 ) (*Service, error) {
 	dslogger := log.New("datasources")
-	store := &SqlStore{db: db, logger: dslogger}
+	store := &SqlStore{db: db, logger: dslogger, features: features}
 	s := &Service{
 		SQLStore:       store,
 		SecretsStore:   secretsStore,
@@ -322,11 +323,12 @@ func (s *Service) GetHTTPTransport(ctx context.Context, ds *datasources.DataSour
 		return t.roundTripper, nil
 	}
 
-	opts, err := s.httpClientOptions(ctx, ds)
+	decrypted, err := s.DecryptedValues(ctx, ds)
 	if err != nil {
 		return nil, err
 	}
 
+	opts := s.credentialHTTPClientOptions(ds, datasources.CredentialSecrets(decrypted, datasources.CredentialPrimary))
 	opts.Middlewares = append(opts.Middlewares, customMiddlewares...)
 
 	rt, err := provider.GetTransport(*opts)
@@ -334,6 +336,24 @@ func (s *Service) GetHTTPTransport(ctx context.Context, ds *datasources.DataSour
 		return nil, err
 	}
 
+	// while the secrets are rotated, the requests failing to authenticate are sent again with the other credential
+	if datasources.HasSecondarySecrets(decrypted) {
+		secondaryOpts := s.credentialHTTPClientOptions(ds, datasources.CredentialSecrets(decrypted, datasources.CredentialSecondary))
+		secondaryOpts.Middlewares = append(secondaryOpts.Middlewares, customMiddlewares...)
+		secondaryRT, err := provider.GetTransport(*secondaryOpts)
+		if err != nil {
+			return nil, err
+		}
+		rt = &credentialFailoverTransport{
+			ds:          ds,
+			credentials: s,
+			transports: map[datasources.Credential]http.RoundTripper{
+				datasources.CredentialPrimary:   rt,
+				datasources.CredentialSecondary: secondaryRT,
+			},
+		}
+	}
+
 	s.ptc.cache[ds.ID] = cachedRoundTripper{
 		roundTripper: rt,
 		updated:      ds.Updated,
@@ -414,10 +434,16 @@ func (s *Service) DecryptedPassword(ctx context.Context, ds *datasources.DataSou
 }
 
 func (s *Service) httpClientOptions(ctx context.Context, ds *datasources.DataSource) (*sdkhttpclient.Options, error) {
-	tlsOptions, err := s.dsTLSOptions(ctx, ds)
+	decrypted, err := s.DecryptedValues(ctx, ds)
 	if err != nil {
 		return nil, err
 	}
+	return s.credentialHTTPClientOptions(ds, datasources.CredentialSecrets(decrypted, datasources.CredentialPrimary)), nil
+}
+
+// credentialHTTPClientOptions returns the options of the HTTP client of the datasource authenticating with the decrypted secrets
+func (s *Service) credentialHTTPClientOptions(ds *datasources.DataSource, secrets map[string]string) *sdkhttpclient.Options {
+	tlsOptions := s.dsTLSOptions(ds, secrets)
 
 	timeouts := &sdkhttpclient.TimeoutOptions{
 		Timeout:               s.getTimeout(ds),
@@ -431,18 +457,14 @@ func (s *Service) httpClientOptions(ctx context.Context, ds *datasources.DataSou
 		IdleConnTimeout:       sdkhttpclient.DefaultTimeoutOptions.IdleConnTimeout,
 	}
 
-	decryptedValues, err := s.DecryptedValues(ctx, ds)
-	if err != nil {
-		return nil, err
-	}
-
 	opts := &sdkhttpclient.Options{
 		Timeouts: timeouts,
-		Headers:  s.getCustomHeaders(ds.JsonData, decryptedValues),
+		Headers:  s.getCustomHeaders(ds.JsonData, secrets),
 		Labels: map[string]string{
-			"datasource_type": ds.Type,
-			"datasource_name": ds.Name,
-			"datasource_uid":  ds.UID,
+			"datasource_type":   ds.Type,
+			"datasource_name":   ds.Name,
+			"datasource_uid":    ds.UID,
+			"datasource_org_id": strconv.FormatInt(ds.OrgID, 10),
 		},
 		TLS: &tlsOptions,
 	}
@@ -457,24 +479,14 @@ func (s *Service) httpClientOptions(ctx context.Context, ds *datasources.DataSou
 		opts.CustomOptions["grafanaData"] = deepJsonDataCopy
 	}
 	if ds.BasicAuth {
-		password, err := s.DecryptedBasicAuthPassword(ctx, ds)
-		if err != nil {
-			return opts, err
-		}
-
 		opts.BasicAuth = &sdkhttpclient.BasicAuthOptions{
 			User:     ds.BasicAuthUser,
-			Password: password,
+			Password: secrets["basicAuthPassword"],
 		}
 	} else if ds.User != "" {
-		password, err := s.DecryptedPassword(ctx, ds)
-		if err != nil {
-			return opts, err
-		}
-
 		opts.BasicAuth = &sdkhttpclient.BasicAuthOptions{
 			User:     ds.User,
-			Password: password,
+			Password: secrets["password"],
 		}
 	}
 
@@ -487,7 +499,7 @@ func (s *Service) httpClientOptions(ctx context.Context, ds *datasources.DataSou
 			Timeouts: &sdkproxy.DefaultTimeoutOptions,
 		}
 
-		if val, exists, err := s.DecryptedValue(ctx, ds, "secureSocksProxyPassword"); err == nil && exists {
+		if val, exists := secrets["secureSocksProxyPassword"]; exists {
 			proxyOpts.Auth.Password = val
 		}
 		if val, err := ds.JsonData.Get("timeout").Float64(); err == nil {
@@ -510,27 +522,18 @@ func (s *Service) httpClientOptions(ctx context.Context, ds *datasources.DataSou
 			Profile:       ds.JsonData.Get("sigV4Profile").MustString(),
 		}
 
-		if val, exists, err := s.DecryptedValue(ctx, ds, "sigV4AccessKey"); err == nil {
-			if exists {
-				opts.SigV4.AccessKey = val
-			}
-		} else {
-			return opts, err
+		if val, exists := secrets["sigV4AccessKey"]; exists {
+			opts.SigV4.AccessKey = val
 		}
-
-		if val, exists, err := s.DecryptedValue(ctx, ds, "sigV4SecretKey"); err == nil {
-			if exists {
-				opts.SigV4.SecretKey = val
-			}
-		} else {
-			return opts, err
+		if val, exists := secrets["sigV4SecretKey"]; exists {
+			opts.SigV4.SecretKey = val
 		}
 	}
 
-	return opts, nil
+	return opts
 }
 
-func (s *Service) dsTLSOptions(ctx context.Context, ds *datasources.DataSource) (sdkhttpclient.TLSOptions, error) {
+func (s *Service) dsTLSOptions(ds *datasources.DataSource, secrets map[string]string) sdkhttpclient.TLSOptions {
 	var tlsSkipVerify, tlsClientAuth, tlsAuthWithCACert bool
 	var serverName string
 
@@ -548,35 +551,22 @@ func (s *Service) dsTLSOptions(ctx context.Context, ds *datasources.DataSource)
 
 	if tlsClientAuth || tlsAuthWithCACert {
 		if tlsAuthWithCACert {
-			if val, exists, err := s.DecryptedValue(ctx, ds, "tlsCACert"); err == nil {
-				if exists && len(val) > 0 {
-					opts.CACertificate = val
-				}
-			} else {
-				return opts, err
+			if val := secrets["tlsCACert"]; len(val) > 0 {
+				opts.CACertificate = val
 			}
 		}
 
 		if tlsClientAuth {
-			if val, exists, err := s.DecryptedValue(ctx, ds, "tlsClientCert"); err == nil {
-				fmt.Print("\n\n\n\n", val, exists, err, "\n\n\n\n")
-				if exists && len(val) > 0 {
-					opts.ClientCertificate = val
-				}
-			} else {
-				return opts, err
+			if val := secrets["tlsClientCert"]; len(val) > 0 {
+				opts.ClientCertificate = val
 			}
-			if val, exists, err := s.DecryptedValue(ctx, ds, "tlsClientKey"); err == nil {
-				if exists && len(val) > 0 {
-					opts.ClientKey = val
-				}
-			} else {
-				return opts, err
+			if val := secrets["tlsClientKey"]; len(val) > 0 {
+				opts.ClientKey = val
 			}
 		}
 	}
 
-	return opts, nil
+	return opts
 }
 
 func (s *Service) getTimeout(ds *datasources.DataSource) time.Duration {
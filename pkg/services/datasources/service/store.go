@@ -16,8 +16,10 @@ import (
 	"github.com/grafana/grafana/pkg/infra/metrics"
 	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/quota"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/store"
 	"github.com/grafana/grafana/pkg/util"
 )
 
@@ -33,11 +35,17 @@ type Store interface {
 	GetAllDataSources(ctx context.Context, query *datasources.GetAllDataSourcesQuery) (res []*datasources.DataSource, err error)
 
 	Count(context.Context, *quota.ScopeParameters) (*quota.Map, error)
+
+	GetCredentialUsage(ctx context.Context, orgID int64, uid string) ([]*datasources.CredentialUsage, error)
+	SetCredentialLastUsed(ctx context.Context, orgID int64, uid string, credential datasources.Credential, lastUsed time.Time) error
+	// PromoteCredentialUsage makes the usage of the secondary credential the usage of the primary credential
+	PromoteCredentialUsage(ctx context.Context, orgID int64, uid string) error
 }
 
 type SqlStore struct {
-	db     db.DB
-	logger log.Logger
+	db       db.DB
+	logger   log.Logger
+	features featuremgmt.FeatureToggles
 }
 
 func CreateStore(db db.DB, logger log.Logger) *SqlStore {
@@ -150,11 +158,21 @@ func (ss *SqlStore) DeleteDataSource(ctx context.Context, cmd *datasources.Delet
 
 			cmd.DeletedDatasourcesCount, _ = result.RowsAffected()
 
+			if _, err := sess.Exec("DELETE FROM data_source_credential_usage WHERE org_id=? AND data_source_uid=?", ds.OrgID, ds.UID); err != nil {
+				return err
+			}
+
 			// Remove associated AccessControl permissions
 			if _, errDeletingPerms := sess.Exec("DELETE FROM permission WHERE scope=?",
 				ac.Scope(datasources.ScopeProvider.GetResourceScope(ds.UID))); errDeletingPerms != nil {
 				return errDeletingPerms
 			}
+
+			if store.EntityEventsEnabled(ss.features) {
+				if _, err := sess.Insert(store.NewDatabaseEntityEvent(ds.UID, ds.OrgID, store.EntityTypeDatasource, store.EntityEventTypeDelete)); err != nil {
+					return err
+				}
+			}
 		}
 
 		if cmd.UpdateSecretFn != nil {
@@ -279,6 +297,12 @@ func (ss *SqlStore) AddDataSource(ctx context.Context, cmd *datasources.AddDataS
 			return err
 		}
 
+		if store.EntityEventsEnabled(ss.features) {
+			if _, err := sess.Insert(store.NewDatabaseEntityEvent(ds.UID, ds.OrgID, store.EntityTypeDatasource, store.EntityEventTypeCreate)); err != nil {
+				return err
+			}
+		}
+
 		if cmd.UpdateSecretFn != nil {
 			if err := cmd.UpdateSecretFn(); err != nil {
 				// ss.logger.Error("Failed to update datasource secrets -- rolling back update", "name", cmd.Name, "type", cmd.Type, "orgId", cmd.OrgID)
@@ -372,6 +396,12 @@ func (ss *SqlStore) UpdateDataSource(ctx context.Context, cmd *datasources.Updat
 
 		err = updateIsDefaultFlag(ds, sess)
 
+		if store.EntityEventsEnabled(ss.features) {
+			if _, err := sess.Insert(store.NewDatabaseEntityEvent(ds.UID, ds.OrgID, store.EntityTypeDatasource, store.EntityEventTypeUpdate)); err != nil {
+				return err
+			}
+		}
+
 		if cmd.UpdateSecretFn != nil {
 			if err := cmd.UpdateSecretFn(); err != nil {
 				ss.logger.Error("Failed to update datasource secrets -- rolling back update", "UID", cmd.UID, "name", cmd.Name, "type", cmd.Type, "orgId", cmd.OrgID)
@@ -387,6 +417,57 @@ func (ss *SqlStore) UpdateDataSource(ctx context.Context, cmd *datasources.Updat
 	})
 }
 
+type credentialUsage struct {
+	ID            int64  `xorm:"pk autoincr 'id'"`
+	OrgID         int64  `xorm:"org_id"`
+	DataSourceUID string `xorm:"data_source_uid"`
+	Credential    string `xorm:"credential"`
+	LastUsed      int64  `xorm:"last_used"`
+}
+
+func (ss *SqlStore) GetCredentialUsage(ctx context.Context, orgID int64, uid string) ([]*datasources.CredentialUsage, error) {
+	var rows []*credentialUsage
+	err := ss.db.WithDbSession(ctx, func(sess *db.Session) error {
+		return sess.Table("data_source_credential_usage").Where("org_id=? AND data_source_uid=?", orgID, uid).Asc("credential").Find(&rows)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	usage := make([]*datasources.CredentialUsage, 0, len(rows))
+	for _, r := range rows {
+		usage = append(usage, &datasources.CredentialUsage{
+			Credential: datasources.Credential(r.Credential),
+			LastUsed:   time.UnixMilli(r.LastUsed),
+		})
+	}
+	return usage, nil
+}
+
+func (ss *SqlStore) SetCredentialLastUsed(ctx context.Context, orgID int64, uid string, credential datasources.Credential, lastUsed time.Time) error {
+	return ss.db.WithDbSession(ctx, func(sess *db.Session) error {
+		upsertSQL := ss.db.GetDialect().UpsertSQL(
+			"data_source_credential_usage",
+			[]string{"org_id", "data_source_uid", "credential"},
+			[]string{"org_id", "data_source_uid", "credential", "last_used"},
+		)
+		_, err := sess.SQL(upsertSQL, orgID, uid, string(credential), lastUsed.UnixMilli()).Query()
+		return err
+	})
+}
+
+func (ss *SqlStore) PromoteCredentialUsage(ctx context.Context, orgID int64, uid string) error {
+	return ss.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		if _, err := sess.Exec("DELETE FROM data_source_credential_usage WHERE org_id=? AND data_source_uid=? AND credential=?",
+			orgID, uid, string(datasources.CredentialPrimary)); err != nil {
+			return err
+		}
+		_, err := sess.Exec("UPDATE data_source_credential_usage SET credential=? WHERE org_id=? AND data_source_uid=? AND credential=?",
+			string(datasources.CredentialPrimary), orgID, uid, string(datasources.CredentialSecondary))
+		return err
+	})
+}
+
 func generateNewDatasourceUid(sess *db.Session, orgId int64) (string, error) {
 	for i := 0; i < 3; i++ {
 		uid := generateNewUid()
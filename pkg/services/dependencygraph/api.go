@@ -0,0 +1,125 @@
+package dependencygraph
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/middleware"
+	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+func (s *DependencyGraphService) registerAPIEndpoints() {
+	dsUIDScope := datasources.ScopeProvider.GetResourceScopeUID(ac.Parameter(":uid"))
+	authorize := ac.Middleware(s.AccessControl)
+
+	s.RouteRegister.Get("/api/dashboards/uid/:uid/dependencies", middleware.ReqSignedIn, authorize(ac.EvalPermission(dashboards.ActionDashboardsRead)), routing.Wrap(s.getDashboardDependenciesHandler))
+	s.RouteRegister.Get("/api/datasources/uid/:uid/dependents", middleware.ReqSignedIn, authorize(ac.EvalPermission(datasources.ActionRead, dsUIDScope)), routing.Wrap(s.getDatasourceDependentsHandler))
+	s.RouteRegister.Get("/api/library-elements/:uid/dependents", middleware.ReqSignedIn, routing.Wrap(s.getLibraryPanelDependentsHandler))
+}
+
+// swagger:route GET /dashboards/uid/{uid}/dependencies dashboards getDashboardDependencies
+//
+// Get the datasources, library panels, linked dashboards and plugins a dashboard depends on.
+//
+// Responses:
+// 200: getDashboardDependenciesResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (s *DependencyGraphService) getDashboardDependenciesHandler(c *contextmodel.ReqContext) response.Response {
+	deps, err := s.GetDashboardDependencies(c.Req.Context(), GetDashboardDependenciesQuery{
+		OrgID: c.OrgID,
+		UID:   web.Params(c.Req)[":uid"],
+	})
+	if err != nil {
+		if errors.Is(err, ErrDashboardNotFound) {
+			return response.Error(http.StatusNotFound, "Dashboard not found", err)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to get dashboard dependencies", err)
+	}
+
+	canRead := s.readFilter(c.Req.Context(), c.OrgID, c.SignedInUser)
+	if !canRead(KindDashboard, deps.Dashboard.UID, deps.Dashboard.FolderUID) {
+		return response.Error(http.StatusForbidden, "Access denied to this dashboard", nil)
+	}
+	return response.JSON(http.StatusOK, deps)
+}
+
+// swagger:route GET /datasources/uid/{uid}/dependents datasources getDatasourceDependents
+//
+// Get the dashboards, library panels and alert rules using a datasource.
+//
+// Only entities the user can read are returned.
+//
+// Responses:
+// 200: getDependentsResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (s *DependencyGraphService) getDatasourceDependentsHandler(c *contextmodel.ReqContext) response.Response {
+	dependents, err := s.GetDatasourceDependents(c.Req.Context(), GetDependentsQuery{
+		OrgID:        c.OrgID,
+		UID:          web.Params(c.Req)[":uid"],
+		SignedInUser: c.SignedInUser,
+	})
+	if err != nil {
+		if errors.Is(err, ErrDatasourceNotFound) {
+			return response.Error(http.StatusNotFound, "Data source not found", err)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to get data source dependents", err)
+	}
+	return response.JSON(http.StatusOK, dependents)
+}
+
+// swagger:route GET /library-elements/{uid}/dependents library_elements getLibraryPanelDependents
+//
+// Get the dashboards using a library panel.
+//
+// Only dashboards the user can read are returned.
+//
+// Responses:
+// 200: getDependentsResponse
+// 401: unauthorisedError
+// 404: notFoundError
+// 500: internalServerError
+func (s *DependencyGraphService) getLibraryPanelDependentsHandler(c *contextmodel.ReqContext) response.Response {
+	dependents, err := s.GetLibraryPanelDependents(c.Req.Context(), GetDependentsQuery{
+		OrgID:        c.OrgID,
+		UID:          web.Params(c.Req)[":uid"],
+		SignedInUser: c.SignedInUser,
+	})
+	if err != nil {
+		if errors.Is(err, ErrLibraryPanelNotFound) {
+			return response.Error(http.StatusNotFound, "Library panel not found", err)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to get library panel dependents", err)
+	}
+	return response.JSON(http.StatusOK, dependents)
+}
+
+// swagger:parameters getDashboardDependencies getDatasourceDependents getLibraryPanelDependents
+type GetDependenciesParams struct {
+	// in:path
+	// required:true
+	UID string `json:"uid"`
+}
+
+// swagger:response getDashboardDependenciesResponse
+type GetDashboardDependenciesResponse struct {
+	// in: body
+	Body DashboardDependencies `json:"body"`
+}
+
+// swagger:response getDependentsResponse
+type GetDependentsResponse struct {
+	// in: body
+	Body Dependents `json:"body"`
+}
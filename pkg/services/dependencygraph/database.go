@@ -0,0 +1,282 @@
+package dependencygraph
+
+import (
+	"context"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/libraryelements/model"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// dashboardBatchSize is the number of dashboards loaded at once when scanning an organization.
+const dashboardBatchSize = 200
+
+type dashboardRow struct {
+	ID        int64  `xorm:"id"`
+	UID       string `xorm:"uid"`
+	Title     string `xorm:"title"`
+	Data      []byte `xorm:"data"`
+	FolderUID string `xorm:"folder_uid"`
+}
+
+type datasourceRow struct {
+	UID       string `xorm:"uid"`
+	Name      string `xorm:"name"`
+	Type      string `xorm:"type"`
+	IsDefault bool   `xorm:"is_default"`
+}
+
+type libraryPanelRow struct {
+	ID        int64  `xorm:"id"`
+	UID       string `xorm:"uid"`
+	Name      string `xorm:"name"`
+	Type      string `xorm:"type"`
+	Model     []byte `xorm:"model"`
+	FolderUID string `xorm:"folder_uid"`
+}
+
+const dashboardSelect = "SELECT d.id, d.uid, d.title, d.data, f.uid AS folder_uid" +
+	" FROM dashboard AS d LEFT JOIN dashboard AS f ON d.folder_id = f.id"
+
+const libraryPanelSelect = "SELECT le.id, le.uid, le.name, le.type, le.model, f.uid AS folder_uid" +
+	" FROM library_element AS le LEFT JOIN dashboard AS f ON le.folder_id = f.id"
+
+func (s *DependencyGraphService) getDashboard(ctx context.Context, orgID int64, uid string) (*dashboardRow, error) {
+	rows := make([]*dashboardRow, 0, 1)
+	err := s.SQLStore.WithDbSession(ctx, func(sess *db.Session) error {
+		rawSQL := dashboardSelect + " WHERE d.org_id = ? AND d.uid = ? AND d.is_folder = " + s.SQLStore.GetDialect().BooleanStr(false)
+		return sess.SQL(rawSQL, orgID, uid).Find(&rows)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrDashboardNotFound
+	}
+	return rows[0], nil
+}
+
+// forEachDashboard calls fn for every dashboard in the organization. When contains is
+// not empty, only dashboards whose model contains one of the given strings are visited.
+func (s *DependencyGraphService) forEachDashboard(ctx context.Context, orgID int64, contains []string, fn func(dash *dashboardRow)) error {
+	dialect := s.SQLStore.GetDialect()
+	var lastID int64
+	for {
+		rows := make([]*dashboardRow, 0, dashboardBatchSize)
+		err := s.SQLStore.WithDbSession(ctx, func(sess *db.Session) error {
+			rawSQL := dashboardSelect + " WHERE d.org_id = ? AND d.is_folder = " + dialect.BooleanStr(false) + " AND d.id > ?"
+			args := []any{orgID, lastID}
+			if len(contains) > 0 {
+				rawSQL += " AND ("
+				for i, str := range contains {
+					if i > 0 {
+						rawSQL += " OR "
+					}
+					rawSQL += "d.data " + dialect.LikeStr() + " ?"
+					args = append(args, "%"+str+"%")
+				}
+				rawSQL += ")"
+			}
+			rawSQL += " ORDER BY d.id ASC" + dialect.Limit(dashboardBatchSize)
+			return sess.SQL(rawSQL, args...).Find(&rows)
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			fn(row)
+		}
+		if len(rows) < dashboardBatchSize {
+			return nil
+		}
+		lastID = rows[len(rows)-1].ID
+	}
+}
+
+func (s *DependencyGraphService) getDatasource(ctx context.Context, orgID int64, uid string) (*datasourceRow, error) {
+	rows := make([]*datasourceRow, 0, 1)
+	err := s.SQLStore.WithDbSession(ctx, func(sess *db.Session) error {
+		return sess.Table("data_source").Where("org_id = ? AND uid = ?", orgID, uid).
+			Cols("uid", "name", "type", "is_default").Find(&rows)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrDatasourceNotFound
+	}
+	return rows[0], nil
+}
+
+func (s *DependencyGraphService) getLibraryPanel(ctx context.Context, orgID int64, uid string) (*libraryPanelRow, error) {
+	rows := make([]*libraryPanelRow, 0, 1)
+	err := s.SQLStore.WithDbSession(ctx, func(sess *db.Session) error {
+		rawSQL := libraryPanelSelect + " WHERE le.org_id = ? AND le.kind = ? AND le.uid = ?"
+		return sess.SQL(rawSQL, orgID, int64(model.PanelElement), uid).Find(&rows)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrLibraryPanelNotFound
+	}
+	return rows[0], nil
+}
+
+func (s *DependencyGraphService) getLibraryPanels(ctx context.Context, orgID int64) ([]*libraryPanelRow, error) {
+	rows := make([]*libraryPanelRow, 0)
+	err := s.SQLStore.WithDbSession(ctx, func(sess *db.Session) error {
+		rawSQL := libraryPanelSelect + " WHERE le.org_id = ? AND le.kind = ?"
+		return sess.SQL(rawSQL, orgID, int64(model.PanelElement)).Find(&rows)
+	})
+	return rows, err
+}
+
+func (s *DependencyGraphService) getConnectedDashboards(ctx context.Context, orgID int64, elementID int64) ([]*dashboardRow, error) {
+	rows := make([]*dashboardRow, 0)
+	err := s.SQLStore.WithDbSession(ctx, func(sess *db.Session) error {
+		rawSQL := "SELECT d.id, d.uid, d.title, f.uid AS folder_uid" +
+			" FROM library_element_connection AS lec" +
+			" INNER JOIN dashboard AS d ON lec.connection_id = d.id" +
+			" LEFT JOIN dashboard AS f ON d.folder_id = f.id" +
+			" WHERE lec.element_id = ? AND d.org_id = ?"
+		return sess.SQL(rawSQL, elementID, orgID).Find(&rows)
+	})
+	return rows, err
+}
+
+func (s *DependencyGraphService) getAlertRulesByDatasource(ctx context.Context, orgID int64, dsUID string) ([]*ngmodels.AlertRule, error) {
+	rows := make([]*ngmodels.AlertRule, 0)
+	err := s.SQLStore.WithDbSession(ctx, func(sess *db.Session) error {
+		return sess.Table("alert_rule").
+			Where("org_id = ?", orgID).
+			Where("data "+s.SQLStore.GetDialect().LikeStr()+" ?", "%"+dsUID+"%").
+			Find(&rows)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the LIKE condition only narrows down the candidates, the queries are checked here
+	result := make([]*ngmodels.AlertRule, 0, len(rows))
+	for _, rule := range rows {
+		for _, q := range rule.Data {
+			if q.DatasourceUID == dsUID {
+				result = append(result, rule)
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+// resolveDatasources sets the name and type of the referenced datasources and flags the missing ones.
+func (s *DependencyGraphService) resolveDatasources(ctx context.Context, orgID int64, refs []Reference) error {
+	uids := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		uids = append(uids, ref.UID)
+	}
+	rows := make([]*datasourceRow, 0, len(uids))
+	if len(uids) > 0 {
+		err := s.SQLStore.WithDbSession(ctx, func(sess *db.Session) error {
+			return sess.Table("data_source").Where("org_id = ?", orgID).In("uid", uids).
+				Cols("uid", "name", "type", "is_default").Find(&rows)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	byUID := make(map[string]*datasourceRow, len(rows))
+	for _, row := range rows {
+		byUID[row.UID] = row
+	}
+	for i := range refs {
+		if row, ok := byUID[refs[i].UID]; ok {
+			refs[i].Name = row.Name
+			refs[i].Type = row.Type
+			continue
+		}
+		if builtInDatasourceUIDs[refs[i].UID] {
+			refs[i].Name = refs[i].UID
+			continue
+		}
+		refs[i].Missing = true
+	}
+	return nil
+}
+
+// resolveLibraryPanels sets the name of the referenced library panels and flags the missing ones.
+func (s *DependencyGraphService) resolveLibraryPanels(ctx context.Context, orgID int64, refs []Reference) error {
+	uids := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		uids = append(uids, ref.UID)
+	}
+	rows := make([]*libraryPanelRow, 0, len(uids))
+	if len(uids) > 0 {
+		err := s.SQLStore.WithDbSession(ctx, func(sess *db.Session) error {
+			return sess.Table("library_element").Where("org_id = ? AND kind = ?", orgID, int64(model.PanelElement)).
+				In("uid", uids).Cols("id", "uid", "name", "type").Find(&rows)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	byUID := make(map[string]*libraryPanelRow, len(rows))
+	for _, row := range rows {
+		byUID[row.UID] = row
+	}
+	for i := range refs {
+		row, ok := byUID[refs[i].UID]
+		if !ok {
+			refs[i].Missing = true
+			continue
+		}
+		refs[i].Name = row.Name
+		refs[i].Type = row.Type
+	}
+	return nil
+}
+
+// resolveDashboards sets the title and folder of the referenced dashboards and flags the missing ones.
+func (s *DependencyGraphService) resolveDashboards(ctx context.Context, orgID int64, refs []Reference) error {
+	uids := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		uids = append(uids, ref.UID)
+	}
+	rows := make([]*dashboardRow, 0, len(uids))
+	if len(uids) > 0 {
+		err := s.SQLStore.WithDbSession(ctx, func(sess *db.Session) error {
+			rawSQL := "SELECT d.id, d.uid, d.title, f.uid AS folder_uid" +
+				" FROM dashboard AS d LEFT JOIN dashboard AS f ON d.folder_id = f.id" +
+				" WHERE d.org_id = ? AND d.is_folder = " + s.SQLStore.GetDialect().BooleanStr(false) +
+				" AND d.uid IN (?" + strings.Repeat(",?", len(uids)-1) + ")"
+			args := []any{orgID}
+			for _, uid := range uids {
+				args = append(args, uid)
+			}
+			return sess.SQL(rawSQL, args...).Find(&rows)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	byUID := make(map[string]*dashboardRow, len(rows))
+	for _, row := range rows {
+		byUID[row.UID] = row
+	}
+	for i := range refs {
+		row, ok := byUID[refs[i].UID]
+		if !ok {
+			refs[i].Missing = true
+			continue
+		}
+		refs[i].Name = row.Title
+		refs[i].FolderUID = row.FolderUID
+	}
+	return nil
+}
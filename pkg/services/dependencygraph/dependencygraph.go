@@ -0,0 +1,292 @@
+package dependencygraph
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/folder"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/store/entity"
+	kdash "github.com/grafana/grafana/pkg/services/store/kind/dashboard"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+// builtInDatasourceUIDs are datasources which are never stored in the data_source table.
+var builtInDatasourceUIDs = map[string]bool{
+	"grafana":         true,
+	"-- Grafana --":   true,
+	"-- Mixed --":     true,
+	"-- Dashboard --": true,
+	"__expr__":        true,
+}
+
+func ProvideService(sqlStore db.DB, routeRegister routing.RouteRegister, folderService folder.Service, ac accesscontrol.AccessControl) *DependencyGraphService {
+	s := &DependencyGraphService{
+		SQLStore:      sqlStore,
+		RouteRegister: routeRegister,
+		AccessControl: ac,
+		folderService: folderService,
+		log:           log.New("dependencygraph"),
+	}
+	s.registerAPIEndpoints()
+	return s
+}
+
+// Service resolves what dashboards depend on and, inversely, what depends on
+// datasources and library panels.
+type Service interface {
+	GetDashboardDependencies(ctx context.Context, query GetDashboardDependenciesQuery) (*DashboardDependencies, error)
+	GetDatasourceDependents(ctx context.Context, query GetDependentsQuery) (*Dependents, error)
+	GetLibraryPanelDependents(ctx context.Context, query GetDependentsQuery) (*Dependents, error)
+}
+
+type DependencyGraphService struct {
+	SQLStore      db.DB
+	RouteRegister routing.RouteRegister
+	AccessControl accesscontrol.AccessControl
+	folderService folder.Service
+	log           log.Logger
+}
+
+var _ Service = (*DependencyGraphService)(nil)
+
+// GetDashboardDependencies returns the datasources, library panels, linked dashboards and plugins
+// referenced by a dashboard. References to entities which do not exist are flagged as missing.
+func (s *DependencyGraphService) GetDashboardDependencies(ctx context.Context, query GetDashboardDependenciesQuery) (*DashboardDependencies, error) {
+	dash, err := s.getDashboard(ctx, query.OrgID, query.UID)
+	if err != nil {
+		return nil, err
+	}
+
+	lookup, err := kdash.LoadDatasourceLookup(ctx, query.OrgID, s.SQLStore)
+	if err != nil {
+		return nil, err
+	}
+	summary, _, err := kdash.NewStaticDashboardSummaryBuilder(keepUnknownLookup{lookup}, false)(ctx, dash.UID, dash.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	deps := &DashboardDependencies{
+		Dashboard:        Reference{Kind: KindDashboard, UID: dash.UID, Name: dash.Title, FolderUID: dash.FolderUID},
+		Datasources:      []Reference{},
+		LibraryPanels:    []Reference{},
+		LinkedDashboards: []Reference{},
+		Plugins:          []Reference{},
+	}
+	for _, ref := range summary.References {
+		switch ref.Family {
+		case entity.StandardKindDataSource:
+			if ref.Identifier != "" {
+				deps.Datasources = append(deps.Datasources, Reference{Kind: KindDatasource, UID: ref.Identifier, Type: ref.Type})
+			}
+		case entity.StandardKindLibraryPanel:
+			deps.LibraryPanels = append(deps.LibraryPanels, Reference{Kind: KindLibraryPanel, UID: ref.Identifier, Type: ref.Type})
+		case entity.StandardKindDashboard:
+			if ref.Type == entity.ExternalEntityReferenceDashboardLink {
+				deps.LinkedDashboards = append(deps.LinkedDashboards, Reference{Kind: KindDashboard, UID: ref.Identifier})
+			}
+		case entity.ExternalEntityReferencePlugin:
+			if ref.Identifier != "" {
+				deps.Plugins = append(deps.Plugins, Reference{Kind: KindPlugin, UID: ref.Identifier, Type: ref.Type})
+			}
+		}
+	}
+
+	if err := s.resolveDatasources(ctx, query.OrgID, deps.Datasources); err != nil {
+		return nil, err
+	}
+	if err := s.resolveLibraryPanels(ctx, query.OrgID, deps.LibraryPanels); err != nil {
+		return nil, err
+	}
+	if err := s.resolveDashboards(ctx, query.OrgID, deps.LinkedDashboards); err != nil {
+		return nil, err
+	}
+	return deps, nil
+}
+
+// GetDatasourceDependents returns the dashboards, library panels and alert rules querying a datasource.
+func (s *DependencyGraphService) GetDatasourceDependents(ctx context.Context, query GetDependentsQuery) (*Dependents, error) {
+	ds, err := s.getDatasource(ctx, query.OrgID, query.UID)
+	if err != nil {
+		return nil, err
+	}
+
+	canRead := s.readFilter(ctx, query.OrgID, query.SignedInUser)
+	dependents := &Dependents{
+		Target:        Reference{Kind: KindDatasource, UID: ds.UID, Name: ds.Name, Type: ds.Type},
+		Dashboards:    []Reference{},
+		LibraryPanels: []Reference{},
+		AlertRules:    []Reference{},
+	}
+
+	lookup, err := kdash.LoadDatasourceLookup(ctx, query.OrgID, s.SQLStore)
+	if err != nil {
+		return nil, err
+	}
+	builder := kdash.NewStaticDashboardSummaryBuilder(lookup, false)
+	referencesDatasource := func(uid string, body []byte) bool {
+		summary, _, err := builder(ctx, uid, body)
+		if summary == nil {
+			s.log.Warn("Failed to read dashboard model", "uid", uid, "error", err)
+			return false
+		}
+		for _, ref := range summary.References {
+			if ref.Family == entity.StandardKindDataSource && ref.Identifier == ds.UID {
+				return true
+			}
+		}
+		return false
+	}
+
+	// The default datasource is used by panels which do not reference any datasource,
+	// so only a full scan finds all of its dependents.
+	contains := []string{ds.UID, ds.Name}
+	if ds.IsDefault {
+		contains = nil
+	}
+
+	err = s.forEachDashboard(ctx, query.OrgID, contains, func(dash *dashboardRow) {
+		if referencesDatasource(dash.UID, dash.Data) && canRead(KindDashboard, dash.UID, dash.FolderUID) {
+			dependents.Dashboards = append(dependents.Dashboards, Reference{Kind: KindDashboard, UID: dash.UID, Name: dash.Title, FolderUID: dash.FolderUID})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	panels, err := s.getLibraryPanels(ctx, query.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	for _, panel := range panels {
+		// Library panels are read as a single panel dashboard so that datasource
+		// references are resolved the same way as for dashboards.
+		body, err := json.Marshal(map[string]any{"panels": []json.RawMessage{panel.Model}})
+		if err != nil {
+			continue
+		}
+		if referencesDatasource(panel.UID, body) && canRead(KindLibraryPanel, panel.UID, panel.FolderUID) {
+			dependents.LibraryPanels = append(dependents.LibraryPanels, Reference{Kind: KindLibraryPanel, UID: panel.UID, Name: panel.Name, Type: panel.Type, FolderUID: panel.FolderUID})
+		}
+	}
+
+	rules, err := s.getAlertRulesByDatasource(ctx, query.OrgID, ds.UID)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		if canRead(KindAlertRule, rule.UID, rule.NamespaceUID) {
+			dependents.AlertRules = append(dependents.AlertRules, Reference{Kind: KindAlertRule, UID: rule.UID, Name: rule.Title, FolderUID: rule.NamespaceUID})
+		}
+	}
+
+	sortReferences(dependents.Dashboards)
+	sortReferences(dependents.LibraryPanels)
+	sortReferences(dependents.AlertRules)
+	return dependents, nil
+}
+
+// GetLibraryPanelDependents returns the dashboards connected to a library panel.
+func (s *DependencyGraphService) GetLibraryPanelDependents(ctx context.Context, query GetDependentsQuery) (*Dependents, error) {
+	panel, err := s.getLibraryPanel(ctx, query.OrgID, query.UID)
+	if err != nil {
+		return nil, err
+	}
+
+	canRead := s.readFilter(ctx, query.OrgID, query.SignedInUser)
+	if !canRead(KindLibraryPanel, panel.UID, panel.FolderUID) {
+		return nil, ErrLibraryPanelNotFound
+	}
+
+	dependents := &Dependents{
+		Target:        Reference{Kind: KindLibraryPanel, UID: panel.UID, Name: panel.Name, Type: panel.Type, FolderUID: panel.FolderUID},
+		Dashboards:    []Reference{},
+		LibraryPanels: []Reference{},
+		AlertRules:    []Reference{},
+	}
+
+	dashes, err := s.getConnectedDashboards(ctx, query.OrgID, panel.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, dash := range dashes {
+		if canRead(KindDashboard, dash.UID, dash.FolderUID) {
+			dependents.Dashboards = append(dependents.Dashboards, Reference{Kind: KindDashboard, UID: dash.UID, Name: dash.Title, FolderUID: dash.FolderUID})
+		}
+	}
+	sortReferences(dependents.Dashboards)
+	return dependents, nil
+}
+
+// readFilter returns a function checking if the user can read an entity. Everything is
+// readable when no user is given, which is the case for internal callers.
+func (s *DependencyGraphService) readFilter(ctx context.Context, orgID int64, usr *user.SignedInUser) func(kind Kind, uid, folderUID string) bool {
+	if usr == nil {
+		return func(Kind, string, string) bool { return true }
+	}
+
+	canReadDashboard := accesscontrol.Checker(usr, dashboards.ActionDashboardsRead)
+	canReadFolder := accesscontrol.Checker(usr, dashboards.ActionFoldersRead)
+	canReadAlertRule := accesscontrol.Checker(usr, accesscontrol.ActionAlertingRuleRead)
+
+	folderScopes := func(folderUID string) []string {
+		scopes, err := dashboards.GetInheritedScopes(ctx, orgID, folderUID, s.folderService)
+		if err != nil {
+			s.log.Debug("Could not retrieve inherited folder scopes", "error", err)
+		}
+		return append(scopes, dashboards.ScopeFoldersProvider.GetResourceScopeUID(folderUID))
+	}
+
+	return func(kind Kind, uid, folderUID string) bool {
+		if folderUID == "" {
+			folderUID = folder.GeneralFolderUID
+		}
+		switch kind {
+		case KindDashboard:
+			scopes := append(folderScopes(folderUID), dashboards.ScopeDashboardsProvider.GetResourceScopeUID(uid))
+			return canReadDashboard(scopes...)
+		case KindLibraryPanel:
+			// library panels in the general folder are visible to every viewer
+			if folderUID == folder.GeneralFolderUID {
+				return usr.HasRole(org.RoleViewer)
+			}
+			return canReadFolder(folderScopes(folderUID)...)
+		case KindAlertRule:
+			scopes := folderScopes(folderUID)
+			return canReadAlertRule(scopes...) && canReadFolder(scopes...)
+		case KindDatasource:
+			return accesscontrol.Checker(usr, datasources.ActionRead)(datasources.ScopeProvider.GetResourceScopeUID(uid))
+		}
+		return false
+	}
+}
+
+// keepUnknownLookup resolves datasources like the wrapped lookup, but keeps references to
+// datasources which do not exist so that they can be reported as missing.
+type keepUnknownLookup struct {
+	kdash.DatasourceLookup
+}
+
+func (l keepUnknownLookup) ByRef(ref *kdash.DataSourceRef) *kdash.DataSourceRef {
+	if ds := l.DatasourceLookup.ByRef(ref); ds != nil {
+		return ds
+	}
+	return ref
+}
+
+func sortReferences(refs []Reference) {
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Name != refs[j].Name {
+			return refs[i].Name < refs[j].Name
+		}
+		return refs[i].UID < refs[j].UID
+	})
+}
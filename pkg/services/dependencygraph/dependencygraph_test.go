@@ -0,0 +1,244 @@
+package dependencygraph
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/folder/foldertest"
+	"github.com/grafana/grafana/pkg/services/libraryelements/model"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+const testOrgID int64 = 1
+
+func TestIntegrationDependencyGraph(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	sqlStore := db.InitTestDB(t)
+	s := &DependencyGraphService{
+		SQLStore:      sqlStore,
+		folderService: foldertest.NewFakeService(),
+		log:           log.NewNopLogger(),
+	}
+	ctx := context.Background()
+
+	insertDatasource(t, sqlStore, "prom-uid", "Prometheus", "prometheus", true)
+	insertDatasource(t, sqlStore, "loki-uid", "Loki", "loki", false)
+
+	folderID := insertDashboard(t, sqlStore, "folder-uid", "Folder", 0, true, `{}`)
+	panelID := insertLibraryPanel(t, sqlStore, "lib-uid", "Logs panel", folderID, `{
+		"type": "logs",
+		"datasource": {"uid": "loki-uid", "type": "loki"},
+		"targets": [{"refId": "A"}]
+	}`)
+
+	dashID := insertDashboard(t, sqlStore, "dash-uid", "Service overview", folderID, false, `{
+		"title": "Service overview",
+		"links": [{"type": "link", "url": "/d/other-uid/other"}, {"type": "link", "url": "/d/gone-uid"}],
+		"panels": [
+			{"id": 1, "type": "timeseries", "datasource": {"uid": "prom-uid", "type": "prometheus"}, "targets": [{"refId": "A"}]},
+			{"id": 2, "type": "table", "datasource": {"uid": "deleted-uid", "type": "mysql"}, "targets": [{"refId": "A"}]},
+			{"id": 3, "libraryPanel": {"uid": "lib-uid", "name": "Logs panel"}},
+			{"id": 4, "libraryPanel": {"uid": "missing-lib-uid", "name": "Gone"}}
+		]
+	}`)
+	insertDashboard(t, sqlStore, "other-uid", "Other", 0, false, `{
+		"title": "Other",
+		"panels": [{"id": 1, "type": "stat", "datasource": {"uid": "loki-uid", "type": "loki"}, "targets": [{"refId": "A"}]}]
+	}`)
+	insertLibraryPanelConnection(t, sqlStore, panelID, dashID)
+	insertAlertRule(t, sqlStore, "rule-uid", "High latency", "folder-uid", "prom-uid")
+	insertAlertRule(t, sqlStore, "other-rule-uid", "Errors", "folder-uid", "loki-uid")
+
+	t.Run("dashboard dependencies", func(t *testing.T) {
+		deps, err := s.GetDashboardDependencies(ctx, GetDashboardDependenciesQuery{OrgID: testOrgID, UID: "dash-uid"})
+		require.NoError(t, err)
+
+		require.Equal(t, Reference{Kind: KindDashboard, UID: "dash-uid", Name: "Service overview", FolderUID: "folder-uid"}, deps.Dashboard)
+		require.ElementsMatch(t, []Reference{
+			{Kind: KindDatasource, UID: "prom-uid", Name: "Prometheus", Type: "prometheus"},
+			{Kind: KindDatasource, UID: "deleted-uid", Type: "mysql", Missing: true},
+		}, deps.Datasources)
+		require.ElementsMatch(t, []Reference{
+			{Kind: KindLibraryPanel, UID: "lib-uid", Name: "Logs panel", Type: "logs"},
+			{Kind: KindLibraryPanel, UID: "missing-lib-uid", Missing: true},
+		}, deps.LibraryPanels)
+		require.ElementsMatch(t, []Reference{
+			{Kind: KindDashboard, UID: "other-uid", Name: "Other"},
+			{Kind: KindDashboard, UID: "gone-uid", Missing: true},
+		}, deps.LinkedDashboards)
+		require.NotEmpty(t, deps.Plugins)
+	})
+
+	t.Run("dashboard dependencies of unknown dashboard", func(t *testing.T) {
+		_, err := s.GetDashboardDependencies(ctx, GetDashboardDependenciesQuery{OrgID: testOrgID, UID: "nope"})
+		require.ErrorIs(t, err, ErrDashboardNotFound)
+	})
+
+	t.Run("datasource dependents", func(t *testing.T) {
+		dependents, err := s.GetDatasourceDependents(ctx, GetDependentsQuery{OrgID: testOrgID, UID: "loki-uid"})
+		require.NoError(t, err)
+
+		require.Equal(t, "Loki", dependents.Target.Name)
+		require.Equal(t, []Reference{{Kind: KindDashboard, UID: "other-uid", Name: "Other"}}, dependents.Dashboards)
+		require.Equal(t, []Reference{{Kind: KindLibraryPanel, UID: "lib-uid", Name: "Logs panel", Type: "logs", FolderUID: "folder-uid"}}, dependents.LibraryPanels)
+		require.Equal(t, []Reference{{Kind: KindAlertRule, UID: "other-rule-uid", Name: "Errors", FolderUID: "folder-uid"}}, dependents.AlertRules)
+	})
+
+	t.Run("datasource dependents are filtered by permissions", func(t *testing.T) {
+		usr := &user.SignedInUser{
+			OrgID:   testOrgID,
+			OrgRole: org.RoleViewer,
+			Permissions: map[int64]map[string][]string{testOrgID: {
+				dashboards.ActionDashboardsRead: {dashboards.ScopeDashboardsProvider.GetResourceScopeUID("dash-uid")},
+			}},
+		}
+		dependents, err := s.GetDatasourceDependents(ctx, GetDependentsQuery{OrgID: testOrgID, UID: "prom-uid", SignedInUser: usr})
+		require.NoError(t, err)
+		require.Equal(t, []Reference{{Kind: KindDashboard, UID: "dash-uid", Name: "Service overview", FolderUID: "folder-uid"}}, dependents.Dashboards)
+		require.Empty(t, dependents.AlertRules)
+
+		usr.Permissions[testOrgID] = map[string][]string{
+			datasources.ActionRead: {datasources.ScopeAll},
+		}
+		dependents, err = s.GetDatasourceDependents(ctx, GetDependentsQuery{OrgID: testOrgID, UID: "prom-uid", SignedInUser: usr})
+		require.NoError(t, err)
+		require.Empty(t, dependents.Dashboards)
+	})
+
+	t.Run("library panel dependents", func(t *testing.T) {
+		dependents, err := s.GetLibraryPanelDependents(ctx, GetDependentsQuery{OrgID: testOrgID, UID: "lib-uid"})
+		require.NoError(t, err)
+		require.Equal(t, []Reference{{Kind: KindDashboard, UID: "dash-uid", Name: "Service overview", FolderUID: "folder-uid"}}, dependents.Dashboards)
+
+		usr := &user.SignedInUser{OrgID: testOrgID, OrgRole: org.RoleViewer, Permissions: map[int64]map[string][]string{testOrgID: {}}}
+		_, err = s.GetLibraryPanelDependents(ctx, GetDependentsQuery{OrgID: testOrgID, UID: "lib-uid", SignedInUser: usr})
+		require.ErrorIs(t, err, ErrLibraryPanelNotFound)
+
+		usr.Permissions[testOrgID] = map[string][]string{
+			dashboards.ActionFoldersRead: {dashboards.ScopeFoldersProvider.GetResourceScopeUID("folder-uid")},
+		}
+		dependents, err = s.GetLibraryPanelDependents(ctx, GetDependentsQuery{OrgID: testOrgID, UID: "lib-uid", SignedInUser: usr})
+		require.NoError(t, err)
+		require.Empty(t, dependents.Dashboards)
+
+		usr.Permissions[testOrgID][dashboards.ActionDashboardsRead] = []string{dashboards.ScopeFoldersProvider.GetResourceScopeUID("folder-uid")}
+		dependents, err = s.GetLibraryPanelDependents(ctx, GetDependentsQuery{OrgID: testOrgID, UID: "lib-uid", SignedInUser: usr})
+		require.NoError(t, err)
+		require.Len(t, dependents.Dashboards, 1)
+	})
+}
+
+func insertDatasource(t *testing.T, sqlStore db.DB, uid, name, dsType string, isDefault bool) {
+	t.Helper()
+	err := sqlStore.WithDbSession(context.Background(), func(sess *db.Session) error {
+		_, err := sess.Insert(&datasources.DataSource{
+			OrgID:     testOrgID,
+			UID:       uid,
+			Name:      name,
+			Type:      dsType,
+			Access:    datasources.DS_ACCESS_PROXY,
+			IsDefault: isDefault,
+			Created:   time.Now(),
+			Updated:   time.Now(),
+		})
+		return err
+	})
+	require.NoError(t, err)
+}
+
+func insertDashboard(t *testing.T, sqlStore db.DB, uid, title string, folderID int64, isFolder bool, data string) int64 {
+	t.Helper()
+	body, err := simplejson.NewJson([]byte(data))
+	require.NoError(t, err)
+	dash := &dashboards.Dashboard{
+		OrgID:    testOrgID,
+		UID:      uid,
+		Title:    title,
+		Slug:     uid,
+		FolderID: folderID,
+		IsFolder: isFolder,
+		Data:     body,
+		Created:  time.Now(),
+		Updated:  time.Now(),
+	}
+	err = sqlStore.WithDbSession(context.Background(), func(sess *db.Session) error {
+		_, err := sess.Insert(dash)
+		return err
+	})
+	require.NoError(t, err)
+	return dash.ID
+}
+
+func insertLibraryPanel(t *testing.T, sqlStore db.DB, uid, name string, folderID int64, panel string) int64 {
+	t.Helper()
+	element := &model.LibraryElement{
+		OrgID:    testOrgID,
+		FolderID: folderID,
+		UID:      uid,
+		Name:     name,
+		Kind:     int64(model.PanelElement),
+		Type:     "logs",
+		Model:    json.RawMessage(panel),
+		Version:  1,
+		Created:  time.Now(),
+		Updated:  time.Now(),
+	}
+	err := sqlStore.WithDbSession(context.Background(), func(sess *db.Session) error {
+		_, err := sess.Table("library_element").Insert(element)
+		return err
+	})
+	require.NoError(t, err)
+	return element.ID
+}
+
+func insertLibraryPanelConnection(t *testing.T, sqlStore db.DB, elementID, dashboardID int64) {
+	t.Helper()
+	err := sqlStore.WithDbSession(context.Background(), func(sess *db.Session) error {
+		_, err := sess.Table("library_element_connection").Insert(&model.LibraryElementConnection{
+			ElementID:    elementID,
+			Kind:         1,
+			ConnectionID: dashboardID,
+			Created:      time.Now(),
+		})
+		return err
+	})
+	require.NoError(t, err)
+}
+
+func insertAlertRule(t *testing.T, sqlStore db.DB, uid, title, folderUID, dsUID string) {
+	t.Helper()
+	err := sqlStore.WithDbSession(context.Background(), func(sess *db.Session) error {
+		_, err := sess.Table("alert_rule").Insert(&ngmodels.AlertRule{
+			OrgID:        testOrgID,
+			UID:          uid,
+			Title:        title,
+			Condition:    "A",
+			NamespaceUID: folderUID,
+			RuleGroup:    "group",
+			Data: []ngmodels.AlertQuery{
+				{RefID: "A", DatasourceUID: dsUID, Model: json.RawMessage(`{}`)},
+				{RefID: "B", DatasourceUID: "__expr__", Model: json.RawMessage(`{}`)},
+			},
+			IntervalSeconds: 60,
+			NoDataState:     ngmodels.NoData,
+			ExecErrState:    ngmodels.AlertingErrState,
+			Updated:         time.Now(),
+		})
+		return err
+	})
+	require.NoError(t, err)
+}
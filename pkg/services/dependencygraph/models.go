@@ -0,0 +1,66 @@
+package dependencygraph
+
+import (
+	"errors"
+
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+var (
+	ErrDashboardNotFound    = errors.New("dashboard not found")
+	ErrDatasourceNotFound   = errors.New("data source not found")
+	ErrLibraryPanelNotFound = errors.New("library panel not found")
+)
+
+type Kind string
+
+const (
+	KindDashboard    Kind = "dashboard"
+	KindDatasource   Kind = "datasource"
+	KindLibraryPanel Kind = "librarypanel"
+	KindPlugin       Kind = "plugin"
+	KindAlertRule    Kind = "alertrule"
+)
+
+// Reference points to an entity in a dependency graph.
+type Reference struct {
+	Kind Kind   `json:"kind"`
+	UID  string `json:"uid"`
+	Name string `json:"name,omitempty"`
+	// Type is the datasource type for datasources, the panel type for library panels
+	// and the plugin type (panel, datasource) for plugins.
+	Type string `json:"type,omitempty"`
+	// FolderUID is set for entities stored in folders.
+	FolderUID string `json:"folderUid,omitempty"`
+	// Missing is true when the referenced entity does not exist in the organization.
+	Missing bool `json:"missing,omitempty"`
+}
+
+// DashboardDependencies lists everything a dashboard needs to render.
+type DashboardDependencies struct {
+	Dashboard        Reference   `json:"dashboard"`
+	Datasources      []Reference `json:"datasources"`
+	LibraryPanels    []Reference `json:"libraryPanels"`
+	LinkedDashboards []Reference `json:"linkedDashboards"`
+	Plugins          []Reference `json:"plugins"`
+}
+
+// Dependents lists everything depending on a datasource or library panel. Only
+// entities the requesting user can read are included.
+type Dependents struct {
+	Target        Reference   `json:"target"`
+	Dashboards    []Reference `json:"dashboards"`
+	LibraryPanels []Reference `json:"libraryPanels"`
+	AlertRules    []Reference `json:"alertRules"`
+}
+
+type GetDashboardDependenciesQuery struct {
+	OrgID int64
+	UID   string
+}
+
+type GetDependentsQuery struct {
+	OrgID        int64
+	UID          string
+	SignedInUser *user.SignedInUser
+}
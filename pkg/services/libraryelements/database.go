@@ -18,6 +18,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/org"
 	"github.com/grafana/grafana/pkg/services/search"
 	"github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+	"github.com/grafana/grafana/pkg/services/store"
 	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/util"
@@ -171,6 +172,11 @@ func (l *LibraryElementService) createLibraryElement(c context.Context, signedIn
 			}
 			return err
 		}
+		if store.EntityEventsEnabled(l.features) {
+			if _, err := session.Insert(store.NewDatabaseEntityEvent(element.UID, element.OrgID, store.EntityTypeLibraryElement, store.EntityEventTypeCreate)); err != nil {
+				return err
+			}
+		}
 		return nil
 	})
 
@@ -241,6 +247,11 @@ func (l *LibraryElementService) deleteLibraryElement(c context.Context, signedIn
 		} else if rowsAffected != 1 {
 			return model.ErrLibraryElementNotFound
 		}
+		if store.EntityEventsEnabled(l.features) {
+			if _, err := session.Insert(store.NewDatabaseEntityEvent(element.UID, element.OrgID, store.EntityTypeLibraryElement, store.EntityEventTypeDelete)); err != nil {
+				return err
+			}
+		}
 
 		elementID = element.ID
 		return nil
@@ -580,6 +591,16 @@ func (l *LibraryElementService) patchLibraryElement(c context.Context, signedInU
 		} else if rowsAffected != 1 {
 			return model.ErrLibraryElementNotFound
 		}
+		if store.EntityEventsEnabled(l.features) {
+			if libraryElement.UID != elementInDB.UID {
+				if _, err := session.Insert(store.NewDatabaseEntityEvent(elementInDB.UID, libraryElement.OrgID, store.EntityTypeLibraryElement, store.EntityEventTypeDelete)); err != nil {
+					return err
+				}
+			}
+			if _, err := session.Insert(store.NewDatabaseEntityEvent(libraryElement.UID, libraryElement.OrgID, store.EntityTypeLibraryElement, store.EntityEventTypeUpdate)); err != nil {
+				return err
+			}
+		}
 
 		dto = model.LibraryElementDTO{
 			ID:          libraryElement.ID,
@@ -814,9 +835,10 @@ func (l *LibraryElementService) deleteLibraryElementsInFolderUID(c context.Conte
 		}
 
 		var elementIDs []struct {
-			ID int64 `xorm:"id"`
+			ID  int64  `xorm:"id"`
+			UID string `xorm:"uid"`
 		}
-		err = session.SQL("SELECT id from library_element WHERE folder_id=? AND org_id=?", folderID, signedInUser.GetOrgID()).Find(&elementIDs)
+		err = session.SQL("SELECT id, uid from library_element WHERE folder_id=? AND org_id=?", folderID, signedInUser.GetOrgID()).Find(&elementIDs)
 		if err != nil {
 			return err
 		}
@@ -825,6 +847,11 @@ func (l *LibraryElementService) deleteLibraryElementsInFolderUID(c context.Conte
 			if err != nil {
 				return err
 			}
+			if store.EntityEventsEnabled(l.features) {
+				if _, err := session.Insert(store.NewDatabaseEntityEvent(elementID.UID, signedInUser.GetOrgID(), store.EntityTypeLibraryElement, store.EntityEventTypeDelete)); err != nil {
+					return err
+				}
+			}
 		}
 		if _, err := session.Exec("DELETE FROM library_element WHERE folder_id=? AND org_id=?", folderID, signedInUser.GetOrgID()); err != nil {
 			return err
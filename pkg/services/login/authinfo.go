@@ -36,14 +36,16 @@ type Store interface {
 
 const (
 	// modules
-	PasswordAuthModule  = "password"
-	APIKeyAuthModule    = "apikey"
-	SAMLAuthModule      = "auth.saml"
-	LDAPAuthModule      = "ldap"
-	AuthProxyAuthModule = "authproxy"
-	JWTModule           = "jwt"
-	ExtendedJWTModule   = "extendedjwt"
-	RenderModule        = "render"
+	PasswordAuthModule    = "password"
+	AppPasswordAuthModule = "app_password"
+	APIKeyAuthModule      = "apikey"
+	SAMLAuthModule        = "auth.saml"
+	LDAPAuthModule        = "ldap"
+	AuthProxyAuthModule   = "authproxy"
+	JWTModule             = "jwt"
+	ExtendedJWTModule     = "extendedjwt"
+	RenderModule          = "render"
+	ClientCertAuthModule  = "clientcert"
 	// OAuth provider modules
 	AzureADAuthModule    = "oauth_azuread"
 	GoogleAuthModule     = "oauth_google"
@@ -55,9 +57,10 @@ const (
 	OktaAuthModule       = "oauth_okta"
 
 	// labels
-	SAMLLabel = "SAML"
-	LDAPLabel = "LDAP"
-	JWTLabel  = "JWT"
+	SAMLLabel       = "SAML"
+	LDAPLabel       = "LDAP"
+	JWTLabel        = "JWT"
+	ClientCertLabel = "Client Certificate"
 	// OAuth provider labels
 	AuthProxyLabel    = "Auth Proxy"
 	AzureADLabel      = "AzureAD"
@@ -88,6 +91,8 @@ func IsExternallySynced(cfg *setting.Cfg, authModule string) bool {
 		return !cfg.LDAPSkipOrgRoleSync
 	case JWTModule:
 		return !cfg.JWTAuthSkipOrgRoleSync
+	case ClientCertAuthModule:
+		return !cfg.ClientCertAuthSkipOrgRoleSync
 	}
 	// then check the rest of the oauth providers
 	// FIXME: remove this once we remove the setting
@@ -125,6 +130,8 @@ func IsGrafanaAdminExternallySynced(cfg *setting.Cfg, authModule string, oAuthAn
 	switch authModule {
 	case JWTModule:
 		return cfg.JWTAuthAllowAssignGrafanaAdmin
+	case ClientCertAuthModule:
+		return cfg.ClientCertAuthAllowAssignGrafanaAdmin
 	case SAMLAuthModule:
 		return cfg.SAMLRoleValuesGrafanaAdmin != ""
 	case LDAPAuthModule:
@@ -142,6 +149,8 @@ func IsProviderEnabled(cfg *setting.Cfg, authModule string) bool {
 		return cfg.LDAPAuthEnabled
 	case JWTModule:
 		return cfg.JWTAuthEnabled
+	case ClientCertAuthModule:
+		return cfg.ClientCertAuthEnabled
 	case GoogleAuthModule:
 		return cfg.GoogleAuthEnabled
 	case OktaAuthModule:
@@ -181,6 +190,8 @@ func GetAuthProviderLabel(authModule string) string {
 		return LDAPLabel
 	case JWTModule:
 		return JWTLabel
+	case ClientCertAuthModule:
+		return ClientCertLabel
 	case AuthProxyAuthModule:
 		return AuthProxyLabel
 	case GenericOAuthModule:
@@ -2,16 +2,22 @@ package loginattempt
 
 import (
 	"context"
+	"net"
+	"net/http"
+	"strings"
+	"time"
 )
 
 type Service interface {
 	// Add adds a new login attempt record for provided username
 	Add(ctx context.Context, username, IPAddress string) error
-	// Validate checks if username has to many login attempts inside a window.
-	// Will return true if provided username do not have too many attempts.
-	Validate(ctx context.Context, username string) (bool, error)
+	// Validate checks if username, IP address or their combination have too many login attempts inside a window.
+	// Will return true if none of them is locked out.
+	Validate(ctx context.Context, username, IPAddress string) (bool, error)
 	// Reset resets all login attempts attached to username
 	Reset(ctx context.Context, username string) error
+	// ClientIP returns the IP address the login attempts of the request are recorded for
+	ClientIP(req *http.Request) string
 }
 
 type LoginAttempt struct {
@@ -20,3 +26,72 @@ type LoginAttempt struct {
 	IpAddress string
 	Created   int64
 }
+
+// LockoutType is the limit which caused a lockout
+type LockoutType string
+
+const (
+	LockoutTypeUsername   LockoutType = "username"
+	LockoutTypeIP         LockoutType = "ip"
+	LockoutTypeIPUsername LockoutType = "ip_username"
+)
+
+// Lockout is an active lockout of a username, an IP address or an IP address for a username
+type Lockout struct {
+	Type        LockoutType `json:"type"`
+	Username    string      `json:"username,omitempty"`
+	IPAddress   string      `json:"ipAddress,omitempty"`
+	Attempts    int64       `json:"attempts"`
+	LastAttempt time.Time   `json:"lastAttempt"`
+	LockedUntil time.Time   `json:"lockedUntil"`
+}
+
+// ClientIP returns the IP address of the client of the request. It is the address of the connection, unless the
+// connection comes from one of the trusted proxies: the client is then the last address of the X-Forwarded-For
+// header which is not a trusted proxy, or the X-Real-IP header if the request has no X-Forwarded-For header.
+func ClientIP(req *http.Request, trustedProxies []*net.IPNet) string {
+	remoteAddr := connectionIP(req.RemoteAddr)
+	if remoteAddr == nil {
+		return req.RemoteAddr
+	}
+	if !isTrustedProxy(remoteAddr, trustedProxies) {
+		return remoteAddr.String()
+	}
+
+	if forwardedFor := req.Header.Values("X-Forwarded-For"); len(forwardedFor) > 0 {
+		// the proxies append the address they received the request from, the client can set the first ones
+		addrs := strings.Split(strings.Join(forwardedFor, ","), ",")
+		for i := len(addrs) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(addrs[i]))
+			if ip == nil {
+				break
+			}
+			if !isTrustedProxy(ip, trustedProxies) {
+				return ip.String()
+			}
+		}
+		return remoteAddr.String()
+	}
+
+	if ip := net.ParseIP(strings.TrimSpace(req.Header.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+	return remoteAddr.String()
+}
+
+func connectionIP(remoteAddr string) net.IP {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+func isTrustedProxy(ip net.IP, trustedProxies []*net.IPNet) bool {
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
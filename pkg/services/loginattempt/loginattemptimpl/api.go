@@ -0,0 +1,58 @@
+package loginattemptimpl
+
+import (
+	"net/http"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/middleware"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+)
+
+func (s *Service) registerAPIEndpoints() {
+	s.routeRegister.Group("/api/admin/login-lockouts", func(lockoutRoute routing.RouteRegister) {
+		lockoutRoute.Get("/", routing.Wrap(s.getLockoutsHandler))
+		lockoutRoute.Delete("/", routing.Wrap(s.clearLockoutsHandler))
+	}, middleware.ReqGrafanaAdmin)
+}
+
+// swagger:route GET /admin/login-lockouts admin getLoginLockouts
+//
+// List the usernames and IP addresses locked out by the brute force login protection.
+//
+// Responses:
+// 200: loginLockoutsResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (s *Service) getLockoutsHandler(c *contextmodel.ReqContext) response.Response {
+	lockouts, err := s.GetLockouts(c.Req.Context())
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get login lockouts", err)
+	}
+	return response.JSON(http.StatusOK, lockouts)
+}
+
+// swagger:route DELETE /admin/login-lockouts admin clearLoginLockouts
+//
+// Clear the failed login attempts of a username, an IP address or an IP address for a username.
+//
+// Responses:
+// 200: okResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (s *Service) clearLockoutsHandler(c *contextmodel.ReqContext) response.Response {
+	username := c.Query("username")
+	ipAddress := c.Query("ipAddress")
+	if username == "" && ipAddress == "" {
+		return response.Error(http.StatusBadRequest, "username or ipAddress is required", nil)
+	}
+
+	if err := s.ClearLockouts(c.Req.Context(), username, ipAddress); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to clear login lockouts", err)
+	}
+	s.logger.FromContext(c.Req.Context()).Info("Login lockouts cleared", "username", username, "ipAddress", ipAddress, "adminId", c.SignedInUser.UserID)
+	return response.Success("Login lockouts cleared")
+}
@@ -2,33 +2,93 @@ package loginattemptimpl
 
 import (
 	"context"
+	"net"
+	"net/http"
+	"strings"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/grafana/pkg/api/routing"
 	"github.com/grafana/grafana/pkg/infra/db"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/infra/serverlock"
+	"github.com/grafana/grafana/pkg/services/loginattempt"
 	"github.com/grafana/grafana/pkg/setting"
 )
 
-const (
-	maxInvalidLoginAttempts int64 = 5
-	loginAttemptsWindow           = time.Minute * 5
-)
+// minCleanupAge is the minimum age of the login attempts deleted by the cleanup job
+const minCleanupAge = time.Minute * 10
 
-func ProvideService(db db.DB, cfg *setting.Cfg, lock *serverlock.ServerLockService) *Service {
-	return &Service{
-		&xormStore{db: db, now: time.Now},
-		cfg,
-		lock,
-		log.New("login_attempt"),
+func ProvideService(db db.DB, cfg *setting.Cfg, lock *serverlock.ServerLockService,
+	routeRegister routing.RouteRegister, registerer prometheus.Registerer,
+) *Service {
+	s := &Service{
+		store:         &xormStore{db: db, now: time.Now},
+		cfg:           cfg,
+		lock:          lock,
+		routeRegister: routeRegister,
+		metrics:       newMetrics(registerer),
+		logger:        log.New("login_attempt"),
 	}
+	s.trustedProxies = parseTrustedProxies(s.logger, cfg.BruteForceLoginProtection.TrustedProxies)
+	s.registerAPIEndpoints()
+	return s
 }
 
 type Service struct {
-	store  store
-	cfg    *setting.Cfg
-	lock   *serverlock.ServerLockService
-	logger log.Logger
+	store         store
+	cfg           *setting.Cfg
+	lock          *serverlock.ServerLockService
+	routeRegister routing.RouteRegister
+	metrics       *metrics
+	logger        log.Logger
+	// trustedProxies are the proxies whose forwarded headers give the IP address of the client
+	trustedProxies []*net.IPNet
+}
+
+// parseTrustedProxies parses the IP addresses and CIDR ranges of the trusted proxies, skipping the invalid ones
+func parseTrustedProxies(logger log.Logger, entries []string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				logger.Warn("Ignoring invalid trusted proxy", "proxy", entry)
+				continue
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			logger.Warn("Ignoring invalid trusted proxy", "proxy", entry, "error", err)
+			continue
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// limit is the max number of failed login attempts inside a window for a username,
+// an IP address or the combination of both
+type limit struct {
+	lockoutType loginattempt.LockoutType
+	maxAttempts int64
+	window      time.Duration
+}
+
+func (l limit) byUsername() bool {
+	return l.lockoutType != loginattempt.LockoutTypeIP
+}
+
+func (l limit) byIPAddress() bool {
+	return l.lockoutType != loginattempt.LockoutTypeUsername
 }
 
 func (s *Service) Run(ctx context.Context) error {
@@ -60,36 +120,143 @@ func (s *Service) Add(ctx context.Context, username, IPAddress string) error {
 	return err
 }
 
+func (s *Service) ClientIP(req *http.Request) string {
+	return loginattempt.ClientIP(req, s.trustedProxies)
+}
+
 func (s *Service) Reset(ctx context.Context, username string) error {
-	return s.store.DeleteLoginAttempts(ctx, DeleteLoginAttemptsCommand{username})
+	return s.store.DeleteLoginAttempts(ctx, DeleteLoginAttemptsCommand{Username: username})
 }
 
-func (s *Service) Validate(ctx context.Context, username string) (bool, error) {
+func (s *Service) Validate(ctx context.Context, username, IPAddress string) (bool, error) {
 	if s.cfg.DisableBruteForceLoginProtection {
 		return true, nil
 	}
 
-	loginAttemptCountQuery := GetUserLoginAttemptCountQuery{
-		Username: username,
-		Since:    time.Now().Add(-loginAttemptsWindow),
+	now := time.Now()
+	for _, l := range s.limits() {
+		// callers without a client address are only limited by username
+		if (l.byUsername() && username == "") || (l.byIPAddress() && IPAddress == "") {
+			continue
+		}
+
+		query := GetLoginAttemptStatsQuery{Since: now.Add(-l.window)}
+		if l.byUsername() {
+			query.Username = username
+		}
+		if l.byIPAddress() {
+			query.IpAddress = IPAddress
+		}
+
+		stats, err := s.store.GetLoginAttemptStats(ctx, query)
+		if err != nil {
+			return false, err
+		}
+
+		if now.Before(s.lockedUntil(l, stats.Count, stats.LastAttempt)) {
+			s.metrics.blockedAttempts.WithLabelValues(string(l.lockoutType)).Inc()
+			s.logger.FromContext(ctx).Info("Login attempt blocked", "limit", l.lockoutType, "username", username, "ipAddress", IPAddress, "attempts", stats.Count)
+			return false, nil
+		}
 	}
 
-	count, err := s.store.GetUserLoginAttemptCount(ctx, loginAttemptCountQuery)
-	if err != nil {
-		return false, err
+	return true, nil
+}
+
+// GetLockouts returns the usernames and IP addresses currently locked out
+func (s *Service) GetLockouts(ctx context.Context) ([]*loginattempt.Lockout, error) {
+	lockouts := make([]*loginattempt.Lockout, 0)
+	if s.cfg.DisableBruteForceLoginProtection {
+		return lockouts, nil
 	}
 
-	if count >= maxInvalidLoginAttempts {
-		return false, nil
+	now := time.Now()
+	for _, l := range s.limits() {
+		groups, err := s.store.GetLoginAttemptGroups(ctx, GetLoginAttemptGroupsQuery{
+			ByUsername:  l.byUsername(),
+			ByIpAddress: l.byIPAddress(),
+			Since:       now.Add(-l.window),
+			MinCount:    l.maxAttempts,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, g := range groups {
+			lockedUntil := s.lockedUntil(l, g.Count, g.LastAttempt)
+			if !now.Before(lockedUntil) {
+				continue
+			}
+			lockouts = append(lockouts, &loginattempt.Lockout{
+				Type:        l.lockoutType,
+				Username:    g.Username,
+				IPAddress:   g.IpAddress,
+				Attempts:    g.Count,
+				LastAttempt: time.Unix(g.LastAttempt, 0),
+				LockedUntil: lockedUntil,
+			})
+		}
 	}
+	return lockouts, nil
+}
 
-	return true, nil
+// ClearLockouts deletes the login attempts of the username, the IP address or the combination of both
+func (s *Service) ClearLockouts(ctx context.Context, username, IPAddress string) error {
+	return s.store.DeleteLoginAttempts(ctx, DeleteLoginAttemptsCommand{Username: username, IpAddress: IPAddress})
+}
+
+// limits returns the enabled limits
+func (s *Service) limits() []limit {
+	settings := s.cfg.BruteForceLoginProtection
+	all := []limit{
+		{lockoutType: loginattempt.LockoutTypeUsername, maxAttempts: settings.MaxAttempts, window: settings.Window},
+		{lockoutType: loginattempt.LockoutTypeIP, maxAttempts: settings.IPMaxAttempts, window: settings.IPWindow},
+		{lockoutType: loginattempt.LockoutTypeIPUsername, maxAttempts: settings.IPUsernameMaxAttempts, window: settings.IPUsernameWindow},
+	}
+
+	enabled := make([]limit, 0, len(all))
+	for _, l := range all {
+		if l.maxAttempts > 0 && l.window > 0 {
+			enabled = append(enabled, l)
+		}
+	}
+	return enabled
+}
+
+// lockedUntil returns the end of the lockout after the given number of attempts. The lockout
+// starts with the configured backoff after the last attempt and doubles with each attempt
+// above the limit, it never exceeds the window as older attempts stop being counted.
+func (s *Service) lockedUntil(l limit, attempts, lastAttempt int64) time.Time {
+	if attempts < l.maxAttempts {
+		return time.Time{}
+	}
+
+	last := time.Unix(lastAttempt, 0)
+	backoff := s.cfg.BruteForceLoginProtection.Backoff
+	if backoff <= 0 {
+		return last.Add(l.window)
+	}
+
+	for i := attempts - l.maxAttempts; i > 0 && backoff < l.window; i-- {
+		backoff *= 2
+	}
+	if backoff > l.window {
+		backoff = l.window
+	}
+	return last.Add(backoff)
 }
 
 func (s *Service) cleanup(ctx context.Context) {
+	maxAge := minCleanupAge
+	for _, l := range s.limits() {
+		if l.window > maxAge {
+			maxAge = l.window
+		}
+	}
+
 	err := s.lock.LockAndExecute(ctx, "delete old login attempts", time.Minute*10, func(context.Context) {
 		cmd := DeleteOldLoginAttemptsCommand{
-			OlderThan: time.Now().Add(time.Minute * -10),
+			OlderThan: time.Now().Add(-maxAge),
 		}
 		if deletedLogs, err := s.store.DeleteOldLoginAttempts(ctx, cmd); err != nil {
 			s.logger.Error("Problem deleting expired login attempts", "error", err.Error())
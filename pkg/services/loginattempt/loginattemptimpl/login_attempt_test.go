@@ -2,14 +2,24 @@ package loginattemptimpl
 
 import (
 	"context"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/services/loginattempt"
 	"github.com/grafana/grafana/pkg/setting"
 )
 
+const (
+	maxInvalidLoginAttempts int64 = 5
+	loginAttemptsWindow           = time.Minute * 5
+)
+
 func TestService_Validate(t *testing.T) {
 	testCases := []struct {
 		name          string
@@ -64,15 +74,22 @@ func TestService_Validate(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			cfg := setting.NewCfg()
 			cfg.DisableBruteForceLoginProtection = tt.disabled
+			cfg.BruteForceLoginProtection = setting.BruteForceLoginProtectionSettings{
+				MaxAttempts: maxInvalidLoginAttempts,
+				Window:      loginAttemptsWindow,
+			}
 			service := &Service{
 				store: fakeStore{
-					ExpectedCount: tt.loginAttempts,
-					ExpectedErr:   tt.expectedErr,
+					ExpectedCount:       tt.loginAttempts,
+					ExpectedLastAttempt: time.Now().Unix(),
+					ExpectedErr:         tt.expectedErr,
 				},
-				cfg: cfg,
+				cfg:     cfg,
+				metrics: newMetrics(nil),
+				logger:  log.NewNopLogger(),
 			}
 
-			ok, err := service.Validate(context.Background(), "test")
+			ok, err := service.Validate(context.Background(), "test", "127.0.0.1")
 			assert.Equal(t, tt.expected, ok)
 			assert.Equal(t, tt.expectedErr, err)
 		})
@@ -84,7 +101,9 @@ var _ store = new(fakeStore)
 type fakeStore struct {
 	ExpectedErr         error
 	ExpectedCount       int64
+	ExpectedLastAttempt int64
 	ExpectedDeletedRows int64
+	ExpectedGroups      []*LoginAttemptGroup
 }
 
 func (f fakeStore) GetUserLoginAttemptCount(ctx context.Context, query GetUserLoginAttemptCountQuery) (int64, error) {
@@ -102,3 +121,162 @@ func (f fakeStore) DeleteOldLoginAttempts(ctx context.Context, command DeleteOld
 func (f fakeStore) DeleteLoginAttempts(ctx context.Context, cmd DeleteLoginAttemptsCommand) error {
 	return f.ExpectedErr
 }
+
+func (f fakeStore) GetLoginAttemptStats(ctx context.Context, query GetLoginAttemptStatsQuery) (LoginAttemptStats, error) {
+	return LoginAttemptStats{Count: f.ExpectedCount, LastAttempt: f.ExpectedLastAttempt}, f.ExpectedErr
+}
+
+func (f fakeStore) GetLoginAttemptGroups(ctx context.Context, query GetLoginAttemptGroupsQuery) ([]*LoginAttemptGroup, error) {
+	return f.ExpectedGroups, f.ExpectedErr
+}
+
+func TestService_lockedUntil(t *testing.T) {
+	cfg := setting.NewCfg()
+	cfg.BruteForceLoginProtection.Backoff = 30 * time.Second
+	service := &Service{cfg: cfg}
+	l := limit{lockoutType: loginattempt.LockoutTypeUsername, maxAttempts: 5, window: 5 * time.Minute}
+	last := time.Unix(1700000000, 0)
+
+	assert.True(t, service.lockedUntil(l, 4, last.Unix()).IsZero())
+	assert.Equal(t, last.Add(30*time.Second), service.lockedUntil(l, 5, last.Unix()))
+	assert.Equal(t, last.Add(time.Minute), service.lockedUntil(l, 6, last.Unix()))
+	assert.Equal(t, last.Add(4*time.Minute), service.lockedUntil(l, 8, last.Unix()))
+	// the lockout is capped at the window
+	assert.Equal(t, last.Add(5*time.Minute), service.lockedUntil(l, 9, last.Unix()))
+	assert.Equal(t, last.Add(5*time.Minute), service.lockedUntil(l, 1000, last.Unix()))
+
+	cfg.BruteForceLoginProtection.Backoff = 0
+	assert.Equal(t, last.Add(5*time.Minute), service.lockedUntil(l, 5, last.Unix()))
+}
+
+func TestService_ClientIP(t *testing.T) {
+	service := &Service{
+		trustedProxies: parseTrustedProxies(log.NewNopLogger(), []string{"10.0.0.1", "192.168.0.0/16", "::1", "invalid"}),
+	}
+	require.Len(t, service.trustedProxies, 3)
+
+	testCases := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		expected   string
+	}{
+		{
+			name:       "the connection address is used without forwarded headers",
+			remoteAddr: "203.0.113.7:51234",
+			expected:   "203.0.113.7",
+		},
+		{
+			name:       "the forwarded headers of an untrusted connection are ignored",
+			remoteAddr: "203.0.113.7:51234",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Real-IP": "198.51.100.2"},
+			expected:   "203.0.113.7",
+		},
+		{
+			name:       "the forwarded address is used behind a trusted proxy",
+			remoteAddr: "10.0.0.1:51234",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			expected:   "198.51.100.1",
+		},
+		{
+			name:       "the addresses set by the client before the trusted proxies are ignored",
+			remoteAddr: "10.0.0.1:51234",
+			headers:    map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.1, 192.168.1.1"},
+			expected:   "198.51.100.1",
+		},
+		{
+			name:       "the real IP header is used behind a trusted proxy without forwarded for header",
+			remoteAddr: "[::1]:51234",
+			headers:    map[string]string{"X-Real-IP": "198.51.100.2"},
+			expected:   "198.51.100.2",
+		},
+		{
+			name:       "the connection address is used when the forwarded for header has only trusted proxies",
+			remoteAddr: "10.0.0.1:51234",
+			headers:    map[string]string{"X-Forwarded-For": "192.168.1.1"},
+			expected:   "10.0.0.1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/login", nil)
+			req.RemoteAddr = tc.remoteAddr
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			assert.Equal(t, tc.expected, service.ClientIP(req))
+		})
+	}
+}
+
+func TestIntegrationService_ValidateLimits(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	setup := func(t *testing.T, settings setting.BruteForceLoginProtectionSettings) *Service {
+		cfg := setting.NewCfg()
+		cfg.BruteForceLoginProtection = settings
+		return &Service{
+			store:   &xormStore{db: db.InitTestDB(t), now: time.Now},
+			cfg:     cfg,
+			metrics: newMetrics(nil),
+			logger:  log.NewNopLogger(),
+		}
+	}
+	ctx := context.Background()
+
+	t.Run("should block an IP address spraying passwords across usernames", func(t *testing.T) {
+		s := setup(t, setting.BruteForceLoginProtectionSettings{
+			MaxAttempts: 5, Window: time.Minute,
+			IPMaxAttempts: 3, IPWindow: time.Minute,
+		})
+		for _, username := range []string{"a", "b", "c"} {
+			require.NoError(t, s.Add(ctx, username, "10.0.0.1"))
+		}
+
+		ok, err := s.Validate(ctx, "d", "10.0.0.1")
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		ok, err = s.Validate(ctx, "d", "10.0.0.2")
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		lockouts, err := s.GetLockouts(ctx)
+		require.NoError(t, err)
+		require.Len(t, lockouts, 1)
+		assert.Equal(t, loginattempt.LockoutTypeIP, lockouts[0].Type)
+		assert.Equal(t, "10.0.0.1", lockouts[0].IPAddress)
+		assert.Equal(t, int64(3), lockouts[0].Attempts)
+
+		require.NoError(t, s.ClearLockouts(ctx, "", "10.0.0.1"))
+		ok, err = s.Validate(ctx, "d", "10.0.0.1")
+		require.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("should block an IP address for a username without blocking the username", func(t *testing.T) {
+		s := setup(t, setting.BruteForceLoginProtectionSettings{
+			IPUsernameMaxAttempts: 2, IPUsernameWindow: time.Minute,
+		})
+		require.NoError(t, s.Add(ctx, "admin", "10.0.0.1"))
+		require.NoError(t, s.Add(ctx, "admin", "10.0.0.1"))
+
+		ok, err := s.Validate(ctx, "admin", "10.0.0.1")
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		ok, err = s.Validate(ctx, "admin", "10.0.0.2")
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		lockouts, err := s.GetLockouts(ctx)
+		require.NoError(t, err)
+		require.Len(t, lockouts, 1)
+		assert.Equal(t, loginattempt.LockoutTypeIPUsername, lockouts[0].Type)
+		assert.Equal(t, "admin", lockouts[0].Username)
+		assert.Equal(t, "10.0.0.1", lockouts[0].IPAddress)
+	})
+}
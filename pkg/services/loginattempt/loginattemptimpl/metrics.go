@@ -0,0 +1,31 @@
+package loginattemptimpl
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsSubSystem = "login_attempt"
+	metricsNamespace = "grafana"
+)
+
+type metrics struct {
+	blockedAttempts *prometheus.CounterVec
+}
+
+func newMetrics(reg prometheus.Registerer) *metrics {
+	m := &metrics{
+		blockedAttempts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubSystem,
+			Name:      "blocked_total",
+			Help:      "Number of login attempts blocked by the brute force login protection",
+		}, []string{"limit"}),
+	}
+
+	if reg != nil {
+		reg.MustRegister(m.blockedAttempts)
+	}
+
+	return m
+}
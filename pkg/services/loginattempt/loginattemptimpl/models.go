@@ -14,10 +14,40 @@ type GetUserLoginAttemptCountQuery struct {
 	Since    time.Time
 }
 
+// GetLoginAttemptStatsQuery filters on the username and the IP address when they are set
+type GetLoginAttemptStatsQuery struct {
+	Username  string
+	IpAddress string
+	Since     time.Time
+}
+
+type LoginAttemptStats struct {
+	Count       int64 `xorm:"count"`
+	LastAttempt int64 `xorm:"last_attempt"`
+}
+
+// GetLoginAttemptGroupsQuery returns the attempts grouped by the username, the IP address or both,
+// for groups with at least MinCount attempts.
+type GetLoginAttemptGroupsQuery struct {
+	ByUsername  bool
+	ByIpAddress bool
+	Since       time.Time
+	MinCount    int64
+}
+
+type LoginAttemptGroup struct {
+	Username    string `xorm:"username"`
+	IpAddress   string `xorm:"ip_address"`
+	Count       int64  `xorm:"count"`
+	LastAttempt int64  `xorm:"last_attempt"`
+}
+
 type DeleteOldLoginAttemptsCommand struct {
 	OlderThan time.Time
 }
 
+// DeleteLoginAttemptsCommand deletes the attempts matching the username and the IP address when they are set
 type DeleteLoginAttemptsCommand struct {
-	Username string
+	Username  string
+	IpAddress string
 }
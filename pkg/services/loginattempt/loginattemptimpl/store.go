@@ -2,6 +2,8 @@ package loginattemptimpl
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/grafana/grafana/pkg/infra/db"
@@ -18,6 +20,8 @@ type store interface {
 	DeleteOldLoginAttempts(ctx context.Context, cmd DeleteOldLoginAttemptsCommand) (int64, error)
 	DeleteLoginAttempts(ctx context.Context, cmd DeleteLoginAttemptsCommand) error
 	GetUserLoginAttemptCount(ctx context.Context, query GetUserLoginAttemptCountQuery) (int64, error)
+	GetLoginAttemptStats(ctx context.Context, query GetLoginAttemptStatsQuery) (LoginAttemptStats, error)
+	GetLoginAttemptGroups(ctx context.Context, query GetLoginAttemptGroupsQuery) ([]*LoginAttemptGroup, error)
 }
 
 func (xs *xormStore) CreateLoginAttempt(ctx context.Context, cmd CreateLoginAttemptCommand) (result loginattempt.LoginAttempt, err error) {
@@ -57,8 +61,12 @@ func (xs *xormStore) DeleteOldLoginAttempts(ctx context.Context, cmd DeleteOldLo
 }
 
 func (xs *xormStore) DeleteLoginAttempts(ctx context.Context, cmd DeleteLoginAttemptsCommand) error {
+	where, args := loginAttemptFilter(cmd.Username, cmd.IpAddress)
+	if len(args) == 0 {
+		return errors.New("username or ip address required")
+	}
 	return xs.db.WithDbSession(ctx, func(sess *db.Session) error {
-		_, err := sess.Exec("DELETE FROM login_attempt WHERE username = ?", cmd.Username)
+		_, err := sess.Exec(append([]any{"DELETE FROM login_attempt WHERE " + strings.Join(where, " AND ")}, args...)...)
 		return err
 	})
 }
@@ -82,3 +90,53 @@ func (xs *xormStore) GetUserLoginAttemptCount(ctx context.Context, query GetUser
 
 	return total, err
 }
+
+func (xs *xormStore) GetLoginAttemptStats(ctx context.Context, query GetLoginAttemptStatsQuery) (LoginAttemptStats, error) {
+	var stats LoginAttemptStats
+	err := xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		where, args := loginAttemptFilter(query.Username, query.IpAddress)
+		where = append(where, "created >= ?")
+		args = append(args, query.Since.Unix())
+
+		_, err := sess.SQL("SELECT COUNT(*) AS count, COALESCE(MAX(created), 0) AS last_attempt FROM login_attempt WHERE "+
+			strings.Join(where, " AND "), args...).Get(&stats)
+		return err
+	})
+	return stats, err
+}
+
+func (xs *xormStore) GetLoginAttemptGroups(ctx context.Context, query GetLoginAttemptGroupsQuery) ([]*LoginAttemptGroup, error) {
+	var columns []string
+	if query.ByUsername {
+		columns = append(columns, "username")
+	}
+	if query.ByIpAddress {
+		columns = append(columns, "ip_address")
+	}
+	if len(columns) == 0 {
+		return nil, errors.New("username or ip address grouping required")
+	}
+
+	groups := make([]*LoginAttemptGroup, 0)
+	err := xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		groupBy := strings.Join(columns, ", ")
+		sql := "SELECT " + groupBy + ", COUNT(*) AS count, MAX(created) AS last_attempt FROM login_attempt" +
+			" WHERE created >= ? GROUP BY " + groupBy + " HAVING COUNT(*) >= ?"
+		return sess.SQL(sql, query.Since.Unix(), query.MinCount).Find(&groups)
+	})
+	return groups, err
+}
+
+func loginAttemptFilter(username, ipAddress string) ([]string, []any) {
+	var where []string
+	var args []any
+	if username != "" {
+		where = append(where, "username = ?")
+		args = append(args, username)
+	}
+	if ipAddress != "" {
+		where = append(where, "ip_address = ?")
+		args = append(args, ipAddress)
+	}
+	return where, args
+}
@@ -2,6 +2,7 @@ package loginattempttest
 
 import (
 	"context"
+	"net/http"
 
 	"github.com/grafana/grafana/pkg/services/loginattempt"
 )
@@ -21,6 +22,10 @@ func (f FakeLoginAttemptService) Reset(ctx context.Context, username string) err
 	return f.ExpectedErr
 }
 
-func (f FakeLoginAttemptService) Validate(ctx context.Context, username string) (bool, error) {
+func (f FakeLoginAttemptService) Validate(ctx context.Context, username, IPAddress string) (bool, error) {
 	return f.ExpectedValid, f.ExpectedErr
 }
+
+func (f FakeLoginAttemptService) ClientIP(req *http.Request) string {
+	return loginattempt.ClientIP(req, nil)
+}
@@ -2,6 +2,7 @@ package loginattempttest
 
 import (
 	"context"
+	"net/http"
 
 	"github.com/grafana/grafana/pkg/services/loginattempt"
 )
@@ -27,7 +28,11 @@ func (f *MockLoginAttemptService) Reset(ctx context.Context, username string) er
 	return f.ExpectedErr
 }
 
-func (f *MockLoginAttemptService) Validate(ctx context.Context, username string) (bool, error) {
+func (f *MockLoginAttemptService) Validate(ctx context.Context, username, IPAddress string) (bool, error) {
 	f.ValidateCalled = true
 	return f.ExpectedValid, f.ExpectedErr
 }
+
+func (f *MockLoginAttemptService) ClientIP(req *http.Request) string {
+	return loginattempt.ClientIP(req, nil)
+}
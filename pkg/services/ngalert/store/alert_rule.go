@@ -15,6 +15,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/search/model"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
 	"github.com/grafana/grafana/pkg/services/sqlstore/searchstore"
+	entitystore "github.com/grafana/grafana/pkg/services/store"
 	"github.com/grafana/grafana/pkg/services/store/entity"
 	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/grafana/grafana/pkg/util"
@@ -65,6 +66,14 @@ func (st DBstore) DeleteAlertRulesByUID(ctx context.Context, orgID int64, ruleUI
 			return err
 		}
 		logger.Debug("Deleted alert instances", "count", rows)
+
+		if entitystore.EntityEventsEnabled(st.FeatureToggles) {
+			for _, uid := range ruleUID {
+				if _, err := sess.Insert(entitystore.NewDatabaseEntityEvent(uid, orgID, entitystore.EntityTypeAlertRule, entitystore.EntityEventTypeDelete)); err != nil {
+					return err
+				}
+			}
+		}
 		return nil
 	})
 }
@@ -167,6 +176,11 @@ func (st DBstore) InsertAlertRules(ctx context.Context, rules []ngmodels.AlertRu
 					return fmt.Errorf("failed to create new rules: %w", err)
 				}
 				ids[newRules[i].UID] = newRules[i].ID
+				if entitystore.EntityEventsEnabled(st.FeatureToggles) {
+					if _, err := sess.Insert(entitystore.NewDatabaseEntityEvent(newRules[i].UID, newRules[i].OrgID, entitystore.EntityTypeAlertRule, entitystore.EntityEventTypeCreate)); err != nil {
+						return err
+					}
+				}
 			}
 		}
 
@@ -208,6 +222,11 @@ func (st DBstore) UpdateAlertRules(ctx context.Context, rules []ngmodels.UpdateR
 				}
 				return fmt.Errorf("%w: alert rule UID %s version %d", ErrOptimisticLock, r.New.UID, r.New.Version)
 			}
+			if entitystore.EntityEventsEnabled(st.FeatureToggles) {
+				if _, err := sess.Insert(entitystore.NewDatabaseEntityEvent(r.New.UID, r.New.OrgID, entitystore.EntityTypeAlertRule, entitystore.EntityEventTypeUpdate)); err != nil {
+					return err
+				}
+			}
 			parentVersion = r.Existing.Version
 			ruleVersions = append(ruleVersions, ngmodels.AlertRuleVersion{
 				RuleOrgID:        r.New.OrgID,
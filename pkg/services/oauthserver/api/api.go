@@ -1,9 +1,14 @@
 package api
 
 import (
+	"net/http"
+
+	"github.com/grafana/grafana/pkg/api/response"
 	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/middleware"
 	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
 	"github.com/grafana/grafana/pkg/services/oauthserver"
+	"github.com/grafana/grafana/pkg/web"
 )
 
 type api struct {
@@ -23,9 +28,33 @@ func NewAPI(
 
 func (a *api) RegisterAPIEndpoints() {
 	a.router.Group("/oauth2", func(oauthRouter routing.RouteRegister) {
+		oauthRouter.Get("/authorize", middleware.ReqSignedIn, a.handleAuthorizeRequest)
+		oauthRouter.Post("/authorize", middleware.ReqSignedIn, a.handleAuthorizeRequest)
 		oauthRouter.Post("/introspect", a.handleIntrospectionRequest)
 		oauthRouter.Post("/token", a.handleTokenRequest)
 	})
+	// Registration of clients that are not plugins, ex: CLIs or external apps acting on behalf of users
+	a.router.Put("/api/oauth2/clients", middleware.ReqGrafanaAdmin, routing.Wrap(a.handleSaveExternalService))
+}
+
+func (a *api) handleSaveExternalService(c *contextmodel.ReqContext) response.Response {
+	registration := oauthserver.ExternalServiceRegistration{}
+	if err := web.Bind(c.Req, &registration); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	if registration.Key == nil {
+		registration.Key = &oauthserver.KeyOption{Generate: true}
+	}
+
+	dto, err := a.oauthServer.SaveExternalService(c.Req.Context(), &registration)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "failed to save the client", err)
+	}
+	return response.JSON(http.StatusOK, dto)
+}
+
+func (a *api) handleAuthorizeRequest(c *contextmodel.ReqContext) {
+	a.oauthServer.HandleAuthorizeRequest(c.Resp, c.Req, c.SignedInUser)
 }
 
 func (a *api) handleTokenRequest(c *contextmodel.ReqContext) {
@@ -17,6 +17,10 @@ var (
 	ErrClientRequiredName = errutil.BadRequest(
 		"oauthserver.required-client-name",
 		errutil.WithPublicMessage("client name is required")).Errorf("Client name is required")
+	ErrClientRequiredRedirectURI = errutil.BadRequest(
+		"oauthserver.required-redirect-uri",
+		errutil.WithPublicMessage("redirect URI is required for delegation")).Errorf("Redirect URI is required for delegation")
+	ErrSessionNotFound = errutil.NotFound("oauthserver.session-not-found")
 )
 
 func ErrClientNotFound(clientID string) error {
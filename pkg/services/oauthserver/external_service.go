@@ -22,9 +22,10 @@ type ExternalServiceDTO struct {
 	Name        string     `json:"name"`
 	ID          string     `json:"clientId"`
 	Secret      string     `json:"clientSecret"`
-	RedirectURI string     `json:"redirectUri,omitempty"` // Not used yet (code flow)
-	GrantTypes  string     `json:"grantTypes"`            // CSV value
-	Audiences   string     `json:"audiences"`             // CSV value
+	RedirectURI string     `json:"redirectUri,omitempty"`
+	GrantTypes  string     `json:"grantTypes"` // CSV value
+	Audiences   string     `json:"audiences"`  // CSV value
+	Public      bool       `json:"public,omitempty"`
 	KeyResult   *KeyResult `json:"key,omitempty"`
 }
 
@@ -33,11 +34,12 @@ type ExternalService struct {
 	Name             string `xorm:"name"`
 	ClientID         string `xorm:"client_id"`
 	Secret           string `xorm:"secret"`
-	RedirectURI      string `xorm:"redirect_uri"` // Not used yet (code flow)
+	RedirectURI      string `xorm:"redirect_uri"` // Used in the authorization code flow
 	GrantTypes       string `xorm:"grant_types"`  // CSV value
 	Audiences        string `xorm:"audiences"`    // CSV value
 	PublicPem        []byte `xorm:"public_pem"`
 	ServiceAccountID int64  `xorm:"service_account_id"`
+	Public           bool   `xorm:"is_public"`
 	// SelfPermissions are the registered service account permissions (registered and managed permissions)
 	SelfPermissions []ac.Permission
 	// ImpersonatePermissions is the restriction set of permissions while impersonating
@@ -57,6 +59,7 @@ func (c *ExternalService) ToDTO() *ExternalServiceDTO {
 		GrantTypes:  c.GrantTypes,
 		Audiences:   c.Audiences,
 		RedirectURI: c.RedirectURI,
+		Public:      c.Public,
 	}
 	if len(c.PublicPem) > 0 {
 		c2.KeyResult = &KeyResult{PublicPem: string(c.PublicPem)}
@@ -109,6 +112,14 @@ func (c *ExternalService) GetScopes() fosite.Arguments {
 		}
 	}
 
+	// Add the scopes the client is allowed to request on behalf of users in the authorization code flow
+	if c.GetGrantTypes().Has(string(fosite.GrantTypeAuthorizationCode)) {
+		ret = append(ret, c.getDelegatedScopes()...)
+		if c.GetGrantTypes().Has(string(fosite.GrantTypeRefreshToken)) {
+			ret = append(ret, ScopeOfflineAccess)
+		}
+	}
+
 	c.Scopes = ret
 	return ret
 }
@@ -121,6 +132,11 @@ func (c *ExternalService) GetScopesOnUser(ctx context.Context, accessControl ac.
 		return nil
 	}
 
+	return c.getDelegatedScopes()
+}
+
+// getDelegatedScopes returns the scopes this client is allowed to request when acting on behalf of a user.
+func (c *ExternalService) getDelegatedScopes() []string {
 	if c.ImpersonateScopes != nil {
 		return c.ImpersonateScopes
 	}
@@ -152,7 +168,7 @@ func (c *ExternalService) GetScopesOnUser(ctx context.Context, accessControl ac.
 
 // IsPublic returns true, if this client is marked as public.
 func (c *ExternalService) IsPublic() bool {
-	return false
+	return c.Public
 }
 
 // GetAudience returns the allowed audience(s) for this client.
@@ -3,8 +3,10 @@ package oauthserver
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/auth/identity"
 	"gopkg.in/square/go-jose.v2"
 )
 
@@ -19,6 +21,9 @@ const (
 	ScopeGlobalUsersSelf = "global.users:self"
 	ScopeTeamsSelf       = "teams:self"
 
+	// ScopeOfflineAccess is the OAuth2 scope a client requests to obtain a refresh token in the authorization code flow.
+	ScopeOfflineAccess = "offline_access"
+
 	// Supported encryptions
 	RS256 = "RS256"
 	ES256 = "ES256"
@@ -34,8 +39,11 @@ type OAuth2Server interface {
 	// SignedInUser from the associated service account.
 	GetExternalService(ctx context.Context, id string) (*ExternalService, error)
 
+	// HandleAuthorizeRequest handles the authorization code flow's authorization request of the signed in user.
+	// It renders the consent page and, once the user approved it, redirects back to the client with an authorization code.
+	HandleAuthorizeRequest(rw http.ResponseWriter, req *http.Request, signedInUser identity.Requester)
 	// HandleTokenRequest handles the client's OAuth2 query to obtain an access_token by presenting its authorization
-	// grant (ex: client_credentials, jwtbearer).
+	// grant (ex: client_credentials, jwtbearer, authorization_code, refresh_token).
 	HandleTokenRequest(rw http.ResponseWriter, req *http.Request)
 	// HandleIntrospectionRequest handles the OAuth2 query to determine the active state of an OAuth 2.0 token and
 	// to determine meta-information about this token.
@@ -52,6 +60,45 @@ type Store interface {
 	GetExternalServicePublicKey(ctx context.Context, clientID string) (*jose.JSONWebKey, error)
 }
 
+// SessionKind is the kind of grant persisted by the SessionStore.
+type SessionKind string
+
+const (
+	AuthorizeCodeSession SessionKind = "authorize_code"
+	RefreshTokenSession  SessionKind = "refresh_token"
+	PKCESession          SessionKind = "pkce"
+)
+
+// Session is an authorization code, a refresh token or a PKCE challenge issued during the authorization code flow.
+// The sessions are persisted so that they survive restarts and can be redeemed on any instance.
+type Session struct {
+	ID                int64       `xorm:"id"`
+	Kind              SessionKind `xorm:"kind"`
+	Signature         string      `xorm:"signature"`
+	RequestID         string      `xorm:"request_id"`
+	ClientID          string      `xorm:"client_id"`
+	RequestedAt       time.Time   `xorm:"requested_at"`
+	Scopes            string      `xorm:"scopes"`
+	GrantedScopes     string      `xorm:"granted_scopes"`
+	RequestedAudience string      `xorm:"requested_audience"`
+	GrantedAudience   string      `xorm:"granted_audience"`
+	Form              string      `xorm:"form_data"`
+	Data              string      `xorm:"session_data"`
+	Active            bool        `xorm:"active"`
+	// Expires is the unix time at which the session expires, 0 if it never expires
+	Expires int64 `xorm:"expires"`
+}
+
+type SessionStore interface {
+	CreateSession(ctx context.Context, session *Session) error
+	GetSession(ctx context.Context, kind SessionKind, signature string) (*Session, error)
+	DeleteSession(ctx context.Context, kind SessionKind, signature string) error
+	// DeactivateSession keeps the session so that a reuse of the authorization code or the refresh token is detected
+	DeactivateSession(ctx context.Context, kind SessionKind, signature string) error
+	DeactivateSessionsByRequestID(ctx context.Context, kind SessionKind, requestID string) error
+	DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error)
+}
+
 type KeyOption struct {
 	// URL       string `json:"url,omitempty"` // TODO allow specifying a URL (to a .jwks file) to fetch the key from
 	// PublicPEM contains the Base64 encoded public key in PEM format
@@ -76,14 +123,31 @@ type ImpersonationCfg struct {
 	Permissions []accesscontrol.Permission `json:"permissions,omitempty"`
 }
 
+type DelegationCfg struct {
+	// Enabled allows the service to act on behalf of users who consented to it using the authorization_code grant
+	// with PKCE. Refresh tokens are issued when the offline_access scope is granted.
+	Enabled bool `json:"enabled"`
+	// Public marks the service as a public client (ex: a CLI) that cannot keep its secret confidential.
+	// Public clients are not authenticated at the token endpoint, PKCE protects the code exchange.
+	Public bool `json:"public"`
+	// Groups allows the service to list the user's teams
+	Groups bool `json:"groups"`
+	// Permissions are the permissions that the external service needs when acting on behalf of a user.
+	// They are merged with the impersonation permissions; the intersection of this set with the user's permissions
+	// guarantees that the client will not gain more privileges than the user has.
+	Permissions []accesscontrol.Permission `json:"permissions,omitempty"`
+}
+
 // ExternalServiceRegistration represents the registration form to save new OAuth2 client.
 type ExternalServiceRegistration struct {
 	Name string `json:"name"`
 	// RedirectURI is the URI that is used in the code flow.
-	// Note that this is not used yet.
+	// It is required when delegation is enabled.
 	RedirectURI *string `json:"redirectUri,omitempty"`
 	// Impersonation access configuration
 	Impersonation ImpersonationCfg `json:"impersonation"`
+	// Delegation access configuration
+	Delegation DelegationCfg `json:"delegation"`
 	// Self access configuration
 	Self SelfCfg `json:"self"`
 	// Key is the option to specify a public key or ask the server to generate a crypto key pair.
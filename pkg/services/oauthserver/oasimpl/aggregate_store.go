@@ -2,13 +2,20 @@ package oasimpl
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/ory/fosite"
 	"github.com/ory/fosite/handler/oauth2"
+	"github.com/ory/fosite/handler/pkce"
 	"github.com/ory/fosite/handler/rfc7523"
 	"gopkg.in/square/go-jose.v2"
 
+	"github.com/grafana/grafana/pkg/services/oauthserver"
 	"github.com/grafana/grafana/pkg/services/oauthserver/utils"
 )
 
@@ -18,6 +25,7 @@ var _ oauth2.AccessTokenStorage = &OAuth2ServiceImpl{}
 var _ oauth2.RefreshTokenStorage = &OAuth2ServiceImpl{}
 var _ rfc7523.RFC7523KeyStorage = &OAuth2ServiceImpl{}
 var _ oauth2.TokenRevocationStorage = &OAuth2ServiceImpl{}
+var _ pkce.PKCERequestStorage = &OAuth2ServiceImpl{}
 
 // GetClient loads the client by its ID or returns an error
 // if the client does not exist or another error occurred.
@@ -41,7 +49,7 @@ func (s *OAuth2ServiceImpl) SetClientAssertionJWT(ctx context.Context, jti strin
 
 // GetAuthorizeCodeSession stores the authorization request for a given authorization code.
 func (s *OAuth2ServiceImpl) CreateAuthorizeCodeSession(ctx context.Context, code string, request fosite.Requester) (err error) {
-	return s.memstore.CreateAuthorizeCodeSession(ctx, code, request)
+	return s.createSession(ctx, oauthserver.AuthorizeCodeSession, fosite.AuthorizeCode, code, request)
 }
 
 // GetAuthorizeCodeSession hydrates the session based on the given code and returns the authorization request.
@@ -50,14 +58,27 @@ func (s *OAuth2ServiceImpl) CreateAuthorizeCodeSession(ctx context.Context, code
 //
 // Make sure to also return the fosite.Requester value when returning the fosite.ErrInvalidatedAuthorizeCode error!
 func (s *OAuth2ServiceImpl) GetAuthorizeCodeSession(ctx context.Context, code string, session fosite.Session) (request fosite.Requester, err error) {
-	return s.memstore.GetAuthorizeCodeSession(ctx, code, session)
+	request, active, err := s.getSession(ctx, oauthserver.AuthorizeCodeSession, code, session)
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return request, fosite.ErrInvalidatedAuthorizeCode
+	}
+	return request, nil
 }
 
 // InvalidateAuthorizeCodeSession is called when an authorize code is being used. The state of the authorization
 // code should be set to invalid and consecutive requests to GetAuthorizeCodeSession should return the
 // ErrInvalidatedAuthorizeCode error.
 func (s *OAuth2ServiceImpl) InvalidateAuthorizeCodeSession(ctx context.Context, code string) (err error) {
-	return s.memstore.InvalidateAuthorizeCodeSession(ctx, code)
+	if err := s.sessionStore.DeactivateSession(ctx, oauthserver.AuthorizeCodeSession, code); err != nil {
+		if errors.Is(err, oauthserver.ErrSessionNotFound) {
+			return fosite.ErrNotFound
+		}
+		return err
+	}
+	return nil
 }
 
 func (s *OAuth2ServiceImpl) CreateAccessTokenSession(ctx context.Context, signature string, request fosite.Requester) (err error) {
@@ -73,15 +94,43 @@ func (s *OAuth2ServiceImpl) DeleteAccessTokenSession(ctx context.Context, signat
 }
 
 func (s *OAuth2ServiceImpl) CreateRefreshTokenSession(ctx context.Context, signature string, request fosite.Requester) (err error) {
-	return s.memstore.CreateRefreshTokenSession(ctx, signature, request)
+	return s.createSession(ctx, oauthserver.RefreshTokenSession, fosite.RefreshToken, signature, request)
 }
 
+// GetRefreshTokenSession returns the request of the refresh token, or the fosite.ErrInactiveToken error along with the
+// request if the refresh token has been revoked so that fosite can detect its reuse.
 func (s *OAuth2ServiceImpl) GetRefreshTokenSession(ctx context.Context, signature string, session fosite.Session) (request fosite.Requester, err error) {
-	return s.memstore.GetRefreshTokenSession(ctx, signature, session)
+	request, active, err := s.getSession(ctx, oauthserver.RefreshTokenSession, signature, session)
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return request, fosite.ErrInactiveToken
+	}
+	return request, nil
 }
 
 func (s *OAuth2ServiceImpl) DeleteRefreshTokenSession(ctx context.Context, signature string) (err error) {
-	return s.memstore.DeleteRefreshTokenSession(ctx, signature)
+	return s.sessionStore.DeleteSession(ctx, oauthserver.RefreshTokenSession, signature)
+}
+
+// CreatePKCERequestSession stores the PKCE challenge of the authorization request for a given authorization code.
+func (s *OAuth2ServiceImpl) CreatePKCERequestSession(ctx context.Context, signature string, requester fosite.Requester) error {
+	return s.createSession(ctx, oauthserver.PKCESession, fosite.AuthorizeCode, signature, requester)
+}
+
+// GetPKCERequestSession returns the authorization request holding the PKCE challenge for a given authorization code.
+func (s *OAuth2ServiceImpl) GetPKCERequestSession(ctx context.Context, signature string, session fosite.Session) (fosite.Requester, error) {
+	request, _, err := s.getSession(ctx, oauthserver.PKCESession, signature, session)
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// DeletePKCERequestSession removes the PKCE challenge once the authorization code has been exchanged.
+func (s *OAuth2ServiceImpl) DeletePKCERequestSession(ctx context.Context, signature string) error {
+	return s.sessionStore.DeleteSession(ctx, oauthserver.PKCESession, signature)
 }
 
 // RevokeRefreshToken revokes a refresh token as specified in:
@@ -92,7 +141,7 @@ func (s *OAuth2ServiceImpl) DeleteRefreshTokenSession(ctx context.Context, signa
 // also invalidate all access tokens based on the same authorization
 // grant (see Implementation Note).
 func (s *OAuth2ServiceImpl) RevokeRefreshToken(ctx context.Context, requestID string) error {
-	return s.memstore.RevokeRefreshToken(ctx, requestID)
+	return s.sessionStore.DeactivateSessionsByRequestID(ctx, oauthserver.RefreshTokenSession, requestID)
 }
 
 // RevokeRefreshTokenMaybeGracePeriod revokes a refresh token as specified in:
@@ -106,7 +155,8 @@ func (s *OAuth2ServiceImpl) RevokeRefreshToken(ctx context.Context, requestID st
 // If the Refresh Token grace period is greater than zero in configuration the token
 // will have its expiration time set as UTCNow + GracePeriod.
 func (s *OAuth2ServiceImpl) RevokeRefreshTokenMaybeGracePeriod(ctx context.Context, requestID string, signature string) error {
-	return s.memstore.RevokeRefreshTokenMaybeGracePeriod(ctx, requestID, signature)
+	// no grace period is configured, the refresh token is revoked right away
+	return s.RevokeRefreshToken(ctx, requestID)
 }
 
 // RevokeAccessToken revokes an access token as specified in:
@@ -160,3 +210,83 @@ func (s *OAuth2ServiceImpl) IsJWTUsed(ctx context.Context, jti string) (bool, er
 func (s *OAuth2ServiceImpl) MarkJWTUsedForTime(ctx context.Context, jti string, exp time.Time) error {
 	return s.memstore.MarkJWTUsedForTime(ctx, jti, exp)
 }
+
+// sessionFormParameters are the parameters of the request form persisted with the sessions. fosite compares them when
+// the authorization code is exchanged, secrets such as the client secret or the refresh token are never persisted.
+var sessionFormParameters = []string{
+	"client_id", "redirect_uri", "response_type", "scope", "audience", "state", "code_challenge", "code_challenge_method",
+}
+
+// createSession persists the request of an authorization code, a refresh token or a PKCE challenge. The session
+// expires with the token of the given type.
+func (s *OAuth2ServiceImpl) createSession(ctx context.Context, kind oauthserver.SessionKind, tokenType fosite.TokenType, signature string, request fosite.Requester) error {
+	data, err := json.Marshal(request.GetSession())
+	if err != nil {
+		return err
+	}
+
+	var expires int64
+	if expiresAt := request.GetSession().GetExpiresAt(tokenType); !expiresAt.IsZero() {
+		expires = expiresAt.Unix()
+	}
+
+	return s.sessionStore.CreateSession(ctx, &oauthserver.Session{
+		Kind:              kind,
+		Signature:         signature,
+		RequestID:         request.GetID(),
+		ClientID:          request.GetClient().GetID(),
+		RequestedAt:       request.GetRequestedAt(),
+		Scopes:            strings.Join(request.GetRequestedScopes(), " "),
+		GrantedScopes:     strings.Join(request.GetGrantedScopes(), " "),
+		RequestedAudience: strings.Join(request.GetRequestedAudience(), " "),
+		GrantedAudience:   strings.Join(request.GetGrantedAudience(), " "),
+		Form:              request.Sanitize(sessionFormParameters).GetRequestForm().Encode(),
+		Data:              string(data),
+		Active:            true,
+		Expires:           expires,
+	})
+}
+
+// getSession hydrates the persisted request into the given session. The request is returned along with its active
+// state, fosite needs the request of a revoked authorization code or refresh token to revoke the tokens issued from it.
+func (s *OAuth2ServiceImpl) getSession(ctx context.Context, kind oauthserver.SessionKind, signature string, session fosite.Session) (fosite.Requester, bool, error) {
+	stored, err := s.sessionStore.GetSession(ctx, kind, signature)
+	if err != nil {
+		if errors.Is(err, oauthserver.ErrSessionNotFound) {
+			return nil, false, fosite.ErrNotFound
+		}
+		return nil, false, err
+	}
+
+	client, err := s.GetClient(ctx, stored.ClientID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// fosite passes the session of the request being handled, the stored session is decoded into a copy so that the
+	// claims already populated for that request are not overwritten
+	if session == nil {
+		session = NewAuthSession()
+	} else {
+		session = session.Clone()
+	}
+	if err := json.Unmarshal([]byte(stored.Data), session); err != nil {
+		return nil, false, fmt.Errorf("failed to decode the %s session: %w", kind, err)
+	}
+	form, err := url.ParseQuery(stored.Form)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to decode the %s request form: %w", kind, err)
+	}
+
+	return &fosite.Request{
+		ID:                stored.RequestID,
+		RequestedAt:       stored.RequestedAt,
+		Client:            client,
+		RequestedScope:    strings.Fields(stored.Scopes),
+		GrantedScope:      strings.Fields(stored.GrantedScopes),
+		RequestedAudience: strings.Fields(stored.RequestedAudience),
+		GrantedAudience:   strings.Fields(stored.GrantedAudience),
+		Form:              form,
+		Session:           session,
+	}, stored.Active, nil
+}
@@ -0,0 +1,137 @@
+package oasimpl
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"github.com/ory/fosite"
+
+	"github.com/grafana/grafana/pkg/services/auth/identity"
+	"github.com/grafana/grafana/pkg/services/oauthserver"
+)
+
+const (
+	consentApprove = "approve"
+	consentDeny    = "deny"
+)
+
+//go:embed templates/consent.html
+var consentPage string
+
+var consentTemplate = template.Must(template.New("consent").Parse(consentPage))
+
+type consentData struct {
+	ClientName string
+	UserLogin  string
+	Scopes     []string
+	Offline    bool
+	FormAction string
+}
+
+// HandleAuthorizeRequest handles the authorization code flow's authorization request of the signed in user.
+// A GET request renders the consent page, the consent page posts the user's decision back to the same URL.
+// Once the user approved the request, the user agent is redirected to the client with an authorization code.
+func (s *OAuth2ServiceImpl) HandleAuthorizeRequest(rw http.ResponseWriter, req *http.Request, signedInUser identity.Requester) {
+	ctx := req.Context()
+
+	// This validates the client, the redirect URI, the requested scopes and the PKCE challenge.
+	authorizeRequest, err := s.oauthProvider.NewAuthorizeRequest(ctx, req)
+	if err != nil {
+		s.writeAuthorizeError(ctx, rw, authorizeRequest, err)
+		return
+	}
+
+	client, err := s.GetExternalService(ctx, authorizeRequest.GetClient().GetID())
+	if err != nil || client == nil {
+		s.writeAuthorizeError(ctx, rw, authorizeRequest, fosite.ErrInvalidClient.WithHint("Could not find the requested client."))
+		return
+	}
+	if !client.GetGrantTypes().Has(string(fosite.GrantTypeAuthorizationCode)) {
+		s.writeAuthorizeError(ctx, rw, authorizeRequest, fosite.ErrUnauthorizedClient.WithHint("The client is not allowed to act on behalf of users."))
+		return
+	}
+
+	// Only users can delegate their access, other identities (ex: API keys) cannot consent
+	namespace, id := signedInUser.GetNamespacedID()
+	if namespace != identity.NamespaceUser {
+		s.writeAuthorizeError(ctx, rw, authorizeRequest, fosite.ErrAccessDenied.WithHint("Only users can grant access to a client."))
+		return
+	}
+	userID, err := identity.IntIdentifier(namespace, id)
+	if err != nil {
+		s.writeAuthorizeError(ctx, rw, authorizeRequest, fosite.ErrServerError.WithWrap(err))
+		return
+	}
+
+	if req.Method != http.MethodPost {
+		s.renderConsentPage(rw, req, client, signedInUser, authorizeRequest)
+		return
+	}
+
+	switch req.PostFormValue("consent") {
+	case consentApprove:
+	case consentDeny:
+		s.writeAuthorizeError(ctx, rw, authorizeRequest, fosite.ErrAccessDenied.WithHint("The user denied the request."))
+		return
+	default:
+		s.writeAuthorizeError(ctx, rw, authorizeRequest, fosite.ErrInvalidRequest.WithHint("The consent decision is missing."))
+		return
+	}
+
+	// The requested scopes have already been checked against the ones the client is allowed to request
+	for _, scope := range authorizeRequest.GetRequestedScopes() {
+		authorizeRequest.GrantScope(scope)
+	}
+	// All tokens we generate in this service should target Grafana's API.
+	authorizeRequest.GrantAudience(s.cfg.AppURL)
+
+	// The session is stored with the authorization code and restored when the code is exchanged
+	oauthSession := NewAuthSession()
+	oauthSession.Subject = fmt.Sprintf("%s:id:%d", identity.NamespaceUser, userID)
+	oauthSession.Username = signedInUser.GetLogin()
+
+	response, err := s.oauthProvider.NewAuthorizeResponse(ctx, authorizeRequest, oauthSession)
+	if err != nil {
+		s.writeAuthorizeError(ctx, rw, authorizeRequest, err)
+		return
+	}
+
+	s.logger.Debug("User granted access to client", "client", client.LogID(), "userID", userID, "scopes", authorizeRequest.GetGrantedScopes())
+	s.oauthProvider.WriteAuthorizeResponse(ctx, rw, authorizeRequest, response)
+}
+
+func (s *OAuth2ServiceImpl) renderConsentPage(rw http.ResponseWriter, req *http.Request, client *oauthserver.ExternalService,
+	signedInUser identity.Requester, authorizeRequest fosite.AuthorizeRequester) {
+	data := consentData{
+		ClientName: client.Name,
+		UserLogin:  signedInUser.GetLogin(),
+		Scopes:     []string{},
+		// The form is posted to the same URL so that the authorization request parameters are preserved
+		FormAction: req.URL.RequestURI(),
+	}
+	for _, scope := range authorizeRequest.GetRequestedScopes() {
+		if scope == oauthserver.ScopeOfflineAccess {
+			data.Offline = true
+			continue
+		}
+		data.Scopes = append(data.Scopes, scope)
+	}
+
+	rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+	// The consent page must not be framed by other sites
+	rw.Header().Set("X-Frame-Options", "deny")
+	rw.Header().Set("Cache-Control", "no-store")
+	rw.WriteHeader(http.StatusOK)
+	if err := consentTemplate.Execute(rw, data); err != nil {
+		s.logger.Error("Error rendering consent page", "client", client.LogID(), "error", err)
+	}
+}
+
+// writeAuthorizeError logs the error then uses fosite to write the error back to the user.
+func (s *OAuth2ServiceImpl) writeAuthorizeError(ctx context.Context, rw http.ResponseWriter, authorizeRequest fosite.AuthorizeRequester, err error) {
+	s.logger.Debug("Authorization request failed", "error", err)
+	s.oauthProvider.WriteAuthorizeError(ctx, rw, authorizeRequest, err)
+}
@@ -0,0 +1,292 @@
+package oasimpl
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ory/fosite"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	"github.com/grafana/grafana/pkg/services/oauthserver"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+const (
+	testRedirectURI  = "https://cli.test/callback"
+	testState        = "random-state-value"
+	testCodeVerifier = "a-code-verifier-that-is-long-enough-to-be-valid-0123456789"
+)
+
+func codeChallenge(verifier string) string {
+	hash := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+func authorizeParams(scope string) url.Values {
+	return url.Values{
+		"response_type":         {"code"},
+		"client_id":             {"CLIENT1ID"},
+		"redirect_uri":          {testRedirectURI},
+		"scope":                 {scope},
+		"state":                 {testState},
+		"code_challenge":        {codeChallenge(testCodeVerifier)},
+		"code_challenge_method": {"S256"},
+	}
+}
+
+// delegatedClient turns the test client into a public client allowed to act on behalf of users
+func delegatedClient(es *oauthserver.ExternalService) {
+	es.GrantTypes = strings.Join([]string{
+		string(fosite.GrantTypeAuthorizationCode), string(fosite.GrantTypeRefreshToken),
+	}, ",")
+	es.RedirectURI = testRedirectURI
+	es.Public = true
+}
+
+func TestOAuth2ServiceImpl_HandleAuthorizeRequest(t *testing.T) {
+	signedInUser := &user.SignedInUser{UserID: 56, OrgID: oauthserver.TmpOrgID, Login: "user56"}
+
+	tests := []struct {
+		name            string
+		tweakTestClient func(*oauthserver.ExternalService)
+		method          string
+		params          url.Values
+		form            url.Values
+		signedInUser    *user.SignedInUser
+		wantCode        int
+		wantError       string
+	}{
+		{
+			name:            "should render the consent page",
+			tweakTestClient: delegatedClient,
+			method:          http.MethodGet,
+			params:          authorizeParams("profile entitlements dashboards:read offline_access"),
+			signedInUser:    signedInUser,
+			wantCode:        http.StatusOK,
+		},
+		{
+			name:            "should redirect with an error when the user denies the request",
+			tweakTestClient: delegatedClient,
+			method:          http.MethodPost,
+			params:          authorizeParams("profile"),
+			form:            url.Values{"consent": {consentDeny}},
+			signedInUser:    signedInUser,
+			wantCode:        http.StatusSeeOther,
+			wantError:       "access_denied",
+		},
+		{
+			name:            "should redirect with an error when PKCE is missing",
+			tweakTestClient: delegatedClient,
+			method:          http.MethodPost,
+			params: func() url.Values {
+				params := authorizeParams("profile")
+				params.Del("code_challenge")
+				params.Del("code_challenge_method")
+				return params
+			}(),
+			form:         url.Values{"consent": {consentApprove}},
+			signedInUser: signedInUser,
+			wantCode:     http.StatusSeeOther,
+			wantError:    "invalid_request",
+		},
+		{
+			name:         "should redirect with an error when the client does not have the grant",
+			method:       http.MethodGet,
+			params:       authorizeParams("profile"),
+			signedInUser: signedInUser,
+			tweakTestClient: func(es *oauthserver.ExternalService) {
+				es.RedirectURI = testRedirectURI
+			},
+			wantCode:  http.StatusSeeOther,
+			wantError: "unauthorized_client",
+		},
+		{
+			name:            "should redirect with an error when the request is not made by a user",
+			tweakTestClient: delegatedClient,
+			method:          http.MethodGet,
+			params:          authorizeParams("profile"),
+			signedInUser:    &user.SignedInUser{ApiKeyID: 3, OrgID: oauthserver.TmpOrgID},
+			wantCode:        http.StatusSeeOther,
+			wantError:       "access_denied",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := setupTestEnv(t)
+			setupHandleTokenRequestEnv(t, env, tt.tweakTestClient)
+
+			resp := httptest.NewRecorder()
+			env.S.HandleAuthorizeRequest(resp, newAuthorizeRequest(tt.method, tt.params, tt.form), tt.signedInUser)
+
+			require.Equal(t, tt.wantCode, resp.Code)
+			if tt.wantError == "" {
+				require.Contains(t, resp.Body.String(), "client-1")
+				require.Contains(t, resp.Body.String(), "dashboards:read")
+				return
+			}
+			location, err := url.Parse(resp.Header().Get("Location"))
+			require.NoError(t, err)
+			require.Equal(t, tt.wantError, location.Query().Get("error"))
+		})
+	}
+}
+
+func TestOAuth2ServiceImpl_AuthorizationCodeFlow(t *testing.T) {
+	env := setupTestEnv(t)
+	setupHandleTokenRequestEnv(t, env, delegatedClient)
+	signedInUser := &user.SignedInUser{UserID: 56, OrgID: oauthserver.TmpOrgID, Login: "user56"}
+
+	// The authorization code cannot be exchanged without the PKCE verifier
+	_, status := requestToken(t, env, url.Values{
+		"grant_type":   {string(fosite.GrantTypeAuthorizationCode)},
+		"client_id":    {"CLIENT1ID"},
+		"code":         {authorize(t, env, signedInUser)},
+		"redirect_uri": {testRedirectURI},
+	})
+	require.Equal(t, http.StatusBadRequest, status)
+
+	code := authorize(t, env, signedInUser)
+	tokenResp, status := requestToken(t, env, url.Values{
+		"grant_type":    {string(fosite.GrantTypeAuthorizationCode)},
+		"client_id":     {"CLIENT1ID"},
+		"code":          {code},
+		"redirect_uri":  {testRedirectURI},
+		"code_verifier": {testCodeVerifier},
+	})
+	require.Equal(t, http.StatusOK, status)
+	require.ElementsMatch(t, []string{"profile", "entitlements", "dashboards:read", "offline_access"}, strings.Split(tokenResp.Scope, " "))
+	require.NotEmpty(t, tokenResp.RefreshToken)
+
+	wantClaims := &claims{
+		Claims: jwt.Claims{
+			Subject:  "user:id:56",
+			Issuer:   AppURL,
+			Audience: jwt.Audience{AppURL},
+		},
+		ClientID: "CLIENT1ID",
+		Name:     "User 56",
+		Login:    "user56",
+		Entitlements: map[string][]string{
+			// The client can only get the user's permissions it was allowed to request
+			"dashboards:read": {"folders:uid:UID1"},
+		},
+	}
+	require.Equal(t, wantClaims, parseAccessToken(t, tokenResp.AccessToken))
+
+	// The refresh token can be exchanged for a new access token on behalf of the user
+	refreshResp, status := requestToken(t, env, url.Values{
+		"grant_type":    {string(fosite.GrantTypeRefreshToken)},
+		"client_id":     {"CLIENT1ID"},
+		"refresh_token": {tokenResp.RefreshToken},
+	})
+	require.Equal(t, http.StatusOK, status)
+	require.NotEqual(t, tokenResp.RefreshToken, refreshResp.RefreshToken)
+	require.Equal(t, wantClaims, parseAccessToken(t, refreshResp.AccessToken))
+
+	// Refreshing fails once the user has been disabled
+	disabled := *env.UserService.ExpectedUser
+	disabled.IsDisabled = true
+	env.UserService.ExpectedUser = &disabled
+	_, status = requestToken(t, env, url.Values{
+		"grant_type":    {string(fosite.GrantTypeRefreshToken)},
+		"client_id":     {"CLIENT1ID"},
+		"refresh_token": {refreshResp.RefreshToken},
+	})
+	require.Equal(t, http.StatusForbidden, status)
+}
+
+func TestOAuth2ServiceImpl_AuthorizationCodeFlowAcrossInstances(t *testing.T) {
+	env := setupTestEnv(t)
+	setupHandleTokenRequestEnv(t, env, delegatedClient)
+	// Another instance sharing the database, or the same instance after a restart
+	other := setupTestEnv(t)
+	setupHandleTokenRequestEnv(t, other, delegatedClient)
+	other.S.sessionStore = env.S.sessionStore
+	signedInUser := &user.SignedInUser{UserID: 56, OrgID: oauthserver.TmpOrgID, Login: "user56"}
+
+	tokenResp, status := requestToken(t, other, url.Values{
+		"grant_type":    {string(fosite.GrantTypeAuthorizationCode)},
+		"client_id":     {"CLIENT1ID"},
+		"code":          {authorize(t, env, signedInUser)},
+		"redirect_uri":  {testRedirectURI},
+		"code_verifier": {testCodeVerifier},
+	})
+	require.Equal(t, http.StatusOK, status)
+
+	refreshResp, status := requestToken(t, env, url.Values{
+		"grant_type":    {string(fosite.GrantTypeRefreshToken)},
+		"client_id":     {"CLIENT1ID"},
+		"refresh_token": {tokenResp.RefreshToken},
+	})
+	require.Equal(t, http.StatusOK, status)
+	require.NotEmpty(t, refreshResp.AccessToken)
+
+	// The rotated refresh token cannot be reused on any instance
+	_, status = requestToken(t, other, url.Values{
+		"grant_type":    {string(fosite.GrantTypeRefreshToken)},
+		"client_id":     {"CLIENT1ID"},
+		"refresh_token": {tokenResp.RefreshToken},
+	})
+	require.Equal(t, http.StatusUnauthorized, status)
+}
+
+// authorize approves the authorization request on behalf of the user and returns the authorization code
+func authorize(t *testing.T, env *TestEnv, signedInUser *user.SignedInUser) string {
+	t.Helper()
+
+	resp := httptest.NewRecorder()
+	env.S.HandleAuthorizeRequest(resp,
+		newAuthorizeRequest(http.MethodPost, authorizeParams("profile entitlements dashboards:read offline_access"), url.Values{"consent": {consentApprove}}),
+		signedInUser)
+	require.Equal(t, http.StatusSeeOther, resp.Code)
+
+	location, err := url.Parse(resp.Header().Get("Location"))
+	require.NoError(t, err)
+	require.Equal(t, testState, location.Query().Get("state"))
+	require.NotEmpty(t, location.Query().Get("code"))
+	return location.Query().Get("code")
+}
+
+func newAuthorizeRequest(method string, params, form url.Values) *http.Request {
+	target := "/oauth2/authorize?" + params.Encode()
+	if form == nil {
+		return httptest.NewRequest(method, target, nil)
+	}
+	req := httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func requestToken(t *testing.T, env *TestEnv, params url.Values) (tokenResponse, int) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/oauth2/token", strings.NewReader(params.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp := httptest.NewRecorder()
+	env.S.HandleTokenRequest(resp, req)
+
+	var tokenResp tokenResponse
+	if resp.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &tokenResp))
+	}
+	return tokenResp, resp.Code
+}
+
+func parseAccessToken(t *testing.T, accessToken string) *claims {
+	t.Helper()
+
+	parsedToken, err := jwt.ParseSigned(accessToken)
+	require.NoError(t, err)
+	var c claims
+	require.NoError(t, parsedToken.Claims(pk.Public(), &c))
+	// Times and ID are checked by the token tests
+	c.IssuedAt, c.Expiry, c.ID = nil, nil, ""
+	return &c
+}
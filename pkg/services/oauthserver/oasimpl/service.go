@@ -4,8 +4,10 @@ import (
 	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/pem"
@@ -49,6 +51,7 @@ const (
 type OAuth2ServiceImpl struct {
 	cache         *localcache.CacheService
 	memstore      *storage.MemoryStore
+	sessionStore  oauthserver.SessionStore
 	cfg           *setting.Cfg
 	sqlstore      oauthserver.Store
 	oauthProvider fosite.OAuth2Provider
@@ -68,11 +71,15 @@ func ProvideService(router routing.RouteRegister, db db.DB, cfg *setting.Cfg,
 		return nil, nil
 	}
 	config := &fosite.Config{
+		GlobalSecret:        deriveGlobalSecret(cfg.SecretKey),
 		AccessTokenLifespan: cfg.OAuth2ServerAccessTokenLifespan,
 		TokenURL:            fmt.Sprintf("%voauth2/token", cfg.AppURL),
 		AccessTokenIssuer:   cfg.AppURL,
 		IDTokenIssuer:       cfg.AppURL,
 		ScopeStrategy:       fosite.WildcardScopeStrategy,
+		// Clients acting on behalf of users must protect the authorization code exchange with PKCE
+		EnforcePKCE:        true,
+		RefreshTokenScopes: []string{oauthserver.ScopeOfflineAccess},
 	}
 
 	privateKey := keySvc.GetServerPrivateKey()
@@ -93,6 +100,7 @@ func ProvideService(router routing.RouteRegister, db db.DB, cfg *setting.Cfg,
 		accessControl: accessControl,
 		acService:     acSvc,
 		memstore:      storage.NewMemoryStore(),
+		sessionStore:  store.NewSessionStore(db),
 		sqlstore:      store.NewStore(db),
 		logger:        log.New("oauthserver"),
 		userService:   userSvc,
@@ -109,6 +117,15 @@ func ProvideService(router routing.RouteRegister, db db.DB, cfg *setting.Cfg,
 	return s, nil
 }
 
+// deriveGlobalSecret returns the secret used to sign the authorization codes and the refresh tokens. The sessions are
+// persisted, the secret must be the same on all instances and survive restarts. Changing the secret_key invalidates
+// the issued authorization codes and refresh tokens.
+func deriveGlobalSecret(secretKey string) []byte {
+	mac := hmac.New(sha256.New, []byte(secretKey))
+	mac.Write([]byte("oauthserver.global_secret"))
+	return mac.Sum(nil)
+}
+
 func newProvider(config *fosite.Config, storage any, key any) fosite.OAuth2Provider {
 	keyGetter := func(context.Context) (any, error) {
 		return key, nil
@@ -122,6 +139,9 @@ func newProvider(config *fosite.Config, storage any, key any) fosite.OAuth2Provi
 		},
 		compose.OAuth2ClientCredentialsGrantFactory,
 		compose.RFC7523AssertionGrantFactory,
+		compose.OAuth2AuthorizeExplicitFactory,
+		compose.OAuth2RefreshTokenGrantFactory,
+		compose.OAuth2PKCEFactory,
 
 		compose.OAuth2TokenIntrospectionFactory,
 		compose.OAuth2TokenRevocationFactory,
@@ -223,6 +243,10 @@ func (s *OAuth2ServiceImpl) SaveExternalService(ctx context.Context, registratio
 	if registration.RedirectURI != nil {
 		client.RedirectURI = *registration.RedirectURI
 	}
+	if registration.Delegation.Enabled && client.RedirectURI == "" {
+		return nil, oauthserver.ErrClientRequiredRedirectURI
+	}
+	client.Public = registration.Delegation.Enabled && registration.Delegation.Public
 
 	var errGenCred error
 	client.ClientID, client.Secret, errGenCred = s.genCredentials()
@@ -238,7 +262,7 @@ func (s *OAuth2ServiceImpl) SaveExternalService(ctx context.Context, registratio
 	}
 	client.ServiceAccountID = saID
 
-	grantTypes := s.computeGrantTypes(registration.Self.Enabled, registration.Impersonation.Enabled)
+	grantTypes := s.computeGrantTypes(registration.Self.Enabled, registration.Impersonation.Enabled, registration.Delegation.Enabled)
 	client.GrantTypes = strings.Join(grantTypes, ",")
 
 	// Handle key options
@@ -292,7 +316,7 @@ func (s *OAuth2ServiceImpl) genCredentials() (string, string, error) {
 	return id, secret, err
 }
 
-func (s *OAuth2ServiceImpl) computeGrantTypes(selfAccessEnabled, impersonationEnabled bool) []string {
+func (s *OAuth2ServiceImpl) computeGrantTypes(selfAccessEnabled, impersonationEnabled, delegationEnabled bool) []string {
 	grantTypes := []string{}
 
 	if selfAccessEnabled {
@@ -303,6 +327,10 @@ func (s *OAuth2ServiceImpl) computeGrantTypes(selfAccessEnabled, impersonationEn
 		grantTypes = append(grantTypes, string(fosite.GrantTypeJWTBearer))
 	}
 
+	if delegationEnabled {
+		grantTypes = append(grantTypes, string(fosite.GrantTypeAuthorizationCode), string(fosite.GrantTypeRefreshToken))
+	}
+
 	return grantTypes
 }
 
@@ -477,7 +505,7 @@ func (s *OAuth2ServiceImpl) createServiceAccount(ctx context.Context, extSvcName
 }
 
 // handleRegistrationPermissions parses the registration form to retrieve requested permissions and adds default
-// permissions when impersonation is requested
+// permissions when impersonation or delegation is requested
 func (*OAuth2ServiceImpl) handleRegistrationPermissions(registration *oauthserver.ExternalServiceRegistration) ([]ac.Permission, []ac.Permission) {
 	selfPermissions := []ac.Permission{}
 	impersonatePermissions := []ac.Permission{}
@@ -485,16 +513,37 @@ func (*OAuth2ServiceImpl) handleRegistrationPermissions(registration *oauthserve
 	if registration.Self.Enabled {
 		selfPermissions = append(selfPermissions, registration.Self.Permissions...)
 	}
-	if registration.Impersonation.Enabled {
+	if registration.Impersonation.Enabled || registration.Delegation.Enabled {
 		requiredForToken := []ac.Permission{
 			{Action: ac.ActionUsersRead, Scope: oauthserver.ScopeGlobalUsersSelf},
 			{Action: ac.ActionUsersPermissionsRead, Scope: oauthserver.ScopeUsersSelf},
 		}
-		if registration.Impersonation.Groups {
+		if (registration.Impersonation.Enabled && registration.Impersonation.Groups) ||
+			(registration.Delegation.Enabled && registration.Delegation.Groups) {
 			requiredForToken = append(requiredForToken, ac.Permission{Action: ac.ActionTeamsRead, Scope: oauthserver.ScopeTeamsSelf})
 		}
-		impersonatePermissions = append(requiredForToken, registration.Impersonation.Permissions...)
+		impersonatePermissions = append(impersonatePermissions, requiredForToken...)
+	}
+	if registration.Impersonation.Enabled {
+		impersonatePermissions = append(impersonatePermissions, registration.Impersonation.Permissions...)
 		selfPermissions = append(selfPermissions, ac.Permission{Action: ac.ActionUsersImpersonate, Scope: ac.ScopeUsersAll})
 	}
+	if registration.Delegation.Enabled {
+		// Delegated and impersonated permissions share the same restriction set, make sure it has no duplicates
+		for _, perm := range registration.Delegation.Permissions {
+			if !containsPermission(impersonatePermissions, perm) {
+				impersonatePermissions = append(impersonatePermissions, perm)
+			}
+		}
+	}
 	return selfPermissions, impersonatePermissions
 }
+
+func containsPermission(permissions []ac.Permission, permission ac.Permission) bool {
+	for _, p := range permissions {
+		if p.Action == permission.Action && p.Scope == permission.Scope {
+			return true
+		}
+	}
+	return false
+}
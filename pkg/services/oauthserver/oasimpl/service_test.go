@@ -43,13 +43,14 @@ var (
 )
 
 type TestEnv struct {
-	S           *OAuth2ServiceImpl
-	Cfg         *setting.Cfg
-	AcStore     *actest.MockStore
-	OAuthStore  *oastest.MockStore
-	UserService *usertest.FakeUserService
-	TeamService *teamtest.FakeService
-	SAService   *satests.MockServiceAccountService
+	S            *OAuth2ServiceImpl
+	Cfg          *setting.Cfg
+	AcStore      *actest.MockStore
+	OAuthStore   *oastest.MockStore
+	SessionStore *oastest.FakeSessionStore
+	UserService  *usertest.FakeUserService
+	TeamService  *teamtest.FakeService
+	SAService    *satests.MockServiceAccountService
 }
 
 func setupTestEnv(t *testing.T) *TestEnv {
@@ -59,22 +60,26 @@ func setupTestEnv(t *testing.T) *TestEnv {
 	cfg.AppURL = AppURL
 
 	config := &fosite.Config{
+		GlobalSecret:        deriveGlobalSecret(cfg.SecretKey),
 		AccessTokenLifespan: time.Hour,
 		TokenURL:            TokenURL,
 		AccessTokenIssuer:   AppURL,
 		IDTokenIssuer:       AppURL,
 		ScopeStrategy:       fosite.WildcardScopeStrategy,
+		EnforcePKCE:         true,
+		RefreshTokenScopes:  []string{oauthserver.ScopeOfflineAccess},
 	}
 
 	fmgt := featuremgmt.WithFeatures(featuremgmt.FlagExternalServiceAuth)
 
 	env := &TestEnv{
-		Cfg:         cfg,
-		AcStore:     &actest.MockStore{},
-		OAuthStore:  &oastest.MockStore{},
-		UserService: usertest.NewUserServiceFake(),
-		TeamService: teamtest.NewFakeService(),
-		SAService:   &satests.MockServiceAccountService{},
+		Cfg:          cfg,
+		AcStore:      &actest.MockStore{},
+		OAuthStore:   &oastest.MockStore{},
+		SessionStore: oastest.NewFakeSessionStore(),
+		UserService:  usertest.NewUserServiceFake(),
+		TeamService:  teamtest.NewFakeService(),
+		SAService:    &satests.MockServiceAccountService{},
 	}
 	env.S = &OAuth2ServiceImpl{
 		cache:         localcache.New(cacheExpirationTime, cacheCleanupInterval),
@@ -82,6 +87,7 @@ func setupTestEnv(t *testing.T) *TestEnv {
 		accessControl: acimpl.ProvideAccessControl(cfg),
 		acService:     acimpl.ProvideOSSService(cfg, env.AcStore, localcache.New(0, 0), fmgt),
 		memstore:      storage.NewMemoryStore(),
+		sessionStore:  env.SessionStore,
 		sqlstore:      env.OAuthStore,
 		logger:        log.New("oauthserver.test"),
 		userService:   env.UserService,
@@ -100,6 +106,7 @@ func TestOAuth2ServiceImpl_SaveExternalService(t *testing.T) {
 	sa1 := sa.ServiceAccountDTO{Id: 1, Name: serviceName, Login: serviceName, OrgId: oauthserver.TmpOrgID, IsDisabled: false, Role: "Viewer"}
 	sa1Profile := sa.ServiceAccountProfileDTO{Id: 1, Name: serviceName, Login: serviceName, OrgId: oauthserver.TmpOrgID, IsDisabled: false, Role: "Viewer"}
 	prevSaID := int64(3)
+	redirectURI := "http://127.0.0.1:8765/callback"
 	// Using a function to prevent modifying the same object in the tests
 	client1 := func() *oauthserver.ExternalService {
 		return &oauthserver.ExternalService{
@@ -274,6 +281,50 @@ func TestOAuth2ServiceImpl_SaveExternalService(t *testing.T) {
 					}))
 			},
 		},
+		{
+			name: "should require a redirect uri for delegation",
+			init: func(env *TestEnv) {
+				env.OAuthStore.On("GetExternalServiceByName", mock.Anything, mock.Anything).Return(nil, oauthserver.ErrClientNotFound(serviceName))
+			},
+			cmd: &oauthserver.ExternalServiceRegistration{
+				Name:       serviceName,
+				Key:        &oauthserver.KeyOption{Generate: true},
+				Delegation: oauthserver.DelegationCfg{Enabled: true},
+			},
+			wantErr: true,
+		},
+		{
+			name: "should allow authorization code grant for a public client and merge delegated permissions",
+			init: func(env *TestEnv) {
+				// No client at the beginning
+				env.OAuthStore.On("GetExternalServiceByName", mock.Anything, mock.Anything).Return(nil, oauthserver.ErrClientNotFound(serviceName))
+				env.OAuthStore.On("SaveExternalService", mock.Anything, mock.Anything).Return(nil)
+			},
+			cmd: &oauthserver.ExternalServiceRegistration{
+				Name:        serviceName,
+				RedirectURI: &redirectURI,
+				Key:         &oauthserver.KeyOption{Generate: true},
+				Delegation: oauthserver.DelegationCfg{
+					Enabled: true,
+					Public:  true,
+					Permissions: []ac.Permission{
+						{Action: "dashboards:read", Scope: "dashboards:*"},
+						{Action: ac.ActionUsersRead, Scope: oauthserver.ScopeGlobalUsersSelf},
+					},
+				},
+			},
+			mockChecks: func(t *testing.T, env *TestEnv) {
+				env.OAuthStore.AssertCalled(t, "SaveExternalService", mock.Anything, mock.MatchedBy(func(client *oauthserver.ExternalService) bool {
+					return client.Public && client.RedirectURI == redirectURI &&
+						client.ServiceAccountID == oauthserver.NoServiceAccountID &&
+						slices.Equal(client.ImpersonatePermissions, []ac.Permission{
+							{Action: ac.ActionUsersRead, Scope: oauthserver.ScopeGlobalUsersSelf},
+							{Action: ac.ActionUsersPermissionsRead, Scope: oauthserver.ScopeUsersSelf},
+							{Action: "dashboards:read", Scope: "dashboards:*"},
+						})
+				}))
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -315,6 +366,12 @@ func TestOAuth2ServiceImpl_SaveExternalService(t *testing.T) {
 			} else {
 				require.NotContains(t, dto.GrantTypes, fosite.GrantTypeJWTBearer, "grant types should not contain JWT Bearer grant")
 			}
+			if tt.cmd.Delegation.Enabled {
+				require.Contains(t, dto.GrantTypes, fosite.GrantTypeAuthorizationCode, "grant types should contain authorization code grant")
+				require.Contains(t, dto.GrantTypes, fosite.GrantTypeRefreshToken, "grant types should contain refresh token grant")
+			} else {
+				require.NotContains(t, dto.GrantTypes, fosite.GrantTypeAuthorizationCode, "grant types should not contain authorization code grant")
+			}
 
 			// Check that mocks were called as expected
 			env.OAuthStore.AssertExpectations(t)
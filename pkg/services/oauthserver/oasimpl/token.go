@@ -18,7 +18,7 @@ import (
 )
 
 // HandleTokenRequest handles the client's OAuth2 query to obtain an access_token by presenting its authorization
-// grant (ex: client_credentials, jwtbearer)
+// grant (ex: client_credentials, jwtbearer, authorization_code, refresh_token)
 func (s *OAuth2ServiceImpl) HandleTokenRequest(rw http.ResponseWriter, req *http.Request) {
 	// This context will be passed to all methods.
 	ctx := req.Context()
@@ -56,6 +56,12 @@ func (s *OAuth2ServiceImpl) HandleTokenRequest(rw http.ResponseWriter, req *http
 		return
 	}
 
+	errAuthCode := s.handleAuthorizationCode(ctx, accessRequest, client)
+	if errAuthCode != nil {
+		s.writeAccessError(ctx, rw, accessRequest, errAuthCode)
+		return
+	}
+
 	// All tokens we generate in this service should target Grafana's API.
 	accessRequest.GrantAudience(s.cfg.AppURL)
 
@@ -88,6 +94,8 @@ func splitOAuthScopes(requestedScopes fosite.Arguments) (map[string]bool, map[st
 		switch scope {
 		case "profile", "email", "groups", "entitlements":
 			claimsFilter[scope] = true
+		case oauthserver.ScopeOfflineAccess:
+			// Only used to obtain a refresh token, it does not restrict the token's content
 		default:
 			actionsFilter[scope] = true
 		}
@@ -139,23 +147,90 @@ func (s *OAuth2ServiceImpl) handleJWTBearer(ctx context.Context, accessRequest f
 	// Populate claims' suject from the session subject
 	oauthSession.JWTClaims.Subject = oauthSession.Subject
 
-	// Get the user
-	query := user.GetUserByIDQuery{ID: userID}
-	dbUser, err := s.userService.GetByID(ctx, &query)
+	dbUser, err := s.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return s.populateUserClaims(ctx, accessRequest, oauthSession, client, dbUser)
+}
+
+// handleAuthorizationCode populates the access_token issued to a client acting on behalf of a user who consented to it,
+// either by exchanging an authorization code or a refresh token. The content of the token follows the rfc9068
+// specifications, like for the "impersonation" access_token, entitlements solely contain the user's permissions
+// that the client is allowed to have.
+func (s *OAuth2ServiceImpl) handleAuthorizationCode(ctx context.Context, accessRequest fosite.AccessRequester, client *oauthserver.ExternalService) error {
+	if !accessRequest.GetGrantTypes().ExactOne(string(fosite.GrantTypeAuthorizationCode)) &&
+		!accessRequest.GetGrantTypes().ExactOne(string(fosite.GrantTypeRefreshToken)) {
+		return nil
+	}
+
+	// fosite restores the session stored when the user consented
+	oauthSession, ok := accessRequest.GetSession().(*oauth2.JWTSession)
+	if !ok {
+		return &fosite.RFC6749Error{
+			DescriptionField: "The request session could not be processed.",
+			ErrorField:       "server_error",
+			CodeField:        http.StatusInternalServerError,
+		}
+	}
+
+	userID, err := utils.ParseUserIDFromSubject(oauthSession.Subject)
+	if err != nil {
+		return &fosite.RFC6749Error{
+			DescriptionField: "Could not find the requested subject.",
+			ErrorField:       "not_found",
+			CodeField:        http.StatusBadRequest,
+		}
+	}
+
+	dbUser, err := s.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	// The user may have been disabled since consenting, refreshing the token must fail in that case
+	if dbUser.IsDisabled {
+		return &fosite.RFC6749Error{
+			DescriptionField: "The requested subject is disabled.",
+			ErrorField:       "access_denied",
+			CodeField:        http.StatusForbidden,
+		}
+	}
+
+	oauthSession.JWTClaims.Subject = oauthSession.Subject
+	oauthSession.JWTClaims.Add("client_id", client.ClientID)
+
+	// fosite only marks the scopes granted by the user as granted once the token is generated, but the requested
+	// scopes have already been restored from the authorization request the user consented to
+	for _, scope := range accessRequest.GetRequestedScopes() {
+		accessRequest.GrantScope(scope)
+	}
+
+	return s.populateUserClaims(ctx, accessRequest, oauthSession, client, dbUser)
+}
+
+func (s *OAuth2ServiceImpl) getUser(ctx context.Context, userID int64) (*user.User, error) {
+	dbUser, err := s.userService.GetByID(ctx, &user.GetUserByIDQuery{ID: userID})
 	if err != nil {
 		if errors.Is(err, user.ErrUserNotFound) {
-			return &fosite.RFC6749Error{
+			return nil, &fosite.RFC6749Error{
 				DescriptionField: "Could not find the requested subject.",
 				ErrorField:       "not_found",
 				CodeField:        http.StatusBadRequest,
 			}
 		}
-		return &fosite.RFC6749Error{
+		return nil, &fosite.RFC6749Error{
 			DescriptionField: "The request subject could not be processed.",
 			ErrorField:       "server_error",
 			CodeField:        http.StatusInternalServerError,
 		}
 	}
+	return dbUser, nil
+}
+
+// populateUserClaims adds the claims matching the granted scopes (profile, email, groups, entitlements) to the
+// access_token of a client acting on behalf of a user.
+func (s *OAuth2ServiceImpl) populateUserClaims(ctx context.Context, accessRequest fosite.AccessRequester, oauthSession *oauth2.JWTSession, client *oauthserver.ExternalService, dbUser *user.User) error {
 	oauthSession.Username = dbUser.Login
 
 	// Split scopes into actions and claims
@@ -205,13 +280,13 @@ func (s *OAuth2ServiceImpl) handleJWTBearer(ctx context.Context, accessRequest f
 
 	if claimsFilter["entitlements"] {
 		// Get the user permissions (apply the actions filter)
-		permissions, errGetPermission := s.filteredUserPermissions(ctx, userID, actionsFilter)
+		permissions, errGetPermission := s.filteredUserPermissions(ctx, dbUser.ID, actionsFilter)
 		if errGetPermission != nil {
 			return errGetPermission
 		}
 
 		// Compute the impersonated permissions (apply the actions filter, replace the scope self with the user id)
-		impPerms := s.filteredImpersonatePermissions(client.ImpersonatePermissions, userID, teams, actionsFilter)
+		impPerms := s.filteredImpersonatePermissions(client.ImpersonatePermissions, dbUser.ID, teams, actionsFilter)
 
 		// Intersect the permissions with the client permissions
 		intesect := ac.Intersect(permissions, impPerms)
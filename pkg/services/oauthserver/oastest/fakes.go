@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 
+	"github.com/grafana/grafana/pkg/services/auth/identity"
 	"github.com/grafana/grafana/pkg/services/oauthserver"
 	"gopkg.in/square/go-jose.v2"
 )
@@ -24,6 +25,9 @@ func (s *FakeService) GetExternalService(ctx context.Context, id string) (*oauth
 	return s.ExpectedClient, s.ExpectedErr
 }
 
+func (s *FakeService) HandleAuthorizeRequest(rw http.ResponseWriter, req *http.Request, signedInUser identity.Requester) {
+}
+
 func (s *FakeService) HandleTokenRequest(rw http.ResponseWriter, req *http.Request) {}
 
 func (s *FakeService) HandleIntrospectionRequest(rw http.ResponseWriter, req *http.Request) {}
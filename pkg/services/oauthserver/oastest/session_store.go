@@ -0,0 +1,87 @@
+package oastest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/oauthserver"
+)
+
+// FakeSessionStore keeps the sessions in memory
+type FakeSessionStore struct {
+	mu       sync.Mutex
+	Sessions map[oauthserver.SessionKind]map[string]oauthserver.Session
+}
+
+var _ oauthserver.SessionStore = &FakeSessionStore{}
+
+func NewFakeSessionStore() *FakeSessionStore {
+	return &FakeSessionStore{Sessions: map[oauthserver.SessionKind]map[string]oauthserver.Session{}}
+}
+
+func (f *FakeSessionStore) CreateSession(ctx context.Context, session *oauthserver.Session) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Sessions[session.Kind] == nil {
+		f.Sessions[session.Kind] = map[string]oauthserver.Session{}
+	}
+	f.Sessions[session.Kind][session.Signature] = *session
+	return nil
+}
+
+func (f *FakeSessionStore) GetSession(ctx context.Context, kind oauthserver.SessionKind, signature string) (*oauthserver.Session, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	session, ok := f.Sessions[kind][signature]
+	if !ok {
+		return nil, oauthserver.ErrSessionNotFound.Errorf("%s session not found", kind)
+	}
+	return &session, nil
+}
+
+func (f *FakeSessionStore) DeleteSession(ctx context.Context, kind oauthserver.SessionKind, signature string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.Sessions[kind], signature)
+	return nil
+}
+
+func (f *FakeSessionStore) DeactivateSession(ctx context.Context, kind oauthserver.SessionKind, signature string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	session, ok := f.Sessions[kind][signature]
+	if !ok {
+		return oauthserver.ErrSessionNotFound.Errorf("%s session not found", kind)
+	}
+	session.Active = false
+	f.Sessions[kind][signature] = session
+	return nil
+}
+
+func (f *FakeSessionStore) DeactivateSessionsByRequestID(ctx context.Context, kind oauthserver.SessionKind, requestID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for signature, session := range f.Sessions[kind] {
+		if session.RequestID == requestID {
+			session.Active = false
+			f.Sessions[kind][signature] = session
+		}
+	}
+	return nil
+}
+
+func (f *FakeSessionStore) DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var deleted int64
+	for _, sessions := range f.Sessions {
+		for signature, session := range sessions {
+			if session.Expires > 0 && session.Expires < now.Unix() {
+				delete(sessions, signature)
+				deleted++
+			}
+		}
+	}
+	return deleted, nil
+}
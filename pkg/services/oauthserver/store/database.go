@@ -40,7 +40,7 @@ func createImpersonatePermissions(sess *db.Session, client *oauthserver.External
 
 func registerExternalService(sess *db.Session, client *oauthserver.ExternalService) error {
 	insertQuery := []any{
-		`INSERT INTO oauth_client (name, client_id, secret, grant_types, audiences, service_account_id, public_pem, redirect_uri) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		`INSERT INTO oauth_client (name, client_id, secret, grant_types, audiences, service_account_id, public_pem, redirect_uri, is_public) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		client.Name,
 		client.ClientID,
 		client.Secret,
@@ -49,6 +49,7 @@ func registerExternalService(sess *db.Session, client *oauthserver.ExternalServi
 		client.ServiceAccountID,
 		client.PublicPem,
 		client.RedirectURI,
+		client.Public,
 	}
 	if _, err := sess.Exec(insertQuery...); err != nil {
 		return err
@@ -78,7 +79,7 @@ func recreateImpersonatePermissions(sess *db.Session, client *oauthserver.Extern
 
 func updateExternalService(sess *db.Session, client *oauthserver.ExternalService, prevClientID string) error {
 	updateQuery := []any{
-		`UPDATE oauth_client SET client_id = ?, secret = ?, grant_types = ?, audiences = ?, service_account_id = ?, public_pem = ?, redirect_uri = ? WHERE name = ?`,
+		`UPDATE oauth_client SET client_id = ?, secret = ?, grant_types = ?, audiences = ?, service_account_id = ?, public_pem = ?, redirect_uri = ?, is_public = ? WHERE name = ?`,
 		client.ClientID,
 		client.Secret,
 		client.GrantTypes,
@@ -86,6 +87,7 @@ func updateExternalService(sess *db.Session, client *oauthserver.ExternalService
 		client.ServiceAccountID,
 		client.PublicPem,
 		client.RedirectURI,
+		client.Public,
 		client.Name,
 	}
 	if _, err := sess.Exec(updateQuery...); err != nil {
@@ -124,7 +126,7 @@ func (s *store) GetExternalService(ctx context.Context, id string) (*oauthserver
 
 	err := s.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
 		getClientQuery := `SELECT
-		id, name, client_id, secret, grant_types, audiences, service_account_id, public_pem, redirect_uri
+		id, name, client_id, secret, grant_types, audiences, service_account_id, public_pem, redirect_uri, is_public
 		FROM oauth_client
 		WHERE client_id = ?`
 		found, err := sess.SQL(getClientQuery, id).Get(res)
@@ -201,7 +203,7 @@ func (s *store) GetExternalServiceByName(ctx context.Context, name string) (*oau
 func getExternalServiceByName(sess *db.Session, name string) (*oauthserver.ExternalService, error) {
 	res := &oauthserver.ExternalService{}
 	getClientQuery := `SELECT
-		id, name, client_id, secret, grant_types, audiences, service_account_id, public_pem, redirect_uri
+		id, name, client_id, secret, grant_types, audiences, service_account_id, public_pem, redirect_uri, is_public
 		FROM oauth_client
 		WHERE name = ?`
 	found, err := sess.SQL(getClientQuery, name).Get(res)
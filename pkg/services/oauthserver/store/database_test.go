@@ -134,6 +134,10 @@ func TestStore_SaveExternalService(t *testing.T) {
 	client1WithAud := client1
 	client1WithAud.Audiences = "https://oauth.test/,https://sub.oauth.test/"
 
+	client1Public := client1
+	client1Public.GrantTypes = "authorization_code,refresh_token"
+	client1Public.Public = true
+
 	tests := []struct {
 		name    string
 		runs    []oauthserver.ExternalService
@@ -174,6 +178,11 @@ func TestStore_SaveExternalService(t *testing.T) {
 			runs:    []oauthserver.ExternalService{client1, client1WithAud},
 			wantErr: false,
 		},
+		{
+			name:    "update public",
+			runs:    []oauthserver.ExternalService{client1, client1Public, client1},
+			wantErr: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
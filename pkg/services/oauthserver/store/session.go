@@ -0,0 +1,107 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/oauthserver"
+)
+
+type sessionStore struct {
+	db db.DB
+}
+
+func NewSessionStore(db db.DB) oauthserver.SessionStore {
+	return &sessionStore{db: db}
+}
+
+func (s *sessionStore) CreateSession(ctx context.Context, session *oauthserver.Session) error {
+	return s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		insertQuery := []any{
+			`INSERT INTO oauth_session (kind, signature, request_id, client_id, requested_at, scopes, granted_scopes, requested_audience, granted_audience, form_data, session_data, active, expires) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			session.Kind,
+			session.Signature,
+			session.RequestID,
+			session.ClientID,
+			session.RequestedAt,
+			session.Scopes,
+			session.GrantedScopes,
+			session.RequestedAudience,
+			session.GrantedAudience,
+			session.Form,
+			session.Data,
+			session.Active,
+			session.Expires,
+		}
+		_, err := sess.Exec(insertQuery...)
+		return err
+	})
+}
+
+func (s *sessionStore) GetSession(ctx context.Context, kind oauthserver.SessionKind, signature string) (*oauthserver.Session, error) {
+	res := &oauthserver.Session{}
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		getSessionQuery := `SELECT
+		id, kind, signature, request_id, client_id, requested_at, scopes, granted_scopes, requested_audience, granted_audience, form_data, session_data, active, expires
+		FROM oauth_session
+		WHERE kind = ? AND signature = ?`
+		found, err := sess.SQL(getSessionQuery, kind, signature).Get(res)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return oauthserver.ErrSessionNotFound.Errorf("%s session not found", kind)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *sessionStore) DeleteSession(ctx context.Context, kind oauthserver.SessionKind, signature string) error {
+	return s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		_, err := sess.Exec(`DELETE FROM oauth_session WHERE kind = ? AND signature = ?`, kind, signature)
+		return err
+	})
+}
+
+func (s *sessionStore) DeactivateSession(ctx context.Context, kind oauthserver.SessionKind, signature string) error {
+	return s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		res, err := sess.Exec(`UPDATE oauth_session SET active = ? WHERE kind = ? AND signature = ?`, false, kind, signature)
+		if err != nil {
+			return err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return oauthserver.ErrSessionNotFound.Errorf("%s session not found", kind)
+		}
+		return nil
+	})
+}
+
+func (s *sessionStore) DeactivateSessionsByRequestID(ctx context.Context, kind oauthserver.SessionKind, requestID string) error {
+	return s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		_, err := sess.Exec(`UPDATE oauth_session SET active = ? WHERE kind = ? AND request_id = ?`, false, kind, requestID)
+		return err
+	})
+}
+
+// DeleteExpiredSessions deletes the sessions which expired before now, sessions which never expire are kept
+func (s *sessionStore) DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error) {
+	var affected int64
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		res, err := sess.Exec(`DELETE FROM oauth_session WHERE expires > 0 AND expires < ?`, now.Unix())
+		if err != nil {
+			return err
+		}
+		affected, err = res.RowsAffected()
+		return err
+	})
+	return affected, err
+}
@@ -0,0 +1,98 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/oauthserver"
+)
+
+func TestSessionStore(t *testing.T) {
+	s := &sessionStore{db: db.InitTestDB(t, db.InitTestDBOpt{FeatureFlags: []string{featuremgmt.FlagExternalServiceAuth}})}
+	ctx := context.Background()
+	now := time.Now()
+
+	newSession := func(kind oauthserver.SessionKind, signature, requestID string, expires int64) *oauthserver.Session {
+		return &oauthserver.Session{
+			Kind:          kind,
+			Signature:     signature,
+			RequestID:     requestID,
+			ClientID:      "client",
+			RequestedAt:   now.Truncate(time.Second),
+			Scopes:        "profile offline_access",
+			GrantedScopes: "profile offline_access",
+			Form:          "redirect_uri=http%3A%2F%2F127.0.0.1%2Fcallback",
+			Data:          `{"subject":"user:id:1"}`,
+			Active:        true,
+			Expires:       expires,
+		}
+	}
+
+	code := newSession(oauthserver.AuthorizeCodeSession, "code", "request-1", now.Add(time.Minute).Unix())
+	require.NoError(t, s.CreateSession(ctx, code))
+	require.NoError(t, s.CreateSession(ctx, newSession(oauthserver.PKCESession, "code", "request-1", now.Add(time.Minute).Unix())))
+	require.NoError(t, s.CreateSession(ctx, newSession(oauthserver.RefreshTokenSession, "refresh", "request-1", now.Add(time.Hour).Unix())))
+	require.NoError(t, s.CreateSession(ctx, newSession(oauthserver.RefreshTokenSession, "expired", "request-2", now.Add(-time.Minute).Unix())))
+	require.NoError(t, s.CreateSession(ctx, newSession(oauthserver.RefreshTokenSession, "forever", "request-3", 0)))
+
+	t.Run("should get the session of the given kind", func(t *testing.T) {
+		got, err := s.GetSession(ctx, oauthserver.AuthorizeCodeSession, "code")
+		require.NoError(t, err)
+		require.Equal(t, oauthserver.AuthorizeCodeSession, got.Kind)
+		require.Equal(t, code.RequestID, got.RequestID)
+		require.Equal(t, code.ClientID, got.ClientID)
+		require.Equal(t, code.RequestedAt.Unix(), got.RequestedAt.Unix())
+		require.Equal(t, code.Scopes, got.Scopes)
+		require.Equal(t, code.Form, got.Form)
+		require.Equal(t, code.Data, got.Data)
+		require.Equal(t, code.Expires, got.Expires)
+		require.True(t, got.Active)
+
+		_, err = s.GetSession(ctx, oauthserver.RefreshTokenSession, "code")
+		require.ErrorIs(t, err, oauthserver.ErrSessionNotFound)
+	})
+
+	t.Run("should deactivate the session", func(t *testing.T) {
+		require.NoError(t, s.DeactivateSession(ctx, oauthserver.AuthorizeCodeSession, "code"))
+		got, err := s.GetSession(ctx, oauthserver.AuthorizeCodeSession, "code")
+		require.NoError(t, err)
+		require.False(t, got.Active)
+
+		got, err = s.GetSession(ctx, oauthserver.PKCESession, "code")
+		require.NoError(t, err)
+		require.True(t, got.Active)
+
+		require.ErrorIs(t, s.DeactivateSession(ctx, oauthserver.AuthorizeCodeSession, "unknown"), oauthserver.ErrSessionNotFound)
+	})
+
+	t.Run("should deactivate the sessions of the request", func(t *testing.T) {
+		require.NoError(t, s.DeactivateSessionsByRequestID(ctx, oauthserver.RefreshTokenSession, "request-1"))
+		got, err := s.GetSession(ctx, oauthserver.RefreshTokenSession, "refresh")
+		require.NoError(t, err)
+		require.False(t, got.Active)
+	})
+
+	t.Run("should delete the session", func(t *testing.T) {
+		require.NoError(t, s.DeleteSession(ctx, oauthserver.PKCESession, "code"))
+		_, err := s.GetSession(ctx, oauthserver.PKCESession, "code")
+		require.ErrorIs(t, err, oauthserver.ErrSessionNotFound)
+	})
+
+	t.Run("should only delete the expired sessions", func(t *testing.T) {
+		deleted, err := s.DeleteExpiredSessions(ctx, now)
+		require.NoError(t, err)
+		require.EqualValues(t, 1, deleted)
+
+		_, err = s.GetSession(ctx, oauthserver.RefreshTokenSession, "expired")
+		require.ErrorIs(t, err, oauthserver.ErrSessionNotFound)
+		_, err = s.GetSession(ctx, oauthserver.RefreshTokenSession, "forever")
+		require.NoError(t, err)
+		_, err = s.GetSession(ctx, oauthserver.RefreshTokenSession, "refresh")
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,196 @@
+package orgarchive
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/middleware"
+	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+// maxUploadSize is the maximum size of an uploaded archive.
+const maxUploadSize = 256 << 20
+
+func (s *OrgArchiveService) registerAPIEndpoints() {
+	authorize := ac.Middleware(s.accessControl)
+
+	s.routeRegister.Group("/api/org/archive", func(archiveRoute routing.RouteRegister) {
+		archiveRoute.Get("/", authorize(ac.EvalAll(
+			ac.EvalPermission(dashboards.ActionFoldersRead),
+			ac.EvalPermission(dashboards.ActionDashboardsRead),
+		)), routing.Wrap(s.exportHandler))
+		archiveRoute.Post("/import", authorize(ac.EvalAll(
+			ac.EvalPermission(dashboards.ActionFoldersCreate),
+			ac.EvalPermission(dashboards.ActionDashboardsCreate),
+		)), routing.Wrap(s.importHandler))
+	}, middleware.ReqSignedIn)
+}
+
+// permissionsEvaluator is the permission required to export or import permissions.
+func permissionsEvaluator(write bool) ac.Evaluator {
+	if write {
+		return ac.EvalAll(
+			ac.EvalPermission(dashboards.ActionFoldersPermissionsWrite),
+			ac.EvalPermission(dashboards.ActionDashboardsPermissionsWrite),
+		)
+	}
+	return ac.EvalAll(
+		ac.EvalPermission(dashboards.ActionFoldersPermissionsRead),
+		ac.EvalPermission(dashboards.ActionDashboardsPermissionsRead),
+	)
+}
+
+// swagger:route GET /org/archive org exportOrgArchive
+//
+// Export the folders, dashboards and library panels of the current organization.
+//
+// Returns a zip archive or a gzip compressed tarball containing a manifest and one file per exported entity.
+// Only the entities the user can read are exported.
+//
+// Produces:
+// - application/zip
+// - application/gzip
+//
+// Responses:
+// 200: exportOrgArchiveResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (s *OrgArchiveService) exportHandler(c *contextmodel.ReqContext) response.Response {
+	opts := ExportOptions{
+		Format:             Format(c.Query("format")),
+		IncludePermissions: c.QueryBool("permissions"),
+	}
+	if opts.Format == "" {
+		opts.Format = FormatZip
+	}
+	if !opts.Format.IsValid() {
+		return response.Error(http.StatusBadRequest, "Unsupported archive format", ErrUnsupportedFormat)
+	}
+
+	if opts.IncludePermissions {
+		hasAccess, err := s.accessControl.Evaluate(c.Req.Context(), c.SignedInUser, permissionsEvaluator(false))
+		if err != nil {
+			return response.Error(http.StatusInternalServerError, "Failed to evaluate permissions", err)
+		}
+		if !hasAccess {
+			return response.Error(http.StatusForbidden, "Exporting permissions requires access to folder and dashboard permissions", nil)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := s.Export(c.Req.Context(), c.SignedInUser, opts, &buf); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to export organization", err)
+	}
+
+	contentType, extension := "application/zip", "zip"
+	if opts.Format == FormatTar {
+		contentType, extension = "application/gzip", "tar.gz"
+	}
+	filename := fmt.Sprintf("grafana-org-%d-%s.%s", c.OrgID, time.Now().UTC().Format("20060102-150405"), extension)
+	return response.Respond(http.StatusOK, buf.Bytes()).
+		SetHeader("Content-Type", contentType).
+		SetHeader("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+}
+
+// swagger:route POST /org/archive/import org importOrgArchive
+//
+// Import an organization archive into the current organization.
+//
+// The archive is uploaded as the `archive` field of a multipart form. The optional `conflictStrategy`
+// field is one of skip (default), overwrite or rename, the optional `datasourceUidMapping` field is a
+// JSON object mapping datasource UIDs of the archive to datasource UIDs of the current organization,
+// and `importPermissions` applies the archived permissions.
+//
+// Consumes:
+// - multipart/form-data
+//
+// Responses:
+// 200: importOrgArchiveResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (s *OrgArchiveService) importHandler(c *contextmodel.ReqContext) response.Response {
+	c.Req.Body = http.MaxBytesReader(c.Resp, c.Req.Body, maxUploadSize)
+	if err := c.Req.ParseMultipartForm(maxUploadSize); err != nil {
+		return response.Error(http.StatusBadRequest, fmt.Sprintf("Please limit the uploaded archive to %s", util.ByteCountSI(maxUploadSize)), err)
+	}
+
+	file, _, err := c.Req.FormFile("archive")
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "Missing archive", err)
+	}
+	defer func() { _ = file.Close() }()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "Failed to read archive", err)
+	}
+
+	opts := ImportOptions{
+		ConflictStrategy:  ConflictStrategy(c.Req.FormValue("conflictStrategy")),
+		ImportPermissions: c.Req.FormValue("importPermissions") == "true",
+	}
+	if mapping := c.Req.FormValue("datasourceUidMapping"); mapping != "" {
+		if err := json.Unmarshal([]byte(mapping), &opts.DatasourceUIDMapping); err != nil {
+			return response.Error(http.StatusBadRequest, "Invalid datasource UID mapping", err)
+		}
+	}
+
+	if opts.ImportPermissions {
+		hasAccess, err := s.accessControl.Evaluate(c.Req.Context(), c.SignedInUser, permissionsEvaluator(true))
+		if err != nil {
+			return response.Error(http.StatusInternalServerError, "Failed to evaluate permissions", err)
+		}
+		if !hasAccess {
+			return response.Error(http.StatusForbidden, "Importing permissions requires access to folder and dashboard permissions", nil)
+		}
+	}
+
+	result, err := s.Import(c.Req.Context(), c.SignedInUser, data, opts)
+	if err != nil {
+		if errors.Is(err, ErrInvalidConflictStrategy) || errors.Is(err, ErrManifestMissing) || errors.Is(err, ErrUnsupportedVersion) ||
+			errors.Is(err, ErrUnsupportedFormat) || errors.Is(err, ErrArchiveEntryMissing) || errors.Is(err, ErrArchiveEntryPathNotValid) ||
+			errors.Is(err, ErrArchiveTooLarge) {
+			return response.Error(http.StatusBadRequest, err.Error(), err)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to import organization archive", err)
+	}
+	return response.JSON(http.StatusOK, result)
+}
+
+// swagger:response exportOrgArchiveResponse
+type ExportOrgArchiveResponse struct {
+	// in: body
+	Body []byte `json:"body"`
+}
+
+// swagger:parameters exportOrgArchive
+type ExportOrgArchiveParams struct {
+	// Archive format, zip or tar.
+	// in:query
+	// required:false
+	// default:zip
+	Format string `json:"format"`
+	// Include the folder and dashboard permissions.
+	// in:query
+	// required:false
+	Permissions bool `json:"permissions"`
+}
+
+// swagger:response importOrgArchiveResponse
+type ImportOrgArchiveResponse struct {
+	// in: body
+	Body ImportResult `json:"body"`
+}
@@ -0,0 +1,256 @@
+package orgarchive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	manifestPath = "manifest.json"
+
+	// maxEntrySize is the maximum uncompressed size of a single archive entry.
+	maxEntrySize = 64 << 20
+	// maxArchiveSize is the maximum uncompressed size of all the archive entries.
+	maxArchiveSize = 256 << 20
+	// maxArchiveEntries is the maximum number of archive entries.
+	maxArchiveEntries = 10000
+)
+
+var (
+	zipMagic  = []byte("PK\x03\x04")
+	gzipMagic = []byte{0x1f, 0x8b}
+)
+
+// archiveWriter writes files to an archive.
+type archiveWriter interface {
+	WriteFile(name string, data []byte) error
+	Close() error
+}
+
+func newArchiveWriter(w io.Writer, format Format) (archiveWriter, error) {
+	switch format {
+	case FormatZip:
+		return &zipArchiveWriter{zw: zip.NewWriter(w)}, nil
+	case FormatTar:
+		gz := gzip.NewWriter(w)
+		return &tarArchiveWriter{gz: gz, tw: tar.NewWriter(gz)}, nil
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+type zipArchiveWriter struct {
+	zw *zip.Writer
+}
+
+func (a *zipArchiveWriter) WriteFile(name string, data []byte) error {
+	f, err := a.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+func (a *zipArchiveWriter) Close() error {
+	return a.zw.Close()
+}
+
+type tarArchiveWriter struct {
+	gz *gzip.Writer
+	tw *tar.Writer
+}
+
+func (a *tarArchiveWriter) WriteFile(name string, data []byte) error {
+	err := a.tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = a.tw.Write(data)
+	return err
+}
+
+func (a *tarArchiveWriter) Close() error {
+	if err := a.tw.Close(); err != nil {
+		return err
+	}
+	return a.gz.Close()
+}
+
+func writeJSON(w archiveWriter, name string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return w.WriteFile(name, data)
+}
+
+// archive is the content of an archive read in memory.
+type archive struct {
+	manifest Manifest
+	files    map[string][]byte
+}
+
+// readArchive reads a zip archive or a tarball, which may be gzip compressed.
+func readArchive(data []byte) (*archive, error) {
+	var files map[string][]byte
+	var err error
+	switch {
+	case bytes.HasPrefix(data, zipMagic):
+		files, err = readZip(data)
+	case bytes.HasPrefix(data, gzipMagic):
+		var gz *gzip.Reader
+		gz, err = gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, err)
+		}
+		files, err = readTar(gz)
+	default:
+		files, err = readTar(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	body, ok := files[manifestPath]
+	if !ok {
+		return nil, ErrManifestMissing
+	}
+	a := &archive{files: files}
+	if err := json.Unmarshal(body, &a.manifest); err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	if a.manifest.Version < 1 || a.manifest.Version > ManifestVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, a.manifest.Version)
+	}
+	return a, nil
+}
+
+func readZip(data []byte) (map[string][]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, err)
+	}
+
+	if len(zr.File) > maxArchiveEntries {
+		return nil, fmt.Errorf("%w: more than %d entries", ErrArchiveTooLarge, maxArchiveEntries)
+	}
+
+	budget := newEntryBudget()
+	files := make(map[string][]byte, len(zr.File))
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		name, err := cleanEntryPath(f.Name)
+		if err != nil {
+			return nil, err
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		body, err := budget.read(rc)
+		_ = rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		files[name] = body
+	}
+	return files, nil
+}
+
+func readTar(r io.Reader) (map[string][]byte, error) {
+	tr := tar.NewReader(r)
+	budget := newEntryBudget()
+	files := make(map[string][]byte)
+	for entries := 0; ; entries++ {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return files, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, err)
+		}
+		if entries >= maxArchiveEntries {
+			return nil, fmt.Errorf("%w: more than %d entries", ErrArchiveTooLarge, maxArchiveEntries)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name, err := cleanEntryPath(hdr.Name)
+		if err != nil {
+			return nil, err
+		}
+		body, err := budget.read(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		files[name] = body
+	}
+}
+
+// entryBudget limits the uncompressed size of the entries read from an archive, so that a highly compressed
+// archive cannot expand beyond the size limits in memory.
+type entryBudget struct {
+	remaining int64
+}
+
+func newEntryBudget() *entryBudget {
+	return &entryBudget{remaining: maxArchiveSize}
+}
+
+func (b *entryBudget) read(r io.Reader) ([]byte, error) {
+	limit := int64(maxEntrySize)
+	if b.remaining < limit {
+		limit = b.remaining
+	}
+	body, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		if limit < maxEntrySize {
+			return nil, fmt.Errorf("%w: uncompressed size exceeds %d bytes", ErrArchiveTooLarge, maxArchiveSize)
+		}
+		return nil, fmt.Errorf("%w: entry exceeds %d bytes", ErrArchiveTooLarge, maxEntrySize)
+	}
+	b.remaining -= int64(len(body))
+	return body, nil
+}
+
+// cleanEntryPath normalizes the path of an entry and rejects paths escaping the archive root.
+func cleanEntryPath(name string) (string, error) {
+	cleaned := path.Clean(strings.TrimPrefix(name, "./"))
+	if path.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("%w: %s", ErrArchiveEntryPathNotValid, name)
+	}
+	return cleaned, nil
+}
+
+// readJSON decodes the entry of the archive referenced by a manifest entry.
+func (a *archive) readJSON(entry ManifestEntry, v any) error {
+	name, err := cleanEntryPath(entry.Path)
+	if err != nil {
+		return err
+	}
+	body, ok := a.files[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrArchiveEntryMissing, entry.Path)
+	}
+	return json.Unmarshal(body, v)
+}
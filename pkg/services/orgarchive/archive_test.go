@@ -0,0 +1,176 @@
+package orgarchive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveRoundTrip(t *testing.T) {
+	for _, format := range []Format{FormatZip, FormatTar} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := newArchiveWriter(&buf, format)
+			require.NoError(t, err)
+
+			entry := ManifestEntry{UID: "abc", Title: "Folder", Path: "folders/abc.json"}
+			require.NoError(t, writeJSON(w, entry.Path, Folder{UID: "abc", Title: "Folder"}))
+			require.NoError(t, writeJSON(w, manifestPath, Manifest{Version: ManifestVersion, Folders: []ManifestEntry{entry}}))
+			require.NoError(t, w.Close())
+
+			a, err := readArchive(buf.Bytes())
+			require.NoError(t, err)
+			require.Equal(t, []ManifestEntry{entry}, a.manifest.Folders)
+
+			var f Folder
+			require.NoError(t, a.readJSON(entry, &f))
+			require.Equal(t, Folder{UID: "abc", Title: "Folder"}, f)
+
+			err = a.readJSON(ManifestEntry{Path: "folders/missing.json"}, &f)
+			require.ErrorIs(t, err, ErrArchiveEntryMissing)
+		})
+	}
+}
+
+func TestReadArchive(t *testing.T) {
+	writeTar := func(t *testing.T, files map[string]string) []byte {
+		t.Helper()
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for name, body := range files {
+			require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(body)), Typeflag: tar.TypeReg}))
+			_, err := tw.Write([]byte(body))
+			require.NoError(t, err)
+		}
+		require.NoError(t, tw.Close())
+		return buf.Bytes()
+	}
+
+	t.Run("uncompressed tarball is supported", func(t *testing.T) {
+		a, err := readArchive(writeTar(t, map[string]string{"./manifest.json": `{"version": 1}`}))
+		require.NoError(t, err)
+		require.Equal(t, 1, a.manifest.Version)
+	})
+
+	t.Run("manifest is required", func(t *testing.T) {
+		_, err := readArchive(writeTar(t, map[string]string{"dashboards/a.json": `{}`}))
+		require.ErrorIs(t, err, ErrManifestMissing)
+	})
+
+	t.Run("newer versions are rejected", func(t *testing.T) {
+		_, err := readArchive(writeTar(t, map[string]string{"manifest.json": `{"version": 99}`}))
+		require.ErrorIs(t, err, ErrUnsupportedVersion)
+	})
+
+	t.Run("paths outside of the archive are rejected", func(t *testing.T) {
+		_, err := readArchive(writeTar(t, map[string]string{
+			"manifest.json":    `{"version": 1}`,
+			"../../etc/passwd": "root",
+		}))
+		require.ErrorIs(t, err, ErrArchiveEntryPathNotValid)
+	})
+
+	t.Run("highly compressed tarballs are rejected", func(t *testing.T) {
+		// entries below the entry size limit whose total exceeds the archive size limit
+		var buf bytes.Buffer
+		gz, err := gzip.NewWriterLevel(&buf, gzip.BestSpeed)
+		require.NoError(t, err)
+		tw := tar.NewWriter(gz)
+		zeros := make([]byte, 1<<20)
+		for i := 0; i < maxArchiveSize/(48<<20)+1; i++ {
+			require.NoError(t, tw.WriteHeader(&tar.Header{Name: fmt.Sprintf("dashboards/%d.json", i), Mode: 0o644, Size: 48 << 20, Typeflag: tar.TypeReg}))
+			for j := 0; j < 48; j++ {
+				_, err := tw.Write(zeros)
+				require.NoError(t, err)
+			}
+		}
+		require.NoError(t, tw.Close())
+		require.NoError(t, gz.Close())
+		require.Less(t, buf.Len(), 1<<20)
+
+		_, err = readArchive(buf.Bytes())
+		require.ErrorIs(t, err, ErrArchiveTooLarge)
+	})
+
+	t.Run("highly compressed zip entries are rejected", func(t *testing.T) {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		w, err := zw.Create("dashboards/a.json")
+		require.NoError(t, err)
+		_, err = w.Write(make([]byte, maxEntrySize+1))
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+
+		_, err = readArchive(buf.Bytes())
+		require.ErrorIs(t, err, ErrArchiveTooLarge)
+	})
+
+	t.Run("archives with too many entries are rejected", func(t *testing.T) {
+		files := make(map[string]string, maxArchiveEntries+1)
+		for i := 0; i <= maxArchiveEntries; i++ {
+			files[fmt.Sprintf("dashboards/%d.json", i)] = ""
+		}
+		_, err := readArchive(writeTar(t, files))
+		require.ErrorIs(t, err, ErrArchiveTooLarge)
+	})
+
+	t.Run("garbage is rejected", func(t *testing.T) {
+		_, err := readArchive([]byte("definitely not an archive, but long enough to read a tar header from it"))
+		require.Error(t, err)
+	})
+}
+
+func TestRewriteReferences(t *testing.T) {
+	var dash any
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"panels": [
+			{"datasource": {"uid": "staging-prom", "type": "prometheus"}, "targets": [{"datasource": {"uid": "staging-prom"}}]},
+			{"datasource": "staging-loki"},
+			{"datasource": {"uid": "unmapped"}},
+			{"type": "row", "panels": [{"libraryPanel": {"uid": "lib-a", "name": "A"}}]}
+		],
+		"templating": {"list": [{"datasource": {"uid": "staging-prom"}}]}
+	}`), &dash))
+
+	rewriteReferences(dash,
+		map[string]string{"staging-prom": "prod-prom", "staging-loki": "prod-loki"},
+		map[string]string{"lib-a": "lib-b"},
+	)
+
+	out, err := json.Marshal(dash)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"panels": [
+			{"datasource": {"uid": "prod-prom", "type": "prometheus"}, "targets": [{"datasource": {"uid": "prod-prom"}}]},
+			{"datasource": "prod-loki"},
+			{"datasource": {"uid": "unmapped"}},
+			{"type": "row", "panels": [{"libraryPanel": {"uid": "lib-b", "name": "A"}}]}
+		],
+		"templating": {"list": [{"datasource": {"uid": "prod-prom"}}]}
+	}`, string(out))
+}
+
+func TestSortFoldersByDepth(t *testing.T) {
+	sorted := sortFoldersByDepth([]Folder{
+		{UID: "grandchild", ParentUID: "child"},
+		{UID: "child", ParentUID: "root"},
+		{UID: "root"},
+		{UID: "orphan", ParentUID: "not-in-archive"},
+		{UID: "cycle-a", ParentUID: "cycle-b"},
+		{UID: "cycle-b", ParentUID: "cycle-a"},
+	})
+
+	index := make(map[string]int, len(sorted))
+	for i, f := range sorted {
+		index[f.UID] = i
+	}
+	require.Len(t, sorted, 6)
+	require.Less(t, index["root"], index["child"])
+	require.Less(t, index["child"], index["grandchild"])
+}
@@ -0,0 +1,219 @@
+package orgarchive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/folder"
+	"github.com/grafana/grafana/pkg/services/libraryelements/model"
+	"github.com/grafana/grafana/pkg/services/sqlstore/searchstore"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+const (
+	searchPageSize         = 1000
+	libraryElementPageSize = 100
+)
+
+// Export writes the folders, dashboards and library elements the user can read to an archive.
+func (s *OrgArchiveService) Export(ctx context.Context, usr *user.SignedInUser, opts ExportOptions, w io.Writer) error {
+	if opts.Format == "" {
+		opts.Format = FormatZip
+	}
+	aw, err := newArchiveWriter(w, opts.Format)
+	if err != nil {
+		return err
+	}
+
+	manifest := Manifest{
+		Version:             ManifestVersion,
+		GrafanaVersion:      s.cfg.BuildVersion,
+		ExportedAt:          time.Now().UTC(),
+		OrgID:               usr.OrgID,
+		IncludesPermissions: opts.IncludePermissions,
+		Folders:             []ManifestEntry{},
+		Dashboards:          []ManifestEntry{},
+		LibraryElements:     []ManifestEntry{},
+		Datasources:         []ManifestDatasource{},
+	}
+
+	if err := s.exportFolders(ctx, usr, opts, aw, &manifest); err != nil {
+		return fmt.Errorf("failed to export folders: %w", err)
+	}
+	if err := s.exportLibraryElements(ctx, usr, aw, &manifest); err != nil {
+		return fmt.Errorf("failed to export library elements: %w", err)
+	}
+	if err := s.exportDashboards(ctx, usr, opts, aw, &manifest); err != nil {
+		return fmt.Errorf("failed to export dashboards: %w", err)
+	}
+
+	dataSources, err := s.dataSourceService.GetDataSources(ctx, &datasources.GetDataSourcesQuery{OrgID: usr.OrgID, User: usr})
+	if err != nil {
+		return fmt.Errorf("failed to list data sources: %w", err)
+	}
+	for _, ds := range dataSources {
+		manifest.Datasources = append(manifest.Datasources, ManifestDatasource{UID: ds.UID, Name: ds.Name, Type: ds.Type})
+	}
+
+	if err := writeJSON(aw, manifestPath, manifest); err != nil {
+		return err
+	}
+	return aw.Close()
+}
+
+func (s *OrgArchiveService) exportFolders(ctx context.Context, usr *user.SignedInUser, opts ExportOptions, aw archiveWriter, manifest *Manifest) error {
+	hits, err := s.search(ctx, usr, searchstore.TypeFolder)
+	if err != nil {
+		return err
+	}
+
+	for _, hit := range hits {
+		uid := hit.UID
+		f, err := s.folderService.Get(ctx, &folder.GetFolderQuery{OrgID: usr.OrgID, UID: &uid, SignedInUser: usr})
+		if err != nil {
+			return err
+		}
+
+		archived := Folder{UID: f.UID, Title: f.Title, Description: f.Description, ParentUID: f.ParentUID}
+		if opts.IncludePermissions {
+			if archived.Permissions, err = s.exportPermissions(ctx, usr, s.folderPermissions, f.UID); err != nil {
+				return err
+			}
+		}
+
+		entry := ManifestEntry{UID: f.UID, Title: f.Title, Path: "folders/" + url.PathEscape(f.UID) + ".json"}
+		if err := writeJSON(aw, entry.Path, archived); err != nil {
+			return err
+		}
+		manifest.Folders = append(manifest.Folders, entry)
+	}
+	return nil
+}
+
+func (s *OrgArchiveService) exportDashboards(ctx context.Context, usr *user.SignedInUser, opts ExportOptions, aw archiveWriter, manifest *Manifest) error {
+	hits, err := s.search(ctx, usr, searchstore.TypeDashboard)
+	if err != nil {
+		return err
+	}
+
+	for _, hit := range hits {
+		dash, err := s.dashboardService.GetDashboard(ctx, &dashboards.GetDashboardQuery{OrgID: usr.OrgID, UID: hit.UID})
+		if err != nil {
+			return err
+		}
+		// the id is specific to the instance
+		dash.Data.Del("id")
+
+		archived := Dashboard{UID: dash.UID, Title: dash.Title, FolderUID: hit.FolderUID, Dashboard: dash.Data}
+		if opts.IncludePermissions {
+			if archived.Permissions, err = s.exportPermissions(ctx, usr, s.dashboardPermissions, dash.UID); err != nil {
+				return err
+			}
+		}
+
+		entry := ManifestEntry{UID: dash.UID, Title: dash.Title, Path: "dashboards/" + url.PathEscape(dash.UID) + ".json"}
+		if err := writeJSON(aw, entry.Path, archived); err != nil {
+			return err
+		}
+		manifest.Dashboards = append(manifest.Dashboards, entry)
+	}
+	return nil
+}
+
+func (s *OrgArchiveService) exportLibraryElements(ctx context.Context, usr *user.SignedInUser, aw archiveWriter, manifest *Manifest) error {
+	for page := 1; ; page++ {
+		result, err := s.libraryElementService.GetAllElements(ctx, usr, model.SearchLibraryElementsQuery{
+			PerPage: libraryElementPageSize,
+			Page:    page,
+			Kind:    int(model.PanelElement),
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, element := range result.Elements {
+			archived := LibraryElement{
+				UID:         element.UID,
+				Name:        element.Name,
+				Kind:        element.Kind,
+				FolderUID:   element.FolderUID,
+				Model:       element.Model,
+				Description: element.Description,
+			}
+			entry := ManifestEntry{UID: element.UID, Title: element.Name, Path: "library-elements/" + url.PathEscape(element.UID) + ".json"}
+			if err := writeJSON(aw, entry.Path, archived); err != nil {
+				return err
+			}
+			manifest.LibraryElements = append(manifest.LibraryElements, entry)
+		}
+
+		if len(result.Elements) < libraryElementPageSize || int64(len(manifest.LibraryElements)) >= result.TotalCount {
+			return nil
+		}
+	}
+}
+
+// exportPermissions returns the permissions managed on the resource itself, inherited
+// permissions are recreated by importing the parent folders.
+func (s *OrgArchiveService) exportPermissions(ctx context.Context, usr *user.SignedInUser, svc accesscontrol.PermissionsService, uid string) ([]Permission, error) {
+	resourcePermissions, err := svc.GetPermissions(ctx, usr, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := make([]Permission, 0, len(resourcePermissions))
+	for _, p := range resourcePermissions {
+		if !p.IsManaged || p.IsInherited {
+			continue
+		}
+		level := svc.MapActions(p)
+		if level == "" {
+			continue
+		}
+		permissions = append(permissions, Permission{
+			UserLogin:   p.UserLogin,
+			Team:        p.Team,
+			BuiltInRole: p.BuiltInRole,
+			Permission:  level,
+		})
+	}
+	return permissions, nil
+}
+
+// search returns all folders or dashboards of the given type the user can read.
+func (s *OrgArchiveService) search(ctx context.Context, usr *user.SignedInUser, hitType string) ([]dashboards.DashboardSearchProjection, error) {
+	seen := make(map[string]bool)
+	hits := make([]dashboards.DashboardSearchProjection, 0)
+	for page := int64(1); ; page++ {
+		res, err := s.dashboardService.FindDashboards(ctx, &dashboards.FindPersistedDashboardsQuery{
+			OrgId:        usr.OrgID,
+			SignedInUser: usr,
+			Type:         hitType,
+			Limit:        searchPageSize,
+			Page:         page,
+			Permission:   dashboards.PERMISSION_VIEW,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, hit := range res {
+			// the search returns a row per tag
+			if seen[hit.UID] {
+				continue
+			}
+			seen[hit.UID] = true
+			hits = append(hits, hit)
+		}
+
+		if len(res) < searchPageSize {
+			return hits, nil
+		}
+	}
+}
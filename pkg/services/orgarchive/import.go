@@ -0,0 +1,465 @@
+package orgarchive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/dashboardimport"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/folder"
+	"github.com/grafana/grafana/pkg/services/libraryelements/model"
+	"github.com/grafana/grafana/pkg/services/team"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+// importer holds the state of a single import.
+type importer struct {
+	*OrgArchiveService
+	usr     *user.SignedInUser
+	opts    ImportOptions
+	archive *archive
+	result  *ImportResult
+
+	// folderUIDs and libraryElementUIDs map UIDs of the archive to the UIDs used in the target
+	// organization, which differ when entities are renamed.
+	folderUIDs         map[string]string
+	folderIDs          map[string]int64
+	libraryElementUIDs map[string]string
+}
+
+// Import imports an archive created by Export into the organization of the user. Failing to import
+// a single entity does not abort the import, the outcome of each entity is reported in the result.
+func (s *OrgArchiveService) Import(ctx context.Context, usr *user.SignedInUser, data []byte, opts ImportOptions) (*ImportResult, error) {
+	if opts.ConflictStrategy == "" {
+		opts.ConflictStrategy = ConflictSkip
+	}
+	if !opts.ConflictStrategy.IsValid() {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidConflictStrategy, opts.ConflictStrategy)
+	}
+
+	a, err := readArchive(data)
+	if err != nil {
+		return nil, err
+	}
+
+	imp := &importer{
+		OrgArchiveService: s,
+		usr:               usr,
+		opts:              opts,
+		archive:           a,
+		result: &ImportResult{
+			Folders:         []ImportedEntity{},
+			Dashboards:      []ImportedEntity{},
+			LibraryElements: []ImportedEntity{},
+			Warnings:        []string{},
+		},
+		folderUIDs:         make(map[string]string),
+		folderIDs:          make(map[string]int64),
+		libraryElementUIDs: make(map[string]string),
+	}
+
+	if imp.result.DatasourceUIDMapping, err = imp.datasourceMapping(ctx); err != nil {
+		return nil, err
+	}
+	if err := imp.importFolders(ctx); err != nil {
+		return nil, err
+	}
+	if err := imp.importLibraryElements(ctx); err != nil {
+		return nil, err
+	}
+	if err := imp.importDashboards(ctx); err != nil {
+		return nil, err
+	}
+	return imp.result, nil
+}
+
+// datasourceMapping completes the requested mapping with the archived datasources which do not
+// exist in the target organization but have a datasource with the same name and type.
+func (imp *importer) datasourceMapping(ctx context.Context) (map[string]string, error) {
+	mapping := make(map[string]string, len(imp.opts.DatasourceUIDMapping))
+	for from, to := range imp.opts.DatasourceUIDMapping {
+		mapping[from] = to
+	}
+
+	existing, err := imp.dataSourceService.GetDataSources(ctx, &datasources.GetDataSourcesQuery{OrgID: imp.usr.OrgID, User: imp.usr})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list data sources: %w", err)
+	}
+	byUID := make(map[string]bool, len(existing))
+	byNameAndType := make(map[string]string, len(existing))
+	for _, ds := range existing {
+		byUID[ds.UID] = true
+		byNameAndType[ds.Type+"/"+ds.Name] = ds.UID
+	}
+
+	for _, ds := range imp.archive.manifest.Datasources {
+		if _, ok := mapping[ds.UID]; ok || byUID[ds.UID] {
+			continue
+		}
+		if uid, ok := byNameAndType[ds.Type+"/"+ds.Name]; ok {
+			mapping[ds.UID] = uid
+			continue
+		}
+		imp.warn("data source %q (%s) does not exist in the target organization", ds.Name, ds.UID)
+	}
+	return mapping, nil
+}
+
+func (imp *importer) importFolders(ctx context.Context) error {
+	folders := make([]Folder, 0, len(imp.archive.manifest.Folders))
+	for _, entry := range imp.archive.manifest.Folders {
+		var f Folder
+		if err := imp.archive.readJSON(entry, &f); err != nil {
+			return err
+		}
+		folders = append(folders, f)
+	}
+
+	for _, f := range sortFoldersByDepth(folders) {
+		entity := ImportedEntity{UID: f.UID, Title: f.Title}
+		created, err := imp.importFolder(ctx, f, &entity)
+		if err != nil {
+			entity.Status = ImportStatusFailed
+			entity.Error = err.Error()
+		} else {
+			imp.folderUIDs[f.UID] = created.UID
+			imp.folderIDs[created.UID] = created.ID
+			imp.importPermissions(ctx, imp.folderPermissions, entity, created.UID, f.Permissions)
+		}
+		imp.result.Folders = append(imp.result.Folders, entity)
+	}
+	return nil
+}
+
+func (imp *importer) importFolder(ctx context.Context, f Folder, entity *ImportedEntity) (*folder.Folder, error) {
+	uid := f.UID
+	existing, err := imp.folderService.Get(ctx, &folder.GetFolderQuery{OrgID: imp.usr.OrgID, UID: &uid, SignedInUser: imp.usr})
+	if err != nil && !errors.Is(err, dashboards.ErrFolderNotFound) && !errors.Is(err, folder.ErrFolderNotFound) {
+		return nil, err
+	}
+
+	cmd := &folder.CreateFolderCommand{
+		UID:          f.UID,
+		OrgID:        imp.usr.OrgID,
+		Title:        f.Title,
+		Description:  f.Description,
+		ParentUID:    imp.folderUID(f.ParentUID),
+		SignedInUser: imp.usr,
+	}
+	entity.Status = ImportStatusCreated
+	if existing != nil {
+		switch imp.opts.ConflictStrategy {
+		case ConflictSkip:
+			entity.Status = ImportStatusSkipped
+			return existing, nil
+		case ConflictOverwrite:
+			entity.Status = ImportStatusOverwritten
+			return imp.folderService.Update(ctx, &folder.UpdateFolderCommand{
+				UID:            existing.UID,
+				OrgID:          imp.usr.OrgID,
+				NewTitle:       &f.Title,
+				NewDescription: &f.Description,
+				Version:        existing.Version,
+				Overwrite:      true,
+				SignedInUser:   imp.usr,
+			})
+		case ConflictRename:
+			entity.Status = ImportStatusRenamed
+			cmd.UID = util.GenerateShortUID()
+			cmd.Title = renamedTitle(f.Title, cmd.UID)
+			entity.NewUID = cmd.UID
+		}
+	}
+	return imp.folderService.Create(ctx, cmd)
+}
+
+func (imp *importer) importLibraryElements(ctx context.Context) error {
+	for _, entry := range imp.archive.manifest.LibraryElements {
+		var element LibraryElement
+		if err := imp.archive.readJSON(entry, &element); err != nil {
+			return err
+		}
+
+		entity := ImportedEntity{UID: element.UID, Title: element.Name}
+		uid, err := imp.importLibraryElement(ctx, element, &entity)
+		if err != nil {
+			entity.Status = ImportStatusFailed
+			entity.Error = err.Error()
+		} else {
+			imp.libraryElementUIDs[element.UID] = uid
+		}
+		imp.result.LibraryElements = append(imp.result.LibraryElements, entity)
+	}
+	return nil
+}
+
+func (imp *importer) importLibraryElement(ctx context.Context, element LibraryElement, entity *ImportedEntity) (string, error) {
+	existing, err := imp.libraryElementService.GetElement(ctx, imp.usr, model.GetLibraryElementCommand{
+		UID:        element.UID,
+		FolderName: dashboards.RootFolderName,
+	})
+	found := err == nil
+	if err != nil && !errors.Is(err, model.ErrLibraryElementNotFound) {
+		return "", err
+	}
+
+	elementModel, err := rewriteJSON(element.Model, imp.result.DatasourceUIDMapping, nil)
+	if err != nil {
+		return "", err
+	}
+	folderID, err := imp.folderID(ctx, element.FolderUID)
+	if err != nil {
+		return "", err
+	}
+
+	cmd := model.CreateLibraryElementCommand{
+		FolderID: folderID,
+		Name:     element.Name,
+		Model:    elementModel,
+		Kind:     element.Kind,
+		UID:      element.UID,
+	}
+	entity.Status = ImportStatusCreated
+	if found {
+		switch imp.opts.ConflictStrategy {
+		case ConflictSkip:
+			entity.Status = ImportStatusSkipped
+			return existing.UID, nil
+		case ConflictOverwrite:
+			entity.Status = ImportStatusOverwritten
+			_, err := imp.libraryElementService.PatchElement(ctx, imp.usr, model.PatchLibraryElementCommand{
+				FolderID: folderID,
+				Name:     element.Name,
+				Model:    elementModel,
+				Kind:     element.Kind,
+				Version:  existing.Version,
+				UID:      existing.UID,
+			}, existing.UID)
+			return existing.UID, err
+		case ConflictRename:
+			entity.Status = ImportStatusRenamed
+			cmd.UID = util.GenerateShortUID()
+			cmd.Name = renamedTitle(element.Name, cmd.UID)
+			entity.NewUID = cmd.UID
+		}
+	}
+
+	created, err := imp.libraryElementService.CreateElement(ctx, imp.usr, cmd)
+	return created.UID, err
+}
+
+func (imp *importer) importDashboards(ctx context.Context) error {
+	for _, entry := range imp.archive.manifest.Dashboards {
+		var dash Dashboard
+		if err := imp.archive.readJSON(entry, &dash); err != nil {
+			return err
+		}
+
+		entity := ImportedEntity{UID: dash.UID, Title: dash.Title}
+		uid, err := imp.importDashboard(ctx, dash, &entity)
+		if err != nil {
+			entity.Status = ImportStatusFailed
+			entity.Error = err.Error()
+		} else {
+			imp.importPermissions(ctx, imp.dashboardPermissions, entity, uid, dash.Permissions)
+		}
+		imp.result.Dashboards = append(imp.result.Dashboards, entity)
+	}
+	return nil
+}
+
+func (imp *importer) importDashboard(ctx context.Context, dash Dashboard, entity *ImportedEntity) (string, error) {
+	if dash.Dashboard == nil {
+		return "", fmt.Errorf("dashboard %s has no model", dash.UID)
+	}
+
+	_, err := imp.dashboardService.GetDashboard(ctx, &dashboards.GetDashboardQuery{OrgID: imp.usr.OrgID, UID: dash.UID})
+	found := err == nil
+	if err != nil && !errors.Is(err, dashboards.ErrDashboardNotFound) {
+		return "", err
+	}
+
+	body := dash.Dashboard
+	rewriteReferences(body.Interface(), imp.result.DatasourceUIDMapping, imp.libraryElementUIDs)
+	body.Del("id")
+	body.Set("uid", dash.UID)
+
+	req := &dashboardimport.ImportDashboardRequest{
+		Dashboard: body,
+		FolderUid: imp.folderUID(dash.FolderUID),
+		User:      imp.usr,
+	}
+	entity.Status = ImportStatusCreated
+	if found {
+		switch imp.opts.ConflictStrategy {
+		case ConflictSkip:
+			entity.Status = ImportStatusSkipped
+			return dash.UID, nil
+		case ConflictOverwrite:
+			entity.Status = ImportStatusOverwritten
+			req.Overwrite = true
+		case ConflictRename:
+			entity.Status = ImportStatusRenamed
+			entity.NewUID = util.GenerateShortUID()
+			body.Set("uid", entity.NewUID)
+			body.Set("title", renamedTitle(dash.Title, entity.NewUID))
+		}
+	}
+
+	resp, err := imp.dashboardImportService.ImportDashboard(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	return resp.UID, nil
+}
+
+// importPermissions applies archived permissions to an imported resource. Users and teams
+// which cannot be found in the target organization are reported as warnings.
+func (imp *importer) importPermissions(ctx context.Context, svc accesscontrol.PermissionsService, entity ImportedEntity, uid string, permissions []Permission) {
+	if !imp.opts.ImportPermissions || !imp.archive.manifest.IncludesPermissions || entity.Status == ImportStatusSkipped || len(permissions) == 0 {
+		return
+	}
+
+	commands := make([]accesscontrol.SetResourcePermissionCommand, 0, len(permissions))
+	for _, p := range permissions {
+		cmd := accesscontrol.SetResourcePermissionCommand{Permission: p.Permission}
+		switch {
+		case p.UserLogin != "":
+			usr, err := imp.userService.GetByLogin(ctx, &user.GetUserByLoginQuery{LoginOrEmail: p.UserLogin})
+			if err != nil {
+				imp.warn("permission of user %q on %q was not imported: %s", p.UserLogin, entity.Title, err)
+				continue
+			}
+			cmd.UserID = usr.ID
+		case p.Team != "":
+			res, err := imp.teamService.SearchTeams(ctx, &team.SearchTeamsQuery{OrgID: imp.usr.OrgID, Name: p.Team, Limit: 1, SignedInUser: imp.usr})
+			if err != nil || len(res.Teams) == 0 {
+				imp.warn("permission of team %q on %q was not imported: team not found", p.Team, entity.Title)
+				continue
+			}
+			cmd.TeamID = res.Teams[0].ID
+		case p.BuiltInRole != "":
+			cmd.BuiltinRole = p.BuiltInRole
+		default:
+			continue
+		}
+		commands = append(commands, cmd)
+	}
+
+	if _, err := svc.SetPermissions(ctx, imp.usr.OrgID, uid, commands...); err != nil {
+		imp.warn("permissions on %q were not imported: %s", entity.Title, err)
+	}
+}
+
+// folderUID returns the UID of the imported folder for a folder UID of the archive.
+func (imp *importer) folderUID(uid string) string {
+	if newUID, ok := imp.folderUIDs[uid]; ok {
+		return newUID
+	}
+	return uid
+}
+
+// folderID returns the ID of the imported folder for a folder UID of the archive.
+func (imp *importer) folderID(ctx context.Context, uid string) (int64, error) {
+	uid = imp.folderUID(uid)
+	if uid == "" {
+		return 0, nil
+	}
+	if id, ok := imp.folderIDs[uid]; ok {
+		return id, nil
+	}
+	f, err := imp.folderService.Get(ctx, &folder.GetFolderQuery{OrgID: imp.usr.OrgID, UID: &uid, SignedInUser: imp.usr})
+	if err != nil {
+		return 0, err
+	}
+	imp.folderIDs[uid] = f.ID
+	return f.ID, nil
+}
+
+func (imp *importer) warn(format string, args ...any) {
+	imp.result.Warnings = append(imp.result.Warnings, fmt.Sprintf(format, args...))
+}
+
+// sortFoldersByDepth orders folders so that parents are imported before their children.
+func sortFoldersByDepth(folders []Folder) []Folder {
+	parents := make(map[string]string, len(folders))
+	for _, f := range folders {
+		parents[f.UID] = f.ParentUID
+	}
+	depth := func(uid string) int {
+		d := 0
+		// bounded to protect against cycles in a hand edited archive
+		for p, ok := parents[uid]; ok && p != "" && d < len(folders); p, ok = parents[p] {
+			d++
+		}
+		return d
+	}
+
+	sorted := make([]Folder, len(folders))
+	copy(sorted, folders)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return depth(sorted[i].UID) < depth(sorted[j].UID)
+	})
+	return sorted
+}
+
+func renamedTitle(title, uid string) string {
+	return fmt.Sprintf("%s (%s)", title, uid)
+}
+
+// rewriteJSON applies rewriteReferences to a raw JSON model.
+func rewriteJSON(raw json.RawMessage, datasourceUIDs, libraryPanelUIDs map[string]string) (json.RawMessage, error) {
+	if len(raw) == 0 {
+		return raw, nil
+	}
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, err
+	}
+	rewriteReferences(v, datasourceUIDs, libraryPanelUIDs)
+	return json.Marshal(v)
+}
+
+// rewriteReferences replaces, anywhere in a dashboard or panel model, the datasource UIDs
+// and library panel UIDs found in the given mappings.
+func rewriteReferences(v any, datasourceUIDs, libraryPanelUIDs map[string]string) {
+	switch node := v.(type) {
+	case map[string]any:
+		for key, child := range node {
+			switch key {
+			case "datasource":
+				switch ref := child.(type) {
+				case string:
+					if uid, ok := datasourceUIDs[ref]; ok {
+						node[key] = uid
+					}
+				case map[string]any:
+					replaceUID(ref, datasourceUIDs)
+				}
+			case "libraryPanel":
+				if ref, ok := child.(map[string]any); ok {
+					replaceUID(ref, libraryPanelUIDs)
+				}
+			}
+			rewriteReferences(child, datasourceUIDs, libraryPanelUIDs)
+		}
+	case []any:
+		for _, child := range node {
+			rewriteReferences(child, datasourceUIDs, libraryPanelUIDs)
+		}
+	}
+}
+
+func replaceUID(ref map[string]any, mapping map[string]string) {
+	if uid, ok := ref["uid"].(string); ok {
+		if newUID, ok := mapping[uid]; ok {
+			ref["uid"] = newUID
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package orgarchive
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+)
+
+// ManifestVersion is the version of the archive layout written by this package.
+const ManifestVersion = 1
+
+var (
+	ErrManifestMissing          = errors.New("archive does not contain a manifest")
+	ErrUnsupportedVersion       = errors.New("unsupported archive version")
+	ErrUnsupportedFormat        = errors.New("unsupported archive format")
+	ErrInvalidConflictStrategy  = errors.New("invalid conflict strategy")
+	ErrArchiveEntryMissing      = errors.New("archive entry referenced by the manifest is missing")
+	ErrArchiveEntryPathNotValid = errors.New("archive entry path is not valid")
+	ErrArchiveTooLarge          = errors.New("archive exceeds the size limits")
+)
+
+// Format is the container format of an archive.
+type Format string
+
+const (
+	FormatZip Format = "zip"
+	// FormatTar is a gzip compressed tarball.
+	FormatTar Format = "tar"
+)
+
+func (f Format) IsValid() bool {
+	return f == FormatZip || f == FormatTar
+}
+
+// ConflictStrategy decides what happens when an entity with the same UID already exists in the target organization.
+type ConflictStrategy string
+
+const (
+	// ConflictSkip keeps the existing entity.
+	ConflictSkip ConflictStrategy = "skip"
+	// ConflictOverwrite replaces the existing entity.
+	ConflictOverwrite ConflictStrategy = "overwrite"
+	// ConflictRename imports the entity next to the existing one with a new UID and title.
+	ConflictRename ConflictStrategy = "rename"
+)
+
+func (s ConflictStrategy) IsValid() bool {
+	return s == ConflictSkip || s == ConflictOverwrite || s == ConflictRename
+}
+
+// Manifest describes the content of an archive. It is stored as manifest.json at the root of the archive.
+type Manifest struct {
+	Version             int                  `json:"version"`
+	GrafanaVersion      string               `json:"grafanaVersion"`
+	ExportedAt          time.Time            `json:"exportedAt"`
+	OrgID               int64                `json:"orgId"`
+	IncludesPermissions bool                 `json:"includesPermissions"`
+	Folders             []ManifestEntry      `json:"folders"`
+	Dashboards          []ManifestEntry      `json:"dashboards"`
+	LibraryElements     []ManifestEntry      `json:"libraryElements"`
+	Datasources         []ManifestDatasource `json:"datasources"`
+}
+
+// ManifestEntry points to an entity file in the archive.
+type ManifestEntry struct {
+	UID   string `json:"uid"`
+	Title string `json:"title"`
+	Path  string `json:"path"`
+}
+
+// ManifestDatasource describes a datasource of the exported organization. Datasources are
+// not exported, they are listed to help building the datasource UID mapping used on import.
+type ManifestDatasource struct {
+	UID  string `json:"uid"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Folder is the archived form of a folder.
+type Folder struct {
+	UID         string       `json:"uid"`
+	Title       string       `json:"title"`
+	Description string       `json:"description,omitempty"`
+	ParentUID   string       `json:"parentUid,omitempty"`
+	Permissions []Permission `json:"permissions,omitempty"`
+}
+
+// Dashboard is the archived form of a dashboard.
+type Dashboard struct {
+	UID         string           `json:"uid"`
+	Title       string           `json:"title"`
+	FolderUID   string           `json:"folderUid,omitempty"`
+	Dashboard   *simplejson.Json `json:"dashboard"`
+	Permissions []Permission     `json:"permissions,omitempty"`
+}
+
+// LibraryElement is the archived form of a library element.
+type LibraryElement struct {
+	UID         string          `json:"uid"`
+	Name        string          `json:"name"`
+	Kind        int64           `json:"kind"`
+	FolderUID   string          `json:"folderUid,omitempty"`
+	Model       json.RawMessage `json:"model"`
+	Description string          `json:"description,omitempty"`
+}
+
+// Permission is a managed permission of a folder or dashboard. Users and teams are referenced
+// by login and name so that they can be resolved in another instance.
+type Permission struct {
+	UserLogin   string `json:"userLogin,omitempty"`
+	Team        string `json:"team,omitempty"`
+	BuiltInRole string `json:"builtInRole,omitempty"`
+	// Permission is the permission level, one of View, Edit or Admin.
+	Permission string `json:"permission"`
+}
+
+// ExportOptions are the options of an organization export.
+type ExportOptions struct {
+	Format             Format `json:"format"`
+	IncludePermissions bool   `json:"includePermissions"`
+}
+
+// ImportOptions are the options of an organization import.
+type ImportOptions struct {
+	ConflictStrategy ConflictStrategy `json:"conflictStrategy"`
+	// DatasourceUIDMapping maps datasource UIDs of the archive to datasource UIDs of the target
+	// organization. Unmapped datasources which do not exist in the target organization are matched
+	// by name and type.
+	DatasourceUIDMapping map[string]string `json:"datasourceUidMapping"`
+	// ImportPermissions applies the archived permissions. It has no effect when the archive does
+	// not include permissions.
+	ImportPermissions bool `json:"importPermissions"`
+}
+
+// ImportStatus is the outcome of importing a single entity.
+type ImportStatus string
+
+const (
+	ImportStatusCreated     ImportStatus = "created"
+	ImportStatusOverwritten ImportStatus = "overwritten"
+	ImportStatusRenamed     ImportStatus = "renamed"
+	ImportStatusSkipped     ImportStatus = "skipped"
+	ImportStatusFailed      ImportStatus = "failed"
+)
+
+// ImportedEntity reports the outcome of importing a single entity.
+type ImportedEntity struct {
+	UID string `json:"uid"`
+	// NewUID is set when the entity was renamed.
+	NewUID string       `json:"newUid,omitempty"`
+	Title  string       `json:"title"`
+	Status ImportStatus `json:"status"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// ImportResult reports the outcome of an organization import.
+type ImportResult struct {
+	Folders         []ImportedEntity `json:"folders"`
+	Dashboards      []ImportedEntity `json:"dashboards"`
+	LibraryElements []ImportedEntity `json:"libraryElements"`
+	// DatasourceUIDMapping is the datasource mapping which was applied, including the datasources matched by name.
+	DatasourceUIDMapping map[string]string `json:"datasourceUidMapping"`
+	Warnings             []string          `json:"warnings"`
+}
@@ -0,0 +1,86 @@
+package orgarchive
+
+import (
+	"context"
+	"io"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/dashboardimport"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/folder"
+	"github.com/grafana/grafana/pkg/services/libraryelements"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/team"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// Service exports the folders, dashboards and library elements of an organization to a single
+// archive, and imports such archives into another organization or instance.
+type Service interface {
+	Export(ctx context.Context, usr *user.SignedInUser, opts ExportOptions, w io.Writer) error
+	Import(ctx context.Context, usr *user.SignedInUser, data []byte, opts ImportOptions) (*ImportResult, error)
+}
+
+type OrgArchiveService struct {
+	cfg                    *setting.Cfg
+	routeRegister          routing.RouteRegister
+	accessControl          accesscontrol.AccessControl
+	dashboardService       dashboards.DashboardService
+	dashboardImportService dashboardimport.Service
+	folderService          folder.Service
+	libraryElementService  libraryelements.Service
+	dataSourceService      datasources.DataSourceService
+	folderPermissions      accesscontrol.FolderPermissionsService
+	dashboardPermissions   accesscontrol.DashboardPermissionsService
+	userService            user.Service
+	teamService            team.Service
+	log                    log.Logger
+}
+
+var _ Service = (*OrgArchiveService)(nil)
+
+func ProvideService(cfg *setting.Cfg, routeRegister routing.RouteRegister, ac accesscontrol.AccessControl,
+	dashboardService dashboards.DashboardService, dashboardImportService dashboardimport.Service,
+	folderService folder.Service, libraryElementService libraryelements.Service,
+	dataSourceService datasources.DataSourceService, folderPermissions accesscontrol.FolderPermissionsService,
+	dashboardPermissions accesscontrol.DashboardPermissionsService, userService user.Service, teamService team.Service,
+) *OrgArchiveService {
+	s := &OrgArchiveService{
+		cfg:                    cfg,
+		routeRegister:          routeRegister,
+		accessControl:          ac,
+		dashboardService:       dashboardService,
+		dashboardImportService: dashboardImportService,
+		folderService:          folderService,
+		libraryElementService:  libraryElementService,
+		dataSourceService:      dataSourceService,
+		folderPermissions:      folderPermissions,
+		dashboardPermissions:   dashboardPermissions,
+		userService:            userService,
+		teamService:            teamService,
+		log:                    log.New("orgarchive"),
+	}
+	s.registerAPIEndpoints()
+	return s
+}
+
+// BackgroundUser returns the identity used to export or import an organization outside of
+// a request, for example from grafana-cli.
+func BackgroundUser(orgID int64) *user.SignedInUser {
+	actions := []string{
+		dashboards.ActionFoldersCreate, dashboards.ActionFoldersRead, dashboards.ActionFoldersWrite,
+		dashboards.ActionFoldersPermissionsRead, dashboards.ActionFoldersPermissionsWrite,
+		dashboards.ActionDashboardsCreate, dashboards.ActionDashboardsRead, dashboards.ActionDashboardsWrite,
+		dashboards.ActionDashboardsPermissionsRead, dashboards.ActionDashboardsPermissionsWrite,
+		datasources.ActionRead, accesscontrol.ActionTeamsRead, accesscontrol.ActionOrgUsersRead,
+	}
+	permissions := make([]accesscontrol.Permission, 0, len(actions))
+	for _, action := range actions {
+		permissions = append(permissions, accesscontrol.Permission{Action: action, Scope: "*"})
+	}
+	return accesscontrol.BackgroundUser("org_archive", orgID, org.RoleAdmin, permissions).(*user.SignedInUser)
+}
@@ -0,0 +1,184 @@
+package clientmiddleware
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/services/datasources"
+)
+
+// NewDatasourceCredentialsMiddleware creates a new plugins.ClientMiddleware that will
+// send the outgoing plugins.Client requests with the active credential of the datasource
+// when it has secondary secrets, and send the queries and health checks again with the
+// other credential when the active one fails to authenticate.
+func NewDatasourceCredentialsMiddleware(credentials datasources.CredentialService) plugins.ClientMiddleware {
+	return plugins.ClientMiddlewareFunc(func(next plugins.Client) plugins.Client {
+		return &DatasourceCredentialsMiddleware{
+			next:        next,
+			credentials: credentials,
+		}
+	})
+}
+
+type DatasourceCredentialsMiddleware struct {
+	credentials datasources.CredentialService
+	next        plugins.Client
+}
+
+// withCredential returns a copy of the plugin context using the secrets of the credential. The settings are
+// considered updated when the credential became active, so that the plugin creates a new instance with the
+// secrets of the credential.
+func withCredential(pCtx backend.PluginContext, credential datasources.Credential, since time.Time) backend.PluginContext {
+	settings := *pCtx.DataSourceInstanceSettings
+	settings.DecryptedSecureJSONData = datasources.CredentialSecrets(settings.DecryptedSecureJSONData, credential)
+	settings.Updated = since
+	pCtx.DataSourceInstanceSettings = &settings
+	return pCtx
+}
+
+// rotatedDataSource returns the datasource of the plugin context if it has secondary secrets.
+func rotatedDataSource(pCtx backend.PluginContext) (*datasources.DataSource, bool) {
+	settings := pCtx.DataSourceInstanceSettings
+	if settings == nil || !datasources.HasSecondarySecrets(settings.DecryptedSecureJSONData) {
+		return nil, false
+	}
+	return &datasources.DataSource{
+		OrgID:   pCtx.OrgID,
+		UID:     settings.UID,
+		Updated: settings.Updated,
+	}, true
+}
+
+func isAuthenticationFailureResponse(resp backend.DataResponse) bool {
+	if resp.Error == nil {
+		return false
+	}
+	return resp.Status == backend.StatusUnauthorized ||
+		datasources.IsAuthenticationFailure(resp.Error)
+}
+
+// isQueryAuthenticationFailure returns true if every query failed because the credentials were rejected.
+func isQueryAuthenticationFailure(resp *backend.QueryDataResponse, err error) bool {
+	if err != nil {
+		return datasources.IsAuthenticationFailure(err)
+	}
+	if resp == nil || len(resp.Responses) == 0 {
+		return false
+	}
+	for _, r := range resp.Responses {
+		if !isAuthenticationFailureResponse(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isHealthAuthenticationFailure(result *backend.CheckHealthResult, err error) bool {
+	if err != nil {
+		return datasources.IsAuthenticationFailure(err)
+	}
+	return result != nil && result.Status == backend.HealthStatusError &&
+		datasources.IsAuthenticationFailure(errors.New(result.Message))
+}
+
+func (m *DatasourceCredentialsMiddleware) QueryData(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+	if req == nil {
+		return m.next.QueryData(ctx, req)
+	}
+	ds, ok := rotatedDataSource(req.PluginContext)
+	if !ok {
+		return m.next.QueryData(ctx, req)
+	}
+
+	pCtx := req.PluginContext
+	active, since := m.credentials.ActiveCredential(ds)
+	req.PluginContext = withCredential(pCtx, active, since)
+	resp, err := m.next.QueryData(ctx, req)
+	if !isQueryAuthenticationFailure(resp, err) {
+		if err == nil {
+			m.credentials.RecordCredentialUsage(ctx, ds, active)
+		}
+		return resp, err
+	}
+
+	other := active.Other()
+	since = time.Now()
+	retry := *req
+	retry.PluginContext = withCredential(pCtx, other, since)
+	retryResp, retryErr := m.next.QueryData(ctx, &retry)
+	if retryErr != nil || isQueryAuthenticationFailure(retryResp, nil) {
+		return resp, err
+	}
+
+	m.credentials.UseCredential(ds, other, since)
+	m.credentials.RecordCredentialUsage(ctx, ds, other)
+	return retryResp, nil
+}
+
+func (m *DatasourceCredentialsMiddleware) CallResource(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
+	if req == nil {
+		return m.next.CallResource(ctx, req, sender)
+	}
+	// the responses of the resources are streamed to the sender, they cannot be sent again with the other
+	// credential so only the active one is used
+	if ds, ok := rotatedDataSource(req.PluginContext); ok {
+		active, since := m.credentials.ActiveCredential(ds)
+		req.PluginContext = withCredential(req.PluginContext, active, since)
+	}
+
+	return m.next.CallResource(ctx, req, sender)
+}
+
+func (m *DatasourceCredentialsMiddleware) CheckHealth(ctx context.Context, req *backend.CheckHealthRequest) (*backend.CheckHealthResult, error) {
+	if req == nil {
+		return m.next.CheckHealth(ctx, req)
+	}
+	ds, ok := rotatedDataSource(req.PluginContext)
+	if !ok {
+		return m.next.CheckHealth(ctx, req)
+	}
+
+	pCtx := req.PluginContext
+	active, since := m.credentials.ActiveCredential(ds)
+	req.PluginContext = withCredential(pCtx, active, since)
+	result, err := m.next.CheckHealth(ctx, req)
+	if !isHealthAuthenticationFailure(result, err) {
+		if err == nil && result != nil && result.Status == backend.HealthStatusOk {
+			m.credentials.RecordCredentialUsage(ctx, ds, active)
+		}
+		return result, err
+	}
+
+	other := active.Other()
+	since = time.Now()
+	retry := *req
+	retry.PluginContext = withCredential(pCtx, other, since)
+	retryResult, retryErr := m.next.CheckHealth(ctx, &retry)
+	if retryErr != nil || retryResult == nil || retryResult.Status != backend.HealthStatusOk {
+		return result, err
+	}
+
+	m.credentials.UseCredential(ds, other, since)
+	m.credentials.RecordCredentialUsage(ctx, ds, other)
+	return retryResult, nil
+}
+
+func (m *DatasourceCredentialsMiddleware) CollectMetrics(ctx context.Context, req *backend.CollectMetricsRequest) (*backend.CollectMetricsResult, error) {
+	return m.next.CollectMetrics(ctx, req)
+}
+
+func (m *DatasourceCredentialsMiddleware) SubscribeStream(ctx context.Context, req *backend.SubscribeStreamRequest) (*backend.SubscribeStreamResponse, error) {
+	return m.next.SubscribeStream(ctx, req)
+}
+
+func (m *DatasourceCredentialsMiddleware) PublishStream(ctx context.Context, req *backend.PublishStreamRequest) (*backend.PublishStreamResponse, error) {
+	return m.next.PublishStream(ctx, req)
+}
+
+func (m *DatasourceCredentialsMiddleware) RunStream(ctx context.Context, req *backend.RunStreamRequest, sender *backend.StreamSender) error {
+	return m.next.RunStream(ctx, req, sender)
+}
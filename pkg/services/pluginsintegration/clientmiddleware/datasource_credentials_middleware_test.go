@@ -0,0 +1,162 @@
+package clientmiddleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/plugins/manager/client/clienttest"
+	"github.com/grafana/grafana/pkg/services/datasources"
+)
+
+func TestDatasourceCredentialsMiddleware(t *testing.T) {
+	updated := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	pluginCtx := backend.PluginContext{
+		OrgID: 1,
+		DataSourceInstanceSettings: &backend.DataSourceInstanceSettings{
+			UID:     "ds",
+			Updated: updated,
+			DecryptedSecureJSONData: map[string]string{
+				"apiKey":           "old",
+				"apiKey.secondary": "new",
+			},
+		},
+	}
+
+	// queryData authenticates the queries sent with the api key
+	queryData := func(apiKey string, sent *[]string) backend.QueryDataHandlerFunc {
+		return func(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+			key := req.PluginContext.DataSourceInstanceSettings.DecryptedSecureJSONData["apiKey"]
+			*sent = append(*sent, key)
+			resp := backend.NewQueryDataResponse()
+			if key != apiKey {
+				resp.Responses["A"] = backend.ErrDataResponse(backend.StatusUnauthorized, "invalid api key")
+				return resp, nil
+			}
+			resp.Responses["A"] = backend.DataResponse{}
+			return resp, nil
+		}
+	}
+
+	t.Run("Should send the queries with the primary credential while it authenticates", func(t *testing.T) {
+		credentials := &fakeCredentialService{}
+		cdt := clienttest.NewClientDecoratorTest(t, clienttest.WithMiddlewares(NewDatasourceCredentialsMiddleware(credentials)))
+		var sent []string
+		cdt.TestClient.QueryDataFunc = queryData("old", &sent)
+
+		resp, err := cdt.Decorator.QueryData(context.Background(), &backend.QueryDataRequest{PluginContext: pluginCtx})
+		require.NoError(t, err)
+		require.NoError(t, resp.Responses["A"].Error)
+		require.Equal(t, []string{"old"}, sent)
+		require.Equal(t, []datasources.Credential{datasources.CredentialPrimary}, credentials.recorded)
+		require.Contains(t, pluginCtx.DataSourceInstanceSettings.DecryptedSecureJSONData, "apiKey.secondary")
+	})
+
+	t.Run("Should send the queries again with the secondary credential when the primary one fails to authenticate", func(t *testing.T) {
+		credentials := &fakeCredentialService{}
+		cdt := clienttest.NewClientDecoratorTest(t, clienttest.WithMiddlewares(NewDatasourceCredentialsMiddleware(credentials)))
+		var sent []string
+		cdt.TestClient.QueryDataFunc = queryData("new", &sent)
+
+		resp, err := cdt.Decorator.QueryData(context.Background(), &backend.QueryDataRequest{PluginContext: pluginCtx})
+		require.NoError(t, err)
+		require.NoError(t, resp.Responses["A"].Error)
+		require.Equal(t, []string{"old", "new"}, sent)
+		require.Equal(t, datasources.CredentialSecondary, credentials.active)
+		require.True(t, credentials.since.After(updated))
+		require.Equal(t, []datasources.Credential{datasources.CredentialSecondary}, credentials.recorded)
+
+		sent = nil
+		_, err = cdt.Decorator.QueryData(context.Background(), &backend.QueryDataRequest{PluginContext: pluginCtx})
+		require.NoError(t, err)
+		require.Equal(t, []string{"new"}, sent)
+	})
+
+	t.Run("Should return the response of the active credential when both fail to authenticate", func(t *testing.T) {
+		credentials := &fakeCredentialService{}
+		cdt := clienttest.NewClientDecoratorTest(t, clienttest.WithMiddlewares(NewDatasourceCredentialsMiddleware(credentials)))
+		var sent []string
+		cdt.TestClient.QueryDataFunc = queryData("other", &sent)
+
+		resp, err := cdt.Decorator.QueryData(context.Background(), &backend.QueryDataRequest{PluginContext: pluginCtx})
+		require.NoError(t, err)
+		require.Equal(t, backend.StatusUnauthorized, resp.Responses["A"].Status)
+		require.Equal(t, []string{"old", "new"}, sent)
+		require.Empty(t, credentials.active)
+		require.Empty(t, credentials.recorded)
+	})
+
+	t.Run("Should not send the queries again with the secondary credential when the primary one is forbidden", func(t *testing.T) {
+		credentials := &fakeCredentialService{}
+		cdt := clienttest.NewClientDecoratorTest(t, clienttest.WithMiddlewares(NewDatasourceCredentialsMiddleware(credentials)))
+		var sent []string
+		cdt.TestClient.QueryDataFunc = func(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+			sent = append(sent, req.PluginContext.DataSourceInstanceSettings.DecryptedSecureJSONData["apiKey"])
+			resp := backend.NewQueryDataResponse()
+			resp.Responses["A"] = backend.ErrDataResponse(backend.StatusForbidden, "request failed with status code 403")
+			return resp, nil
+		}
+
+		resp, err := cdt.Decorator.QueryData(context.Background(), &backend.QueryDataRequest{PluginContext: pluginCtx})
+		require.NoError(t, err)
+		require.Equal(t, backend.StatusForbidden, resp.Responses["A"].Status)
+		require.Equal(t, []string{"old"}, sent)
+		require.Empty(t, credentials.active)
+	})
+
+	t.Run("Should check the health again with the secondary credential when the primary one fails to authenticate", func(t *testing.T) {
+		credentials := &fakeCredentialService{}
+		cdt := clienttest.NewClientDecoratorTest(t, clienttest.WithMiddlewares(NewDatasourceCredentialsMiddleware(credentials)))
+		cdt.TestClient.CheckHealthFunc = func(ctx context.Context, req *backend.CheckHealthRequest) (*backend.CheckHealthResult, error) {
+			if req.PluginContext.DataSourceInstanceSettings.DecryptedSecureJSONData["apiKey"] != "new" {
+				return &backend.CheckHealthResult{Status: backend.HealthStatusError, Message: "401 Unauthorized"}, nil
+			}
+			return &backend.CheckHealthResult{Status: backend.HealthStatusOk}, nil
+		}
+
+		result, err := cdt.Decorator.CheckHealth(context.Background(), &backend.CheckHealthRequest{PluginContext: pluginCtx})
+		require.NoError(t, err)
+		require.Equal(t, backend.HealthStatusOk, result.Status)
+		require.Equal(t, datasources.CredentialSecondary, credentials.active)
+	})
+
+	t.Run("Should leave the requests of the datasources without secondary secrets unchanged", func(t *testing.T) {
+		credentials := &fakeCredentialService{}
+		cdt := clienttest.NewClientDecoratorTest(t, clienttest.WithMiddlewares(NewDatasourceCredentialsMiddleware(credentials)))
+		pCtx := backend.PluginContext{
+			DataSourceInstanceSettings: &backend.DataSourceInstanceSettings{
+				DecryptedSecureJSONData: map[string]string{"apiKey": "old"},
+			},
+		}
+
+		err := cdt.Decorator.CallResource(context.Background(), &backend.CallResourceRequest{PluginContext: pCtx}, nopCallResourceSender)
+		require.NoError(t, err)
+		require.Same(t, pCtx.DataSourceInstanceSettings, cdt.CallResourceReq.PluginContext.DataSourceInstanceSettings)
+		require.Empty(t, credentials.recorded)
+	})
+}
+
+type fakeCredentialService struct {
+	active   datasources.Credential
+	since    time.Time
+	recorded []datasources.Credential
+}
+
+func (s *fakeCredentialService) ActiveCredential(ds *datasources.DataSource) (datasources.Credential, time.Time) {
+	if s.active == "" {
+		return datasources.CredentialPrimary, ds.Updated
+	}
+	return s.active, s.since
+}
+
+func (s *fakeCredentialService) UseCredential(ds *datasources.DataSource, credential datasources.Credential, since time.Time) {
+	s.active = credential
+	s.since = since
+}
+
+func (s *fakeCredentialService) RecordCredentialUsage(ctx context.Context, ds *datasources.DataSource, credential datasources.Credential) {
+	s.recorded = append(s.recorded, credential)
+}
@@ -0,0 +1,130 @@
+package accesscontrol
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+type configReader interface {
+	readConfig(path string) ([]*rolesAsConfig, error)
+}
+
+type configReaderImpl struct {
+	log log.Logger
+}
+
+func newConfigReader(logger log.Logger) configReader {
+	return &configReaderImpl{log: logger}
+}
+
+func (cr *configReaderImpl) readConfig(path string) ([]*rolesAsConfig, error) {
+	var configs []*rolesAsConfig
+	cr.log.Debug("Looking for access control provisioning files", "path", path)
+
+	files, err := os.ReadDir(path)
+	if err != nil {
+		cr.log.Error("Failed to read access control provisioning files from directory", "path", path, "error", err)
+		return configs, nil
+	}
+
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), ".yaml") || strings.HasSuffix(file.Name(), ".yml") {
+			cr.log.Debug("Parsing access control provisioning file", "path", path, "file.Name", file.Name())
+			cfg, err := cr.parseConfig(path, file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %q: %w", file.Name(), err)
+			}
+
+			if cfg != nil {
+				configs = append(configs, cfg)
+			}
+		}
+	}
+
+	if err := validateRequiredFields(configs); err != nil {
+		return nil, err
+	}
+
+	checkOrgID(configs)
+
+	return configs, nil
+}
+
+func (cr *configReaderImpl) parseConfig(path string, file fs.DirEntry) (*rolesAsConfig, error) {
+	filename, err := filepath.Abs(filepath.Join(path, file.Name()))
+	if err != nil {
+		return nil, err
+	}
+
+	// nolint:gosec
+	// We can ignore the gosec G304 warning on this one because `filename` comes from ps.Cfg.ProvisioningPath
+	yamlFile, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg *rolesAsConfigV2
+	if err := yaml.Unmarshal(yamlFile, &cfg); err != nil {
+		return nil, err
+	}
+
+	if cfg != nil {
+		for _, role := range cfg.Roles {
+			if len(role.From) > 0 {
+				return nil, fmt.Errorf("role %q: copying permissions from other roles is not supported", role.Name.Value())
+			}
+		}
+	}
+
+	return cfg.mapToRolesFromConfig(), nil
+}
+
+func validateRequiredFields(configs []*rolesAsConfig) error {
+	var errStrings []string
+	for _, cfg := range configs {
+		for index, role := range cfg.Roles {
+			// roles to delete can be identified by uid only
+			if role.Name == "" && (role.UID == "" || !role.Absent) {
+				errStrings = append(errStrings, fmt.Sprintf("role item %d in configuration doesn't contain required field name", index+1))
+			}
+		}
+
+		for index, team := range cfg.Teams {
+			if team.Name == "" {
+				errStrings = append(errStrings, fmt.Sprintf("team item %d in configuration doesn't contain required field name", index+1))
+			}
+			for _, role := range team.Roles {
+				if role.Name == "" && role.UID == "" {
+					errStrings = append(errStrings, fmt.Sprintf("role of team item %d in configuration doesn't contain required field name or uid", index+1))
+				}
+			}
+		}
+	}
+
+	if len(errStrings) != 0 {
+		return fmt.Errorf(strings.Join(errStrings, "\n"))
+	}
+	return nil
+}
+
+func checkOrgID(configs []*rolesAsConfig) {
+	for _, cfg := range configs {
+		for _, role := range cfg.Roles {
+			if role.OrgID < 1 {
+				role.OrgID = 1
+			}
+		}
+		for _, team := range cfg.Teams {
+			if team.OrgID < 1 {
+				team.OrgID = 1
+			}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package accesscontrol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
+)
+
+const (
+	brokenYaml        = "./testdata/test-configs/broken-yaml"
+	emptyFolder       = "./testdata/test-configs/empty_folder"
+	incorrectSettings = "./testdata/test-configs/incorrect-settings"
+	copyFrom          = "./testdata/test-configs/copy-from"
+	correctProperties = "./testdata/test-configs/correct-properties"
+)
+
+func TestConfigReader(t *testing.T) {
+	t.Run("Broken yaml should return error", func(t *testing.T) {
+		reader := newConfigReader(log.New("test logger"))
+		_, err := reader.readConfig(brokenYaml)
+		require.Error(t, err)
+	})
+
+	t.Run("Skip invalid directory", func(t *testing.T) {
+		reader := newConfigReader(log.New("test logger"))
+		cfg, err := reader.readConfig(emptyFolder)
+		require.NoError(t, err)
+		require.Len(t, cfg, 0)
+	})
+
+	t.Run("Read incorrect properties", func(t *testing.T) {
+		reader := newConfigReader(log.New("test logger"))
+		_, err := reader.readConfig(incorrectSettings)
+		require.Error(t, err)
+		require.Equal(t, "role item 1 in configuration doesn't contain required field name\nteam item 1 in configuration doesn't contain required field name", err.Error())
+	})
+
+	t.Run("Copying permissions from other roles should return error", func(t *testing.T) {
+		reader := newConfigReader(log.New("test logger"))
+		_, err := reader.readConfig(copyFrom)
+		require.ErrorContains(t, err, "copying permissions from other roles is not supported")
+	})
+
+	t.Run("Can read correct properties", func(t *testing.T) {
+		t.Setenv("USERS_SCOPE", "global.users:*")
+
+		reader := newConfigReader(log.New("test logger"))
+		cfg, err := reader.readConfig(correctProperties)
+		require.NoError(t, err)
+		require.Len(t, cfg, 1)
+
+		require.Equal(t, []*roleFromConfig{
+			{
+				UID:         "alerting_editor",
+				Name:        "custom:alerting:editor",
+				Description: "Edit alert rules of the alerting folder",
+				Version:     2,
+				OrgID:       1,
+				Permissions: []ac.Permission{
+					{Action: "alert.rules:read", Scope: "folders:uid:alerting"},
+					{Action: "alert.rules:write", Scope: "folders:uid:alerting"},
+				},
+			},
+			{
+				Name:        "custom:global:reader",
+				OrgID:       1,
+				Global:      true,
+				Permissions: []ac.Permission{{Action: "users:read", Scope: "global.users:*"}},
+			},
+			{
+				UID:         "obsolete",
+				OrgID:       1,
+				Absent:      true,
+				Force:       true,
+				Permissions: []ac.Permission{},
+			},
+		}, cfg[0].Roles)
+
+		require.Equal(t, []*teamFromConfig{
+			{
+				Name:  "Alerting",
+				OrgID: 2,
+				Roles: []*roleRefFromConfig{
+					{UID: "alerting_editor"},
+					{Name: "custom:global:reader", Absent: true},
+				},
+			},
+		}, cfg[0].Teams)
+	})
+}
@@ -0,0 +1,176 @@
+package accesscontrol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/accesscontrol/customroles"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/team"
+)
+
+// Provision scans a directory for provisioning config files
+// and provisions the custom roles and team role assignments in those files.
+func Provision(ctx context.Context, configDirectory string, roleService customroles.Service, teamService team.Service) error {
+	logger := log.New("provisioning.accesscontrol")
+	rp := RoleProvisioner{
+		log:         logger,
+		cfgProvider: newConfigReader(logger),
+		roleService: roleService,
+		teamService: teamService,
+	}
+	return rp.applyChanges(ctx, configDirectory)
+}
+
+// RoleProvisioner is responsible for provisioning custom roles and their assignments based on
+// configuration read by the `configReader`
+type RoleProvisioner struct {
+	log         log.Logger
+	cfgProvider configReader
+	roleService customroles.Service
+	teamService team.Service
+}
+
+func (rp *RoleProvisioner) applyChanges(ctx context.Context, configPath string) error {
+	configs, err := rp.cfgProvider.readConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	// roles are applied first so that assignments can reference roles from any file
+	for _, cfg := range configs {
+		for _, role := range cfg.Roles {
+			if err := rp.applyRole(ctx, role); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, cfg := range configs {
+		for _, t := range cfg.Teams {
+			if err := rp.applyTeam(ctx, t); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (rp *RoleProvisioner) applyRole(ctx context.Context, role *roleFromConfig) error {
+	existing, err := rp.findRole(ctx, role.OrgID, role.UID, role.Name)
+	if err != nil && !errors.Is(err, customroles.ErrRoleNotFound) {
+		return err
+	}
+
+	if role.Absent {
+		if existing == nil {
+			return nil
+		}
+		rp.log.Info("Deleting role from configuration", "name", existing.Name, "uid", existing.UID)
+		return rp.roleService.DeleteRole(ctx, customroles.DeleteRoleCommand{OrgID: role.OrgID, UID: existing.UID, Force: role.Force})
+	}
+
+	if existing == nil {
+		rp.log.Info("Inserting role from configuration", "name", role.Name, "uid", role.UID)
+		_, err := rp.roleService.CreateRole(ctx, customroles.CreateRoleCommand{
+			OrgID:       role.OrgID,
+			UID:         role.UID,
+			Name:        role.Name,
+			DisplayName: role.DisplayName,
+			Description: role.Description,
+			Group:       role.Group,
+			Hidden:      role.Hidden,
+			Global:      role.Global,
+			Version:     role.Version,
+			Permissions: role.Permissions,
+		})
+		return err
+	}
+
+	if role.Version <= existing.Version {
+		rp.log.Debug("Skipping role update, the provisioned version is not greater than the stored version",
+			"name", existing.Name, "version", role.Version, "storedVersion", existing.Version)
+		return nil
+	}
+
+	rp.log.Info("Updating role from configuration", "name", role.Name, "uid", existing.UID, "version", role.Version)
+	_, err = rp.roleService.UpdateRole(ctx, customroles.UpdateRoleCommand{
+		OrgID:       role.OrgID,
+		UID:         existing.UID,
+		Name:        role.Name,
+		DisplayName: role.DisplayName,
+		Description: role.Description,
+		Group:       role.Group,
+		Hidden:      role.Hidden,
+		Version:     role.Version,
+		Permissions: role.Permissions,
+	})
+	return err
+}
+
+func (rp *RoleProvisioner) applyTeam(ctx context.Context, t *teamFromConfig) error {
+	teamID, err := rp.findTeam(ctx, t.OrgID, t.Name)
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range t.Roles {
+		role, err := rp.findRole(ctx, t.OrgID, ref.UID, ref.Name)
+		if err != nil {
+			return fmt.Errorf("team %q: %w", t.Name, err)
+		}
+
+		if ref.Absent {
+			rp.log.Info("Removing role assignment from configuration", "team", t.Name, "role", role.Name)
+			err = rp.roleService.RemoveTeamRole(ctx, t.OrgID, teamID, role.UID)
+		} else {
+			rp.log.Info("Assigning role from configuration", "team", t.Name, "role", role.Name)
+			err = rp.roleService.AddTeamRole(ctx, t.OrgID, teamID, role.UID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// findRole looks up a role by uid, or by name when the uid is not provisioned
+func (rp *RoleProvisioner) findRole(ctx context.Context, orgID int64, uid, name string) (*ac.RoleDTO, error) {
+	if uid != "" {
+		return rp.roleService.GetRole(ctx, orgID, uid)
+	}
+
+	roles, err := rp.roleService.GetRoles(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range roles {
+		if role.Name == name {
+			return role, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", customroles.ErrRoleNotFound, name)
+}
+
+func (rp *RoleProvisioner) findTeam(ctx context.Context, orgID int64, name string) (int64, error) {
+	result, err := rp.teamService.SearchTeams(ctx, &team.SearchTeamsQuery{
+		OrgID: orgID,
+		Name:  name,
+		Limit: 1,
+		SignedInUser: ac.BackgroundUser("provisioning", orgID, org.RoleAdmin, []ac.Permission{
+			{Action: ac.ActionTeamsRead, Scope: ac.ScopeTeamsAll},
+		}),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(result.Teams) == 0 {
+		return 0, fmt.Errorf("team %q: %w", name, team.ErrTeamNotFound)
+	}
+	return result.Teams[0].ID, nil
+}
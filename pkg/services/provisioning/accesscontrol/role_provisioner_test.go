@@ -0,0 +1,138 @@
+package accesscontrol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/accesscontrol/customroles"
+	"github.com/grafana/grafana/pkg/services/team"
+	"github.com/grafana/grafana/pkg/services/team/teamtest"
+)
+
+func TestRoleProvisioner(t *testing.T) {
+	t.Run("Should return error when config reader returns error", func(t *testing.T) {
+		expectedErr := errors.New("test")
+		rp := RoleProvisioner{log: log.New("test"), cfgProvider: &testConfigReader{err: expectedErr}}
+		err := rp.applyChanges(context.Background(), "")
+		require.Equal(t, expectedErr, err)
+	})
+
+	t.Run("Should create, update and delete roles", func(t *testing.T) {
+		roles := &fakeRoleService{roles: []*ac.RoleDTO{
+			{UID: "outdated", Name: "custom:outdated", Version: 1},
+			{UID: "current", Name: "custom:current", Version: 3},
+			{UID: "obsolete", Name: "custom:obsolete", Version: 1},
+		}}
+		cfg := []*rolesAsConfig{{
+			Roles: []*roleFromConfig{
+				{UID: "new", Name: "custom:new", OrgID: 1},
+				{Name: "custom:outdated", OrgID: 1, Version: 2},
+				{UID: "current", Name: "custom:current", OrgID: 1, Version: 3},
+				{UID: "obsolete", OrgID: 1, Absent: true, Force: true},
+				{UID: "missing", OrgID: 1, Absent: true},
+			},
+		}}
+		rp := RoleProvisioner{log: log.New("test"), cfgProvider: &testConfigReader{result: cfg}, roleService: roles}
+
+		require.NoError(t, rp.applyChanges(context.Background(), ""))
+		require.Equal(t, []string{"new"}, roles.created)
+		require.Equal(t, []string{"outdated"}, roles.updated)
+		require.Equal(t, []customroles.DeleteRoleCommand{{OrgID: 1, UID: "obsolete", Force: true}}, roles.deleted)
+	})
+
+	t.Run("Should assign roles to teams", func(t *testing.T) {
+		roles := &fakeRoleService{roles: []*ac.RoleDTO{
+			{UID: "editor", Name: "custom:editor"},
+			{UID: "reader", Name: "custom:reader"},
+		}}
+		teams := teamtest.NewFakeService()
+		teams.ExpectedSearchTeams = team.SearchTeamQueryResult{Teams: []*team.TeamDTO{{ID: 3, Name: "Editors"}}}
+		cfg := []*rolesAsConfig{{
+			Teams: []*teamFromConfig{{
+				Name:  "Editors",
+				OrgID: 1,
+				Roles: []*roleRefFromConfig{{UID: "editor"}, {Name: "custom:reader", Absent: true}},
+			}},
+		}}
+		rp := RoleProvisioner{log: log.New("test"), cfgProvider: &testConfigReader{result: cfg}, roleService: roles, teamService: teams}
+
+		require.NoError(t, rp.applyChanges(context.Background(), ""))
+		require.Equal(t, []string{"3:editor"}, roles.assigned)
+		require.Equal(t, []string{"3:reader"}, roles.unassigned)
+	})
+
+	t.Run("Should return error when assigned role does not exist", func(t *testing.T) {
+		teams := teamtest.NewFakeService()
+		teams.ExpectedSearchTeams = team.SearchTeamQueryResult{Teams: []*team.TeamDTO{{ID: 3, Name: "Editors"}}}
+		cfg := []*rolesAsConfig{{
+			Teams: []*teamFromConfig{{Name: "Editors", OrgID: 1, Roles: []*roleRefFromConfig{{Name: "custom:missing"}}}},
+		}}
+		rp := RoleProvisioner{log: log.New("test"), cfgProvider: &testConfigReader{result: cfg}, roleService: &fakeRoleService{}, teamService: teams}
+
+		err := rp.applyChanges(context.Background(), "")
+		require.ErrorIs(t, err, customroles.ErrRoleNotFound)
+	})
+}
+
+type testConfigReader struct {
+	result []*rolesAsConfig
+	err    error
+}
+
+func (tcr *testConfigReader) readConfig(_ string) ([]*rolesAsConfig, error) {
+	return tcr.result, tcr.err
+}
+
+type fakeRoleService struct {
+	customroles.Service
+	roles      []*ac.RoleDTO
+	created    []string
+	updated    []string
+	deleted    []customroles.DeleteRoleCommand
+	assigned   []string
+	unassigned []string
+}
+
+func (f *fakeRoleService) GetRoles(_ context.Context, _ int64) ([]*ac.RoleDTO, error) {
+	return f.roles, nil
+}
+
+func (f *fakeRoleService) GetRole(_ context.Context, _ int64, uid string) (*ac.RoleDTO, error) {
+	for _, role := range f.roles {
+		if role.UID == uid {
+			return role, nil
+		}
+	}
+	return nil, customroles.ErrRoleNotFound
+}
+
+func (f *fakeRoleService) CreateRole(_ context.Context, cmd customroles.CreateRoleCommand) (*ac.RoleDTO, error) {
+	f.created = append(f.created, cmd.UID)
+	return &ac.RoleDTO{UID: cmd.UID, Name: cmd.Name}, nil
+}
+
+func (f *fakeRoleService) UpdateRole(_ context.Context, cmd customroles.UpdateRoleCommand) (*ac.RoleDTO, error) {
+	f.updated = append(f.updated, cmd.UID)
+	return &ac.RoleDTO{UID: cmd.UID, Name: cmd.Name}, nil
+}
+
+func (f *fakeRoleService) DeleteRole(_ context.Context, cmd customroles.DeleteRoleCommand) error {
+	f.deleted = append(f.deleted, cmd)
+	return nil
+}
+
+func (f *fakeRoleService) AddTeamRole(_ context.Context, _, teamID int64, roleUID string) error {
+	f.assigned = append(f.assigned, fmt.Sprintf("%d:%s", teamID, roleUID))
+	return nil
+}
+
+func (f *fakeRoleService) RemoveTeamRole(_ context.Context, _, teamID int64, roleUID string) error {
+	f.unassigned = append(f.unassigned, fmt.Sprintf("%d:%s", teamID, roleUID))
+	return nil
+}
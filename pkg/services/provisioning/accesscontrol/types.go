@@ -0,0 +1,130 @@
+package accesscontrol
+
+import (
+	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/provisioning/values"
+)
+
+const stateAbsent = "absent"
+
+// rolesAsConfig is a normalized data object for access control config data. Any config version should be mappable
+// to this type.
+type rolesAsConfig struct {
+	Roles []*roleFromConfig
+	Teams []*teamFromConfig
+}
+
+type roleFromConfig struct {
+	UID         string
+	Name        string
+	DisplayName string
+	Description string
+	Group       string
+	Hidden      bool
+	Version     int64
+	OrgID       int64
+	Global      bool
+	Absent      bool
+	Force       bool
+	Permissions []ac.Permission
+}
+
+type teamFromConfig struct {
+	Name  string
+	OrgID int64
+	Roles []*roleRefFromConfig
+}
+
+type roleRefFromConfig struct {
+	UID    string
+	Name   string
+	Absent bool
+}
+
+type rolesAsConfigV2 struct {
+	Roles []*roleFromConfigV2 `json:"roles" yaml:"roles"`
+	Teams []*teamFromConfigV2 `json:"teams" yaml:"teams"`
+}
+
+type roleFromConfigV2 struct {
+	UID         values.StringValue        `json:"uid" yaml:"uid"`
+	Name        values.StringValue        `json:"name" yaml:"name"`
+	DisplayName values.StringValue        `json:"displayName" yaml:"displayName"`
+	Description values.StringValue        `json:"description" yaml:"description"`
+	Group       values.StringValue        `json:"group" yaml:"group"`
+	Hidden      values.BoolValue          `json:"hidden" yaml:"hidden"`
+	Version     values.Int64Value         `json:"version" yaml:"version"`
+	OrgID       values.Int64Value         `json:"orgId" yaml:"orgId"`
+	Global      values.BoolValue          `json:"global" yaml:"global"`
+	State       values.StringValue        `json:"state" yaml:"state"`
+	Force       values.BoolValue          `json:"force" yaml:"force"`
+	Permissions []*permissionFromConfigV2 `json:"permissions" yaml:"permissions"`
+	From        []map[string]any          `json:"from" yaml:"from"`
+}
+
+type permissionFromConfigV2 struct {
+	Action values.StringValue `json:"action" yaml:"action"`
+	Scope  values.StringValue `json:"scope" yaml:"scope"`
+}
+
+type teamFromConfigV2 struct {
+	Name  values.StringValue     `json:"name" yaml:"name"`
+	OrgID values.Int64Value      `json:"orgId" yaml:"orgId"`
+	Roles []*roleRefFromConfigV2 `json:"roles" yaml:"roles"`
+}
+
+type roleRefFromConfigV2 struct {
+	UID   values.StringValue `json:"uid" yaml:"uid"`
+	Name  values.StringValue `json:"name" yaml:"name"`
+	State values.StringValue `json:"state" yaml:"state"`
+}
+
+// mapToRolesFromConfig maps config syntax to a normalized rolesAsConfig object. Every version
+// of the config syntax should have this function.
+func (cfg *rolesAsConfigV2) mapToRolesFromConfig() *rolesAsConfig {
+	r := &rolesAsConfig{}
+	if cfg == nil {
+		return r
+	}
+
+	for _, role := range cfg.Roles {
+		permissions := make([]ac.Permission, 0, len(role.Permissions))
+		for _, p := range role.Permissions {
+			permissions = append(permissions, ac.Permission{Action: p.Action.Value(), Scope: p.Scope.Value()})
+		}
+
+		r.Roles = append(r.Roles, &roleFromConfig{
+			UID:         role.UID.Value(),
+			Name:        role.Name.Value(),
+			DisplayName: role.DisplayName.Value(),
+			Description: role.Description.Value(),
+			Group:       role.Group.Value(),
+			Hidden:      role.Hidden.Value(),
+			Version:     role.Version.Value(),
+			OrgID:       role.OrgID.Value(),
+			Global:      role.Global.Value(),
+			Absent:      role.State.Value() == stateAbsent,
+			Force:       role.Force.Value(),
+			Permissions: permissions,
+		})
+	}
+
+	for _, team := range cfg.Teams {
+		roles := make([]*roleRefFromConfig, 0, len(team.Roles))
+		for _, role := range team.Roles {
+			roles = append(roles, &roleRefFromConfig{
+				UID:    role.UID.Value(),
+				Name:   role.Name.Value(),
+				Absent: role.State.Value() == stateAbsent,
+			})
+		}
+
+		r.Teams = append(r.Teams, &teamFromConfig{
+			Name:  team.Name.Value(),
+			OrgID: team.OrgID.Value(),
+			Roles: roles,
+		})
+	}
+
+	return r
+}
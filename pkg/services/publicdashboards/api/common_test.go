@@ -136,6 +136,7 @@ func buildQueryDataService(t *testing.T, cs datasources.CacheService, fpc *fakeP
 		&fakePluginRequestValidator{},
 		fpc,
 		pCtxProvider,
+		guardian.ProvideGuardian(),
 	)
 }
 
@@ -0,0 +1,93 @@
+package query
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/query/inspect"
+)
+
+// QueryDebug is the debug information of a query: the requests the data source sent upstream to run it
+type QueryDebug struct {
+	RefID          string             `json:"refId"`
+	DatasourceUID  string             `json:"datasourceUid"`
+	DatasourceType string             `json:"datasourceType"`
+	DurationMs     float64            `json:"durationMs"`
+	Requests       []inspect.Request  `json:"requests"`
+	SQLQueries     []inspect.SQLQuery `json:"sqlQueries,omitempty"`
+}
+
+// DebugCollector collects the debug information of the queries of a request running in debug mode
+type DebugCollector struct {
+	mu      sync.Mutex
+	queries []QueryDebug
+}
+
+func (c *DebugCollector) add(q QueryDebug) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queries = append(c.queries, q)
+}
+
+// Queries returns the debug information of the queries sorted by refId
+func (c *DebugCollector) Queries() []QueryDebug {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	queries := append([]QueryDebug{}, c.queries...)
+	sort.Slice(queries, func(i, j int) bool { return queries[i].RefID < queries[j].RefID })
+	return queries
+}
+
+type debugCollectorKey struct{}
+
+// WithDebug returns a context running the queries in debug mode, their debug information is collected by the returned collector.
+// Only the queries of the built-in data sources sending their requests with the context of the query are recorded, the
+// queries of expressions are not.
+func WithDebug(ctx context.Context) (context.Context, *DebugCollector) {
+	c := &DebugCollector{}
+	return context.WithValue(ctx, debugCollectorKey{}, c), c
+}
+
+func debugCollectorFromContext(ctx context.Context) *DebugCollector {
+	c, _ := ctx.Value(debugCollectorKey{}).(*DebugCollector)
+	return c
+}
+
+// queryDebug runs the queries one by one so that the requests sent upstream are recorded for each query
+func (s *ServiceImpl) queryDebug(ctx context.Context, collector *DebugCollector, ds *datasources.DataSource, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+	resp := backend.NewQueryDataResponse()
+	for _, q := range req.Queries {
+		headers := make(map[string]string, len(req.Headers))
+		for k, v := range req.Headers {
+			headers[k] = v
+		}
+
+		recorder := inspect.NewRecorder()
+		start := time.Now()
+		qdr, err := s.pluginClient.QueryData(inspect.WithRecorder(ctx, recorder), &backend.QueryDataRequest{
+			PluginContext: req.PluginContext,
+			Headers:       headers,
+			Queries:       []backend.DataQuery{q},
+		})
+		collector.add(QueryDebug{
+			RefID:          q.RefID,
+			DatasourceUID:  ds.UID,
+			DatasourceType: ds.Type,
+			DurationMs:     inspect.Milliseconds(time.Since(start)),
+			Requests:       recorder.Requests(),
+			SQLQueries:     recorder.SQLQueries(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		for refID, r := range qdr.Responses {
+			resp.Responses[refID] = r
+		}
+	}
+	return resp, nil
+}
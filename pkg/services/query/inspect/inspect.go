@@ -0,0 +1,262 @@
+// Package inspect records the requests sent upstream by the data sources while a query runs in debug mode, so that
+// the query API can return exactly what Grafana sent along with the frames.
+package inspect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxBodySize is the maximum number of bytes of a request body that are recorded
+	MaxBodySize = 64 * 1024
+
+	redacted = "[REDACTED]"
+)
+
+// Timings is the timing breakdown of an upstream HTTP request, in milliseconds
+type Timings struct {
+	DNSMs       float64 `json:"dnsMs"`
+	ConnectMs   float64 `json:"connectMs"`
+	TLSMs       float64 `json:"tlsMs"`
+	FirstByteMs float64 `json:"firstByteMs"`
+	// TotalMs is the time until the response headers are received
+	TotalMs float64 `json:"totalMs"`
+	// ReusedConnection is true when the request was sent on an idle connection, there is no DNS, connect and TLS time
+	ReusedConnection bool `json:"reusedConnection"`
+}
+
+// Request is an upstream HTTP request sent by a data source, with the secrets redacted
+type Request struct {
+	Method        string      `json:"method"`
+	URL           string      `json:"url"`
+	Headers       http.Header `json:"headers"`
+	Body          string      `json:"body,omitempty"`
+	BodyTruncated bool        `json:"bodyTruncated,omitempty"`
+	StatusCode    int         `json:"statusCode,omitempty"`
+	Error         string      `json:"error,omitempty"`
+	Timings       Timings     `json:"timings"`
+}
+
+// SQLQuery is a query sent by a SQL data source, with the macros interpolated
+type SQLQuery struct {
+	RefID           string  `json:"refId"`
+	RawSQL          string  `json:"rawSql"`
+	InterpolatedSQL string  `json:"interpolatedSql"`
+	DurationMs      float64 `json:"durationMs"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// Recorder records the upstream requests of a query. A nil recorder discards them.
+type Recorder struct {
+	mu         sync.Mutex
+	requests   []Request
+	sqlQueries []SQLQuery
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// AddRequest records an upstream HTTP request
+func (r *Recorder) AddRequest(req Request) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+}
+
+// AddSQLQuery records a query sent by a SQL data source
+func (r *Recorder) AddSQLQuery(q SQLQuery) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sqlQueries = append(r.sqlQueries, q)
+}
+
+// Requests returns the recorded upstream HTTP requests in the order they completed
+func (r *Recorder) Requests() []Request {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Request(nil), r.requests...)
+}
+
+// SQLQueries returns the recorded SQL queries in the order they completed
+func (r *Recorder) SQLQueries() []SQLQuery {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]SQLQuery(nil), r.sqlQueries...)
+}
+
+type recorderKey struct{}
+
+// WithRecorder returns a context recording the upstream requests sent with it into r
+func WithRecorder(ctx context.Context, r *Recorder) context.Context {
+	return context.WithValue(ctx, recorderKey{}, r)
+}
+
+// FromContext returns the recorder of the context, nil when the upstream requests are not recorded
+func FromContext(ctx context.Context) *Recorder {
+	r, _ := ctx.Value(recorderKey{}).(*Recorder)
+	return r
+}
+
+// Milliseconds returns the duration in fractional milliseconds
+func Milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// sensitiveNames are the parts of the header, query parameter and form field names holding secrets
+var sensitiveNames = []string{"auth", "token", "secret", "password", "passwd", "cookie", "key", "signature", "credential", "session"}
+
+// IsSensitive returns true if the header, query parameter or form field is likely to hold a secret
+func IsSensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range sensitiveNames {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactHeaders returns a copy of the headers with the values of the sensitive headers and of the secret headers
+// redacted, ex: the custom headers of a data source
+func RedactHeaders(header http.Header, secretHeaders ...string) http.Header {
+	secret := make(map[string]bool, len(secretHeaders))
+	for _, name := range secretHeaders {
+		secret[http.CanonicalHeaderKey(name)] = true
+	}
+
+	res := make(http.Header, len(header))
+	for name, values := range header {
+		if IsSensitive(name) || secret[http.CanonicalHeaderKey(name)] {
+			res[name] = []string{redacted}
+			continue
+		}
+		res[name] = append([]string(nil), values...)
+	}
+	return res
+}
+
+// RedactURL returns the URL with the password of the user info and the sensitive query parameters redacted
+func RedactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	cp := *u
+	if _, ok := cp.User.Password(); ok {
+		cp.User = url.UserPassword(cp.User.Username(), redacted)
+	}
+	if cp.RawQuery != "" {
+		cp.RawQuery = RedactValues(cp.RawQuery)
+	}
+	return cp.String()
+}
+
+// RedactValues redacts the sensitive fields of a URL encoded query or form, the other fields are kept as is
+func RedactValues(encoded string) string {
+	fields := strings.Split(encoded, "&")
+	for i, field := range fields {
+		rawName, _, found := strings.Cut(field, "=")
+		name, err := url.QueryUnescape(rawName)
+		if err != nil {
+			name = rawName
+		}
+		if found && IsSensitive(name) {
+			fields[i] = rawName + "=" + url.QueryEscape(redacted)
+		}
+	}
+	return strings.Join(fields, "&")
+}
+
+// RedactBody returns the body with the sensitive fields of the URL encoded forms and of the JSON documents redacted.
+// The other bodies, and the JSON documents which cannot be parsed such as the truncated ones, are redacted entirely.
+func RedactBody(body string, contentType string) string {
+	if body == "" {
+		return ""
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		return RedactValues(body)
+	case strings.HasSuffix(mediaType, "json"):
+		if res, ok := redactJSON(body); ok {
+			return res
+		}
+	}
+	return redacted
+}
+
+// redactJSON redacts the sensitive fields of a JSON document or of a stream of documents, ex: newline delimited
+// JSON. The body is kept as is when it has no sensitive fields.
+func redactJSON(body string) (string, bool) {
+	dec := json.NewDecoder(strings.NewReader(body))
+	dec.UseNumber()
+	var docs []any
+	changed := false
+	for {
+		var doc any
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", false
+		}
+		changed = redactFields(doc) || changed
+		docs = append(docs, doc)
+	}
+	if !changed {
+		return body, true
+	}
+
+	var res strings.Builder
+	enc := json.NewEncoder(&res)
+	enc.SetEscapeHTML(false)
+	for _, doc := range docs {
+		if err := enc.Encode(doc); err != nil {
+			return "", false
+		}
+	}
+	return res.String(), true
+}
+
+// redactFields redacts the sensitive fields of the JSON objects in place, it returns true if a field was redacted
+func redactFields(v any) bool {
+	changed := false
+	switch v := v.(type) {
+	case map[string]any:
+		for name, value := range v {
+			if IsSensitive(name) {
+				v[name] = redacted
+				changed = true
+				continue
+			}
+			changed = redactFields(value) || changed
+		}
+	case []any:
+		for _, value := range v {
+			changed = redactFields(value) || changed
+		}
+	}
+	return changed
+}
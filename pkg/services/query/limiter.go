@@ -0,0 +1,360 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/time/rate"
+
+	"github.com/grafana/grafana/pkg/services/auth/identity"
+	"github.com/grafana/grafana/pkg/services/datasources"
+)
+
+const (
+	defaultMaxQueuedQueries  = 100
+	defaultQueryQueueTimeout = 30 * time.Second
+	// idleUsersSweepInterval is how often the users idle with a full rate limit bucket are forgotten
+	idleUsersSweepInterval = time.Minute
+
+	throttledReasonQueueFull    = "queue_full"
+	throttledReasonQueueTimeout = "queue_timeout"
+)
+
+var (
+	queryQueueDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "grafana",
+		Subsystem: "query",
+		Name:      "datasource_queue_duration_seconds",
+		Help:      "Time spent by data source queries waiting for the query limits of the data source",
+		Buckets:   []float64{.001, .01, .05, .1, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"datasource", "datasource_type"})
+
+	queryThrottledTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "grafana",
+		Subsystem: "query",
+		Name:      "datasource_throttled_total",
+		Help:      "Number of data source queries rejected by the query limits of the data source",
+	}, []string{"datasource", "datasource_type", "reason"})
+)
+
+// queryLimits are the limits of the queries sent to a data source, configured in the data source JSON data:
+//
+//	"queryLimits": {
+//	  "maxConcurrentQueries": 10,
+//	  "maxConcurrentQueriesPerUser": 2,
+//	  "maxQueriesPerSecond": 20,
+//	  "maxQueriesPerSecondPerUser": 5,
+//	  "maxQueuedQueries": 100,
+//	  "queueTimeout": "30s"
+//	}
+//
+// A limit of zero is unlimited.
+type queryLimits struct {
+	MaxConcurrent           int
+	MaxConcurrentPerUser    int
+	QueriesPerSecond        float64
+	QueriesPerSecondPerUser float64
+	MaxQueued               int
+	QueueTimeout            time.Duration
+}
+
+// readQueryLimits returns the query limits of a data source, false if the data source queries are unlimited
+func readQueryLimits(ds *datasources.DataSource) (queryLimits, bool) {
+	if ds.JsonData == nil {
+		return queryLimits{}, false
+	}
+	settings := ds.JsonData.Get("queryLimits")
+	limits := queryLimits{
+		MaxConcurrent:           settings.Get("maxConcurrentQueries").MustInt(0),
+		MaxConcurrentPerUser:    settings.Get("maxConcurrentQueriesPerUser").MustInt(0),
+		QueriesPerSecond:        settings.Get("maxQueriesPerSecond").MustFloat64(0),
+		QueriesPerSecondPerUser: settings.Get("maxQueriesPerSecondPerUser").MustFloat64(0),
+		MaxQueued:               settings.Get("maxQueuedQueries").MustInt(defaultMaxQueuedQueries),
+		QueueTimeout:            defaultQueryQueueTimeout,
+	}
+	if timeout, err := time.ParseDuration(settings.Get("queueTimeout").MustString("")); err == nil && timeout > 0 {
+		limits.QueueTimeout = timeout
+	}
+
+	limited := limits.MaxConcurrent > 0 || limits.MaxConcurrentPerUser > 0 || limits.QueriesPerSecond > 0 || limits.QueriesPerSecondPerUser > 0
+	return limits, limited
+}
+
+// queryLimiter enforces the query limits of the data sources
+type queryLimiter struct {
+	mu       sync.Mutex
+	limiters map[string]*dataSourceLimiter
+}
+
+func newQueryLimiter() *queryLimiter {
+	return &queryLimiter{limiters: map[string]*dataSourceLimiter{}}
+}
+
+// acquire waits until the query limits of the data source allow the user to query it.
+// The returned function must be called once the query is done.
+func (l *queryLimiter) acquire(ctx context.Context, ds *datasources.DataSource, user identity.Requester) (func(), error) {
+	limits, limited := readQueryLimits(ds)
+	if !limited {
+		return func() {}, nil
+	}
+
+	key := strconv.FormatInt(ds.OrgID, 10) + "/" + ds.UID
+	l.mu.Lock()
+	dsLimiter, ok := l.limiters[key]
+	// the limits have changed when the data source is updated
+	if !ok || dsLimiter.version != ds.Version {
+		dsLimiter = newDataSourceLimiter(limits, ds.Version)
+		l.limiters[key] = dsLimiter
+	}
+	l.mu.Unlock()
+
+	start := time.Now()
+	release, reason, err := dsLimiter.acquire(ctx, userKey(user))
+	if reason != "" {
+		queryThrottledTotal.WithLabelValues(ds.UID, ds.Type, reason).Inc()
+	}
+	if err != nil {
+		return nil, err
+	}
+	queryQueueDuration.WithLabelValues(ds.UID, ds.Type).Observe(time.Since(start).Seconds())
+	return release, nil
+}
+
+func userKey(user identity.Requester) string {
+	if user == nil || user.IsNil() {
+		return ""
+	}
+	namespace, id := user.GetNamespacedID()
+	return namespace + ":" + id
+}
+
+// dataSourceLimiter limits the queries to a data source. Queries over the concurrency limits are queued and
+// dispatched in a round-robin fashion across users, so that a single user cannot starve the others.
+type dataSourceLimiter struct {
+	limits  queryLimits
+	version int
+	rate    *rate.Limiter
+
+	mu       sync.Mutex
+	inFlight int
+	queued   int
+	users    map[string]*userQueries
+	// order is the round-robin order of the users with queued queries
+	order     []string
+	lastSweep time.Time
+}
+
+type userQueries struct {
+	inFlight int
+	rate     *rate.Limiter
+	queue    []*queuedQuery
+}
+
+// idle is true when the user has no running or queued queries and a full rate limit bucket,
+// forgetting the user then loses nothing since a new rate limiter starts full as well
+func (u *userQueries) idle(now time.Time) bool {
+	if u.inFlight > 0 || len(u.queue) > 0 {
+		return false
+	}
+	return u.rate == nil || u.rate.TokensAt(now) >= float64(u.rate.Burst())
+}
+
+type queuedQuery struct {
+	ready   chan struct{}
+	granted bool
+}
+
+func newDataSourceLimiter(limits queryLimits, version int) *dataSourceLimiter {
+	return &dataSourceLimiter{
+		limits:  limits,
+		version: version,
+		rate:    newRateLimiter(limits.QueriesPerSecond),
+		users:   map[string]*userQueries{},
+	}
+}
+
+func newRateLimiter(perSecond float64) *rate.Limiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	burst := int(perSecond)
+	if burst < 1 {
+		burst = 1
+	}
+	return rate.NewLimiter(rate.Limit(perSecond), burst)
+}
+
+// acquire returns the reason of the rejection when the query is throttled
+func (l *dataSourceLimiter) acquire(ctx context.Context, user string) (func(), string, error) {
+	ctx, cancel := context.WithTimeout(ctx, l.limits.QueueTimeout)
+	defer cancel()
+
+	l.mu.Lock()
+	u := l.user(user)
+	if !l.canRun(u) {
+		if l.queued >= l.limits.MaxQueued {
+			l.cleanup(user, u)
+			l.mu.Unlock()
+			return nil, throttledReasonQueueFull, ErrQueryThrottled.Errorf("data source query queue is full")
+		}
+		if err := l.wait(ctx, user, u); err != nil {
+			if errors.Is(err, context.Canceled) {
+				return nil, "", err
+			}
+			return nil, throttledReasonQueueTimeout, err
+		}
+	} else {
+		l.inFlight++
+		u.inFlight++
+		l.mu.Unlock()
+	}
+
+	release := func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.inFlight--
+		u.inFlight--
+		l.dispatch()
+		l.cleanup(user, u)
+	}
+
+	for _, limiter := range []*rate.Limiter{l.rate, u.rate} {
+		if limiter == nil {
+			continue
+		}
+		// Wait fails right away when the query would not be allowed before the queue timeout
+		if err := limiter.Wait(ctx); err != nil {
+			release()
+			if ctxErr := ctx.Err(); ctxErr != nil && !errors.Is(ctxErr, context.DeadlineExceeded) {
+				return nil, "", ctxErr
+			}
+			return nil, throttledReasonQueueTimeout, ErrQueryThrottled.Errorf("data source query rate limit exceeded")
+		}
+	}
+	return release, "", nil
+}
+
+// wait queues the query until it is dispatched, it must be called with the lock held and releases it
+func (l *dataSourceLimiter) wait(ctx context.Context, user string, u *userQueries) error {
+	q := &queuedQuery{ready: make(chan struct{})}
+	u.queue = append(u.queue, q)
+	if len(u.queue) == 1 {
+		l.order = append(l.order, user)
+	}
+	l.queued++
+	l.mu.Unlock()
+
+	select {
+	case <-q.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// the query may have been dispatched concurrently
+	if q.granted {
+		l.inFlight--
+		u.inFlight--
+		l.dispatch()
+	} else {
+		l.remove(user, u, q)
+	}
+	l.cleanup(user, u)
+
+	if err := ctx.Err(); !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return ErrQueryThrottled.Errorf("data source query queue timeout")
+}
+
+func (l *dataSourceLimiter) user(user string) *userQueries {
+	l.sweep(time.Now())
+	u, ok := l.users[user]
+	if !ok {
+		u = &userQueries{rate: newRateLimiter(l.limits.QueriesPerSecondPerUser)}
+		l.users[user] = u
+	}
+	return u
+}
+
+func (l *dataSourceLimiter) canRun(u *userQueries) bool {
+	if l.limits.MaxConcurrent > 0 && l.inFlight >= l.limits.MaxConcurrent {
+		return false
+	}
+	return l.limits.MaxConcurrentPerUser <= 0 || u.inFlight < l.limits.MaxConcurrentPerUser
+}
+
+// dispatch runs the queued queries allowed by the limits, one user at a time. It must be called with the lock held.
+func (l *dataSourceLimiter) dispatch() {
+	for dispatched := true; dispatched; {
+		dispatched = false
+		for i, user := range l.order {
+			u := l.users[user]
+			if !l.canRun(u) {
+				continue
+			}
+
+			q := u.queue[0]
+			u.queue = u.queue[1:]
+			q.granted = true
+			close(q.ready)
+			l.inFlight++
+			u.inFlight++
+			l.queued--
+
+			// the user goes to the back of the line
+			l.order = append(l.order[:i], l.order[i+1:]...)
+			if len(u.queue) > 0 {
+				l.order = append(l.order, user)
+			}
+			dispatched = true
+			break
+		}
+	}
+}
+
+func (l *dataSourceLimiter) remove(user string, u *userQueries, q *queuedQuery) {
+	for i, queued := range u.queue {
+		if queued == q {
+			u.queue = append(u.queue[:i], u.queue[i+1:]...)
+			l.queued--
+			break
+		}
+	}
+	if len(u.queue) > 0 {
+		return
+	}
+	for i, queued := range l.order {
+		if queued == user {
+			l.order = append(l.order[:i], l.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// cleanup forgets the user if idle. It must be called with the lock held.
+func (l *dataSourceLimiter) cleanup(user string, u *userQueries) {
+	if u.idle(time.Now()) {
+		delete(l.users, user)
+	}
+}
+
+// sweep forgets the users whose rate limit bucket refilled since their last query, at most once per
+// idleUsersSweepInterval. It must be called with the lock held.
+func (l *dataSourceLimiter) sweep(now time.Time) {
+	if l.limits.QueriesPerSecondPerUser <= 0 || now.Sub(l.lastSweep) < idleUsersSweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for user, u := range l.users {
+		if u.idle(now) {
+			delete(l.users, user)
+		}
+	}
+}
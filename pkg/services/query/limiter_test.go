@@ -0,0 +1,219 @@
+package query
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestReadQueryLimits(t *testing.T) {
+	t.Run("should be unlimited without settings", func(t *testing.T) {
+		_, limited := readQueryLimits(&datasources.DataSource{JsonData: simplejson.New()})
+		assert.False(t, limited)
+	})
+
+	t.Run("should read the limits with defaults", func(t *testing.T) {
+		limits, limited := readQueryLimits(&datasources.DataSource{JsonData: simplejson.NewFromAny(map[string]any{
+			"queryLimits": map[string]any{"maxConcurrentQueries": 10, "maxQueriesPerSecondPerUser": 0.5},
+		})})
+		assert.True(t, limited)
+		assert.Equal(t, queryLimits{
+			MaxConcurrent:           10,
+			QueriesPerSecondPerUser: 0.5,
+			MaxQueued:               defaultMaxQueuedQueries,
+			QueueTimeout:            defaultQueryQueueTimeout,
+		}, limits)
+	})
+
+	t.Run("should read the queue settings", func(t *testing.T) {
+		limits, _ := readQueryLimits(&datasources.DataSource{JsonData: simplejson.NewFromAny(map[string]any{
+			"queryLimits": map[string]any{"maxConcurrentQueriesPerUser": 2, "maxQueuedQueries": 5, "queueTimeout": "1m"},
+		})})
+		assert.Equal(t, 2, limits.MaxConcurrentPerUser)
+		assert.Equal(t, 5, limits.MaxQueued)
+		assert.Equal(t, time.Minute, limits.QueueTimeout)
+	})
+}
+
+func TestDataSourceLimiter(t *testing.T) {
+	// acquireAsync returns a channel receiving the release function once the query runs, nil if it is rejected
+	acquireAsync := func(l *dataSourceLimiter, user string) <-chan func() {
+		acquired := make(chan func(), 1)
+		go func() {
+			release, _, err := l.acquire(context.Background(), user)
+			if err != nil {
+				release = nil
+			}
+			acquired <- release
+		}()
+		return acquired
+	}
+
+	queuedCount := func(l *dataSourceLimiter) int {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return l.queued
+	}
+
+	t.Run("should queue the queries over the concurrency limit", func(t *testing.T) {
+		l := newDataSourceLimiter(queryLimits{MaxConcurrent: 1, MaxQueued: 10, QueueTimeout: time.Second}, 0)
+
+		release, reason, err := l.acquire(context.Background(), "user:1")
+		require.NoError(t, err)
+		assert.Empty(t, reason)
+
+		acquired := acquireAsync(l, "user:2")
+		require.Eventually(t, func() bool { return queuedCount(l) == 1 }, time.Second, time.Millisecond)
+
+		release()
+		next := <-acquired
+		require.NotNil(t, next)
+		next()
+		assert.Equal(t, 0, l.inFlight)
+		assert.Empty(t, l.users)
+	})
+
+	t.Run("should dispatch the queued queries fairly across users", func(t *testing.T) {
+		l := newDataSourceLimiter(queryLimits{MaxConcurrent: 1, MaxQueued: 10, QueueTimeout: time.Second}, 0)
+		release, _, err := l.acquire(context.Background(), "user:1")
+		require.NoError(t, err)
+
+		first := acquireAsync(l, "user:1")
+		require.Eventually(t, func() bool { return queuedCount(l) == 1 }, time.Second, time.Millisecond)
+		second := acquireAsync(l, "user:1")
+		require.Eventually(t, func() bool { return queuedCount(l) == 2 }, time.Second, time.Millisecond)
+		other := acquireAsync(l, "user:2")
+		require.Eventually(t, func() bool { return queuedCount(l) == 3 }, time.Second, time.Millisecond)
+
+		release()
+		release = <-first
+		require.NotNil(t, release)
+
+		// the other user goes before the second query of the first user
+		release()
+		release = <-other
+		require.NotNil(t, release)
+		assert.Len(t, second, 0)
+
+		release()
+		release = <-second
+		require.NotNil(t, release)
+		release()
+	})
+
+	t.Run("should let other users run under the per user limit", func(t *testing.T) {
+		l := newDataSourceLimiter(queryLimits{MaxConcurrentPerUser: 1, MaxQueued: 10, QueueTimeout: time.Second}, 0)
+		release, _, err := l.acquire(context.Background(), "user:1")
+		require.NoError(t, err)
+		defer release()
+
+		otherRelease, _, err := l.acquire(context.Background(), "user:2")
+		require.NoError(t, err)
+		defer otherRelease()
+
+		acquired := acquireAsync(l, "user:1")
+		require.Eventually(t, func() bool { return queuedCount(l) == 1 }, time.Second, time.Millisecond)
+		assert.Len(t, acquired, 0)
+	})
+
+	t.Run("should reject the queries when the queue is full", func(t *testing.T) {
+		l := newDataSourceLimiter(queryLimits{MaxConcurrent: 1, MaxQueued: 0, QueueTimeout: time.Second}, 0)
+		release, _, err := l.acquire(context.Background(), "user:1")
+		require.NoError(t, err)
+		defer release()
+
+		_, reason, err := l.acquire(context.Background(), "user:2")
+		assert.ErrorIs(t, err, ErrQueryThrottled)
+		assert.Equal(t, throttledReasonQueueFull, reason)
+	})
+
+	t.Run("should reject the queries queued for longer than the timeout", func(t *testing.T) {
+		l := newDataSourceLimiter(queryLimits{MaxConcurrent: 1, MaxQueued: 10, QueueTimeout: 10 * time.Millisecond}, 0)
+		release, _, err := l.acquire(context.Background(), "user:1")
+		require.NoError(t, err)
+
+		_, reason, err := l.acquire(context.Background(), "user:2")
+		assert.ErrorIs(t, err, ErrQueryThrottled)
+		assert.Equal(t, throttledReasonQueueTimeout, reason)
+		assert.Equal(t, 0, queuedCount(l))
+
+		release()
+		assert.Empty(t, l.users)
+	})
+
+	t.Run("should not count canceled queries as throttled", func(t *testing.T) {
+		l := newDataSourceLimiter(queryLimits{MaxConcurrent: 1, MaxQueued: 10, QueueTimeout: time.Second}, 0)
+		release, _, err := l.acquire(context.Background(), "user:1")
+		require.NoError(t, err)
+		defer release()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, reason, err := l.acquire(ctx, "user:2")
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Empty(t, reason)
+	})
+
+	t.Run("should reject the queries over the rate limit", func(t *testing.T) {
+		l := newDataSourceLimiter(queryLimits{QueriesPerSecondPerUser: 1, MaxQueued: 10, QueueTimeout: 10 * time.Millisecond}, 0)
+		release, _, err := l.acquire(context.Background(), "user:1")
+		require.NoError(t, err)
+		release()
+
+		_, reason, err := l.acquire(context.Background(), "user:1")
+		assert.ErrorIs(t, err, ErrQueryThrottled)
+		assert.Equal(t, throttledReasonQueueTimeout, reason)
+
+		// the rate limit is per user
+		release, _, err = l.acquire(context.Background(), "user:2")
+		require.NoError(t, err)
+		release()
+	})
+
+	t.Run("should forget the idle users once their rate limit is restored", func(t *testing.T) {
+		l := newDataSourceLimiter(queryLimits{QueriesPerSecondPerUser: 100, MaxQueued: 10, QueueTimeout: time.Second}, 0)
+		release, _, err := l.acquire(context.Background(), "user:1")
+		require.NoError(t, err)
+		release()
+		// the bucket is not full right after the query
+		require.Contains(t, l.users, "user:1")
+
+		time.Sleep(20 * time.Millisecond)
+		l.lastSweep = time.Time{}
+		release, _, err = l.acquire(context.Background(), "user:2")
+		require.NoError(t, err)
+		assert.NotContains(t, l.users, "user:1")
+		release()
+	})
+}
+
+func TestQueryDataThrottled(t *testing.T) {
+	tc := setup(t)
+	ds := &datasources.DataSource{UID: "limited", Type: "mysql", OrgID: 1, JsonData: simplejson.NewFromAny(map[string]any{
+		"queryLimits": map[string]any{"maxConcurrentQueries": 1, "maxQueuedQueries": 0},
+	})}
+	tc.queryService.dataSourceCache = &fakeDataSourceCache{cache: []*datasources.DataSource{ds}}
+
+	// another query is in flight
+	release, err := tc.queryService.queryLimiter.acquire(context.Background(), ds, &user.SignedInUser{UserID: 2, OrgID: 1})
+	require.NoError(t, err)
+
+	reqDTO := metricRequestWithQueries(t, `{"refId": "A", "datasource": {"uid": "limited"}}`)
+	resp, err := tc.queryService.QueryData(context.Background(), tc.signedInUser, true, reqDTO)
+	require.NoError(t, err)
+	assert.ErrorIs(t, resp.Responses["A"].Error, ErrQueryThrottled)
+	assert.Equal(t, backend.StatusTooManyRequests, resp.Responses["A"].Status)
+
+	release()
+	resp, err = tc.queryService.QueryData(context.Background(), tc.signedInUser, true, reqDTO)
+	require.NoError(t, err)
+	assert.NoError(t, resp.Responses["A"].Error)
+}
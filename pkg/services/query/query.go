@@ -2,9 +2,12 @@ package query
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
@@ -19,6 +22,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/auth/identity"
 	"github.com/grafana/grafana/pkg/services/contexthandler"
 	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/datasources/guardian"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/plugincontext"
 	"github.com/grafana/grafana/pkg/services/validations"
 	"github.com/grafana/grafana/pkg/setting"
@@ -43,6 +47,7 @@ func ProvideService(
 	pluginRequestValidator validations.PluginRequestValidator,
 	pluginClient plugins.Client,
 	pCtxProvider *plugincontext.Provider,
+	dsGuardian guardian.DatasourceGuardianProvider,
 ) *ServiceImpl {
 	g := &ServiceImpl{
 		cfg:                    cfg,
@@ -51,8 +56,10 @@ func ProvideService(
 		pluginRequestValidator: pluginRequestValidator,
 		pluginClient:           pluginClient,
 		pCtxProvider:           pCtxProvider,
+		dsGuardian:             dsGuardian,
 		log:                    log.New("query_data"),
 		concurrentQueryLimit:   cfg.SectionWithEnvOverrides("query").Key("concurrent_query_limit").MustInt(runtime.NumCPU()),
+		queryLimiter:           newQueryLimiter(),
 	}
 	g.log.Info("Query Service initialization")
 	return g
@@ -74,8 +81,10 @@ type ServiceImpl struct {
 	pluginRequestValidator validations.PluginRequestValidator
 	pluginClient           plugins.Client
 	pCtxProvider           *plugincontext.Provider
+	dsGuardian             guardian.DatasourceGuardianProvider
 	log                    log.Logger
 	concurrentQueryLimit   int
+	queryLimiter           *queryLimiter
 }
 
 // Run ServiceImpl.
@@ -91,6 +100,10 @@ func (s *ServiceImpl) QueryData(ctx context.Context, user identity.Requester, sk
 	if err != nil {
 		return nil, err
 	}
+	// Restrict the queries to the series allowed by the label policies, before they are sent to the datasources or expressions
+	if err := s.applyLabelPolicies(user, parsedReq); err != nil {
+		return nil, err
+	}
 
 	// If there are expressions, handle them and return
 	if parsedReq.hasExpression {
@@ -267,9 +280,69 @@ func (s *ServiceImpl) handleQuerySingleDatasource(ctx context.Context, user iden
 		req.Queries = append(req.Queries, q.query)
 	}
 
+	// wait for the query limits of the data source, if any
+	release, err := s.queryLimiter.acquire(ctx, ds, user)
+	if err != nil {
+		if errors.Is(err, ErrQueryThrottled) {
+			return throttledResponse(err, req.Queries), nil
+		}
+		return nil, err
+	}
+	defer release()
+
+	if collector := debugCollectorFromContext(ctx); collector != nil {
+		return s.queryDebug(ctx, collector, ds, req)
+	}
 	return s.pluginClient.QueryData(ctx, req)
 }
 
+// throttledResponse returns an error response for each query rejected by the query limits of the data source
+func throttledResponse(err error, queries []backend.DataQuery) *backend.QueryDataResponse {
+	resp := backend.NewQueryDataResponse()
+	for _, q := range queries {
+		resp.Responses[q.RefID] = backend.DataResponse{
+			Error:  err,
+			Status: backend.StatusTooManyRequests,
+		}
+	}
+	return resp
+}
+
+// applyLabelPolicies restricts the queries of Prometheus and Loki datasources to the series allowed by the label policies of the user
+func (s *ServiceImpl) applyLabelPolicies(user identity.Requester, parsedReq *parsedRequest) error {
+	for _, queries := range parsedReq.parsedQueries {
+		ds := queries[0].datasource
+		if !guardian.SupportsLabelPolicies(ds.Type) {
+			continue
+		}
+		matchers, err := s.dsGuardian.New(ds.OrgID, user).LabelMatchers(ds)
+		if err != nil {
+			return err
+		}
+		if len(matchers) == 0 {
+			continue
+		}
+
+		for i := range queries {
+			model := map[string]any{}
+			if err := json.Unmarshal(queries[i].query.JSON, &model); err != nil {
+				return err
+			}
+			expr, ok := model["expr"].(string)
+			if !ok || strings.TrimSpace(expr) == "" {
+				continue
+			}
+			if model["expr"], err = guardian.RestrictQuery(ds.Type, expr, matchers); err != nil {
+				return err
+			}
+			if queries[i].query.JSON, err = json.Marshal(model); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // parseRequest parses a request into parsed queries grouped by datasource uid
 func (s *ServiceImpl) parseMetricRequest(ctx context.Context, user identity.Requester, skipDSCache bool, reqDTO dtos.MetricRequest) (*parsedRequest, error) {
 	if len(reqDTO.Queries) == 0 {
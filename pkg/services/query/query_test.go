@@ -30,10 +30,12 @@ import (
 	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
 	"github.com/grafana/grafana/pkg/services/datasources"
 	fakeDatasources "github.com/grafana/grafana/pkg/services/datasources/fakes"
+	"github.com/grafana/grafana/pkg/services/datasources/guardian"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/plugincontext"
 	pluginSettings "github.com/grafana/grafana/pkg/services/pluginsintegration/pluginsettings/service"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/pluginstore"
+	"github.com/grafana/grafana/pkg/services/query/inspect"
 	"github.com/grafana/grafana/pkg/services/secrets/fakes"
 	secretskvs "github.com/grafana/grafana/pkg/services/secrets/kvstore"
 	secretsmng "github.com/grafana/grafana/pkg/services/secrets/manager"
@@ -446,6 +448,70 @@ func TestQueryDataMultipleSources(t *testing.T) {
 	})
 }
 
+func TestQueryDataLabelPolicies(t *testing.T) {
+	tc := setup(t)
+	ds := &datasources.DataSource{UID: "prom", Type: datasources.DS_PROMETHEUS, OrgID: 1, JsonData: simplejson.NewFromAny(map[string]any{
+		"labelPolicies": []any{map[string]any{"roles": []any{"Admin"}, "matchers": `namespace="team-a"`}},
+	})}
+	tc.queryService.dataSourceCache = &fakeDataSourceCache{cache: []*datasources.DataSource{ds}}
+	reqDTO := metricRequestWithQueries(t, `{"refId": "A", "datasource": {"uid": "prom"}, "expr": "rate(up[$__rate_interval])"}`)
+
+	t.Run("should restrict the queries of the user", func(t *testing.T) {
+		_, err := tc.queryService.QueryData(context.Background(), tc.signedInUser, true, reqDTO)
+		require.NoError(t, err)
+
+		query, err := simplejson.NewJson(tc.pluginContext.req.Queries[0].JSON)
+		require.NoError(t, err)
+		assert.Equal(t, `rate(up{namespace="team-a"}[$__rate_interval])`, query.Get("expr").MustString())
+	})
+
+	t.Run("should deny the users without label policy", func(t *testing.T) {
+		viewer := &user.SignedInUser{OrgID: 1, Login: "viewer", OrgRole: roletype.RoleViewer}
+		_, err := tc.queryService.QueryData(context.Background(), viewer, true, reqDTO)
+		assert.ErrorIs(t, err, guardian.ErrLabelPolicyDenied)
+	})
+}
+
+func TestQueryDataDebug(t *testing.T) {
+	tc := setup(t)
+
+	t.Run("should record the upstream requests of each query", func(t *testing.T) {
+		reqDTO := metricRequestWithQueries(t,
+			`{"refId": "B", "datasource": {"uid": "ds1"}}`,
+			`{"refId": "A", "datasource": {"uid": "ds1"}}`,
+		)
+		ctx, collector := WithDebug(context.Background())
+		_, err := tc.queryService.QueryData(ctx, tc.signedInUser, true, reqDTO)
+		require.NoError(t, err)
+
+		debug := collector.Queries()
+		require.Len(t, debug, 2)
+		for i, refID := range []string{"A", "B"} {
+			assert.Equal(t, refID, debug[i].RefID)
+			assert.Equal(t, "ds1", debug[i].DatasourceUID)
+			assert.Equal(t, "mysql", debug[i].DatasourceType)
+			require.Len(t, debug[i].Requests, 1)
+			assert.Equal(t, "http://upstream/query?refId="+refID, debug[i].Requests[0].URL)
+		}
+	})
+
+	t.Run("should record the upstream requests of the queries of multiple datasources", func(t *testing.T) {
+		reqDTO := metricRequestWithQueries(t,
+			`{"refId": "A", "datasource": {"uid": "ds1"}}`,
+			`{"refId": "B", "datasource": {"uid": "ds2"}}`,
+		)
+		ctx, collector := WithDebug(context.Background())
+		_, err := tc.queryService.QueryData(ctx, tc.signedInUser, true, reqDTO)
+		require.NoError(t, err)
+
+		debug := collector.Queries()
+		require.Len(t, debug, 2)
+		assert.Equal(t, "ds1", debug[0].DatasourceUID)
+		assert.Equal(t, "ds2", debug[1].DatasourceUID)
+		assert.Len(t, debug[1].Requests, 1)
+	})
+}
+
 func setup(t *testing.T) *testContext {
 	dss := []*datasources.DataSource{
 		{UID: "gIEkMvIVz", Type: "postgres"},
@@ -473,13 +539,14 @@ func setup(t *testing.T) *testContext {
 				{JSONData: plugins.JSONData{ID: "postgres"}},
 				{JSONData: plugins.JSONData{ID: "testdata"}},
 				{JSONData: plugins.JSONData{ID: "mysql"}},
+				{JSONData: plugins.JSONData{ID: "prometheus"}},
 			},
 		}, fakeDatasourceService,
 		pluginSettings.ProvideService(sqlStore, secretsService),
 	)
 	exprService := expr.ProvideService(&setting.Cfg{ExpressionsEnabled: true}, pc, pCtxProvider,
 		&featuremgmt.FeatureManager{}, nil, tracing.InitializeTracerForTest())
-	queryService := ProvideService(setting.NewCfg(), dc, exprService, rv, pc, pCtxProvider) // provider belonging to this package
+	queryService := ProvideService(setting.NewCfg(), dc, exprService, rv, pc, pCtxProvider, guardian.ProvideGuardian()) // provider belonging to this package
 	return &testContext{
 		pluginContext:          pc,
 		secretStore:            ss,
@@ -565,5 +632,10 @@ func (c *fakePluginClient) QueryData(ctx context.Context, req *backend.QueryData
 		reqCtx.Resp.Header().Add("test", fmt.Sprintf("header-%d", time.Now().Nanosecond()))
 	}
 
+	// record a request per query when the queries run in debug mode
+	for _, q := range req.Queries {
+		inspect.FromContext(ctx).AddRequest(inspect.Request{Method: http.MethodGet, URL: "http://upstream/query?refId=" + q.RefID})
+	}
+
 	return &backend.QueryDataResponse{Responses: make(backend.Responses)}, nil
 }
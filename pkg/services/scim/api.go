@@ -0,0 +1,266 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/middleware"
+	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+)
+
+const (
+	contentType = "application/scim+json"
+	// maxBodySize is the maximum size of a request body, large groups are sent as a list of members
+	maxBodySize = 4 << 20
+	// maxCount is the maximum number of resources returned in a single page
+	maxCount = 100
+)
+
+func (s *SCIMService) registerAPIEndpoints() {
+	authorize := ac.Middleware(s.accessControl)
+
+	readUsers := ac.EvalPermission(ac.ActionOrgUsersRead)
+	writeUsers := ac.EvalAll(
+		ac.EvalPermission(ac.ActionOrgUsersRead),
+		ac.EvalPermission(ac.ActionOrgUsersAdd),
+		ac.EvalPermission(ac.ActionOrgUsersWrite),
+		ac.EvalPermission(ac.ActionOrgUsersRemove),
+	)
+	readGroups := ac.EvalAll(
+		ac.EvalPermission(ac.ActionTeamsRead),
+		ac.EvalPermission(ac.ActionOrgUsersRead),
+	)
+	writeGroups := ac.EvalAll(
+		ac.EvalPermission(ac.ActionTeamsRead),
+		ac.EvalPermission(ac.ActionTeamsCreate),
+		ac.EvalPermission(ac.ActionTeamsWrite),
+		ac.EvalPermission(ac.ActionTeamsDelete),
+		ac.EvalPermission(ac.ActionTeamsPermissionsWrite),
+		ac.EvalPermission(ac.ActionOrgUsersRead),
+	)
+
+	s.routeRegister.Group("/api/scim/v2", func(scimRoute routing.RouteRegister) {
+		scimRoute.Get("/ServiceProviderConfig", routing.Wrap(s.serviceProviderConfigHandler))
+		scimRoute.Get("/ResourceTypes", routing.Wrap(s.resourceTypesHandler))
+		scimRoute.Get("/Schemas", routing.Wrap(s.schemasHandler))
+
+		scimRoute.Group("/Users", func(userRoute routing.RouteRegister) {
+			userRoute.Get("/", authorize(readUsers), routing.Wrap(s.listUsersHandler))
+			userRoute.Post("/", authorize(writeUsers), routing.Wrap(s.createUserHandler))
+			userRoute.Get("/:id", authorize(readUsers), routing.Wrap(s.getUserHandler))
+			userRoute.Put("/:id", authorize(writeUsers), routing.Wrap(s.replaceUserHandler))
+			userRoute.Patch("/:id", authorize(writeUsers), routing.Wrap(s.patchUserHandler))
+			userRoute.Delete("/:id", authorize(writeUsers), routing.Wrap(s.deleteUserHandler))
+		})
+
+		scimRoute.Group("/Groups", func(groupRoute routing.RouteRegister) {
+			groupRoute.Get("/", authorize(readGroups), routing.Wrap(s.listGroupsHandler))
+			groupRoute.Post("/", authorize(writeGroups), routing.Wrap(s.createGroupHandler))
+			groupRoute.Get("/:id", authorize(readGroups), routing.Wrap(s.getGroupHandler))
+			groupRoute.Put("/:id", authorize(writeGroups), routing.Wrap(s.replaceGroupHandler))
+			groupRoute.Patch("/:id", authorize(writeGroups), routing.Wrap(s.patchGroupHandler))
+			groupRoute.Delete("/:id", authorize(writeGroups), routing.Wrap(s.deleteGroupHandler))
+		})
+	}, middleware.ReqSignedIn, reqServiceAccount)
+}
+
+// reqServiceAccount only lets service accounts call the SCIM API, provisioning is done by
+// the identity provider and not on behalf of a user.
+func reqServiceAccount(c *contextmodel.ReqContext) {
+	if !c.SignedInUser.IsServiceAccount {
+		err := &Error{Status: http.StatusForbidden, Detail: "the SCIM API requires a service account token"}
+		c.Resp.Header().Set("Content-Type", contentType)
+		c.JSON(err.Status, err.response())
+	}
+}
+
+func scimResponse(status int, body any) *response.NormalResponse {
+	return response.JSON(status, body).SetHeader("Content-Type", contentType)
+}
+
+// errorResponse writes err in the SCIM error format. Errors not raised by the SCIM service
+// are logged and hidden from the client.
+func (s *SCIMService) errorResponse(c *contextmodel.ReqContext, err error) response.Response {
+	var scimErr *Error
+	if !errors.As(err, &scimErr) {
+		s.log.FromContext(c.Req.Context()).Error("Failed to handle SCIM request", "method", c.Req.Method, "path", c.Req.URL.Path, "error", err)
+		scimErr = &Error{Status: http.StatusInternalServerError, Detail: "internal server error"}
+	}
+	return scimResponse(scimErr.Status, scimErr.response())
+}
+
+// bind decodes the request body, identity providers send it as application/scim+json
+func bind(req *http.Request, v any) error {
+	m, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil || (m != contentType && m != "application/json") {
+		return errBadRequest(scimTypeInvalidSyntax, "unsupported content type %q", req.Header.Get("Content-Type"))
+	}
+	defer func() { _ = req.Body.Close() }()
+	if err := json.NewDecoder(io.LimitReader(req.Body, maxBodySize)).Decode(v); err != nil {
+		return errBadRequest(scimTypeInvalidSyntax, "invalid request body: %s", err)
+	}
+	return nil
+}
+
+// pagination returns the 1-based index of the first resource and the number of resources to return
+func pagination(c *contextmodel.ReqContext) (int, int) {
+	startIndex := c.QueryInt("startIndex")
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	count := maxCount
+	if c.Query("count") != "" {
+		count = c.QueryInt("count")
+	}
+	if count < 0 {
+		count = 0
+	}
+	if count > maxCount {
+		count = maxCount
+	}
+	return startIndex, count
+}
+
+// pageBounds returns the bounds of the requested page in a list of total resources
+func pageBounds(total, startIndex, count int) (int, int) {
+	from := startIndex - 1
+	if from > total {
+		from = total
+	}
+	to := from + count
+	if to > total {
+		to = total
+	}
+	return from, to
+}
+
+func listResponse(resources []any, total, startIndex int) ListResponse {
+	return ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+func (s *SCIMService) location(resource string, id string) string {
+	return s.cfg.AppURL + "api/scim/v2/" + resource + "/" + id
+}
+
+func parseID(id string) (int64, bool) {
+	parsed, err := strconv.ParseInt(id, 10, 64)
+	return parsed, err == nil && parsed > 0
+}
+
+// serviceProviderConfigHandler returns the SCIM features supported by the server
+func (s *SCIMService) serviceProviderConfigHandler(c *contextmodel.ReqContext) response.Response {
+	return scimResponse(http.StatusOK, map[string]any{
+		"schemas":        []string{SchemaServiceProviderConfig},
+		"patch":          map[string]any{"supported": true},
+		"bulk":           map[string]any{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]any{"supported": true, "maxResults": maxCount},
+		"changePassword": map[string]any{"supported": false},
+		"sort":           map[string]any{"supported": false},
+		"etag":           map[string]any{"supported": false},
+		"authenticationSchemes": []map[string]any{{
+			"type":        "oauthbearertoken",
+			"name":        "Service account token",
+			"description": "Authentication with the token of a service account of the organization",
+			"primary":     true,
+		}},
+		"meta": Meta{ResourceType: "ServiceProviderConfig", Location: s.cfg.AppURL + "api/scim/v2/ServiceProviderConfig"},
+	})
+}
+
+// resourceTypesHandler returns the SCIM resource types supported by the server
+func (s *SCIMService) resourceTypesHandler(c *contextmodel.ReqContext) response.Response {
+	types := []any{
+		map[string]any{
+			"schemas":  []string{SchemaResourceType},
+			"id":       resourceTypeUser,
+			"name":     resourceTypeUser,
+			"endpoint": "/Users",
+			"schema":   SchemaUser,
+			"meta":     Meta{ResourceType: "ResourceType", Location: s.location("ResourceTypes", resourceTypeUser)},
+		},
+		map[string]any{
+			"schemas":  []string{SchemaResourceType},
+			"id":       resourceTypeGroup,
+			"name":     resourceTypeGroup,
+			"endpoint": "/Groups",
+			"schema":   SchemaGroup,
+			"meta":     Meta{ResourceType: "ResourceType", Location: s.location("ResourceTypes", resourceTypeGroup)},
+		},
+	}
+	return scimResponse(http.StatusOK, listResponse(types, len(types), 1))
+}
+
+// schemasHandler returns the attributes of the SCIM resources supported by the server
+func (s *SCIMService) schemasHandler(c *contextmodel.ReqContext) response.Response {
+	attribute := func(name, typ string, required bool, mutability string, sub ...map[string]any) map[string]any {
+		attr := map[string]any{
+			"name":        name,
+			"type":        typ,
+			"multiValued": false,
+			"required":    required,
+			"mutability":  mutability,
+			"returned":    "default",
+			"uniqueness":  "none",
+		}
+		if len(sub) > 0 {
+			attr["subAttributes"] = sub
+		}
+		return attr
+	}
+	multiValued := func(attr map[string]any) map[string]any {
+		attr["multiValued"] = true
+		return attr
+	}
+
+	userSchema := map[string]any{
+		"schemas": []string{SchemaSchema},
+		"id":      SchemaUser,
+		"name":    resourceTypeUser,
+		"attributes": []any{
+			attribute("userName", "string", true, "readWrite"),
+			attribute("name", "complex", false, "readWrite",
+				attribute("formatted", "string", false, "readWrite"),
+				attribute("givenName", "string", false, "readWrite"),
+				attribute("familyName", "string", false, "readWrite"),
+			),
+			attribute("displayName", "string", false, "readWrite"),
+			multiValued(attribute("emails", "complex", false, "readWrite",
+				attribute("value", "string", false, "readWrite"),
+				attribute("type", "string", false, "readWrite"),
+				attribute("primary", "boolean", false, "readWrite"),
+			)),
+			attribute("active", "boolean", false, "readWrite"),
+		},
+		"meta": Meta{ResourceType: "Schema", Location: s.location("Schemas", SchemaUser)},
+	}
+	groupSchema := map[string]any{
+		"schemas": []string{SchemaSchema},
+		"id":      SchemaGroup,
+		"name":    resourceTypeGroup,
+		"attributes": []any{
+			attribute("displayName", "string", true, "readWrite"),
+			multiValued(attribute("members", "complex", false, "readWrite",
+				attribute("value", "string", false, "immutable"),
+				attribute("display", "string", false, "readOnly"),
+				attribute("$ref", "reference", false, "immutable"),
+			)),
+		},
+		"meta": Meta{ResourceType: "Schema", Location: s.location("Schemas", SchemaGroup)},
+	}
+
+	schemas := []any{userSchema, groupSchema}
+	return scimResponse(http.StatusOK, listResponse(schemas, len(schemas), 1))
+}
@@ -0,0 +1,57 @@
+package scim
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// scimType values defined by RFC 7644 section 3.12
+const (
+	scimTypeInvalidFilter = "invalidFilter"
+	scimTypeInvalidSyntax = "invalidSyntax"
+	scimTypeInvalidPath   = "invalidPath"
+	scimTypeInvalidValue  = "invalidValue"
+	scimTypeNoTarget      = "noTarget"
+	scimTypeUniqueness    = "uniqueness"
+	scimTypeMutability    = "mutability"
+)
+
+// Error is returned to the client in the SCIM error format.
+type Error struct {
+	Status   int
+	ScimType string
+	Detail   string
+}
+
+func (e *Error) Error() string {
+	if e.ScimType == "" {
+		return fmt.Sprintf("scim: %d: %s", e.Status, e.Detail)
+	}
+	return fmt.Sprintf("scim: %d %s: %s", e.Status, e.ScimType, e.Detail)
+}
+
+func (e *Error) response() ErrorResponse {
+	return ErrorResponse{
+		Schemas:  []string{SchemaError},
+		Status:   strconv.Itoa(e.Status),
+		ScimType: e.ScimType,
+		Detail:   e.Detail,
+	}
+}
+
+func errBadRequest(scimType, format string, args ...any) *Error {
+	return &Error{Status: http.StatusBadRequest, ScimType: scimType, Detail: fmt.Sprintf(format, args...)}
+}
+
+func errForbidden(format string, args ...any) *Error {
+	return &Error{Status: http.StatusForbidden, Detail: fmt.Sprintf(format, args...)}
+}
+
+func errNotFound(format string, args ...any) *Error {
+	return &Error{Status: http.StatusNotFound, Detail: fmt.Sprintf(format, args...)}
+}
+
+func errConflict(format string, args ...any) *Error {
+	return &Error{Status: http.StatusConflict, ScimType: scimTypeUniqueness, Detail: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,205 @@
+package scim
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// filter is a parsed SCIM filter. Only the conditions used by identity providers to look up
+// resources are supported: comparisons and presence tests joined with "and".
+type filter []condition
+
+type condition struct {
+	attr  string
+	op    string
+	value string
+}
+
+const (
+	opEqual      = "eq"
+	opNotEqual   = "ne"
+	opContains   = "co"
+	opStartsWith = "sw"
+	opEndsWith   = "ew"
+	opPresent    = "pr"
+)
+
+// caseExactAttrs are compared with case, the other attributes are case-insensitive like in the core schema
+var caseExactAttrs = map[string]bool{"id": true, "externalId": true}
+
+// parseFilter parses the filter query parameter. attrs are the filterable attributes of the
+// resource, attribute names are matched case-insensitively and returned in this form.
+func parseFilter(s string, attrs []string) (filter, error) {
+	tokens, err := tokenizeFilter(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var f filter
+	for len(tokens) > 0 {
+		if len(f) > 0 {
+			if !strings.EqualFold(tokens[0].text, "and") || tokens[0].quoted {
+				return nil, errBadRequest(scimTypeInvalidFilter, "unsupported filter operator %q, only and is supported", tokens[0].text)
+			}
+			tokens = tokens[1:]
+		}
+		if len(tokens) < 2 {
+			return nil, errBadRequest(scimTypeInvalidFilter, "incomplete filter expression")
+		}
+
+		attr, ok := lookupAttr(tokens[0].text, attrs)
+		if !ok || tokens[0].quoted {
+			return nil, errBadRequest(scimTypeInvalidFilter, "unsupported filter attribute %q", tokens[0].text)
+		}
+
+		op := strings.ToLower(tokens[1].text)
+		switch op {
+		case opPresent:
+			f = append(f, condition{attr: attr, op: op})
+			tokens = tokens[2:]
+			continue
+		case opEqual, opNotEqual, opContains, opStartsWith, opEndsWith:
+		default:
+			return nil, errBadRequest(scimTypeInvalidFilter, "unsupported filter operator %q", tokens[1].text)
+		}
+
+		if len(tokens) < 3 {
+			return nil, errBadRequest(scimTypeInvalidFilter, "missing value for attribute %q", attr)
+		}
+		value := tokens[2]
+		if !value.quoted {
+			// booleans are the only unquoted values of the supported attributes
+			value.text = strings.ToLower(value.text)
+			if value.text != "true" && value.text != "false" {
+				return nil, errBadRequest(scimTypeInvalidFilter, "invalid value %q for attribute %q", tokens[2].text, attr)
+			}
+		}
+		f = append(f, condition{attr: attr, op: op, value: value.text})
+		tokens = tokens[3:]
+	}
+
+	return f, nil
+}
+
+func lookupAttr(name string, attrs []string) (string, bool) {
+	// attributes can be prefixed with the schema URN, e.g. urn:ietf:params:scim:schemas:core:2.0:User:userName
+	for _, schema := range []string{SchemaUser, SchemaGroup} {
+		if len(name) > len(schema) && strings.EqualFold(name[:len(schema)+1], schema+":") {
+			name = name[len(schema)+1:]
+		}
+	}
+	// emails is a shorthand for emails.value
+	if strings.EqualFold(name, "emails") {
+		name = "emails.value"
+	}
+	for _, attr := range attrs {
+		if strings.EqualFold(name, attr) {
+			return attr, true
+		}
+	}
+	return "", false
+}
+
+type filterToken struct {
+	text   string
+	quoted bool
+}
+
+func tokenizeFilter(s string) ([]filterToken, error) {
+	var tokens []filterToken
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == ' ':
+			i++
+		case s[i] == '"':
+			end := i + 1
+			for ; end < len(s) && s[end] != '"'; end++ {
+				if s[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(s) {
+				return nil, errBadRequest(scimTypeInvalidFilter, "unterminated string in filter")
+			}
+			var value string
+			if err := json.Unmarshal([]byte(s[i:end+1]), &value); err != nil {
+				return nil, errBadRequest(scimTypeInvalidFilter, "invalid string %s in filter", s[i:end+1])
+			}
+			tokens = append(tokens, filterToken{text: value, quoted: true})
+			i = end + 1
+		case s[i] == '(' || s[i] == ')' || s[i] == '[' || s[i] == ']':
+			return nil, errBadRequest(scimTypeInvalidFilter, "grouping is not supported in filters")
+		default:
+			end := strings.IndexAny(s[i:], " \"")
+			if end < 0 {
+				end = len(s) - i
+			}
+			tokens = append(tokens, filterToken{text: s[i : i+end]})
+			i += end
+		}
+	}
+	return tokens, nil
+}
+
+// match returns true if the resource matches all the conditions. values returns the values of
+// an attribute of the resource, multi-valued attributes like emails.value match if any value does.
+func (f filter) match(values func(attr string) []string) bool {
+	for _, c := range f {
+		if !c.match(values(c.attr)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c condition) match(values []string) bool {
+	if c.op == opPresent {
+		for _, v := range values {
+			if v != "" {
+				return true
+			}
+		}
+		return false
+	}
+
+	if c.op == opNotEqual {
+		return !condition{attr: c.attr, op: opEqual, value: c.value}.match(values)
+	}
+
+	expected := c.value
+	for _, v := range values {
+		if !caseExactAttrs[c.attr] {
+			v, expected = strings.ToLower(v), strings.ToLower(expected)
+		}
+		switch c.op {
+		case opEqual:
+			if v == expected {
+				return true
+			}
+		case opContains:
+			if strings.Contains(v, expected) {
+				return true
+			}
+		case opStartsWith:
+			if strings.HasPrefix(v, expected) {
+				return true
+			}
+		case opEndsWith:
+			if strings.HasSuffix(v, expected) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// equal returns the value of the first equality condition on attr. It is used to narrow
+// down the resources to fetch before the filter is applied.
+func (f filter) equal(attr string) (string, bool) {
+	for _, c := range f {
+		if c.attr == attr && c.op == opEqual {
+			return c.value, true
+		}
+	}
+	return "", false
+}
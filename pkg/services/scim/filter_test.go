@@ -0,0 +1,93 @@
+package scim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		desc     string
+		filter   string
+		expected filter
+		err      bool
+	}{
+		{desc: "empty filter", filter: ""},
+		{
+			desc:     "equality",
+			filter:   `userName eq "jdoe@example.org"`,
+			expected: filter{{attr: "userName", op: opEqual, value: "jdoe@example.org"}},
+		},
+		{
+			desc:     "case-insensitive attribute and operator with schema prefix",
+			filter:   `urn:ietf:params:scim:schemas:core:2.0:User:USERNAME EQ "jdoe"`,
+			expected: filter{{attr: "userName", op: opEqual, value: "jdoe"}},
+		},
+		{
+			desc:   "conditions joined with and",
+			filter: `emails pr and active eq True and externalId eq "a \"quoted\" id"`,
+			expected: filter{
+				{attr: "emails.value", op: opPresent},
+				{attr: "active", op: opEqual, value: "true"},
+				{attr: "externalId", op: opEqual, value: `a "quoted" id`},
+			},
+		},
+		{desc: "or is not supported", filter: `userName eq "a" or userName eq "b"`, err: true},
+		{desc: "grouping is not supported", filter: `(userName eq "a")`, err: true},
+		{desc: "unknown attribute", filter: `title eq "a"`, err: true},
+		{desc: "unknown operator", filter: `userName gt "a"`, err: true},
+		{desc: "missing value", filter: `userName eq`, err: true},
+		{desc: "unquoted string", filter: `userName eq jdoe`, err: true},
+		{desc: "unterminated string", filter: `userName eq "jdoe`, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			f, err := parseFilter(tt.filter, userFilterAttrs)
+			if tt.err {
+				var scimErr *Error
+				require.ErrorAs(t, err, &scimErr)
+				assert.Equal(t, scimTypeInvalidFilter, scimErr.ScimType)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, f)
+		})
+	}
+}
+
+func TestFilter_Match(t *testing.T) {
+	values := map[string][]string{
+		"id":           {"12"},
+		"userName":     {"JDoe"},
+		"emails.value": {"jdoe@example.org"},
+		"externalId":   {"ABC"},
+		"active":       {"true"},
+	}
+	get := func(attr string) []string { return values[attr] }
+
+	tests := []struct {
+		filter  string
+		matches bool
+	}{
+		{filter: `userName eq "jdoe"`, matches: true},
+		{filter: `externalId eq "abc"`, matches: false},
+		{filter: `externalId eq "ABC"`, matches: true},
+		{filter: `userName ne "jdoe"`, matches: false},
+		{filter: `emails co "@example"`, matches: true},
+		{filter: `userName sw "jd" and emails.value ew ".org"`, matches: true},
+		{filter: `userName sw "jd" and active eq false`, matches: false},
+		{filter: `displayName pr`, matches: false},
+		{filter: `id eq "12"`, matches: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			f, err := parseFilter(tt.filter, userFilterAttrs)
+			require.NoError(t, err)
+			assert.Equal(t, tt.matches, f.match(get))
+		})
+	}
+}
@@ -0,0 +1,484 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/team"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+var groupFilterAttrs = []string{"id", "displayName"}
+
+// teamMemberPermission is the permission of the members added by the identity provider
+const teamMemberPermission = "Member"
+
+func (s *SCIMService) listGroupsHandler(c *contextmodel.ReqContext) response.Response {
+	ctx := c.Req.Context()
+	f, err := parseFilter(c.Query("filter"), groupFilterAttrs)
+	if err != nil {
+		return s.errorResponse(c, err)
+	}
+
+	teams, err := s.searchTeams(ctx, c.SignedInUser, f)
+	if err != nil {
+		return s.errorResponse(c, err)
+	}
+
+	startIndex, count := pagination(c)
+	from, to := pageBounds(len(teams), startIndex, count)
+	resources := make([]any, 0, to-from)
+	for _, t := range teams[from:to] {
+		g, err := s.toGroup(ctx, c.SignedInUser, t, includeMembers(c))
+		if err != nil {
+			return s.errorResponse(c, err)
+		}
+		resources = append(resources, g)
+	}
+	return scimResponse(http.StatusOK, listResponse(resources, len(teams), startIndex))
+}
+
+func (s *SCIMService) getGroupHandler(c *contextmodel.ReqContext) response.Response {
+	t, err := s.getTeam(c.Req.Context(), c.SignedInUser, web.Params(c.Req)[":id"])
+	if err != nil {
+		return s.errorResponse(c, err)
+	}
+	return s.groupResponse(c, http.StatusOK, t.ID)
+}
+
+func (s *SCIMService) createGroupHandler(c *contextmodel.ReqContext) response.Response {
+	ctx := c.Req.Context()
+	var in Group
+	if err := bind(c.Req, &in); err != nil {
+		return s.errorResponse(c, err)
+	}
+	if in.DisplayName == "" {
+		return s.errorResponse(c, errBadRequest(scimTypeInvalidValue, "displayName is required"))
+	}
+	members, err := s.memberIDs(ctx, c.SignedInUser, in.Members)
+	if err != nil {
+		return s.errorResponse(c, err)
+	}
+
+	orgID := c.SignedInUser.GetOrgID()
+	t, err := s.teamService.CreateTeam(in.DisplayName, "", orgID)
+	if err != nil {
+		if errors.Is(err, team.ErrTeamNameTaken) {
+			return s.errorResponse(c, errConflict("group %s already exists", in.DisplayName))
+		}
+		return s.errorResponse(c, err)
+	}
+	if err := s.syncMembers(ctx, orgID, t.ID, map[int64]bool{}, members); err != nil {
+		return s.errorResponse(c, err)
+	}
+
+	s.log.FromContext(ctx).Info("Team provisioned", "teamId", t.ID, "orgId", orgID, "members", len(members), "serviceAccountId", c.SignedInUser.UserID)
+	return s.groupResponse(c, http.StatusCreated, t.ID)
+}
+
+func (s *SCIMService) replaceGroupHandler(c *contextmodel.ReqContext) response.Response {
+	ctx := c.Req.Context()
+	t, err := s.getTeam(ctx, c.SignedInUser, web.Params(c.Req)[":id"])
+	if err != nil {
+		return s.errorResponse(c, err)
+	}
+
+	var in Group
+	if err := bind(c.Req, &in); err != nil {
+		return s.errorResponse(c, err)
+	}
+	if in.DisplayName == "" {
+		return s.errorResponse(c, errBadRequest(scimTypeInvalidValue, "displayName is required"))
+	}
+
+	current, err := s.currentMembers(ctx, c.SignedInUser, t.ID)
+	if err != nil {
+		return s.errorResponse(c, err)
+	}
+	members, err := s.memberIDs(ctx, c.SignedInUser, in.Members)
+	if err != nil {
+		return s.errorResponse(c, err)
+	}
+	if err := s.updateTeam(ctx, t, in.DisplayName, current, members); err != nil {
+		return s.errorResponse(c, err)
+	}
+	return s.groupResponse(c, http.StatusOK, t.ID)
+}
+
+func (s *SCIMService) patchGroupHandler(c *contextmodel.ReqContext) response.Response {
+	ctx := c.Req.Context()
+	t, err := s.getTeam(ctx, c.SignedInUser, web.Params(c.Req)[":id"])
+	if err != nil {
+		return s.errorResponse(c, err)
+	}
+
+	var req PatchRequest
+	if err := bind(c.Req, &req); err != nil {
+		return s.errorResponse(c, err)
+	}
+
+	current, err := s.currentMembers(ctx, c.SignedInUser, t.ID)
+	if err != nil {
+		return s.errorResponse(c, err)
+	}
+
+	patch := &groupPatch{name: t.Name, members: make(map[int64]bool, len(current))}
+	for id := range current {
+		patch.members[id] = true
+	}
+	if err := patch.apply(req.Operations); err != nil {
+		return s.errorResponse(c, err)
+	}
+
+	// only the members added by the patch must be users of the organization
+	added := make([]MemberRef, 0)
+	for id := range patch.members {
+		if !current[id] {
+			added = append(added, MemberRef{Value: strconv.FormatInt(id, 10)})
+		}
+	}
+	if _, err := s.memberIDs(ctx, c.SignedInUser, added); err != nil {
+		return s.errorResponse(c, err)
+	}
+
+	if err := s.updateTeam(ctx, t, patch.name, current, patch.members); err != nil {
+		return s.errorResponse(c, err)
+	}
+	return s.groupResponse(c, http.StatusOK, t.ID)
+}
+
+func (s *SCIMService) deleteGroupHandler(c *contextmodel.ReqContext) response.Response {
+	ctx := c.Req.Context()
+	t, err := s.getTeam(ctx, c.SignedInUser, web.Params(c.Req)[":id"])
+	if err != nil {
+		return s.errorResponse(c, err)
+	}
+
+	if err := s.teamService.DeleteTeam(ctx, &team.DeleteTeamCommand{OrgID: t.OrgID, ID: t.ID}); err != nil {
+		if errors.Is(err, team.ErrTeamNotFound) {
+			return s.errorResponse(c, errNotFound("group %d not found", t.ID))
+		}
+		return s.errorResponse(c, err)
+	}
+
+	s.log.FromContext(ctx).Info("Team deprovisioned", "teamId", t.ID, "orgId", t.OrgID, "serviceAccountId", c.SignedInUser.UserID)
+	return response.Empty(http.StatusNoContent)
+}
+
+// searchTeams returns the teams of the organization matching the filter
+func (s *SCIMService) searchTeams(ctx context.Context, signedInUser *user.SignedInUser, f filter) ([]*team.TeamDTO, error) {
+	if id, ok := f.equal("id"); ok {
+		t, err := s.getTeam(ctx, signedInUser, id)
+		if err != nil {
+			var scimErr *Error
+			if errors.As(err, &scimErr) && scimErr.Status == http.StatusNotFound {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return filterTeams([]*team.TeamDTO{t}, f), nil
+	}
+
+	query := &team.SearchTeamsQuery{OrgID: signedInUser.GetOrgID(), SignedInUser: signedInUser, Page: 1}
+	// the team search is not case-insensitive on every database, the filter is applied to the result
+	if name, ok := f.equal("displayName"); ok {
+		query.Query = name
+	}
+	result, err := s.teamService.SearchTeams(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return filterTeams(result.Teams, f), nil
+}
+
+func filterTeams(teams []*team.TeamDTO, f filter) []*team.TeamDTO {
+	if len(f) == 0 {
+		return teams
+	}
+	matches := make([]*team.TeamDTO, 0, len(teams))
+	for _, t := range teams {
+		matched := f.match(func(attr string) []string {
+			switch attr {
+			case "id":
+				return []string{strconv.FormatInt(t.ID, 10)}
+			case "displayName":
+				return []string{t.Name}
+			}
+			return nil
+		})
+		if matched {
+			matches = append(matches, t)
+		}
+	}
+	return matches
+}
+
+// getTeam returns a team of the organization of the service account
+func (s *SCIMService) getTeam(ctx context.Context, signedInUser *user.SignedInUser, id string) (*team.TeamDTO, error) {
+	teamID, ok := parseID(id)
+	if !ok {
+		return nil, errNotFound("group %s not found", id)
+	}
+	t, err := s.teamService.GetTeamByID(ctx, &team.GetTeamByIDQuery{
+		OrgID:        signedInUser.GetOrgID(),
+		ID:           teamID,
+		SignedInUser: signedInUser,
+	})
+	if err != nil {
+		if errors.Is(err, team.ErrTeamNotFound) {
+			return nil, errNotFound("group %s not found", id)
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *SCIMService) groupResponse(c *contextmodel.ReqContext, status int, teamID int64) response.Response {
+	ctx := c.Req.Context()
+	t, err := s.getTeam(ctx, c.SignedInUser, strconv.FormatInt(teamID, 10))
+	if err != nil {
+		return s.errorResponse(c, err)
+	}
+	g, err := s.toGroup(ctx, c.SignedInUser, t, includeMembers(c))
+	if err != nil {
+		return s.errorResponse(c, err)
+	}
+	return scimResponse(status, g).SetHeader("Location", g.Meta.Location)
+}
+
+func (s *SCIMService) toGroup(ctx context.Context, signedInUser *user.SignedInUser, t *team.TeamDTO, withMembers bool) (*Group, error) {
+	id := strconv.FormatInt(t.ID, 10)
+	g := &Group{
+		Schemas:     []string{SchemaGroup},
+		ID:          id,
+		DisplayName: t.Name,
+		Meta: &Meta{
+			ResourceType: resourceTypeGroup,
+			Location:     s.location("Groups", id),
+		},
+	}
+	if !withMembers {
+		return g, nil
+	}
+
+	members, err := s.teamService.GetTeamMembers(ctx, &team.GetTeamMembersQuery{
+		OrgID:        t.OrgID,
+		TeamID:       t.ID,
+		SignedInUser: signedInUser,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range members {
+		memberID := strconv.FormatInt(m.UserID, 10)
+		g.Members = append(g.Members, MemberRef{Value: memberID, Display: m.Login, Ref: s.location("Users", memberID)})
+	}
+	return g, nil
+}
+
+// includeMembers returns false when the identity provider excludes the members from the response,
+// which is common when checking if a group exists
+func includeMembers(c *contextmodel.ReqContext) bool {
+	for _, attr := range strings.Split(c.Query("excludedAttributes"), ",") {
+		if strings.EqualFold(strings.TrimSpace(attr), "members") {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *SCIMService) currentMembers(ctx context.Context, signedInUser *user.SignedInUser, teamID int64) (map[int64]bool, error) {
+	members, err := s.teamService.GetTeamMembers(ctx, &team.GetTeamMembersQuery{
+		OrgID:        signedInUser.GetOrgID(),
+		TeamID:       teamID,
+		SignedInUser: signedInUser,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[int64]bool, len(members))
+	for _, m := range members {
+		ids[m.UserID] = true
+	}
+	return ids, nil
+}
+
+// memberIDs returns the ids of the members, they must be users of the organization
+func (s *SCIMService) memberIDs(ctx context.Context, signedInUser *user.SignedInUser, members []MemberRef) (map[int64]bool, error) {
+	ids := make(map[int64]bool, len(members))
+	for _, m := range members {
+		userID, ok := parseID(m.Value)
+		if !ok {
+			return nil, errBadRequest(scimTypeInvalidValue, "invalid member %q", m.Value)
+		}
+		if _, err := s.getOrgUser(ctx, signedInUser, m.Value); err != nil {
+			var scimErr *Error
+			if errors.As(err, &scimErr) && scimErr.Status == http.StatusNotFound {
+				return nil, errBadRequest(scimTypeInvalidValue, "member %s is not a user of the organization", m.Value)
+			}
+			return nil, err
+		}
+		ids[userID] = true
+	}
+	return ids, nil
+}
+
+func (s *SCIMService) updateTeam(ctx context.Context, t *team.TeamDTO, name string, current, members map[int64]bool) error {
+	if name == "" {
+		return errBadRequest(scimTypeInvalidValue, "displayName is required")
+	}
+	if name != t.Name {
+		if err := s.teamService.UpdateTeam(ctx, &team.UpdateTeamCommand{ID: t.ID, OrgID: t.OrgID, Name: name, Email: t.Email}); err != nil {
+			if errors.Is(err, team.ErrTeamNameTaken) {
+				return errConflict("group %s already exists", name)
+			}
+			return err
+		}
+	}
+	return s.syncMembers(ctx, t.OrgID, t.ID, current, members)
+}
+
+// syncMembers adds and removes team members through the team permissions, like the team API
+func (s *SCIMService) syncMembers(ctx context.Context, orgID, teamID int64, current, members map[int64]bool) error {
+	teamIDString := strconv.FormatInt(teamID, 10)
+	for userID := range members {
+		if current[userID] {
+			continue
+		}
+		if _, err := s.teamPermissionsService.SetUserPermission(ctx, orgID, accesscontrol.User{ID: userID}, teamIDString, teamMemberPermission); err != nil {
+			return err
+		}
+	}
+	for userID := range current {
+		if members[userID] {
+			continue
+		}
+		if _, err := s.teamPermissionsService.SetUserPermission(ctx, orgID, accesscontrol.User{ID: userID}, teamIDString, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// groupPatch is the name and the members of a team after PATCH operations
+type groupPatch struct {
+	name    string
+	members map[int64]bool
+}
+
+func (p *groupPatch) apply(ops []PatchOperation) error {
+	for _, op := range ops {
+		switch opType := strings.ToLower(op.Op); opType {
+		case patchOpAdd, patchOpReplace:
+			if op.Path != "" {
+				if err := p.set(opType, op.Path, op.Value); err != nil {
+					return err
+				}
+				continue
+			}
+			values, err := patchObject(op.Value)
+			if err != nil {
+				return err
+			}
+			for _, path := range sortedKeys(values) {
+				if readOnlyAttrs[path] {
+					continue
+				}
+				if err := p.set(opType, path, values[path]); err != nil {
+					return err
+				}
+			}
+		case patchOpRemove:
+			if err := p.remove(op.Path, op.Value); err != nil {
+				return err
+			}
+		default:
+			return errBadRequest(scimTypeInvalidSyntax, "unsupported patch operation %q", op.Op)
+		}
+	}
+	return nil
+}
+
+func (p *groupPatch) set(op, path string, value json.RawMessage) error {
+	attr := normalizePath(path, SchemaGroup)
+	switch {
+	case strings.EqualFold(attr, "displayName"):
+		return unmarshalString(path, value, &p.name)
+	case strings.EqualFold(attr, "members"):
+		ids, err := decodeMembers(path, value)
+		if err != nil {
+			return err
+		}
+		if op == patchOpReplace {
+			p.members = map[int64]bool{}
+		}
+		for _, id := range ids {
+			p.members[id] = true
+		}
+		return nil
+	}
+	return errBadRequest(scimTypeInvalidPath, "unsupported attribute %s", path)
+}
+
+func (p *groupPatch) remove(path string, value json.RawMessage) error {
+	attr := normalizePath(path, SchemaGroup)
+	if memberID, ok, err := memberPathFilter(attr); err != nil {
+		return err
+	} else if ok {
+		id, valid := parseID(memberID)
+		if !valid {
+			return errBadRequest(scimTypeInvalidValue, "invalid member %q", memberID)
+		}
+		delete(p.members, id)
+		return nil
+	}
+
+	switch {
+	case path == "":
+		return errBadRequest(scimTypeNoTarget, "path is required to remove an attribute")
+	case strings.EqualFold(attr, "members"):
+		// without value all the members are removed
+		if len(value) == 0 || string(value) == "null" {
+			p.members = map[int64]bool{}
+			return nil
+		}
+		ids, err := decodeMembers(path, value)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			delete(p.members, id)
+		}
+		return nil
+	}
+	return errBadRequest(scimTypeMutability, "attribute %s cannot be removed", path)
+}
+
+func decodeMembers(path string, value json.RawMessage) ([]int64, error) {
+	var members []MemberRef
+	if err := json.Unmarshal(value, &members); err != nil {
+		// a single member can be sent without array
+		var member MemberRef
+		if err := json.Unmarshal(value, &member); err != nil {
+			return nil, errBadRequest(scimTypeInvalidValue, "invalid value for %s", path)
+		}
+		members = []MemberRef{member}
+	}
+	ids := make([]int64, 0, len(members))
+	for _, m := range members {
+		id, ok := parseID(m.Value)
+		if !ok {
+			return nil, errBadRequest(scimTypeInvalidValue, "invalid member %q", m.Value)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,216 @@
+package scim
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	patchOpAdd     = "add"
+	patchOpReplace = "replace"
+	patchOpRemove  = "remove"
+)
+
+// readOnlyAttrs are ignored in the value of an operation without path, some identity providers
+// send the whole resource
+var readOnlyAttrs = map[string]bool{"schemas": true, "id": true, "meta": true}
+
+// applyUserPatch applies the PATCH operations to the user. Identity providers either set a path
+// or send the attributes to add or replace as an object without path.
+func applyUserPatch(u *User, ops []PatchOperation) error {
+	patched := map[string]bool{}
+	for _, op := range ops {
+		switch strings.ToLower(op.Op) {
+		case patchOpAdd, patchOpReplace:
+			if op.Path != "" {
+				if err := setUserAttr(u, op.Path, op.Value, patched); err != nil {
+					return err
+				}
+				continue
+			}
+			values, err := patchObject(op.Value)
+			if err != nil {
+				return err
+			}
+			for _, path := range sortedKeys(values) {
+				if readOnlyAttrs[path] {
+					continue
+				}
+				if err := setUserAttr(u, path, values[path], patched); err != nil {
+					return err
+				}
+			}
+		case patchOpRemove:
+			if err := removeUserAttr(u, op.Path); err != nil {
+				return err
+			}
+		default:
+			return errBadRequest(scimTypeInvalidSyntax, "unsupported patch operation %q", op.Op)
+		}
+	}
+
+	// the Grafana user has a single name, the most specific name attribute wins so changing
+	// the given or family name must not be shadowed by the current display name
+	if !patched["displayName"] && (patched["name.formatted"] || patched["name.givenName"] || patched["name.familyName"]) {
+		u.DisplayName = ""
+		if !patched["name.formatted"] && u.Name != nil {
+			u.Name.Formatted = ""
+		}
+	}
+	return nil
+}
+
+func setUserAttr(u *User, path string, value json.RawMessage, patched map[string]bool) error {
+	attr := normalizePath(path, SchemaUser)
+	switch {
+	case strings.EqualFold(attr, "userName"):
+		return unmarshalString(path, value, &u.UserName)
+	case strings.EqualFold(attr, "displayName"):
+		patched["displayName"] = true
+		return unmarshalString(path, value, &u.DisplayName)
+	case strings.EqualFold(attr, "externalId"):
+		return unmarshalString(path, value, &u.ExternalID)
+	case strings.EqualFold(attr, "active"):
+		active, err := unmarshalBool(path, value)
+		if err != nil {
+			return err
+		}
+		u.Active = &active
+		return nil
+	case strings.EqualFold(attr, "name"):
+		var name Name
+		if err := json.Unmarshal(value, &name); err != nil {
+			return errBadRequest(scimTypeInvalidValue, "invalid value for %s", path)
+		}
+		u.Name = &name
+		patched["name.formatted"] = name.Formatted != ""
+		patched["name.givenName"] = name.GivenName != ""
+		patched["name.familyName"] = name.FamilyName != ""
+		return nil
+	case strings.EqualFold(attr, "name.formatted"):
+		patched["name.formatted"] = true
+		return unmarshalString(path, value, &userName(u).Formatted)
+	case strings.EqualFold(attr, "name.givenName"):
+		patched["name.givenName"] = true
+		return unmarshalString(path, value, &userName(u).GivenName)
+	case strings.EqualFold(attr, "name.familyName"):
+		patched["name.familyName"] = true
+		return unmarshalString(path, value, &userName(u).FamilyName)
+	case strings.EqualFold(attr, "emails"):
+		var emails []Email
+		if err := json.Unmarshal(value, &emails); err != nil {
+			return errBadRequest(scimTypeInvalidValue, "invalid value for %s", path)
+		}
+		u.Emails = emails
+		return nil
+	case strings.EqualFold(attr, "emails.value"), isEmailValuePath(attr):
+		// Grafana users have a single email, it replaces the primary one whatever its type
+		var email string
+		if err := unmarshalString(path, value, &email); err != nil {
+			return err
+		}
+		u.Emails = []Email{{Value: email, Type: "work", Primary: true}}
+		return nil
+	}
+	return errBadRequest(scimTypeInvalidPath, "unsupported attribute %s", path)
+}
+
+// userName returns the name of the user, it is created if the user has none
+func userName(u *User) *Name {
+	if u.Name == nil {
+		u.Name = &Name{}
+	}
+	return u.Name
+}
+
+func removeUserAttr(u *User, path string) error {
+	attr := normalizePath(path, SchemaUser)
+	switch {
+	case strings.EqualFold(attr, "displayName"):
+		u.DisplayName = ""
+		return nil
+	case strings.EqualFold(attr, "name"):
+		u.Name = nil
+		u.DisplayName = ""
+		return nil
+	case path == "":
+		return errBadRequest(scimTypeNoTarget, "path is required to remove an attribute")
+	}
+	return errBadRequest(scimTypeMutability, "attribute %s cannot be removed", path)
+}
+
+// normalizePath removes the schema URN prefix of an attribute path
+func normalizePath(path, schema string) string {
+	if len(path) > len(schema) && strings.EqualFold(path[:len(schema)+1], schema+":") {
+		return path[len(schema)+1:]
+	}
+	return path
+}
+
+// isEmailValuePath matches paths such as emails[type eq "work"].value
+func isEmailValuePath(attr string) bool {
+	lower := strings.ToLower(attr)
+	return strings.HasPrefix(lower, "emails[") && strings.HasSuffix(lower, "].value")
+}
+
+// memberPathFilter returns the id of the member in paths such as members[value eq "1"]
+func memberPathFilter(attr string) (string, bool, error) {
+	lower := strings.ToLower(attr)
+	if !strings.HasPrefix(lower, "members[") {
+		return "", false, nil
+	}
+	if !strings.HasSuffix(lower, "]") {
+		return "", false, errBadRequest(scimTypeInvalidPath, "unsupported attribute %s", attr)
+	}
+	f, err := parseFilter(attr[len("members["):len(attr)-1], []string{"value"})
+	if err != nil {
+		return "", false, err
+	}
+	value, ok := f.equal("value")
+	if !ok || len(f) != 1 {
+		return "", false, errBadRequest(scimTypeInvalidPath, "unsupported attribute %s", attr)
+	}
+	return value, true, nil
+}
+
+// patchObject decodes the value of an operation without path
+func patchObject(value json.RawMessage) (map[string]json.RawMessage, error) {
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(value, &values); err != nil {
+		return nil, errBadRequest(scimTypeInvalidValue, "the value of an operation without path must be an object")
+	}
+	return values, nil
+}
+
+func sortedKeys(values map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func unmarshalString(path string, value json.RawMessage, dest *string) error {
+	if err := json.Unmarshal(value, dest); err != nil {
+		return errBadRequest(scimTypeInvalidValue, "invalid value for %s, expected a string", path)
+	}
+	return nil
+}
+
+// unmarshalBool accepts booleans sent as strings, some identity providers send "False"
+func unmarshalBool(path string, value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b, nil
+		}
+	}
+	return false, errBadRequest(scimTypeInvalidValue, "invalid value for %s, expected a boolean", path)
+}
@@ -0,0 +1,74 @@
+package scim
+
+import (
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/auth"
+	"github.com/grafana/grafana/pkg/services/login"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/team"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// authModule is the auth module of the user_auth entries storing the externalId of provisioned users
+const authModule = "scim"
+
+// Service is a SCIM 2.0 server provisioning the users and teams of an organization from an
+// identity provider. The provider authenticates with a service account token of the organization.
+type Service interface {
+	IsEnabled() bool
+}
+
+type SCIMService struct {
+	cfg                    *setting.Cfg
+	routeRegister          routing.RouteRegister
+	accessControl          accesscontrol.AccessControl
+	accessControlService   accesscontrol.Service
+	userService            user.Service
+	orgService             org.Service
+	teamService            team.Service
+	teamPermissionsService accesscontrol.TeamPermissionsService
+	authInfoService        login.AuthInfoService
+	userTokenService       auth.UserTokenService
+	log                    log.Logger
+}
+
+var _ Service = (*SCIMService)(nil)
+
+func ProvideService(cfg *setting.Cfg, routeRegister routing.RouteRegister, ac accesscontrol.AccessControl,
+	acService accesscontrol.Service, userService user.Service, orgService org.Service, teamService team.Service,
+	teamPermissionsService accesscontrol.TeamPermissionsService, authInfoService login.AuthInfoService,
+	userTokenService auth.UserTokenService,
+) *SCIMService {
+	s := &SCIMService{
+		cfg:                    cfg,
+		routeRegister:          routeRegister,
+		accessControl:          ac,
+		accessControlService:   acService,
+		userService:            userService,
+		orgService:             orgService,
+		teamService:            teamService,
+		teamPermissionsService: teamPermissionsService,
+		authInfoService:        authInfoService,
+		userTokenService:       userTokenService,
+		log:                    log.New("scim"),
+	}
+	if s.IsEnabled() {
+		s.registerAPIEndpoints()
+	}
+	return s
+}
+
+func (s *SCIMService) IsEnabled() bool {
+	return s.cfg.SCIMEnabled
+}
+
+// defaultRole is the role of the users provisioned in the organization
+func (s *SCIMService) defaultRole() org.RoleType {
+	if role := org.RoleType(s.cfg.AutoAssignOrgRole); role.IsValid() {
+		return role
+	}
+	return org.RoleViewer
+}
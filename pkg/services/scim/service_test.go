@@ -0,0 +1,283 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/accesscontrol/acimpl"
+	"github.com/grafana/grafana/pkg/services/accesscontrol/actest"
+	acmock "github.com/grafana/grafana/pkg/services/accesscontrol/mock"
+	"github.com/grafana/grafana/pkg/services/auth/authtest"
+	"github.com/grafana/grafana/pkg/services/login/logintest"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/org/orgtest"
+	"github.com/grafana/grafana/pkg/services/team"
+	"github.com/grafana/grafana/pkg/services/team/teamtest"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/web/webtest"
+)
+
+type testEnv struct {
+	server      *webtest.Server
+	userService *usertest.FakeUserService
+	orgService  *orgtest.FakeOrgService
+	tokens      *authtest.FakeUserAuthTokenService
+	permissions *acmock.MockPermissionsService
+}
+
+func setupTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	cfg := setting.NewCfg()
+	cfg.SCIMEnabled = true
+	cfg.AppURL = "http://localhost:3000/"
+
+	env := &testEnv{
+		userService: usertest.NewUserServiceFake(),
+		orgService:  orgtest.NewOrgServiceFake(),
+		tokens:      authtest.NewFakeUserAuthTokenService(),
+		permissions: acmock.NewMockedPermissionsService(),
+	}
+	env.userService.ExpectedUser = &user.User{ID: 1, Login: "jdoe", Email: "jdoe@example.org"}
+	env.orgService.ExpectedUserOrgDTO = []*org.UserOrgDTO{{OrgID: 1, Name: "Main Org."}}
+	env.orgService.ExpectedSearchOrgUsersResult = &org.SearchOrgUsersQueryResult{OrgUsers: []*org.OrgUserDTO{
+		{OrgID: 1, UserID: 1, Login: "jdoe", Email: "jdoe@example.org", Name: "John Doe"},
+		{OrgID: 1, UserID: 2, Login: "jdoe2", Email: "jdoe2@example.org", Name: "Jane Doe", IsDisabled: true},
+	}}
+	teamService := teamtest.NewFakeService()
+	teamService.ExpectedTeamDTO = &team.TeamDTO{ID: 3, OrgID: 1, Name: "sre"}
+	teamService.ExpectedMembers = []*team.TeamMemberDTO{{UserID: 1, Login: "jdoe"}}
+
+	routeRegister := routing.NewRouteRegister()
+	ProvideService(cfg, routeRegister, acimpl.ProvideAccessControl(cfg), actest.FakeService{}, env.userService,
+		env.orgService, teamService, env.permissions, &logintest.AuthInfoServiceFake{ExpectedError: user.ErrUserNotFound}, env.tokens)
+	env.server = webtest.NewServer(t, routeRegister)
+	return env
+}
+
+func (env *testEnv) send(t *testing.T, method, url, body string, usr *user.SignedInUser) (*http.Response, map[string]any) {
+	t.Helper()
+	req := env.server.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	webtest.RequestWithSignedInUser(req, usr)
+	res, err := env.server.Send(req)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, res.Body.Close()) }()
+
+	var output map[string]any
+	if res.StatusCode != http.StatusNoContent {
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&output))
+	}
+	return res, output
+}
+
+func serviceAccount() *user.SignedInUser {
+	return &user.SignedInUser{
+		UserID:           10,
+		OrgID:            1,
+		OrgRole:          org.RoleAdmin,
+		IsServiceAccount: true,
+		Permissions: map[int64]map[string][]string{1: accesscontrol.GroupScopesByAction([]accesscontrol.Permission{
+			{Action: accesscontrol.ActionOrgUsersRead, Scope: "users:*"},
+			{Action: accesscontrol.ActionOrgUsersAdd, Scope: "users:*"},
+			{Action: accesscontrol.ActionOrgUsersWrite, Scope: "users:*"},
+			{Action: accesscontrol.ActionOrgUsersRemove, Scope: "users:*"},
+			{Action: accesscontrol.ActionTeamsRead, Scope: "teams:*"},
+			{Action: accesscontrol.ActionTeamsCreate},
+			{Action: accesscontrol.ActionTeamsWrite, Scope: "teams:*"},
+			{Action: accesscontrol.ActionTeamsDelete, Scope: "teams:*"},
+			{Action: accesscontrol.ActionTeamsPermissionsWrite, Scope: "teams:*"},
+		})},
+	}
+}
+
+func TestSCIMService_Authorization(t *testing.T) {
+	env := setupTestEnv(t)
+
+	usr := serviceAccount()
+	usr.IsServiceAccount = false
+	res, output := env.send(t, http.MethodGet, "/api/scim/v2/Users", "", usr)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+	assert.Equal(t, []any{SchemaError}, output["schemas"])
+
+	usr = serviceAccount()
+	usr.Permissions = map[int64]map[string][]string{1: {}}
+	res, _ = env.send(t, http.MethodGet, "/api/scim/v2/Users", "", usr)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+}
+
+func TestSCIMService_Users(t *testing.T) {
+	t.Run("should filter users", func(t *testing.T) {
+		env := setupTestEnv(t)
+		res, output := env.send(t, http.MethodGet, `/api/scim/v2/Users?filter=userName+eq+%22JDOE%22`, "", serviceAccount())
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, contentType, res.Header.Get("Content-Type"))
+		assert.EqualValues(t, 1, output["totalResults"])
+
+		resources := output["Resources"].([]any)
+		require.Len(t, resources, 1)
+		assert.Equal(t, "1", resources[0].(map[string]any)["id"])
+		assert.Equal(t, true, resources[0].(map[string]any)["active"])
+	})
+
+	t.Run("should page users", func(t *testing.T) {
+		env := setupTestEnv(t)
+		res, output := env.send(t, http.MethodGet, "/api/scim/v2/Users?startIndex=2&count=5", "", serviceAccount())
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.EqualValues(t, 2, output["totalResults"])
+		assert.EqualValues(t, 2, output["startIndex"])
+		assert.EqualValues(t, 1, output["itemsPerPage"])
+	})
+
+	t.Run("should reject unsupported filters", func(t *testing.T) {
+		env := setupTestEnv(t)
+		res, output := env.send(t, http.MethodGet, `/api/scim/v2/Users?filter=userName+gt+%22a%22`, "", serviceAccount())
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.Equal(t, scimTypeInvalidFilter, output["scimType"])
+	})
+
+	t.Run("should disable the user and revoke its sessions", func(t *testing.T) {
+		env := setupTestEnv(t)
+		var disabled *user.DisableUserCommand
+		env.userService.DisableFn = func(ctx context.Context, cmd *user.DisableUserCommand) error {
+			disabled = cmd
+			return nil
+		}
+		var revoked int64
+		env.tokens.RevokeAllUserTokensProvider = func(ctx context.Context, userID int64) error {
+			revoked = userID
+			return nil
+		}
+
+		body := `{"schemas":["` + SchemaPatchOp + `"],"Operations":[{"op":"Replace","path":"active","value":"False"}]}`
+		res, _ := env.send(t, http.MethodPatch, "/api/scim/v2/Users/1", body, serviceAccount())
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NotNil(t, disabled)
+		assert.Equal(t, user.DisableUserCommand{UserID: 1, IsDisabled: true}, *disabled)
+		assert.EqualValues(t, 1, revoked)
+	})
+
+	t.Run("should refuse to change a user of another organization", func(t *testing.T) {
+		env := setupTestEnv(t)
+		env.orgService.ExpectedUserOrgDTO = []*org.UserOrgDTO{{OrgID: 1, Name: "Main Org."}, {OrgID: 2, Name: "Other Org."}}
+		updated := false
+		env.userService.UpdateFn = func(ctx context.Context, cmd *user.UpdateUserCommand) error {
+			updated = true
+			return nil
+		}
+		env.userService.DisableFn = func(ctx context.Context, cmd *user.DisableUserCommand) error {
+			t.Fatal("the user must not be disabled")
+			return nil
+		}
+
+		res, _ := env.send(t, http.MethodPut, "/api/scim/v2/Users/1", `{"userName":"jdoe","emails":[{"value":"attacker@example.org"}]}`, serviceAccount())
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+		assert.False(t, updated)
+
+		body := `{"schemas":["` + SchemaPatchOp + `"],"Operations":[{"op":"replace","path":"active","value":false}]}`
+		res, _ = env.send(t, http.MethodPatch, "/api/scim/v2/Users/1", body, serviceAccount())
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+	})
+
+	t.Run("should refuse to disable a Grafana admin", func(t *testing.T) {
+		env := setupTestEnv(t)
+		env.userService.ExpectedUser = &user.User{ID: 1, Login: "jdoe", Email: "jdoe@example.org", IsAdmin: true}
+		env.userService.DisableFn = func(ctx context.Context, cmd *user.DisableUserCommand) error {
+			t.Fatal("the user must not be disabled")
+			return nil
+		}
+
+		body := `{"schemas":["` + SchemaPatchOp + `"],"Operations":[{"op":"replace","path":"active","value":false}]}`
+		res, _ := env.send(t, http.MethodPatch, "/api/scim/v2/Users/1", body, serviceAccount())
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+	})
+
+	t.Run("should disable a user of another organization with the global permission", func(t *testing.T) {
+		env := setupTestEnv(t)
+		env.orgService.ExpectedUserOrgDTO = []*org.UserOrgDTO{{OrgID: 1, Name: "Main Org."}, {OrgID: 2, Name: "Other Org."}}
+		disabled := false
+		env.userService.DisableFn = func(ctx context.Context, cmd *user.DisableUserCommand) error {
+			disabled = true
+			return nil
+		}
+
+		usr := serviceAccount()
+		usr.Permissions[1][accesscontrol.ActionUsersDisable] = []string{accesscontrol.ScopeGlobalUsersAll}
+		body := `{"schemas":["` + SchemaPatchOp + `"],"Operations":[{"op":"replace","path":"active","value":false}]}`
+		res, _ := env.send(t, http.MethodPatch, "/api/scim/v2/Users/1", body, usr)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.True(t, disabled)
+	})
+
+	t.Run("should return a conflict for an existing user", func(t *testing.T) {
+		env := setupTestEnv(t)
+		env.userService.CreateFn = func(ctx context.Context, cmd *user.CreateUserCommand) (*user.User, error) {
+			return nil, user.ErrUserAlreadyExists
+		}
+		res, output := env.send(t, http.MethodPost, "/api/scim/v2/Users", `{"userName":"jdoe"}`, serviceAccount())
+		assert.Equal(t, http.StatusConflict, res.StatusCode)
+		assert.Equal(t, scimTypeUniqueness, output["scimType"])
+	})
+}
+
+func TestSCIMService_Groups(t *testing.T) {
+	t.Run("should sync the members of the team", func(t *testing.T) {
+		env := setupTestEnv(t)
+		env.permissions.On("SetUserPermission", mock.Anything, int64(1), accesscontrol.User{ID: 2}, "3", teamMemberPermission).
+			Return(&accesscontrol.ResourcePermission{}, nil).Once()
+		env.permissions.On("SetUserPermission", mock.Anything, int64(1), accesscontrol.User{ID: 1}, "3", "").
+			Return(&accesscontrol.ResourcePermission{}, nil).Once()
+
+		body := `{"schemas":["` + SchemaPatchOp + `"],"Operations":[
+			{"op":"add","path":"members","value":[{"value":"2"}]},
+			{"op":"remove","path":"members[value eq \"1\"]"}
+		]}`
+		res, output := env.send(t, http.MethodPatch, "/api/scim/v2/Groups/3", body, serviceAccount())
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "sre", output["displayName"])
+		env.permissions.AssertExpectations(t)
+	})
+
+	t.Run("should exclude the members", func(t *testing.T) {
+		env := setupTestEnv(t)
+		res, output := env.send(t, http.MethodGet, "/api/scim/v2/Groups/3?excludedAttributes=members", "", serviceAccount())
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.NotContains(t, output, "members")
+	})
+
+	t.Run("should return not found for an unknown group", func(t *testing.T) {
+		env := setupTestEnv(t)
+		res, output := env.send(t, http.MethodGet, "/api/scim/v2/Groups/abc", "", serviceAccount())
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+		assert.Equal(t, "404", output["status"])
+	})
+}
+
+func TestApplyUserPatch(t *testing.T) {
+	active := true
+	u := &User{UserName: "jdoe", DisplayName: "John Doe", Name: &Name{Formatted: "John Doe"}, Active: &active}
+
+	err := applyUserPatch(u, []PatchOperation{
+		{Op: "replace", Value: json.RawMessage(`{"name.givenName":"Johnny","name.familyName":"Doe","id":"1"}`)},
+		{Op: "replace", Path: `emails[type eq "work"].value`, Value: json.RawMessage(`"johnny@example.org"`)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Johnny Doe", u.fullName())
+	assert.Equal(t, "johnny@example.org", u.email())
+	assert.True(t, u.active())
+
+	err = applyUserPatch(u, []PatchOperation{{Op: "remove", Path: "userName"}})
+	var scimErr *Error
+	require.ErrorAs(t, err, &scimErr)
+	assert.Equal(t, scimTypeMutability, scimErr.ScimType)
+}
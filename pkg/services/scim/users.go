@@ -0,0 +1,489 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/login"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+var userFilterAttrs = []string{"id", "externalId", "userName", "displayName", "name.formatted", "emails.value", "active"}
+
+func (s *SCIMService) listUsersHandler(c *contextmodel.ReqContext) response.Response {
+	ctx := c.Req.Context()
+	f, err := parseFilter(c.Query("filter"), userFilterAttrs)
+	if err != nil {
+		return s.errorResponse(c, err)
+	}
+
+	orgUsers, err := s.searchUsers(ctx, c.SignedInUser, f)
+	if err != nil {
+		return s.errorResponse(c, err)
+	}
+
+	startIndex, count := pagination(c)
+	from, to := pageBounds(len(orgUsers), startIndex, count)
+	resources := make([]any, 0, to-from)
+	for _, orgUser := range orgUsers[from:to] {
+		u, err := s.toUser(ctx, orgUser)
+		if err != nil {
+			return s.errorResponse(c, err)
+		}
+		resources = append(resources, u)
+	}
+	return scimResponse(http.StatusOK, listResponse(resources, len(orgUsers), startIndex))
+}
+
+func (s *SCIMService) getUserHandler(c *contextmodel.ReqContext) response.Response {
+	orgUser, err := s.getOrgUser(c.Req.Context(), c.SignedInUser, web.Params(c.Req)[":id"])
+	if err != nil {
+		return s.errorResponse(c, err)
+	}
+	return s.userResponse(c, http.StatusOK, orgUser.UserID)
+}
+
+func (s *SCIMService) createUserHandler(c *contextmodel.ReqContext) response.Response {
+	ctx := c.Req.Context()
+	var in User
+	if err := bind(c.Req, &in); err != nil {
+		return s.errorResponse(c, err)
+	}
+	if in.UserName == "" {
+		return s.errorResponse(c, errBadRequest(scimTypeInvalidValue, "userName is required"))
+	}
+	if in.ExternalID != "" {
+		if err := s.checkExternalID(ctx, 0, in.ExternalID); err != nil {
+			return s.errorResponse(c, err)
+		}
+	}
+
+	// the user is only added to the organization of the service account
+	usr, err := s.userService.Create(ctx, &user.CreateUserCommand{
+		Login:        in.UserName,
+		Email:        in.email(),
+		Name:         in.fullName(),
+		IsDisabled:   !in.active(),
+		SkipOrgSetup: true,
+	})
+	if err != nil {
+		if errors.Is(err, user.ErrUserAlreadyExists) {
+			return s.errorResponse(c, errConflict("user %s already exists", in.UserName))
+		}
+		return s.errorResponse(c, err)
+	}
+
+	orgID := c.SignedInUser.GetOrgID()
+	if err := s.orgService.AddOrgUser(ctx, &org.AddOrgUserCommand{OrgID: orgID, UserID: usr.ID, Role: s.defaultRole()}); err != nil {
+		return s.errorResponse(c, err)
+	}
+	if in.ExternalID != "" {
+		if err := s.setExternalID(ctx, usr.ID, in.ExternalID); err != nil {
+			return s.errorResponse(c, err)
+		}
+	}
+
+	s.log.FromContext(ctx).Info("User provisioned", "userId", usr.ID, "orgId", orgID, "serviceAccountId", c.SignedInUser.UserID)
+	return s.userResponse(c, http.StatusCreated, usr.ID)
+}
+
+func (s *SCIMService) replaceUserHandler(c *contextmodel.ReqContext) response.Response {
+	ctx := c.Req.Context()
+	orgUser, err := s.getOrgUser(ctx, c.SignedInUser, web.Params(c.Req)[":id"])
+	if err != nil {
+		return s.errorResponse(c, err)
+	}
+
+	var in User
+	if err := bind(c.Req, &in); err != nil {
+		return s.errorResponse(c, err)
+	}
+	if err := s.updateUser(ctx, c.SignedInUser, orgUser, &in); err != nil {
+		return s.errorResponse(c, err)
+	}
+	return s.userResponse(c, http.StatusOK, orgUser.UserID)
+}
+
+func (s *SCIMService) patchUserHandler(c *contextmodel.ReqContext) response.Response {
+	ctx := c.Req.Context()
+	orgUser, err := s.getOrgUser(ctx, c.SignedInUser, web.Params(c.Req)[":id"])
+	if err != nil {
+		return s.errorResponse(c, err)
+	}
+
+	var req PatchRequest
+	if err := bind(c.Req, &req); err != nil {
+		return s.errorResponse(c, err)
+	}
+
+	u, err := s.toUser(ctx, orgUser)
+	if err != nil {
+		return s.errorResponse(c, err)
+	}
+	if err := applyUserPatch(u, req.Operations); err != nil {
+		return s.errorResponse(c, err)
+	}
+	if err := s.updateUser(ctx, c.SignedInUser, orgUser, u); err != nil {
+		return s.errorResponse(c, err)
+	}
+	return s.userResponse(c, http.StatusOK, orgUser.UserID)
+}
+
+func (s *SCIMService) deleteUserHandler(c *contextmodel.ReqContext) response.Response {
+	ctx := c.Req.Context()
+	orgUser, err := s.getOrgUser(ctx, c.SignedInUser, web.Params(c.Req)[":id"])
+	if err != nil {
+		return s.errorResponse(c, err)
+	}
+
+	// the sessions are revoked first, the user can be a member of other organizations
+	if err := s.userTokenService.RevokeAllUserTokens(ctx, orgUser.UserID); err != nil {
+		return s.errorResponse(c, err)
+	}
+
+	cmd := &org.RemoveOrgUserCommand{UserID: orgUser.UserID, OrgID: orgUser.OrgID, ShouldDeleteOrphanedUser: true}
+	if err := s.orgService.RemoveOrgUser(ctx, cmd); err != nil {
+		if errors.Is(err, org.ErrLastOrgAdmin) {
+			return s.errorResponse(c, errBadRequest(scimTypeMutability, "cannot remove the last organization admin"))
+		}
+		return s.errorResponse(c, err)
+	}
+
+	permissionsOrgID := orgUser.OrgID
+	if cmd.UserWasDeleted {
+		permissionsOrgID = accesscontrol.GlobalOrgID
+	}
+	if err := s.accessControlService.DeleteUserPermissions(ctx, permissionsOrgID, orgUser.UserID); err != nil {
+		s.log.FromContext(ctx).Warn("Failed to delete permissions for user", "userId", orgUser.UserID, "orgId", permissionsOrgID, "error", err)
+	}
+
+	s.log.FromContext(ctx).Info("User deprovisioned", "userId", orgUser.UserID, "orgId", orgUser.OrgID, "deleted", cmd.UserWasDeleted, "serviceAccountId", c.SignedInUser.UserID)
+	return response.Empty(http.StatusNoContent)
+}
+
+// searchUsers returns the users of the organization matching the filter
+func (s *SCIMService) searchUsers(ctx context.Context, signedInUser *user.SignedInUser, f filter) ([]*org.OrgUserDTO, error) {
+	query := &org.SearchOrgUsersQuery{OrgID: signedInUser.GetOrgID(), User: signedInUser}
+
+	// an equality condition narrows down the users to fetch, the filter is applied to the result
+	if id, ok := f.equal("id"); ok {
+		userID, valid := parseID(id)
+		if !valid {
+			return nil, nil
+		}
+		query.UserID = userID
+	} else if externalID, ok := f.equal("externalId"); ok {
+		authInfo, err := s.authInfoService.GetAuthInfo(ctx, &login.GetAuthInfoQuery{AuthModule: authModule, AuthId: externalID})
+		if err != nil {
+			if errors.Is(err, user.ErrUserNotFound) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		query.UserID = authInfo.UserId
+	} else {
+		for _, attr := range []string{"userName", "emails.value", "displayName", "name.formatted"} {
+			if value, ok := f.equal(attr); ok {
+				query.Query = value
+				break
+			}
+		}
+	}
+
+	result, err := s.orgService.SearchOrgUsers(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if len(f) == 0 {
+		return result.OrgUsers, nil
+	}
+
+	matches := make([]*org.OrgUserDTO, 0, len(result.OrgUsers))
+	for _, orgUser := range result.OrgUsers {
+		var lookupErr error
+		matched := f.match(func(attr string) []string {
+			switch attr {
+			case "id":
+				return []string{strconv.FormatInt(orgUser.UserID, 10)}
+			case "externalId":
+				externalID, err := s.externalID(ctx, orgUser.UserID)
+				if err != nil {
+					lookupErr = err
+				}
+				return []string{externalID}
+			case "userName":
+				return []string{orgUser.Login}
+			case "displayName", "name.formatted":
+				return []string{orgUser.Name}
+			case "emails.value":
+				return []string{orgUser.Email}
+			case "active":
+				return []string{strconv.FormatBool(!orgUser.IsDisabled)}
+			}
+			return nil
+		})
+		if lookupErr != nil {
+			return nil, lookupErr
+		}
+		if matched {
+			matches = append(matches, orgUser)
+		}
+	}
+	return matches, nil
+}
+
+// getOrgUser returns a user of the organization of the service account
+func (s *SCIMService) getOrgUser(ctx context.Context, signedInUser *user.SignedInUser, id string) (*org.OrgUserDTO, error) {
+	userID, ok := parseID(id)
+	if !ok {
+		return nil, errNotFound("user %s not found", id)
+	}
+	result, err := s.orgService.SearchOrgUsers(ctx, &org.SearchOrgUsersQuery{
+		OrgID:  signedInUser.GetOrgID(),
+		UserID: userID,
+		User:   signedInUser,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(result.OrgUsers) == 0 {
+		return nil, errNotFound("user %s not found", id)
+	}
+	return result.OrgUsers[0], nil
+}
+
+func (s *SCIMService) userResponse(c *contextmodel.ReqContext, status int, userID int64) response.Response {
+	ctx := c.Req.Context()
+	orgUser, err := s.getOrgUser(ctx, c.SignedInUser, strconv.FormatInt(userID, 10))
+	if err != nil {
+		return s.errorResponse(c, err)
+	}
+	u, err := s.toUser(ctx, orgUser)
+	if err != nil {
+		return s.errorResponse(c, err)
+	}
+	return scimResponse(status, u).SetHeader("Location", u.Meta.Location)
+}
+
+func (s *SCIMService) toUser(ctx context.Context, orgUser *org.OrgUserDTO) (*User, error) {
+	externalID, err := s.externalID(ctx, orgUser.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	id := strconv.FormatInt(orgUser.UserID, 10)
+	active := !orgUser.IsDisabled
+	u := &User{
+		Schemas:     []string{SchemaUser},
+		ID:          id,
+		ExternalID:  externalID,
+		UserName:    orgUser.Login,
+		DisplayName: orgUser.Name,
+		Active:      &active,
+		Meta: &Meta{
+			ResourceType: resourceTypeUser,
+			Created:      &orgUser.Created,
+			LastModified: &orgUser.Updated,
+			Location:     s.location("Users", id),
+		},
+	}
+	if orgUser.Name != "" {
+		u.Name = &Name{Formatted: orgUser.Name}
+	}
+	if orgUser.Email != "" {
+		u.Emails = []Email{{Value: orgUser.Email, Type: "work", Primary: true}}
+	}
+	return u, nil
+}
+
+// updateUser saves the attributes of the SCIM user. Disabling the user revokes its sessions.
+func (s *SCIMService) updateUser(ctx context.Context, signedInUser *user.SignedInUser, orgUser *org.OrgUserDTO, in *User) error {
+	if in.UserName == "" {
+		return errBadRequest(scimTypeInvalidValue, "userName is required")
+	}
+
+	email := in.email()
+	if email == "" {
+		email = orgUser.Email
+	}
+	name := in.fullName()
+	if in.UserName != orgUser.Login || email != orgUser.Email || name != orgUser.Name {
+		if err := s.checkGlobalUser(ctx, signedInUser, orgUser.UserID, accesscontrol.ActionUsersWrite); err != nil {
+			return err
+		}
+		if err := s.checkUnique(ctx, orgUser.UserID, in.UserName, email); err != nil {
+			return err
+		}
+		if err := s.userService.Update(ctx, &user.UpdateUserCommand{
+			UserID: orgUser.UserID,
+			Login:  in.UserName,
+			Email:  email,
+			Name:   name,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if active := in.active(); active == orgUser.IsDisabled {
+		if err := s.checkGlobalUser(ctx, signedInUser, orgUser.UserID, accesscontrol.ActionUsersDisable); err != nil {
+			return err
+		}
+		if err := s.setActive(ctx, orgUser.UserID, active); err != nil {
+			return err
+		}
+	}
+
+	if in.ExternalID != "" {
+		return s.setExternalID(ctx, orgUser.UserID, in.ExternalID)
+	}
+	return nil
+}
+
+// checkGlobalUser returns a forbidden error if the user is a Grafana admin or a member of another organization,
+// the login, the email, the name and the active flag are shared by all the organizations of the user.
+// Service accounts with the global permission can still change them.
+func (s *SCIMService) checkGlobalUser(ctx context.Context, signedInUser *user.SignedInUser, userID int64, action string) error {
+	hasAccess, err := s.accessControl.Evaluate(ctx, signedInUser, accesscontrol.EvalPermission(action, accesscontrol.ScopeGlobalUsersAll))
+	if err != nil {
+		return err
+	}
+	if hasAccess {
+		return nil
+	}
+
+	usr, err := s.userService.GetByID(ctx, &user.GetUserByIDQuery{ID: userID})
+	if err != nil {
+		return err
+	}
+	if usr.IsAdmin {
+		return errForbidden("user %d is a Grafana admin, %s is required to change it", userID, action)
+	}
+
+	orgs, err := s.orgService.GetUserOrgList(ctx, &org.GetUserOrgListQuery{UserID: userID})
+	if err != nil {
+		return err
+	}
+	for _, o := range orgs {
+		if o.OrgID != signedInUser.GetOrgID() {
+			return errForbidden("user %d is a member of another organization, %s is required to change it", userID, action)
+		}
+	}
+	return nil
+}
+
+// checkUnique returns a conflict if the login or the email belongs to another user
+func (s *SCIMService) checkUnique(ctx context.Context, userID int64, login, email string) error {
+	for _, loginOrEmail := range []string{login, email} {
+		usr, err := s.userService.GetByLogin(ctx, &user.GetUserByLoginQuery{LoginOrEmail: loginOrEmail})
+		if err != nil {
+			if errors.Is(err, user.ErrUserNotFound) {
+				continue
+			}
+			return err
+		}
+		if usr.ID != userID {
+			return errConflict("user %s already exists", loginOrEmail)
+		}
+	}
+	return nil
+}
+
+func (s *SCIMService) setActive(ctx context.Context, userID int64, active bool) error {
+	if err := s.userService.Disable(ctx, &user.DisableUserCommand{UserID: userID, IsDisabled: !active}); err != nil {
+		return err
+	}
+	if active {
+		return nil
+	}
+	// identity providers disable offboarded users, their sessions must not outlive the account
+	if err := s.userTokenService.RevokeAllUserTokens(ctx, userID); err != nil {
+		return err
+	}
+	s.log.FromContext(ctx).Info("User disabled and sessions revoked", "userId", userID)
+	return nil
+}
+
+// externalID returns the id of the user in the identity provider, or an empty string if it was never set
+func (s *SCIMService) externalID(ctx context.Context, userID int64) (string, error) {
+	authInfo, err := s.authInfoService.GetAuthInfo(ctx, &login.GetAuthInfoQuery{UserId: userID, AuthModule: authModule})
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	return authInfo.AuthId, nil
+}
+
+// checkExternalID returns a conflict if the externalId belongs to another user
+func (s *SCIMService) checkExternalID(ctx context.Context, userID int64, externalID string) error {
+	authInfo, err := s.authInfoService.GetAuthInfo(ctx, &login.GetAuthInfoQuery{AuthModule: authModule, AuthId: externalID})
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+	if authInfo.UserId != userID {
+		return errConflict("externalId %s is already used by another user", externalID)
+	}
+	return nil
+}
+
+func (s *SCIMService) setExternalID(ctx context.Context, userID int64, externalID string) error {
+	current, err := s.externalID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if current == externalID {
+		return nil
+	}
+	if err := s.checkExternalID(ctx, userID, externalID); err != nil {
+		return err
+	}
+	if current == "" {
+		return s.authInfoService.SetAuthInfo(ctx, &login.SetAuthInfoCommand{UserId: userID, AuthModule: authModule, AuthId: externalID})
+	}
+	return s.authInfoService.UpdateAuthInfo(ctx, &login.UpdateAuthInfoCommand{UserId: userID, AuthModule: authModule, AuthId: externalID})
+}
+
+// email returns the primary email of the user, or the first one if none is primary
+func (u *User) email() string {
+	for _, e := range u.Emails {
+		if e.Primary {
+			return e.Value
+		}
+	}
+	if len(u.Emails) > 0 {
+		return u.Emails[0].Value
+	}
+	return ""
+}
+
+// fullName returns the name of the Grafana user, from the most to the least specific attribute
+func (u *User) fullName() string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	if u.Name != nil {
+		if u.Name.Formatted != "" {
+			return u.Name.Formatted
+		}
+		if name := strings.TrimSpace(u.Name.GivenName + " " + u.Name.FamilyName); name != "" {
+			return name
+		}
+	}
+	return u.UserName
+}
+
+func (u *User) active() bool {
+	return u.Active == nil || *u.Active
+}
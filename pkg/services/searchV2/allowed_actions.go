@@ -217,7 +217,8 @@ func getEntityReferences(resp *backend.DataResponse) ([]entityReferences, error)
 			return nil, errors.New("invalid value in uid field")
 		}
 
-		if entityKind(kind) != entityKindDashboard {
+		kindWithDatasources := entityKind(kind) == entityKindDashboard || entityKind(kind) == entityKindAlertRule || entityKind(kind) == entityKindLibraryPanel
+		if !kindWithDatasources {
 			out = append(out, entityReferences{
 				entityKind: entityKind(kind),
 				uid:        uid,
@@ -248,7 +249,7 @@ func getEntityReferences(resp *backend.DataResponse) ([]entityReferences, error)
 			}
 		}
 
-		out = append(out, entityReferences{entityKind: entityKindDashboard, uid: uid, dsUids: uids})
+		out = append(out, entityReferences{entityKind: entityKind(kind), uid: uid, dsUids: uids})
 	}
 
 	return out, nil
@@ -37,7 +37,7 @@ const (
 	DocumentFieldUpdatedAt   = "updated_at"
 )
 
-func initOrgIndex(dashboards []dashboard, logger log.Logger, extendDoc ExtendDashboardFunc) (*orgIndex, error) {
+func initOrgIndex(dashboards []dashboard, entities []searchEntity, logger log.Logger, extendDoc ExtendDashboardFunc) (*orgIndex, error) {
 	dashboardWriter, err := bluge.OpenWriter(bluge.InMemoryOnlyConfig())
 	if err != nil {
 		return nil, fmt.Errorf("error opening writer: %v", err)
@@ -120,6 +120,14 @@ func initOrgIndex(dashboards []dashboard, logger log.Logger, extendDoc ExtendDas
 		}
 	}
 
+	// Then alert rules, library panels and datasources.
+	for _, e := range entities {
+		batch.Insert(getEntityDoc(e))
+		if err := flushIfRequired(false); err != nil {
+			return nil, err
+		}
+	}
+
 	// Flush docs in batch with force as we are in the end.
 	if err := flushIfRequired(true); err != nil {
 		return nil, err
@@ -581,7 +589,7 @@ func doSearchQuery(
 		}
 
 		fKind.Append(kind)
-		fUID.Append(uid)
+		fUID.Append(entityUIDFromDocumentID(entityKind(kind), uid))
 		fPType.Append(ptype)
 		fName.Append(name)
 		fURL.Append(url)
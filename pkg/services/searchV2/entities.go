@@ -0,0 +1,332 @@
+package searchV2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blugelabs/bluge"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/folder"
+	"github.com/grafana/grafana/pkg/services/libraryelements/model"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/store"
+	"github.com/grafana/grafana/pkg/services/store/entity"
+	kdash "github.com/grafana/grafana/pkg/services/store/kind/dashboard"
+)
+
+// expressionDatasourceUID is the datasource UID used by server side expressions in alert rule queries.
+const expressionDatasourceUID = "__expr__"
+
+// indexedEntityKinds are the kinds indexed next to dashboards, folders and panels.
+var indexedEntityKinds = []entityKind{entityKindAlertRule, entityKindLibraryPanel, entityKindDatasource}
+
+// entityKindByEntityType maps entity event types to the kind of the document they update.
+var entityKindByEntityType = map[store.EntityType]entityKind{
+	store.EntityTypeAlertRule:      entityKindAlertRule,
+	store.EntityTypeLibraryElement: entityKindLibraryPanel,
+	store.EntityTypeDatasource:     entityKindDatasource,
+}
+
+type entityLoader interface {
+	// LoadEntities returns slice of entities of the given kind. If uid is empty – then
+	// implementation must return all entities of that kind in the organization. If uid
+	// is not empty – then only return the entity with specified UID or empty slice if
+	// not found (this is required to apply partial update).
+	LoadEntities(ctx context.Context, orgID int64, kind entityKind, uid string) ([]searchEntity, error)
+}
+
+// searchEntity is an alert rule, library panel or datasource as stored in the index.
+type searchEntity struct {
+	kind        entityKind
+	uid         string
+	name        string
+	description string
+	url         string
+	folderUID   string // empty for entities which do not live in a folder
+	labels      map[string]string
+	panelType   string
+	dsUIDs      []string
+	dsTypes     []string
+	created     time.Time
+	updated     time.Time
+}
+
+// entityDocumentID returns the document identifier for standalone entities. Their UIDs are only unique
+// per kind, so the kind is used as a prefix to avoid collisions with dashboards and folders.
+func entityDocumentID(kind entityKind, uid string) string {
+	return string(kind) + "/" + uid
+}
+
+// entityUIDFromDocumentID is the inverse of entityDocumentID. Identifiers of documents
+// which are not standalone entities are returned as is.
+func entityUIDFromDocumentID(kind entityKind, id string) string {
+	if !kind.isStandaloneEntity() {
+		return id
+	}
+	return strings.TrimPrefix(id, string(kind)+"/")
+}
+
+func getEntityDoc(e searchEntity) *bluge.Document {
+	doc := newSearchDocument(entityDocumentID(e.kind, e.uid), e.name, e.description, e.url).
+		AddField(bluge.NewKeywordField(documentFieldKind, string(e.kind)).Aggregatable().StoreValue()).
+		AddField(bluge.NewDateTimeField(DocumentFieldCreatedAt, e.created).Sortable().StoreValue()).
+		AddField(bluge.NewDateTimeField(DocumentFieldUpdatedAt, e.updated).Sortable().StoreValue())
+
+	if e.folderUID != "" {
+		doc.AddField(bluge.NewKeywordField(documentFieldLocation, e.folderUID).Aggregatable().StoreValue())
+	}
+
+	if e.panelType != "" {
+		doc.AddField(bluge.NewKeywordField(documentFieldPanelType, e.panelType).Aggregatable().StoreValue())
+	}
+
+	// unlike dashboards, labels of alert rules carry meaningful values so both are indexed
+	keys := make([]string, 0, len(e.labels))
+	for k := range e.labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		doc.AddField(bluge.NewKeywordField(documentFieldTag, k+"="+e.labels[k]).
+			StoreValue().
+			Aggregatable().
+			SearchTermPositions())
+	}
+
+	for _, dsType := range e.dsTypes {
+		doc.AddField(bluge.NewKeywordField(documentFieldDSType, dsType).
+			StoreValue().
+			Aggregatable().
+			SearchTermPositions())
+	}
+	for _, dsUID := range e.dsUIDs {
+		doc.AddField(bluge.NewKeywordField(documentFieldDSUID, dsUID).
+			StoreValue().
+			Aggregatable().
+			SearchTermPositions())
+	}
+
+	return doc
+}
+
+type sqlEntityLoader struct {
+	sql    db.DB
+	logger log.Logger
+	tracer tracing.Tracer
+}
+
+func newSQLEntityLoader(sql db.DB, tracer tracing.Tracer) *sqlEntityLoader {
+	return &sqlEntityLoader{sql: sql, logger: log.New("sqlEntityLoader"), tracer: tracer}
+}
+
+func (l sqlEntityLoader) LoadEntities(ctx context.Context, orgID int64, kind entityKind, uid string) ([]searchEntity, error) {
+	ctx, span := l.tracer.Start(ctx, "sqlEntityLoader LoadEntities")
+	span.SetAttributes("orgID", orgID, attribute.Key("orgID").Int64(orgID))
+	span.SetAttributes("kind", kind, attribute.Key("kind").String(string(kind)))
+	defer span.End()
+
+	switch kind {
+	case entityKindAlertRule:
+		return l.loadAlertRules(ctx, orgID, uid)
+	case entityKindLibraryPanel:
+		return l.loadLibraryPanels(ctx, orgID, uid)
+	case entityKindDatasource:
+		return l.loadDatasources(ctx, orgID, uid)
+	default:
+		return nil, fmt.Errorf("unsupported entity kind: %s", kind)
+	}
+}
+
+type alertRuleCreatedQueryResult struct {
+	RuleUID string `xorm:"rule_uid"`
+	Created time.Time
+}
+
+func (l sqlEntityLoader) loadAlertRules(ctx context.Context, orgID int64, uid string) ([]searchEntity, error) {
+	rows := make([]*ngmodels.AlertRule, 0)
+	created := map[string]time.Time{}
+	err := l.sql.WithDbSession(ctx, func(sess *db.Session) error {
+		sess.Table("alert_rule").Where("org_id = ?", orgID)
+		if uid != "" {
+			sess.Where("uid = ?", uid)
+		}
+		if err := sess.Find(&rows); err != nil {
+			return err
+		}
+
+		// the alert rules have no creation time, it is the time of their first version
+		versions := make([]*alertRuleCreatedQueryResult, 0)
+		sess.Table("alert_rule_version").Select("rule_uid, MIN(created) AS created").Where("rule_org_id = ?", orgID)
+		if uid != "" {
+			sess.Where("rule_uid = ?", uid)
+		}
+		if err := sess.GroupBy("rule_uid").Find(&versions); err != nil {
+			return err
+		}
+		for _, v := range versions {
+			created[v.RuleUID] = v.Created
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entities := make([]searchEntity, 0, len(rows))
+	for _, rule := range rows {
+		var dsUIDs []string
+		for _, q := range rule.Data {
+			if q.DatasourceUID == "" || q.DatasourceUID == expressionDatasourceUID || stringInSlice(q.DatasourceUID, dsUIDs) {
+				continue
+			}
+			dsUIDs = append(dsUIDs, q.DatasourceUID)
+		}
+		entities = append(entities, searchEntity{
+			kind:      entityKindAlertRule,
+			uid:       rule.UID,
+			name:      rule.Title,
+			url:       fmt.Sprintf("/alerting/grafana/%s/view", rule.UID),
+			folderUID: rule.NamespaceUID,
+			labels:    rule.Labels,
+			dsUIDs:    dsUIDs,
+			created:   created[rule.UID],
+			updated:   rule.Updated,
+		})
+	}
+	return entities, nil
+}
+
+type libraryPanelQueryResult struct {
+	UID         string `xorm:"uid"`
+	Name        string `xorm:"name"`
+	Description string `xorm:"description"`
+	Type        string `xorm:"type"`
+	Model       []byte `xorm:"model"`
+	FolderUID   string `xorm:"folder_uid"`
+	Created     time.Time
+	Updated     time.Time
+}
+
+func (l sqlEntityLoader) loadLibraryPanels(ctx context.Context, orgID int64, uid string) ([]searchEntity, error) {
+	rows := make([]*libraryPanelQueryResult, 0)
+	err := l.sql.WithDbSession(ctx, func(sess *db.Session) error {
+		rawSQL := "SELECT le.uid, le.name, le.description, le.type, le.model, le.created, le.updated, d.uid AS folder_uid" +
+			" FROM library_element AS le LEFT JOIN dashboard AS d ON le.folder_id = d.id" +
+			" WHERE le.org_id = ? AND le.kind = ?"
+		args := []any{orgID, int64(model.PanelElement)}
+		if uid != "" {
+			rawSQL += " AND le.uid = ?"
+			args = append(args, uid)
+		}
+		return sess.SQL(rawSQL, args...).Find(&rows)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return []searchEntity{}, nil
+	}
+
+	lookup, err := kdash.LoadDatasourceLookup(ctx, orgID, l.sql)
+	if err != nil {
+		return nil, err
+	}
+	reader := kdash.NewStaticDashboardSummaryBuilder(lookup, false)
+
+	entities := make([]searchEntity, 0, len(rows))
+	for _, row := range rows {
+		folderUID := row.FolderUID
+		if folderUID == "" {
+			folderUID = folder.GeneralFolderUID
+		}
+
+		e := searchEntity{
+			kind:        entityKindLibraryPanel,
+			uid:         row.UID,
+			name:        row.Name,
+			description: row.Description,
+			url:         "/library-panels",
+			folderUID:   folderUID,
+			panelType:   row.Type,
+			created:     row.Created,
+			updated:     row.Updated,
+		}
+
+		// Library panels are summarized as a single panel dashboard so that datasource
+		// references are resolved exactly the same way as for panels inside dashboards.
+		body, err := json.Marshal(map[string]any{
+			"title":  row.Name,
+			"panels": []json.RawMessage{row.Model},
+		})
+		if err == nil {
+			var summary *entity.EntitySummary
+			summary, _, err = reader(ctx, row.UID, body)
+			if summary != nil {
+				for _, ref := range summary.References {
+					if ref.Family != entity.StandardKindDataSource {
+						continue
+					}
+					if ref.Identifier != "" {
+						e.dsUIDs = append(e.dsUIDs, ref.Identifier)
+					}
+					if ref.Type != "" {
+						e.dsTypes = append(e.dsTypes, ref.Type)
+					}
+				}
+			}
+		}
+		if err != nil {
+			l.logger.Warn("Error indexing library panel model", "error", err, "uid", row.UID)
+			// But append info anyway, the name is still useful.
+		}
+		entities = append(entities, e)
+	}
+	return entities, nil
+}
+
+type datasourceQueryResult struct {
+	UID     string `xorm:"uid"`
+	Name    string `xorm:"name"`
+	Type    string `xorm:"type"`
+	Created time.Time
+	Updated time.Time
+}
+
+func (l sqlEntityLoader) loadDatasources(ctx context.Context, orgID int64, uid string) ([]searchEntity, error) {
+	rows := make([]*datasourceQueryResult, 0)
+	err := l.sql.WithDbSession(ctx, func(sess *db.Session) error {
+		sess.Table("data_source").Where("org_id = ?", orgID)
+		if uid != "" {
+			sess.Where("uid = ?", uid)
+		}
+		sess.Cols("uid", "name", "type", "created", "updated")
+		return sess.Find(&rows)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entities := make([]searchEntity, 0, len(rows))
+	for _, row := range rows {
+		entities = append(entities, searchEntity{
+			kind: entityKindDatasource,
+			uid:  row.UID,
+			name: row.Name,
+			url:  fmt.Sprintf("/datasources/edit/%s", row.UID),
+			// A datasource references itself, so filtering by datasource includes the datasource.
+			dsUIDs:  []string{row.UID},
+			dsTypes: []string{row.Type},
+			created: row.Created,
+			updated: row.Updated,
+		})
+	}
+	return entities, nil
+}
@@ -0,0 +1,57 @@
+package searchV2
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+func TestIntegrationLoadAlertRules(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	sqlStore := db.InitTestDB(t)
+
+	created := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	rule := ngmodels.AlertRuleGen(ngmodels.WithOrgID(1))()
+	rule.ID = 0
+	rule.Updated = created.Add(48 * time.Hour)
+	versioned := ngmodels.AlertRuleGen(ngmodels.WithOrgID(1))()
+	versioned.ID = 0
+	err := sqlStore.WithDbSession(context.Background(), func(sess *db.Session) error {
+		if _, err := sess.Table("alert_rule").Insert(rule, versioned); err != nil {
+			return err
+		}
+		for i, uid := range []string{versioned.UID, versioned.UID} {
+			if _, err := sess.Insert(&ngmodels.AlertRuleVersion{
+				RuleOrgID: 1,
+				RuleUID:   uid,
+				Version:   int64(i + 1),
+				Created:   created.Add(time.Duration(i) * time.Hour),
+				Data:      versioned.Data,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	entities, err := sqlEntityLoader{sql: sqlStore}.loadAlertRules(context.Background(), 1, "")
+	require.NoError(t, err)
+	require.Len(t, entities, 2)
+	for _, e := range entities {
+		switch e.uid {
+		case versioned.UID:
+			require.True(t, created.Equal(e.created), "the creation time is the time of the first version")
+		case rule.UID:
+			require.True(t, e.created.IsZero(), "the creation time is unknown without version")
+			require.True(t, rule.Updated.Equal(e.updated))
+		}
+	}
+}
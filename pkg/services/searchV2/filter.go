@@ -26,14 +26,22 @@ const (
 	entityKindFolder     entityKind = entity.StandardKindFolder
 	entityKindDatasource entityKind = entity.StandardKindDataSource
 	entityKindQuery      entityKind = entity.StandardKindQuery
+	entityKindAlertRule  entityKind = entity.StandardKindAlertRule
+	// entityKindLibraryPanel is used for library elements of the panel kind
+	entityKindLibraryPanel entityKind = entity.StandardKindLibraryPanel
 )
 
 func (r entityKind) IsValid() bool {
-	return r == entityKindPanel || r == entityKindDashboard || r == entityKindFolder
+	return r == entityKindPanel || r == entityKindDashboard || r == entityKindFolder || r.isStandaloneEntity()
 }
 
 func (r entityKind) supportsAuthzCheck() bool {
-	return r == entityKindPanel || r == entityKindDashboard || r == entityKindFolder
+	return r == entityKindPanel || r == entityKindDashboard || r == entityKindFolder || r.isStandaloneEntity()
+}
+
+// isStandaloneEntity is true for kinds which are indexed next to dashboards but are not stored in the dashboard table
+func (r entityKind) isStandaloneEntity() bool {
+	return r == entityKindAlertRule || r == entityKindLibraryPanel || r == entityKindDatasource
 }
 
 var (
@@ -98,6 +106,10 @@ func (q *PermissionFilter) canAccess(kind entityKind, id, location string) bool
 		decision := q.filter(entityKindDashboard, dashboardUid, folderUid)
 		q.logAccessDecision(decision, kind, id, "resourceFilter", "folderUid", folderUid, "dashboardUid", dashboardUid, "panelId", matches[panelIdFieldPanelIdSubmatchIndex])
 		return decision
+	case entityKindAlertRule, entityKindLibraryPanel, entityKindDatasource:
+		decision := q.filter(kind, entityUIDFromDocumentID(kind, id), location)
+		q.logAccessDecision(decision, kind, id, "resourceFilter", "location", location)
+		return decision
 	default:
 		q.logAccessDecision(false, kind, id, "reason", "unknownKind")
 		return false
@@ -90,6 +90,7 @@ func (i *orgIndex) readerForIndex(idxType indexType) (*bluge.Reader, func(), err
 type searchIndex struct {
 	mu                      sync.RWMutex
 	loader                  dashboardLoader
+	entityLoader            entityLoader
 	perOrgIndex             map[int64]*orgIndex
 	initializedOrgs         map[int64]bool
 	initialIndexingComplete bool
@@ -105,9 +106,10 @@ type searchIndex struct {
 	settings                setting.SearchSettings
 }
 
-func newSearchIndex(dashLoader dashboardLoader, evStore eventStore, extender DocumentExtender, folderIDs folderUIDLookup, tracer tracing.Tracer, features featuremgmt.FeatureToggles, settings setting.SearchSettings) *searchIndex {
+func newSearchIndex(dashLoader dashboardLoader, entLoader entityLoader, evStore eventStore, extender DocumentExtender, folderIDs folderUIDLookup, tracer tracing.Tracer, features featuremgmt.FeatureToggles, settings setting.SearchSettings) *searchIndex {
 	return &searchIndex{
 		loader:          dashLoader,
+		entityLoader:    entLoader,
 		eventStore:      evStore,
 		perOrgIndex:     map[int64]*orgIndex{},
 		initializedOrgs: map[int64]bool{},
@@ -487,13 +489,23 @@ func (i *searchIndex) buildOrgIndex(ctx context.Context, orgID int64) (int, erro
 	}
 	i.logger.Info("Finish loading org dashboards", "elapsed", orgSearchIndexLoadTime, "orgId", orgID)
 
+	var entities []searchEntity
+	for _, kind := range indexedEntityKinds {
+		loaded, err := i.entityLoader.LoadEntities(ctx, orgID, kind, "")
+		if err != nil {
+			return 0, fmt.Errorf("error loading %s entities: %w", kind, err)
+		}
+		entities = append(entities, loaded...)
+	}
+	orgSearchIndexLoadTime = time.Since(started)
+
 	dashboardExtender := i.extender.GetDashboardExtender(orgID)
 
 	_, initOrgIndexSpan := i.tracer.Start(ctx, "searchV2 buildOrgIndex init org index")
 	initOrgIndexSpan.SetAttributes("org_id", orgID, attribute.Key("org_id").Int64(orgID))
 	initOrgIndexSpan.SetAttributes("dashboardCount", len(dashboards), attribute.Key("dashboardCount").Int(len(dashboards)))
 
-	index, err := initOrgIndex(dashboards, i.logger, dashboardExtender)
+	index, err := initOrgIndex(dashboards, entities, i.logger, dashboardExtender)
 
 	initOrgIndexSpan.End()
 
@@ -508,7 +520,8 @@ func (i *searchIndex) buildOrgIndex(ctx context.Context, orgID int64) (int, erro
 			"orgSearchIndexLoadTime", orgSearchIndexLoadTime,
 			"orgSearchIndexBuildTime", orgSearchIndexBuildTime,
 			"orgSearchIndexTotalTime", orgSearchIndexTotalTime,
-			"orgSearchDashboardCount", len(dashboards))...)
+			"orgSearchDashboardCount", len(dashboards),
+			"orgSearchEntityCount", len(entities))...)
 
 	i.mu.Lock()
 	if oldIndex, ok := i.perOrgIndex[orgID]; ok {
@@ -651,6 +664,10 @@ func (i *searchIndex) applyEvent(ctx context.Context, orgID int64, kind store.En
 	}
 	i.mu.Unlock()
 
+	if entKind, ok := entityKindByEntityType[kind]; ok {
+		return i.applyEntityEvent(ctx, orgID, entKind, uid)
+	}
+
 	// Both dashboard and folder share same DB table.
 	dbDashboards, err := i.loader.LoadDashboards(ctx, orgID, uid)
 	if err != nil {
@@ -685,6 +702,33 @@ func (i *searchIndex) applyEvent(ctx context.Context, orgID int64, kind store.En
 	return nil
 }
 
+// applyEntityEvent updates or removes the document of an alert rule, library panel or datasource.
+func (i *searchIndex) applyEntityEvent(ctx context.Context, orgID int64, kind entityKind, uid string) error {
+	entities, err := i.entityLoader.LoadEntities(ctx, orgID, kind, uid)
+	if err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	index, ok := i.perOrgIndex[orgID]
+	if !ok {
+		// Skip event for org not yet fully indexed.
+		return nil
+	}
+
+	writer := index.writerForIndex(indexTypeDashboard)
+	if len(entities) == 0 {
+		batch := bluge.NewBatch()
+		batch.Delete(bluge.NewDocument(entityDocumentID(kind, uid)).ID())
+		return writer.Batch(batch)
+	}
+
+	doc := getEntityDoc(entities[0])
+	return writer.Update(doc.ID(), doc)
+}
+
 func (i *searchIndex) removeDashboard(_ context.Context, index *orgIndex, dashboardUID string) error {
 	dashboardLocation, ok, err := getDashboardLocation(index, dashboardUID)
 	if err != nil {
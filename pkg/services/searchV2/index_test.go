@@ -28,6 +28,20 @@ func (t *testDashboardLoader) LoadDashboards(_ context.Context, _ int64, _ strin
 	return t.dashboards, nil
 }
 
+type testEntityLoader struct {
+	entities []searchEntity
+}
+
+func (t *testEntityLoader) LoadEntities(_ context.Context, _ int64, kind entityKind, uid string) ([]searchEntity, error) {
+	var res []searchEntity
+	for _, e := range t.entities {
+		if e.kind == kind && (uid == "" || e.uid == uid) {
+			res = append(res, e)
+		}
+	}
+	return res, nil
+}
+
 var testLogger = log.New("index-test-logger")
 
 var testAllowAllFilter = func(kind entityKind, uid, parent string) bool {
@@ -62,7 +76,7 @@ func initTestIndexFromDashesExtended(t *testing.T, dashboards []dashboard, exten
 	dashboardLoader := &testDashboardLoader{
 		dashboards: dashboards,
 	}
-	index := newSearchIndex(dashboardLoader, &store.MockEntityEventsService{}, extender, func(ctx context.Context, folderId int64) (string, error) { return "x", nil }, tracing.InitializeTracerForTest(), featuremgmt.WithFeatures(), setting.SearchSettings{})
+	index := newSearchIndex(dashboardLoader, &testEntityLoader{}, &store.MockEntityEventsService{}, extender, func(ctx context.Context, folderId int64) (string, error) { return "x", nil }, tracing.InitializeTracerForTest(), featuremgmt.WithFeatures(), setting.SearchSettings{})
 	require.NotNil(t, index)
 	numDashboards, err := index.buildOrgIndex(context.Background(), testOrgID)
 	require.NoError(t, err)
@@ -731,3 +745,128 @@ func TestDashboardIndex_MultiTermPrefixMatch(t *testing.T) {
 		})
 	}
 }
+
+var testEntities = []searchEntity{
+	{
+		kind:      entityKindAlertRule,
+		uid:       "1",
+		name:      "Payments latency",
+		folderUID: "1",
+		labels:    map[string]string{"team": "payments"},
+		dsUIDs:    []string{"payments-db"},
+	},
+	{
+		kind:      entityKindLibraryPanel,
+		uid:       "1",
+		name:      "Payments throughput",
+		folderUID: "general",
+		panelType: "timeseries",
+		dsUIDs:    []string{"payments-db"},
+		dsTypes:   []string{"postgres"},
+	},
+	{
+		kind:    entityKindDatasource,
+		uid:     "payments-db",
+		name:    "Payments DB",
+		dsUIDs:  []string{"payments-db"},
+		dsTypes: []string{"postgres"},
+	},
+	{
+		kind:    entityKindDatasource,
+		uid:     "other-db",
+		name:    "Other DB",
+		dsUIDs:  []string{"other-db"},
+		dsTypes: []string{"mysql"},
+	},
+}
+
+func initTestIndexFromEntities(t *testing.T, dashboards []dashboard, entities []searchEntity) *searchIndex {
+	t.Helper()
+	entityLoader := &testEntityLoader{entities: entities}
+	index := newSearchIndex(&testDashboardLoader{dashboards: dashboards}, entityLoader, &store.MockEntityEventsService{}, &NoopDocumentExtender{}, func(ctx context.Context, folderId int64) (string, error) { return "x", nil }, tracing.InitializeTracerForTest(), featuremgmt.WithFeatures(), setting.SearchSettings{})
+	_, err := index.buildOrgIndex(context.Background(), testOrgID)
+	require.NoError(t, err)
+	return index
+}
+
+func getResultUIDsByKind(t *testing.T, resp *backend.DataResponse) map[string][]string {
+	t.Helper()
+	require.NoError(t, resp.Error)
+	frame := resp.Frames[0]
+	kindField, _ := frame.FieldByName("kind")
+	uidField, _ := frame.FieldByName("uid")
+	res := make(map[string][]string)
+	for i := 0; i < frame.Rows(); i++ {
+		kind := kindField.At(i).(string)
+		res[kind] = append(res[kind], uidField.At(i).(string))
+	}
+	return res
+}
+
+func TestDashboardIndex_Entities(t *testing.T) {
+	t.Run("entities-indexed-by-datasource", func(t *testing.T) {
+		index := initTestIndexFromEntities(t, dashboardsWithFolders, testEntities)
+		orgIdx, ok := index.getOrgIndex(testOrgID)
+		require.True(t, ok)
+		resp := doSearchQuery(context.Background(), testLogger, orgIdx, testAllowAllFilter,
+			DashboardQuery{Datasource: "payments-db"}, &NoopQueryExtender{}, "")
+		require.Equal(t, map[string][]string{
+			string(entityKindAlertRule):    {"1"},
+			string(entityKindLibraryPanel): {"1"},
+			string(entityKindDatasource):   {"payments-db"},
+		}, getResultUIDsByKind(t, resp))
+	})
+	t.Run("entities-indexed-by-kind", func(t *testing.T) {
+		index := initTestIndexFromEntities(t, dashboardsWithFolders, testEntities)
+		orgIdx, ok := index.getOrgIndex(testOrgID)
+		require.True(t, ok)
+		resp := doSearchQuery(context.Background(), testLogger, orgIdx, testAllowAllFilter,
+			DashboardQuery{Query: "payments", Kind: []string{string(entityKindAlertRule)}, Tags: []string{"team=payments"}},
+			&NoopQueryExtender{}, "")
+		require.Equal(t, map[string][]string{
+			string(entityKindAlertRule): {"1"},
+		}, getResultUIDsByKind(t, resp))
+	})
+	t.Run("entities-filtered-by-permissions", func(t *testing.T) {
+		index := initTestIndexFromEntities(t, dashboardsWithFolders, testEntities)
+		orgIdx, ok := index.getOrgIndex(testOrgID)
+		require.True(t, ok)
+		onlyDatasources := func(kind entityKind, uid, parent string) bool {
+			return kind == entityKindDatasource && uid == "payments-db"
+		}
+		resp := doSearchQuery(context.Background(), testLogger, orgIdx, onlyDatasources,
+			DashboardQuery{Query: "payments"}, &NoopQueryExtender{}, "")
+		require.Equal(t, map[string][]string{
+			string(entityKindDatasource): {"payments-db"},
+		}, getResultUIDsByKind(t, resp))
+	})
+	t.Run("entities-updated-on-event", func(t *testing.T) {
+		entities := make([]searchEntity, len(testEntities))
+		copy(entities, testEntities)
+		index := initTestIndexFromEntities(t, nil, entities)
+		loader := index.entityLoader.(*testEntityLoader)
+
+		// rename the alert rule and remove the datasource
+		loader.entities = []searchEntity{entities[0], entities[1], entities[3]}
+		loader.entities[0].name = "Checkout latency"
+		require.NoError(t, index.applyEvent(context.Background(), testOrgID, store.EntityTypeAlertRule, "1", store.EntityEventTypeUpdate))
+		require.NoError(t, index.applyEvent(context.Background(), testOrgID, store.EntityTypeDatasource, "payments-db", store.EntityEventTypeDelete))
+
+		orgIdx, ok := index.getOrgIndex(testOrgID)
+		require.True(t, ok)
+		resp := doSearchQuery(context.Background(), testLogger, orgIdx, testAllowAllFilter,
+			DashboardQuery{Query: "payments"}, &NoopQueryExtender{}, "")
+		require.Equal(t, map[string][]string{
+			string(entityKindLibraryPanel): {"1"},
+		}, getResultUIDsByKind(t, resp))
+	})
+	t.Run("entities-removed-on-folder-removed", func(t *testing.T) {
+		index := initTestIndexFromEntities(t, dashboardsWithFolders, testEntities)
+		orgIdx, ok := index.getOrgIndex(testOrgID)
+		require.True(t, ok)
+		require.NoError(t, index.removeFolder(context.Background(), orgIdx, "1"))
+		resp := doSearchQuery(context.Background(), testLogger, orgIdx, testAllowAllFilter,
+			DashboardQuery{Kind: []string{string(entityKindAlertRule)}}, &NoopQueryExtender{}, "")
+		require.Equal(t, map[string][]string{}, getResultUIDsByKind(t, resp))
+	})
+}
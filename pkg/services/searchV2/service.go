@@ -98,6 +98,7 @@ func ProvideService(cfg *setting.Cfg, sql db.DB, entityEventStore store.EntityEv
 		},
 		dashboardIndex: newSearchIndex(
 			newSQLDashboardLoader(sql, tracer, cfg.Search),
+			newSQLEntityLoader(sql, tracer),
 			entityEventStore,
 			extender.GetDocumentExtender(),
 			newFolderIDLookup(sql),
@@ -358,6 +358,10 @@ func (f *fakeServiceAccountService) DeleteServiceAccountToken(ctx context.Contex
 	return f.ExpectedErr
 }
 
+func (f *fakeServiceAccountService) RotateServiceAccountToken(ctx context.Context, id, tokenID int64, cmd *serviceaccounts.RotateServiceAccountTokenCommand) (*apikey.APIKey, error) {
+	return f.ExpectedAPIKey, f.ExpectedErr
+}
+
 func (f *fakeServiceAccountService) MigrateApiKeysToServiceAccounts(ctx context.Context, orgID int64) (*serviceaccounts.MigrationResult, error) {
 	fmt.Printf("fake migration result: %v", f.ExpectedMigrationResult)
 	return f.ExpectedMigrationResult, f.ExpectedErr
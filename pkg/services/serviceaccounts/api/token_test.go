@@ -63,6 +63,7 @@ func TestServiceAccountsAPI_CreateToken(t *testing.T) {
 		body           string
 		permissions    []accesscontrol.Permission
 		tokenTTL       int64
+		tokens         []apikey.APIKey
 		expectedErr    error
 		expectedAPIKey *apikey.APIKey
 		expectedCode   int
@@ -176,3 +177,96 @@ func TestServiceAccountsAPI_DeleteToken(t *testing.T) {
 		})
 	}
 }
+
+func TestServiceAccountsAPI_RotateToken(t *testing.T) {
+	inheritedExpires := time.Now().Add(2 * time.Hour).Unix()
+
+	type TestCase struct {
+		desc           string
+		saID           int64
+		body           string
+		permissions    []accesscontrol.Permission
+		tokenTTL       int64
+		tokens         []apikey.APIKey
+		expectedErr    error
+		expectedAPIKey *apikey.APIKey
+		expectedCode   int
+	}
+
+	tests := []TestCase{
+		{
+			desc:           "should be able to rotate service account token with correct permission",
+			saID:           1,
+			tokenTTL:       -1,
+			tokens:         []apikey.APIKey{{ID: 1, Name: "token", Created: time.Now()}},
+			permissions:    []accesscontrol.Permission{{Action: serviceaccounts.ActionWrite, Scope: "serviceaccounts:id:1"}},
+			expectedAPIKey: &apikey.APIKey{ID: 2, Name: "rotated"},
+			expectedCode:   http.StatusOK,
+		},
+		{
+			desc:         "should not be able to rotate service account token with wrong permission",
+			saID:         2,
+			tokenTTL:     -1,
+			permissions:  []accesscontrol.Permission{{Action: serviceaccounts.ActionWrite, Scope: "serviceaccounts:id:1"}},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			desc:         "should not be able to rotate service account token that does not exist",
+			saID:         1,
+			tokenTTL:     -1,
+			permissions:  []accesscontrol.Permission{{Action: serviceaccounts.ActionWrite, Scope: "serviceaccounts:id:1"}},
+			expectedErr:  serviceaccounts.ErrServiceAccountTokenNotFound.Errorf(""),
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			desc:         "should not be able to rotate service account token with lifetime greater than the global limit",
+			saID:         1,
+			body:         `{"secondsToLive": 7200}`,
+			tokenTTL:     3600,
+			permissions:  []accesscontrol.Permission{{Action: serviceaccounts.ActionWrite, Scope: "serviceaccounts:id:1"}},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			desc:         "should not be able to rotate service account token inheriting a lifetime greater than the global limit",
+			saID:         1,
+			tokenTTL:     3600,
+			tokens:       []apikey.APIKey{{ID: 1, Name: "token", Created: time.Now(), Expires: &inheritedExpires}},
+			permissions:  []accesscontrol.Permission{{Action: serviceaccounts.ActionWrite, Scope: "serviceaccounts:id:1"}},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			desc:         "should not be able to rotate service account token without expiration when the lifetime is limited",
+			saID:         1,
+			tokenTTL:     3600,
+			tokens:       []apikey.APIKey{{ID: 1, Name: "token", Created: time.Now()}},
+			permissions:  []accesscontrol.Permission{{Action: serviceaccounts.ActionWrite, Scope: "serviceaccounts:id:1"}},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			desc:         "should not be able to rotate expired service account token",
+			saID:         1,
+			body:         `{"secondsToLive": 1800}`,
+			tokenTTL:     3600,
+			permissions:  []accesscontrol.Permission{{Action: serviceaccounts.ActionWrite, Scope: "serviceaccounts:id:1"}},
+			expectedErr:  serviceaccounts.ErrServiceAccountTokenExpired.Errorf(""),
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			server := setupTests(t, func(a *ServiceAccountsAPI) {
+				a.cfg.ApiKeyMaxSecondsToLive = tt.tokenTTL
+				a.service = &fakeServiceAccountService{ExpectedErr: tt.expectedErr, ExpectedAPIKey: tt.expectedAPIKey, ExpectedServiceAccountTokens: tt.tokens}
+			})
+
+			req := server.NewRequest(http.MethodPost, fmt.Sprintf("/api/serviceaccounts/%d/tokens/1/rotate", tt.saID), strings.NewReader(tt.body))
+			webtest.RequestWithSignedInUser(req, &user.SignedInUser{OrgID: 1, Permissions: map[int64]map[string][]string{1: accesscontrol.GroupScopesByAction(tt.permissions)}})
+			res, err := server.SendJSON(req)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expectedCode, res.StatusCode)
+			require.NoError(t, res.Body.Close())
+		})
+	}
+}
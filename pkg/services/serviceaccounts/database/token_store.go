@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/grafana/grafana/pkg/infra/db"
 	"github.com/grafana/grafana/pkg/services/apikey"
@@ -109,6 +110,38 @@ func (s *ServiceAccountsStoreImpl) RevokeServiceAccountToken(ctx context.Context
 	})
 }
 
+// UpdateServiceAccountTokenExpiration sets the expiration of a service account token to the given unix timestamp
+func (s *ServiceAccountsStoreImpl) UpdateServiceAccountTokenExpiration(ctx context.Context, orgId, serviceAccountId, tokenId, expires int64) error {
+	rawSQL := "UPDATE api_key SET expires = ?, updated = ? WHERE id=? and org_id=? and service_account_id=?"
+
+	return s.sqlStore.WithDbSession(ctx, func(sess *db.Session) error {
+		result, err := sess.Exec(rawSQL, expires, time.Now(), tokenId, orgId, serviceAccountId)
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		if affected == 0 {
+			return serviceaccounts.ErrServiceAccountTokenNotFound.Errorf("service account token with id %d not found for service account with id %d", tokenId, serviceAccountId)
+		}
+
+		return err
+	})
+}
+
+// ListTokensExpiringBefore returns the service account tokens of all organizations that have not expired
+// or been revoked yet, but expire before the given time
+func (s *ServiceAccountsStoreImpl) ListTokensExpiringBefore(ctx context.Context, before time.Time) ([]apikey.APIKey, error) {
+	result := make([]apikey.APIKey, 0)
+	err := s.sqlStore.WithDbSession(ctx, func(sess *db.Session) error {
+		return sess.Where("service_account_id IS NOT NULL").
+			Where("expires IS NOT NULL AND expires > ? AND expires <= ?", time.Now().Unix(), before.Unix()).
+			Where("(is_revoked IS NULL OR is_revoked = ?)", s.sqlStore.GetDialect().BooleanStr(false)).
+			Asc("expires").
+			Find(&result)
+	})
+	return result, err
+}
+
 // assignApiKeyToServiceAccount sets the API key service account ID
 func (s *ServiceAccountsStoreImpl) assignApiKeyToServiceAccount(ctx context.Context, apiKeyId int64, serviceAccountId int64) error {
 	return s.sqlStore.WithDbSession(ctx, func(sess *db.Session) error {
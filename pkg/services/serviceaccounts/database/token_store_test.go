@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -176,3 +177,39 @@ func TestStore_DeleteServiceAccountToken(t *testing.T) {
 		}
 	}
 }
+
+func TestStore_ListTokensExpiringBefore(t *testing.T) {
+	userToCreate := tests.TestUser{Login: "servicetestwithTeam@admin", IsServiceAccount: true}
+	db, store := setupTestDatabase(t)
+	sa := tests.SetupUserServiceAccount(t, db, userToCreate)
+
+	addToken := func(name string, secondsToLive int64) int64 {
+		key, err := apikeygen.New(sa.OrgID, name)
+		require.NoError(t, err)
+		newKey, err := store.AddServiceAccountToken(context.Background(), sa.ID, &serviceaccounts.AddServiceAccountTokenCommand{
+			Name:          name,
+			OrgId:         sa.OrgID,
+			Key:           key.HashedKey,
+			SecondsToLive: secondsToLive,
+		})
+		require.NoError(t, err)
+		return newKey.ID
+	}
+
+	expiring := addToken("expiring", int64(time.Hour.Seconds()))
+	addToken("no-expiry", 0)
+	addToken("later", int64((30 * 24 * time.Hour).Seconds()))
+	revoked := addToken("revoked", int64(time.Hour.Seconds()))
+	require.NoError(t, store.RevokeServiceAccountToken(context.Background(), sa.OrgID, sa.ID, revoked))
+	rotated := addToken("rotated", 0)
+	require.NoError(t, store.UpdateServiceAccountTokenExpiration(context.Background(), sa.OrgID, sa.ID, rotated, time.Now().Add(2*time.Hour).Unix()))
+
+	keys, err := store.ListTokensExpiringBefore(context.Background(), time.Now().Add(24*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	require.Equal(t, expiring, keys[0].ID)
+	require.Equal(t, rotated, keys[1].ID)
+
+	err = store.UpdateServiceAccountTokenExpiration(context.Background(), sa.OrgID, sa.ID+1, rotated, 0)
+	require.ErrorIs(t, err, serviceaccounts.ErrServiceAccountTokenNotFound)
+}
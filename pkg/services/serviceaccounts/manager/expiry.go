@@ -0,0 +1,113 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/services/apikey"
+	"github.com/grafana/grafana/pkg/services/notifications"
+	"github.com/grafana/grafana/pkg/services/org"
+)
+
+const (
+	tokenExpiryNamespace         = "serviceaccounts.tokens"
+	tokenExpiringEmailTemplate   = "service_account_token_expiring"
+	tokenExpiryNotifiedKeyFormat = "%d.expiry-notified"
+)
+
+// checkExpiringTokens updates the expiring tokens metric and notifies the org admins
+// about tokens expiring within the notification period. Admins are notified once per
+// token expiration, so a token that gets its expiration changed is notified about again.
+func (sa *ServiceAccountsService) checkExpiringTokens(ctx context.Context) error {
+	if sa.tokenExpiryNotificationPeriod <= 0 {
+		MStatTotalServiceAccountTokensExpiring.Set(0)
+		return nil
+	}
+
+	tokens, err := sa.store.ListTokensExpiringBefore(ctx, time.Now().Add(sa.tokenExpiryNotificationPeriod))
+	if err != nil {
+		return err
+	}
+	MStatTotalServiceAccountTokensExpiring.Set(float64(len(tokens)))
+
+	if !sa.smtpEnabled || sa.notificationService == nil {
+		return nil
+	}
+
+	recipients := map[int64][]string{}
+	for i := range tokens {
+		token := tokens[i]
+		kv := kvstore.WithNamespace(sa.kvStore, token.OrgID, tokenExpiryNamespace)
+		key := fmt.Sprintf(tokenExpiryNotifiedKeyFormat, token.ID)
+		expires := strconv.FormatInt(*token.Expires, 10)
+
+		notified, ok, err := kv.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		if ok && notified == expires {
+			continue
+		}
+
+		if _, ok := recipients[token.OrgID]; !ok {
+			if recipients[token.OrgID], err = sa.getOrgAdminEmails(ctx, token.OrgID); err != nil {
+				return err
+			}
+		}
+		if len(recipients[token.OrgID]) == 0 {
+			sa.backgroundLog.Debug("No org admin to notify about expiring token", "orgId", token.OrgID, "tokenId", token.ID)
+			continue
+		}
+
+		if err := sa.notifyTokenExpiring(ctx, &token, recipients[token.OrgID]); err != nil {
+			sa.backgroundLog.Warn("Failed to notify about expiring token", "orgId", token.OrgID, "tokenId", token.ID, "error", err)
+			continue
+		}
+		if err := kv.Set(ctx, key, expires); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (sa *ServiceAccountsService) notifyTokenExpiring(ctx context.Context, token *apikey.APIKey, to []string) error {
+	serviceAccount, err := sa.store.RetrieveServiceAccount(ctx, token.OrgID, *token.ServiceAccountId)
+	if err != nil {
+		return err
+	}
+
+	expires := time.Unix(*token.Expires, 0)
+	return sa.notificationService.SendEmailCommandHandler(ctx, &notifications.SendEmailCommand{
+		To:       to,
+		Template: tokenExpiringEmailTemplate,
+		Data: map[string]any{
+			"ServiceAccountName": serviceAccount.Name,
+			"ServiceAccountID":   serviceAccount.Id,
+			"TokenName":          token.Name,
+			"Expires":            expires.UTC().Format(time.RFC1123),
+			"ExpiresInDays":      int(time.Until(expires).Hours() / 24),
+		},
+	})
+}
+
+func (sa *ServiceAccountsService) getOrgAdminEmails(ctx context.Context, orgID int64) ([]string, error) {
+	result, err := sa.orgService.SearchOrgUsers(ctx, &org.SearchOrgUsersQuery{
+		OrgID:                    orgID,
+		DontEnforceAccessControl: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	emails := make([]string, 0)
+	for _, user := range result.OrgUsers {
+		if user.Role == string(org.RoleAdmin) && !user.IsDisabled && user.Email != "" {
+			emails = append(emails, user.Email)
+		}
+	}
+	return emails, nil
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/grafana/grafana/pkg/infra/usagestats"
 	"github.com/grafana/grafana/pkg/services/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/apikey"
+	"github.com/grafana/grafana/pkg/services/notifications"
 	"github.com/grafana/grafana/pkg/services/org"
 	"github.com/grafana/grafana/pkg/services/serviceaccounts"
 	"github.com/grafana/grafana/pkg/services/serviceaccounts/api"
@@ -25,16 +26,25 @@ import (
 const (
 	metricsCollectionInterval = time.Minute * 30
 	defaultSecretScanInterval = time.Minute * 5
+	tokenExpiryCheckInterval  = time.Hour
 )
 
 type ServiceAccountsService struct {
-	store             store
-	log               log.Logger
-	backgroundLog     log.Logger
-	secretScanService secretscan.Checker
+	store               store
+	log                 log.Logger
+	backgroundLog       log.Logger
+	secretScanService   secretscan.Checker
+	notificationService notifications.EmailSender
+	orgService          org.Service
+	kvStore             kvstore.KVStore
 
 	secretScanEnabled  bool
 	secretScanInterval time.Duration
+
+	tokenMaxTTL                   time.Duration
+	tokenRotationGracePeriod      time.Duration
+	tokenExpiryNotificationPeriod time.Duration
+	smtpEnabled                   bool
 }
 
 func ProvideServiceAccountsService(
@@ -49,6 +59,7 @@ func ProvideServiceAccountsService(
 	orgService org.Service,
 	permissionService accesscontrol.ServiceAccountPermissionsService,
 	accesscontrolService accesscontrol.Service,
+	notificationService notifications.EmailSender,
 ) (*ServiceAccountsService, error) {
 	serviceAccountsStore := database.ProvideServiceAccountsStore(
 		cfg,
@@ -59,9 +70,16 @@ func ProvideServiceAccountsService(
 		orgService,
 	)
 	s := &ServiceAccountsService{
-		store:         serviceAccountsStore,
-		log:           log.New("serviceaccounts"),
-		backgroundLog: log.New("serviceaccounts.background"),
+		store:                         serviceAccountsStore,
+		log:                           log.New("serviceaccounts"),
+		backgroundLog:                 log.New("serviceaccounts.background"),
+		notificationService:           notificationService,
+		orgService:                    orgService,
+		kvStore:                       kvStore,
+		tokenMaxTTL:                   cfg.SATokenMaxTTL,
+		tokenRotationGracePeriod:      cfg.SATokenRotationGracePeriod,
+		tokenExpiryNotificationPeriod: cfg.SATokenExpiryNotificationPeriod,
+		smtpEnabled:                   cfg.Smtp.Enabled,
 	}
 
 	if err := RegisterRoles(accesscontrolService); err != nil {
@@ -99,6 +117,12 @@ func (sa *ServiceAccountsService) Run(ctx context.Context) error {
 	updateStatsTicker := time.NewTicker(metricsCollectionInterval)
 	defer updateStatsTicker.Stop()
 
+	tokenExpiryTicker := time.NewTicker(tokenExpiryCheckInterval)
+	defer tokenExpiryTicker.Stop()
+	if err := sa.checkExpiringTokens(ctx); err != nil {
+		sa.backgroundLog.Warn("Failed to check for expiring tokens", "error", err.Error())
+	}
+
 	// Enforce a minimum interval of 1 minute.
 	if sa.secretScanEnabled && sa.secretScanInterval < time.Minute {
 		sa.backgroundLog.Warn("Secret scan interval is too low, increasing to " +
@@ -136,6 +160,12 @@ func (sa *ServiceAccountsService) Run(ctx context.Context) error {
 			if _, err := sa.getUsageMetrics(ctx); err != nil {
 				sa.backgroundLog.Warn("Failed to get usage metrics", "error", err.Error())
 			}
+		case <-tokenExpiryTicker.C:
+			sa.backgroundLog.Debug("Checking for expiring tokens")
+
+			if err := sa.checkExpiringTokens(ctx); err != nil {
+				sa.backgroundLog.Warn("Failed to check for expiring tokens", "error", err.Error())
+			}
 		case <-tokenCheckTicker.C:
 			sa.backgroundLog.Debug("Checking for leaked tokens")
 
@@ -209,9 +239,100 @@ func (sa *ServiceAccountsService) AddServiceAccountToken(ctx context.Context, se
 	if err := validServiceAccountID(serviceAccountID); err != nil {
 		return nil, err
 	}
+	if err := sa.applyTokenMaxTTL(&query.SecondsToLive); err != nil {
+		return nil, err
+	}
 	return sa.store.AddServiceAccountToken(ctx, serviceAccountID, query)
 }
 
+// RotateServiceAccountToken issues a replacement for a service account token. The rotated token stays valid
+// for the configured grace period so that clients can switch to the replacement without downtime.
+func (sa *ServiceAccountsService) RotateServiceAccountToken(ctx context.Context, serviceAccountID, tokenID int64, cmd *serviceaccounts.RotateServiceAccountTokenCommand) (*apikey.APIKey, error) {
+	if err := validOrgID(cmd.OrgId); err != nil {
+		return nil, err
+	}
+	if err := validServiceAccountID(serviceAccountID); err != nil {
+		return nil, err
+	}
+	if err := validServiceAccountTokenID(tokenID); err != nil {
+		return nil, err
+	}
+
+	tokens, err := sa.store.ListTokens(ctx, &serviceaccounts.GetSATokensQuery{OrgID: &cmd.OrgId, ServiceAccountID: &serviceAccountID})
+	if err != nil {
+		return nil, err
+	}
+	var rotated *apikey.APIKey
+	for i := range tokens {
+		if tokens[i].ID == tokenID {
+			rotated = &tokens[i]
+			break
+		}
+	}
+	if rotated == nil {
+		return nil, serviceaccounts.ErrServiceAccountTokenNotFound.Errorf("service account token with id %d not found for service account with id %d", tokenID, serviceAccountID)
+	}
+	if rotated.IsRevoked != nil && *rotated.IsRevoked {
+		return nil, serviceaccounts.ErrServiceAccountTokenRevoked.Errorf("service account token with id %d has been revoked", tokenID)
+	}
+
+	now := time.Now()
+	if rotated.Expires != nil && *rotated.Expires <= now.Unix() {
+		return nil, serviceaccounts.ErrServiceAccountTokenExpired.Errorf("service account token with id %d has expired", tokenID)
+	}
+
+	name := cmd.Name
+	if name == "" {
+		name = fmt.Sprintf("%s-%s", rotated.Name, now.Format("20060102150405"))
+	}
+	secondsToLive := cmd.SecondsToLive
+	if secondsToLive == 0 && rotated.Expires != nil {
+		secondsToLive = *rotated.Expires - rotated.Created.Unix()
+	}
+
+	replacement, err := sa.AddServiceAccountToken(ctx, serviceAccountID, &serviceaccounts.AddServiceAccountTokenCommand{
+		Name:          name,
+		OrgId:         cmd.OrgId,
+		Key:           cmd.Key,
+		SecondsToLive: secondsToLive,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the rotated token is never extended beyond its original expiration
+	expires := now.Add(sa.tokenRotationGracePeriod).Unix()
+	if rotated.Expires != nil && *rotated.Expires < expires {
+		expires = *rotated.Expires
+	}
+	if err := sa.store.UpdateServiceAccountTokenExpiration(ctx, cmd.OrgId, serviceAccountID, tokenID, expires); err != nil {
+		// the replacement is deleted so that the rotation can be retried without leaving an unknown token behind
+		if deleteErr := sa.store.DeleteServiceAccountToken(ctx, cmd.OrgId, serviceAccountID, replacement.ID); deleteErr != nil {
+			sa.log.Error("Failed to delete the replacement of a service account token which could not be rotated", "serviceAccountId", serviceAccountID, "tokenId", tokenID, "replacementTokenId", replacement.ID, "error", deleteErr)
+		}
+		return nil, fmt.Errorf("failed to expire the rotated token: %w", err)
+	}
+
+	sa.log.Info("Rotated service account token", "serviceAccountId", serviceAccountID, "tokenId", tokenID, "replacementTokenId", replacement.ID, "expires", time.Unix(expires, 0))
+	return replacement, nil
+}
+
+// applyTokenMaxTTL enforces the configured maximum token lifetime, tokens without expiry get the maximum lifetime
+func (sa *ServiceAccountsService) applyTokenMaxTTL(secondsToLive *int64) error {
+	if sa.tokenMaxTTL <= 0 {
+		return nil
+	}
+	maxSecondsToLive := int64(sa.tokenMaxTTL.Seconds())
+	if *secondsToLive == 0 {
+		*secondsToLive = maxSecondsToLive
+		return nil
+	}
+	if *secondsToLive > maxSecondsToLive {
+		return serviceaccounts.ErrTokenExceedsMaxTTL.Errorf("token lifetime of %d seconds exceeds the maximum of %d seconds", *secondsToLive, maxSecondsToLive)
+	}
+	return nil
+}
+
 func (sa *ServiceAccountsService) DeleteServiceAccountToken(ctx context.Context, orgID, serviceAccountID int64, tokenID int64) error {
 	if err := validOrgID(orgID); err != nil {
 		return err
@@ -2,13 +2,18 @@ package manager
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/grafana/grafana/pkg/infra/kvstore"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/services/apikey"
+	"github.com/grafana/grafana/pkg/services/notifications"
 	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/org/orgtest"
 	"github.com/grafana/grafana/pkg/services/serviceaccounts"
 )
 
@@ -21,8 +26,13 @@ type FakeServiceAccountStore struct {
 	expectedMigratedResults                 *serviceaccounts.MigrationResult
 	ExpectedAPIKeys                         []apikey.APIKey
 	ExpectedAPIKey                          *apikey.APIKey
+	ExpectedExpiringAPIKeys                 []apikey.APIKey
 	ExpectedBoolean                         bool
 	ExpectedError                           error
+
+	UpdatedTokenExpiration   map[int64]int64
+	ExpectedUpdateExpiration error
+	DeletedTokens            []int64
 }
 
 func newServiceAccountStoreFake() *FakeServiceAccountStore {
@@ -92,9 +102,27 @@ func (f *FakeServiceAccountStore) AddServiceAccountToken(ctx context.Context, se
 
 // DeleteServiceAccountToken is a fake deleting a service account token.
 func (f *FakeServiceAccountStore) DeleteServiceAccountToken(ctx context.Context, orgID, serviceAccountID, tokenID int64) error {
+	f.DeletedTokens = append(f.DeletedTokens, tokenID)
+	return f.ExpectedError
+}
+
+// UpdateServiceAccountTokenExpiration is a fake updating the expiration of a service account token.
+func (f *FakeServiceAccountStore) UpdateServiceAccountTokenExpiration(ctx context.Context, orgID, serviceAccountID, tokenID, expires int64) error {
+	if f.UpdatedTokenExpiration == nil {
+		f.UpdatedTokenExpiration = map[int64]int64{}
+	}
+	f.UpdatedTokenExpiration[tokenID] = expires
+	if f.ExpectedUpdateExpiration != nil {
+		return f.ExpectedUpdateExpiration
+	}
 	return f.ExpectedError
 }
 
+// ListTokensExpiringBefore is a fake listing expiring tokens.
+func (f *FakeServiceAccountStore) ListTokensExpiringBefore(ctx context.Context, before time.Time) ([]apikey.APIKey, error) {
+	return f.ExpectedExpiringAPIKeys, f.ExpectedError
+}
+
 // GetUsageMetrics is a fake getting usage metrics.
 func (f *FakeServiceAccountStore) GetUsageMetrics(ctx context.Context) (*serviceaccounts.Stats, error) {
 	return f.ExpectedStats, f.ExpectedError
@@ -110,7 +138,7 @@ func (f *SecretsCheckerFake) CheckTokens(ctx context.Context) error {
 
 func TestProvideServiceAccount_DeleteServiceAccount(t *testing.T) {
 	storeMock := newServiceAccountStoreFake()
-	svc := ServiceAccountsService{storeMock, log.New("test"), log.New("background.test"), &SecretsCheckerFake{}, false, 0}
+	svc := ServiceAccountsService{store: storeMock, log: log.New("test"), backgroundLog: log.New("background.test"), secretScanService: &SecretsCheckerFake{}}
 	testOrgId := 1
 
 	t.Run("should create service account", func(t *testing.T) {
@@ -137,3 +165,142 @@ func TestProvideServiceAccount_DeleteServiceAccount(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func TestProvideServiceAccount_AddServiceAccountToken(t *testing.T) {
+	storeMock := newServiceAccountStoreFake()
+	svc := ServiceAccountsService{store: storeMock, log: log.New("test"), tokenMaxTTL: time.Hour}
+
+	t.Run("should set max lifetime on token without expiry", func(t *testing.T) {
+		cmd := &serviceaccounts.AddServiceAccountTokenCommand{Name: "test", OrgId: 1}
+		_, err := svc.AddServiceAccountToken(context.Background(), 1, cmd)
+		require.NoError(t, err)
+		require.Equal(t, int64(3600), cmd.SecondsToLive)
+	})
+
+	t.Run("should reject token exceeding max lifetime", func(t *testing.T) {
+		cmd := &serviceaccounts.AddServiceAccountTokenCommand{Name: "test", OrgId: 1, SecondsToLive: 3601}
+		_, err := svc.AddServiceAccountToken(context.Background(), 1, cmd)
+		require.ErrorIs(t, err, serviceaccounts.ErrTokenExceedsMaxTTL)
+	})
+}
+
+func TestProvideServiceAccount_RotateServiceAccountToken(t *testing.T) {
+	revoked := true
+	created := time.Now().Add(-time.Hour)
+	expires := created.Add(48 * time.Hour).Unix()
+	soon := time.Now().Add(time.Minute).Unix()
+
+	t.Run("should issue replacement and expire rotated token after grace period", func(t *testing.T) {
+		storeMock := newServiceAccountStoreFake()
+		storeMock.ExpectedAPIKeys = []apikey.APIKey{{ID: 2, Name: "token", Created: created, Expires: &expires}}
+		storeMock.ExpectedAPIKey = &apikey.APIKey{ID: 3}
+		svc := ServiceAccountsService{store: storeMock, log: log.New("test"), tokenRotationGracePeriod: time.Hour}
+
+		key, err := svc.RotateServiceAccountToken(context.Background(), 1, 2, &serviceaccounts.RotateServiceAccountTokenCommand{OrgId: 1})
+		require.NoError(t, err)
+		require.Equal(t, int64(3), key.ID)
+		require.InDelta(t, time.Now().Add(time.Hour).Unix(), storeMock.UpdatedTokenExpiration[2], 5)
+	})
+
+	t.Run("should not extend rotated token expiring before the grace period", func(t *testing.T) {
+		storeMock := newServiceAccountStoreFake()
+		storeMock.ExpectedAPIKeys = []apikey.APIKey{{ID: 2, Name: "token", Created: created, Expires: &soon}}
+		storeMock.ExpectedAPIKey = &apikey.APIKey{ID: 3}
+		svc := ServiceAccountsService{store: storeMock, log: log.New("test"), tokenRotationGracePeriod: time.Hour}
+
+		_, err := svc.RotateServiceAccountToken(context.Background(), 1, 2, &serviceaccounts.RotateServiceAccountTokenCommand{OrgId: 1})
+		require.NoError(t, err)
+		require.Equal(t, soon, storeMock.UpdatedTokenExpiration[2])
+	})
+
+	t.Run("should delete replacement when rotated token cannot be expired", func(t *testing.T) {
+		storeMock := newServiceAccountStoreFake()
+		storeMock.ExpectedAPIKeys = []apikey.APIKey{{ID: 2, Name: "token", Created: created, Expires: &expires}}
+		storeMock.ExpectedAPIKey = &apikey.APIKey{ID: 3}
+		storeMock.ExpectedUpdateExpiration = errors.New("database is locked")
+		svc := ServiceAccountsService{store: storeMock, log: log.New("test"), tokenRotationGracePeriod: time.Hour}
+
+		key, err := svc.RotateServiceAccountToken(context.Background(), 1, 2, &serviceaccounts.RotateServiceAccountTokenCommand{OrgId: 1})
+		require.ErrorIs(t, err, storeMock.ExpectedUpdateExpiration)
+		require.Nil(t, key)
+		require.Equal(t, []int64{3}, storeMock.DeletedTokens)
+	})
+
+	t.Run("should not rotate unknown token", func(t *testing.T) {
+		storeMock := newServiceAccountStoreFake()
+		svc := ServiceAccountsService{store: storeMock, log: log.New("test")}
+
+		_, err := svc.RotateServiceAccountToken(context.Background(), 1, 2, &serviceaccounts.RotateServiceAccountTokenCommand{OrgId: 1})
+		require.ErrorIs(t, err, serviceaccounts.ErrServiceAccountTokenNotFound)
+	})
+
+	t.Run("should not rotate revoked token", func(t *testing.T) {
+		storeMock := newServiceAccountStoreFake()
+		storeMock.ExpectedAPIKeys = []apikey.APIKey{{ID: 2, Name: "token", IsRevoked: &revoked}}
+		svc := ServiceAccountsService{store: storeMock, log: log.New("test")}
+
+		_, err := svc.RotateServiceAccountToken(context.Background(), 1, 2, &serviceaccounts.RotateServiceAccountTokenCommand{OrgId: 1})
+		require.ErrorIs(t, err, serviceaccounts.ErrServiceAccountTokenRevoked)
+		require.Empty(t, storeMock.UpdatedTokenExpiration)
+	})
+
+	t.Run("should not rotate expired token", func(t *testing.T) {
+		expired := time.Now().Add(-time.Minute).Unix()
+		storeMock := newServiceAccountStoreFake()
+		storeMock.ExpectedAPIKeys = []apikey.APIKey{{ID: 2, Name: "token", Created: created, Expires: &expired}}
+		storeMock.ExpectedAPIKey = &apikey.APIKey{ID: 3}
+		svc := ServiceAccountsService{store: storeMock, log: log.New("test")}
+
+		key, err := svc.RotateServiceAccountToken(context.Background(), 1, 2, &serviceaccounts.RotateServiceAccountTokenCommand{OrgId: 1})
+		require.ErrorIs(t, err, serviceaccounts.ErrServiceAccountTokenExpired)
+		require.Nil(t, key)
+		require.Empty(t, storeMock.UpdatedTokenExpiration)
+	})
+}
+
+func TestProvideServiceAccount_CheckExpiringTokens(t *testing.T) {
+	saID := int64(1)
+	expires := time.Now().Add(24 * time.Hour).Unix()
+
+	storeMock := newServiceAccountStoreFake()
+	storeMock.ExpectedExpiringAPIKeys = []apikey.APIKey{{ID: 2, OrgID: 1, Name: "token", Expires: &expires, ServiceAccountId: &saID}}
+	storeMock.ExpectedServiceAccountProfileDTO = &serviceaccounts.ServiceAccountProfileDTO{Id: saID, Name: "sa"}
+
+	sent := 0
+	notificationService := notifications.MockNotificationService()
+	notificationService.EmailHandler = func(ctx context.Context, cmd *notifications.SendEmailCommand) error {
+		sent++
+		return nil
+	}
+
+	orgService := orgtest.NewOrgServiceFake()
+	orgService.ExpectedSearchOrgUsersResult = &org.SearchOrgUsersQueryResult{OrgUsers: []*org.OrgUserDTO{
+		{Email: "admin@example.org", Role: string(org.RoleAdmin)},
+		{Email: "viewer@example.org", Role: string(org.RoleViewer)},
+		{Email: "disabled@example.org", Role: string(org.RoleAdmin), IsDisabled: true},
+	}}
+
+	svc := ServiceAccountsService{
+		store:                         storeMock,
+		log:                           log.New("test"),
+		backgroundLog:                 log.New("background.test"),
+		notificationService:           notificationService,
+		orgService:                    orgService,
+		kvStore:                       kvstore.NewFakeKVStore(),
+		tokenExpiryNotificationPeriod: 7 * 24 * time.Hour,
+		smtpEnabled:                   true,
+	}
+
+	require.NoError(t, svc.checkExpiringTokens(context.Background()))
+	require.Equal(t, 1, sent)
+	require.Equal(t, []string{"admin@example.org"}, notificationService.Email.To)
+	require.Equal(t, "token", notificationService.Email.Data["TokenName"])
+
+	// admins are notified once per token expiration
+	require.NoError(t, svc.checkExpiringTokens(context.Background()))
+	require.Equal(t, 1, sent)
+
+	expires = time.Now().Add(48 * time.Hour).Unix()
+	require.NoError(t, svc.checkExpiringTokens(context.Background()))
+	require.Equal(t, 2, sent)
+}
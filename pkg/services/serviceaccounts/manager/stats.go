@@ -20,6 +20,10 @@ var (
 	// MStatTotalServiceAccountTokens is a metric gauge for total number of service account tokens
 	MStatTotalServiceAccountTokens prometheus.Gauge
 
+	// MStatTotalServiceAccountTokensExpiring is a metric gauge for the number of service account tokens expiring
+	// within the expiry notification period
+	MStatTotalServiceAccountTokensExpiring prometheus.Gauge
+
 	Initialised bool = false
 )
 
@@ -42,10 +46,17 @@ func init() {
 		Namespace: ExporterName,
 	})
 
+	MStatTotalServiceAccountTokensExpiring = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name:      "stat_total_service_account_tokens_expiring",
+		Help:      "total amount of service account tokens expiring within the expiry notification period",
+		Namespace: ExporterName,
+	})
+
 	prometheus.MustRegister(
 		MStatTotalServiceAccounts,
 		MStatTotalServiceAccountTokens,
 		MStatTotalServiceAccountsNoRole,
+		MStatTotalServiceAccountTokensExpiring,
 	)
 }
 
@@ -2,6 +2,7 @@ package manager
 
 import (
 	"context"
+	"time"
 
 	"github.com/grafana/grafana/pkg/services/apikey"
 	"github.com/grafana/grafana/pkg/services/serviceaccounts"
@@ -21,5 +22,7 @@ type store interface {
 	RevokeServiceAccountToken(ctx context.Context, orgId, serviceAccountId, tokenId int64) error
 	AddServiceAccountToken(ctx context.Context, serviceAccountID int64, cmd *serviceaccounts.AddServiceAccountTokenCommand) (*apikey.APIKey, error)
 	DeleteServiceAccountToken(ctx context.Context, orgID, serviceAccountID, tokenID int64) error
+	UpdateServiceAccountTokenExpiration(ctx context.Context, orgID, serviceAccountID, tokenID, expires int64) error
+	ListTokensExpiringBefore(ctx context.Context, before time.Time) ([]apikey.APIKey, error)
 	GetUsageMetrics(ctx context.Context) (*serviceaccounts.Stats, error)
 }
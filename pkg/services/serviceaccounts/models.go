@@ -35,6 +35,9 @@ var (
 	ErrServiceAccountTokenNotFound       = errutil.NotFound("serviceaccounts.ErrTokenNotFound", errutil.WithPublicMessage("service account token not found"))
 	ErrInvalidTokenExpiration            = errutil.ValidationFailed("serviceaccounts.ErrInvalidInput", errutil.WithPublicMessage("invalid SecondsToLive value"))
 	ErrDuplicateToken                    = errutil.BadRequest("serviceaccounts.ErrTokenAlreadyExists", errutil.WithPublicMessage("service account token with given name already exists in the organization"))
+	ErrTokenExceedsMaxTTL                = errutil.ValidationFailed("serviceaccounts.ErrTokenExceedsMaxTTL", errutil.WithPublicMessage("service account token lifetime exceeds the configured maximum"))
+	ErrServiceAccountTokenRevoked        = errutil.BadRequest("serviceaccounts.ErrTokenRevoked", errutil.WithPublicMessage("service account token has been revoked"))
+	ErrServiceAccountTokenExpired        = errutil.BadRequest("serviceaccounts.ErrTokenExpired", errutil.WithPublicMessage("service account token has expired"))
 )
 
 type MigrationResult struct {
@@ -100,6 +103,16 @@ type AddServiceAccountTokenCommand struct {
 	SecondsToLive int64  `json:"secondsToLive"`
 }
 
+// swagger:model
+type RotateServiceAccountTokenCommand struct {
+	// Name of the replacement token, defaults to the name of the rotated token with a timestamp suffix
+	Name string `json:"name"`
+	// SecondsToLive of the replacement token, defaults to the lifetime of the rotated token
+	SecondsToLive int64  `json:"secondsToLive"`
+	OrgId         int64  `json:"-"`
+	Key           string `json:"-"`
+}
+
 type SearchOrgServiceAccountsQuery struct {
 	OrgID        int64
 	Query        string
@@ -0,0 +1,34 @@
+package migrations
+
+import . "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+
+func addAuditLogMigrations(mg *Migrator) {
+	auditLogV1 := Table{
+		Name: "audit_log",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "created", Type: DB_BigInt, Nullable: false},
+			{Name: "actor_id", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "actor_login", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "action", Type: DB_NVarchar, Length: 255, Nullable: false},
+			{Name: "resource_kind", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "resource_id", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "before_state", Type: DB_Text, Nullable: true},
+			{Name: "after_state", Type: DB_Text, Nullable: true},
+			{Name: "ip_address", Type: DB_NVarchar, Length: 50, Nullable: false},
+			{Name: "result", Type: DB_NVarchar, Length: 20, Nullable: false},
+			{Name: "status_code", Type: DB_Int, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"created"}},
+			{Cols: []string{"org_id", "created"}},
+			{Cols: []string{"resource_kind", "resource_id"}},
+		},
+	}
+
+	mg.AddMigration("create audit_log table", NewAddTableMigration(auditLogV1))
+	mg.AddMigration("add index audit_log.created", NewAddIndexMigration(auditLogV1, auditLogV1.Indices[0]))
+	mg.AddMigration("add index audit_log.org_id_created", NewAddIndexMigration(auditLogV1, auditLogV1.Indices[1]))
+	mg.AddMigration("add index audit_log.resource_kind_resource_id", NewAddIndexMigration(auditLogV1, auditLogV1.Indices[2]))
+}
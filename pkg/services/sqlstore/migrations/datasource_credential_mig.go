@@ -0,0 +1,22 @@
+package migrations
+
+import . "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+
+func addDataSourceCredentialMigrations(mg *Migrator) {
+	dataSourceCredentialUsageV1 := Table{
+		Name: "data_source_credential_usage",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "data_source_uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "credential", Type: DB_NVarchar, Length: 20, Nullable: false},
+			{Name: "last_used", Type: DB_BigInt, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id", "data_source_uid", "credential"}, Type: UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create data_source_credential_usage table", NewAddTableMigration(dataSourceCredentialUsageV1))
+	mg.AddMigration("add unique index data_source_credential_usage.org_id_data_source_uid_credential", NewAddIndexMigration(dataSourceCredentialUsageV1, dataSourceCredentialUsageV1.Indices[0]))
+}
@@ -40,3 +40,14 @@ func addLoginAttemptMigrations(mg *Migrator) {
 		"ip_address": "ip_address",
 	})
 }
+
+func addLoginAttemptIPAddressMigrations(mg *Migrator) {
+	// IPv6 addresses do not fit in 30 characters
+	mg.AddMigration("increase login_attempt.ip_address column length", NewRawSQLMigration("").
+		Postgres("ALTER TABLE login_attempt ALTER COLUMN ip_address TYPE VARCHAR(50);").
+		Mysql("ALTER TABLE login_attempt MODIFY ip_address VARCHAR(50) NOT NULL;"))
+
+	mg.AddMigration("add index login_attempt.ip_address", NewAddIndexMigration(Table{Name: "login_attempt"}, &Index{
+		Cols: []string{"ip_address"},
+	}))
+}
@@ -91,6 +91,12 @@ func (*OSSMigrations) AddMigration(mg *Migrator) {
 	AddExternalAlertmanagerToDatasourceMigration(mg)
 
 	addFolderMigrations(mg)
+	addTOTPMigrations(mg)
+	addLoginAttemptIPAddressMigrations(mg)
+	addAuditLogMigrations(mg)
+	addDataSourceHealthMigrations(mg)
+	addDataSourceCredentialMigrations(mg)
+
 	if mg.Cfg != nil && mg.Cfg.IsFeatureToggleEnabled != nil {
 		if mg.Cfg.IsFeatureToggleEnabled(featuremgmt.FlagExternalServiceAuth) {
 			oauthserver.AddMigration(mg)
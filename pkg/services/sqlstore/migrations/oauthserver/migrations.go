@@ -49,4 +49,41 @@ func AddMigration(mg *migrator.Migrator) {
 	mg.AddMigration("add unique index client_id", migrator.NewAddIndexMigration(clientTable, clientTable.Indices[0]))
 	mg.AddMigration("add unique index client_id service_account_id", migrator.NewAddIndexMigration(clientTable, clientTable.Indices[1]))
 	mg.AddMigration("add unique index name", migrator.NewAddIndexMigration(clientTable, clientTable.Indices[2]))
+
+	mg.AddMigration("add column is_public", migrator.NewAddColumnMigration(clientTable, &migrator.Column{
+		Name: "is_public", Type: migrator.DB_Bool, Nullable: false, Default: "0",
+	}))
+
+	sessionTable := migrator.Table{
+		Name: "oauth_session",
+		Columns: []*migrator.Column{
+			{Name: "id", Type: migrator.DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "kind", Type: migrator.DB_Varchar, Length: 20, Nullable: false},
+			{Name: "signature", Type: migrator.DB_Varchar, Length: 190, Nullable: false},
+			{Name: "request_id", Type: migrator.DB_Varchar, Length: 190, Nullable: false},
+			{Name: "client_id", Type: migrator.DB_Varchar, Length: 190, Nullable: false},
+			{Name: "requested_at", Type: migrator.DB_DateTime, Nullable: false},
+			{Name: "scopes", Type: migrator.DB_Text, Nullable: true},
+			{Name: "granted_scopes", Type: migrator.DB_Text, Nullable: true},
+			{Name: "requested_audience", Type: migrator.DB_Text, Nullable: true},
+			{Name: "granted_audience", Type: migrator.DB_Text, Nullable: true},
+			{Name: "form_data", Type: migrator.DB_Text, Nullable: true},
+			{Name: "session_data", Type: migrator.DB_Text, Nullable: false},
+			{Name: "active", Type: migrator.DB_Bool, Nullable: false},
+			{Name: "expires", Type: migrator.DB_BigInt, Nullable: false},
+		},
+		Indices: []*migrator.Index{
+			{Cols: []string{"kind", "signature"}, Type: migrator.UniqueIndex},
+			{Cols: []string{"kind", "request_id"}},
+			{Cols: []string{"expires"}},
+		},
+	}
+
+	// Session
+	mg.AddMigration("create oauth session table", migrator.NewAddTableMigration(sessionTable))
+
+	//-------  indexes ------------------
+	mg.AddMigration("add unique index oauth session kind signature", migrator.NewAddIndexMigration(sessionTable, sessionTable.Indices[0]))
+	mg.AddMigration("add index oauth session kind request_id", migrator.NewAddIndexMigration(sessionTable, sessionTable.Indices[1]))
+	mg.AddMigration("add index oauth session expires", migrator.NewAddIndexMigration(sessionTable, sessionTable.Indices[2]))
 }
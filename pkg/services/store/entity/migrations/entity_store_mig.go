@@ -180,6 +180,34 @@ func initEntityTables(mg *migrator.Migrator) {
 		},
 	})
 
+	// Append only log of the changes, used to replay events to watchers
+	tables = append(tables, migrator.Table{
+		Name: "entity_event",
+		Columns: []*migrator.Column{
+			{Name: "id", Type: migrator.DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "grn", Type: migrator.DB_NVarchar, Length: grnLength, Nullable: false},
+
+			// The entity identifier
+			{Name: "tenant_id", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "kind", Type: migrator.DB_NVarchar, Length: 255, Nullable: false},
+			{Name: "uid", Type: migrator.DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "folder", Type: migrator.DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "version", Type: migrator.DB_NVarchar, Length: 128, Nullable: false},
+
+			// EntityWatchResponse_Action
+			{Name: "action", Type: migrator.DB_Int, Nullable: false},
+			{Name: "labels", Type: migrator.DB_Text, Nullable: true}, // JSON object, kept for deleted entities
+
+			// Who changed what when
+			{Name: "event_ts", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "updated_by", Type: migrator.DB_NVarchar, Length: 190, Nullable: false},
+		},
+		Indices: []*migrator.Index{
+			{Cols: []string{"tenant_id", "event_ts"}},
+			{Cols: []string{"grn"}},
+		},
+	})
+
 	// Initialize all tables
 	for t := range tables {
 		mg.AddMigration("drop table "+tables[t].Name, migrator.NewDropTableMigration(tables[t].Name))
@@ -62,6 +62,10 @@ const (
 	// ExternalEntityReferenceRuntime_Transformer is a "type" under runtime
 	// UIDs include: joinByField, organize, seriesToColumns, etc
 	ExternalEntityReferenceRuntime_Transformer = "transformer"
+
+	// ExternalEntityReferenceDashboardLink is a "type" under the dashboard family for
+	// dashboards linked from dashboard or panel links
+	ExternalEntityReferenceDashboardLink = "link"
 )
 
 // EntityKindInfo describes information needed from the object store
@@ -0,0 +1,174 @@
+package sqlstash
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type labelOperator string
+
+const (
+	labelOpEquals       labelOperator = "="
+	labelOpNotEquals    labelOperator = "!="
+	labelOpIn           labelOperator = "in"
+	labelOpNotIn        labelOperator = "notin"
+	labelOpExists       labelOperator = "exists"
+	labelOpDoesNotExist labelOperator = "!"
+)
+
+var (
+	labelKeyPattern        = `([A-Za-z0-9][-A-Za-z0-9_./]*)`
+	labelSetRequirement    = regexp.MustCompile(`^` + labelKeyPattern + `\s+(in|notin)\s*\(([^()]*)\)$`)
+	labelOpRequirement     = regexp.MustCompile(`^` + labelKeyPattern + `\s*(==|!=|=)\s*([^,()!=]*)$`)
+	labelExistsRequirement = regexp.MustCompile(`^(!?)\s*` + labelKeyPattern + `$`)
+)
+
+// labelRequirement is a single term of a label selector
+type labelRequirement struct {
+	key      string
+	operator labelOperator
+	values   []string
+}
+
+// parseLabelSelector parses a kubernetes style label selector, eg:
+//
+//	env=prod,tier!=frontend,team in (a,b),region notin (eu),beta,!deprecated
+//
+// All the requirements must match.  Tags are labels with an empty value, so `beta`
+// matches all entities tagged with beta.
+func parseLabelSelector(selector string) ([]labelRequirement, error) {
+	var requirements []labelRequirement
+	for _, term := range splitLabelSelector(selector) {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			return nil, fmt.Errorf("invalid label selector %q: empty requirement", selector)
+		}
+
+		if m := labelSetRequirement.FindStringSubmatch(term); m != nil {
+			values := []string{}
+			for _, v := range strings.Split(m[3], ",") {
+				if v = strings.TrimSpace(v); v != "" {
+					values = append(values, v)
+				}
+			}
+			if len(values) == 0 {
+				return nil, fmt.Errorf("invalid label selector %q: empty set for %s", selector, m[1])
+			}
+			requirements = append(requirements, labelRequirement{key: m[1], operator: labelOperator(m[2]), values: values})
+			continue
+		}
+
+		if m := labelOpRequirement.FindStringSubmatch(term); m != nil {
+			op := labelOpEquals
+			if m[2] == "!=" {
+				op = labelOpNotEquals
+			}
+			requirements = append(requirements, labelRequirement{key: m[1], operator: op, values: []string{strings.TrimSpace(m[3])}})
+			continue
+		}
+
+		if m := labelExistsRequirement.FindStringSubmatch(term); m != nil {
+			op := labelOpExists
+			if m[1] == "!" {
+				op = labelOpDoesNotExist
+			}
+			requirements = append(requirements, labelRequirement{key: m[2], operator: op})
+			continue
+		}
+
+		return nil, fmt.Errorf("invalid label selector %q: unable to parse %q", selector, term)
+	}
+	return requirements, nil
+}
+
+// splitLabelSelector splits the selector on the commas outside of the value sets
+func splitLabelSelector(selector string) []string {
+	if strings.TrimSpace(selector) == "" {
+		return nil
+	}
+	var terms []string
+	depth := 0
+	start := 0
+	for i, c := range selector {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(terms, selector[start:])
+}
+
+// labelRequirements returns the requirements for the exact match labels and the label selector of a request
+func labelRequirements(labels map[string]string, selector string) ([]labelRequirement, error) {
+	requirements, err := parseLabelSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	// sorted to keep the generated queries stable
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		requirements = append(requirements, labelRequirement{key: k, operator: labelOpEquals, values: []string{labels[k]}})
+	}
+	return requirements, nil
+}
+
+// matches checks the requirement against the labels of an entity
+func (r labelRequirement) matches(labels map[string]string) bool {
+	value, found := labels[r.key]
+	switch r.operator {
+	case labelOpEquals:
+		return found && value == r.values[0]
+	case labelOpNotEquals:
+		return !found || value != r.values[0]
+	case labelOpIn:
+		return found && contains(r.values, value)
+	case labelOpNotIn:
+		return !found || !contains(r.values, value)
+	case labelOpExists:
+		return found
+	case labelOpDoesNotExist:
+		return !found
+	}
+	return false
+}
+
+// subquery returns a query selecting the grn of the entities matching (or not matching when negated) the requirement
+func (r labelRequirement) subquery() (query string, args []any, negated bool) {
+	args = []any{r.key}
+	switch r.operator {
+	case labelOpEquals, labelOpNotEquals:
+		args = append(args, r.values[0])
+		return "SELECT grn FROM entity_labels WHERE label=? AND value=?", args, r.operator == labelOpNotEquals
+	case labelOpIn, labelOpNotIn:
+		for _, v := range r.values {
+			args = append(args, v)
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(r.values)), ",")
+		return "SELECT grn FROM entity_labels WHERE label=? AND value IN (" + placeholders + ")", args, r.operator == labelOpNotIn
+	default:
+		return "SELECT grn FROM entity_labels WHERE label=?", args, r.operator == labelOpDoesNotExist
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
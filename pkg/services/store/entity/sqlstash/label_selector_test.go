@@ -0,0 +1,56 @@
+package sqlstash
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLabelSelector(t *testing.T) {
+	requirements, err := parseLabelSelector("env=prod, tier != frontend,team in (a, b),region notin (eu),beta,!deprecated,app==grafana")
+	require.NoError(t, err)
+	require.Equal(t, []labelRequirement{
+		{key: "env", operator: labelOpEquals, values: []string{"prod"}},
+		{key: "tier", operator: labelOpNotEquals, values: []string{"frontend"}},
+		{key: "team", operator: labelOpIn, values: []string{"a", "b"}},
+		{key: "region", operator: labelOpNotIn, values: []string{"eu"}},
+		{key: "beta", operator: labelOpExists},
+		{key: "deprecated", operator: labelOpDoesNotExist},
+		{key: "app", operator: labelOpEquals, values: []string{"grafana"}},
+	}, requirements)
+
+	requirements, err = parseLabelSelector("")
+	require.NoError(t, err)
+	require.Empty(t, requirements)
+
+	for _, invalid := range []string{"a,,b", "a in b", "a in ()", "a=(b)", "=b", "a b"} {
+		_, err := parseLabelSelector(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestLabelRequirementMatches(t *testing.T) {
+	labels := map[string]string{"env": "prod", "team": "a", "beta": ""}
+	tests := []struct {
+		selector string
+		matches  bool
+	}{
+		{selector: "env=prod", matches: true},
+		{selector: "env!=prod", matches: false},
+		{selector: "tier!=frontend", matches: true},
+		{selector: "team in (a,b)", matches: true},
+		{selector: "team notin (a,b)", matches: false},
+		{selector: "region notin (eu)", matches: true},
+		{selector: "beta,!deprecated", matches: true},
+		{selector: "env=prod,deprecated", matches: false},
+	}
+	for _, tc := range tests {
+		requirements, err := parseLabelSelector(tc.selector)
+		require.NoError(t, err)
+		matches := true
+		for _, r := range requirements {
+			matches = matches && r.matches(labels)
+		}
+		require.Equal(t, tc.matches, matches, tc.selector)
+	}
+}
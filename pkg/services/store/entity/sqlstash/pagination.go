@@ -0,0 +1,78 @@
+package sqlstash
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// continueToken is the content of the opaque next_page_token returned by History and Search
+type continueToken struct {
+	// Number of items already returned
+	Offset int64 `json:"o"`
+}
+
+func (t continueToken) String() string {
+	b, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func parseContinueToken(token string) (continueToken, error) {
+	t := continueToken{}
+	if token == "" {
+		return t, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return t, fmt.Errorf("invalid next page token")
+	}
+	if err := json.Unmarshal(b, &t); err != nil || t.Offset < 0 {
+		return t, fmt.Errorf("invalid next page token")
+	}
+	return t, nil
+}
+
+// sortFields maps the fields accepted in search sort instructions to their column
+var sortFields = map[string]string{
+	"name":       "name",
+	"slug":       "slug",
+	"kind":       "kind",
+	"uid":        "uid",
+	"folder":     "folder",
+	"size":       "size",
+	"created_at": "created_at",
+	"createdAt":  "created_at",
+	"updated_at": "updated_at",
+	"updatedAt":  "updated_at",
+}
+
+// parseSort converts `field ASC/DESC` sort instructions to ORDER BY terms.  The grn is always
+// appended so the order, and with it the pagination, is stable
+func parseSort(sort []string) ([]string, error) {
+	orderBy := make([]string, 0, len(sort)+1)
+	for _, s := range sort {
+		parts := strings.Fields(s)
+		if len(parts) == 0 || len(parts) > 2 {
+			return nil, fmt.Errorf("invalid sort %q", s)
+		}
+		field := parts[0]
+		direction := "ASC"
+		if strings.HasPrefix(field, "-") {
+			field = field[1:]
+			direction = "DESC"
+		}
+		if len(parts) == 2 {
+			direction = strings.ToUpper(parts[1])
+			if direction != "ASC" && direction != "DESC" {
+				return nil, fmt.Errorf("invalid sort direction %q", parts[1])
+			}
+		}
+		column, ok := sortFields[field]
+		if !ok {
+			return nil, fmt.Errorf("unsupported sort field %q", field)
+		}
+		orderBy = append(orderBy, column+" "+direction)
+	}
+	return append(orderBy, "grn ASC"), nil
+}
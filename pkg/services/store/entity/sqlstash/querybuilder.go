@@ -6,7 +6,9 @@ type selectQuery struct {
 	fields   []string // SELECT xyz
 	from     string   // FROM object
 	limit    int64
+	offset   int64
 	oneExtra bool
+	orderBy  []string // ORDER BY xyz
 
 	where []string
 	args  []any
@@ -17,11 +19,21 @@ func (q *selectQuery) addWhere(f string, val any) {
 	q.where = append(q.where, f+"=?")
 }
 
+func (q *selectQuery) addWhereGreaterThan(f string, val any) {
+	q.args = append(q.args, val)
+	q.where = append(q.where, f+">?")
+}
+
 func (q *selectQuery) addWhereInSubquery(f string, subquery string, subqueryArgs []any) {
 	q.args = append(q.args, subqueryArgs...)
 	q.where = append(q.where, f+" IN ("+subquery+")")
 }
 
+func (q *selectQuery) addWhereNotInSubquery(f string, subquery string, subqueryArgs []any) {
+	q.args = append(q.args, subqueryArgs...)
+	q.where = append(q.where, f+" NOT IN ("+subquery+")")
+}
+
 func (q *selectQuery) addWhereIn(f string, vals []string) {
 	count := len(vals)
 	if count > 1 {
@@ -62,6 +74,11 @@ func (q *selectQuery) toQuery() (string, []any) {
 		}
 	}
 
+	if len(q.orderBy) > 0 {
+		sb.WriteString(" ORDER BY ")
+		sb.WriteString(strings.Join(q.orderBy, ","))
+	}
+
 	if q.limit > 0 || q.oneExtra {
 		limit := q.limit
 		if limit < 1 {
@@ -73,6 +90,11 @@ func (q *selectQuery) toQuery() (string, []any) {
 		}
 		sb.WriteString(" LIMIT ?")
 		args = append(args, limit)
+
+		if q.offset > 0 {
+			sb.WriteString(" OFFSET ?")
+			args = append(args, q.offset)
+		}
 	}
 	return sb.String(), args
 }
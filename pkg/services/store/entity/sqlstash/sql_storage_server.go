@@ -33,6 +33,8 @@ func ProvideSQLEntityServer(db db.DB, cfg *setting.Cfg, grpcServerProvider grpcs
 		log:      log.New("sql-entity-server"),
 		kinds:    kinds,
 		resolver: resolver,
+
+		watchPollInterval: defaultWatchPollInterval,
 	}
 	entity.RegisterEntityStoreServer(grpcServerProvider.GetServer(), entityServer)
 	return entityServer
@@ -43,8 +45,14 @@ type sqlEntityServer struct {
 	sess     *session.SessionDB
 	kinds    kind.KindRegistry
 	resolver resolver.EntityReferenceResolver
+
+	// how often watchers check for new events
+	watchPollInterval time.Duration
 }
 
+// maxHistoryLimit is the maximum number of versions returned by a single History request
+const maxHistoryLimit = 100
+
 func getReadSelect(r *entity.ReadEntityRequest) string {
 	fields := []string{
 		"tenant_id", "kind", "uid", "folder", // GRN + folder
@@ -406,14 +414,14 @@ func (s *sqlEntityServer) AdminWrite(ctx context.Context, r *entity.AdminWriteEn
 			rsp.Status = entity.WriteEntityResponse_UPDATED
 			_, err = tx.Exec(ctx, "UPDATE entity SET "+
 				"body=?, size=?, etag=?, version=?, "+
-				"updated_at=?, updated_by=?,"+
-				"name=?, description=?,"+
+				"updated_at=?, updated_by=?, folder=?, "+
+				"name=?, description=?, slug=?, "+
 				"labels=?, fields=?, errors=?, "+
 				"origin=?, origin_key=?, origin_ts=? "+
 				"WHERE grn=?",
 				body, versionInfo.Size, etag, versionInfo.Version,
-				updatedAt, versionInfo.UpdatedBy,
-				summary.model.Name, summary.model.Description,
+				updatedAt, versionInfo.UpdatedBy, r.Folder,
+				summary.model.Name, summary.model.Description, summary.model.Slug,
 				summary.labels, summary.fields, summary.errors,
 				origin.Source, origin.Key, timestamp,
 				oid,
@@ -453,7 +461,21 @@ func (s *sqlEntityServer) AdminWrite(ctx context.Context, r *entity.AdminWriteEn
 		if err == nil {
 			summary.folder = r.Folder
 			summary.parent_grn = grn
-			return s.writeSearchInfo(ctx, tx, oid, summary)
+			err = s.writeSearchInfo(ctx, tx, oid, summary)
+		}
+		if err == nil {
+			action := entity.EntityWatchResponse_CREATED
+			if isUpdate {
+				action = entity.EntityWatchResponse_UPDATED
+			}
+			err = writeEvent(ctx, tx, &entityEvent{
+				grn:       grn,
+				folder:    r.Folder,
+				version:   versionInfo.Version,
+				action:    action,
+				labels:    summary.labels,
+				updatedBy: updatedBy,
+			})
 		}
 		return err
 	})
@@ -631,9 +653,38 @@ func (s *sqlEntityServer) Delete(ctx context.Context, r *entity.DeleteEntityRequ
 		return nil, err
 	}
 
+	modifier, err := appcontext.User(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	rsp := &entity.DeleteEntityResponse{}
 	err = s.sess.WithTransaction(ctx, func(tx *session.SessionTx) error {
+		// keep the folder and labels of the deleted entity so watchers can filter the event
+		event := &entityEvent{
+			grn:       grn2,
+			action:    entity.EntityWatchResponse_DELETED,
+			updatedBy: store.GetUserIDString(modifier),
+		}
+		rows, err := tx.Query(ctx, "SELECT folder,version,labels FROM entity WHERE grn=?", grn2.ToGRNString())
+		if err != nil {
+			return err
+		}
+		found := rows.Next()
+		if found {
+			err = rows.Scan(&event.folder, &event.version, &event.labels)
+		}
+		if errClose := rows.Close(); err == nil {
+			err = errClose
+		}
+		if err != nil {
+			return err
+		}
+
 		rsp.OK, err = doDelete(ctx, tx, grn2)
+		if err == nil && found {
+			err = writeEvent(ctx, tx, event)
+		}
 		return err
 	})
 	return rsp, err
@@ -681,19 +732,27 @@ func (s *sqlEntityServer) History(ctx context.Context, r *entity.EntityHistoryRe
 	}
 	oid := grn2.ToGRNString()
 
-	page := ""
-	args := []any{oid}
-	if r.NextPageToken != "" {
-		// args = append(args, r.NextPageToken) // TODO, need to get time from the version
-		// page = "AND updated <= ?"
-		return nil, fmt.Errorf("next page not supported yet")
+	token, err := parseContinueToken(r.NextPageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := r.Limit
+	if limit < 1 || limit > maxHistoryLimit {
+		limit = maxHistoryLimit
 	}
 
-	query := "SELECT version,size,etag,updated_at,updated_by,message \n" +
-		" FROM entity_history \n" +
-		" WHERE grn=? " + page + "\n" +
-		" ORDER BY updated_at DESC LIMIT 100"
+	historyQuery := selectQuery{
+		fields:   []string{"version", "size", "etag", "updated_at", "updated_by", "message"},
+		from:     "entity_history",
+		limit:    limit,
+		offset:   token.Offset,
+		oneExtra: true, // request one more than the limit (and show next token if it exists)
+		orderBy:  []string{"updated_at DESC", "version DESC"},
+	}
+	historyQuery.addWhere("grn", oid)
 
+	query, args := historyQuery.toQuery()
 	rows, err := s.sess.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
@@ -703,6 +762,12 @@ func (s *sqlEntityServer) History(ctx context.Context, r *entity.EntityHistoryRe
 		GRN: r.GRN,
 	}
 	for rows.Next() {
+		// found one more than requested
+		if int64(len(rsp.Versions)) >= historyQuery.limit {
+			rsp.NextPageToken = continueToken{Offset: token.Offset + historyQuery.limit}.String()
+			break
+		}
+
 		v := &entity.EntityVersionInfo{}
 		err := rows.Scan(&v.Version, &v.Size, &v.ETag, &v.UpdatedAt, &v.UpdatedBy, &v.Comment)
 		if err != nil {
@@ -710,7 +775,7 @@ func (s *sqlEntityServer) History(ctx context.Context, r *entity.EntityHistoryRe
 		}
 		rsp.Versions = append(rsp.Versions, v)
 	}
-	return rsp, err
+	return rsp, rows.Err()
 }
 
 func (s *sqlEntityServer) Search(ctx context.Context, r *entity.EntitySearchRequest) (*entity.EntitySearchResponse, error) {
@@ -722,8 +787,17 @@ func (s *sqlEntityServer) Search(ctx context.Context, r *entity.EntitySearchRequ
 		return nil, fmt.Errorf("missing user in context")
 	}
 
-	if r.NextPageToken != "" || len(r.Sort) > 0 {
-		return nil, fmt.Errorf("not yet supported")
+	token, err := parseContinueToken(r.NextPageToken)
+	if err != nil {
+		return nil, err
+	}
+	orderBy, err := parseSort(r.Sort)
+	if err != nil {
+		return nil, err
+	}
+	requirements, err := labelRequirements(r.Labels, r.LabelSelector)
+	if err != nil {
+		return nil, err
 	}
 
 	fields := []string{
@@ -749,7 +823,9 @@ func (s *sqlEntityServer) Search(ctx context.Context, r *entity.EntitySearchRequ
 		from:     "entity", // the table
 		args:     []any{},
 		limit:    r.Limit,
+		offset:   token.Offset,
 		oneExtra: true, // request one more than the limit (and show next token if it exists)
+		orderBy:  orderBy,
 	}
 	entityQuery.addWhere("tenant_id", user.OrgID)
 
@@ -762,19 +838,13 @@ func (s *sqlEntityServer) Search(ctx context.Context, r *entity.EntitySearchRequ
 		entityQuery.addWhere("folder", r.Folder)
 	}
 
-	if len(r.Labels) > 0 {
-		var args []any
-		var conditions []string
-		for labelKey, labelValue := range r.Labels {
-			args = append(args, labelKey)
-			args = append(args, labelValue)
-			conditions = append(conditions, "(label = ? AND value = ?)")
+	for _, requirement := range requirements {
+		subquery, args, negated := requirement.subquery()
+		if negated {
+			entityQuery.addWhereNotInSubquery("grn", subquery, args)
+		} else {
+			entityQuery.addWhereInSubquery("grn", subquery, args)
 		}
-		joinedConditions := strings.Join(conditions, " OR ")
-		query := "SELECT grn FROM entity_labels WHERE " + joinedConditions + " GROUP BY grn HAVING COUNT(label) = ?"
-		args = append(args, len(r.Labels))
-
-		entityQuery.addWhereInSubquery("grn", query, args)
 	}
 
 	query, args := entityQuery.toQuery()
@@ -815,8 +885,7 @@ func (s *sqlEntityServer) Search(ctx context.Context, r *entity.EntitySearchRequ
 
 		// found one more than requested
 		if int64(len(rsp.Results)) >= entityQuery.limit {
-			// TODO? should this encode start+offset?
-			rsp.NextPageToken = oid
+			rsp.NextPageToken = continueToken{Offset: token.Offset + entityQuery.limit}.String()
 			break
 		}
 
@@ -845,7 +914,3 @@ func (s *sqlEntityServer) Search(ctx context.Context, r *entity.EntitySearchRequ
 
 	return rsp, err
 }
-
-func (s *sqlEntityServer) Watch(*entity.EntityWatchRequest, entity.EntityStore_WatchServer) error {
-	return fmt.Errorf("unimplemented")
-}
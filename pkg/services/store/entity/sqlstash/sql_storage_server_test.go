@@ -0,0 +1,237 @@
+package sqlstash
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/grafana/grafana/pkg/infra/appcontext"
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/grn"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/store/entity"
+	"github.com/grafana/grafana/pkg/services/store/entity/migrations"
+	"github.com/grafana/grafana/pkg/services/store/kind"
+	"github.com/grafana/grafana/pkg/services/store/resolver"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+type fakeResolver struct{}
+
+func (f *fakeResolver) Resolve(ctx context.Context, ref *entity.EntityExternalReference) (resolver.ResolutionInfo, error) {
+	return resolver.ResolutionInfo{OK: true, Timestamp: time.Now()}, nil
+}
+
+func setupTestServer(t *testing.T) (*sqlEntityServer, context.Context) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	sqlStore := db.InitTestDB(t)
+	err := migrations.MigrateEntityStore(sqlStore, featuremgmt.WithFeatures(featuremgmt.FlagEntityStore))
+	require.NoError(t, err)
+
+	s := &sqlEntityServer{
+		sess:              sqlStore.GetSqlxSession(),
+		log:               log.NewNopLogger(),
+		kinds:             kind.NewKindRegistry(),
+		resolver:          &fakeResolver{},
+		watchPollInterval: 10 * time.Millisecond,
+	}
+	ctx := appcontext.WithUser(context.Background(), &user.SignedInUser{UserID: 1, OrgID: 1, Login: "admin"})
+	return s, ctx
+}
+
+func dashboardGRN(uid string) *grn.GRN {
+	return &grn.GRN{TenantID: 1, ResourceKind: entity.StandardKindDashboard, ResourceIdentifier: uid}
+}
+
+func writeDashboard(t *testing.T, ctx context.Context, s *sqlEntityServer, uid, folder, title string, tags ...string) *entity.WriteEntityResponse {
+	t.Helper()
+	body, err := json.Marshal(map[string]any{"title": title, "tags": tags, "schemaVersion": 38})
+	require.NoError(t, err)
+	rsp, err := s.Write(ctx, &entity.WriteEntityRequest{GRN: dashboardGRN(uid), Folder: folder, Body: body})
+	require.NoError(t, err)
+	return rsp
+}
+
+func TestIntegrationHistory(t *testing.T) {
+	s, ctx := setupTestServer(t)
+
+	for i := 1; i <= 5; i++ {
+		rsp := writeDashboard(t, ctx, s, "history", "", fmt.Sprintf("Version %d", i))
+		require.Equal(t, fmt.Sprintf("%d", i), rsp.Entity.Version)
+	}
+
+	versions := []string{}
+	token := ""
+	for page := 0; page < 5; page++ {
+		rsp, err := s.History(ctx, &entity.EntityHistoryRequest{GRN: dashboardGRN("history"), Limit: 2, NextPageToken: token})
+		require.NoError(t, err)
+		for _, v := range rsp.Versions {
+			versions = append(versions, v.Version)
+		}
+		token = rsp.NextPageToken
+		if token == "" {
+			break
+		}
+	}
+	require.ElementsMatch(t, []string{"1", "2", "3", "4", "5"}, versions)
+
+	// every version can be read back
+	old, err := s.Read(ctx, &entity.ReadEntityRequest{GRN: dashboardGRN("history"), Version: "2", WithBody: true})
+	require.NoError(t, err)
+	require.Contains(t, string(old.Body), "Version 2")
+
+	_, err = s.History(ctx, &entity.EntityHistoryRequest{GRN: dashboardGRN("history"), NextPageToken: "not a token"})
+	require.Error(t, err)
+}
+
+func TestIntegrationSearch(t *testing.T) {
+	s, ctx := setupTestServer(t)
+
+	_, err := s.Write(ctx, &entity.WriteEntityRequest{
+		GRN:  &grn.GRN{ResourceKind: entity.StandardKindFolder, ResourceIdentifier: "ops"},
+		Body: []byte(`{"title": "Ops"}`),
+	})
+	require.NoError(t, err)
+	_, err = s.Write(ctx, &entity.WriteEntityRequest{
+		GRN:  &grn.GRN{ResourceKind: entity.StandardKindPlaylist, ResourceIdentifier: "tv"},
+		Body: []byte(`{"name": "TV", "interval": "5m", "items": [{"type": "dashboard_by_tag", "value": "prod"}]}`),
+	})
+	require.NoError(t, err)
+	writeDashboard(t, ctx, s, "a", "ops", "A", "prod", "team-a")
+	writeDashboard(t, ctx, s, "b", "ops", "B", "prod", "beta")
+	writeDashboard(t, ctx, s, "c", "", "C", "dev")
+	writeDashboard(t, ctx, s, "d", "", "D")
+
+	search := func(t *testing.T, r *entity.EntitySearchRequest) []string {
+		t.Helper()
+		rsp, err := s.Search(ctx, r)
+		require.NoError(t, err)
+		uids := []string{}
+		for _, res := range rsp.Results {
+			uids = append(uids, res.GRN.ResourceIdentifier)
+		}
+		return uids
+	}
+
+	t.Run("kinds", func(t *testing.T) {
+		require.Equal(t, []string{"tv"}, search(t, &entity.EntitySearchRequest{Kind: []string{entity.StandardKindPlaylist}}))
+		require.Equal(t, []string{"ops"}, search(t, &entity.EntitySearchRequest{Kind: []string{entity.StandardKindFolder}}))
+	})
+
+	t.Run("label selector", func(t *testing.T) {
+		dashboards := []string{entity.StandardKindDashboard}
+		require.Equal(t, []string{"a", "b"}, search(t, &entity.EntitySearchRequest{Kind: dashboards, LabelSelector: "prod"}))
+		require.Equal(t, []string{"a"}, search(t, &entity.EntitySearchRequest{Kind: dashboards, LabelSelector: "prod,!beta"}))
+		require.Equal(t, []string{"c", "d"}, search(t, &entity.EntitySearchRequest{Kind: dashboards, LabelSelector: "!prod"}))
+		require.Equal(t, []string{"b"}, search(t, &entity.EntitySearchRequest{Kind: dashboards, LabelSelector: "prod=,team-a!="}))
+		require.Equal(t, []string{"a", "b", "tv"}, search(t, &entity.EntitySearchRequest{Labels: map[string]string{"prod": ""}}))
+
+		_, err := s.Search(ctx, &entity.EntitySearchRequest{LabelSelector: "a in b"})
+		require.Error(t, err)
+	})
+
+	t.Run("sort and pagination", func(t *testing.T) {
+		uids := []string{}
+		token := ""
+		for page := 0; page < 5; page++ {
+			rsp, err := s.Search(ctx, &entity.EntitySearchRequest{
+				Kind:          []string{entity.StandardKindDashboard},
+				Sort:          []string{"name DESC"},
+				Limit:         3,
+				NextPageToken: token,
+			})
+			require.NoError(t, err)
+			for _, res := range rsp.Results {
+				uids = append(uids, res.GRN.ResourceIdentifier)
+			}
+			token = rsp.NextPageToken
+			if token == "" {
+				break
+			}
+		}
+		require.Equal(t, []string{"d", "c", "b", "a"}, uids)
+
+		_, err := s.Search(ctx, &entity.EntitySearchRequest{Sort: []string{"body"}})
+		require.Error(t, err)
+	})
+
+	t.Run("moved to another folder", func(t *testing.T) {
+		writeDashboard(t, ctx, s, "d", "ops", "D (moved)")
+		require.Equal(t, []string{"a", "b", "d"}, search(t, &entity.EntitySearchRequest{Kind: []string{entity.StandardKindDashboard}, Folder: "ops"}))
+	})
+}
+
+type fakeWatchServer struct {
+	grpc.ServerStream
+	ctx    context.Context
+	events chan *entity.EntityWatchResponse
+}
+
+func (f *fakeWatchServer) Context() context.Context { return f.ctx }
+
+func (f *fakeWatchServer) Send(rsp *entity.EntityWatchResponse) error {
+	f.events <- rsp
+	return nil
+}
+
+func TestIntegrationWatch(t *testing.T) {
+	s, ctx := setupTestServer(t)
+
+	// changes made before the watch started are replayed
+	start := time.Now().UnixMilli() - 1
+	writeDashboard(t, ctx, s, "before", "", "Before", "prod")
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	server := &fakeWatchServer{ctx: watchCtx, events: make(chan *entity.EntityWatchResponse, 10)}
+	done := make(chan error)
+	go func() {
+		done <- s.Watch(&entity.EntityWatchRequest{Since: start, LabelSelector: "prod", WithBody: true}, server)
+	}()
+
+	next := func(t *testing.T) *entity.EntityWatchResponse {
+		t.Helper()
+		select {
+		case rsp := <-server.events:
+			require.Len(t, rsp.Entity, 1)
+			return rsp
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for event")
+			return nil
+		}
+	}
+
+	rsp := next(t)
+	require.Equal(t, entity.EntityWatchResponse_CREATED, rsp.Action)
+	require.Equal(t, "before", rsp.Entity[0].GRN.ResourceIdentifier)
+	require.Contains(t, string(rsp.Entity[0].Body), "Before")
+
+	writeDashboard(t, ctx, s, "ignored", "", "Ignored", "dev")
+	writeDashboard(t, ctx, s, "before", "", "Updated", "prod")
+
+	rsp = next(t)
+	require.Equal(t, entity.EntityWatchResponse_UPDATED, rsp.Action)
+	require.Equal(t, "2", rsp.Entity[0].Version)
+	require.Contains(t, string(rsp.Entity[0].Body), "Updated")
+
+	_, err := s.Delete(ctx, &entity.DeleteEntityRequest{GRN: dashboardGRN("before")})
+	require.NoError(t, err)
+
+	rsp = next(t)
+	require.Equal(t, entity.EntityWatchResponse_DELETED, rsp.Action)
+	require.Equal(t, "before", rsp.Entity[0].GRN.ResourceIdentifier)
+	require.Empty(t, rsp.Entity[0].Body)
+
+	cancel()
+	require.NoError(t, <-done)
+	require.Empty(t, server.events)
+}
@@ -0,0 +1,233 @@
+package sqlstash
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/appcontext"
+	"github.com/grafana/grafana/pkg/infra/grn"
+	"github.com/grafana/grafana/pkg/services/sqlstore/session"
+	"github.com/grafana/grafana/pkg/services/store/entity"
+)
+
+const (
+	defaultWatchPollInterval = time.Second
+
+	// maximum number of events read from the database at once
+	watchBatchSize = 100
+)
+
+// entityEvent is a row of the entity_event table.  Events are written in the same transaction
+// as the change, so watchers on any instance see every committed change.
+type entityEvent struct {
+	id        int64
+	grn       *grn.GRN
+	folder    string
+	version   string
+	action    entity.EntityWatchResponse_Action
+	labels    *string
+	timestamp int64
+	updatedBy string
+}
+
+func writeEvent(ctx context.Context, tx *session.SessionTx, e *entityEvent) error {
+	if e.timestamp == 0 {
+		e.timestamp = time.Now().UnixMilli()
+	}
+	_, err := tx.Exec(ctx, "INSERT INTO entity_event ("+
+		"grn, tenant_id, kind, uid, folder, version, "+
+		"action, labels, event_ts, updated_by) "+
+		"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		e.grn.ToGRNString(), e.grn.TenantID, e.grn.ResourceKind, e.grn.ResourceIdentifier, e.folder, e.version,
+		int64(e.action), e.labels, e.timestamp, e.updatedBy,
+	)
+	return err
+}
+
+// Watch streams the changes matching the request until the client disconnects.  When `since` is set,
+// the changes made after that time are replayed first, otherwise only new changes are sent.
+func (s *sqlEntityServer) Watch(r *entity.EntityWatchRequest, w entity.EntityStore_WatchServer) error {
+	ctx := w.Context()
+	user, err := appcontext.User(ctx)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return fmt.Errorf("missing user in context")
+	}
+
+	requirements, err := labelRequirements(r.Labels, r.LabelSelector)
+	if err != nil {
+		return err
+	}
+
+	grns := make([]string, 0, len(r.GRN))
+	for _, g := range r.GRN {
+		g, err := s.validateGRN(ctx, g)
+		if err != nil {
+			return err
+		}
+		grns = append(grns, g.ToGRNString())
+	}
+
+	// start after the latest event unless a replay is requested
+	lastID := int64(0)
+	if r.Since < 1 {
+		if lastID, err = s.lastEventID(ctx); err != nil {
+			return err
+		}
+	}
+
+	ticker := time.NewTicker(s.watchPollInterval)
+	defer ticker.Stop()
+	for {
+		for {
+			events, err := s.readEvents(ctx, r, user.OrgID, grns, lastID)
+			if err != nil {
+				return err
+			}
+
+			for _, e := range events {
+				lastID = e.id
+				if !e.matches(requirements) {
+					continue
+				}
+				rsp, err := s.eventToWatchResponse(ctx, r, e)
+				if err != nil {
+					return err
+				}
+				if err := w.Send(rsp); err != nil {
+					return err
+				}
+			}
+
+			if len(events) < watchBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *sqlEntityServer) lastEventID(ctx context.Context) (int64, error) {
+	var id sql.NullInt64
+	err := s.sess.Get(ctx, &id, "SELECT MAX(id) FROM entity_event")
+	return id.Int64, err
+}
+
+func (s *sqlEntityServer) readEvents(ctx context.Context, r *entity.EntityWatchRequest, tenantID int64, grns []string, lastID int64) ([]*entityEvent, error) {
+	eventQuery := selectQuery{
+		fields: []string{
+			"id", "tenant_id", "kind", "uid", "folder", "version",
+			"action", "labels", "event_ts", "updated_by",
+		},
+		from:    "entity_event",
+		limit:   watchBatchSize,
+		orderBy: []string{"id ASC"},
+	}
+	eventQuery.addWhere("tenant_id", tenantID)
+	eventQuery.addWhereGreaterThan("id", lastID)
+	if r.Since > 0 {
+		eventQuery.addWhereGreaterThan("event_ts", r.Since)
+	}
+	if len(grns) > 0 {
+		eventQuery.addWhereIn("grn", grns)
+	}
+	if len(r.Kind) > 0 {
+		eventQuery.addWhereIn("kind", r.Kind)
+	}
+	if r.Folder != "" {
+		eventQuery.addWhere("folder", r.Folder)
+	}
+
+	query, args := eventQuery.toQuery()
+	rows, err := s.sess.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var events []*entityEvent
+	for rows.Next() {
+		e := &entityEvent{grn: &grn.GRN{}}
+		var action int64
+		err := rows.Scan(
+			&e.id, &e.grn.TenantID, &e.grn.ResourceKind, &e.grn.ResourceIdentifier, &e.folder, &e.version,
+			&action, &e.labels, &e.timestamp, &e.updatedBy,
+		)
+		if err != nil {
+			return nil, err
+		}
+		e.action = entity.EntityWatchResponse_Action(action)
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (e *entityEvent) matches(requirements []labelRequirement) bool {
+	if len(requirements) == 0 {
+		return true
+	}
+	labels := map[string]string{}
+	if e.labels != nil {
+		if err := json.Unmarshal([]byte(*e.labels), &labels); err != nil {
+			return false
+		}
+	}
+	for _, requirement := range requirements {
+		if !requirement.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *sqlEntityServer) eventToWatchResponse(ctx context.Context, r *entity.EntityWatchRequest, e *entityEvent) (*entity.EntityWatchResponse, error) {
+	raw := &entity.Entity{}
+
+	// The body and summary come from the version in the history, the entity may have changed since
+	withSummary := r.WithLabels || r.WithFields
+	if e.action != entity.EntityWatchResponse_DELETED && (r.WithBody || withSummary) {
+		var err error
+		raw, err = s.readFromHistory(ctx, &entity.ReadEntityRequest{
+			GRN:         e.grn,
+			Version:     e.version,
+			WithBody:    r.WithBody,
+			WithSummary: withSummary,
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else if r.WithLabels && e.labels != nil {
+		summary := &entity.EntitySummary{}
+		if err := json.Unmarshal([]byte(*e.labels), &summary.Labels); err != nil {
+			return nil, err
+		}
+		js, err := json.Marshal(summary)
+		if err != nil {
+			return nil, err
+		}
+		raw.SummaryJson = js
+	}
+
+	raw.GRN = e.grn
+	raw.Version = e.version
+	raw.Folder = e.folder
+	raw.UpdatedAt = e.timestamp
+	raw.UpdatedBy = e.updatedBy
+
+	return &entity.EntityWatchResponse{
+		// the time of the change, can be used as `since` to resume watching
+		Timestamp: e.timestamp,
+		Entity:    []*entity.Entity{raw},
+		Action:    e.action,
+	}, nil
+}
@@ -27,6 +27,10 @@ const (
 	EntityTypeFolder    EntityType = "folder"
 	EntityTypeImage     EntityType = "image"
 	EntityTypeJSON      EntityType = "json"
+
+	EntityTypeAlertRule      EntityType = "alertrule"
+	EntityTypeLibraryElement EntityType = "libraryelement"
+	EntityTypeDatasource     EntityType = "datasource"
 )
 
 // CreateDatabaseEntityId creates entityId for entities stored in the existing SQL tables
@@ -42,6 +46,22 @@ func CreateDatabaseEntityId(internalId any, orgId int64, entityType EntityType)
 	return fmt.Sprintf("database/%d/%s/%s", orgId, entityType, internalIdAsString)
 }
 
+// NewDatabaseEntityEvent creates an event for an entity stored in the existing SQL tables. The
+// returned value is meant to be inserted within the same session that modified the entity.
+func NewDatabaseEntityEvent(internalId any, orgId int64, entityType EntityType, eventType EntityEventType) *EntityEvent {
+	return &EntityEvent{
+		EventType: eventType,
+		EntityId:  CreateDatabaseEntityId(internalId, orgId, entityType),
+		Created:   time.Now().Unix(),
+	}
+}
+
+// EntityEventsEnabled reports whether the changes of the entities stored in the existing SQL tables should be
+// recorded as entity events for the search index.
+func EntityEventsEnabled(features featuremgmt.FeatureToggles) bool {
+	return features != nil && features.IsEnabled(featuremgmt.FlagPanelTitleSearch)
+}
+
 type EntityEvent struct {
 	Id        int64
 	EventType EntityEventType
@@ -2,6 +2,7 @@ package dashboard
 
 import (
 	"io"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -174,7 +175,9 @@ func readDashboard(stream io.Reader, lookup DatasourceLookup) (*dashboardInfo, e
 
 		case "links":
 			for iter.ReadArray() {
-				iter.Skip()
+				if uid := readLinkedDashboardUID(iter); uid != "" {
+					dash.Links = append(dash.Links, uid)
+				}
 				dash.LinkCount++
 			}
 
@@ -428,6 +431,13 @@ func readpanelInfo(iter *jsoniter.Iterator, lookup DatasourceLookup) panelInfo {
 				}
 			}
 
+		case "links":
+			for iter.ReadArray() {
+				if uid := readLinkedDashboardUID(iter); uid != "" {
+					panel.Links = append(panel.Links, uid)
+				}
+			}
+
 		// Rows have nested panels
 		case "panels":
 			for iter.ReadArray() {
@@ -453,3 +463,26 @@ func readpanelInfo(iter *jsoniter.Iterator, lookup DatasourceLookup) panelInfo {
 
 	return panel
 }
+
+// dashboardURLRegex matches links to dashboards, i.e. `/d/<uid>/<slug>` or `/d-solo/<uid>/<slug>`
+var dashboardURLRegex = regexp.MustCompile(`(?:^|/)d(?:-solo)?/([a-zA-Z0-9_-]+)`)
+
+// readLinkedDashboardUID reads a dashboard or panel link and returns the UID of the linked dashboard, if any
+func readLinkedDashboardUID(iter *jsoniter.Iterator) string {
+	if iter.WhatIsNext() != jsoniter.ObjectValue {
+		iter.Skip()
+		return ""
+	}
+
+	uid := ""
+	for k := iter.ReadObject(); k != ""; k = iter.ReadObject() {
+		if k == "url" && iter.WhatIsNext() == jsoniter.StringValue {
+			if matches := dashboardURLRegex.FindStringSubmatch(iter.ReadString()); len(matches) == 2 {
+				uid = matches[1]
+			}
+		} else {
+			iter.Skip()
+		}
+	}
+	return uid
+}
@@ -92,6 +92,12 @@ func NewStaticDashboardSummaryBuilder(lookup DatasourceLookup, sanitize bool) en
 					dashboardRefs.Add(entity.ExternalEntityReferencePlugin, string(plugins.TypeDataSource), v.Type)
 				}
 			}
+			for _, v := range panel.Links {
+				// only tracked on the dashboard, panel references are used for datasource and plugin lookups
+				if v != uid {
+					dashboardRefs.Add(entity.StandardKindDashboard, entity.ExternalEntityReferenceDashboardLink, v)
+				}
+			}
 			for _, v := range panel.Transformer {
 				panelRefs.Add(entity.ExternalEntityReferenceRuntime, entity.ExternalEntityReferenceRuntime_Transformer, v)
 				dashboardRefs.Add(entity.ExternalEntityReferenceRuntime, entity.ExternalEntityReferenceRuntime_Transformer, v)
@@ -100,6 +106,12 @@ func NewStaticDashboardSummaryBuilder(lookup DatasourceLookup, sanitize bool) en
 			summary.Nested = append(summary.Nested, p)
 		}
 
+		for _, v := range dash.Links {
+			if v != uid {
+				dashboardRefs.Add(entity.StandardKindDashboard, entity.ExternalEntityReferenceDashboardLink, v)
+			}
+		}
+
 		summary.References = dashboardRefs.Get()
 		if sanitize {
 			body, err = json.MarshalIndent(parsed, "", "  ")
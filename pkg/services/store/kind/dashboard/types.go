@@ -9,6 +9,7 @@ type panelInfo struct {
 	LibraryPanel  string          `json:"libraryPanel,omitempty"` // UID of referenced library panel
 	Datasource    []DataSourceRef `json:"datasource,omitempty"`   // UIDs
 	Transformer   []string        `json:"transformer,omitempty"`  // ids of the transformation steps
+	Links         []string        `json:"links,omitempty"`        // UIDs of dashboards linked from the panel
 	// Rows define panels as sub objects
 	Collapsed []panelInfo `json:"collapsed,omitempty"`
 }
@@ -24,6 +25,7 @@ type dashboardInfo struct {
 	Panels        []panelInfo     `json:"panels"`                 // nesed documents
 	SchemaVersion int64           `json:"schemaVersion"`
 	LinkCount     int64           `json:"linkCount"`
+	Links         []string        `json:"links,omitempty"` // UIDs of dashboards linked from the dashboard
 	TimeFrom      string          `json:"timeFrom"`
 	TimeTo        string          `json:"timeTo"`
 	TimeZone      string          `json:"timezone"`
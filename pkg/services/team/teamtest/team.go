@@ -14,6 +14,7 @@ type FakeService struct {
 	ExpectedTeamDTO     *team.TeamDTO
 	ExpectedTeamsByUser []*team.TeamDTO
 	ExpectedMembers     []*team.TeamMemberDTO
+	ExpectedSearchTeams team.SearchTeamQueryResult
 	ExpectedError       error
 }
 
@@ -34,7 +35,7 @@ func (s *FakeService) DeleteTeam(ctx context.Context, cmd *team.DeleteTeamComman
 }
 
 func (s *FakeService) SearchTeams(ctx context.Context, query *team.SearchTeamsQuery) (team.SearchTeamQueryResult, error) {
-	return team.SearchTeamQueryResult{}, s.ExpectedError
+	return s.ExpectedSearchTeams, s.ExpectedError
 }
 
 func (s *FakeService) GetTeamByID(ctx context.Context, query *team.GetTeamByIDQuery) (*team.TeamDTO, error) {
@@ -0,0 +1,89 @@
+package totp
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/util/errutil"
+)
+
+// AppPasswordPrefix is the prefix of every app password, it allows to skip the lookup for regular passwords.
+const AppPasswordPrefix = "glap_"
+
+// RoleGrafanaAdmin can be used in the enforced roles to require a second factor for server admins.
+const RoleGrafanaAdmin = "GrafanaAdmin"
+
+var (
+	ErrDisabled           = errutil.BadRequest("totp.disabled", errutil.WithPublicMessage("Two-factor authentication is not enabled"))
+	ErrAlreadyEnrolled    = errutil.BadRequest("totp.already-enrolled", errutil.WithPublicMessage("Two-factor authentication is already enabled"))
+	ErrNotEnrolled        = errutil.BadRequest("totp.not-enrolled", errutil.WithPublicMessage("Two-factor authentication is not enabled for the user"))
+	ErrRequired           = errutil.Forbidden("totp.required", errutil.WithPublicMessage("Two-factor authentication is required and can not be disabled"))
+	ErrCodeRequired       = errutil.Unauthorized("totp.code-required", errutil.WithPublicMessage("Two-factor authentication code required"))
+	ErrInvalidCode        = errutil.Unauthorized("totp.invalid-code", errutil.WithPublicMessage("Invalid two-factor authentication code"))
+	ErrEnrollmentRequired = errutil.Unauthorized("totp.enrollment-required", errutil.WithPublicMessage("Two-factor authentication must be set up before logging in"))
+	ErrAppPasswordInvalid = errutil.BadRequest("totp.app-password-invalid", errutil.WithPublicMessage("Invalid app password name"))
+	ErrAppPasswordExists  = errutil.BadRequest("totp.app-password-exists", errutil.WithPublicMessage("An app password with this name already exists"))
+	ErrAppPasswordMissing = errutil.NotFound("totp.app-password-not-found", errutil.WithPublicMessage("App password not found"))
+)
+
+// Service manages the time-based one-time passwords (RFC 6238) used as second factor by
+// Grafana-managed users, their recovery codes and the app passwords used for basic auth.
+type Service interface {
+	// IsEnabled returns true when users can enroll a second factor.
+	IsEnabled() bool
+	// GetStatus returns the two-factor authentication status of the user.
+	GetStatus(ctx context.Context, userID int64, isGrafanaAdmin bool) (*Status, error)
+	// RequiresSecondFactor returns true when the user has a second factor or is required to have one.
+	RequiresSecondFactor(ctx context.Context, userID int64, isGrafanaAdmin bool) (bool, error)
+	// Enroll creates a new secret and recovery codes for the user. They are pending
+	// until a code generated from the secret is confirmed.
+	Enroll(ctx context.Context, userID int64, login string) (*Enrollment, error)
+	// Confirm enables the pending enrollment of the user.
+	Confirm(ctx context.Context, userID int64, code string) error
+	// Disable removes the second factor of the user.
+	Disable(ctx context.Context, userID int64) error
+	// VerifyLogin checks the second factor of a user logging in. The code can be a time-based
+	// one-time password or an unused recovery code, a valid code confirms a pending enrollment.
+	VerifyLogin(ctx context.Context, userID int64, isGrafanaAdmin bool, code string) error
+	// Verify checks a code of a user with an enabled second factor.
+	Verify(ctx context.Context, userID int64, code string) error
+	// RegenerateRecoveryCodes replaces the recovery codes of the user.
+	RegenerateRecoveryCodes(ctx context.Context, userID int64) ([]string, error)
+
+	// CreateAppPassword creates a password which can be used for basic auth instead of the user password.
+	CreateAppPassword(ctx context.Context, userID int64, name string) (*NewAppPassword, error)
+	// ListAppPasswords returns the app passwords of the user.
+	ListAppPasswords(ctx context.Context, userID int64) ([]*AppPassword, error)
+	// DeleteAppPassword removes an app password of the user.
+	DeleteAppPassword(ctx context.Context, userID, id int64) error
+	// AuthenticateAppPassword returns true when the password is an app password of the user.
+	AuthenticateAppPassword(ctx context.Context, userID int64, password string) (bool, error)
+}
+
+type Status struct {
+	Enabled                bool `json:"enabled"`
+	Pending                bool `json:"pending"`
+	Required               bool `json:"required"`
+	RecoveryCodesRemaining int  `json:"recoveryCodesRemaining"`
+}
+
+type Enrollment struct {
+	// Secret is the base32 encoded secret to enter in an authenticator app
+	Secret string `json:"secret"`
+	// URL is the otpauth:// key URI, usually shown as a QR code
+	URL           string   `json:"url"`
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+type AppPassword struct {
+	ID       int64      `json:"id"`
+	Name     string     `json:"name"`
+	Created  time.Time  `json:"created"`
+	LastUsed *time.Time `json:"lastUsed,omitempty"`
+}
+
+type NewAppPassword struct {
+	AppPassword
+	// Password is only returned on creation
+	Password string `json:"password"`
+}
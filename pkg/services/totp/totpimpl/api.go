@@ -0,0 +1,303 @@
+package totpimpl
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/middleware"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/totp"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/util/errutil"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+var errInvalidCredentials = errutil.Unauthorized("totp.invalid-credentials", errutil.WithPublicMessage("Invalid username or password"))
+
+func (s *Service) registerAPIEndpoints() {
+	s.routeRegister.Group("/api/user/totp", func(totpRoute routing.RouteRegister) {
+		totpRoute.Get("/", routing.Wrap(s.getStatusHandler))
+		totpRoute.Post("/enroll", routing.Wrap(s.enrollHandler))
+		totpRoute.Post("/confirm", routing.Wrap(s.confirmHandler))
+		totpRoute.Post("/disable", routing.Wrap(s.disableHandler))
+		totpRoute.Post("/recovery-codes", routing.Wrap(s.recoveryCodesHandler))
+	}, middleware.ReqSignedInNoAnonymous)
+
+	s.routeRegister.Group("/api/user/app-passwords", func(appPasswordRoute routing.RouteRegister) {
+		appPasswordRoute.Get("/", routing.Wrap(s.listAppPasswordsHandler))
+		appPasswordRoute.Post("/", routing.Wrap(s.createAppPasswordHandler))
+		appPasswordRoute.Delete("/:id", routing.Wrap(s.deleteAppPasswordHandler))
+	}, middleware.ReqSignedInNoAnonymous)
+
+	// users required to use a second factor enroll before their first login
+	s.routeRegister.Post("/api/login/totp/enroll", routing.Wrap(s.loginEnrollHandler))
+	s.routeRegister.Delete("/api/admin/users/:id/totp", middleware.ReqGrafanaAdmin, routing.Wrap(s.adminDisableHandler))
+}
+
+type CodeForm struct {
+	Code string `json:"code"`
+}
+
+type LoginEnrollForm struct {
+	User     string `json:"user" binding:"Required"`
+	Password string `json:"password" binding:"Required"`
+}
+
+type CreateAppPasswordForm struct {
+	Name string `json:"name" binding:"Required"`
+}
+
+// swagger:route GET /user/totp signed_in_user getTOTPStatus
+//
+// Get the two-factor authentication status of the signed in user.
+//
+// Responses:
+// 200: totpStatusResponse
+// 401: unauthorisedError
+// 500: internalServerError
+func (s *Service) getStatusHandler(c *contextmodel.ReqContext) response.Response {
+	status, err := s.GetStatus(c.Req.Context(), c.SignedInUser.UserID, c.SignedInUser.IsGrafanaAdmin)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to get two-factor authentication status", err)
+	}
+	return response.JSON(http.StatusOK, status)
+}
+
+// swagger:route POST /user/totp/enroll signed_in_user enrollTOTP
+//
+// Start the two-factor authentication enrollment of the signed in user.
+//
+// Returns the secret to add to an authenticator app and the recovery codes. The enrollment
+// is pending until it is confirmed with a code generated by the app.
+//
+// Responses:
+// 200: totpEnrollmentResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 500: internalServerError
+func (s *Service) enrollHandler(c *contextmodel.ReqContext) response.Response {
+	enrollment, err := s.Enroll(c.Req.Context(), c.SignedInUser.UserID, c.SignedInUser.Login)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to enroll two-factor authentication", err)
+	}
+	return response.JSON(http.StatusOK, enrollment)
+}
+
+// swagger:route POST /user/totp/confirm signed_in_user confirmTOTP
+//
+// Confirm the pending two-factor authentication enrollment of the signed in user.
+//
+// Responses:
+// 200: okResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 500: internalServerError
+func (s *Service) confirmHandler(c *contextmodel.ReqContext) response.Response {
+	form := CodeForm{}
+	if err := web.Bind(c.Req, &form); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	if err := s.Confirm(c.Req.Context(), c.SignedInUser.UserID, form.Code); err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to confirm two-factor authentication", err)
+	}
+	return response.Success("Two-factor authentication enabled")
+}
+
+// swagger:route POST /user/totp/disable signed_in_user disableTOTP
+//
+// Disable the two-factor authentication of the signed in user.
+//
+// Requires a valid code. Users required to use two-factor authentication can not disable it.
+//
+// Responses:
+// 200: okResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (s *Service) disableHandler(c *contextmodel.ReqContext) response.Response {
+	form := CodeForm{}
+	if err := web.Bind(c.Req, &form); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	ctx := c.Req.Context()
+	required, err := s.isRequired(ctx, c.SignedInUser.UserID, c.SignedInUser.IsGrafanaAdmin)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to disable two-factor authentication", err)
+	}
+	if required {
+		return response.Err(totp.ErrRequired.Errorf("user %d is required to use a second factor", c.SignedInUser.UserID))
+	}
+	if err := s.Verify(ctx, c.SignedInUser.UserID, form.Code); err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to disable two-factor authentication", err)
+	}
+	if err := s.Disable(ctx, c.SignedInUser.UserID); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to disable two-factor authentication", err)
+	}
+	return response.Success("Two-factor authentication disabled")
+}
+
+// swagger:route POST /user/totp/recovery-codes signed_in_user regenerateTOTPRecoveryCodes
+//
+// Replace the recovery codes of the signed in user. Requires a valid code.
+//
+// Responses:
+// 200: totpRecoveryCodesResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 500: internalServerError
+func (s *Service) recoveryCodesHandler(c *contextmodel.ReqContext) response.Response {
+	form := CodeForm{}
+	if err := web.Bind(c.Req, &form); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	ctx := c.Req.Context()
+	if err := s.Verify(ctx, c.SignedInUser.UserID, form.Code); err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to regenerate recovery codes", err)
+	}
+	codes, err := s.RegenerateRecoveryCodes(ctx, c.SignedInUser.UserID)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to regenerate recovery codes", err)
+	}
+	return response.JSON(http.StatusOK, map[string]any{"recoveryCodes": codes})
+}
+
+// swagger:route POST /login/totp/enroll signed_in_user loginEnrollTOTP
+//
+// Start the two-factor authentication enrollment of a user required to use it before logging in.
+//
+// The enrollment is confirmed by the first login with a code generated by the authenticator app.
+//
+// Responses:
+// 200: totpEnrollmentResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 500: internalServerError
+func (s *Service) loginEnrollHandler(c *contextmodel.ReqContext) response.Response {
+	form := LoginEnrollForm{}
+	if err := web.Bind(c.Req, &form); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	ctx := c.Req.Context()
+	ipAddress := s.loginAttempts.ClientIP(c.Req)
+	ok, err := s.loginAttempts.Validate(ctx, form.User, ipAddress)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to enroll two-factor authentication", err)
+	}
+	if !ok {
+		return response.Err(errInvalidCredentials.Errorf("too many consecutive incorrect login attempts for user"))
+	}
+
+	usr, err := s.userService.GetByLogin(ctx, &user.GetUserByLoginQuery{LoginOrEmail: form.User})
+	if err != nil && !errors.Is(err, user.ErrUserNotFound) {
+		return response.Error(http.StatusInternalServerError, "Failed to enroll two-factor authentication", err)
+	}
+	if usr == nil || usr.IsDisabled || !compareHash(form.Password, usr.Salt, usr.Password) {
+		_ = s.loginAttempts.Add(ctx, form.User, ipAddress)
+		return response.Err(errInvalidCredentials.Errorf("invalid credentials"))
+	}
+
+	// users who can log in without a second factor enroll once signed in
+	required, err := s.isRequired(ctx, usr.ID, usr.IsAdmin)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to enroll two-factor authentication", err)
+	}
+	if !required {
+		return response.Err(totp.ErrNotEnrolled.Errorf("user %d is not required to use a second factor", usr.ID))
+	}
+
+	enrollment, err := s.Enroll(ctx, usr.ID, usr.Login)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to enroll two-factor authentication", err)
+	}
+	return response.JSON(http.StatusOK, enrollment)
+}
+
+// swagger:route DELETE /admin/users/{user_id}/totp admin_users adminDisableUserTOTP
+//
+// Remove the second factor of a user, for example after the loss of the device and recovery codes.
+//
+// Responses:
+// 200: okResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (s *Service) adminDisableHandler(c *contextmodel.ReqContext) response.Response {
+	userID, err := strconv.ParseInt(web.Params(c.Req)[":id"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "id is invalid", err)
+	}
+	if err := s.Disable(c.Req.Context(), userID); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to disable two-factor authentication", err)
+	}
+	s.log.FromContext(c.Req.Context()).Info("Two-factor authentication removed by admin", "userId", userID, "adminId", c.SignedInUser.UserID)
+	return response.Success("Two-factor authentication disabled")
+}
+
+// swagger:route GET /user/app-passwords signed_in_user listAppPasswords
+//
+// List the app passwords of the signed in user.
+//
+// Responses:
+// 200: appPasswordsResponse
+// 401: unauthorisedError
+// 500: internalServerError
+func (s *Service) listAppPasswordsHandler(c *contextmodel.ReqContext) response.Response {
+	passwords, err := s.ListAppPasswords(c.Req.Context(), c.SignedInUser.UserID)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to list app passwords", err)
+	}
+	return response.JSON(http.StatusOK, passwords)
+}
+
+// swagger:route POST /user/app-passwords signed_in_user createAppPassword
+//
+// Create an app password for the signed in user.
+//
+// App passwords replace the user password for basic auth when two-factor authentication is used.
+// The password is only returned once.
+//
+// Responses:
+// 200: newAppPasswordResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 500: internalServerError
+func (s *Service) createAppPasswordHandler(c *contextmodel.ReqContext) response.Response {
+	form := CreateAppPasswordForm{}
+	if err := web.Bind(c.Req, &form); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	password, err := s.CreateAppPassword(c.Req.Context(), c.SignedInUser.UserID, form.Name)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to create app password", err)
+	}
+	return response.JSON(http.StatusOK, password)
+}
+
+// swagger:route DELETE /user/app-passwords/{id} signed_in_user deleteAppPassword
+//
+// Delete an app password of the signed in user.
+//
+// Responses:
+// 200: okResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 404: notFoundError
+// 500: internalServerError
+func (s *Service) deleteAppPasswordHandler(c *contextmodel.ReqContext) response.Response {
+	id, err := strconv.ParseInt(web.Params(c.Req)[":id"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "id is invalid", err)
+	}
+	if err := s.DeleteAppPassword(c.Req.Context(), c.SignedInUser.UserID, id); err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to delete app password", err)
+	}
+	return response.Success("App password deleted")
+}
@@ -0,0 +1,87 @@
+package totpimpl
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec // RFC 6238 uses HMAC-SHA1 and authenticator apps expect it
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	codeDigits = 6
+	// stepPeriod is the time step of RFC 6238
+	stepPeriod = 30 * time.Second
+	// allowedSkew is the number of steps accepted before and after the current one, to handle clock drift
+	allowedSkew = 1
+	secretSize  = 20
+)
+
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// generateSecret returns a random base32 encoded secret
+func generateSecret() (string, error) {
+	b := make([]byte, secretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return secretEncoding.EncodeToString(b), nil
+}
+
+// hotp returns the HOTP value (RFC 4226) of the counter
+func hotp(key []byte, counter uint64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+	mac := hmac.New(sha1.New, key)
+	_, _ = mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", codeDigits, value%1000000)
+}
+
+func timeStep(t time.Time) int64 {
+	return t.Unix() / int64(stepPeriod.Seconds())
+}
+
+// validateCode checks the code against the steps around t and returns the matching step.
+// Steps up to lastUsedStep are rejected so a code can not be replayed.
+func validateCode(secret, code string, t time.Time, lastUsedStep int64) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != codeDigits {
+		return 0, false
+	}
+	key, err := secretEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, false
+	}
+
+	current := timeStep(t)
+	for step := current - allowedSkew; step <= current+allowedSkew; step++ {
+		if step <= lastUsedStep || step < 0 {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(hotp(key, uint64(step))), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// keyURI returns the otpauth:// URI understood by authenticator apps
+func keyURI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprintf("%d", codeDigits))
+	params.Set("period", fmt.Sprintf("%d", int(stepPeriod.Seconds())))
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
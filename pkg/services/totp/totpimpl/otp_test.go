@@ -0,0 +1,76 @@
+package totpimpl
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHOTP(t *testing.T) {
+	// test vectors from RFC 6238 appendix B, truncated to 6 digits
+	key := []byte("12345678901234567890")
+	tests := []struct {
+		time     int64
+		expected string
+	}{
+		{time: 59, expected: "287082"},
+		{time: 1111111109, expected: "081804"},
+		{time: 1111111111, expected: "050471"},
+		{time: 1234567890, expected: "005924"},
+		{time: 2000000000, expected: "279037"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, hotp(key, uint64(timeStep(time.Unix(tt.time, 0)))))
+	}
+}
+
+func TestValidateCode(t *testing.T) {
+	secret := secretEncoding.EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1111111109, 0)
+
+	t.Run("should accept the current code", func(t *testing.T) {
+		step, ok := validateCode(secret, "081804", now, 0)
+		require.True(t, ok)
+		assert.Equal(t, timeStep(now), step)
+	})
+
+	t.Run("should accept the code of the previous and next step", func(t *testing.T) {
+		previous := hotp([]byte("12345678901234567890"), uint64(timeStep(now)-1))
+		step, ok := validateCode(secret, previous, now, 0)
+		require.True(t, ok)
+		assert.Equal(t, timeStep(now)-1, step)
+
+		_, ok = validateCode(secret, "081804", now.Add(stepPeriod), 0)
+		assert.True(t, ok)
+	})
+
+	t.Run("should reject codes outside of the allowed skew", func(t *testing.T) {
+		_, ok := validateCode(secret, "081804", now.Add(2*stepPeriod), 0)
+		assert.False(t, ok)
+	})
+
+	t.Run("should reject a code already used", func(t *testing.T) {
+		_, ok := validateCode(secret, "081804", now, timeStep(now))
+		assert.False(t, ok)
+	})
+
+	t.Run("should reject malformed codes", func(t *testing.T) {
+		for _, code := range []string{"", "08180", "0818045", "abcdef"} {
+			_, ok := validateCode(secret, code, now, 0)
+			assert.False(t, ok, code)
+		}
+	})
+}
+
+func TestKeyURI(t *testing.T) {
+	u, err := url.Parse(keyURI("Grafana", "admin@example.com", "SECRET"))
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth", u.Scheme)
+	assert.Equal(t, "totp", u.Host)
+	assert.Equal(t, "/Grafana:admin@example.com", u.Path)
+	assert.Equal(t, "SECRET", u.Query().Get("secret"))
+	assert.Equal(t, "Grafana", u.Query().Get("issuer"))
+}
@@ -0,0 +1,434 @@
+package totpimpl
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/loginattempt"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/secrets"
+	"github.com/grafana/grafana/pkg/services/totp"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+const (
+	recoveryCodeCount  = 10
+	appPasswordLength  = 32
+	maxAppPasswordName = 190
+)
+
+var recoveryCodeEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+var _ totp.Service = (*Service)(nil)
+
+type Service struct {
+	cfg           *setting.Cfg
+	store         store
+	secrets       secrets.Service
+	orgService    org.Service
+	userService   user.Service
+	loginAttempts loginattempt.Service
+	routeRegister routing.RouteRegister
+	log           log.Logger
+	now           func() time.Time
+}
+
+func ProvideService(db db.DB, cfg *setting.Cfg, secretsService secrets.Service, orgService org.Service,
+	userService user.Service, loginAttempts loginattempt.Service, routeRegister routing.RouteRegister,
+) *Service {
+	s := &Service{
+		cfg:           cfg,
+		store:         &xormStore{db: db},
+		secrets:       secretsService,
+		orgService:    orgService,
+		userService:   userService,
+		loginAttempts: loginAttempts,
+		routeRegister: routeRegister,
+		log:           log.New("totp"),
+		now:           time.Now,
+	}
+	if cfg.TOTPEnabled {
+		s.registerAPIEndpoints()
+	}
+	return s
+}
+
+func (s *Service) IsEnabled() bool {
+	return s.cfg.TOTPEnabled
+}
+
+func (s *Service) GetStatus(ctx context.Context, userID int64, isGrafanaAdmin bool) (*totp.Status, error) {
+	status := &totp.Status{}
+	if !s.cfg.TOTPEnabled {
+		return status, nil
+	}
+
+	secret, err := s.getTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if secret != nil {
+		status.Enabled = secret.Enabled
+		status.Pending = !secret.Enabled
+		codes, err := s.store.GetRecoveryCodes(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		status.RecoveryCodesRemaining = len(codes)
+	}
+
+	status.Required, err = s.isRequired(ctx, userID, isGrafanaAdmin)
+	return status, err
+}
+
+func (s *Service) RequiresSecondFactor(ctx context.Context, userID int64, isGrafanaAdmin bool) (bool, error) {
+	if !s.cfg.TOTPEnabled {
+		return false, nil
+	}
+	secret, err := s.getTOTP(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if secret != nil && secret.Enabled {
+		return true, nil
+	}
+	return s.isRequired(ctx, userID, isGrafanaAdmin)
+}
+
+// isRequired checks if the user must have a second factor, globally or because of a role in any organization
+func (s *Service) isRequired(ctx context.Context, userID int64, isGrafanaAdmin bool) (bool, error) {
+	if s.cfg.TOTPEnforced {
+		return true, nil
+	}
+	if len(s.cfg.TOTPEnforcedRoles) == 0 {
+		return false, nil
+	}
+
+	roles := map[string]bool{}
+	for _, role := range s.cfg.TOTPEnforcedRoles {
+		roles[role] = true
+	}
+	if isGrafanaAdmin && roles[totp.RoleGrafanaAdmin] {
+		return true, nil
+	}
+
+	orgs, err := s.orgService.GetUserOrgList(ctx, &org.GetUserOrgListQuery{UserID: userID})
+	if err != nil {
+		return false, err
+	}
+	for _, o := range orgs {
+		if roles[string(o.Role)] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *Service) Enroll(ctx context.Context, userID int64, login string) (*totp.Enrollment, error) {
+	if !s.cfg.TOTPEnabled {
+		return nil, totp.ErrDisabled.Errorf("totp is disabled")
+	}
+
+	current, err := s.getTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil && current.Enabled {
+		return nil, totp.ErrAlreadyEnrolled.Errorf("user %d already has a second factor", userID)
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := s.secrets.Encrypt(ctx, []byte(secret), secrets.WithoutScope())
+	if err != nil {
+		return nil, err
+	}
+
+	codes, hashed, err := s.generateRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now().Unix()
+	err = s.store.SaveTOTP(ctx, &userTOTP{
+		UserID:  userID,
+		Secret:  base64.StdEncoding.EncodeToString(encrypted),
+		Created: now,
+		Updated: now,
+	}, hashed)
+	if err != nil {
+		return nil, err
+	}
+
+	return &totp.Enrollment{
+		Secret:        secret,
+		URL:           keyURI(s.cfg.TOTPIssuer, login, secret),
+		RecoveryCodes: codes,
+	}, nil
+}
+
+func (s *Service) Confirm(ctx context.Context, userID int64, code string) error {
+	secret, err := s.getTOTP(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if secret == nil {
+		return totp.ErrNotEnrolled.Errorf("user %d has no pending enrollment", userID)
+	}
+	if secret.Enabled {
+		return totp.ErrAlreadyEnrolled.Errorf("user %d already has a second factor", userID)
+	}
+	// recovery codes can not be used to confirm an enrollment, the user must prove the app is set up
+	return s.verifyTOTP(ctx, secret, code)
+}
+
+func (s *Service) Disable(ctx context.Context, userID int64) error {
+	return s.store.DeleteTOTP(ctx, userID)
+}
+
+func (s *Service) VerifyLogin(ctx context.Context, userID int64, isGrafanaAdmin bool, code string) error {
+	if !s.cfg.TOTPEnabled {
+		return nil
+	}
+
+	secret, err := s.getTOTP(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if secret == nil || !secret.Enabled {
+		required, err := s.isRequired(ctx, userID, isGrafanaAdmin)
+		if err != nil || !required {
+			return err
+		}
+		if secret == nil {
+			return totp.ErrEnrollmentRequired.Errorf("user %d must enroll a second factor", userID)
+		}
+		if code == "" {
+			return totp.ErrCodeRequired.Errorf("missing code")
+		}
+		// a valid code completes the enrollment started before logging in
+		return s.verifyTOTP(ctx, secret, code)
+	}
+
+	if code == "" {
+		return totp.ErrCodeRequired.Errorf("missing code")
+	}
+	return s.verify(ctx, secret, code)
+}
+
+func (s *Service) Verify(ctx context.Context, userID int64, code string) error {
+	secret, err := s.getTOTP(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if secret == nil || !secret.Enabled {
+		return totp.ErrNotEnrolled.Errorf("user %d has no second factor", userID)
+	}
+	return s.verify(ctx, secret, code)
+}
+
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID int64) ([]string, error) {
+	secret, err := s.getTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || !secret.Enabled {
+		return nil, totp.ErrNotEnrolled.Errorf("user %d has no second factor", userID)
+	}
+
+	codes, hashed, err := s.generateRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+	return codes, s.store.ReplaceRecoveryCodes(ctx, userID, hashed)
+}
+
+// verify accepts a time-based code or an unused recovery code
+func (s *Service) verify(ctx context.Context, secret *userTOTP, code string) error {
+	err := s.verifyTOTP(ctx, secret, code)
+	if !errors.Is(err, totp.ErrInvalidCode) {
+		return err
+	}
+
+	normalized := normalizeRecoveryCode(code)
+	codes, err := s.store.GetRecoveryCodes(ctx, secret.UserID)
+	if err != nil {
+		return err
+	}
+	for _, c := range codes {
+		if !compareHash(normalized, c.Salt, c.CodeHash) {
+			continue
+		}
+		deleted, err := s.store.DeleteRecoveryCode(ctx, c.ID)
+		if err != nil {
+			return err
+		}
+		if !deleted {
+			break
+		}
+		s.log.FromContext(ctx).Info("Recovery code used", "userId", secret.UserID, "remaining", len(codes)-1)
+		return nil
+	}
+	return totp.ErrInvalidCode.Errorf("invalid code")
+}
+
+func (s *Service) verifyTOTP(ctx context.Context, secret *userTOTP, code string) error {
+	encrypted, err := base64.StdEncoding.DecodeString(secret.Secret)
+	if err != nil {
+		return err
+	}
+	decrypted, err := s.secrets.Decrypt(ctx, encrypted)
+	if err != nil {
+		return err
+	}
+
+	step, ok := validateCode(string(decrypted), code, s.now(), secret.LastUsedStep)
+	if !ok {
+		return totp.ErrInvalidCode.Errorf("invalid code")
+	}
+	used, err := s.store.UseStep(ctx, secret.UserID, step, s.now().Unix())
+	if err != nil {
+		return err
+	}
+	if !used {
+		return totp.ErrInvalidCode.Errorf("code already used")
+	}
+	return nil
+}
+
+func (s *Service) getTOTP(ctx context.Context, userID int64) (*userTOTP, error) {
+	secret, err := s.store.GetTOTP(ctx, userID)
+	if errors.Is(err, errNotFound) {
+		return nil, nil
+	}
+	return secret, err
+}
+
+// generateRecoveryCodes returns the codes to show to the user and their hashed form to store
+func (s *Service) generateRecoveryCodes(userID int64) ([]string, []*recoveryCode, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashed := make([]*recoveryCode, 0, recoveryCodeCount)
+	now := s.now().Unix()
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := recoveryCodeEncoding.EncodeToString(b)[:10]
+		salt, err := util.GetRandomString(10)
+		if err != nil {
+			return nil, nil, err
+		}
+		hash, err := util.EncodePassword(code, salt)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashed = append(hashed, &recoveryCode{UserID: userID, CodeHash: hash, Salt: salt, Created: now})
+	}
+	return codes, hashed, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+func (s *Service) CreateAppPassword(ctx context.Context, userID int64, name string) (*totp.NewAppPassword, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxAppPasswordName {
+		return nil, totp.ErrAppPasswordInvalid.Errorf("invalid name %q", name)
+	}
+
+	secret, err := util.GetRandomString(appPasswordLength)
+	if err != nil {
+		return nil, err
+	}
+	salt, err := util.GetRandomString(10)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := util.EncodePassword(secret, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	created := s.now()
+	password := &appPassword{UserID: userID, Name: name, Hash: hash, Salt: salt, Created: created.Unix()}
+	if err := s.store.CreateAppPassword(ctx, password); err != nil {
+		return nil, err
+	}
+	return &totp.NewAppPassword{
+		AppPassword: totp.AppPassword{ID: password.ID, Name: name, Created: time.Unix(password.Created, 0)},
+		Password:    totp.AppPasswordPrefix + secret,
+	}, nil
+}
+
+func (s *Service) ListAppPasswords(ctx context.Context, userID int64) ([]*totp.AppPassword, error) {
+	passwords, err := s.store.ListAppPasswords(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*totp.AppPassword, 0, len(passwords))
+	for _, p := range passwords {
+		dto := &totp.AppPassword{ID: p.ID, Name: p.Name, Created: time.Unix(p.Created, 0)}
+		if p.LastUsed != nil {
+			lastUsed := time.Unix(*p.LastUsed, 0)
+			dto.LastUsed = &lastUsed
+		}
+		result = append(result, dto)
+	}
+	return result, nil
+}
+
+func (s *Service) DeleteAppPassword(ctx context.Context, userID, id int64) error {
+	deleted, err := s.store.DeleteAppPassword(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return totp.ErrAppPasswordMissing.Errorf("app password %d not found", id)
+	}
+	return nil
+}
+
+func (s *Service) AuthenticateAppPassword(ctx context.Context, userID int64, password string) (bool, error) {
+	if !strings.HasPrefix(password, totp.AppPasswordPrefix) {
+		return false, nil
+	}
+	secret := strings.TrimPrefix(password, totp.AppPasswordPrefix)
+
+	passwords, err := s.store.ListAppPasswords(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range passwords {
+		if compareHash(secret, p.Salt, p.Hash) {
+			if err := s.store.UpdateAppPasswordLastUsed(ctx, p.ID, s.now().Unix()); err != nil {
+				s.log.FromContext(ctx).Warn("Failed to update app password last used time", "id", p.ID, "error", err)
+			}
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func compareHash(value, salt, hash string) bool {
+	// util.EncodePassword never returns an error
+	encoded, _ := util.EncodePassword(value, salt)
+	return subtle.ConstantTimeCompare([]byte(encoded), []byte(hash)) == 1
+}
@@ -0,0 +1,193 @@
+package totpimpl
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/loginattempt/loginattempttest"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/org/orgtest"
+	"github.com/grafana/grafana/pkg/services/secrets/fakes"
+	"github.com/grafana/grafana/pkg/services/totp"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func setupTestService(t *testing.T, cfg *setting.Cfg, orgService org.Service) (*Service, *time.Time) {
+	t.Helper()
+	cfg.TOTPEnabled = true
+	cfg.TOTPIssuer = "Grafana"
+	s := ProvideService(db.InitTestDB(t), cfg, fakes.NewFakeSecretsService(), orgService,
+		usertest.NewUserServiceFake(), loginattempttest.FakeLoginAttemptService{}, routing.NewRouteRegister())
+	now := time.Unix(1700000000, 0)
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func currentCode(t *testing.T, secret string, now time.Time) string {
+	t.Helper()
+	key, err := secretEncoding.DecodeString(secret)
+	require.NoError(t, err)
+	return hotp(key, uint64(timeStep(now)))
+}
+
+func TestIntegrationEnrollment(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	s, now := setupTestService(t, setting.NewCfg(), orgtest.NewOrgServiceFake())
+
+	enrollment, err := s.Enroll(ctx, 1, "admin")
+	require.NoError(t, err)
+	require.Len(t, enrollment.RecoveryCodes, recoveryCodeCount)
+	assert.True(t, strings.HasPrefix(enrollment.URL, "otpauth://totp/Grafana:admin?"))
+
+	status, err := s.GetStatus(ctx, 1, false)
+	require.NoError(t, err)
+	assert.Equal(t, &totp.Status{Pending: true, RecoveryCodesRemaining: recoveryCodeCount}, status)
+
+	// a pending enrollment is not required at login
+	require.NoError(t, s.VerifyLogin(ctx, 1, false, ""))
+
+	require.ErrorIs(t, s.Confirm(ctx, 1, "000000"), totp.ErrInvalidCode)
+	require.NoError(t, s.Confirm(ctx, 1, currentCode(t, enrollment.Secret, *now)))
+
+	required, err := s.RequiresSecondFactor(ctx, 1, false)
+	require.NoError(t, err)
+	assert.True(t, required)
+
+	_, err = s.Enroll(ctx, 1, "admin")
+	require.ErrorIs(t, err, totp.ErrAlreadyEnrolled)
+
+	t.Run("login requires a code", func(t *testing.T) {
+		require.ErrorIs(t, s.VerifyLogin(ctx, 1, false, ""), totp.ErrCodeRequired)
+		require.ErrorIs(t, s.VerifyLogin(ctx, 1, false, "000000"), totp.ErrInvalidCode)
+	})
+
+	t.Run("codes can not be replayed", func(t *testing.T) {
+		*now = now.Add(stepPeriod)
+		code := currentCode(t, enrollment.Secret, *now)
+		require.NoError(t, s.VerifyLogin(ctx, 1, false, code))
+		require.ErrorIs(t, s.VerifyLogin(ctx, 1, false, code), totp.ErrInvalidCode)
+	})
+
+	t.Run("recovery codes can be used once", func(t *testing.T) {
+		code := strings.ToUpper(enrollment.RecoveryCodes[0])
+		require.NoError(t, s.VerifyLogin(ctx, 1, false, code))
+		require.ErrorIs(t, s.VerifyLogin(ctx, 1, false, code), totp.ErrInvalidCode)
+
+		status, err := s.GetStatus(ctx, 1, false)
+		require.NoError(t, err)
+		assert.Equal(t, recoveryCodeCount-1, status.RecoveryCodesRemaining)
+	})
+
+	t.Run("regenerating recovery codes invalidates the previous ones", func(t *testing.T) {
+		codes, err := s.RegenerateRecoveryCodes(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, codes, recoveryCodeCount)
+		require.ErrorIs(t, s.VerifyLogin(ctx, 1, false, enrollment.RecoveryCodes[1]), totp.ErrInvalidCode)
+		require.NoError(t, s.VerifyLogin(ctx, 1, false, codes[0]))
+	})
+
+	t.Run("disable removes the second factor", func(t *testing.T) {
+		require.NoError(t, s.Disable(ctx, 1))
+		required, err := s.RequiresSecondFactor(ctx, 1, false)
+		require.NoError(t, err)
+		assert.False(t, required)
+		require.NoError(t, s.VerifyLogin(ctx, 1, false, ""))
+	})
+}
+
+func TestIntegrationEnforcement(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+
+	t.Run("enforced roles", func(t *testing.T) {
+		cfg := setting.NewCfg()
+		cfg.TOTPEnforcedRoles = []string{string(org.RoleAdmin), totp.RoleGrafanaAdmin}
+		orgService := orgtest.NewOrgServiceFake()
+		orgService.ExpectedUserOrgDTO = []*org.UserOrgDTO{{OrgID: 1, Role: org.RoleViewer}}
+		s, _ := setupTestService(t, cfg, orgService)
+
+		required, err := s.RequiresSecondFactor(ctx, 1, false)
+		require.NoError(t, err)
+		assert.False(t, required)
+
+		required, err = s.RequiresSecondFactor(ctx, 1, true)
+		require.NoError(t, err)
+		assert.True(t, required)
+
+		orgService.ExpectedUserOrgDTO = append(orgService.ExpectedUserOrgDTO, &org.UserOrgDTO{OrgID: 2, Role: org.RoleAdmin})
+		required, err = s.RequiresSecondFactor(ctx, 1, false)
+		require.NoError(t, err)
+		assert.True(t, required)
+	})
+
+	t.Run("users required to use a second factor must enroll before logging in", func(t *testing.T) {
+		cfg := setting.NewCfg()
+		cfg.TOTPEnforced = true
+		s, now := setupTestService(t, cfg, orgtest.NewOrgServiceFake())
+
+		require.ErrorIs(t, s.VerifyLogin(ctx, 1, false, ""), totp.ErrEnrollmentRequired)
+
+		enrollment, err := s.Enroll(ctx, 1, "admin")
+		require.NoError(t, err)
+		require.ErrorIs(t, s.VerifyLogin(ctx, 1, false, ""), totp.ErrCodeRequired)
+		// recovery codes can not complete an enrollment
+		require.ErrorIs(t, s.VerifyLogin(ctx, 1, false, enrollment.RecoveryCodes[0]), totp.ErrInvalidCode)
+		require.NoError(t, s.VerifyLogin(ctx, 1, false, currentCode(t, enrollment.Secret, *now)))
+
+		status, err := s.GetStatus(ctx, 1, false)
+		require.NoError(t, err)
+		assert.True(t, status.Enabled)
+		assert.True(t, status.Required)
+	})
+}
+
+func TestIntegrationAppPasswords(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	s, _ := setupTestService(t, setting.NewCfg(), orgtest.NewOrgServiceFake())
+
+	created, err := s.CreateAppPassword(ctx, 1, "ci")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(created.Password, totp.AppPasswordPrefix))
+
+	_, err = s.CreateAppPassword(ctx, 1, "ci")
+	require.ErrorIs(t, err, totp.ErrAppPasswordExists)
+	_, err = s.CreateAppPassword(ctx, 1, " ")
+	require.ErrorIs(t, err, totp.ErrAppPasswordInvalid)
+
+	ok, err := s.AuthenticateAppPassword(ctx, 1, created.Password)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = s.AuthenticateAppPassword(ctx, 2, created.Password)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	passwords, err := s.ListAppPasswords(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, passwords, 1)
+	assert.Equal(t, "ci", passwords[0].Name)
+	assert.NotNil(t, passwords[0].LastUsed)
+
+	require.ErrorIs(t, s.DeleteAppPassword(ctx, 2, created.ID), totp.ErrAppPasswordMissing)
+	require.NoError(t, s.DeleteAppPassword(ctx, 1, created.ID))
+
+	ok, err = s.AuthenticateAppPassword(ctx, 1, created.Password)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
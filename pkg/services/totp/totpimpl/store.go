@@ -0,0 +1,207 @@
+package totpimpl
+
+import (
+	"context"
+	"errors"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/totp"
+)
+
+var errNotFound = errors.New("not found")
+
+type userTOTP struct {
+	ID           int64  `xorm:"pk autoincr 'id'"`
+	UserID       int64  `xorm:"user_id"`
+	Secret       string `xorm:"secret"`
+	Enabled      bool   `xorm:"enabled"`
+	LastUsedStep int64  `xorm:"last_used_step"`
+	Created      int64  `xorm:"created"`
+	Updated      int64  `xorm:"updated"`
+}
+
+type recoveryCode struct {
+	ID       int64  `xorm:"pk autoincr 'id'"`
+	UserID   int64  `xorm:"user_id"`
+	CodeHash string `xorm:"code_hash"`
+	Salt     string `xorm:"salt"`
+	Created  int64  `xorm:"created"`
+}
+
+type appPassword struct {
+	ID       int64  `xorm:"pk autoincr 'id'"`
+	UserID   int64  `xorm:"user_id"`
+	Name     string `xorm:"name"`
+	Hash     string `xorm:"hash"`
+	Salt     string `xorm:"salt"`
+	Created  int64  `xorm:"created"`
+	LastUsed *int64 `xorm:"last_used"`
+}
+
+const (
+	totpTable         = "user_totp"
+	recoveryCodeTable = "user_totp_recovery_code"
+	appPasswordTable  = "user_app_password"
+)
+
+type store interface {
+	GetTOTP(ctx context.Context, userID int64) (*userTOTP, error)
+	// SaveTOTP replaces the secret and recovery codes of the user
+	SaveTOTP(ctx context.Context, secret *userTOTP, codes []*recoveryCode) error
+	// UseStep moves the last used step of the user forward and enables the secret. It returns false when
+	// the step was already used, which prevents the replay of a code by concurrent requests.
+	UseStep(ctx context.Context, userID, step, now int64) (bool, error)
+	DeleteTOTP(ctx context.Context, userID int64) error
+
+	GetRecoveryCodes(ctx context.Context, userID int64) ([]*recoveryCode, error)
+	// DeleteRecoveryCode returns false when the code was already used
+	DeleteRecoveryCode(ctx context.Context, id int64) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID int64, codes []*recoveryCode) error
+
+	CreateAppPassword(ctx context.Context, password *appPassword) error
+	ListAppPasswords(ctx context.Context, userID int64) ([]*appPassword, error)
+	DeleteAppPassword(ctx context.Context, userID, id int64) (bool, error)
+	UpdateAppPasswordLastUsed(ctx context.Context, id, now int64) error
+}
+
+type xormStore struct {
+	db db.DB
+}
+
+func (s *xormStore) GetTOTP(ctx context.Context, userID int64) (*userTOTP, error) {
+	result := &userTOTP{}
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		found, err := sess.Table(totpTable).Where("user_id = ?", userID).Get(result)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return errNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *xormStore) SaveTOTP(ctx context.Context, secret *userTOTP, codes []*recoveryCode) error {
+	return s.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		if _, err := sess.Exec("DELETE FROM "+totpTable+" WHERE user_id = ?", secret.UserID); err != nil {
+			return err
+		}
+		if _, err := sess.Table(totpTable).Insert(secret); err != nil {
+			return err
+		}
+		return replaceRecoveryCodes(sess, secret.UserID, codes)
+	})
+}
+
+func (s *xormStore) UseStep(ctx context.Context, userID, step, now int64) (bool, error) {
+	var updated bool
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		res, err := sess.Exec("UPDATE "+totpTable+" SET last_used_step = ?, enabled = ?, updated = ? WHERE user_id = ? AND last_used_step < ?",
+			step, true, now, userID, step)
+		if err != nil {
+			return err
+		}
+		rows, err := res.RowsAffected()
+		updated = rows > 0
+		return err
+	})
+	return updated, err
+}
+
+func (s *xormStore) DeleteTOTP(ctx context.Context, userID int64) error {
+	return s.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		if _, err := sess.Exec("DELETE FROM "+totpTable+" WHERE user_id = ?", userID); err != nil {
+			return err
+		}
+		_, err := sess.Exec("DELETE FROM "+recoveryCodeTable+" WHERE user_id = ?", userID)
+		return err
+	})
+}
+
+func (s *xormStore) GetRecoveryCodes(ctx context.Context, userID int64) ([]*recoveryCode, error) {
+	codes := make([]*recoveryCode, 0)
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		return sess.Table(recoveryCodeTable).Where("user_id = ?", userID).Find(&codes)
+	})
+	return codes, err
+}
+
+func (s *xormStore) DeleteRecoveryCode(ctx context.Context, id int64) (bool, error) {
+	var deleted bool
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		res, err := sess.Exec("DELETE FROM "+recoveryCodeTable+" WHERE id = ?", id)
+		if err != nil {
+			return err
+		}
+		rows, err := res.RowsAffected()
+		deleted = rows > 0
+		return err
+	})
+	return deleted, err
+}
+
+func (s *xormStore) ReplaceRecoveryCodes(ctx context.Context, userID int64, codes []*recoveryCode) error {
+	return s.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		return replaceRecoveryCodes(sess, userID, codes)
+	})
+}
+
+func replaceRecoveryCodes(sess *db.Session, userID int64, codes []*recoveryCode) error {
+	if _, err := sess.Exec("DELETE FROM "+recoveryCodeTable+" WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	for _, code := range codes {
+		if _, err := sess.Table(recoveryCodeTable).Insert(code); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *xormStore) CreateAppPassword(ctx context.Context, password *appPassword) error {
+	return s.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		exists, err := sess.Table(appPasswordTable).Where("user_id = ? AND name = ?", password.UserID, password.Name).Exist()
+		if err != nil {
+			return err
+		}
+		if exists {
+			return totp.ErrAppPasswordExists.Errorf("app password %q already exists", password.Name)
+		}
+		_, err = sess.Table(appPasswordTable).Insert(password)
+		return err
+	})
+}
+
+func (s *xormStore) ListAppPasswords(ctx context.Context, userID int64) ([]*appPassword, error) {
+	passwords := make([]*appPassword, 0)
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		return sess.Table(appPasswordTable).Where("user_id = ?", userID).Asc("name").Find(&passwords)
+	})
+	return passwords, err
+}
+
+func (s *xormStore) DeleteAppPassword(ctx context.Context, userID, id int64) (bool, error) {
+	var deleted bool
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		res, err := sess.Exec("DELETE FROM "+appPasswordTable+" WHERE user_id = ? AND id = ?", userID, id)
+		if err != nil {
+			return err
+		}
+		rows, err := res.RowsAffected()
+		deleted = rows > 0
+		return err
+	})
+	return deleted, err
+}
+
+func (s *xormStore) UpdateAppPasswordLastUsed(ctx context.Context, id, now int64) error {
+	return s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		_, err := sess.Exec("UPDATE "+appPasswordTable+" SET last_used = ? WHERE id = ?", now, id)
+		return err
+	})
+}
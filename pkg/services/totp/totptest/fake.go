@@ -0,0 +1,77 @@
+package totptest
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/services/totp"
+)
+
+var _ totp.Service = new(FakeService)
+
+type FakeService struct {
+	ExpectedEnabled        bool
+	ExpectedStatus         *totp.Status
+	ExpectedRequired       bool
+	ExpectedEnrollment     *totp.Enrollment
+	ExpectedRecoveryCodes  []string
+	ExpectedAppPassword    *totp.NewAppPassword
+	ExpectedAppPasswords   []*totp.AppPassword
+	ExpectedAuthenticated  bool
+	ExpectedVerifyLoginErr error
+	ExpectedErr            error
+
+	VerifyLoginCode string
+}
+
+func (f *FakeService) IsEnabled() bool {
+	return f.ExpectedEnabled
+}
+
+func (f *FakeService) GetStatus(ctx context.Context, userID int64, isGrafanaAdmin bool) (*totp.Status, error) {
+	return f.ExpectedStatus, f.ExpectedErr
+}
+
+func (f *FakeService) RequiresSecondFactor(ctx context.Context, userID int64, isGrafanaAdmin bool) (bool, error) {
+	return f.ExpectedRequired, f.ExpectedErr
+}
+
+func (f *FakeService) Enroll(ctx context.Context, userID int64, login string) (*totp.Enrollment, error) {
+	return f.ExpectedEnrollment, f.ExpectedErr
+}
+
+func (f *FakeService) Confirm(ctx context.Context, userID int64, code string) error {
+	return f.ExpectedErr
+}
+
+func (f *FakeService) Disable(ctx context.Context, userID int64) error {
+	return f.ExpectedErr
+}
+
+func (f *FakeService) VerifyLogin(ctx context.Context, userID int64, isGrafanaAdmin bool, code string) error {
+	f.VerifyLoginCode = code
+	return f.ExpectedVerifyLoginErr
+}
+
+func (f *FakeService) Verify(ctx context.Context, userID int64, code string) error {
+	return f.ExpectedErr
+}
+
+func (f *FakeService) RegenerateRecoveryCodes(ctx context.Context, userID int64) ([]string, error) {
+	return f.ExpectedRecoveryCodes, f.ExpectedErr
+}
+
+func (f *FakeService) CreateAppPassword(ctx context.Context, userID int64, name string) (*totp.NewAppPassword, error) {
+	return f.ExpectedAppPassword, f.ExpectedErr
+}
+
+func (f *FakeService) ListAppPasswords(ctx context.Context, userID int64) ([]*totp.AppPassword, error) {
+	return f.ExpectedAppPasswords, f.ExpectedErr
+}
+
+func (f *FakeService) DeleteAppPassword(ctx context.Context, userID, id int64) error {
+	return f.ExpectedErr
+}
+
+func (f *FakeService) AuthenticateAppPassword(ctx context.Context, userID int64, password string) (bool, error) {
+	return f.ExpectedAuthenticated, f.ExpectedErr
+}
@@ -209,6 +209,7 @@ type Cfg struct {
 	// Security
 	DisableInitAdminCreation          bool
 	DisableBruteForceLoginProtection  bool
+	BruteForceLoginProtection         BruteForceLoginProtectionSettings
 	CookieSecure                      bool
 	CookieSameSiteDisabled            bool
 	CookieSameSiteMode                http.SameSite
@@ -276,6 +277,10 @@ type Cfg struct {
 	AzureAuthEnabled             bool
 	AzureSkipOrgRoleSync         bool
 	BasicAuthEnabled             bool
+	TOTPEnabled                  bool
+	TOTPIssuer                   string
+	TOTPEnforced                 bool
+	TOTPEnforcedRoles            []string
 	AdminUser                    string
 	AdminPassword                string
 	DisableLogin                 bool
@@ -331,6 +336,21 @@ type Cfg struct {
 	JWTAuthAllowAssignGrafanaAdmin bool
 	JWTAuthSkipOrgRoleSync         bool
 
+	// Client certificate (mTLS) Auth
+	ClientCertAuthEnabled                 bool
+	ClientCertAuthCACertPath              string
+	ClientCertAuthLoginAttributePath      string
+	ClientCertAuthEmailAttributePath      string
+	ClientCertAuthNameAttributePath       string
+	ClientCertAuthAutoSignUp              bool
+	ClientCertAuthRoleAttributePath       string
+	ClientCertAuthRoleAttributeStrict     bool
+	ClientCertAuthAllowAssignGrafanaAdmin bool
+	ClientCertAuthSkipOrgRoleSync         bool
+
+	// SCIM provisioning
+	SCIMEnabled bool
+
 	// Extended JWT Auth
 	ExtendedJWTAuthEnabled    bool
 	ExtendedJWTExpectIssuer   string
@@ -376,7 +396,10 @@ type Cfg struct {
 	CaseInsensitiveLogin  bool // Login and Email will be considered case insensitive
 
 	// Service Accounts
-	SATokenExpirationDayLimit int
+	SATokenExpirationDayLimit       int
+	SATokenMaxTTL                   time.Duration
+	SATokenRotationGracePeriod      time.Duration
+	SATokenExpiryNotificationPeriod time.Duration
 
 	// Annotations
 	AnnotationCleanupJobBatchSize      int64
@@ -395,6 +418,7 @@ type Cfg struct {
 	SqlDatasourceMaxOpenConnsDefault    int
 	SqlDatasourceMaxIdleConnsDefault    int
 	SqlDatasourceMaxConnLifetimeDefault int
+	SqlDatasourceMaxConnIdleTimeDefault int
 
 	// Snapshots
 	SnapshotEnabled       bool
@@ -404,6 +428,9 @@ type Cfg struct {
 	SnapShotRemoveExpired bool
 
 	SnapshotPublicMode bool
+	// SnapshotMaxBundleSize is the maximum size in bytes of an imported snapshot bundle
+	SnapshotMaxBundleSize int64
+	SnapshotSchedules     []SnapshotSchedule
 
 	ErrTemplateName string
 
@@ -712,7 +739,7 @@ func (cfg *Cfg) readGrafanaEnvironmentMetrics() error {
 
 func (cfg *Cfg) readAnnotationSettings() error {
 	section := cfg.Raw.Section("annotations")
-	cfg.AnnotationCleanupJobBatchSize = section.Key("cleanupjob_batchsize").MustInt64(100)
+	cfg.AnnotationCleanupJobBatchSize = section.Key("cleanupjob_batchsize").MustInt64(0)
 	cfg.AnnotationMaximumTagsLength = section.Key("tags_length").MustInt64(500)
 	switch {
 	case cfg.AnnotationMaximumTagsLength > 4096:
@@ -758,6 +785,23 @@ type AnnotationCleanupSettings struct {
 	MaxCount int64
 }
 
+// BruteForceLoginProtectionSettings limits the failed login attempts per username, per source IP and
+// per source IP and username. A max attempts of 0 disables the limit.
+type BruteForceLoginProtectionSettings struct {
+	MaxAttempts           int64
+	Window                time.Duration
+	IPMaxAttempts         int64
+	IPWindow              time.Duration
+	IPUsernameMaxAttempts int64
+	IPUsernameWindow      time.Duration
+	// Backoff is the lockout after reaching a limit, it doubles with every further failed attempt up to the window.
+	// A backoff of 0 locks until the attempts leave the window.
+	Backoff time.Duration
+	// TrustedProxies are the IP addresses and CIDR ranges of the proxies whose X-Forwarded-For and X-Real-IP
+	// headers give the IP address of the client. The IP address of the other requests is the connection address.
+	TrustedProxies []string
+}
+
 func EnvKey(sectionName string, keyName string) string {
 	sN := strings.ToUpper(strings.ReplaceAll(sectionName, ".", "_"))
 	sN = strings.ReplaceAll(sN, "-", "_")
@@ -1397,6 +1441,16 @@ func readSecuritySettings(iniFile *ini.File, cfg *Cfg) error {
 	cfg.SecretKey = SecretKey
 	DisableGravatar = security.Key("disable_gravatar").MustBool(true)
 	cfg.DisableBruteForceLoginProtection = security.Key("disable_brute_force_login_protection").MustBool(false)
+	cfg.BruteForceLoginProtection = BruteForceLoginProtectionSettings{
+		MaxAttempts:           security.Key("brute_force_login_protection_max_attempts").MustInt64(5),
+		Window:                security.Key("brute_force_login_protection_window").MustDuration(5 * time.Minute),
+		IPMaxAttempts:         security.Key("brute_force_login_protection_ip_max_attempts").MustInt64(0),
+		IPWindow:              security.Key("brute_force_login_protection_ip_window").MustDuration(5 * time.Minute),
+		IPUsernameMaxAttempts: security.Key("brute_force_login_protection_ip_username_max_attempts").MustInt64(0),
+		IPUsernameWindow:      security.Key("brute_force_login_protection_ip_username_window").MustDuration(5 * time.Minute),
+		Backoff:               security.Key("brute_force_login_protection_backoff").MustDuration(0),
+		TrustedProxies:        util.SplitString(security.Key("brute_force_login_protection_trusted_proxies").MustString("")),
+	}
 
 	CookieSecure = security.Key("cookie_secure").MustBool(false)
 	cfg.CookieSecure = CookieSecure
@@ -1603,6 +1657,13 @@ func readAuthSettings(iniFile *ini.File, cfg *Cfg) (err error) {
 	authBasic := iniFile.Section("auth.basic")
 	cfg.BasicAuthEnabled = authBasic.Key("enabled").MustBool(true)
 
+	// TOTP two-factor authentication
+	authTOTP := iniFile.Section("auth.totp")
+	cfg.TOTPEnabled = authTOTP.Key("enabled").MustBool(false)
+	cfg.TOTPIssuer = valueAsString(authTOTP, "issuer", "Grafana")
+	cfg.TOTPEnforced = authTOTP.Key("enforced").MustBool(false)
+	cfg.TOTPEnforcedRoles = util.SplitString(valueAsString(authTOTP, "enforced_roles", ""))
+
 	// JWT auth
 	authJWT := iniFile.Section("auth.jwt")
 	cfg.JWTAuthEnabled = authJWT.Key("enabled").MustBool(false)
@@ -1622,6 +1683,22 @@ func readAuthSettings(iniFile *ini.File, cfg *Cfg) (err error) {
 	cfg.JWTAuthAllowAssignGrafanaAdmin = authJWT.Key("allow_assign_grafana_admin").MustBool(false)
 	cfg.JWTAuthSkipOrgRoleSync = authJWT.Key("skip_org_role_sync").MustBool(false)
 
+	// Client certificate auth
+	authClientCert := iniFile.Section("auth.client_cert")
+	cfg.ClientCertAuthEnabled = authClientCert.Key("enabled").MustBool(false)
+	cfg.ClientCertAuthCACertPath = valueAsString(authClientCert, "ca_cert_path", "")
+	cfg.ClientCertAuthLoginAttributePath = valueAsString(authClientCert, "login_attribute_path", "subject.common_name")
+	cfg.ClientCertAuthEmailAttributePath = valueAsString(authClientCert, "email_attribute_path", "email_addresses[0]")
+	cfg.ClientCertAuthNameAttributePath = valueAsString(authClientCert, "name_attribute_path", "subject.common_name")
+	cfg.ClientCertAuthAutoSignUp = authClientCert.Key("auto_sign_up").MustBool(false)
+	cfg.ClientCertAuthRoleAttributePath = valueAsString(authClientCert, "role_attribute_path", "")
+	cfg.ClientCertAuthRoleAttributeStrict = authClientCert.Key("role_attribute_strict").MustBool(false)
+	cfg.ClientCertAuthAllowAssignGrafanaAdmin = authClientCert.Key("allow_assign_grafana_admin").MustBool(false)
+	cfg.ClientCertAuthSkipOrgRoleSync = authClientCert.Key("skip_org_role_sync").MustBool(false)
+
+	// SCIM provisioning
+	cfg.SCIMEnabled = iniFile.Section("auth.scim").Key("enabled").MustBool(false)
+
 	// Extended JWT auth
 	authExtendedJWT := iniFile.Section("auth.extended_jwt")
 	cfg.ExtendedJWTAuthEnabled = authExtendedJWT.Key("enabled").MustBool(false)
@@ -1724,6 +1801,20 @@ func readUserSettings(iniFile *ini.File, cfg *Cfg) error {
 func readServiceAccountSettings(iniFile *ini.File, cfg *Cfg) error {
 	serviceAccount := iniFile.Section("service_accounts")
 	cfg.SATokenExpirationDayLimit = serviceAccount.Key("token_expiration_day_limit").MustInt(-1)
+
+	var err error
+	cfg.SATokenMaxTTL, err = gtime.ParseDuration(valueAsString(serviceAccount, "token_max_ttl", "0"))
+	if err != nil {
+		return err
+	}
+	cfg.SATokenRotationGracePeriod, err = gtime.ParseDuration(valueAsString(serviceAccount, "token_rotation_grace_period", "24h"))
+	if err != nil {
+		return err
+	}
+	cfg.SATokenExpiryNotificationPeriod, err = gtime.ParseDuration(valueAsString(serviceAccount, "token_expiry_notification_period", "7d"))
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -1857,6 +1948,13 @@ func readSnapshotsSettings(cfg *Cfg, iniFile *ini.File) error {
 	cfg.ExternalEnabled = snapshots.Key("external_enabled").MustBool(true)
 	cfg.SnapShotRemoveExpired = snapshots.Key("snapshot_remove_expired").MustBool(true)
 	cfg.SnapshotPublicMode = snapshots.Key("public_mode").MustBool(false)
+	cfg.SnapshotMaxBundleSize = snapshots.Key("max_bundle_size_mb").MustInt64(64) << 20
+
+	schedules, err := extractSnapshotSchedules(iniFile.Sections())
+	if err != nil {
+		return err
+	}
+	cfg.SnapshotSchedules = schedules
 
 	return nil
 }
@@ -1958,6 +2056,7 @@ func (cfg *Cfg) readSqlDataSourceSettings() {
 	cfg.SqlDatasourceMaxOpenConnsDefault = sqlDatasources.Key("max_open_conns_default").MustInt(100)
 	cfg.SqlDatasourceMaxIdleConnsDefault = sqlDatasources.Key("max_idle_conns_default").MustInt(100)
 	cfg.SqlDatasourceMaxConnLifetimeDefault = sqlDatasources.Key("max_conn_lifetime_default").MustInt(14400)
+	cfg.SqlDatasourceMaxConnIdleTimeDefault = sqlDatasources.Key("max_conn_idle_time_default").MustInt(0)
 }
 
 func GetAllowedOriginGlobs(originPatterns []string) ([]glob.Glob, error) {
@@ -0,0 +1,65 @@
+package setting
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
+	"gopkg.in/ini.v1"
+)
+
+const snapshotScheduleSectionPrefix = "snapshot_schedule."
+
+// SnapshotSchedule configures a dashboard snapshot taken periodically by the server.
+type SnapshotSchedule struct {
+	// Name is the part of the section name following the snapshot_schedule. prefix.
+	Name         string
+	OrgID        int64
+	DashboardUID string
+	// Cron is a standard five field cron expression, evaluated in UTC.
+	Cron string
+	// Expires is how long the snapshots are kept, zero means forever.
+	Expires time.Duration
+	// TimeFrom and TimeTo override the time range of the dashboard.
+	TimeFrom string
+	TimeTo   string
+}
+
+func extractSnapshotSchedules(sections []*ini.Section) ([]SnapshotSchedule, error) {
+	var schedules []SnapshotSchedule
+	for _, section := range sections {
+		name, ok := strings.CutPrefix(section.Name(), snapshotScheduleSectionPrefix)
+		if !ok {
+			continue
+		}
+
+		schedule := SnapshotSchedule{
+			Name:         name,
+			OrgID:        section.Key("org_id").MustInt64(1),
+			DashboardUID: valueAsString(section, "dashboard_uid", ""),
+			Cron:         valueAsString(section, "cron", ""),
+			TimeFrom:     valueAsString(section, "time_from", ""),
+			TimeTo:       valueAsString(section, "time_to", ""),
+		}
+		if schedule.DashboardUID == "" {
+			return nil, fmt.Errorf("snapshot schedule %q: dashboard_uid is required", name)
+		}
+		if schedule.Cron == "" {
+			return nil, fmt.Errorf("snapshot schedule %q: cron is required", name)
+		}
+		if (schedule.TimeFrom == "") != (schedule.TimeTo == "") {
+			return nil, fmt.Errorf("snapshot schedule %q: time_from and time_to must be set together", name)
+		}
+
+		expires, err := gtime.ParseDuration(valueAsString(section, "expires", "0"))
+		if err != nil {
+			return nil, fmt.Errorf("snapshot schedule %q: invalid expires: %w", name, err)
+		}
+		schedule.Expires = expires
+
+		schedules = append(schedules, schedule)
+	}
+
+	return schedules, nil
+}
@@ -0,0 +1,52 @@
+package setting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/ini.v1"
+)
+
+func TestExtractSnapshotSchedules(t *testing.T) {
+	t.Run("reads every schedule section", func(t *testing.T) {
+		f, err := ini.Load([]byte(`
+[snapshots]
+enabled = true
+
+[snapshot_schedule.capacity]
+dashboard_uid = capacity
+cron = 0 6 * * *
+expires = 90d
+
+[snapshot_schedule.billing]
+org_id = 2
+dashboard_uid = billing
+cron = @monthly
+time_from = now-1M/M
+time_to = now-1M/M
+`))
+		require.NoError(t, err)
+
+		schedules, err := extractSnapshotSchedules(f.Sections())
+		require.NoError(t, err)
+		require.Equal(t, []SnapshotSchedule{
+			{Name: "capacity", OrgID: 1, DashboardUID: "capacity", Cron: "0 6 * * *", Expires: 90 * 24 * time.Hour},
+			{Name: "billing", OrgID: 2, DashboardUID: "billing", Cron: "@monthly", TimeFrom: "now-1M/M", TimeTo: "now-1M/M"},
+		}, schedules)
+	})
+
+	for name, section := range map[string]string{
+		"missing dashboard": "cron = @daily",
+		"missing cron":      "dashboard_uid = abc",
+		"partial range":     "dashboard_uid = abc\ncron = @daily\ntime_from = now-1d",
+		"invalid expires":   "dashboard_uid = abc\ncron = @daily\nexpires = soon",
+	} {
+		t.Run(name, func(t *testing.T) {
+			f, err := ini.Load([]byte("[snapshot_schedule.invalid]\n" + section))
+			require.NoError(t, err)
+			_, err = extractSnapshotSchedules(f.Sections())
+			require.Error(t, err)
+		})
+	}
+}
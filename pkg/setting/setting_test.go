@@ -32,6 +32,9 @@ func TestLoadingSettings(t *testing.T) {
 		require.Equal(t, "admin", cfg.AdminUser)
 		require.Equal(t, "http://localhost:3000/", cfg.RendererCallbackUrl)
 		require.Equal(t, "TLS1.2", cfg.MinTLSVersion)
+		// the IP address limit is opt-in, behind a proxy it would lock out every user
+		require.Equal(t, int64(0), cfg.BruteForceLoginProtection.IPMaxAttempts)
+		require.Equal(t, time.Duration(0), cfg.BruteForceLoginProtection.Backoff)
 	})
 
 	t.Run("default.ini should have no semi-colon commented entries", func(t *testing.T) {
@@ -30,6 +30,12 @@ import (
 
 var logger = log.New("tsdb.mssql")
 
+// mssqlSyntax is the syntax of the MSSQL queries checked by the guardrails
+var mssqlSyntax = sqleng.SQLSyntax{
+	NestedComments:     true,
+	BracketIdentifiers: true,
+}
+
 type Service struct {
 	im instancemgmt.InstanceManager
 }
@@ -69,6 +75,7 @@ func newInstanceSettings(cfg *setting.Cfg) datasource.InstanceFactoryFunc {
 			MaxOpenConns:      cfg.SqlDatasourceMaxOpenConnsDefault,
 			MaxIdleConns:      cfg.SqlDatasourceMaxIdleConnsDefault,
 			ConnMaxLifetime:   cfg.SqlDatasourceMaxConnLifetimeDefault,
+			ConnMaxIdleTime:   cfg.SqlDatasourceMaxConnIdleTimeDefault,
 			Encrypt:           "false",
 			ConnectionTimeout: 0,
 			SecureDSProxy:     false,
@@ -124,11 +131,13 @@ func newInstanceSettings(cfg *setting.Cfg) datasource.InstanceFactoryFunc {
 		}
 
 		config := sqleng.DataPluginConfiguration{
+			DataSourceType:    "mssql",
 			DriverName:        driverName,
 			ConnectionString:  cnnstr,
 			DSInfo:            dsInfo,
 			MetricColumnTypes: []string{"VARCHAR", "CHAR", "NVARCHAR", "NCHAR"},
 			RowLimit:          cfg.DataProxyRowLimit,
+			Syntax:            mssqlSyntax,
 		}
 
 		queryResultTransformer := mssqlQueryResultTransformer{
@@ -0,0 +1,93 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/grafana/grafana/pkg/tsdb/sqleng"
+)
+
+// mysqlSyntax is the syntax of the MySQL queries checked by the guardrails
+var mysqlSyntax = sqleng.SQLSyntax{
+	HashComments:          true,
+	DashCommentsNeedSpace: true,
+	ExecutableComments:    true,
+	BackslashEscapes:      true,
+	BacktickIdentifiers:   true,
+}
+
+// mysqlCostEstimator estimates the cost of the queries with the plan returned by EXPLAIN
+type mysqlCostEstimator struct{}
+
+func (mysqlCostEstimator) EstimateQueryCost(ctx context.Context, db *sql.DB, query string) (float64, error) {
+	var plan string
+	if err := db.QueryRowContext(ctx, "EXPLAIN FORMAT=JSON "+query).Scan(&plan); err != nil {
+		return 0, err
+	}
+	return parsePlanCost([]byte(plan))
+}
+
+// parsePlanCost returns the cost of the plan returned by EXPLAIN FORMAT=JSON. The plans of the UNION queries
+// have no cost for the whole query, their cost is the sum of the costs of their query blocks.
+func parsePlanCost(plan []byte) (float64, error) {
+	var explain map[string]any
+	if err := json.Unmarshal(plan, &explain); err != nil {
+		return 0, fmt.Errorf("failed to read the query plan: %w", err)
+	}
+
+	if block, ok := explain["query_block"].(map[string]any); ok {
+		if cost, ok := queryCost(block); ok {
+			return cost, nil
+		}
+	}
+
+	total, found := sumQueryCosts(explain)
+	if !found {
+		return 0, fmt.Errorf("the query plan has no cost")
+	}
+	return total, nil
+}
+
+// queryCost returns the cost_info.query_cost of the query block
+func queryCost(block map[string]any) (float64, bool) {
+	info, ok := block["cost_info"].(map[string]any)
+	if !ok {
+		return 0, false
+	}
+	switch v := info["query_cost"].(type) {
+	case string:
+		cost, err := strconv.ParseFloat(v, 64)
+		return cost, err == nil
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func sumQueryCosts(v any) (float64, bool) {
+	var total float64
+	found := false
+	switch v := v.(type) {
+	case map[string]any:
+		if cost, ok := queryCost(v); ok {
+			return cost, true
+		}
+		for _, child := range v {
+			if cost, ok := sumQueryCosts(child); ok {
+				total += cost
+				found = true
+			}
+		}
+	case []any:
+		for _, child := range v {
+			if cost, ok := sumQueryCosts(child); ok {
+				total += cost
+				found = true
+			}
+		}
+	}
+	return total, found
+}
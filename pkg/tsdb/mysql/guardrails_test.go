@@ -0,0 +1,29 @@
+package mysql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePlanCost(t *testing.T) {
+	t.Run("should return the cost of the query block", func(t *testing.T) {
+		cost, err := parsePlanCost([]byte(`{"query_block": {"select_id": 1, "cost_info": {"query_cost": "1.20"}, "table": {"table_name": "metrics"}}}`))
+		require.NoError(t, err)
+		require.Equal(t, 1.2, cost)
+	})
+
+	t.Run("should sum the costs of the query blocks of a union", func(t *testing.T) {
+		cost, err := parsePlanCost([]byte(`{"query_block": {"union_result": {"query_specifications": [
+			{"query_block": {"cost_info": {"query_cost": "1.50"}}},
+			{"query_block": {"cost_info": {"query_cost": "2.50"}}}
+		]}}}`))
+		require.NoError(t, err)
+		require.Equal(t, 4.0, cost)
+	})
+
+	t.Run("should fail when the plan has no cost", func(t *testing.T) {
+		_, err := parsePlanCost([]byte(`{"query_block": {"select_id": 1}}`))
+		require.Error(t, err)
+	})
+}
@@ -56,6 +56,7 @@ func newInstanceSettings(cfg *setting.Cfg, httpClientProvider httpclient.Provide
 			MaxOpenConns:            cfg.SqlDatasourceMaxOpenConnsDefault,
 			MaxIdleConns:            cfg.SqlDatasourceMaxIdleConnsDefault,
 			ConnMaxLifetime:         cfg.SqlDatasourceMaxConnLifetimeDefault,
+			ConnMaxIdleTime:         cfg.SqlDatasourceMaxConnIdleTimeDefault,
 			SecureDSProxy:           false,
 			AllowCleartextPasswords: false,
 		}
@@ -136,12 +137,16 @@ func newInstanceSettings(cfg *setting.Cfg, httpClientProvider httpclient.Provide
 		}
 
 		config := sqleng.DataPluginConfiguration{
-			DriverName:        "mysql",
-			ConnectionString:  cnnstr,
-			DSInfo:            dsInfo,
-			TimeColumnNames:   []string{"time", "time_sec"},
-			MetricColumnTypes: []string{"CHAR", "VARCHAR", "TINYTEXT", "TEXT", "MEDIUMTEXT", "LONGTEXT"},
-			RowLimit:          cfg.DataProxyRowLimit,
+			DataSourceType:        "mysql",
+			DriverName:            "mysql",
+			ConnectionString:      cnnstr,
+			DSInfo:                dsInfo,
+			TimeColumnNames:       []string{"time", "time_sec"},
+			MetricColumnTypes:     []string{"CHAR", "VARCHAR", "TINYTEXT", "TEXT", "MEDIUMTEXT", "LONGTEXT"},
+			RowLimit:              cfg.DataProxyRowLimit,
+			Syntax:                mysqlSyntax,
+			LimitRowsWithSubquery: true,
+			CostEstimator:         mysqlCostEstimator{},
 		}
 
 		rowTransformer := mysqlQueryResultTransformer{
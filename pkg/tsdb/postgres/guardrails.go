@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/tsdb/sqleng"
+)
+
+// postgresSyntax is the syntax of the Postgres queries checked by the guardrails
+var postgresSyntax = sqleng.SQLSyntax{
+	NestedComments: true,
+	DollarQuotes:   true,
+}
+
+// postgresCostEstimator estimates the cost of the queries with the plan returned by EXPLAIN
+type postgresCostEstimator struct{}
+
+func (postgresCostEstimator) EstimateQueryCost(ctx context.Context, db *sql.DB, query string) (float64, error) {
+	var plan string
+	if err := db.QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) "+query).Scan(&plan); err != nil {
+		return 0, err
+	}
+	return parsePlanCost([]byte(plan))
+}
+
+// parsePlanCost returns the total cost of the plan returned by EXPLAIN (FORMAT JSON)
+func parsePlanCost(plan []byte) (float64, error) {
+	var explain []struct {
+		Plan struct {
+			TotalCost *float64 `json:"Total Cost"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal(plan, &explain); err != nil {
+		return 0, fmt.Errorf("failed to read the query plan: %w", err)
+	}
+	if len(explain) == 0 || explain[0].Plan.TotalCost == nil {
+		return 0, fmt.Errorf("the query plan has no total cost")
+	}
+	return *explain[0].Plan.TotalCost, nil
+}
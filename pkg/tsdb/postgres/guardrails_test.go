@@ -0,0 +1,19 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePlanCost(t *testing.T) {
+	cost, err := parsePlanCost([]byte(`[{"Plan": {"Node Type": "Seq Scan", "Startup Cost": 0.00, "Total Cost": 35.50, "Plan Rows": 2550}}]`))
+	require.NoError(t, err)
+	require.Equal(t, 35.5, cost)
+
+	_, err = parsePlanCost([]byte(`[{"Plan": {}}]`))
+	require.Error(t, err)
+
+	_, err = parsePlanCost([]byte(`invalid`))
+	require.Error(t, err)
+}
@@ -60,6 +60,7 @@ func (s *Service) newInstanceSettings(cfg *setting.Cfg) datasource.InstanceFacto
 			MaxOpenConns:        cfg.SqlDatasourceMaxOpenConnsDefault,
 			MaxIdleConns:        cfg.SqlDatasourceMaxIdleConnsDefault,
 			ConnMaxLifetime:     cfg.SqlDatasourceMaxConnLifetimeDefault,
+			ConnMaxIdleTime:     cfg.SqlDatasourceMaxConnIdleTimeDefault,
 			Timescaledb:         false,
 			ConfigurationMethod: "file-path",
 			SecureDSProxy:       false,
@@ -106,11 +107,15 @@ func (s *Service) newInstanceSettings(cfg *setting.Cfg) datasource.InstanceFacto
 		}
 
 		config := sqleng.DataPluginConfiguration{
-			DriverName:        driverName,
-			ConnectionString:  cnnstr,
-			DSInfo:            dsInfo,
-			MetricColumnTypes: []string{"UNKNOWN", "TEXT", "VARCHAR", "CHAR"},
-			RowLimit:          cfg.DataProxyRowLimit,
+			DataSourceType:        "postgres",
+			DriverName:            driverName,
+			ConnectionString:      cnnstr,
+			DSInfo:                dsInfo,
+			MetricColumnTypes:     []string{"UNKNOWN", "TEXT", "VARCHAR", "CHAR"},
+			RowLimit:              cfg.DataProxyRowLimit,
+			Syntax:                postgresSyntax,
+			LimitRowsWithSubquery: true,
+			CostEstimator:         postgresCostEstimator{},
 		}
 
 		queryResultTransformer := postgresQueryResultTransformer{}
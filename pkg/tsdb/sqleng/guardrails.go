@@ -0,0 +1,160 @@
+package sqleng
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/util/errutil"
+)
+
+var (
+	ErrQueryNotReadOnly    = errutil.BadRequest("sqleng.queryNotReadOnly", errutil.WithPublicMessage("Only SELECT statements can be run on the data source"))
+	ErrQueryCostExceeded   = errutil.BadRequest("sqleng.queryCostExceeded", errutil.WithPublicMessage("The estimated cost of the query exceeds the maximum cost allowed by the data source"))
+	ErrQueryCostEstimation = errutil.BadRequest("sqleng.queryCostEstimation", errutil.WithPublicMessage("The cost of the query could not be estimated"))
+	ErrStatementTimeout    = errutil.Timeout("sqleng.statementTimeout", errutil.WithPublicMessage("The query exceeded the statement timeout of the data source"))
+)
+
+// QueryCostEstimator estimates the cost of the statements of a SQL dialect, ex: by running EXPLAIN. The cost is in
+// the unit of the query planner of the database. The estimator is called once per statement of a query.
+type QueryCostEstimator interface {
+	EstimateQueryCost(ctx context.Context, db *sql.DB, query string) (float64, error)
+}
+
+// readOnlyKeywords are the keywords a read-only statement can start with
+var readOnlyKeywords = map[string]bool{
+	"SELECT": true,
+	"WITH":   true,
+}
+
+// writeKeywords are the keywords of the statements modifying the database, a read-only statement cannot
+// contain them, ex: a data-modifying WITH statement or SELECT ... INTO
+var writeKeywords = map[string]bool{
+	"INSERT":   true,
+	"UPDATE":   true,
+	"DELETE":   true,
+	"MERGE":    true,
+	"UPSERT":   true,
+	"REPLACE":  true,
+	"DROP":     true,
+	"CREATE":   true,
+	"ALTER":    true,
+	"TRUNCATE": true,
+	"GRANT":    true,
+	"REVOKE":   true,
+	"INTO":     true,
+	"CALL":     true,
+	"EXEC":     true,
+	"EXECUTE":  true,
+}
+
+// checkReadOnly returns an error if a statement of the query is not a SELECT statement. The functions called
+// by the statements are not checked, read-only credentials are still recommended.
+func (s SQLSyntax) checkReadOnly(query string) error {
+	statements, err := s.splitStatements(query)
+	if err != nil {
+		return ErrQueryNotReadOnly.Errorf("failed to parse the query: %w", err)
+	}
+
+	for _, stmt := range statements {
+		if len(stmt.keywords) == 0 || !readOnlyKeywords[stmt.keywords[0]] {
+			return ErrQueryNotReadOnly.Errorf("the query is not a SELECT statement")
+		}
+		for _, keyword := range stmt.keywords {
+			if writeKeywords[keyword] {
+				return ErrQueryNotReadOnly.Errorf("the query contains a %s statement", keyword)
+			}
+		}
+	}
+	return nil
+}
+
+// limitRows wraps the query in a subquery returning at most limit rows
+func (s SQLSyntax) limitRows(query string, limit int64) (string, error) {
+	statements, err := s.splitStatements(query)
+	if err != nil {
+		return "", err
+	}
+	if len(statements) != 1 {
+		return "", fmt.Errorf("the row limit can only be applied to a single statement")
+	}
+	return fmt.Sprintf("SELECT * FROM (\n%s\n) AS grafana_row_limit LIMIT %d", statements[0].text, limit), nil
+}
+
+// applyGuardrails checks the query against the guardrails configured for the data source and returns the
+// query to run
+func (e *DataSourceHandler) applyGuardrails(ctx context.Context, db *sql.DB, query string) (string, error) {
+	jsonData := e.dsInfo.JsonData
+
+	if jsonData.ReadOnlyQueries {
+		if err := e.syntax.checkReadOnly(query); err != nil {
+			return "", err
+		}
+	}
+
+	if jsonData.QueryRowLimit > 0 && e.limitRowsWithSubquery {
+		limited, err := e.syntax.limitRows(query, jsonData.QueryRowLimit)
+		if err != nil {
+			return "", err
+		}
+		query = limited
+	}
+
+	if jsonData.MaxQueryCost > 0 && e.costEstimator != nil {
+		cost, err := e.estimateQueryCost(ctx, db, query)
+		if err != nil {
+			if e.timedOut(ctx) {
+				return "", e.statementTimeoutError()
+			}
+			return "", ErrQueryCostEstimation.Errorf("failed to estimate the query cost: %w", err)
+		}
+		if cost > jsonData.MaxQueryCost {
+			return "", ErrQueryCostExceeded.Errorf("the estimated cost of the query %.2f exceeds the maximum cost %.2f", cost, jsonData.MaxQueryCost)
+		}
+	}
+
+	return query, nil
+}
+
+// estimateQueryCost returns the sum of the estimated costs of the statements of the query, EXPLAIN only
+// covers the first statement of a query
+func (e *DataSourceHandler) estimateQueryCost(ctx context.Context, db *sql.DB, query string) (float64, error) {
+	statements, err := e.syntax.splitStatements(query)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0.0
+	for _, stmt := range statements {
+		cost, err := e.costEstimator.EstimateQueryCost(ctx, db, stmt.text)
+		if err != nil {
+			return 0, err
+		}
+		total += cost
+	}
+	return total, nil
+}
+
+func (e *DataSourceHandler) statementTimeout() time.Duration {
+	return time.Duration(e.dsInfo.JsonData.StatementTimeout) * time.Second
+}
+
+func (e *DataSourceHandler) statementTimeoutError() error {
+	return ErrStatementTimeout.Errorf("the query exceeded the statement timeout of %s", e.statementTimeout())
+}
+
+// timedOut returns true if the query was canceled by the statement timeout
+func (e *DataSourceHandler) timedOut(ctx context.Context) bool {
+	return e.statementTimeout() > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
+
+// queryError returns the statement timeout error if the query was canceled by the statement timeout
+func (e *DataSourceHandler) queryError(ctx context.Context, logger log.Logger, err error) error {
+	if e.timedOut(ctx) {
+		return e.statementTimeoutError()
+	}
+	return e.TransformQueryError(logger, err)
+}
@@ -0,0 +1,157 @@
+package sqleng
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+var (
+	testMySQLSyntax    = SQLSyntax{HashComments: true, DashCommentsNeedSpace: true, ExecutableComments: true, BackslashEscapes: true, BacktickIdentifiers: true}
+	testPostgresSyntax = SQLSyntax{NestedComments: true, DollarQuotes: true}
+	testMSSQLSyntax    = SQLSyntax{NestedComments: true, BracketIdentifiers: true}
+)
+
+func TestSplitStatements(t *testing.T) {
+	statements, err := testPostgresSyntax.splitStatements("SELECT now(), 'a;b' AS \"c;d\"; -- comment;\n/* x; */ DELETE FROM t;;")
+	require.NoError(t, err)
+	require.Len(t, statements, 2)
+	require.Equal(t, "SELECT now(), 'a;b' AS \"c;d\"", statements[0].text)
+	require.Equal(t, []string{"SELECT", "AS"}, statements[0].keywords)
+	require.Equal(t, []string{"DELETE", "FROM", "T"}, statements[1].keywords)
+
+	for _, query := range []string{"SELECT 'a", "SELECT \"a", "SELECT /* a", "SELECT $x$ a"} {
+		_, err := testPostgresSyntax.splitStatements(query)
+		require.Error(t, err, query)
+	}
+}
+
+func TestCheckReadOnly(t *testing.T) {
+	tcs := []struct {
+		desc     string
+		syntax   SQLSyntax
+		query    string
+		readOnly bool
+	}{
+		{desc: "select", syntax: testPostgresSyntax, query: "SELECT time, value FROM metrics WHERE name = 'delete' ORDER BY 1", readOnly: true},
+		{desc: "common table expression", syntax: testPostgresSyntax, query: "WITH m AS (SELECT * FROM metrics) SELECT * FROM m;", readOnly: true},
+		{desc: "function named like a keyword", syntax: testMySQLSyntax, query: "SELECT replace(name, 'a', 'b') FROM metrics", readOnly: true},
+		{desc: "delete", syntax: testPostgresSyntax, query: "DELETE FROM metrics", readOnly: false},
+		{desc: "select into", syntax: testMSSQLSyntax, query: "SELECT * INTO backup FROM metrics", readOnly: false},
+		{desc: "data-modifying common table expression", syntax: testPostgresSyntax, query: "WITH d AS (DELETE FROM metrics RETURNING *) SELECT * FROM d", readOnly: false},
+		{desc: "stacked statement", syntax: testPostgresSyntax, query: "SELECT 1; DROP TABLE metrics", readOnly: false},
+		{desc: "empty query", syntax: testPostgresSyntax, query: "-- nothing", readOnly: false},
+		{desc: "statement in a string with a backslash escaped quote", syntax: testMySQLSyntax, query: "SELECT 'a\\'; DROP TABLE metrics; -- '", readOnly: true},
+		{desc: "statement after a backslash ending a string", syntax: testPostgresSyntax, query: "SELECT 'a\\'; DROP TABLE metrics; -- '", readOnly: false},
+		{desc: "statement after a hash comment", syntax: testMySQLSyntax, query: "SELECT 1 # comment\n; DROP TABLE metrics", readOnly: false},
+		{desc: "statement hidden by a nested comment", syntax: testPostgresSyntax, query: "SELECT 1 /* /* */ ; DROP TABLE metrics; */", readOnly: true},
+		{desc: "statement after a non nested comment", syntax: testMySQLSyntax, query: "SELECT 1 /* /* */ ; DROP TABLE metrics; */", readOnly: false},
+		{desc: "statement in a dollar-quoted string", syntax: testPostgresSyntax, query: "SELECT $q$ ; DROP TABLE metrics $q$", readOnly: true},
+		{desc: "statement in a bracket identifier", syntax: testMSSQLSyntax, query: "SELECT 1 AS [a;DROP TABLE metrics]", readOnly: true},
+		{desc: "executable comment", syntax: testMySQLSyntax, query: "SELECT 1 /*! ; DROP TABLE metrics */", readOnly: false},
+		{desc: "unterminated comment", syntax: testPostgresSyntax, query: "SELECT 1 /* DROP TABLE metrics", readOnly: false},
+		{desc: "exec call", syntax: testMSSQLSyntax, query: "SELECT 1 EXEC('DROP TABLE metrics')", readOnly: false},
+		{desc: "execute call", syntax: testMSSQLSyntax, query: "SELECT 1 EXECUTE ('DROP TABLE metrics')", readOnly: false},
+		{desc: "truncate function", syntax: testMySQLSyntax, query: "SELECT truncate(value, 2) FROM metrics", readOnly: true},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := tc.syntax.checkReadOnly(tc.query)
+			if tc.readOnly {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, ErrQueryNotReadOnly)
+		})
+	}
+}
+
+func TestLimitRows(t *testing.T) {
+	query, err := testPostgresSyntax.limitRows("SELECT * FROM metrics;\n", 100)
+	require.NoError(t, err)
+	require.Equal(t, "SELECT * FROM (\nSELECT * FROM metrics\n) AS grafana_row_limit LIMIT 100", query)
+
+	_, err = testPostgresSyntax.limitRows("SELECT 1; SELECT 2", 100)
+	require.Error(t, err)
+}
+
+func TestApplyGuardrails(t *testing.T) {
+	newHandler := func(jsonData JsonData, estimator QueryCostEstimator) *DataSourceHandler {
+		return &DataSourceHandler{
+			log:                   log.New("test"),
+			dsInfo:                DataSourceInfo{JsonData: jsonData},
+			syntax:                testPostgresSyntax,
+			limitRowsWithSubquery: true,
+			costEstimator:         estimator,
+		}
+	}
+
+	t.Run("should leave the query unchanged without guardrails", func(t *testing.T) {
+		query, err := newHandler(JsonData{}, nil).applyGuardrails(context.Background(), nil, "DELETE FROM metrics")
+		require.NoError(t, err)
+		require.Equal(t, "DELETE FROM metrics", query)
+	})
+
+	t.Run("should limit the rows of the query", func(t *testing.T) {
+		query, err := newHandler(JsonData{ReadOnlyQueries: true, QueryRowLimit: 10}, nil).applyGuardrails(context.Background(), nil, "SELECT 1")
+		require.NoError(t, err)
+		require.Equal(t, "SELECT * FROM (\nSELECT 1\n) AS grafana_row_limit LIMIT 10", query)
+	})
+
+	t.Run("should reject the queries exceeding the maximum cost", func(t *testing.T) {
+		estimator := fakeCostEstimator{cost: 1000}
+		_, err := newHandler(JsonData{MaxQueryCost: 100}, estimator).applyGuardrails(context.Background(), nil, "SELECT 1")
+		require.ErrorIs(t, err, ErrQueryCostExceeded)
+
+		_, err = newHandler(JsonData{MaxQueryCost: 10000}, estimator).applyGuardrails(context.Background(), nil, "SELECT 1")
+		require.NoError(t, err)
+	})
+
+	t.Run("should sum the costs of the statements of the query", func(t *testing.T) {
+		estimator := fakeCostEstimator{costs: map[string]float64{"SELECT 1": 1, "SELECT * FROM metrics": 1000}}
+		_, err := newHandler(JsonData{MaxQueryCost: 100}, estimator).applyGuardrails(context.Background(), nil, "SELECT 1; SELECT * FROM metrics")
+		require.ErrorIs(t, err, ErrQueryCostExceeded)
+
+		_, err = newHandler(JsonData{MaxQueryCost: 1000}, estimator).applyGuardrails(context.Background(), nil, "SELECT 1; SELECT * FROM metrics")
+		require.ErrorIs(t, err, ErrQueryCostExceeded)
+
+		_, err = newHandler(JsonData{MaxQueryCost: 1001}, estimator).applyGuardrails(context.Background(), nil, "SELECT 1; SELECT * FROM metrics;")
+		require.NoError(t, err)
+	})
+
+	t.Run("should reject the queries whose cost cannot be estimated", func(t *testing.T) {
+		estimator := fakeCostEstimator{err: errors.New("syntax error")}
+		_, err := newHandler(JsonData{MaxQueryCost: 100}, estimator).applyGuardrails(context.Background(), nil, "SELECT 1")
+		require.ErrorIs(t, err, ErrQueryCostEstimation)
+	})
+
+	t.Run("should return the statement timeout error when the cost estimation times out", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+		defer cancel()
+		<-ctx.Done()
+
+		estimator := fakeCostEstimator{err: ctx.Err()}
+		_, err := newHandler(JsonData{MaxQueryCost: 100, StatementTimeout: 1}, estimator).applyGuardrails(ctx, nil, "SELECT 1")
+		require.ErrorIs(t, err, ErrStatementTimeout)
+	})
+}
+
+type fakeCostEstimator struct {
+	cost float64
+	// costs are the costs by query, the cost is used for the other queries
+	costs map[string]float64
+	err   error
+}
+
+func (e fakeCostEstimator) EstimateQueryCost(_ context.Context, _ *sql.DB, query string) (float64, error) {
+	if cost, ok := e.costs[query]; ok {
+		return cost, e.err
+	}
+	return e.cost, e.err
+}
@@ -0,0 +1,306 @@
+package sqleng
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"xorm.io/xorm"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+// ConnectionPoolStats are the statistics and the settings of the connection pool of a SQL data source
+type ConnectionPoolStats struct {
+	DataSourceID   int64  `json:"datasourceId"`
+	DataSourceUID  string `json:"datasourceUid"`
+	DataSourceType string `json:"datasourceType"`
+	// Created is the time the pool was created, at the first query of the data source or at its last reset
+	Created time.Time `json:"created"`
+
+	MaxOpenConns    int `json:"maxOpenConns"`
+	MaxIdleConns    int `json:"maxIdleConns"`
+	ConnMaxLifetime int `json:"connMaxLifetime"`
+	ConnMaxIdleTime int `json:"connMaxIdleTime"`
+
+	OpenConnections     int     `json:"openConnections"`
+	InUse               int     `json:"inUse"`
+	Idle                int     `json:"idle"`
+	WaitCount           int64   `json:"waitCount"`
+	WaitDurationSeconds float64 `json:"waitDurationSeconds"`
+	MaxIdleClosed       int64   `json:"maxIdleClosed"`
+	MaxIdleTimeClosed   int64   `json:"maxIdleTimeClosed"`
+	MaxLifetimeClosed   int64   `json:"maxLifetimeClosed"`
+}
+
+// connectionPool is the xorm engine of a data source with the queries using it. The engine of a reset pool is
+// closed once its queries are done.
+type connectionPool struct {
+	engine  *xorm.Engine
+	created time.Time
+	queries sync.WaitGroup
+}
+
+func (e *DataSourceHandler) newConnectionPool() (*connectionPool, error) {
+	engine, err := NewXormEngine(e.driverName, e.connectionString)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData := e.dsInfo.JsonData
+	engine.SetMaxOpenConns(jsonData.MaxOpenConns)
+	engine.SetMaxIdleConns(jsonData.MaxIdleConns)
+	engine.SetConnMaxLifetime(time.Duration(jsonData.ConnMaxLifetime) * time.Second)
+	engine.DB().SetConnMaxIdleTime(time.Duration(jsonData.ConnMaxIdleTime) * time.Second)
+
+	return &connectionPool{engine: engine, created: time.Now()}, nil
+}
+
+// acquirePool returns the connection pool the query runs with, the query must release it when done
+func (e *DataSourceHandler) acquirePool() *connectionPool {
+	e.poolMu.RLock()
+	defer e.poolMu.RUnlock()
+	e.pool.queries.Add(1)
+	return e.pool
+}
+
+func (p *connectionPool) release() {
+	p.queries.Done()
+}
+
+// ConnectionPoolStats returns the statistics of the connection pool of the data source
+func (e *DataSourceHandler) ConnectionPoolStats() ConnectionPoolStats {
+	e.poolMu.RLock()
+	pool := e.pool
+	e.poolMu.RUnlock()
+
+	jsonData := e.dsInfo.JsonData
+	stats := pool.engine.DB().Stats()
+	return ConnectionPoolStats{
+		DataSourceID:        e.dsInfo.ID,
+		DataSourceUID:       e.dsInfo.UID,
+		DataSourceType:      e.dsType,
+		Created:             pool.created,
+		MaxOpenConns:        jsonData.MaxOpenConns,
+		MaxIdleConns:        jsonData.MaxIdleConns,
+		ConnMaxLifetime:     jsonData.ConnMaxLifetime,
+		ConnMaxIdleTime:     jsonData.ConnMaxIdleTime,
+		OpenConnections:     stats.OpenConnections,
+		InUse:               stats.InUse,
+		Idle:                stats.Idle,
+		WaitCount:           stats.WaitCount,
+		WaitDurationSeconds: stats.WaitDuration.Seconds(),
+		MaxIdleClosed:       stats.MaxIdleClosed,
+		MaxIdleTimeClosed:   stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:   stats.MaxLifetimeClosed,
+	}
+}
+
+// ResetConnectionPool replaces the connection pool of the data source by a new one. The connections of the
+// previous pool are closed once the queries running with them are done.
+func (e *DataSourceHandler) ResetConnectionPool() error {
+	pool, err := e.newConnectionPool()
+	if err != nil {
+		return err
+	}
+
+	e.poolMu.Lock()
+	previous := e.pool
+	e.pool = pool
+	e.poolMu.Unlock()
+
+	e.log.Info("Connection pool reset", "uid", e.dsInfo.UID)
+	go func() {
+		previous.queries.Wait()
+		if err := previous.engine.Close(); err != nil {
+			e.log.Error("Failed to close the connection pool", "error", err)
+		}
+	}()
+	return nil
+}
+
+// connectionPoolRegistry holds the data source handlers of the running SQL data sources by data source ID. It
+// collects the metrics of their connection pools.
+type connectionPoolRegistry struct {
+	mu       sync.RWMutex
+	handlers map[int64]*DataSourceHandler
+
+	maxOpen           *prometheus.Desc
+	open              *prometheus.Desc
+	inUse             *prometheus.Desc
+	idle              *prometheus.Desc
+	waitCount         *prometheus.Desc
+	waitDuration      *prometheus.Desc
+	maxIdleClosed     *prometheus.Desc
+	maxIdleTimeClosed *prometheus.Desc
+	maxLifetimeClosed *prometheus.Desc
+}
+
+var (
+	connectionPools         = newConnectionPoolRegistry()
+	registerConnectionPools sync.Once
+)
+
+func newConnectionPoolRegistry() *connectionPoolRegistry {
+	ns := "grafana"
+	sub := "sql_datasource"
+	labels := []string{"datasource_uid", "datasource_type"}
+
+	return &connectionPoolRegistry{
+		handlers: map[int64]*DataSourceHandler{},
+		maxOpen: prometheus.NewDesc(
+			prometheus.BuildFQName(ns, sub, "conn_max_open"),
+			"Maximum number of open connections to the data source",
+			labels, nil,
+		),
+		open: prometheus.NewDesc(
+			prometheus.BuildFQName(ns, sub, "conn_open"),
+			"The number of established connections to the data source both in use and idle",
+			labels, nil,
+		),
+		inUse: prometheus.NewDesc(
+			prometheus.BuildFQName(ns, sub, "conn_in_use"),
+			"The number of connections to the data source currently in use",
+			labels, nil,
+		),
+		idle: prometheus.NewDesc(
+			prometheus.BuildFQName(ns, sub, "conn_idle"),
+			"The number of idle connections to the data source",
+			labels, nil,
+		),
+		waitCount: prometheus.NewDesc(
+			prometheus.BuildFQName(ns, sub, "conn_wait_count_total"),
+			"The total number of connections to the data source waited for",
+			labels, nil,
+		),
+		waitDuration: prometheus.NewDesc(
+			prometheus.BuildFQName(ns, sub, "conn_wait_duration_seconds_total"),
+			"The total time blocked waiting for a new connection to the data source",
+			labels, nil,
+		),
+		maxIdleClosed: prometheus.NewDesc(
+			prometheus.BuildFQName(ns, sub, "conn_max_idle_closed_total"),
+			"The total number of connections to the data source closed due to the maximum idle connections",
+			labels, nil,
+		),
+		maxIdleTimeClosed: prometheus.NewDesc(
+			prometheus.BuildFQName(ns, sub, "conn_max_idle_time_closed_total"),
+			"The total number of connections to the data source closed due to the maximum idle time",
+			labels, nil,
+		),
+		maxLifetimeClosed: prometheus.NewDesc(
+			prometheus.BuildFQName(ns, sub, "conn_max_lifetime_closed_total"),
+			"The total number of connections to the data source closed due to the maximum lifetime",
+			labels, nil,
+		),
+	}
+}
+
+func (r *connectionPoolRegistry) add(logger log.Logger, handler *DataSourceHandler) {
+	registerConnectionPools.Do(func() {
+		if err := prometheus.Register(r); err != nil {
+			logger.Warn("Failed to register SQL data source connection pool metrics", "error", err)
+		}
+	})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[handler.dsInfo.ID] = handler
+}
+
+// remove removes the handler, unless it was replaced by the handler of the updated data source
+func (r *connectionPoolRegistry) remove(handler *DataSourceHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.handlers[handler.dsInfo.ID] == handler {
+		delete(r.handlers, handler.dsInfo.ID)
+	}
+}
+
+func (r *connectionPoolRegistry) get(id int64) (*DataSourceHandler, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	handler, ok := r.handlers[id]
+	return handler, ok
+}
+
+func (r *connectionPoolRegistry) list() []*DataSourceHandler {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	handlers := make([]*DataSourceHandler, 0, len(r.handlers))
+	for _, handler := range r.handlers {
+		handlers = append(handlers, handler)
+	}
+	sort.Slice(handlers, func(i, j int) bool {
+		return handlers[i].dsInfo.ID < handlers[j].dsInfo.ID
+	})
+	return handlers
+}
+
+// GetConnectionPoolStats returns the statistics of the connection pool of the data source, false if the data
+// source has no connection pool, ex: it was not queried since Grafana started
+func GetConnectionPoolStats(dataSourceID int64) (ConnectionPoolStats, bool) {
+	handler, ok := connectionPools.get(dataSourceID)
+	if !ok {
+		return ConnectionPoolStats{}, false
+	}
+	return handler.ConnectionPoolStats(), true
+}
+
+// ResetConnectionPool resets the connection pool of the data source, false if the data source has no
+// connection pool
+func ResetConnectionPool(dataSourceID int64) (bool, error) {
+	handler, ok := connectionPools.get(dataSourceID)
+	if !ok {
+		return false, nil
+	}
+	return true, handler.ResetConnectionPool()
+}
+
+// ListConnectionPoolStats returns the statistics of the connection pools of all the SQL data sources
+func ListConnectionPoolStats() []ConnectionPoolStats {
+	handlers := connectionPools.list()
+	stats := make([]ConnectionPoolStats, 0, len(handlers))
+	for _, handler := range handlers {
+		stats = append(stats, handler.ConnectionPoolStats())
+	}
+	return stats
+}
+
+// Describe implements prometheus.Collector.
+func (r *connectionPoolRegistry) Describe(ch chan<- *prometheus.Desc) {
+	ch <- r.maxOpen
+	ch <- r.open
+	ch <- r.inUse
+	ch <- r.idle
+	ch <- r.waitCount
+	ch <- r.waitDuration
+	ch <- r.maxIdleClosed
+	ch <- r.maxIdleTimeClosed
+	ch <- r.maxLifetimeClosed
+}
+
+// Collect implements prometheus.Collector.
+func (r *connectionPoolRegistry) Collect(ch chan<- prometheus.Metric) {
+	collected := map[[2]string]bool{}
+	for _, handler := range r.list() {
+		stats := handler.ConnectionPoolStats()
+		labels := []string{stats.DataSourceUID, stats.DataSourceType}
+		// the data sources of different organizations can have the same uid, the metrics are collected once
+		if collected[[2]string(labels)] {
+			continue
+		}
+		collected[[2]string(labels)] = true
+
+		ch <- prometheus.MustNewConstMetric(r.maxOpen, prometheus.GaugeValue, float64(stats.MaxOpenConns), labels...)
+		ch <- prometheus.MustNewConstMetric(r.open, prometheus.GaugeValue, float64(stats.OpenConnections), labels...)
+		ch <- prometheus.MustNewConstMetric(r.inUse, prometheus.GaugeValue, float64(stats.InUse), labels...)
+		ch <- prometheus.MustNewConstMetric(r.idle, prometheus.GaugeValue, float64(stats.Idle), labels...)
+		ch <- prometheus.MustNewConstMetric(r.waitCount, prometheus.CounterValue, float64(stats.WaitCount), labels...)
+		ch <- prometheus.MustNewConstMetric(r.waitDuration, prometheus.CounterValue, stats.WaitDurationSeconds, labels...)
+		ch <- prometheus.MustNewConstMetric(r.maxIdleClosed, prometheus.CounterValue, float64(stats.MaxIdleClosed), labels...)
+		ch <- prometheus.MustNewConstMetric(r.maxIdleTimeClosed, prometheus.CounterValue, float64(stats.MaxIdleTimeClosed), labels...)
+		ch <- prometheus.MustNewConstMetric(r.maxLifetimeClosed, prometheus.CounterValue, float64(stats.MaxLifetimeClosed), labels...)
+	}
+}
@@ -0,0 +1,104 @@
+package sqleng
+
+import (
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestConnectionPool(t *testing.T) {
+	newHandler := func(t *testing.T, id int64, uid string) *DataSourceHandler {
+		t.Helper()
+		config := DataPluginConfiguration{
+			DataSourceType:   "sqlite",
+			DriverName:       "sqlite3",
+			ConnectionString: ":memory:",
+			DSInfo: DataSourceInfo{
+				ID:  id,
+				UID: uid,
+				JsonData: JsonData{
+					MaxOpenConns:    10,
+					MaxIdleConns:    2,
+					ConnMaxLifetime: 14400,
+					ConnMaxIdleTime: 60,
+				},
+			},
+		}
+		handler, err := NewQueryDataHandler(setting.NewCfg(), config, &testQueryResultTransformer{}, nil, log.New("test"))
+		require.NoError(t, err)
+		t.Cleanup(handler.Dispose)
+		return handler
+	}
+
+	t.Run("should return the statistics of the connection pool", func(t *testing.T) {
+		handler := newHandler(t, 1001, "pool-a")
+		require.NoError(t, handler.Ping())
+
+		stats, ok := GetConnectionPoolStats(1001)
+		require.True(t, ok)
+		require.Equal(t, "pool-a", stats.DataSourceUID)
+		require.Equal(t, "sqlite", stats.DataSourceType)
+		require.Equal(t, 10, stats.MaxOpenConns)
+		require.Equal(t, 60, stats.ConnMaxIdleTime)
+		require.Equal(t, 1, stats.OpenConnections)
+		require.Equal(t, 1, stats.Idle)
+		require.Contains(t, ListConnectionPoolStats(), stats)
+
+		_, ok = GetConnectionPoolStats(1002)
+		require.False(t, ok)
+	})
+
+	t.Run("should replace the connection pool and close the previous one when reset", func(t *testing.T) {
+		handler := newHandler(t, 1003, "pool-b")
+		require.NoError(t, handler.Ping())
+		previous := handler.acquirePool()
+		created := previous.created
+
+		ok, err := ResetConnectionPool(1003)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		stats, _ := GetConnectionPoolStats(1003)
+		require.Equal(t, 0, stats.OpenConnections)
+		require.True(t, stats.Created.After(created))
+
+		// the previous pool is closed once its queries are done
+		require.NoError(t, previous.engine.Ping())
+		previous.release()
+		require.Eventually(t, func() bool {
+			return previous.engine.Ping() != nil
+		}, time.Second, 10*time.Millisecond)
+		require.NoError(t, handler.Ping())
+
+		ok, err = ResetConnectionPool(1004)
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("should remove the connection pool when the handler is disposed", func(t *testing.T) {
+		handler := newHandler(t, 1005, "pool-c")
+		replaced := newHandler(t, 1005, "pool-c")
+
+		handler.Dispose()
+		_, ok := connectionPools.get(1005)
+		require.True(t, ok)
+
+		replaced.Dispose()
+		_, ok = connectionPools.get(1005)
+		require.False(t, ok)
+	})
+
+	t.Run("should collect the metrics of the connection pools", func(t *testing.T) {
+		newHandler(t, 1006, "pool-d")
+		newHandler(t, 1007, "pool-d")
+
+		// the pools of the data sources with the same uid are collected once
+		require.Equal(t, 1, testutil.CollectAndCount(connectionPools, "grafana_sql_datasource_conn_max_open"))
+	})
+}
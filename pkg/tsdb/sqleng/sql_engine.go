@@ -20,6 +20,7 @@ import (
 	"xorm.io/xorm"
 
 	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/query/inspect"
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/tsdb/intervalv2"
 	"github.com/grafana/grafana/pkg/util/errutil"
@@ -59,6 +60,7 @@ type JsonData struct {
 	MaxOpenConns            int    `json:"maxOpenConns"`
 	MaxIdleConns            int    `json:"maxIdleConns"`
 	ConnMaxLifetime         int    `json:"connMaxLifetime"`
+	ConnMaxIdleTime         int    `json:"connMaxIdleTime"`
 	ConnectionTimeout       int    `json:"connectionTimeout"`
 	Timescaledb             bool   `json:"timescaledb"`
 	Mode                    string `json:"sslmode"`
@@ -76,6 +78,14 @@ type JsonData struct {
 	SecureDSProxyUsername   string `json:"secureSocksProxyUsername"`
 	AllowCleartextPasswords bool   `json:"allowCleartextPasswords"`
 	AuthenticationType      string `json:"authenticationType"`
+	// ReadOnlyQueries rejects the queries which are not SELECT statements
+	ReadOnlyQueries bool `json:"readOnlyQueries"`
+	// QueryRowLimit wraps the queries to return at most this number of rows, no limit if 0
+	QueryRowLimit int64 `json:"queryRowLimit"`
+	// StatementTimeout is the maximum duration of the queries in seconds, no timeout if 0
+	StatementTimeout int `json:"statementTimeout"`
+	// MaxQueryCost rejects the queries whose cost estimated by the query planner exceeds it, no maximum if 0
+	MaxQueryCost float64 `json:"maxQueryCost"`
 }
 
 type DataSourceInfo struct {
@@ -94,27 +104,43 @@ type DefaultConnectionInfo struct {
 	MaxOpenConns    int
 	MaxIdleConns    int
 	ConnMaxLifetime int
+	ConnMaxIdleTime int
 }
 
 type DataPluginConfiguration struct {
+	// DataSourceType is the plugin ID of the data source, ex: mysql
+	DataSourceType    string
 	DriverName        string
 	DSInfo            DataSourceInfo
 	ConnectionString  string
 	TimeColumnNames   []string
 	MetricColumnTypes []string
 	RowLimit          int64
+	// Syntax is the SQL syntax of the data source, used by the guardrails to parse the queries
+	Syntax SQLSyntax
+	// LimitRowsWithSubquery is true if the row limit guardrail can wrap the queries in a subquery with a LIMIT clause
+	LimitRowsWithSubquery bool
+	// CostEstimator estimates the cost of the queries for the maximum query cost guardrail, not supported if nil
+	CostEstimator QueryCostEstimator
 }
 
 type DataSourceHandler struct {
 	macroEngine            SQLMacroEngine
 	queryResultTransformer SqlQueryResultTransformer
-	engine                 *xorm.Engine
+	dsType                 string
+	driverName             string
+	connectionString       string
+	poolMu                 sync.RWMutex
+	pool                   *connectionPool
 	timeColumnNames        []string
 	metricColumnTypes      []string
 	log                    log.Logger
 	dsInfo                 DataSourceInfo
 	rowLimit               int64
 	userError              string
+	syntax                 SQLSyntax
+	limitRowsWithSubquery  bool
+	costEstimator          QueryCostEstimator
 }
 
 type QueryJson struct {
@@ -152,9 +178,22 @@ func NewQueryDataHandler(cfg *setting.Cfg, config DataPluginConfiguration, query
 		macroEngine:            macroEngine,
 		timeColumnNames:        []string{"time"},
 		log:                    log,
+		dsType:                 config.DataSourceType,
+		driverName:             config.DriverName,
+		connectionString:       config.ConnectionString,
 		dsInfo:                 config.DSInfo,
 		rowLimit:               config.RowLimit,
 		userError:              cfg.UserFacingDefaultError,
+		syntax:                 config.Syntax,
+		limitRowsWithSubquery:  config.LimitRowsWithSubquery,
+		costEstimator:          config.CostEstimator,
+	}
+
+	if config.DSInfo.JsonData.QueryRowLimit > 0 && !config.LimitRowsWithSubquery {
+		log.Warn("The query row limit is not supported by the data source and is ignored", "uid", config.DSInfo.UID)
+	}
+	if config.DSInfo.JsonData.MaxQueryCost > 0 && config.CostEstimator == nil {
+		log.Warn("The maximum query cost is not supported by the data source and is ignored", "uid", config.DSInfo.UID)
 	}
 
 	if len(config.TimeColumnNames) > 0 {
@@ -165,16 +204,13 @@ func NewQueryDataHandler(cfg *setting.Cfg, config DataPluginConfiguration, query
 		queryDataHandler.metricColumnTypes = config.MetricColumnTypes
 	}
 
-	engine, err := NewXormEngine(config.DriverName, config.ConnectionString)
+	pool, err := queryDataHandler.newConnectionPool()
 	if err != nil {
 		return nil, err
 	}
+	queryDataHandler.pool = pool
 
-	engine.SetMaxOpenConns(config.DSInfo.JsonData.MaxOpenConns)
-	engine.SetMaxIdleConns(config.DSInfo.JsonData.MaxIdleConns)
-	engine.SetConnMaxLifetime(time.Duration(config.DSInfo.JsonData.ConnMaxLifetime) * time.Second)
-
-	queryDataHandler.engine = engine
+	connectionPools.add(log, &queryDataHandler)
 	return &queryDataHandler, nil
 }
 
@@ -185,8 +221,11 @@ type DBDataResponse struct {
 
 func (e *DataSourceHandler) Dispose() {
 	e.log.Debug("Disposing engine...")
-	if e.engine != nil {
-		if err := e.engine.Close(); err != nil {
+	connectionPools.remove(e)
+	e.poolMu.RLock()
+	defer e.poolMu.RUnlock()
+	if e.pool != nil {
+		if err := e.pool.engine.Close(); err != nil {
 			e.log.Error("Failed to dispose engine", "error", err)
 		}
 	}
@@ -194,7 +233,9 @@ func (e *DataSourceHandler) Dispose() {
 }
 
 func (e *DataSourceHandler) Ping() error {
-	return e.engine.Ping()
+	pool := e.acquirePool()
+	defer pool.release()
+	return pool.engine.Ping()
 }
 
 func (e *DataSourceHandler) QueryData(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
@@ -285,13 +326,45 @@ func (e *DataSourceHandler) executeQuery(query backend.DataQuery, wg *sync.WaitG
 		return
 	}
 
-	session := e.engine.NewSession()
+	if timeout := e.statementTimeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		queryContext, cancel = context.WithTimeout(queryContext, timeout)
+		defer cancel()
+	}
+
+	// record the query sent to the database when the query runs in debug mode
+	if recorder := inspect.FromContext(queryContext); recorder != nil {
+		start := time.Now()
+		defer func() {
+			recorded := inspect.SQLQuery{
+				RefID:           query.RefID,
+				RawSQL:          queryJson.RawSql,
+				InterpolatedSQL: interpolatedQuery,
+				DurationMs:      inspect.Milliseconds(time.Since(start)),
+			}
+			if queryResult.dataResponse.Error != nil {
+				recorded.Error = queryResult.dataResponse.Error.Error()
+			}
+			recorder.AddSQLQuery(recorded)
+		}()
+	}
+
+	pool := e.acquirePool()
+	defer pool.release()
+	session := pool.engine.NewSession()
 	defer session.Close()
 	db := session.DB()
 
+	guardedQuery, err := e.applyGuardrails(queryContext, db.DB, interpolatedQuery)
+	if err != nil {
+		errAppendDebug("query rejected", err, interpolatedQuery)
+		return
+	}
+	interpolatedQuery = guardedQuery
+
 	rows, err := db.QueryContext(queryContext, interpolatedQuery)
 	if err != nil {
-		errAppendDebug("db query error", e.TransformQueryError(logger, err), interpolatedQuery)
+		errAppendDebug("db query error", e.queryError(queryContext, logger, err), interpolatedQuery)
 		return
 	}
 	defer func() {
@@ -310,6 +383,9 @@ func (e *DataSourceHandler) executeQuery(query backend.DataQuery, wg *sync.WaitG
 	stringConverters := e.queryResultTransformer.GetConverterList()
 	frame, err := sqlutil.FrameFromRows(rows.Rows, e.rowLimit, sqlutil.ToConverters(stringConverters...)...)
 	if err != nil {
+		if e.timedOut(queryContext) {
+			err = e.statementTimeoutError()
+		}
 		errAppendDebug("convert frame from rows error", err, interpolatedQuery)
 		return
 	}
@@ -0,0 +1,224 @@
+package sqleng
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SQLSyntax describes the lexical rules of the SQL dialect of a data source. The guardrails parse the queries
+// with these rules so that the comments and quoted strings of the dialect cannot hide statements.
+type SQLSyntax struct {
+	// HashComments is true if # starts a comment, ex: MySQL
+	HashComments bool
+	// DashCommentsNeedSpace is true if -- only starts a comment when followed by a whitespace, ex: MySQL
+	DashCommentsNeedSpace bool
+	// NestedComments is true if the /* */ comments can be nested, ex: Postgres
+	NestedComments bool
+	// ExecutableComments is true if the /*! */ comments are executed, ex: MySQL
+	ExecutableComments bool
+	// BackslashEscapes is true if a backslash escapes the quotes in the quoted strings, ex: MySQL
+	BackslashEscapes bool
+	// DollarQuotes is true if the strings can be quoted with $tag$, ex: Postgres
+	DollarQuotes bool
+	// BacktickIdentifiers is true if the identifiers can be quoted with backticks, ex: MySQL
+	BacktickIdentifiers bool
+	// BracketIdentifiers is true if the identifiers can be quoted with brackets, ex: MSSQL
+	BracketIdentifiers bool
+}
+
+// sqlStatement is a statement of a query
+type sqlStatement struct {
+	// text is the statement without its terminating semicolon
+	text string
+	// keywords are the upper-cased unquoted words of the statement, the names of the called functions excluded
+	// unless they are write keywords
+	keywords []string
+}
+
+// functionKeywords are the write keywords which are also the names of functions, ex: REPLACE(str, from, to).
+// The other write keywords followed by a parenthesis are kept since they can start a statement, ex: the
+// EXEC('DROP TABLE t') statement of MSSQL.
+var functionKeywords = map[string]bool{
+	"REPLACE":  true,
+	"TRUNCATE": true,
+}
+
+// isFunctionName returns true if the word followed by a parenthesis is the name of a called function
+func isFunctionName(keyword string) bool {
+	return !writeKeywords[keyword] || functionKeywords[keyword]
+}
+
+func isIdentifierStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isIdentifierPart(c byte) bool {
+	return isIdentifierStart(c) || c == '$' || (c >= '0' && c <= '9')
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+// splitStatements splits the query into its statements, the empty statements are left out
+func (s SQLSyntax) splitStatements(query string) ([]sqlStatement, error) {
+	var statements []sqlStatement
+	start := 0
+	var keywords []string
+
+	endStatement := func(end int) {
+		if text := strings.TrimSpace(query[start:end]); text != "" {
+			statements = append(statements, sqlStatement{text: text, keywords: keywords})
+		}
+		keywords = nil
+	}
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ';':
+			endStatement(i)
+			i++
+			start = i
+		case c == '-' && strings.HasPrefix(query[i:], "--") &&
+			(!s.DashCommentsNeedSpace || i+2 == len(query) || isSpace(query[i+2])):
+			i = skipLine(query, i)
+		case c == '#' && s.HashComments:
+			i = skipLine(query, i)
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			if s.ExecutableComments && (strings.HasPrefix(query[i:], "/*!") || strings.HasPrefix(query[i:], "/*+")) {
+				return nil, fmt.Errorf("executable comments are not supported")
+			}
+			end, err := s.skipBlockComment(query, i)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+		case c == '\'' || c == '"':
+			end, err := s.skipQuoted(query, i, c)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+		case c == '`' && s.BacktickIdentifiers:
+			end, err := s.skipQuoted(query, i, c)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+		case c == '[' && s.BracketIdentifiers:
+			end, err := skipBracketIdentifier(query, i)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+		case c == '$' && s.DollarQuotes:
+			end, ok, err := skipDollarQuoted(query, i)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				end = i + 1
+			}
+			i = end
+		case isIdentifierStart(c):
+			end := i + 1
+			for end < len(query) && isIdentifierPart(query[end]) {
+				end++
+			}
+			word := query[i:end]
+			next := end
+			for next < len(query) && isSpace(query[next]) {
+				next++
+			}
+			if keyword := strings.ToUpper(word); next == len(query) || query[next] != '(' || !isFunctionName(keyword) {
+				keywords = append(keywords, keyword)
+			}
+			i = end
+		default:
+			i++
+		}
+	}
+	endStatement(len(query))
+
+	return statements, nil
+}
+
+func skipLine(query string, i int) int {
+	if end := strings.IndexByte(query[i:], '\n'); end >= 0 {
+		return i + end + 1
+	}
+	return len(query)
+}
+
+func (s SQLSyntax) skipBlockComment(query string, i int) (int, error) {
+	depth := 0
+	for i < len(query) {
+		switch {
+		case strings.HasPrefix(query[i:], "/*") && (depth == 0 || s.NestedComments):
+			depth++
+			i += 2
+		case strings.HasPrefix(query[i:], "*/"):
+			depth--
+			i += 2
+			if depth == 0 {
+				return i, nil
+			}
+		default:
+			i++
+		}
+	}
+	return 0, fmt.Errorf("unterminated comment")
+}
+
+func (s SQLSyntax) skipQuoted(query string, i int, quote byte) (int, error) {
+	for i++; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			if s.BackslashEscapes && quote != '`' {
+				i++
+			}
+		case quote:
+			// a doubled quote is an escaped quote
+			if i+1 < len(query) && query[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated quoted string")
+}
+
+func skipBracketIdentifier(query string, i int) (int, error) {
+	for i++; i < len(query); i++ {
+		if query[i] == ']' {
+			if i+1 < len(query) && query[i+1] == ']' {
+				i++
+				continue
+			}
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated quoted identifier")
+}
+
+// skipDollarQuoted skips a string quoted with $tag$, false if the $ does not start a dollar quote, ex: $1
+func skipDollarQuoted(query string, i int) (int, bool, error) {
+	end := i + 1
+	if end < len(query) && isIdentifierStart(query[end]) {
+		for end < len(query) && isIdentifierPart(query[end]) && query[end] != '$' {
+			end++
+		}
+	}
+	if end >= len(query) || query[end] != '$' {
+		return 0, false, nil
+	}
+
+	tag := query[i : end+1]
+	closing := strings.Index(query[end+1:], tag)
+	if closing < 0 {
+		return 0, false, fmt.Errorf("unterminated dollar-quoted string")
+	}
+	return end + 1 + closing + len(tag), true, nil
+}